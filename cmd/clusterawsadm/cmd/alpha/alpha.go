@@ -19,6 +19,9 @@ package alpha
 import (
 	"github.com/spf13/cobra"
 	"sigs.k8s.io/cluster-api-provider-aws/cmd/clusterawsadm/cmd/alpha/bootstrap"
+	"sigs.k8s.io/cluster-api-provider-aws/cmd/clusterawsadm/cmd/alpha/discover"
+	"sigs.k8s.io/cluster-api-provider-aws/cmd/clusterawsadm/cmd/alpha/ec2"
+	"sigs.k8s.io/cluster-api-provider-aws/cmd/clusterawsadm/cmd/alpha/template"
 )
 
 // AlphaCmd is the top-level alpha set of commands
@@ -32,5 +35,8 @@ func AlphaCmd() *cobra.Command { // nolint
 		},
 	}
 	newCmd.AddCommand(bootstrap.RootCmd())
+	newCmd.AddCommand(discover.RootCmd())
+	newCmd.AddCommand(ec2.RootCmd())
+	newCmd.AddCommand(template.RootCmd())
 	return newCmd
 }
@@ -0,0 +1,369 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package discover
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	cfn "github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/elb"
+	awssts "github.com/aws/aws-sdk-go/service/sts"
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/sts"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/tags"
+)
+
+const (
+	// kopsClusterTag is the legacy cluster tag of the Kubernetes AWS cloud
+	// provider, still set by kops on the resources of its clusters.
+	kopsClusterTag = "KubernetesCluster"
+
+	// eksctlClusterTag is the tag set by eksctl on the resources and
+	// CloudFormation stacks of its clusters.
+	eksctlClusterTag = "alpha.eksctl.io/cluster-name"
+
+	// elbDescribeTagsMaxNames is the maximum number of load balancers whose
+	// tags are described at once.
+	elbDescribeTagsMaxNames = 20
+)
+
+// clusterTagKeys are the keys of the tags identifying Kubernetes resources,
+// or the tool which created them.
+var clusterTagKeys = []string{
+	tags.NameKubernetesClusterPrefix + "*",
+	tags.NameAWSProviderManaged,
+	kopsClusterTag,
+	eksctlClusterTag,
+}
+
+// candidate is a cluster whose resources were found in the account.
+type candidate struct {
+	name          string
+	tools         map[string]bool
+	instances     []string
+	loadBalancers []string
+	stacks        []string
+	resources     []string
+}
+
+// suggestion tells whether a cluster could be adopted, should be cleaned up,
+// or is already managed by the Cluster API.
+func (c *candidate) suggestion() string {
+	switch {
+	case c.tools["cluster-api"]:
+		return "managed"
+	case len(c.instances) == 0:
+		return "cleanup"
+	default:
+		return "adopt"
+	}
+}
+
+// inventory is the set of clusters found in the account, by name.
+type inventory map[string]*candidate
+
+// record adds the resource of the given kind, identified by its tags, to
+// the clusters it belongs to.
+func (inv inventory) record(kind, id string, t map[string]string) {
+	for name, tool := range clusterNames(t) {
+		c, ok := inv[name]
+		if !ok {
+			c = &candidate{name: name, tools: map[string]bool{}}
+			inv[name] = c
+		}
+		c.tools[tool] = true
+
+		switch kind {
+		case "instance":
+			c.instances = append(c.instances, id)
+		case "load-balancer":
+			c.loadBalancers = append(c.loadBalancers, id)
+		case "stack":
+			c.stacks = append(c.stacks, id)
+		default:
+			c.resources = append(c.resources, fmt.Sprintf("%s/%s", kind, id))
+		}
+	}
+}
+
+// clusterNames returns the names of the clusters a resource belongs to
+// according to its tags, along with the tool which created it. Resources
+// shared with a cluster, like an existing VPC, are not part of it.
+func clusterNames(t map[string]string) map[string]string {
+	tool := "cloud-provider"
+	switch {
+	case t[tags.NameAWSProviderManaged] == "true":
+		tool = "cluster-api"
+	case t[eksctlClusterTag] != "":
+		tool = "eksctl"
+	case t[kopsClusterTag] != "":
+		tool = "kops"
+	}
+
+	names := map[string]string{}
+	for key, value := range t {
+		switch {
+		case strings.HasPrefix(key, tags.NameKubernetesClusterPrefix):
+			if value != string(tags.ResourceLifecycleOwned) {
+				continue
+			}
+			names[strings.TrimPrefix(key, tags.NameKubernetesClusterPrefix)] = tool
+		case key == kopsClusterTag, key == eksctlClusterTag:
+			if value != "" {
+				names[value] = tool
+			}
+		}
+	}
+
+	return names
+}
+
+// RootCmd is the root of the `alpha discover` command
+func RootCmd() *cobra.Command {
+	var details bool
+
+	newCmd := &cobra.Command{
+		Use:   "discover",
+		Short: "Discover Kubernetes clusters in the account",
+		Long: `Scan the region of the account for the resources of Kubernetes clusters, such as instances,
+load balancers and other EC2 resources tagged by the Kubernetes cloud provider, the Cluster API,
+kops or eksctl, and the CloudFormation stacks of eksctl. The clusters found are reported as
+candidates for adoption when they still have instances, or for cleanup otherwise.
+The state stores of kops in S3 are not scanned.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			sess, err := session.NewSessionWithOptions(session.Options{
+				SharedConfigState: session.SharedConfigEnable,
+			})
+			if err != nil {
+				return err
+			}
+
+			accountID, err := sts.NewService(awssts.New(sess)).AccountID()
+			if err != nil {
+				return err
+			}
+
+			inv := inventory{}
+			if err := discoverInstances(ec2.New(sess), inv); err != nil {
+				return err
+			}
+			if err := discoverEC2Resources(ec2.New(sess), inv); err != nil {
+				return err
+			}
+			if err := discoverLoadBalancers(elb.New(sess), inv); err != nil {
+				return err
+			}
+			if err := discoverStacks(cfn.New(sess), inv); err != nil {
+				return err
+			}
+
+			fmt.Printf("Found %d clusters in region %s of account %s\n\n", len(inv), aws.StringValue(sess.Config.Region), accountID)
+			return report(inv, details)
+		},
+	}
+	newCmd.Flags().BoolVar(&details, "details", false, "List the resources of each cluster")
+
+	return newCmd
+}
+
+// discoverInstances records the instances of clusters which are not terminated.
+func discoverInstances(svc *ec2.EC2, inv inventory) error {
+	input := &ec2.DescribeInstancesInput{
+		Filters: []*ec2.Filter{
+			{
+				Name:   aws.String("tag-key"),
+				Values: aws.StringSlice(clusterTagKeys),
+			},
+			{
+				Name: aws.String("instance-state-name"),
+				Values: aws.StringSlice([]string{
+					ec2.InstanceStateNamePending,
+					ec2.InstanceStateNameRunning,
+					ec2.InstanceStateNameStopping,
+					ec2.InstanceStateNameStopped,
+				}),
+			},
+		},
+	}
+
+	err := svc.DescribeInstancesPages(input, func(page *ec2.DescribeInstancesOutput, lastPage bool) bool {
+		for _, reservation := range page.Reservations {
+			for _, instance := range reservation.Instances {
+				t := map[string]string{}
+				for _, tag := range instance.Tags {
+					t[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+				}
+				inv.record("instance", aws.StringValue(instance.InstanceId), t)
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return fmt.Errorf("failed to describe instances: %v", err)
+	}
+
+	return nil
+}
+
+// discoverEC2Resources records the EC2 resources of clusters other than
+// instances, such as VPCs, subnets, security groups and volumes.
+func discoverEC2Resources(svc *ec2.EC2, inv inventory) error {
+	input := &ec2.DescribeTagsInput{
+		Filters: []*ec2.Filter{
+			{
+				Name:   aws.String("key"),
+				Values: aws.StringSlice(clusterTagKeys),
+			},
+		},
+	}
+
+	kinds := map[string]string{}
+	resources := map[string]map[string]string{}
+	err := svc.DescribeTagsPages(input, func(page *ec2.DescribeTagsOutput, lastPage bool) bool {
+		for _, tag := range page.Tags {
+			id := aws.StringValue(tag.ResourceId)
+			if resources[id] == nil {
+				kinds[id] = aws.StringValue(tag.ResourceType)
+				resources[id] = map[string]string{}
+			}
+			resources[id][aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+		}
+		return true
+	})
+	if err != nil {
+		return fmt.Errorf("failed to describe tags: %v", err)
+	}
+
+	for id, t := range resources {
+		// The tags of terminated instances are still returned for a while.
+		if kinds[id] == ec2.ResourceTypeInstance {
+			continue
+		}
+		inv.record(kinds[id], id, t)
+	}
+
+	return nil
+}
+
+// discoverLoadBalancers records the classic load balancers of clusters.
+func discoverLoadBalancers(svc *elb.ELB, inv inventory) error {
+	var names []string
+	err := svc.DescribeLoadBalancersPages(&elb.DescribeLoadBalancersInput{}, func(page *elb.DescribeLoadBalancersOutput, lastPage bool) bool {
+		for _, lb := range page.LoadBalancerDescriptions {
+			names = append(names, aws.StringValue(lb.LoadBalancerName))
+		}
+		return true
+	})
+	if err != nil {
+		return fmt.Errorf("failed to describe load balancers: %v", err)
+	}
+
+	for len(names) > 0 {
+		n := len(names)
+		if n > elbDescribeTagsMaxNames {
+			n = elbDescribeTagsMaxNames
+		}
+
+		out, err := svc.DescribeTags(&elb.DescribeTagsInput{LoadBalancerNames: aws.StringSlice(names[:n])})
+		if err != nil {
+			return fmt.Errorf("failed to describe tags of load balancers: %v", err)
+		}
+
+		for _, desc := range out.TagDescriptions {
+			t := map[string]string{}
+			for _, tag := range desc.Tags {
+				t[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+			}
+			inv.record("load-balancer", aws.StringValue(desc.LoadBalancerName), t)
+		}
+
+		names = names[n:]
+	}
+
+	return nil
+}
+
+// discoverStacks records the CloudFormation stacks of clusters, like the
+// ones created by eksctl.
+func discoverStacks(svc *cfn.CloudFormation, inv inventory) error {
+	err := svc.DescribeStacksPages(&cfn.DescribeStacksInput{}, func(page *cfn.DescribeStacksOutput, lastPage bool) bool {
+		for _, stack := range page.Stacks {
+			t := map[string]string{}
+			for _, tag := range stack.Tags {
+				t[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+			}
+			inv.record("stack", aws.StringValue(stack.StackName), t)
+		}
+		return true
+	})
+	if err != nil {
+		return fmt.Errorf("failed to describe stacks: %v", err)
+	}
+
+	return nil
+}
+
+// report prints the clusters of the inventory, sorted by name.
+func report(inv inventory, details bool) error {
+	names := make([]string, 0, len(inv))
+	for name := range inv {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "CLUSTER\tCREATED BY\tINSTANCES\tLOAD BALANCERS\tSTACKS\tOTHER RESOURCES\tSUGGESTION")
+	for _, name := range names {
+		c := inv[name]
+		tools := make([]string, 0, len(c.tools))
+		for tool := range c.tools {
+			tools = append(tools, tool)
+		}
+		sort.Strings(tools)
+
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%d\t%d\t%s\n", name, strings.Join(tools, ","),
+			len(c.instances), len(c.loadBalancers), len(c.stacks), len(c.resources), c.suggestion())
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if !details {
+		return nil
+	}
+
+	for _, name := range names {
+		c := inv[name]
+		fmt.Printf("\n%s:\n", name)
+		for _, ids := range [][]string{c.instances, c.loadBalancers, c.stacks, c.resources} {
+			sort.Strings(ids)
+			for _, id := range ids {
+				fmt.Printf("  %s\n", id)
+			}
+		}
+	}
+
+	return nil
+}
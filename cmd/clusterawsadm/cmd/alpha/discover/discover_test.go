@@ -0,0 +1,119 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package discover
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestClusterNames(t *testing.T) {
+	testCases := []struct {
+		name     string
+		tags     map[string]string
+		expected map[string]string
+	}{
+		{
+			name: "owned by the cloud provider",
+			tags: map[string]string{"kubernetes.io/cluster/a": "owned"},
+			expected: map[string]string{
+				"a": "cloud-provider",
+			},
+		},
+		{
+			name: "owned by the cluster api",
+			tags: map[string]string{
+				"kubernetes.io/cluster/a":                      "owned",
+				"sigs.k8s.io/cluster-api-provider-aws/managed": "true",
+			},
+			expected: map[string]string{
+				"a": "cluster-api",
+			},
+		},
+		{
+			name:     "shared with a cluster",
+			tags:     map[string]string{"kubernetes.io/cluster/a": "shared"},
+			expected: map[string]string{},
+		},
+		{
+			name: "owned by one cluster and shared with another",
+			tags: map[string]string{
+				"kubernetes.io/cluster/a": "owned",
+				"kubernetes.io/cluster/b": "shared",
+			},
+			expected: map[string]string{
+				"a": "cloud-provider",
+			},
+		},
+		{
+			name: "created by kops",
+			tags: map[string]string{"KubernetesCluster": "a"},
+			expected: map[string]string{
+				"a": "kops",
+			},
+		},
+		{
+			name: "created by eksctl",
+			tags: map[string]string{"alpha.eksctl.io/cluster-name": "a"},
+			expected: map[string]string{
+				"a": "eksctl",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if names := clusterNames(tc.tags); !reflect.DeepEqual(names, tc.expected) {
+				t.Fatalf("expected clusters %v, got %v", tc.expected, names)
+			}
+		})
+	}
+}
+
+func TestInventory(t *testing.T) {
+	inv := inventory{}
+
+	// Cluster a still runs an instance and uses the VPC of cluster b, which
+	// has no instance left.
+	inv.record("instance", "i-1", map[string]string{"kubernetes.io/cluster/a": "owned"})
+	inv.record("vpc", "vpc-1", map[string]string{
+		"kubernetes.io/cluster/a": "shared",
+		"kubernetes.io/cluster/b": "owned",
+	})
+	// Cluster c only shares a subnet, none of its resources are left.
+	inv.record("subnet", "subnet-1", map[string]string{"kubernetes.io/cluster/c": "shared"})
+
+	if len(inv) != 2 {
+		t.Fatalf("expected clusters a and b, got %v", inv)
+	}
+
+	a := inv["a"]
+	if !reflect.DeepEqual(a.instances, []string{"i-1"}) || len(a.resources) != 0 {
+		t.Fatalf("expected cluster a to own instance i-1 only, got %+v", a)
+	}
+	if s := a.suggestion(); s != "adopt" {
+		t.Fatalf("expected cluster a to be adopted, got %q", s)
+	}
+
+	b := inv["b"]
+	if !reflect.DeepEqual(b.resources, []string{"vpc/vpc-1"}) {
+		t.Fatalf("expected cluster b to own vpc-1, got %+v", b)
+	}
+	if s := b.suggestion(); s != "cleanup" {
+		t.Fatalf("expected cluster b to be cleaned up, got %q", s)
+	}
+}
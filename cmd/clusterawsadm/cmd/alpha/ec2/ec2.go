@@ -0,0 +1,173 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ec2
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/spf13/cobra"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/cluster-api-provider-aws/cmd/clusterawsadm/client"
+	providerv1 "sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+)
+
+// RootCmd is the root of the `alpha ec2` command
+func RootCmd() *cobra.Command {
+	newCmd := &cobra.Command{
+		Use:   "ec2",
+		Short: "EC2 account settings and instances",
+		Long:  `Manage the EC2 settings of the account and access the instances of machines`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := cmd.Help(); err != nil {
+				return err
+			}
+			return nil
+		},
+	}
+	newCmd.AddCommand(serialConsoleCmd())
+	return newCmd
+}
+
+func serialConsoleCmd() *cobra.Command {
+	newCmd := &cobra.Command{
+		Use:   "serial-console",
+		Short: "EC2 serial console access",
+		Long: `Manage the access to the EC2 serial console, to debug instances whose network is broken.
+The access is an account setting of each region, and machines must also enable it in their provider spec.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := cmd.Help(); err != nil {
+				return err
+			}
+			return nil
+		},
+	}
+	newCmd.AddCommand(serialConsoleAccessCmd("enable", "Enable the serial console access of the account in the region", "EnableSerialConsoleAccess"))
+	newCmd.AddCommand(serialConsoleAccessCmd("disable", "Disable the serial console access of the account in the region", "DisableSerialConsoleAccess"))
+	newCmd.AddCommand(serialConsoleAccessCmd("status", "Show whether the serial console access of the account is enabled in the region", "GetSerialConsoleAccessStatus"))
+	newCmd.AddCommand(serialConsoleConnectCmd())
+	return newCmd
+}
+
+func serialConsoleAccessCmd(use, short, operation string) *cobra.Command {
+	newCmd := &cobra.Command{
+		Use:   use,
+		Short: short,
+		Long:  short,
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			sess, err := session.NewSessionWithOptions(session.Options{
+				SharedConfigState: session.SharedConfigEnable,
+			})
+			if err != nil {
+				return err
+			}
+
+			enabled, err := serialConsoleAccess(ec2.New(sess), operation)
+			if err != nil {
+				return err
+			}
+
+			status := "disabled"
+			if enabled {
+				status = "enabled"
+			}
+			fmt.Printf("Serial console access is %s in region %s\n", status, aws.StringValue(sess.Config.Region))
+			return nil
+		},
+	}
+
+	return newCmd
+}
+
+func serialConsoleConnectCmd() *cobra.Command {
+	var namespace, publicKey string
+
+	newCmd := &cobra.Command{
+		Use:   "connect [machine name]",
+		Short: "Show how to connect to the serial console of a machine",
+		Long: `Show the commands pushing an SSH public key for the serial console of the instance of a machine,
+and connecting to it. The key is valid for 60 seconds.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			sess, err := session.NewSessionWithOptions(session.Options{
+				SharedConfigState: session.SharedConfigEnable,
+			})
+			if err != nil {
+				return err
+			}
+
+			c := client.NewClient()
+			m, err := c.ClusterV1alpha1().Machines(namespace).Get(args[0], v1.GetOptions{})
+			if err != nil {
+				return err
+			}
+
+			status, err := providerv1.MachineStatusFromProviderStatus(m.Status.ProviderStatus)
+			if err != nil {
+				return err
+			}
+			if status == nil || status.InstanceID == nil {
+				return fmt.Errorf("machine %q has no instance", args[0])
+			}
+
+			instanceID := *status.InstanceID
+			region := aws.StringValue(sess.Config.Region)
+			fmt.Printf("aws ec2-instance-connect send-serial-console-ssh-public-key --region %s --instance-id %s --serial-port 0 --ssh-public-key file://%s\n", region, instanceID, publicKey)
+			fmt.Printf("ssh -i %s %s.port0@serial-console.ec2-instance-connect.%s.aws\n", strings.TrimSuffix(publicKey, ".pub"), instanceID, region)
+			return nil
+		},
+	}
+	newCmd.Flags().StringVarP(&namespace, "namespace", "n", "default", "Namespace of the machine")
+	newCmd.Flags().StringVar(&publicKey, "ssh-public-key", filepath.Join(os.Getenv("HOME"), ".ssh", "id_rsa.pub"), "SSH public key to push, next to its private key")
+
+	return newCmd
+}
+
+// serialConsoleAccessOutput is the output of the serial console access
+// operations, which the vendored SDK predates.
+type serialConsoleAccessOutput struct {
+	_ struct{} `type:"structure"`
+
+	SerialConsoleAccessEnabled *bool `locationName:"serialConsoleAccessEnabled" type:"boolean"`
+}
+
+// serialConsoleAccess calls one of the serial console access operations,
+// and returns whether the access is enabled.
+func serialConsoleAccess(svc *ec2.EC2, operation string) (bool, error) {
+	op := &request.Operation{
+		Name:       operation,
+		HTTPMethod: "POST",
+		HTTPPath:   "/",
+	}
+	input := &struct {
+		_ struct{} `type:"structure"`
+	}{}
+	output := &serialConsoleAccessOutput{}
+
+	if err := svc.NewRequest(op, input, output).Send(); err != nil {
+		return false, err
+	}
+
+	return aws.BoolValue(output.SerialConsoleAccessEnabled), nil
+}
@@ -0,0 +1,142 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package template
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"github.com/spf13/cobra"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/cluster-api-provider-aws/cmd/clusterawsadm/client"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/actuators"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/template"
+	"sigs.k8s.io/yaml"
+)
+
+// RootCmd is the root of the `alpha template` command
+func RootCmd() *cobra.Command {
+	newCmd := &cobra.Command{
+		Use:   "template",
+		Short: "golden cluster templates",
+		Long:  `Capture provisioned clusters as templates, and create identical clusters from them`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := cmd.Help(); err != nil {
+				return err
+			}
+			return nil
+		},
+	}
+	newCmd.AddCommand(captureCmd())
+	newCmd.AddCommand(instantiateCmd())
+	return newCmd
+}
+
+func captureCmd() *cobra.Command {
+	var namespace string
+
+	newCmd := &cobra.Command{
+		Use:   "capture [cluster name]",
+		Short: "Capture a provisioned cluster as a template",
+		Long: `Capture the provider spec of a provisioned cluster and of its machines as a template,
+along with the AMIs, instance types and network layout that were resolved for them.
+The template is printed as YAML.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c := client.NewClient()
+
+			cluster, err := c.ClusterV1alpha1().Clusters(namespace).Get(args[0], v1.GetOptions{})
+			if err != nil {
+				return err
+			}
+
+			machines, err := c.ClusterV1alpha1().Machines(namespace).List(v1.ListOptions{})
+			if err != nil {
+				return err
+			}
+
+			scope, err := actuators.NewScope(actuators.ScopeParams{Cluster: cluster})
+			if err != nil {
+				return err
+			}
+
+			tpl, err := template.Capture(scope, machines.Items)
+			if err != nil {
+				return err
+			}
+
+			out, err := yaml.Marshal(tpl)
+			if err != nil {
+				return err
+			}
+
+			fmt.Print(string(out))
+			return nil
+		},
+	}
+	newCmd.Flags().StringVarP(&namespace, "namespace", "n", "default", "Namespace of the cluster and its machines")
+
+	return newCmd
+}
+
+func instantiateCmd() *cobra.Command {
+	var namespace string
+
+	newCmd := &cobra.Command{
+		Use:   "instantiate [template file] [cluster name]",
+		Short: "Create a cluster from a template",
+		Long: `Create the cluster and machines defined by a template under a new name.
+They are printed as YAML, to be applied to the management cluster.`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			data, err := ioutil.ReadFile(args[0])
+			if err != nil {
+				return err
+			}
+
+			tpl := &v1alpha1.AWSClusterTemplate{}
+			if err := yaml.Unmarshal(data, tpl); err != nil {
+				return err
+			}
+
+			cluster, machines, err := template.Instantiate(tpl, args[1], namespace)
+			if err != nil {
+				return err
+			}
+
+			out, err := yaml.Marshal(cluster)
+			if err != nil {
+				return err
+			}
+			fmt.Print(string(out))
+
+			for _, m := range machines {
+				out, err := yaml.Marshal(m)
+				if err != nil {
+					return err
+				}
+				fmt.Printf("---\n%s", out)
+			}
+
+			return nil
+		},
+	}
+	newCmd.Flags().StringVarP(&namespace, "namespace", "n", "default", "Namespace of the new cluster and its machines")
+
+	return newCmd
+}
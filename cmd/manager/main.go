@@ -17,12 +17,21 @@ limitations under the License.
 package main
 
 import (
+	"expvar"
 	"flag"
+	"net/http"
+	"strings"
 
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
 	"k8s.io/klog"
 	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis"
 	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/actuators/cluster"
 	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/actuators/machine"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/actuators/machinepool"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/compatibility"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/features"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/metrics"
 	"sigs.k8s.io/cluster-api-provider-aws/pkg/record"
 	clusterapis "sigs.k8s.io/cluster-api/pkg/apis"
 	"sigs.k8s.io/cluster-api/pkg/apis/cluster/common"
@@ -34,6 +43,24 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/runtime/signals"
 )
 
+var (
+	namespace = flag.String("namespace", "", "Namespace to watch for clusters and machines. If empty, all namespaces are watched.")
+	selector  = flag.String("selector", "", "Label selector restricting the controller to the matching clusters and their machines. If empty, all clusters are handled.")
+	readOnly  = flag.Bool("read-only", false, "Observe clusters and machines without creating, modifying or deleting any AWS resource, e.g. to investigate an incident. "+
+		"Deletions of clusters and machines are postponed until the controller runs without this flag.")
+
+	compatibilityMatrix = flag.String("compatibility-matrix", "", "Namespace/name of a ConfigMap overriding the matrix of the supported Kubernetes versions embedded in the controller, "+
+		"with the matrix under its "+compatibility.MatrixKey+" key. If empty, or while the ConfigMap does not exist, the embedded matrix is used.")
+
+	metricsAddr = flag.String("metrics-addr", "", "Address to serve the convergence times of clusters and machines on, at /debug/vars. If empty, they are not served.")
+)
+
+func init() {
+	flag.DurationVar(&metrics.SlowThreshold, "slow-convergence-threshold", metrics.SlowThreshold, "Time after which a cluster or machine component that has not converged is logged as a warning.")
+	flag.Var(features.DefaultFeatureGate, "feature-gates", "A set of key=value pairs that enable or disable features, e.g. Foo=true,Bar=false. "+
+		"Clusters and machines can override them with the "+features.FeatureGatesAnnotation+" annotation.")
+}
+
 // initLogs is a temporary hack to enable proper logging until upstream dependencies
 // are migrated to fully utilize klog instead of glog.
 func initLogs() {
@@ -49,8 +76,17 @@ func main() {
 	initLogs()
 	cfg := config.GetConfigOrDie()
 
+	clusterSelector, err := labels.Parse(*selector)
+	if err != nil {
+		klog.Fatalf("Failed to parse cluster selector %q: %v", *selector, err)
+	}
+
+	if *readOnly {
+		klog.Warning("Running in read-only mode, AWS resources will not be created, modified or deleted")
+	}
+
 	// Setup a Manager
-	mgr, err := manager.New(cfg, manager.Options{})
+	mgr, err := manager.New(cfg, manager.Options{Namespace: *namespace})
 	if err != nil {
 		klog.Fatalf("Failed to set up overall controller manager: %v", err)
 	}
@@ -60,17 +96,42 @@ func main() {
 		klog.Fatalf("Failed to create client from configuration: %v", err)
 	}
 
+	kubeClient, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		klog.Fatalf("Failed to create kubernetes client from configuration: %v", err)
+	}
+
+	var compatibilitySource compatibility.Source
+	if *compatibilityMatrix != "" {
+		parts := strings.SplitN(*compatibilityMatrix, "/", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			klog.Fatalf("Invalid compatibility matrix ConfigMap %q, expected namespace/name", *compatibilityMatrix)
+		}
+
+		compatibilitySource = &compatibility.ConfigMapSource{
+			Client:    kubeClient.CoreV1(),
+			Namespace: parts[0],
+			Name:      parts[1],
+		}
+	}
+
 	// Initialize event recorder.
 	record.InitFromRecorder(mgr.GetRecorder("aws-controller"))
 
 	// Initialize cluster actuator.
 	clusterActuator := cluster.NewActuator(cluster.ActuatorParams{
-		Client: cs.ClusterV1alpha1(),
+		Client:   cs.ClusterV1alpha1(),
+		Selector: clusterSelector,
+		ReadOnly: *readOnly,
 	})
 
 	// Initialize machine actuator.
 	machineActuator := machine.NewActuator(machine.ActuatorParams{
-		Client: cs.ClusterV1alpha1(),
+		Client:        cs.ClusterV1alpha1(),
+		Selector:      clusterSelector,
+		ReadOnly:      *readOnly,
+		Compatibility: compatibilitySource,
+		FileSources:   kubeClient.CoreV1(),
 	})
 
 	// Register our cluster deployer (the interface is in clusterctl and we define the Deployer interface on the actuator)
@@ -87,6 +148,23 @@ func main() {
 	capimachine.AddWithActuator(mgr, machineActuator)
 	capicluster.AddWithActuator(mgr, clusterActuator)
 
+	// Initialize machine pool controller.
+	if err := machinepool.Add(mgr, machinepool.NewReconciler(machinepool.ReconcilerParams{
+		Client:   mgr.GetClient(),
+		Selector: clusterSelector,
+		ReadOnly: *readOnly,
+	})); err != nil {
+		klog.Fatalf("Failed to add machine pool controller: %v", err)
+	}
+
+	if *metricsAddr != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/debug/vars", expvar.Handler())
+		go func() {
+			klog.Fatalf("Failed to serve metrics: %v", http.ListenAndServe(*metricsAddr, mux))
+		}()
+	}
+
 	if err := mgr.Start(signals.SetupSignalHandler()); err != nil {
 		klog.Fatalf("Failed to run manager: %v", err)
 	}
@@ -40,7 +40,151 @@ type AWSClusterProviderSpec struct {
 	CACertificate []byte `json:"caCertificate,omitempty"`
 
 	// CAPrivateKey is a PEM encoded PKCS1 CA PrivateKey for the control plane nodes.
+	// It is left empty when the key is stored in the SecretBackend.
 	CAPrivateKey []byte `json:"caKey,omitempty"`
+
+	// SecretBackend, if set, stores the CA private key outside of the cluster
+	// object. A key already recorded in the cluster object is moved to the backend.
+	// +optional
+	SecretBackend *SecretBackend `json:"secretBackend,omitempty"`
+
+	// ExternalNetwork, if set, makes the cluster use network resources managed
+	// outside of the provider. They are only looked up and recorded in the
+	// cluster status, and never created, modified or deleted.
+	// +optional
+	ExternalNetwork *ExternalNetwork `json:"externalNetwork,omitempty"`
+
+	// ResourceNaming configures the names of the AWS resources created for the cluster.
+	// If not specified, resources are named after the cluster.
+	// +optional
+	ResourceNaming *ResourceNaming `json:"resourceNaming,omitempty"`
+
+	// ManagementPeering, if set, peers the cluster VPC with the VPC the
+	// management cluster runs in, and routes traffic between both VPCs
+	// through the peering connection.
+	// +optional
+	ManagementPeering *VPCPeering `json:"managementPeering,omitempty"`
+
+	// ImageEncryption, if set, causes the AMIs used by machines to be copied into
+	// the cluster account and region with encrypted EBS snapshots before use.
+	// +optional
+	ImageEncryption *ImageEncryption `json:"imageEncryption,omitempty"`
+
+	// ImageBuild, if set, builds the AMIs the default image lookup of a
+	// machine does not find, with an SSM Automation runbook, and launches
+	// the machine from the built AMI once the build succeeds.
+	// +optional
+	ImageBuild *ImageBuild `json:"imageBuild,omitempty"`
+
+	// PrivateAPIServer makes the API server load balancer internal, placed in
+	// the private subnets of the cluster, when it is created. The controllers
+	// then reach the API server with Session Manager port forwarding through
+	// a running control plane instance, which requires the SSM agent.
+	// +optional
+	PrivateAPIServer bool `json:"privateAPIServer,omitempty"`
+
+	// APIServerElasticIP replaces the API server load balancer with an
+	// Elastic IP allocated for the cluster and associated with its control
+	// plane machine, for single-node clusters where a load balancer is not
+	// worth its cost. The cluster then supports a single control plane
+	// machine, launched in a public subnet unless one is set. It cannot be
+	// combined with PrivateAPIServer.
+	// +optional
+	APIServerElasticIP bool `json:"apiServerElasticIP,omitempty"`
+
+	// APIServerPrivateLink, if set, exposes the API server as a PrivateLink
+	// endpoint service, backed by an internal network load balancer in the
+	// private subnets of the cluster, so that clients in other VPCs and
+	// accounts reach it through interface VPC endpoints, without peering. It
+	// cannot be combined with APIServerElasticIP.
+	// +optional
+	APIServerPrivateLink *PrivateLink `json:"apiServerPrivateLink,omitempty"`
+
+	// ReportReservedInstanceCoverage enables reporting, in the cluster status, of
+	// how many of the running cluster instances are covered by the active
+	// reserved instances of the account.
+	// +optional
+	ReportReservedInstanceCoverage bool `json:"reportReservedInstanceCoverage,omitempty"`
+
+	// EnableEFA allows all the traffic between the nodes in the node security
+	// group, as required by the Elastic Fabric Adapters machines may request.
+	// +optional
+	EnableEFA bool `json:"enableEFA,omitempty"`
+
+	// NotificationTopicARN is the ARN of an SNS topic to which the lifecycle
+	// events of the machines of the cluster are published as JSON messages,
+	// for automation outside of Kubernetes. The events are created, failed,
+	// deleted and replaced.
+	// +optional
+	NotificationTopicARN string `json:"notificationTopicARN,omitempty"`
+
+	// RegionAMIs maps regions to the ID of the default AMI of the machines
+	// in the region, used by the machines which specify no image of their
+	// own, so that the same manifests can be applied in several regions.
+	// The region of the cluster must be listed.
+	// +optional
+	RegionAMIs map[string]string `json:"regionAMIs,omitempty"`
+
+	// LifecycleHooks are custom provisioning steps invoked before the
+	// instances of machines are launched or terminated, and once their nodes
+	// have joined the cluster.
+	// +optional
+	LifecycleHooks []LifecycleHook `json:"lifecycleHooks,omitempty"`
+
+	// IPAM, if set, allocates the CIDR blocks of the VPC and subnets created
+	// for the cluster, and its pod CIDR block if the cluster network has
+	// none, from a central address plan. The CIDR blocks recorded in the
+	// cluster status and spec are used as is.
+	// +optional
+	IPAM *IPAM `json:"ipam,omitempty"`
+
+	// RestrictedEgress replaces the open egress of the security groups of the
+	// control plane and nodes with the traffic they require: to each other,
+	// to the API server load balancer, HTTP, HTTPS, DNS and NTP. The API server load
+	// balancer then gets a security group of its own, whose egress is
+	// restricted to the API server port of the control plane.
+	// +optional
+	RestrictedEgress bool `json:"restrictedEgress,omitempty"`
+
+	// TransitEncryption, if set, enforces the encryption and authentication
+	// of the connections to the kubelets and between etcd members, for
+	// clusters that must pass security benchmarks.
+	// +optional
+	TransitEncryption *TransitEncryption `json:"transitEncryption,omitempty"`
+
+	// NTPServers are the NTP servers, by host name or IP address, the clocks
+	// of the machines are synchronised with by chrony or systemd-timesyncd.
+	// Defaults to the Amazon Time Sync Service, which every instance reaches
+	// at 169.254.169.123 without access to the internet.
+	// +optional
+	NTPServers []string `json:"ntpServers,omitempty"`
+
+	// SpreadControlPlane enforces the spread of the control plane machines
+	// across availability zones. Control plane machines without a subnet are
+	// placed in the zone hosting the fewest control plane instances, and
+	// those whose subnet is in a zone already hosting one are rejected while
+	// another zone of the cluster hosts none. Control plane machines cannot
+	// be launched in a cluster placement group, which is bound to one zone.
+	// +optional
+	SpreadControlPlane bool `json:"spreadControlPlane,omitempty"`
+
+	// MachinePools are the Auto Scaling groups whose instances join the
+	// cluster as nodes, alongside the machines.
+	// +optional
+	MachinePools []MachinePool `json:"machinePools,omitempty"`
+
+	// TerminationHandler, if set, deploys a node termination handler into
+	// the cluster once any of its machine pools runs spot instances, so that
+	// the nodes of interrupted instances are drained beforehand.
+	// +optional
+	TerminationHandler *TerminationHandler `json:"terminationHandler,omitempty"`
+
+	// LifecycleHookQueueURL is the URL of an SQS queue receiving the
+	// notifications of the EC2_INSTANCE_TERMINATING lifecycle hooks of the
+	// machine pools. The nodes of the terminating instances are drained and
+	// deleted before the lifecycle actions are completed.
+	// +optional
+	LifecycleHookQueueURL string `json:"lifecycleHookQueueURL,omitempty"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
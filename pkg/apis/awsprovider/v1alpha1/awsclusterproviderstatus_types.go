@@ -32,6 +32,46 @@ type AWSClusterProviderStatus struct {
 
 	Network Network  `json:"network,omitempty"`
 	Bastion Instance `json:"bastion,omitempty"`
+
+	// Phase summarizes the provisioning progress of the cluster.
+	// +optional
+	Phase ClusterPhase `json:"phase,omitempty"`
+
+	// PhaseMessage details the phase, e.g. the error blocking the progress of the cluster.
+	// +optional
+	PhaseMessage string `json:"phaseMessage,omitempty"`
+
+	// ReservedInstanceCoverage compares, per instance type, the running cluster
+	// instances with the active reserved instances of the account.
+	// It is only populated if enabled in the cluster provider spec.
+	// +optional
+	ReservedInstanceCoverage []ReservedInstanceCoverage `json:"reservedInstanceCoverage,omitempty"`
+
+	// ZoneLaunchFailures records, per availability zone, instances that failed
+	// to launch because of insufficient capacity or an impaired zone.
+	// Zones with repeated recent failures are avoided when placing new machines.
+	// +optional
+	ZoneLaunchFailures map[string]ZoneLaunchFailure `json:"zoneLaunchFailures,omitempty"`
+
+	// AccountID is the ID of the AWS account the cluster was created in.
+	// The controller refuses to reconcile the cluster with the credentials
+	// of another account.
+	// +optional
+	AccountID string `json:"accountID,omitempty"`
+
+	// Region is the AWS region the cluster was created in.
+	// +optional
+	Region string `json:"region,omitempty"`
+
+	// ScaleInProtectedInstances are the instances of machine pools protected
+	// from scale in by the provider while their node is being drained.
+	// +optional
+	ScaleInProtectedInstances []string `json:"scaleInProtectedInstances,omitempty"`
+
+	// Conditions is a set of conditions associated with the cluster, to
+	// indicate errors or other status.
+	// +optional
+	Conditions []AWSClusterProviderCondition `json:"conditions,omitempty"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
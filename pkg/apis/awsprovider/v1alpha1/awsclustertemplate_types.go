@@ -0,0 +1,114 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// AWSClusterTemplate is a snapshot of a provisioned cluster, capturing its
+// provider spec and the defaults that were resolved for it, from which
+// identical clusters can be created.
+// +k8s:openapi-gen=true
+type AWSClusterTemplate struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// Cluster is the cluster configuration.
+	Cluster ClusterTemplate `json:"cluster"`
+
+	// Network is the network layout of the captured cluster.
+	// The network of new clusters is laid out from the same defaults.
+	// +optional
+	Network NetworkLayout `json:"network,omitempty"`
+
+	// Machines are the machines of the cluster.
+	// +optional
+	Machines []MachineTemplate `json:"machines,omitempty"`
+}
+
+// ClusterTemplate is the configuration of the clusters created from a template.
+type ClusterTemplate struct {
+	// PodsCIDRBlocks are the CIDR blocks of the pods of the cluster.
+	// +optional
+	PodsCIDRBlocks []string `json:"podsCidrBlocks,omitempty"`
+
+	// ServicesCIDRBlocks are the CIDR blocks of the services of the cluster.
+	// +optional
+	ServicesCIDRBlocks []string `json:"servicesCidrBlocks,omitempty"`
+
+	// ServiceDomain is the domain of the services of the cluster.
+	// +optional
+	ServiceDomain string `json:"serviceDomain,omitempty"`
+
+	// ProviderSpec is the provider spec of the cluster, without its CA.
+	ProviderSpec AWSClusterProviderSpec `json:"providerSpec"`
+}
+
+// NetworkLayout describes the address ranges of a cluster network.
+type NetworkLayout struct {
+	// VPCCidrBlock is the CIDR block of the VPC.
+	// +optional
+	VPCCidrBlock string `json:"vpcCidrBlock,omitempty"`
+
+	// Subnets are the subnets of the VPC.
+	// +optional
+	Subnets []SubnetLayout `json:"subnets,omitempty"`
+}
+
+// SubnetLayout describes the address range of a subnet.
+type SubnetLayout struct {
+	// AvailabilityZone is the availability zone of the subnet.
+	AvailabilityZone string `json:"availabilityZone"`
+
+	// CidrBlock is the CIDR block of the subnet.
+	CidrBlock string `json:"cidrBlock"`
+
+	// IsPublic is true for the public subnets.
+	// +optional
+	IsPublic bool `json:"public,omitempty"`
+}
+
+// MachineTemplate is the configuration of a machine created from a template.
+type MachineTemplate struct {
+	// Name of the machine, without the cluster name prefix if the captured
+	// machine had one. Machines created from the template are named after
+	// the new cluster.
+	Name string `json:"name"`
+
+	// Labels of the machine.
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// KubeletVersion is the version of the kubelet of the machine.
+	KubeletVersion string `json:"kubeletVersion"`
+
+	// ControlPlaneVersion is the version of the control plane of the machine,
+	// for control plane machines.
+	// +optional
+	ControlPlaneVersion string `json:"controlPlaneVersion,omitempty"`
+
+	// ProviderSpec is the provider spec of the machine, with its AMI and
+	// instance type resolved.
+	ProviderSpec AWSMachineProviderSpec `json:"providerSpec"`
+}
+
+func init() {
+	SchemeBuilder.Register(&AWSClusterTemplate{})
+}
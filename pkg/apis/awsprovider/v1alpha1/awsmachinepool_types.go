@@ -0,0 +1,149 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// MachinePoolFinalizer allows the machine pool controller to delete the Auto
+// Scaling group and launch template of a pool before the pool is removed.
+const MachinePoolFinalizer = "awsmachinepool.awsprovider.k8s.io"
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// AWSMachinePool is a pool of worker nodes of a cluster backed by an Auto
+// Scaling group, rather than by a Machine per instance. The instances of the
+// group are launched from a launch template carrying the same bootstrap user
+// data as the node machines of the cluster, and join the cluster as nodes.
+// +k8s:openapi-gen=true
+// +kubebuilder:subresource:status
+// +kubebuilder:subresource:scale:specpath=.spec.replicas,statuspath=.status.replicas
+type AWSMachinePool struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   AWSMachinePoolSpec   `json:"spec,omitempty"`
+	Status AWSMachinePoolStatus `json:"status,omitempty"`
+}
+
+// AWSMachinePoolSpec is the configuration of a machine pool.
+type AWSMachinePoolSpec struct {
+	// ClusterName is the name of the cluster the nodes of the pool join, in
+	// the namespace of the pool.
+	ClusterName string `json:"clusterName"`
+
+	// Replicas is the number of instances of the pool. Defaults to 1.
+	// +optional
+	Replicas *int32 `json:"replicas,omitempty"`
+
+	// KubernetesVersion is the version of the kubelet of the nodes, used to
+	// look up their AMI unless AMI is set.
+	KubernetesVersion string `json:"kubernetesVersion"`
+
+	// InstanceType is the type of the instances, e.g. m5.large.
+	InstanceType string `json:"instanceType"`
+
+	// AMI is the reference to the AMI of the instances. Defaults to the AMI
+	// published for this project for KubernetesVersion.
+	// +optional
+	AMI AWSResourceReference `json:"ami,omitempty"`
+
+	// IAMInstanceProfile is the name of the IAM instance profile of the
+	// instances.
+	// +optional
+	IAMInstanceProfile string `json:"iamInstanceProfile,omitempty"`
+
+	// KeyName is the name of the SSH key installed on the instances.
+	// Defaults to the SSH key of the machines of the cluster.
+	// +optional
+	KeyName string `json:"keyName,omitempty"`
+
+	// AdditionalSecurityGroupIDs are the IDs of security groups of the
+	// instances, in addition to the node security group of the cluster.
+	// +optional
+	AdditionalSecurityGroupIDs []string `json:"additionalSecurityGroupIDs,omitempty"`
+
+	// Subnets are the IDs of the subnets the group launches instances in.
+	// Defaults to the private subnets of the cluster.
+	// +optional
+	Subnets []string `json:"subnets,omitempty"`
+
+	// FIPSMode enables the FIPS mode of the kernel of the instances, which
+	// are rebooted into it before they are bootstrapped, and points the AWS
+	// SDKs and CLI of their node agents at the FIPS endpoints of the region.
+	// +optional
+	FIPSMode bool `json:"fipsMode,omitempty"`
+
+	// AdditionalTags are tags of the group, propagated to its instances, in
+	// addition to the tags of the nodes of the cluster.
+	// +optional
+	AdditionalTags map[string]string `json:"additionalTags,omitempty"`
+
+	// Labels are the labels of the nodes of the pool, set by the kubelet
+	// when the nodes register.
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// AWSMachinePoolStatus is the observed state of a machine pool.
+type AWSMachinePoolStatus struct {
+	// Replicas is the number of instances of the group in service.
+	// +optional
+	Replicas int32 `json:"replicas,omitempty"`
+
+	// AutoScalingGroupName is the name of the Auto Scaling group of the pool.
+	// +optional
+	AutoScalingGroupName string `json:"autoScalingGroupName,omitempty"`
+
+	// LaunchTemplateID is the ID of the launch template of the group.
+	// +optional
+	LaunchTemplateID string `json:"launchTemplateID,omitempty"`
+
+	// LaunchTemplateVersion is the version of the launch template the group
+	// launches new instances from.
+	// +optional
+	LaunchTemplateVersion int64 `json:"launchTemplateVersion,omitempty"`
+
+	// ObservedGeneration is the generation of the spec of the pool the
+	// launch template version was created from.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// BootstrapTokenExpiration is the time the bootstrap token of the user
+	// data of the launch template version expires. A new version is created
+	// with a new token before then.
+	// +optional
+	BootstrapTokenExpiration *metav1.Time `json:"bootstrapTokenExpiration,omitempty"`
+
+	// ErrorMessage describes the last failure to reconcile the pool, if any.
+	// +optional
+	ErrorMessage *string `json:"errorMessage,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// AWSMachinePoolList is a list of machine pools.
+type AWSMachinePoolList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []AWSMachinePool `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&AWSMachinePool{}, &AWSMachinePoolList{})
+}
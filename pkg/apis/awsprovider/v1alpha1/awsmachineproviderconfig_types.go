@@ -36,9 +36,22 @@ type AWSMachineProviderSpec struct {
 	// AMI is the reference to the AMI from which to create the machine instance.
 	AMI AWSResourceReference `json:"ami,omitempty"`
 
+	// ImageMaxAge is the age after which the AMI backing the instance is
+	// considered outdated. When exceeded, the machine is flagged with the
+	// ImageOutdated condition and a warning event, as a nudge to roll the machine.
+	// +optional
+	ImageMaxAge *metav1.Duration `json:"imageMaxAge,omitempty"`
+
 	// InstanceType is the type of instance to create. Example: m4.xlarge
 	InstanceType string `json:"instanceType,omitempty"`
 
+	// LaunchTemplate is a reference to an existing EC2 launch template to launch
+	// the instance from. The AMI, instance type and SSH key are taken from the
+	// template unless set in this spec. The subnet, security groups, user data,
+	// IAM instance profile and tags are always set by the actuator.
+	// +optional
+	LaunchTemplate *LaunchTemplateReference `json:"launchTemplate,omitempty"`
+
 	// AdditionalTags is the set of tags to add to an instance, in addition to the ones
 	// added by default by the actuator. These tags are additive. The actuator will ensure
 	// these tags are present, but will not remove any other tags that may exist on the
@@ -53,8 +66,9 @@ type AWSMachineProviderSpec struct {
 	// PublicIP specifies whether the instance should get a public IP.
 	// Precedence for this setting is as follows:
 	// 1. This field if set
-	// 2. Cluster/flavor setting
-	// 3. Subnet default
+	// 2. Subnet default
+	// Machines requesting a public IP are launched in a public subnet unless
+	// one is set. It cannot be set along with additional network interfaces.
 	// +optional
 	PublicIP *bool `json:"publicIP,omitempty"`
 
@@ -69,9 +83,276 @@ type AWSMachineProviderSpec struct {
 	// +optional
 	Subnet *AWSResourceReference `json:"subnet,omitempty"`
 
+	// RootVolume configures the size, type and encryption of the root volume
+	// of the instance. If not specified, the settings of the AMI are used.
+	// Changes to the size, type, IOPS and throughput are applied in place to
+	// running instances.
+	// +optional
+	RootVolume *RootVolume `json:"rootVolume,omitempty"`
+
+	// AdditionalVolumes is a list of EBS volumes created and attached to the
+	// instance at launch, e.g. a dedicated data disk. They are tagged like
+	// the instance. Changes to their size, type, IOPS and throughput are
+	// applied in place to running instances.
+	// +optional
+	AdditionalVolumes []Volume `json:"additionalVolumes,omitempty"`
+
+	// VolumeDeletionPolicy configures, per class of volume, whether the EBS
+	// volumes attached at launch are deleted when the instance is terminated.
+	// If not specified, the settings of the AMI are used.
+	// +optional
+	VolumeDeletionPolicy *VolumeDeletionPolicy `json:"volumeDeletionPolicy,omitempty"`
+
+	// KubeletDNS configures name resolution for the kubelet and its pods.
+	// +optional
+	KubeletDNS *KubeletDNS `json:"kubeletDNS,omitempty"`
+
+	// HardeningProfile is the host hardening profile applied to the instance
+	// before it is bootstrapped, for images that do not meet the required
+	// benchmarks themselves. The only supported profile is "baseline".
+	// +optional
+	HardeningProfile HardeningProfile `json:"hardeningProfile,omitempty"`
+
+	// FIPSMode enables the FIPS mode of the kernel of the instance, which is
+	// rebooted into it before it is bootstrapped, and points the AWS SDKs and
+	// CLI of its node agents at the FIPS endpoints of the region. The image
+	// must ship the FIPS tooling of its distribution.
+	// +optional
+	FIPSMode bool `json:"fipsMode,omitempty"`
+
 	// KeyName is the name of the SSH key to install on the instance.
 	// +optional
 	KeyName string `json:"keyName,omitempty"`
+
+	// ScrubUserData specifies whether the instance user data, which carries
+	// bootstrap tokens and certificates, should be overwritten once the
+	// instance has joined the cluster. EC2 only allows user data to be
+	// modified on a stopped instance, so the node is drained and the instance
+	// is stopped and started again as part of this operation. It is therefore
+	// not supported on control plane machines, nor on spot instances.
+	// +optional
+	ScrubUserData bool `json:"scrubUserData,omitempty"`
+
+	// ReplaceOnRetirement specifies whether a machine managed by a MachineSet
+	// should be deleted as soon as AWS schedules the retirement of its instance,
+	// so that a replacement is created ahead of the retirement date.
+	// +optional
+	ReplaceOnRetirement bool `json:"replaceOnRetirement,omitempty"`
+
+	// ConnectivityPreflight specifies whether the instance should verify that
+	// the API server and the regional AWS endpoints are reachable before
+	// bootstrapping. Failures are reported on the machine with the
+	// BootstrapBlocked condition.
+	// +optional
+	ConnectivityPreflight bool `json:"connectivityPreflight,omitempty"`
+
+	// BootstrapTimeout is the time allowed for the machine to join the cluster
+	// after its instance was launched. When exceeded, the instance is
+	// terminated and the machine is marked as failed, instead of leaving a
+	// half-bootstrapped instance running.
+	// +optional
+	BootstrapTimeout *metav1.Duration `json:"bootstrapTimeout,omitempty"`
+
+	// ReplaceOnBootstrapTimeout specifies whether a machine managed by a
+	// MachineSet should be deleted once its BootstrapTimeout is exceeded,
+	// so that a replacement is created. With a quarantine policy, the machine
+	// is deleted once the quarantine ends.
+	// +optional
+	ReplaceOnBootstrapTimeout bool `json:"replaceOnBootstrapTimeout,omitempty"`
+
+	// DrainTimeout is how long the termination of the instance of a deleted
+	// machine waits for its node to be drained, after which the instance is
+	// terminated anyway. Defaults to 5 minutes.
+	// +optional
+	DrainTimeout *metav1.Duration `json:"drainTimeout,omitempty"`
+
+	// Quarantine, if set, keeps the instance of a failed machine for debugging
+	// instead of terminating it right away.
+	// +optional
+	Quarantine *QuarantinePolicy `json:"quarantine,omitempty"`
+
+	// EtcdVolume, if set, dedicates a volume to the etcd data of a control
+	// plane machine. The volume is formatted and mounted at /var/lib/etcd
+	// before bootstrapping.
+	// +optional
+	EtcdVolume *EtcdVolume `json:"etcdVolume,omitempty"`
+
+	// PlacementGroup, if set, launches the instance in a placement group,
+	// either an existing one or one created for the cluster.
+	// +optional
+	PlacementGroup *PlacementGroup `json:"placementGroup,omitempty"`
+
+	// Tenancy is the tenancy of the instance: default to run on shared
+	// hardware, dedicated to run on hardware dedicated to the account, or
+	// host to run on a Dedicated Host.
+	// +optional
+	Tenancy Tenancy `json:"tenancy,omitempty"`
+
+	// HostID is the ID of the Dedicated Host the instance runs on. It requires
+	// the host tenancy. Without it, the instance runs on any Dedicated Host of
+	// the account with auto-placement enabled.
+	// +optional
+	HostID string `json:"hostId,omitempty"`
+
+	// CapacityReservationID is the ID of the On-Demand Capacity Reservation
+	// the instance is launched in. It cannot be combined with
+	// CapacityReservationPreference.
+	// +optional
+	CapacityReservationID string `json:"capacityReservationId,omitempty"`
+
+	// CapacityReservationPreference is open to let the instance run in any
+	// open Capacity Reservation with matching attributes, or none to never
+	// run it in a Capacity Reservation. Defaults to open.
+	// +optional
+	CapacityReservationPreference CapacityReservationPreference `json:"capacityReservationPreference,omitempty"`
+
+	// CPUOptions restricts the number of CPU cores and threads per core of the
+	// instance, e.g. to disable hyperthreading or to reduce the cores licensed
+	// software is billed for. If not specified, the defaults of the instance type apply.
+	// +optional
+	CPUOptions *CPUOptions `json:"cpuOptions,omitempty"`
+
+	// CreditSpecification is the CPU credit option of burstable instance
+	// types: standard or unlimited. Changes are applied in place to running
+	// instances. If not specified, the default of the instance type applies.
+	// +optional
+	CreditSpecification CPUCredits `json:"creditSpecification,omitempty"`
+
+	// EnableDetailedMonitoring enables the detailed CloudWatch monitoring of
+	// the instance, with metrics collected every minute rather than every five
+	// minutes. Changes are applied in place to running instances.
+	// +optional
+	EnableDetailedMonitoring bool `json:"enableDetailedMonitoring,omitempty"`
+
+	// InstanceMetadataOptions are the instance metadata service options of
+	// the instance, e.g. to require IMDSv2. The AWS cloud provider of the
+	// Kubernetes version of the machine must support IMDSv2 to require it.
+	// They only apply when the instance is launched.
+	// +optional
+	InstanceMetadataOptions *InstanceMetadataOptions `json:"instanceMetadataOptions,omitempty"`
+
+	// SerialConsole enables logging in on the EC2 serial console of the
+	// instance, to debug instances whose network is broken. The serial
+	// console access must also be enabled for the account, see
+	// "clusterawsadm alpha ec2 serial-console". It only applies when the
+	// instance is launched, and requires an instance type built on the
+	// Nitro System.
+	// +optional
+	SerialConsole *SerialConsole `json:"serialConsole,omitempty"`
+
+	// NetworkInterfaceType is the type of the primary network interface of
+	// the instance: interface for a standard Elastic Network Interface, or
+	// efa for an Elastic Fabric Adapter. EFA requires a supported instance
+	// type and a cluster enabling EFA, and is only available to nodes.
+	// Defaults to interface.
+	// +optional
+	NetworkInterfaceType NetworkInterfaceType `json:"networkInterfaceType,omitempty"`
+
+	// TagAnnotations lists the keys of the instance tags reflected as
+	// annotations of the machine, so that tags added by automation on the
+	// AWS side are visible to Kubernetes tooling. A key ending with "*"
+	// selects all the tags beginning with the rest of the key. The
+	// annotation of a tag is its key prefixed with
+	// "tags.cluster-api-provider-aws.sigs.k8s.io/", and tags whose key is
+	// not a valid annotation name are not reflected.
+	// +optional
+	TagAnnotations []string `json:"tagAnnotations,omitempty"`
+
+	// ImageLookup selects the AMI of the machine by filters instead of by ID.
+	// The newest AMI matching the filters is resolved when the instance is
+	// created, so the same spec can be used in every region. Mutually
+	// exclusive with AMI.
+	// +optional
+	ImageLookup *ImageLookup `json:"imageLookup,omitempty"`
+
+	// ImageSSMParameter is the name of the SSM parameter holding the ID of
+	// the AMI of the machine, such as the public parameters of the Amazon
+	// Linux 2 and EKS optimized AMIs, e.g.
+	// /aws/service/eks/optimized-ami/1.13/amazon-linux-2/recommended/image_id.
+	// The parameter is read when the instance is created, so new machines
+	// pick up the AMIs patched since. Mutually exclusive with AMI and
+	// ImageLookup.
+	// +optional
+	ImageSSMParameter string `json:"imageSSMParameter,omitempty"`
+
+	// RegionAMIs maps regions to the ID of the AMI of the machine in the
+	// region, so that the same manifest can be applied in several regions.
+	// The region of the cluster must be listed. Mutually exclusive with AMI,
+	// ImageLookup and ImageSSMParameter.
+	// +optional
+	RegionAMIs map[string]string `json:"regionAMIs,omitempty"`
+
+	// GPU configures the NVIDIA GPUs of GPU instance types, such as p3 or
+	// g4dn. Machines of these instance types are labelled with
+	// nvidia.com/gpu.present=true whether or not this is set.
+	// +optional
+	GPU *GPUConfig `json:"gpu,omitempty"`
+
+	// ElasticIP, if set, associates an Elastic IP allocated for the machine
+	// with its instance, giving it a stable public address. The address is
+	// released when the machine is deleted. Only control plane machines
+	// support it, and they are launched in a public subnet unless one is set.
+	// +optional
+	ElasticIP bool `json:"elasticIP,omitempty"`
+
+	// SecondaryPrivateIPCount is the number of secondary private IP
+	// addresses assigned to the primary network interface of the instance
+	// at launch.
+	// +optional
+	SecondaryPrivateIPCount int64 `json:"secondaryPrivateIPCount,omitempty"`
+
+	// AdditionalNetworkInterfaces are the network interfaces created and
+	// attached to the instance at launch, in order from device index 1, for
+	// CNIs and appliances consuming extra interfaces. They are tagged like
+	// the instance and deleted when it is terminated. Instances with several
+	// network interfaces are not assigned a public IP address, and cannot use
+	// an Elastic IP.
+	// +optional
+	AdditionalNetworkInterfaces []NetworkInterface `json:"additionalNetworkInterfaces,omitempty"`
+
+	// ENAExpress enables ENA Express on all the network interfaces of the
+	// instance at launch, for latency-sensitive workloads exchanging
+	// traffic within an availability zone. It requires an instance type
+	// supporting ENA Express, and only applies between instances which
+	// both enable it.
+	// +optional
+	ENAExpress *ENAExpress `json:"enaExpress,omitempty"`
+
+	// PreKubeadmCommands are shell commands run by the user data of the
+	// instance before kubeadm bootstraps it, such as to mount disks, join a
+	// directory or install agents. They run as root, after the preflight
+	// checks and the installation of the GPU drivers, and a failing command
+	// aborts the bootstrap.
+	// +optional
+	PreKubeadmCommands []string `json:"preKubeadmCommands,omitempty"`
+
+	// PostKubeadmCommands are shell commands run by the user data of the
+	// instance once kubeadm bootstrapped it. A failing command aborts the
+	// remaining commands.
+	// +optional
+	PostKubeadmCommands []string `json:"postKubeadmCommands,omitempty"`
+
+	// Files are written on the instance by its user data before kubeadm
+	// bootstraps it, such as registry credentials, audit policies or sysctl
+	// files. Their content is given inline, or read from a Secret or
+	// ConfigMap in the namespace of the machine when the instance is
+	// launched. Files holding secrets should be combined with ScrubUserData.
+	// +optional
+	Files []File `json:"files,omitempty"`
+
+	// DeletionPolicy is what happens to the instance when the machine is
+	// deleted: terminate to terminate it, or stop to park it, in which case
+	// it is started again by the next machine of the same name. Defaults to
+	// terminate. Control plane machines and spot instances cannot be stopped.
+	// +optional
+	DeletionPolicy InstanceDeletionPolicy `json:"deletionPolicy,omitempty"`
+
+	// KubeletReserved reserves resources of the instance for the system and
+	// Kubernetes daemons, so that pods cannot starve them. With the
+	// KubeletReservedDefaults feature gate, reservations not set are
+	// computed from the CPU and memory of the instance type.
+	// +optional
+	KubeletReserved *KubeletReserved `json:"kubeletReserved,omitempty"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -37,10 +37,34 @@ type AWSMachineProviderStatus struct {
 	// +optional
 	InstanceState *string `json:"instanceState,omitempty"`
 
+	// UserDataScrubbed is true once the instance user data has been overwritten
+	// after the machine joined the cluster.
+	// +optional
+	UserDataScrubbed bool `json:"userDataScrubbed,omitempty"`
+
+	// ElasticIPAllocationID is the allocation ID of the Elastic IP allocated
+	// for the machine, if any.
+	// +optional
+	ElasticIPAllocationID string `json:"elasticIPAllocationID,omitempty"`
+
+	// QuarantinedAt is the time the instance of the failed machine was quarantined.
+	// +optional
+	QuarantinedAt *metav1.Time `json:"quarantinedAt,omitempty"`
+
 	// Conditions is a set of conditions associated with the Machine to indicate
 	// errors or other status
 	// +optional
 	Conditions []AWSMachineProviderCondition `json:"conditions,omitempty"`
+
+	// AccountID is the ID of the AWS account the instance was created in.
+	// The controller refuses to reconcile the machine with the credentials
+	// of another account.
+	// +optional
+	AccountID string `json:"accountID,omitempty"`
+
+	// Region is the AWS region the instance was created in.
+	// +optional
+	Region string `json:"region,omitempty"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
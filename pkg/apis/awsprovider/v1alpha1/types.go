@@ -54,6 +54,340 @@ type Filter struct {
 	Values []string `json:"values"`
 }
 
+// ExternalNetwork references the network resources, managed outside of the
+// provider, that a cluster runs in.
+type ExternalNetwork struct {
+	// VPCID is the id of the VPC.
+	VPCID string `json:"vpcId"`
+
+	// PublicSubnetIDs are the ids of the subnets the api server load balancer
+	// and the bastion host are placed in.
+	// +optional
+	PublicSubnetIDs []string `json:"publicSubnetIds,omitempty"`
+
+	// PrivateSubnetIDs are the ids of the subnets machines are placed in.
+	PrivateSubnetIDs []string `json:"privateSubnetIds"`
+
+	// ControlPlaneSecurityGroupID is the id of the security group of control plane machines
+	// and of the api server load balancer.
+	ControlPlaneSecurityGroupID string `json:"controlPlaneSecurityGroupId"`
+
+	// NodeSecurityGroupID is the id of the security group of node machines.
+	NodeSecurityGroupID string `json:"nodeSecurityGroupId"`
+
+	// BastionSecurityGroupID is the id of the security group of the bastion host.
+	// If not specified, no bastion host is created.
+	// +optional
+	BastionSecurityGroupID string `json:"bastionSecurityGroupId,omitempty"`
+}
+
+// SecurityGroupIDs returns the ids of the external security groups by role.
+func (n *ExternalNetwork) SecurityGroupIDs() map[SecurityGroupRole]string {
+	ids := map[SecurityGroupRole]string{
+		SecurityGroupControlPlane: n.ControlPlaneSecurityGroupID,
+		SecurityGroupNode:         n.NodeSecurityGroupID,
+	}
+
+	if n.BastionSecurityGroupID != "" {
+		ids[SecurityGroupBastion] = n.BastionSecurityGroupID
+	}
+
+	return ids
+}
+
+// ResourceNaming configures the naming scheme of the AWS resources created for a cluster.
+// Resources are named <prefix><cluster name>-<resource kind><suffix>.
+type ResourceNaming struct {
+	// Prefix is prepended to the names of the cluster resources.
+	// +optional
+	Prefix string `json:"prefix,omitempty"`
+
+	// Suffix is appended to the names of the cluster resources.
+	// +optional
+	Suffix string `json:"suffix,omitempty"`
+
+	// MaxLength is the maximum length of the names of the cluster resources.
+	// Longer names are truncated and made unique with a hash of the full name.
+	// Resource specific limits, like the 32 characters of load balancer names,
+	// always apply.
+	// +optional
+	MaxLength int `json:"maxLength,omitempty"`
+}
+
+// VPCPeering describes a VPC to peer the cluster VPC with.
+// The peer VPC must be in the same account and region as the cluster.
+type VPCPeering struct {
+	// VPCID is the id of the peer VPC.
+	VPCID string `json:"vpcId"`
+
+	// CidrBlock is the CIDR block of the peer VPC, which is routed from the
+	// cluster subnets through the peering connection.
+	CidrBlock string `json:"cidrBlock"`
+
+	// RouteTableIDs are the route tables of the peer VPC in which a route to
+	// the cluster VPC through the peering connection is added.
+	// +optional
+	RouteTableIDs []string `json:"routeTableIds,omitempty"`
+}
+
+// ImageLookup defines the filters selecting the AMI of a machine. Unset
+// fields default to the AMIs published for this project.
+type ImageLookup struct {
+	// Owner is the ID of the AWS account owning the AMI, or one of the
+	// aliases "self", "amazon" and "aws-marketplace".
+	// +optional
+	Owner string `json:"owner,omitempty"`
+
+	// Name is the name pattern of the AMI, where "*" and "?" are wildcards.
+	// When set, BaseOS, BaseOSVersion and KubernetesVersion are ignored.
+	// +optional
+	Name string `json:"name,omitempty"`
+
+	// Architecture is the architecture of the AMI. Defaults to arm64 for the
+	// instance types built on AWS Graviton processors, and to x86_64 otherwise.
+	// +optional
+	Architecture string `json:"architecture,omitempty"`
+
+	// BaseOS is the operating system of the AMI. Defaults to ubuntu.
+	// +optional
+	BaseOS string `json:"baseOS,omitempty"`
+
+	// BaseOSVersion is the version of the operating system of the AMI.
+	// Defaults to 18.04.
+	// +optional
+	BaseOSVersion string `json:"baseOSVersion,omitempty"`
+
+	// KubernetesVersion is the version of Kubernetes installed in the AMI.
+	// Defaults to the kubelet version of the machine.
+	// +optional
+	KubernetesVersion string `json:"kubernetesVersion,omitempty"`
+
+	// ProductCode is the AWS Marketplace product code of the AMI. When set,
+	// Owner defaults to aws-marketplace and the AMI is only filtered by
+	// Name when it is set.
+	// +optional
+	ProductCode string `json:"productCode,omitempty"`
+}
+
+// ImageEncryption defines how machine AMIs are copied and encrypted before use.
+type ImageEncryption struct {
+	// KMSKeyID is the ID or ARN of the KMS key used to encrypt the snapshots
+	// of the copied AMI. If not specified, the default EBS key of the account is used.
+	// +optional
+	KMSKeyID string `json:"kmsKeyId,omitempty"`
+}
+
+// ImageBuild defines the SSM Automation runbook building the AMIs of a
+// Kubernetes version, which can run an image pipeline of Packer or EC2
+// Image Builder.
+//
+// The runbook is passed the KubernetesVersion, BaseOS, BaseOSVersion and
+// Architecture parameters of the AMI, along with Parameters. Builds of the
+// same AMI are shared by the clusters of the account and region.
+type ImageBuild struct {
+	// DocumentName is the name or ARN of the Automation runbook.
+	DocumentName string `json:"documentName"`
+
+	// ImageIDOutput is the output of the runbook holding the ID of the
+	// built AMI, such as "createImage.ImageId".
+	ImageIDOutput string `json:"imageIDOutput"`
+
+	// Parameters are additional parameters of the runbook, such as the
+	// role it assumes.
+	// +optional
+	Parameters map[string]string `json:"parameters,omitempty"`
+}
+
+// MachinePool is an Auto Scaling group whose instances join a cluster as
+// nodes. The group, its launch template and its scaling policies are managed
+// outside of the provider, which only coordinates the nodes of the group with
+// the cluster.
+type MachinePool struct {
+	// AutoScalingGroupName is the name of the Auto Scaling group.
+	AutoScalingGroupName string `json:"autoScalingGroupName"`
+
+	// Schedules resize the group on recurring schedules, as scheduled
+	// actions of the group. Scheduled actions created outside of the
+	// provider are left untouched.
+	// +optional
+	Schedules []ScalingSchedule `json:"schedules,omitempty"`
+}
+
+// ScalingSchedule resizes a machine pool on a recurring schedule. At least
+// one of MinSize, MaxSize and DesiredCapacity must be set; the sizes left
+// unset are not changed.
+type ScalingSchedule struct {
+	// Name is the name of the schedule, unique within the pool.
+	Name string `json:"name"`
+
+	// Recurrence is the cron expression of the schedule, in the
+	// "minute hour day-of-month month day-of-week" format, e.g.
+	// "0 8 * * 1-5" for 8am on weekdays.
+	Recurrence string `json:"recurrence"`
+
+	// TimeZone is the IANA time zone of the recurrence, e.g.
+	// "Europe/Paris". Defaults to UTC.
+	// +optional
+	TimeZone string `json:"timeZone,omitempty"`
+
+	// MinSize is the minimum number of instances of the group.
+	// +optional
+	MinSize *int64 `json:"minSize,omitempty"`
+
+	// MaxSize is the maximum number of instances of the group.
+	// +optional
+	MaxSize *int64 `json:"maxSize,omitempty"`
+
+	// DesiredCapacity is the number of instances the group maintains.
+	// +optional
+	DesiredCapacity *int64 `json:"desiredCapacity,omitempty"`
+}
+
+// DefaultTerminationHandlerImage is the image of the node termination
+// handler deployed into clusters whose termination handler sets none.
+const DefaultTerminationHandlerImage = "public.ecr.aws/aws-ec2/aws-node-termination-handler:v1.22.0"
+
+// TerminationHandler configures the AWS node termination handler deployed
+// into a cluster, which watches the instance metadata of the nodes for spot
+// interruption notices and scheduled events, and cordons and drains the nodes
+// before their instances are interrupted.
+type TerminationHandler struct {
+	// Image is the image of the handler. Defaults to
+	// DefaultTerminationHandlerImage.
+	// +optional
+	Image string `json:"image,omitempty"`
+
+	// NodeSelector restricts the handler to the nodes with these labels,
+	// such as the labels the launch templates of the spot machine pools
+	// give to their nodes. Defaults to every Linux node.
+	// +optional
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+}
+
+// SecretBackend configures an external store for the private key of the
+// cluster CA, from which the kubeconfigs of the cluster are derived.
+// Exactly one of Vault or SecretsManager must be set.
+type SecretBackend struct {
+	// Vault stores the key in the KV version 2 secrets engine of a HashiCorp
+	// Vault server. The controller authenticates with the token of its
+	// VAULT_TOKEN environment variable.
+	// +optional
+	Vault *VaultSecretBackend `json:"vault,omitempty"`
+
+	// SecretsManager stores the key in AWS Secrets Manager, in the cluster region.
+	// +optional
+	SecretsManager *SecretsManagerSecretBackend `json:"secretsManager,omitempty"`
+}
+
+// VaultSecretBackend describes where secrets are stored in HashiCorp Vault.
+type VaultSecretBackend struct {
+	// Address is the URL of the Vault server, e.g. https://vault.example.com:8200.
+	Address string `json:"address"`
+
+	// MountPath is the path the KV version 2 secrets engine is mounted at.
+	// Defaults to "secret".
+	// +optional
+	MountPath string `json:"mountPath,omitempty"`
+
+	// PathPrefix is prepended to the paths of the secrets, which are stored
+	// under <prefix>/<cluster namespace>/<cluster name>/.
+	// +optional
+	PathPrefix string `json:"pathPrefix,omitempty"`
+}
+
+// SecretsManagerSecretBackend describes how secrets are stored in AWS Secrets Manager.
+type SecretsManagerSecretBackend struct {
+	// Prefix is prepended to the names of the secrets, which are named
+	// <prefix><cluster namespace>/<cluster name>/<secret>.
+	// +optional
+	Prefix string `json:"prefix,omitempty"`
+
+	// KMSKeyID is the ID or ARN of the KMS key used to encrypt the secrets.
+	// If not specified, the default Secrets Manager key of the account is used.
+	// +optional
+	KMSKeyID string `json:"kmsKeyId,omitempty"`
+}
+
+// IPAM configures how the CIDR blocks of the network of a cluster are
+// allocated. Exactly one of Pool or Webhook must be set.
+type IPAM struct {
+	// Pool allocates the CIDR blocks from an address pool.
+	// +optional
+	Pool *IPAMPool `json:"pool,omitempty"`
+
+	// Webhook allocates the CIDR blocks with an external IPAM service.
+	// +optional
+	Webhook *IPAMWebhook `json:"webhook,omitempty"`
+}
+
+// IPAMPool allocates VPC CIDR blocks from an address pool, skipping the
+// blocks overlapping the VPCs which already exist in the region, and carves
+// the subnets out of the VPC CIDR block.
+type IPAMPool struct {
+	// CidrBlock is the IPv4 address pool, e.g. 10.0.0.0/8.
+	CidrBlock string `json:"cidrBlock"`
+
+	// VPCPrefixLength is the prefix length of the VPC CIDR blocks.
+	// Defaults to 16.
+	// +optional
+	VPCPrefixLength int `json:"vpcPrefixLength,omitempty"`
+
+	// SubnetPrefixLength is the prefix length of the subnet CIDR blocks.
+	// Defaults to 24.
+	// +optional
+	SubnetPrefixLength int `json:"subnetPrefixLength,omitempty"`
+
+	// PodCidrBlock is the IPv4 address pool the pod CIDR blocks of clusters
+	// are allocated from, skipping the blocks overlapping the VPCs and the pod
+	// CIDR blocks of the other clusters of the region. Pod CIDR blocks are
+	// not allocated if empty.
+	// +optional
+	PodCidrBlock string `json:"podCidrBlock,omitempty"`
+
+	// PodPrefixLength is the prefix length of the pod CIDR blocks.
+	// Defaults to 16.
+	// +optional
+	PodPrefixLength int `json:"podPrefixLength,omitempty"`
+}
+
+// IPAMWebhook allocates CIDR blocks by POSTing JSON requests to an external
+// IPAM service, which responds with the allocated blocks. Allocations are
+// requested again for a cluster until the blocks are recorded in the cluster,
+// so the service should return the blocks already allocated to the cluster.
+type IPAMWebhook struct {
+	// URL is the endpoint of the IPAM service. It must be an HTTPS URL on a
+	// public address.
+	URL string `json:"url"`
+
+	// Timeout bounds the duration of the requests. Defaults to 30 seconds.
+	// +optional
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+}
+
+// ClusterPhase is a coarse summary of the provisioning progress of a cluster.
+type ClusterPhase string
+
+// Valid phases for a cluster
+const (
+	// ClusterPhaseNetworkProvisioning means the VPC, subnets, gateways,
+	// security groups or bastion of the cluster are being provisioned.
+	ClusterPhaseNetworkProvisioning ClusterPhase = "NetworkProvisioning"
+
+	// ClusterPhaseControlPlaneProvisioning means the network is ready, and the
+	// load balancers or the control plane machines are being provisioned.
+	ClusterPhaseControlPlaneProvisioning ClusterPhase = "ControlPlaneProvisioning"
+
+	// ClusterPhaseControlPlaneReady means a control plane machine has joined the cluster.
+	ClusterPhaseControlPlaneReady ClusterPhase = "ControlPlaneReady"
+
+	// ClusterPhaseDeleting means the cluster infrastructure is being deleted.
+	ClusterPhaseDeleting ClusterPhase = "Deleting"
+
+	// ClusterPhaseFailed means the provisioning failed with an error that
+	// requires an intervention, such as missing permissions or an exhausted quota.
+	ClusterPhaseFailed ClusterPhase = "Failed"
+)
+
 // AWSMachineProviderConditionType is a valid value for AWSMachineProviderCondition.Type
 type AWSMachineProviderConditionType string
 
@@ -62,6 +396,38 @@ const (
 	// MachineCreated indicates whether the machine has been created or not. If not,
 	// it should include a reason and message for the failure.
 	MachineCreated AWSMachineProviderConditionType = "MachineCreated"
+
+	// ImageOutdated indicates whether the AMI backing the machine instance is
+	// older than the maximum age configured for the machine.
+	ImageOutdated AWSMachineProviderConditionType = "ImageOutdated"
+
+	// InstanceEventScheduled indicates whether AWS has scheduled a maintenance
+	// event, such as a reboot or a retirement, for the machine instance.
+	InstanceEventScheduled AWSMachineProviderConditionType = "InstanceEventScheduled"
+
+	// BootstrapBlocked indicates whether the machine instance failed to bootstrap
+	// because a required endpoint was unreachable from the instance.
+	BootstrapBlocked AWSMachineProviderConditionType = "BootstrapBlocked"
+
+	// CapacityReservationExhausted indicates whether the machine instance
+	// could not be launched because its Capacity Reservation has no available
+	// capacity left.
+	CapacityReservationExhausted AWSMachineProviderConditionType = "CapacityReservationExhausted"
+
+	// MarketplaceSubscriptionRequired indicates whether the machine instance
+	// could not be launched because its AWS Marketplace AMI requires a
+	// subscription the account has not accepted.
+	MarketplaceSubscriptionRequired AWSMachineProviderConditionType = "MarketplaceSubscriptionRequired"
+
+	// BurstableControlPlane indicates whether the control plane machine
+	// instance is of a burstable instance type without unlimited CPU credits,
+	// whose CPU is throttled once its credits are spent.
+	BurstableControlPlane AWSMachineProviderConditionType = "BurstableControlPlane"
+
+	// UnsupportedVersion indicates whether the Kubernetes versions of a
+	// machine with an image of its own are not known to be supported by the
+	// compatibility matrix.
+	UnsupportedVersion AWSMachineProviderConditionType = "UnsupportedVersion"
 )
 
 // AWSMachineProviderCondition is a condition in a AWSMachineProviderStatus
@@ -84,6 +450,42 @@ type AWSMachineProviderCondition struct {
 	Message string `json:"message"`
 }
 
+// AWSClusterProviderConditionType is a valid value for AWSClusterProviderCondition.Type
+type AWSClusterProviderConditionType string
+
+// Valid conditions for an AWS cluster
+const (
+	// ControlPlaneZoneConcentrated indicates whether a single availability
+	// zone hosts a quorum of the control plane instances of the cluster, so
+	// that an outage of the zone makes etcd lose its quorum.
+	ControlPlaneZoneConcentrated AWSClusterProviderConditionType = "ControlPlaneZoneConcentrated"
+
+	// SecurityGroupsInUse indicates whether the deletion of the cluster is
+	// blocked by resources outside the cluster, such as network interfaces or
+	// the security groups of other clusters, referencing its security groups.
+	SecurityGroupsInUse AWSClusterProviderConditionType = "SecurityGroupsInUse"
+)
+
+// AWSClusterProviderCondition is a condition in a AWSClusterProviderStatus
+type AWSClusterProviderCondition struct {
+	// Type is the type of the condition.
+	Type AWSClusterProviderConditionType `json:"type"`
+	// Status is the status of the condition.
+	Status corev1.ConditionStatus `json:"status"`
+	// LastProbeTime is the last time we probed the condition.
+	// +optional
+	LastProbeTime metav1.Time `json:"lastProbeTime"`
+	// LastTransitionTime is the last time the condition transitioned from one status to another.
+	// +optional
+	LastTransitionTime metav1.Time `json:"lastTransitionTime"`
+	// Reason is a unique, one-word, CamelCase reason for the condition's last transition.
+	// +optional
+	Reason string `json:"reason"`
+	// Message is a human-readable message indicating details about last transition.
+	// +optional
+	Message string `json:"message"`
+}
+
 // Network encapsulates AWS networking resources.
 type Network struct {
 	// VPC defines the cluster vpc.
@@ -100,6 +502,96 @@ type Network struct {
 
 	// APIServerELB is the Kubernetes api server classic load balancer.
 	APIServerELB ClassicELB `json:"apiServerElb,omitempty"`
+
+	// APIServerElasticIP is the Elastic IP the API server is reached at, when
+	// the cluster has no API server load balancer.
+	APIServerElasticIP *ElasticIP `json:"apiServerElasticIp,omitempty"`
+
+	// ManagementPeeringID is the id of the VPC peering connection with the
+	// management cluster VPC, if any.
+	ManagementPeeringID *string `json:"managementPeeringId,omitempty"`
+
+	// APIServerPrivateLink is the PrivateLink endpoint service of the API
+	// server, if any.
+	APIServerPrivateLink *PrivateLinkService `json:"apiServerPrivateLink,omitempty"`
+
+	// APIServerELBMigration is the migration of the API server to a new
+	// classic load balancer, after its scheme changed, if in progress.
+	APIServerELBMigration *APIServerELBMigration `json:"apiServerElbMigration,omitempty"`
+}
+
+// APIServerELBMigrationPhase is a phase of the migration of the API server to
+// a new load balancer.
+type APIServerELBMigrationPhase string
+
+const (
+	// APIServerELBMigrationCertificates adds the DNS name of the new load
+	// balancer to the serving certificates of the API servers, before the
+	// endpoint of the cluster switches to it.
+	APIServerELBMigrationCertificates APIServerELBMigrationPhase = "Certificates"
+
+	// APIServerELBMigrationKubeconfigs points the kubeconfigs of the
+	// instances and the kubeadm configuration of the cluster at the new load
+	// balancer, before the previous one is deleted.
+	APIServerELBMigrationKubeconfigs APIServerELBMigrationPhase = "Kubeconfigs"
+)
+
+// APIServerELBMigration describes the migration of the API server from a
+// classic load balancer to another.
+type APIServerELBMigration struct {
+	// Phase is the phase of the migration.
+	Phase APIServerELBMigrationPhase `json:"phase"`
+
+	// From is the load balancer the API server migrates from, deleted once
+	// the migration completes.
+	From ClassicELB `json:"from"`
+
+	// To is the load balancer the API server migrates to.
+	To ClassicELB `json:"to"`
+
+	// CommandID is the ID of the Run Command command of the phase, once sent.
+	// +optional
+	CommandID string `json:"commandId,omitempty"`
+}
+
+// PrivateLink configures a PrivateLink endpoint service.
+type PrivateLink struct {
+	// AllowedPrincipals are the ARNs of the principals allowed to create
+	// interface endpoints to the service, e.g. arn:aws:iam::123456789012:root
+	// for all the principals of an account.
+	// +optional
+	AllowedPrincipals []string `json:"allowedPrincipals,omitempty"`
+
+	// AcceptanceRequired specifies whether the connections of new endpoints
+	// wait for their acceptance by the owner of the cluster.
+	// +optional
+	AcceptanceRequired bool `json:"acceptanceRequired,omitempty"`
+}
+
+// PrivateLinkService defines a PrivateLink endpoint service backed by a
+// network load balancer.
+type PrivateLinkService struct {
+	// ServiceID is the ID of the endpoint service.
+	ServiceID string `json:"serviceId"`
+
+	// ServiceName is the name consumers create interface endpoints to the
+	// service with.
+	ServiceName string `json:"serviceName"`
+
+	// LoadBalancerARN is the ARN of the network load balancer of the service.
+	LoadBalancerARN string `json:"loadBalancerArn"`
+
+	// TargetGroupARN is the ARN of the target group of the load balancer.
+	TargetGroupARN string `json:"targetGroupArn"`
+}
+
+// ElasticIP defines an AWS Elastic IP address.
+type ElasticIP struct {
+	// AllocationID is the allocation ID of the address.
+	AllocationID string `json:"allocationId"`
+
+	// PublicIP is the public IP address.
+	PublicIP string `json:"publicIp"`
 }
 
 // VPC defines an AWS vpc.
@@ -256,6 +748,10 @@ var (
 
 	// SecurityGroupControlPlane defines a Kubernetes control plane node role
 	SecurityGroupControlPlane = SecurityGroupRole("controlplane")
+
+	// SecurityGroupAPIServerLB defines the role of the API server load
+	// balancer, which only has a security group of its own with restricted egress
+	SecurityGroupAPIServerLB = SecurityGroupRole("apiserver-lb")
 )
 
 // SecurityGroup defines an AWS security group.
@@ -269,6 +765,11 @@ type SecurityGroup struct {
 	// IngressRules is the inbound rules associated with the security group.
 	IngressRules IngressRules `json:"ingressRule"`
 
+	// EgressRules is the outbound rules associated with the security group,
+	// whose CIDR blocks and security groups are the destinations of the traffic.
+	// +optional
+	EgressRules IngressRules `json:"egressRules,omitempty"`
+
 	// Tags is a map of tags associated with the security group.
 	Tags map[string]string `json:"tags,omitempty"`
 }
@@ -305,6 +806,14 @@ type IngressRule struct {
 	// List of CIDR blocks to allow access from. Cannot be specified with SourceSecurityGroupID.
 	CidrBlocks []string `json:"cidrBlocks"`
 
+	// List of IPv6 CIDR blocks to allow access from.
+	// +optional
+	IPv6CidrBlocks []string `json:"ipv6CidrBlocks,omitempty"`
+
+	// The IDs of the prefix lists to allow access from.
+	// +optional
+	PrefixListIDs []string `json:"prefixListIds,omitempty"`
+
 	// The security group id to allow access from. Cannot be specified with CidrBlocks.
 	SourceSecurityGroupIDs []string `json:"sourceSecurityGroupIds"`
 }
@@ -326,6 +835,10 @@ func (i IngressRules) Difference(o IngressRules) (out IngressRules) {
 			sort.Strings(y.CidrBlocks)
 			sort.Strings(x.SourceSecurityGroupIDs)
 			sort.Strings(y.SourceSecurityGroupIDs)
+			sort.Strings(x.IPv6CidrBlocks)
+			sort.Strings(y.IPv6CidrBlocks)
+			sort.Strings(x.PrefixListIDs)
+			sort.Strings(y.PrefixListIDs)
 			if reflect.DeepEqual(x, y) {
 				found = true
 				break
@@ -372,6 +885,9 @@ type Instance struct {
 	// The current state of the instance.
 	State InstanceState `json:"instanceState,omitempty"`
 
+	// The time the instance was last launched or started.
+	LaunchTime *metav1.Time `json:"launchTime,omitempty"`
+
 	// The instance type.
 	Type string `json:"type,omitempty"`
 
@@ -397,15 +913,622 @@ type Instance struct {
 	// The private IPv4 address assigned to the instance.
 	PrivateIP *string `json:"privateIp,omitempty"`
 
+	// The private DNS name of the instance, which is the name of its node.
+	PrivateDNSName *string `json:"privateDnsName,omitempty"`
+
 	// The public IPv4 address assigned to the instance, if applicable.
 	PublicIP *string `json:"publicIp,omitempty"`
 
+	// The public DNS name of the instance, if applicable.
+	PublicDNSName *string `json:"publicDnsName,omitempty"`
+
 	// Specifies whether enhanced networking with ENA is enabled.
 	ENASupport *bool `json:"enaSupport,omitempty"`
 
 	// Indicates whether the instance is optimized for Amazon EBS I/O.
 	EBSOptimized *bool `json:"ebsOptimized,omitempty"`
 
+	// Spot is whether the instance is a spot instance.
+	Spot bool `json:"spot,omitempty"`
+
 	// The tags associated with the instance.
 	Tags map[string]string `json:"tags,omitempty"`
+
+	// VolumeDeletionPolicy configures whether the EBS volumes attached at launch
+	// are deleted on termination. It should only be used when running a new instance.
+	VolumeDeletionPolicy *VolumeDeletionPolicy `json:"volumeDeletionPolicy,omitempty"`
+
+	// LaunchTemplate is the launch template the instance is launched from.
+	// It should only be used when running a new instance.
+	LaunchTemplate *LaunchTemplateReference `json:"launchTemplate,omitempty"`
+
+	// RootVolume configures the root volume of the instance.
+	// It should only be used when running a new instance.
+	RootVolume *RootVolume `json:"rootVolume,omitempty"`
+
+	// AdditionalVolumes are the EBS volumes attached to the instance at launch.
+	// It should only be used when running a new instance.
+	AdditionalVolumes []Volume `json:"additionalVolumes,omitempty"`
+
+	// The name of the placement group of the instance, if any.
+	PlacementGroupName string `json:"placementGroupName,omitempty"`
+
+	// The tenancy of the instance.
+	Tenancy Tenancy `json:"tenancy,omitempty"`
+
+	// The ID of the Dedicated Host of the instance, if any.
+	HostID string `json:"hostId,omitempty"`
+
+	// The ID of the Capacity Reservation the instance runs in, if any.
+	CapacityReservationID string `json:"capacityReservationId,omitempty"`
+
+	// CapacityReservationPreference is the Capacity Reservation preference of
+	// the instance. It should only be used when running a new instance.
+	CapacityReservationPreference CapacityReservationPreference `json:"capacityReservationPreference,omitempty"`
+
+	// CPUOptions are the CPU options of the instance.
+	CPUOptions *CPUOptions `json:"cpuOptions,omitempty"`
+
+	// CreditSpecification is the CPU credit option of the instance.
+	// It should only be used when running a new instance.
+	CreditSpecification CPUCredits `json:"creditSpecification,omitempty"`
+
+	// DetailedMonitoring is true if the instance is monitored by CloudWatch
+	// every minute rather than every five minutes.
+	DetailedMonitoring bool `json:"detailedMonitoring,omitempty"`
+
+	// MetadataOptions are the instance metadata service options of the instance.
+	// It should only be used when running a new instance.
+	MetadataOptions *InstanceMetadataOptions `json:"metadataOptions,omitempty"`
+
+	// NetworkInterfaceType is the type of the primary network interface of the instance.
+	// It should only be used when running a new instance.
+	NetworkInterfaceType NetworkInterfaceType `json:"networkInterfaceType,omitempty"`
+
+	// SecondaryPrivateIPCount is the number of secondary private IP addresses
+	// of the primary network interface of the instance.
+	// It should only be used when running a new instance.
+	SecondaryPrivateIPCount int64 `json:"secondaryPrivateIPCount,omitempty"`
+
+	// AdditionalNetworkInterfaces are the network interfaces attached to the instance at launch.
+	// It should only be used when running a new instance.
+	AdditionalNetworkInterfaces []NetworkInterface `json:"additionalNetworkInterfaces,omitempty"`
+
+	// ENAExpress enables ENA Express on the network interfaces of the instance.
+	// It should only be used when running a new instance.
+	ENAExpress *ENAExpress `json:"enaExpress,omitempty"`
+
+	// AssociatePublicIPAddress overrides the public IP address assignment of
+	// the subnet of the instance, if set.
+	// It should only be used when running a new instance.
+	AssociatePublicIPAddress *bool `json:"associatePublicIPAddress,omitempty"`
+}
+
+// CPUOptions defines the CPU cores of an instance.
+type CPUOptions struct {
+	// CoreCount is the number of CPU cores of the instance.
+	CoreCount int64 `json:"coreCount"`
+
+	// ThreadsPerCore is the number of threads per CPU core: 1 to disable
+	// hyperthreading, or 2.
+	ThreadsPerCore int64 `json:"threadsPerCore"`
+}
+
+// InstanceMetadataOptions defines the instance metadata service options of an instance.
+type InstanceMetadataOptions struct {
+	// HTTPTokens is optional to accept both IMDSv1 and IMDSv2 requests, or
+	// required to only accept IMDSv2 requests, authenticated with a session token.
+	// Defaults to optional.
+	// +optional
+	HTTPTokens HTTPTokensState `json:"httpTokens,omitempty"`
+
+	// HTTPPutResponseHopLimit is the number of network hops the responses
+	// to the session token requests may travel, from 1 to 64. Containers not
+	// running in the host network need at least 2 to use IMDSv2.
+	// Defaults to 1.
+	// +optional
+	HTTPPutResponseHopLimit int64 `json:"httpPutResponseHopLimit,omitempty"`
+
+	// HTTPEndpoint is enabled or disabled to turn off the instance metadata
+	// service. Machines cannot bootstrap without it, and the AWS cloud
+	// provider of Kubernetes relies on it. Defaults to enabled.
+	// +optional
+	HTTPEndpoint InstanceMetadataEndpointState `json:"httpEndpoint,omitempty"`
+}
+
+// SerialConsole defines the access to the EC2 serial console of an instance.
+type SerialConsole struct {
+	// User is the name of the user logging in on the serial console.
+	// Defaults to root.
+	// +optional
+	User string `json:"user,omitempty"`
+
+	// PasswordHash is the hash of the password of the user, in the crypt(3)
+	// format of /etc/shadow, e.g. as generated by "openssl passwd -6". The
+	// password of the user is left untouched if not specified, in which
+	// case the image must provide a user able to log in.
+	// +optional
+	PasswordHash string `json:"passwordHash,omitempty"`
+}
+
+// RootVolume defines the EBS root volume of an instance.
+type RootVolume struct {
+	// Size of the volume in GiB. It must be at least the size of the AMI root
+	// snapshot, which it defaults to.
+	// +optional
+	Size int64 `json:"size,omitempty"`
+
+	// Type of the volume, e.g. gp2, gp3 or io1.
+	// If not specified, the type of the AMI root volume is used.
+	// +optional
+	Type string `json:"type,omitempty"`
+
+	// IOPS is the number of I/O operations per second provisioned for the volume.
+	// It is only supported for io1, io2 and gp3 volumes, and required for io1
+	// and io2 volumes.
+	// +optional
+	IOPS int64 `json:"iops,omitempty"`
+
+	// Throughput is the throughput provisioned for the volume, in MiB/s.
+	// It is only supported for gp3 volumes, and is provisioned by modifying
+	// the volume once the instance is running.
+	// +optional
+	Throughput int64 `json:"throughput,omitempty"`
+
+	// Encrypted specifies whether the volume should be encrypted.
+	// +optional
+	Encrypted bool `json:"encrypted,omitempty"`
+
+	// EncryptionKeyID is the ID, alias or ARN of the KMS key used to encrypt
+	// the volume. It requires Encrypted, and the key must be in the region of
+	// the cluster. If not specified, the default EBS key of the account is used.
+	// +optional
+	EncryptionKeyID string `json:"encryptionKeyId,omitempty"`
+}
+
+// Volume defines an EBS volume attached to an instance at launch.
+type Volume struct {
+	// DeviceName is the device name exposed to the instance, e.g. /dev/sdb.
+	DeviceName string `json:"deviceName"`
+
+	// Size of the volume in GiB.
+	Size int64 `json:"size"`
+
+	// Type of the volume, e.g. gp2, gp3 or io1. Defaults to the EC2 default volume type.
+	// +optional
+	Type string `json:"type,omitempty"`
+
+	// IOPS is the number of I/O operations per second provisioned for the volume.
+	// It is only supported for io1, io2 and gp3 volumes, and required for io1
+	// and io2 volumes.
+	// +optional
+	IOPS int64 `json:"iops,omitempty"`
+
+	// Throughput is the throughput provisioned for the volume, in MiB/s.
+	// It is only supported for gp3 volumes, and is provisioned by modifying
+	// the volume once the instance is running.
+	// +optional
+	Throughput int64 `json:"throughput,omitempty"`
+
+	// Encrypted specifies whether the volume should be encrypted.
+	// +optional
+	Encrypted bool `json:"encrypted,omitempty"`
+
+	// EncryptionKeyID is the ID, alias or ARN of the KMS key used to encrypt
+	// the volume. It requires Encrypted, and the key must be in the region of
+	// the cluster. If not specified, the default EBS key of the account is used.
+	// +optional
+	EncryptionKeyID string `json:"encryptionKeyId,omitempty"`
+}
+
+// LaunchTemplateReference is a reference to an EC2 launch template by ID or name.
+// Only one of ID or Name may be specified.
+type LaunchTemplateReference struct {
+	// ID of the launch template.
+	// +optional
+	ID *string `json:"id,omitempty"`
+
+	// Name of the launch template.
+	// +optional
+	Name *string `json:"name,omitempty"`
+
+	// Version of the launch template. If not specified, the default version is used.
+	// +optional
+	Version *string `json:"version,omitempty"`
+}
+
+// VolumeDeletionPolicy defines, per class of volume, whether EBS volumes are
+// deleted when the instance they are attached to is terminated.
+type VolumeDeletionPolicy struct {
+	// Root applies to the root volume of the instance.
+	// +optional
+	Root *bool `json:"root,omitempty"`
+
+	// NonRoot applies to all other EBS volumes attached at launch.
+	// +optional
+	NonRoot *bool `json:"nonRoot,omitempty"`
+}
+
+// EtcdVolume defines the volume dedicated to etcd on a control plane machine.
+// Exactly one of InstanceStore or Volume must be specified.
+type EtcdVolume struct {
+	// InstanceStore uses the first NVMe instance store volume of the instance.
+	// The instance type must provide one. Instance store data is lost when
+	// the instance is stopped, so the member is expected to resync from the
+	// rest of the etcd cluster.
+	// +optional
+	InstanceStore bool `json:"instanceStore,omitempty"`
+
+	// Volume is a dedicated EBS volume created and attached at launch.
+	// +optional
+	Volume *Volume `json:"volume,omitempty"`
+}
+
+// Tenancy is the tenancy of an instance.
+type Tenancy string
+
+var (
+	// TenancyDefault runs the instance on shared hardware.
+	TenancyDefault = Tenancy("default")
+
+	// TenancyDedicated runs the instance on hardware dedicated to the account.
+	TenancyDedicated = Tenancy("dedicated")
+
+	// TenancyHost runs the instance on a Dedicated Host.
+	TenancyHost = Tenancy("host")
+)
+
+// CapacityReservationPreference is the Capacity Reservation preference of an instance.
+type CapacityReservationPreference string
+
+var (
+	// CapacityReservationPreferenceOpen runs the instance in any open Capacity
+	// Reservation with matching attributes, if one is available.
+	CapacityReservationPreferenceOpen = CapacityReservationPreference("open")
+
+	// CapacityReservationPreferenceNone never runs the instance in a Capacity Reservation.
+	CapacityReservationPreferenceNone = CapacityReservationPreference("none")
+)
+
+// InstanceDeletionPolicy is what happens to the instance of a machine when
+// the machine is deleted.
+type InstanceDeletionPolicy string
+
+var (
+	// InstanceDeletionPolicyTerminate terminates the instance.
+	InstanceDeletionPolicyTerminate = InstanceDeletionPolicy("terminate")
+
+	// InstanceDeletionPolicyStop stops the instance, which keeps its volumes.
+	InstanceDeletionPolicyStop = InstanceDeletionPolicy("stop")
+)
+
+// CPUCredits is the CPU credit option of a burstable instance.
+type CPUCredits string
+
+var (
+	// CPUCreditsStandard limits the CPU usage of the instance to its earned credits.
+	CPUCreditsStandard = CPUCredits("standard")
+
+	// CPUCreditsUnlimited lets the instance burst beyond its earned credits,
+	// for an additional charge.
+	CPUCreditsUnlimited = CPUCredits("unlimited")
+)
+
+// HTTPTokensState is the state of token usage of the instance metadata service.
+type HTTPTokensState string
+
+var (
+	// HTTPTokensStateOptional accepts both IMDSv1 and IMDSv2 requests.
+	HTTPTokensStateOptional = HTTPTokensState("optional")
+
+	// HTTPTokensStateRequired only accepts IMDSv2 requests.
+	HTTPTokensStateRequired = HTTPTokensState("required")
+)
+
+// InstanceMetadataEndpointState is the state of the instance metadata service.
+type InstanceMetadataEndpointState string
+
+var (
+	// InstanceMetadataEndpointStateEnabled enables the instance metadata service.
+	InstanceMetadataEndpointStateEnabled = InstanceMetadataEndpointState("enabled")
+
+	// InstanceMetadataEndpointStateDisabled disables the instance metadata service.
+	InstanceMetadataEndpointStateDisabled = InstanceMetadataEndpointState("disabled")
+)
+
+// File defines a file written on an instance.
+type File struct {
+	// Path is the absolute path of the file. Missing parent directories are
+	// created.
+	Path string `json:"path"`
+
+	// Permissions are the octal permissions of the file, e.g. "0600".
+	// Defaults to "0600" for the content of a Secret, and to "0644"
+	// otherwise.
+	// +optional
+	Permissions string `json:"permissions,omitempty"`
+
+	// Content is the content of the file. Mutually exclusive with ContentFrom.
+	// +optional
+	Content string `json:"content,omitempty"`
+
+	// ContentFrom reads the content of the file from a Secret or ConfigMap.
+	// Mutually exclusive with Content.
+	// +optional
+	ContentFrom *FileSource `json:"contentFrom,omitempty"`
+}
+
+// FileSource defines the Secret or ConfigMap key holding the content of a
+// file. Exactly one of Secret or ConfigMap must be set.
+type FileSource struct {
+	// Secret is the key of a Secret in the namespace of the machine.
+	// +optional
+	Secret *FileSourceKey `json:"secret,omitempty"`
+
+	// ConfigMap is the key of a ConfigMap in the namespace of the machine.
+	// +optional
+	ConfigMap *FileSourceKey `json:"configMap,omitempty"`
+}
+
+// FileSourceKey selects a key of a Secret or ConfigMap.
+type FileSourceKey struct {
+	// Name is the name of the Secret or ConfigMap.
+	Name string `json:"name"`
+
+	// Key is the key holding the content of the file.
+	Key string `json:"key"`
+}
+
+// NetworkInterface defines an additional network interface of an instance.
+type NetworkInterface struct {
+	// SubnetID is the ID of the subnet of the network interface, which must
+	// be in the availability zone of the instance. Defaults to the subnet of
+	// the instance.
+	// +optional
+	SubnetID string `json:"subnetID,omitempty"`
+
+	// SecondaryPrivateIPCount is the number of secondary private IP
+	// addresses assigned to the network interface.
+	// +optional
+	SecondaryPrivateIPCount int64 `json:"secondaryPrivateIPCount,omitempty"`
+
+	// NetworkCardIndex is the network card the network interface is
+	// attached to, on instance types with several network cards to spread
+	// bandwidth across. Defaults to the first network card, 0.
+	// +optional
+	NetworkCardIndex int64 `json:"networkCardIndex,omitempty"`
+}
+
+// ENAExpress configures ENA Express on the network interfaces of an
+// instance, which uses the AWS Scalable Reliable Datagram protocol to raise
+// the single flow bandwidth and lower the tail latency of the traffic between
+// instances in the same availability zone.
+type ENAExpress struct {
+	// UDP also applies ENA Express to UDP traffic, which TCP traffic only
+	// uses by default.
+	// +optional
+	UDP bool `json:"udp,omitempty"`
+}
+
+// NetworkInterfaceType is the type of the primary network interface of an instance.
+type NetworkInterfaceType string
+
+var (
+	// NetworkInterfaceTypeInterface is a standard Elastic Network Interface.
+	NetworkInterfaceTypeInterface = NetworkInterfaceType("interface")
+
+	// NetworkInterfaceTypeEFA is an Elastic Fabric Adapter, for low latency
+	// communication between instances.
+	NetworkInterfaceTypeEFA = NetworkInterfaceType("efa")
+)
+
+// LifecycleHook is a custom provisioning step invoked at lifecycle points of
+// the machines of a cluster, such as a registration in a CMDB or an IPAM.
+// The hook receives the context of the machine as a JSON document posted to
+// an HTTPS endpoint, which must have a public address. Hooks must be
+// idempotent, as they are invoked again when a step is retried.
+type LifecycleHook struct {
+	// Name identifies the hook in logs and events.
+	Name string `json:"name"`
+
+	// Points are the lifecycle points at which the hook is invoked.
+	Points []LifecyclePoint `json:"points"`
+
+	// URL is the HTTPS endpoint to which the machine context is posted.
+	// Responses with a status other than 2xx are failures.
+	URL string `json:"url"`
+
+	// Timeout is how long the hook may run. Defaults to 30 seconds.
+	// +optional
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+
+	// FailurePolicy defines how failures of the hook are handled.
+	// Defaults to Fail.
+	// +optional
+	FailurePolicy HookFailurePolicy `json:"failurePolicy,omitempty"`
+}
+
+// LifecyclePoint is a point of the lifecycle of a machine.
+type LifecyclePoint string
+
+var (
+	// LifecyclePreCreate is before the instance of a machine is launched.
+	LifecyclePreCreate = LifecyclePoint("preCreate")
+
+	// LifecyclePostJoin is once the node of a machine has joined the cluster.
+	LifecyclePostJoin = LifecyclePoint("postJoin")
+
+	// LifecyclePreDelete is before the instance of a machine is terminated.
+	LifecyclePreDelete = LifecyclePoint("preDelete")
+)
+
+// HookFailurePolicy defines how failures of a lifecycle hook are handled.
+type HookFailurePolicy string
+
+var (
+	// HookFailurePolicyFail retries the lifecycle step of the machine until
+	// the hook succeeds.
+	HookFailurePolicyFail = HookFailurePolicy("Fail")
+
+	// HookFailurePolicyIgnore reports failures of the hook with a warning
+	// event and carries on with the lifecycle step.
+	HookFailurePolicyIgnore = HookFailurePolicy("Ignore")
+)
+
+// PlacementStrategy is the strategy of a placement group.
+type PlacementStrategy string
+
+var (
+	// PlacementStrategyCluster packs instances close together in an availability zone.
+	PlacementStrategyCluster = PlacementStrategy("cluster")
+
+	// PlacementStrategySpread places instances on distinct hardware.
+	PlacementStrategySpread = PlacementStrategy("spread")
+
+	// PlacementStrategyPartition spreads instances across partitions that
+	// do not share hardware.
+	PlacementStrategyPartition = PlacementStrategy("partition")
+)
+
+// PlacementGroup defines the placement group an instance is launched in.
+// Exactly one of Name or Strategy must be specified.
+type PlacementGroup struct {
+	// Name is the name of an existing placement group, which is left
+	// untouched when the cluster is deleted.
+	// +optional
+	Name string `json:"name,omitempty"`
+
+	// Strategy is the strategy of a placement group created for the cluster.
+	// Machines requesting the same strategy share the placement group, which
+	// is deleted along with the cluster.
+	// +optional
+	Strategy PlacementStrategy `json:"strategy,omitempty"`
+}
+
+// QuarantinePolicy defines how the instance of a failed machine is isolated
+// and kept for debugging before it is terminated.
+type QuarantinePolicy struct {
+	// TTL is how long the instance is kept once quarantined.
+	TTL metav1.Duration `json:"ttl"`
+
+	// SecurityGroup is a reference, by ID, to the security group that replaces
+	// the security groups of the instance while it is quarantined, e.g. one
+	// that only allows SSH from the bastion.
+	// If not specified, the security groups of the instance are left unchanged.
+	// +optional
+	SecurityGroup *AWSResourceReference `json:"securityGroup,omitempty"`
+}
+
+// HardeningProfile is a host hardening profile applied to a machine instance at bootstrap.
+type HardeningProfile string
+
+var (
+	// HardeningProfileBaseline applies CIS-style kernel parameters, disables
+	// unused services and installs audit rules, where auditd is available.
+	HardeningProfileBaseline = HardeningProfile("baseline")
+)
+
+// GPUConfig configures the NVIDIA GPUs of a machine instance.
+type GPUConfig struct {
+	// InstallDrivers installs the NVIDIA driver and the NVIDIA container
+	// toolkit before bootstrapping, and makes the NVIDIA runtime the default
+	// runtime of containerd, for AMIs which do not include them.
+	// +optional
+	InstallDrivers bool `json:"installDrivers,omitempty"`
+
+	// DriverVersion is the branch of the NVIDIA driver installed, e.g. "580".
+	// Defaults to 580.
+	// +optional
+	DriverVersion string `json:"driverVersion,omitempty"`
+}
+
+// KubeletDNS configures name resolution for the kubelet and the pods it runs.
+type KubeletDNS struct {
+	// ClusterDNS is the list of DNS server addresses that pods are configured
+	// to use, e.g. the address of a node-local DNS cache.
+	// If not specified, the cluster DNS service is used.
+	// +optional
+	ClusterDNS []string `json:"clusterDNS,omitempty"`
+
+	// ResolvConf is the resolver configuration file that pod DNS settings are
+	// derived from, e.g. /run/systemd/resolve/resolv.conf to bypass the local
+	// systemd-resolved stub when the VPC uses custom DHCP options.
+	// +optional
+	ResolvConf string `json:"resolvConf,omitempty"`
+}
+
+// KubeletReserved defines the resources of an instance reserved for the
+// system and Kubernetes daemons, keyed by resource name, e.g. cpu: 100m or
+// memory: 512Mi.
+type KubeletReserved struct {
+	// SystemReserved are the resources reserved for the system daemons.
+	// Defaults to 100m of CPU, 100Mi of memory and 1Gi of ephemeral storage
+	// with the KubeletReservedDefaults feature gate.
+	// +optional
+	SystemReserved map[string]string `json:"systemReserved,omitempty"`
+
+	// KubeReserved are the resources reserved for the kubelet and the
+	// container runtime. Defaults to a share of the CPU and memory of the
+	// instance type decreasing with their size, and 1Gi of ephemeral storage,
+	// with the KubeletReservedDefaults feature gate.
+	// +optional
+	KubeReserved map[string]string `json:"kubeReserved,omitempty"`
+}
+
+// TransitEncryption configures the encryption in transit of the traffic of
+// the kubelets and of etcd.
+type TransitEncryption struct {
+	// KubeletServingCertificates has the API server require serving
+	// certificates signed by the cluster CA when connecting to the kubelets.
+	// The kubelets request these certificates from the cluster CA, and the
+	// controller approves the requests once their addresses are checked
+	// against the instances of their machines.
+	// +optional
+	KubeletServingCertificates bool `json:"kubeletServingCertificates,omitempty"`
+
+	// EtcdCipherSuites restricts the TLS cipher suites of the client and peer
+	// connections of etcd, named as in Go, e.g.
+	// TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256. Defaults to the cipher suites of
+	// etcd.
+	// +optional
+	EtcdCipherSuites []string `json:"etcdCipherSuites,omitempty"`
+}
+
+// ReservedInstanceCoverage describes the reserved instance coverage of an instance type.
+type ReservedInstanceCoverage struct {
+	// InstanceType is the EC2 instance type.
+	InstanceType string `json:"instanceType"`
+
+	// Running is the number of running cluster instances of this type.
+	Running int64 `json:"running"`
+
+	// Reserved is the number of active reserved instances of this type in the region.
+	Reserved int64 `json:"reserved"`
+}
+
+// InstanceEvent describes a maintenance event scheduled by AWS for an instance.
+type InstanceEvent struct {
+	// Code is the type of the event, e.g. instance-retirement or system-reboot.
+	Code string `json:"code"`
+
+	// Description of the event.
+	Description string `json:"description,omitempty"`
+
+	// NotBefore is the earliest scheduled start time of the event.
+	NotBefore *metav1.Time `json:"notBefore,omitempty"`
+}
+
+// ZoneLaunchFailure tracks consecutive instance launch failures in an availability zone.
+type ZoneLaunchFailure struct {
+	// Count is the number of consecutive launch failures in the zone.
+	Count int `json:"count"`
+
+	// LastFailureTime is the time of the most recent launch failure in the zone.
+	LastFailureTime metav1.Time `json:"lastFailureTime"`
+}
+
+// String returns a string representation of the instance.
+// User data is deliberately left out as it may contain secrets.
+func (i *Instance) String() string {
+	return fmt.Sprintf("id=%s/type=%s/state=%s/subnet=%s/image=%s", i.ID, i.Type, i.State, i.SubnetID, i.ImageID)
 }
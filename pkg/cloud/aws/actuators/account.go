@@ -0,0 +1,64 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actuators
+
+import (
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/sts"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/record"
+)
+
+// AccountID returns the ID of the AWS account the credentials of the
+// controller resolve to.
+func (s *Scope) AccountID() (string, error) {
+	if s.accountID == "" {
+		accountID, err := sts.NewService(s.STS).AccountID()
+		if err != nil {
+			return "", err
+		}
+		s.accountID = accountID
+	}
+	return s.accountID, nil
+}
+
+// VerifyAccount checks that the credentials of the controller resolve to the
+// AWS account and region recorded in the status of a cluster or machine when
+// it was created, and records them if they were not yet.
+// It returns an error if they differ, so that a misconfiguration of the
+// credentials doesn't lead the controller to modify the resources of another
+// account, or to recreate the resources of the object in it.
+func (s *Scope) VerifyAccount(obj runtime.Object, accountID, region *string) error {
+	current, err := s.AccountID()
+	if err != nil {
+		return errors.Wrap(err, "failed to verify the AWS account")
+	}
+
+	if *accountID != "" && *accountID != current {
+		record.Warnf(obj, "AccountMismatch", "Refusing to reconcile resources of AWS account %s with credentials of account %s", *accountID, current)
+		return errors.Errorf("credentials of the controller resolve to AWS account %s, the resources were created in account %s", current, *accountID)
+	}
+
+	if *region != "" && *region != s.Region() {
+		record.Warnf(obj, "RegionMismatch", "Refusing to reconcile resources of AWS region %s in region %s", *region, s.Region())
+		return errors.Errorf("cluster is configured for AWS region %s, the resources were created in region %s", s.Region(), *region)
+	}
+
+	*accountID = current
+	*region = s.Region()
+	return nil
+}
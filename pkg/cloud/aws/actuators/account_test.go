@@ -0,0 +1,99 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actuators
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/aws/aws-sdk-go/service/sts/stsiface"
+	clusterv1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+)
+
+// fakeSTS resolves the credentials to a fixed account.
+type fakeSTS struct {
+	stsiface.STSAPI
+	account string
+}
+
+func (f *fakeSTS) GetCallerIdentity(*sts.GetCallerIdentityInput) (*sts.GetCallerIdentityOutput, error) {
+	return &sts.GetCallerIdentityOutput{Account: aws.String(f.account)}, nil
+}
+
+func TestVerifyAccount(t *testing.T) {
+	testCases := []struct {
+		name            string
+		accountID       string
+		region          string
+		expectError     bool
+		expectAccountID string
+	}{
+		{
+			name:            "not recorded yet",
+			expectAccountID: "111111111111",
+		},
+		{
+			name:            "same account and region",
+			accountID:       "111111111111",
+			region:          "us-east-1",
+			expectAccountID: "111111111111",
+		},
+		{
+			name:            "other account",
+			accountID:       "222222222222",
+			region:          "us-east-1",
+			expectError:     true,
+			expectAccountID: "222222222222",
+		},
+		{
+			name:            "other region",
+			accountID:       "111111111111",
+			region:          "eu-west-1",
+			expectError:     true,
+			expectAccountID: "111111111111",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cluster := &clusterv1.Cluster{}
+			scope, err := NewScope(ScopeParams{
+				Cluster:    cluster,
+				AWSClients: AWSClients{STS: &fakeSTS{account: "111111111111"}},
+			})
+			if err != nil {
+				t.Fatalf("failed to create scope: %v", err)
+			}
+			scope.ClusterConfig.Region = "us-east-1"
+
+			accountID, region := tc.accountID, tc.region
+			err = scope.VerifyAccount(cluster, &accountID, &region)
+			if tc.expectError != (err != nil) {
+				t.Fatalf("expected error: %t, got: %v", tc.expectError, err)
+			}
+
+			if accountID != tc.expectAccountID {
+				t.Fatalf("expected account %q to be recorded, got %q", tc.expectAccountID, accountID)
+			}
+
+			if !tc.expectError && region != "us-east-1" {
+				t.Fatalf("expected region %q to be recorded, got %q", "us-east-1", region)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actuators
+
+import (
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/features"
+)
+
+const (
+	// SkipSecurityGroupsAnnotation disables the reconciliation of security groups.
+	// On a cluster, existing security groups are looked up but never created, modified
+	// or deleted. On a machine, the security groups of the instance are left untouched.
+	SkipSecurityGroupsAnnotation = "sigs.k8s.io/cluster-api-provider-aws/skip-security-groups"
+
+	// SkipLoadBalancerAttachmentAnnotation disables the registration of control plane
+	// instances with the api server load balancer.
+	SkipLoadBalancerAttachmentAnnotation = "sigs.k8s.io/cluster-api-provider-aws/skip-lb-attachment"
+
+	// SkipTagsAnnotation disables the reconciliation of the additional tags of instances.
+	SkipTagsAnnotation = "sigs.k8s.io/cluster-api-provider-aws/skip-tags"
+
+	// RebootAnnotation reboots the instance of a machine, and is removed once
+	// the reboot is requested. Set it to "true" to reboot the instance right
+	// away, or to "drain" to cordon and drain its node first, in which case
+	// the node is uncordoned once it is ready again after the reboot.
+	RebootAnnotation = "sigs.k8s.io/cluster-api-provider-aws/reboot"
+
+	// StopAnnotation stops the instance of a machine while it is set to
+	// "true". The instance is started again once the annotation is removed.
+	StopAnnotation = "sigs.k8s.io/cluster-api-provider-aws/stop"
+
+	// SkipLastControlPlaneGuardAnnotation allows the deletion of the last
+	// running control plane machine of a cluster that is not being deleted.
+	SkipLastControlPlaneGuardAnnotation = "sigs.k8s.io/cluster-api-provider-aws/skip-last-control-plane-guard"
+)
+
+// Skips returns true if the cluster opts out of the reconciliation
+// disabled by the given annotation.
+func (s *Scope) Skips(annotation string) bool {
+	return s.Cluster.Annotations[annotation] == "true"
+}
+
+// Skips returns true if the machine, or its cluster, opts out of the
+// reconciliation disabled by the given annotation.
+func (m *MachineScope) Skips(annotation string) bool {
+	return m.Machine.Annotations[annotation] == "true" || m.Scope.Skips(annotation)
+}
+
+// FeatureEnabled returns true if the feature is enabled for the cluster.
+func (s *Scope) FeatureEnabled(feature features.Feature) bool {
+	return features.DefaultFeatureGate.EnabledFor(feature, s.Cluster.Annotations)
+}
+
+// FeatureEnabled returns true if the feature is enabled for the machine.
+// The machine annotations take precedence over those of its cluster.
+func (m *MachineScope) FeatureEnabled(feature features.Feature) bool {
+	return features.DefaultFeatureGate.EnabledFor(feature, m.Machine.Annotations, m.Scope.Cluster.Annotations)
+}
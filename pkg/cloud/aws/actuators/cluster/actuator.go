@@ -18,11 +18,12 @@ package cluster
 
 import (
 	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/klog"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
 	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/actuators"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services"
 	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/certificates"
-	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/ec2"
-	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/elb"
 	"sigs.k8s.io/cluster-api-provider-aws/pkg/deployer"
 	clusterv1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
 	client "sigs.k8s.io/cluster-api/pkg/client/clientset_generated/clientset/typed/cluster/v1alpha1"
@@ -33,24 +34,52 @@ import (
 type Actuator struct {
 	*deployer.Deployer
 
-	client client.ClusterV1alpha1Interface
+	client   client.ClusterV1alpha1Interface
+	selector labels.Selector
+	readOnly bool
+	services services.Getter
 }
 
 // ActuatorParams holds parameter information for Actuator
 type ActuatorParams struct {
 	Client client.ClusterV1alpha1Interface
+
+	// Selector, if set, restricts the actuator to the clusters whose labels it matches,
+	// and to their machines. Other clusters are left to other controllers.
+	Selector labels.Selector
+
+	// ReadOnly, if set, prevents the actuator from modifying AWS resources.
+	// Existing resources are still observed and reported in the status.
+	ReadOnly bool
+
+	// Getter returns the EC2 and ELB services of the clusters.
+	// Defaults to services.DefaultGetter.
+	Getter services.Getter
 }
 
 // NewActuator creates a new Actuator
 func NewActuator(params ActuatorParams) *Actuator {
+	getter := params.Getter
+	if getter == nil {
+		getter = services.DefaultGetter
+	}
+
 	return &Actuator{
-		Deployer: deployer.New(deployer.Params{ScopeGetter: actuators.DefaultScopeGetter}),
+		Deployer: deployer.New(deployer.Params{ServicesGetter: getter}),
 		client:   params.Client,
+		selector: params.Selector,
+		readOnly: params.ReadOnly,
+		services: getter,
 	}
 }
 
 // Reconcile reconciles a cluster and is invoked by the Cluster Controller
 func (a *Actuator) Reconcile(cluster *clusterv1.Cluster) error {
+	if !actuators.Selects(a.selector, cluster) {
+		klog.V(2).Infof("Cluster %v is not selected by this controller, skipping", cluster.Name)
+		return nil
+	}
+
 	klog.Infof("Reconciling cluster %v", cluster.Name)
 
 	scope, err := actuators.NewScope(actuators.ScopeParams{Cluster: cluster, Client: a.client})
@@ -60,8 +89,54 @@ func (a *Actuator) Reconcile(cluster *clusterv1.Cluster) error {
 
 	defer scope.Close()
 
-	ec2svc := ec2.NewService(scope)
-	elbsvc := elb.NewService(scope)
+	// Invalid configurations are rejected before any resource is created,
+	// rather than failing halfway through provisioning.
+	validators := []struct {
+		reason   string
+		subject  string
+		validate func() error
+	}{
+		{"InvalidName", "names", func() error {
+			return actuators.ValidateClusterNames(cluster.Name, scope.ClusterConfig.ResourceNaming)
+		}},
+		{"InvalidTransitEncryption", "transit encryption settings", func() error {
+			return actuators.ValidateTransitEncryption(scope.ClusterConfig.TransitEncryption)
+		}},
+		{"InvalidAPIServerElasticIP", "API server Elastic IP", func() error {
+			return actuators.ValidateAPIServerElasticIP(scope.ClusterConfig)
+		}},
+		{"InvalidNTPServers", "NTP servers", func() error {
+			return actuators.ValidateNTPServers(scope.ClusterConfig.NTPServers)
+		}},
+		{"InvalidMachinePools", "machine pools", func() error {
+			return actuators.ValidateMachinePools(scope.ClusterConfig.MachinePools)
+		}},
+		{"InvalidIPAM", "ipam configuration", func() error {
+			return actuators.ValidateIPAM(scope.ClusterConfig.IPAM)
+		}},
+	}
+	for _, v := range validators {
+		if err := v.validate(); err != nil {
+			return failValidation(scope, v.reason, v.subject, err)
+		}
+	}
+
+	// In read-only mode, only the phase derived from the machines is updated.
+	if a.readOnly {
+		klog.Infof("Controller is read-only, skipping the reconciliation of the AWS resources of cluster %v", cluster.Name)
+		switch scope.ClusterStatus.Phase {
+		case v1alpha1.ClusterPhaseControlPlaneProvisioning, v1alpha1.ClusterPhaseControlPlaneReady:
+			return a.updateControlPlanePhase(scope)
+		}
+		return nil
+	}
+
+	if err := scope.VerifyAccount(cluster, &scope.ClusterStatus.AccountID, &scope.ClusterStatus.Region); err != nil {
+		return errors.Wrapf(err, "refusing to reconcile cluster %q", cluster.Name)
+	}
+
+	ec2svc := a.services.EC2(scope)
+	elbsvc := a.services.ELB(scope)
 
 	// Store some config parameters in the status.
 	if len(scope.ClusterConfig.CACertificate) == 0 {
@@ -74,23 +149,91 @@ func (a *Actuator) Reconcile(cluster *clusterv1.Cluster) error {
 		scope.ClusterConfig.CAPrivateKey = certificates.EncodePrivateKeyPEM(caKey)
 	}
 
+	if err := scope.StoreCAPrivateKey(); err != nil {
+		return errors.Wrap(err, "failed to store the CA private key")
+	}
+
 	if err := ec2svc.ReconcileNetwork(); err != nil {
+		setPhase(scope.ClusterStatus, v1alpha1.ClusterPhaseNetworkProvisioning, err)
 		return errors.Errorf("unable to reconcile network: %+v", err)
 	}
 
-	if err := ec2svc.ReconcileBastion(); err != nil {
+	if err := scope.Converge("bastion", ec2svc.ReconcileBastion); err != nil {
+		setPhase(scope.ClusterStatus, v1alpha1.ClusterPhaseNetworkProvisioning, err)
 		return errors.Errorf("unable to reconcile network: %+v", err)
 	}
 
-	if err := elbsvc.ReconcileLoadbalancers(); err != nil {
+	if err := scope.Converge("load-balancers", elbsvc.ReconcileLoadbalancers); err != nil {
+		setPhase(scope.ClusterStatus, v1alpha1.ClusterPhaseControlPlaneProvisioning, err)
 		return errors.Errorf("unable to reconcile load balancers: %+v", err)
 	}
 
+	if err := scope.Converge("api-server-address", ec2svc.ReconcileAPIServerAddress); err != nil {
+		setPhase(scope.ClusterStatus, v1alpha1.ClusterPhaseControlPlaneProvisioning, err)
+		return errors.Errorf("unable to reconcile API server address: %+v", err)
+	}
+
+	if err := scope.Converge("api-server-private-link", elbsvc.ReconcileAPIServerPrivateLink); err != nil {
+		return errors.Errorf("unable to reconcile API server PrivateLink endpoint service: %+v", err)
+	}
+
+	if err := scope.Converge("api-server-elb-migration", elbsvc.ReconcileAPIServerELBMigration); err != nil {
+		return errors.Errorf("unable to migrate API server load balancer: %+v", err)
+	}
+
+	if err := ec2svc.ReconcileReservedInstanceCoverage(); err != nil {
+		return errors.Errorf("unable to reconcile reserved instance coverage: %+v", err)
+	}
+
+	controlPlaneZones, err := ec2svc.ControlPlaneZones()
+	if err != nil {
+		return errors.Errorf("unable to check the availability zones of the control plane: %+v", err)
+	}
+	setControlPlaneZoneCondition(cluster, scope.ClusterStatus, controlPlaneZones)
+
+	if err := a.updateControlPlanePhase(scope); err != nil {
+		return err
+	}
+
+	// The workload cluster is only reached once its control plane is ready.
+	if scope.ClusterStatus.Phase != v1alpha1.ClusterPhaseControlPlaneReady {
+		return nil
+	}
+
+	return a.reconcileMachinePools(scope, ec2svc)
+}
+
+// updateControlPlanePhase sets the phase of a cluster whose network and load
+// balancers are provisioned, depending on whether its control plane is ready.
+// Only the machines labelled with the name of the cluster are considered, as
+// other clusters may share its namespace.
+func (a *Actuator) updateControlPlanePhase(scope *actuators.Scope) error {
+	machines, err := a.client.Machines(scope.Namespace()).List(actuators.ClusterMachines(scope.Cluster))
+	if err != nil {
+		return errors.Wrapf(err, "failed to retrieve machines in cluster %q", scope.Name())
+	}
+
+	if controlPlaneReady(machines.Items) {
+		setPhase(scope.ClusterStatus, v1alpha1.ClusterPhaseControlPlaneReady, nil)
+	} else {
+		setPhase(scope.ClusterStatus, v1alpha1.ClusterPhaseControlPlaneProvisioning, nil)
+	}
+
 	return nil
 }
 
 // Delete deletes a cluster and is invoked by the Cluster Controller
 func (a *Actuator) Delete(cluster *clusterv1.Cluster) error {
+	if !actuators.Selects(a.selector, cluster) {
+		klog.V(2).Infof("Cluster %v is not selected by this controller, waiting for its deletion", cluster.Name)
+		return &controllerError.RequeueAfterError{RequeueAfter: actuators.UnselectedRequeueAfter}
+	}
+
+	if a.readOnly {
+		klog.Infof("Controller is read-only, postponing the deletion of cluster %v", cluster.Name)
+		return &controllerError.RequeueAfterError{RequeueAfter: actuators.ReadOnlyRequeueAfter}
+	}
+
 	klog.Infof("Deleting cluster %v.", cluster.Name)
 
 	scope, err := actuators.NewScope(actuators.ScopeParams{Cluster: cluster, Client: a.client})
@@ -100,8 +243,14 @@ func (a *Actuator) Delete(cluster *clusterv1.Cluster) error {
 
 	defer scope.Close()
 
-	ec2svc := ec2.NewService(scope)
-	elbsvc := elb.NewService(scope)
+	if err := scope.VerifyAccount(cluster, &scope.ClusterStatus.AccountID, &scope.ClusterStatus.Region); err != nil {
+		return errors.Wrapf(err, "refusing to delete cluster %q", cluster.Name)
+	}
+
+	ec2svc := a.services.EC2(scope)
+	elbsvc := a.services.ELB(scope)
+
+	setPhase(scope.ClusterStatus, v1alpha1.ClusterPhaseDeleting, nil)
 
 	if err := elbsvc.DeleteLoadbalancers(); err != nil {
 		return errors.Errorf("unable to delete load balancers: %+v", err)
@@ -111,12 +260,32 @@ func (a *Actuator) Delete(cluster *clusterv1.Cluster) error {
 		return errors.Errorf("unable to delete bastion: %+v", err)
 	}
 
-	if err := ec2svc.DeleteNetwork(); err != nil {
+	if err := ec2svc.DeleteStoppedInstances(); err != nil {
+		return errors.Errorf("unable to delete stopped instances: %+v", err)
+	}
+
+	if err := ec2svc.DeletePlacementGroups(); err != nil {
+		return errors.Errorf("unable to delete placement groups: %+v", err)
+	}
+
+	err = ec2svc.DeleteNetwork()
+	setSecurityGroupsInUseCondition(cluster, scope.ClusterStatus, err)
+	if err != nil {
 		klog.Errorf("Error deleting cluster %v: %v.", cluster.Name, err)
 		return &controllerError.RequeueAfterError{
 			RequeueAfter: 5 * 1000 * 1000 * 1000,
 		}
 	}
 
+	if err := ec2svc.DeleteImages(); err != nil {
+		return errors.Errorf("unable to delete images: %+v", err)
+	}
+
+	if err := scope.DeleteCAPrivateKey(); err != nil {
+		return errors.Errorf("unable to delete CA private key: %+v", err)
+	}
+
+	scope.ForgetConvergence()
+
 	return nil
 }
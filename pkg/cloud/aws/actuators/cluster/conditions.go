@@ -0,0 +1,151 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/awserrors"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/record"
+	clusterv1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+)
+
+// setCondition sets the given condition on the cluster provider status,
+// replacing any existing condition of the same type. The transition time is
+// only updated when the status of the condition changes.
+// Returns true if the status of the condition changed.
+func setCondition(status *v1alpha1.AWSClusterProviderStatus, condition v1alpha1.AWSClusterProviderCondition) bool {
+	now := metav1.Now()
+	condition.LastProbeTime = now
+
+	for i := range status.Conditions {
+		existing := &status.Conditions[i]
+		if existing.Type != condition.Type {
+			continue
+		}
+
+		changed := existing.Status != condition.Status
+		if changed {
+			condition.LastTransitionTime = now
+		} else {
+			condition.LastTransitionTime = existing.LastTransitionTime
+		}
+
+		*existing = condition
+		return changed
+	}
+
+	condition.LastTransitionTime = now
+	status.Conditions = append(status.Conditions, condition)
+	return true
+}
+
+// hasCondition returns true if the cluster provider status has a condition of
+// the given type.
+func hasCondition(status *v1alpha1.AWSClusterProviderStatus, conditionType v1alpha1.AWSClusterProviderConditionType) bool {
+	for _, condition := range status.Conditions {
+		if condition.Type == conditionType {
+			return true
+		}
+	}
+	return false
+}
+
+// Reports with the ControlPlaneZoneConcentrated condition whether a single
+// availability zone hosts a quorum of the control plane instances of the
+// cluster, given their number per zone. The condition is only cleared on
+// clusters already reporting it.
+// Returns true if the control plane is concentrated in a zone.
+func setControlPlaneZoneCondition(cluster *clusterv1.Cluster, status *v1alpha1.AWSClusterProviderStatus, zones map[string]int) bool {
+	var total int
+	for _, n := range zones {
+		total += n
+	}
+
+	var concentrated string
+	if total > 1 {
+		for zone, n := range zones {
+			if n > total/2 {
+				concentrated = zone
+			}
+		}
+	}
+
+	if concentrated == "" && !hasCondition(status, v1alpha1.ControlPlaneZoneConcentrated) {
+		return false
+	}
+
+	condition := v1alpha1.AWSClusterProviderCondition{
+		Type:   v1alpha1.ControlPlaneZoneConcentrated,
+		Status: corev1.ConditionFalse,
+	}
+
+	if concentrated != "" {
+		counts := make([]string, 0, len(zones))
+		for zone, n := range zones {
+			counts = append(counts, fmt.Sprintf("%s=%d", zone, n))
+		}
+		sort.Strings(counts)
+
+		condition.Status = corev1.ConditionTrue
+		condition.Reason = "QuorumInSingleZone"
+		condition.Message = fmt.Sprintf("Availability zone %q hosts %d of the %d control plane instances, which lose their quorum if it fails (%s)",
+			concentrated, zones[concentrated], total, strings.Join(counts, ", "))
+	}
+
+	if setCondition(status, condition) && concentrated != "" {
+		record.Warn(cluster, "ControlPlaneZoneConcentrated", condition.Message)
+	}
+
+	return concentrated != ""
+}
+
+// Reports with the SecurityGroupsInUse condition whether the deletion of the
+// network of the cluster failed because resources outside the cluster
+// reference its security groups. The condition is only cleared on clusters
+// already reporting it.
+// Returns true if the security groups are in use.
+func setSecurityGroupsInUseCondition(cluster *clusterv1.Cluster, status *v1alpha1.AWSClusterProviderStatus, deleteErr error) bool {
+	inUse := awserrors.IsInUse(errors.Cause(deleteErr))
+
+	if !inUse && !hasCondition(status, v1alpha1.SecurityGroupsInUse) {
+		return false
+	}
+
+	condition := v1alpha1.AWSClusterProviderCondition{
+		Type:   v1alpha1.SecurityGroupsInUse,
+		Status: corev1.ConditionFalse,
+	}
+
+	if inUse {
+		condition.Status = corev1.ConditionTrue
+		condition.Reason = awserrors.DependencyViolation
+		condition.Message = fmt.Sprintf("The cluster cannot be deleted until the resources using its security groups are deleted: %v", errors.Cause(deleteErr))
+	}
+
+	if setCondition(status, condition) && inUse {
+		record.Warn(cluster, "SecurityGroupsInUse", condition.Message)
+	}
+
+	return inUse
+}
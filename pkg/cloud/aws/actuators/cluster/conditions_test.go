@@ -0,0 +1,85 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/awserrors"
+	clusterv1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+)
+
+func TestSetControlPlaneZoneCondition(t *testing.T) {
+	cluster := &clusterv1.Cluster{}
+
+	// A single control plane instance is not concentrated.
+	status := &v1alpha1.AWSClusterProviderStatus{}
+	if setControlPlaneZoneCondition(cluster, status, map[string]int{"us-east-1a": 1}) || len(status.Conditions) != 0 {
+		t.Fatalf("expected no condition, got %+v", status.Conditions)
+	}
+
+	if !setControlPlaneZoneCondition(cluster, status, map[string]int{"us-east-1a": 2, "us-east-1b": 1}) {
+		t.Fatalf("expected the control plane to be concentrated")
+	}
+	if len(status.Conditions) != 1 || status.Conditions[0].Type != v1alpha1.ControlPlaneZoneConcentrated || status.Conditions[0].Status != corev1.ConditionTrue {
+		t.Fatalf("expected a true %s condition, got %+v", v1alpha1.ControlPlaneZoneConcentrated, status.Conditions)
+	}
+	if !strings.Contains(status.Conditions[0].Message, `"us-east-1a" hosts 2 of the 3`) {
+		t.Fatalf("expected the condition message to name the zone, got %q", status.Conditions[0].Message)
+	}
+
+	// The condition is cleared once the control plane is spread.
+	if setControlPlaneZoneCondition(cluster, status, map[string]int{"us-east-1a": 1, "us-east-1b": 1, "us-east-1c": 1}) {
+		t.Fatalf("expected the control plane not to be concentrated")
+	}
+	if len(status.Conditions) != 1 || status.Conditions[0].Status != corev1.ConditionFalse {
+		t.Fatalf("expected a false %s condition, got %+v", v1alpha1.ControlPlaneZoneConcentrated, status.Conditions)
+	}
+}
+
+func TestSetSecurityGroupsInUseCondition(t *testing.T) {
+	cluster := &clusterv1.Cluster{}
+
+	// Unrelated failures do not set the condition.
+	status := &v1alpha1.AWSClusterProviderStatus{}
+	if setSecurityGroupsInUseCondition(cluster, status, errors.New("timeout")) || len(status.Conditions) != 0 {
+		t.Fatalf("expected no condition, got %+v", status.Conditions)
+	}
+
+	inUse := awserrors.NewInUse(errors.New(`security group "sg-1" is in use by security group "sg-2" (other)`))
+	if !setSecurityGroupsInUseCondition(cluster, status, errors.Wrap(inUse, "failed to delete network")) {
+		t.Fatalf("expected the security groups to be in use")
+	}
+	if len(status.Conditions) != 1 || status.Conditions[0].Type != v1alpha1.SecurityGroupsInUse || status.Conditions[0].Status != corev1.ConditionTrue {
+		t.Fatalf("expected a true %s condition, got %+v", v1alpha1.SecurityGroupsInUse, status.Conditions)
+	}
+	if !strings.Contains(status.Conditions[0].Message, `"sg-2" (other)`) {
+		t.Fatalf("expected the condition message to name the blocker, got %q", status.Conditions[0].Message)
+	}
+
+	// The condition is cleared once the network is deleted.
+	if setSecurityGroupsInUseCondition(cluster, status, nil) {
+		t.Fatalf("expected the security groups not to be in use")
+	}
+	if len(status.Conditions) != 1 || status.Conditions[0].Status != corev1.ConditionFalse {
+		t.Fatalf("expected a false %s condition, got %+v", v1alpha1.SecurityGroupsInUse, status.Conditions)
+	}
+}
@@ -0,0 +1,157 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/actuators"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/autoscaling"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/awserrors"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/drain"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/record"
+)
+
+// lifecycleHookVisibilityTimeout is how long, in seconds, received lifecycle
+// hook notifications are hidden from the queue. It is shorter than
+// machinePoolsRequeueAfter, so that the notifications of nodes still being
+// drained are received again by the next reconciliation.
+const lifecycleHookVisibilityTimeout = 30
+
+// reconcileLifecycleHooks handles the notifications of the termination
+// lifecycle hooks of the machine pools: the node of each terminating
+// instance is cordoned, drained and deleted before its lifecycle action is
+// completed, and the heartbeat of the action is recorded meanwhile so that
+// the group keeps waiting for the drain.
+func (a *Actuator) reconcileLifecycleHooks(scope *actuators.Scope) error {
+	queueURL := scope.ClusterConfig.LifecycleHookQueueURL
+	if queueURL == "" {
+		return nil
+	}
+
+	messages, err := scope.Queue.ReceiveMessages(queueURL, lifecycleHookVisibilityTimeout)
+	if err != nil {
+		return err
+	}
+
+	pools := sets.NewString()
+	for _, pool := range scope.ClusterConfig.MachinePools {
+		pools.Insert(pool.AutoScalingGroupName)
+	}
+
+	var client kubernetes.Interface
+	var nodes []corev1.Node
+	for _, message := range messages {
+		action, err := autoscaling.ParseLifecycleAction(message.Body)
+		if err != nil {
+			record.Warnf(scope.Cluster, "InvalidLifecycleHookNotification", "Deleting invalid message %q from queue %q: %v", message.ID, queueURL, err)
+		}
+
+		// Invalid and test notifications are dropped.
+		if action == nil {
+			if err := scope.Queue.DeleteMessage(queueURL, message.ReceiptHandle); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if !pools.Has(action.Group) || action.Transition != autoscaling.LifecycleTransitionTerminating {
+			klog.V(2).Infof("Ignoring %s lifecycle hook notification for instance %q of group %q", action.Transition, action.InstanceID, action.Group)
+			continue
+		}
+
+		if client == nil {
+			if client, err = a.WorkloadClient(scope); err != nil {
+				return errors.Wrapf(err, "failed to create client for cluster %q", scope.Name())
+			}
+
+			list, err := client.CoreV1().Nodes().List(metav1.ListOptions{})
+			if err != nil {
+				return errors.Wrapf(err, "failed to list the nodes of cluster %q", scope.Name())
+			}
+			nodes = list.Items
+		}
+
+		removed, err := drainTerminatingNode(client, nodes, action.InstanceID)
+		if err != nil {
+			return err
+		}
+
+		if removed {
+			err = scope.AutoScaling.CompleteLifecycleAction(action, autoscaling.LifecycleActionContinue)
+		} else {
+			err = scope.AutoScaling.RecordLifecycleActionHeartbeat(action)
+		}
+
+		switch {
+		case lifecycleActionGone(err):
+			// The action was completed, or timed out, and the instance
+			// is terminated regardless of its node.
+			klog.Infof("Lifecycle hook %q of instance %q is no longer pending: %v", action.Hook, action.InstanceID, err)
+		case err != nil:
+			return err
+		case !removed:
+			klog.Infof("Waiting for node of instance %q of group %q to be drained", action.InstanceID, action.Group)
+			continue
+		default:
+			record.Eventf(scope.Cluster, "CompletedLifecycleAction", "Completed the termination of instance %q of group %q after draining its node", action.InstanceID, action.Group)
+		}
+
+		if err := scope.Queue.DeleteMessage(queueURL, message.ReceiptHandle); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// drainTerminatingNode cordons and drains the node of an instance being
+// terminated, and deletes it once drained. It returns true once the instance
+// has no node left.
+func drainTerminatingNode(client kubernetes.Interface, nodes []corev1.Node, instanceID string) (bool, error) {
+	node := poolNode(nodes, instanceID)
+	if node == nil {
+		return true, nil
+	}
+
+	if err := drain.Cordon(client, node, true); err != nil {
+		return false, err
+	}
+
+	drained, err := drain.Drain(client, node.Name)
+	if err != nil || !drained {
+		return false, err
+	}
+
+	if err := client.CoreV1().Nodes().Delete(node.Name, &metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+		return false, errors.Wrapf(err, "failed to delete node %q", node.Name)
+	}
+
+	return true, nil
+}
+
+// lifecycleActionGone returns true if an error reports that a lifecycle
+// action is no longer pending.
+func lifecycleActionGone(err error) bool {
+	code, ok := awserrors.Code(errors.Cause(err))
+	return ok && code == awserrors.ValidationError
+}
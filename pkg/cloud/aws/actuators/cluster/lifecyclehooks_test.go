@@ -0,0 +1,37 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/pkg/errors"
+)
+
+func TestLifecycleActionGone(t *testing.T) {
+	gone := awserr.New("ValidationError", "No active Lifecycle Action found with instance ID i-1", nil)
+	if !lifecycleActionGone(errors.Wrap(gone, "failed to complete lifecycle hook")) {
+		t.Fatalf("expected %v to report a lifecycle action no longer pending", gone)
+	}
+
+	for _, err := range []error{nil, errors.New("timeout"), awserr.New("Throttling", "Rate exceeded", nil)} {
+		if lifecycleActionGone(err) {
+			t.Fatalf("did not expect %v to report a lifecycle action no longer pending", err)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/actuators"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/awserrors"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/record"
+	clusterv1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+)
+
+// setPhase records the phase reached by the cluster, along with the error
+// blocking its progress, if any. Errors that retrying cannot fix move the
+// cluster to the Failed phase.
+func setPhase(status *v1alpha1.AWSClusterProviderStatus, phase v1alpha1.ClusterPhase, err error) {
+	status.Phase = phase
+	status.PhaseMessage = ""
+
+	if err == nil {
+		return
+	}
+
+	if requiresIntervention(err) {
+		status.Phase = v1alpha1.ClusterPhaseFailed
+	}
+	status.PhaseMessage = err.Error()
+}
+
+// failValidation moves a cluster whose configuration is invalid to the Failed
+// phase, and records a warning event with the given reason. The returned
+// error describes the invalid subject of the configuration.
+func failValidation(scope *actuators.Scope, reason, subject string, err error) error {
+	scope.ClusterStatus.Phase = v1alpha1.ClusterPhaseFailed
+	scope.ClusterStatus.PhaseMessage = err.Error()
+	record.Warnf(scope.Cluster, reason, "Invalid %s: %v", subject, err)
+	return errors.Wrapf(err, "invalid %s for cluster %q", subject, scope.Name())
+}
+
+// requiresIntervention returns true for AWS errors caused by missing
+// permissions or exhausted quotas.
+func requiresIntervention(err error) bool {
+	code, ok := awserrors.Code(errors.Cause(err))
+	if !ok {
+		return false
+	}
+	return code == awserrors.AuthFailure || code == "UnauthorizedOperation" || strings.HasSuffix(code, "LimitExceeded")
+}
+
+// controlPlaneReady returns true if a control plane machine has joined the cluster.
+func controlPlaneReady(machines []clusterv1.Machine) bool {
+	for _, m := range machines {
+		if m.Spec.Versions.ControlPlane != "" && m.Status.NodeRef != nil {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,195 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/actuators"
+	clusterv1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+	client "sigs.k8s.io/cluster-api/pkg/client/clientset_generated/clientset/typed/cluster/v1alpha1"
+)
+
+func TestSetPhase(t *testing.T) {
+	testCases := []struct {
+		name            string
+		phase           v1alpha1.ClusterPhase
+		err             error
+		expectedPhase   v1alpha1.ClusterPhase
+		expectedMessage string
+	}{
+		{
+			name:          "no error",
+			phase:         v1alpha1.ClusterPhaseControlPlaneReady,
+			expectedPhase: v1alpha1.ClusterPhaseControlPlaneReady,
+		},
+		{
+			name:            "transient error",
+			phase:           v1alpha1.ClusterPhaseNetworkProvisioning,
+			err:             errors.New("nat gateway is pending"),
+			expectedPhase:   v1alpha1.ClusterPhaseNetworkProvisioning,
+			expectedMessage: "nat gateway is pending",
+		},
+		{
+			name:            "exhausted quota",
+			phase:           v1alpha1.ClusterPhaseNetworkProvisioning,
+			err:             errors.Wrap(awserr.New("VpcLimitExceeded", "too many vpcs", nil), "failed to create vpc"),
+			expectedPhase:   v1alpha1.ClusterPhaseFailed,
+			expectedMessage: "failed to create vpc: VpcLimitExceeded: too many vpcs",
+		},
+		{
+			name:            "missing permissions",
+			phase:           v1alpha1.ClusterPhaseControlPlaneProvisioning,
+			err:             awserr.New("UnauthorizedOperation", "not allowed", nil),
+			expectedPhase:   v1alpha1.ClusterPhaseFailed,
+			expectedMessage: "UnauthorizedOperation: not allowed",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			status := &v1alpha1.AWSClusterProviderStatus{PhaseMessage: "previous error"}
+			setPhase(status, tc.phase, tc.err)
+
+			if status.Phase != tc.expectedPhase {
+				t.Fatalf("expected phase %q, got %q", tc.expectedPhase, status.Phase)
+			}
+
+			if status.PhaseMessage != tc.expectedMessage {
+				t.Fatalf("expected message %q, got %q", tc.expectedMessage, status.PhaseMessage)
+			}
+		})
+	}
+}
+
+func TestFailValidation(t *testing.T) {
+	cluster := &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"}}
+	scope, err := actuators.NewScope(actuators.ScopeParams{Cluster: cluster})
+	if err != nil {
+		t.Fatalf("failed to create scope: %v", err)
+	}
+
+	err = failValidation(scope, "InvalidNTPServers", "NTP servers", errors.New("not a host name"))
+	if expected := `invalid NTP servers for cluster "test-cluster": not a host name`; err == nil || err.Error() != expected {
+		t.Fatalf("expected error %q, got %v", expected, err)
+	}
+
+	if scope.ClusterStatus.Phase != v1alpha1.ClusterPhaseFailed || scope.ClusterStatus.PhaseMessage != "not a host name" {
+		t.Fatalf("expected the cluster to be failed, got phase %q: %q", scope.ClusterStatus.Phase, scope.ClusterStatus.PhaseMessage)
+	}
+}
+
+func TestControlPlaneReady(t *testing.T) {
+	controlPlane := clusterv1.Machine{Spec: clusterv1.MachineSpec{Versions: clusterv1.MachineVersionInfo{ControlPlane: "1.13.0"}}}
+	joined := controlPlane
+	joined.Status.NodeRef = &corev1.ObjectReference{Name: "ip-10-0-0-1"}
+	node := clusterv1.Machine{Status: clusterv1.MachineStatus{NodeRef: &corev1.ObjectReference{Name: "ip-10-0-0-2"}}}
+
+	if controlPlaneReady([]clusterv1.Machine{controlPlane, node}) {
+		t.Fatalf("did not expect the control plane to be ready before a control plane machine joined")
+	}
+
+	if !controlPlaneReady([]clusterv1.Machine{joined, node}) {
+		t.Fatalf("expected the control plane to be ready once a control plane machine joined")
+	}
+}
+
+func TestUpdateControlPlanePhase(t *testing.T) {
+	controlPlane := func(cluster string, joined bool) clusterv1.Machine {
+		m := clusterv1.Machine{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "controlplane-" + cluster,
+				Namespace: "default",
+				Labels:    map[string]string{actuators.ClusterNameLabel: cluster},
+			},
+			Spec: clusterv1.MachineSpec{Versions: clusterv1.MachineVersionInfo{ControlPlane: "1.13.0"}},
+		}
+		if joined {
+			m.Status.NodeRef = &corev1.ObjectReference{Name: "ip-10-0-0-1"}
+		}
+		return m
+	}
+
+	testCases := []struct {
+		name          string
+		machines      []clusterv1.Machine
+		expectedPhase v1alpha1.ClusterPhase
+	}{
+		{
+			name:          "control plane machine of the cluster joined",
+			machines:      []clusterv1.Machine{controlPlane("test-cluster", true)},
+			expectedPhase: v1alpha1.ClusterPhaseControlPlaneReady,
+		},
+		{
+			name:          "only a control plane machine of another cluster joined",
+			machines:      []clusterv1.Machine{controlPlane("test-cluster", false), controlPlane("other-cluster", true)},
+			expectedPhase: v1alpha1.ClusterPhaseControlPlaneProvisioning,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cluster := &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test-cluster", Namespace: "default"}}
+			scope, err := actuators.NewScope(actuators.ScopeParams{Cluster: cluster})
+			if err != nil {
+				t.Fatalf("failed to create scope: %v", err)
+			}
+
+			a := NewActuator(ActuatorParams{Client: &fakeClusterClient{machines: tc.machines}})
+			if err := a.updateControlPlanePhase(scope); err != nil {
+				t.Fatalf("did not expect error: %v", err)
+			}
+
+			if scope.ClusterStatus.Phase != tc.expectedPhase {
+				t.Fatalf("expected phase %q, got %q", tc.expectedPhase, scope.ClusterStatus.Phase)
+			}
+		})
+	}
+}
+
+// fakeClusterClient lists machines from memory, honoring label selectors.
+type fakeClusterClient struct {
+	client.ClusterV1alpha1Interface
+	client.MachineInterface
+
+	machines []clusterv1.Machine
+}
+
+func (f *fakeClusterClient) Machines(namespace string) client.MachineInterface {
+	return f
+}
+
+func (f *fakeClusterClient) List(opts metav1.ListOptions) (*clusterv1.MachineList, error) {
+	selector, err := labels.Parse(opts.LabelSelector)
+	if err != nil {
+		return nil, err
+	}
+
+	list := &clusterv1.MachineList{}
+	for _, m := range f.machines {
+		if selector.Matches(labels.Set(m.Labels)) {
+			list.Items = append(list.Items, m)
+		}
+	}
+	return list, nil
+}
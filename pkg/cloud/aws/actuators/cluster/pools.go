@@ -0,0 +1,75 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/actuators"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services"
+	controllerError "sigs.k8s.io/cluster-api/pkg/controller/error"
+)
+
+// machinePoolsRequeueAfter is how often clusters with machine pools are
+// reconciled, as their pools scale outside of the provider.
+const machinePoolsRequeueAfter = time.Minute
+
+// reconcileMachinePools coordinates the machine pools of a cluster whose
+// control plane is ready with the cluster.
+func (a *Actuator) reconcileMachinePools(scope *actuators.Scope, ec2svc services.EC2ClusterInterface) error {
+	if len(scope.ClusterConfig.MachinePools) == 0 {
+		return nil
+	}
+
+	if err := scope.Converge("termination-handler", func() error { return a.reconcileTerminationHandler(scope, ec2svc) }); err != nil {
+		return errors.Errorf("unable to reconcile node termination handler: %+v", err)
+	}
+
+	if err := a.reconcileScaleInProtection(scope); err != nil {
+		return errors.Errorf("unable to reconcile scale in protection: %+v", err)
+	}
+
+	if err := scope.Converge("scaling-schedules", func() error { return a.reconcileScalingSchedules(scope) }); err != nil {
+		return errors.Errorf("unable to reconcile scaling schedules: %+v", err)
+	}
+
+	if err := a.reconcileLifecycleHooks(scope); err != nil {
+		return errors.Errorf("unable to reconcile lifecycle hooks: %+v", err)
+	}
+
+	return &controllerError.RequeueAfterError{RequeueAfter: machinePoolsRequeueAfter}
+}
+
+// instanceIDOfNode returns the ID of the instance of a node, from its
+// provider ID of the form aws:///<zone>/<instance ID>.
+func instanceIDOfNode(node *corev1.Node) string {
+	return node.Spec.ProviderID[strings.LastIndex(node.Spec.ProviderID, "/")+1:]
+}
+
+// poolNode returns the node of an instance of a machine pool, or nil if the
+// instance has no node.
+func poolNode(nodes []corev1.Node, instanceID string) *corev1.Node {
+	for i := range nodes {
+		if nodes[i].Spec.ProviderID != "" && instanceIDOfNode(&nodes[i]) == instanceID {
+			return &nodes[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestPoolNode(t *testing.T) {
+	nodes := []corev1.Node{
+		{ObjectMeta: metav1.ObjectMeta{Name: "registering"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}, Spec: corev1.NodeSpec{ProviderID: "aws:///us-east-1a/i-1"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "node-12"}, Spec: corev1.NodeSpec{ProviderID: "aws:///us-east-1a/i-12"}},
+	}
+
+	if node := poolNode(nodes, "i-1"); node == nil || node.Name != "node-1" {
+		t.Fatalf("expected node-1, got %v", node)
+	}
+	if node := poolNode(nodes, "i-2"); node != nil {
+		t.Fatalf("expected no node, got %q", node.Name)
+	}
+	if node := poolNode(nodes, ""); node != nil {
+		t.Fatalf("expected no node for an empty instance ID, got %q", node.Name)
+	}
+}
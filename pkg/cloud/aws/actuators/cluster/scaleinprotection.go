@@ -0,0 +1,160 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"sort"
+
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/actuators"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/autoscaling"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/drain"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/record"
+)
+
+// reconcileScaleInProtection protects the instances of machine pools from
+// scale in while their node is being drained, so that the Auto Scaling group
+// does not terminate them with pods left to evict. The protection is lifted
+// once the node is drained or uncordoned. Instances protected outside of the
+// provider are left untouched.
+func (a *Actuator) reconcileScaleInProtection(scope *actuators.Scope) error {
+	names := make([]string, 0, len(scope.ClusterConfig.MachinePools))
+	for _, pool := range scope.ClusterConfig.MachinePools {
+		names = append(names, pool.AutoScalingGroupName)
+	}
+
+	groups, err := scope.AutoScaling.DescribeGroups(names)
+	if err != nil {
+		return errors.Wrap(err, "failed to describe the Auto Scaling groups of the machine pools")
+	}
+
+	draining, err := a.drainingPoolInstances(scope, groups)
+	if err != nil {
+		return err
+	}
+
+	protect, unprotect, protected := scaleInProtectionChanges(groups, draining, scope.ClusterStatus.ScaleInProtectedInstances)
+
+	for _, group := range sortedKeys(protect) {
+		if err := scope.AutoScaling.SetInstanceProtection(group, protect[group], true); err != nil {
+			return errors.Wrapf(err, "failed to protect instances %v of group %q from scale in", protect[group], group)
+		}
+		record.Eventf(scope.Cluster, "ProtectedFromScaleIn", "Protected draining instances %v of group %q from scale in", protect[group], group)
+	}
+
+	for _, group := range sortedKeys(unprotect) {
+		if err := scope.AutoScaling.SetInstanceProtection(group, unprotect[group], false); err != nil {
+			return errors.Wrapf(err, "failed to lift the scale in protection of instances %v of group %q", unprotect[group], group)
+		}
+		record.Eventf(scope.Cluster, "UnprotectedFromScaleIn", "Lifted the scale in protection of instances %v of group %q", unprotect[group], group)
+	}
+
+	scope.ClusterStatus.ScaleInProtectedInstances = protected
+	return nil
+}
+
+// drainingPoolInstances returns the in service instances of the groups whose
+// node is cordoned with pods left to evict.
+func (a *Actuator) drainingPoolInstances(scope *actuators.Scope, groups []*autoscaling.Group) (sets.String, error) {
+	draining := sets.NewString()
+
+	members := sets.NewString()
+	for _, group := range groups {
+		for _, instance := range group.Instances {
+			if instance.LifecycleState == autoscaling.LifecycleStateInService {
+				members.Insert(instance.ID)
+			}
+		}
+	}
+
+	if members.Len() == 0 {
+		return draining, nil
+	}
+
+	client, err := a.WorkloadClient(scope)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create client for cluster %q", scope.Name())
+	}
+
+	nodes, err := client.CoreV1().Nodes().List(metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list the nodes of cluster %q", scope.Name())
+	}
+
+	for _, node := range nodes.Items {
+		id := instanceIDOfNode(&node)
+		if !node.Spec.Unschedulable || !members.Has(id) {
+			continue
+		}
+
+		pods, err := drain.Pending(client, node.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(pods) > 0 {
+			draining.Insert(id)
+		}
+	}
+
+	return draining, nil
+}
+
+// scaleInProtectionChanges returns, per group, the draining instances to
+// protect from scale in and the instances protected by the provider whose
+// protection must be lifted, along with the instances protected by the
+// provider once applied. Instances that left their group are forgotten.
+func scaleInProtectionChanges(groups []*autoscaling.Group, draining sets.String, recorded []string) (map[string][]string, map[string][]string, []string) {
+	protect := map[string][]string{}
+	unprotect := map[string][]string{}
+	previous := sets.NewString(recorded...)
+	protected := sets.NewString()
+
+	for _, group := range groups {
+		for _, instance := range group.Instances {
+			switch {
+			case draining.Has(instance.ID) && !instance.ProtectedFromScaleIn:
+				protect[group.Name] = append(protect[group.Name], instance.ID)
+				protected.Insert(instance.ID)
+
+			case draining.Has(instance.ID) && previous.Has(instance.ID):
+				protected.Insert(instance.ID)
+
+			case previous.Has(instance.ID) && instance.ProtectedFromScaleIn:
+				unprotect[group.Name] = append(unprotect[group.Name], instance.ID)
+			}
+		}
+	}
+
+	if protected.Len() == 0 {
+		return protect, unprotect, nil
+	}
+
+	return protect, unprotect, protected.List()
+}
+
+// sortedKeys returns the keys of a map in order.
+func sortedKeys(m map[string][]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
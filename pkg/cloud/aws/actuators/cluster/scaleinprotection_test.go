@@ -0,0 +1,64 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/autoscaling"
+)
+
+func TestScaleInProtectionChanges(t *testing.T) {
+	groups := []*autoscaling.Group{
+		{
+			Name: "pool-a",
+			Instances: []autoscaling.Instance{
+				{ID: "i-draining"},
+				{ID: "i-still-draining", ProtectedFromScaleIn: true},
+				{ID: "i-drained", ProtectedFromScaleIn: true},
+				{ID: "i-user", ProtectedFromScaleIn: true},
+			},
+		},
+		{
+			Name: "pool-b",
+			Instances: []autoscaling.Instance{
+				{ID: "i-user-draining", ProtectedFromScaleIn: true},
+				{ID: "i-unprotected"},
+			},
+		},
+	}
+	draining := sets.NewString("i-draining", "i-still-draining", "i-user-draining")
+	recorded := []string{"i-still-draining", "i-drained", "i-unprotected", "i-gone"}
+
+	protect, unprotect, protected := scaleInProtectionChanges(groups, draining, recorded)
+
+	if expected := map[string][]string{"pool-a": {"i-draining"}}; !reflect.DeepEqual(protect, expected) {
+		t.Fatalf("expected to protect %v, got %v", expected, protect)
+	}
+	if expected := map[string][]string{"pool-a": {"i-drained"}}; !reflect.DeepEqual(unprotect, expected) {
+		t.Fatalf("expected to unprotect %v, got %v", expected, unprotect)
+	}
+	if expected := []string{"i-draining", "i-still-draining"}; !reflect.DeepEqual(protected, expected) {
+		t.Fatalf("expected the provider to protect %v, got %v", expected, protected)
+	}
+
+	if _, _, protected := scaleInProtectionChanges(groups, sets.NewString(), nil); protected != nil {
+		t.Fatalf("expected no protected instance, got %v", protected)
+	}
+}
@@ -0,0 +1,107 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"reflect"
+	"strings"
+
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/actuators"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/autoscaling"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/record"
+)
+
+// scheduledActionPrefix prefixes the names of the scheduled actions created
+// for the schedules of machine pools, to tell them from the scheduled actions
+// created outside of the provider.
+const scheduledActionPrefix = "cluster-api-provider-aws-"
+
+// reconcileScalingSchedules converges the scheduled actions of the groups of
+// the machine pools with the schedules of the pools.
+func (a *Actuator) reconcileScalingSchedules(scope *actuators.Scope) error {
+	for _, pool := range scope.ClusterConfig.MachinePools {
+		group := pool.AutoScalingGroupName
+
+		current, err := scope.AutoScaling.DescribeScheduledActions(group)
+		if err != nil {
+			return err
+		}
+
+		put, remove := scheduledActionChanges(scheduledActions(pool.Schedules), current)
+
+		for _, action := range put {
+			if err := scope.AutoScaling.PutScheduledAction(group, action); err != nil {
+				return err
+			}
+			record.Eventf(scope.Cluster, "PutScheduledAction", "Scheduled action %q of group %q to run on %q", action.Name, group, action.Recurrence)
+		}
+
+		for _, name := range remove {
+			if err := scope.AutoScaling.DeleteScheduledAction(group, name); err != nil {
+				return err
+			}
+			record.Eventf(scope.Cluster, "DeletedScheduledAction", "Deleted scheduled action %q of group %q", name, group)
+		}
+	}
+
+	return nil
+}
+
+// scheduledActions returns the scheduled actions of the schedules of a pool.
+func scheduledActions(schedules []v1alpha1.ScalingSchedule) []*autoscaling.ScheduledAction {
+	actions := make([]*autoscaling.ScheduledAction, 0, len(schedules))
+	for _, schedule := range schedules {
+		actions = append(actions, &autoscaling.ScheduledAction{
+			Name:            scheduledActionPrefix + schedule.Name,
+			Recurrence:      schedule.Recurrence,
+			TimeZone:        schedule.TimeZone,
+			MinSize:         schedule.MinSize,
+			MaxSize:         schedule.MaxSize,
+			DesiredCapacity: schedule.DesiredCapacity,
+		})
+	}
+	return actions
+}
+
+// scheduledActionChanges returns the desired actions missing or differing
+// from the current actions of a group, and the names of the current actions
+// created by the provider which are no longer desired.
+func scheduledActionChanges(desired, current []*autoscaling.ScheduledAction) ([]*autoscaling.ScheduledAction, []string) {
+	existing := map[string]*autoscaling.ScheduledAction{}
+	for _, action := range current {
+		existing[action.Name] = action
+	}
+
+	var put []*autoscaling.ScheduledAction
+	wanted := map[string]bool{}
+	for _, action := range desired {
+		wanted[action.Name] = true
+		if !reflect.DeepEqual(existing[action.Name], action) {
+			put = append(put, action)
+		}
+	}
+
+	var remove []string
+	for _, action := range current {
+		if strings.HasPrefix(action.Name, scheduledActionPrefix) && !wanted[action.Name] {
+			remove = append(remove, action.Name)
+		}
+	}
+
+	return put, remove
+}
@@ -0,0 +1,57 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/autoscaling"
+)
+
+func TestScheduledActionChanges(t *testing.T) {
+	desired := scheduledActions([]v1alpha1.ScalingSchedule{
+		{Name: "day", Recurrence: "0 8 * * 1-5", TimeZone: "Europe/Paris", MinSize: aws.Int64(3)},
+		{Name: "night", Recurrence: "0 20 * * *", DesiredCapacity: aws.Int64(0)},
+		{Name: "weekend", Recurrence: "0 0 * * 6", MaxSize: aws.Int64(1)},
+	})
+	current := []*autoscaling.ScheduledAction{
+		{Name: "cluster-api-provider-aws-day", Recurrence: "0 8 * * 1-5", TimeZone: "Europe/Paris", MinSize: aws.Int64(3)},
+		{Name: "cluster-api-provider-aws-night", Recurrence: "0 20 * * *", DesiredCapacity: aws.Int64(1)},
+		{Name: "cluster-api-provider-aws-removed", Recurrence: "0 12 * * *", DesiredCapacity: aws.Int64(2)},
+		{Name: "external", Recurrence: "0 6 * * *", DesiredCapacity: aws.Int64(4)},
+	}
+
+	put, remove := scheduledActionChanges(desired, current)
+
+	expectedPut := []*autoscaling.ScheduledAction{
+		{Name: "cluster-api-provider-aws-night", Recurrence: "0 20 * * *", DesiredCapacity: aws.Int64(0)},
+		{Name: "cluster-api-provider-aws-weekend", Recurrence: "0 0 * * 6", MaxSize: aws.Int64(1)},
+	}
+	if !reflect.DeepEqual(put, expectedPut) {
+		t.Fatalf("expected to put %+v, got %+v", expectedPut, put)
+	}
+	if expected := []string{"cluster-api-provider-aws-removed"}; !reflect.DeepEqual(remove, expected) {
+		t.Fatalf("expected to delete %v, got %v", expected, remove)
+	}
+
+	if put, remove := scheduledActionChanges(desired, append(current[:1], expectedPut...)); put != nil || remove != nil {
+		t.Fatalf("expected no change once converged, got %+v, %v", put, remove)
+	}
+}
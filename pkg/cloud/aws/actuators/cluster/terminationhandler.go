@@ -0,0 +1,204 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"reflect"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/actuators"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/record"
+)
+
+// terminationHandlerName is the name of the daemon set, service account and
+// RBAC objects of the node termination handler.
+const terminationHandlerName = "aws-node-termination-handler"
+
+// reconcileTerminationHandler deploys the node termination handler into the
+// cluster once any of its machine pools runs spot instances. The handler is
+// left in place afterwards, as pools scaled to zero keep using spot.
+func (a *Actuator) reconcileTerminationHandler(scope *actuators.Scope, ec2svc services.EC2ClusterInterface) error {
+	handler := scope.ClusterConfig.TerminationHandler
+	if handler == nil {
+		return nil
+	}
+
+	pools, err := ec2svc.SpotMachinePools()
+	if err != nil {
+		return err
+	}
+
+	if len(pools) == 0 {
+		return nil
+	}
+
+	client, err := a.WorkloadClient(scope)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create client for cluster %q", scope.Name())
+	}
+
+	if err := ensureTerminationHandlerRBAC(client); err != nil {
+		return err
+	}
+
+	desired := terminationHandlerDaemonSet(handler)
+	daemonSets := client.AppsV1().DaemonSets(metav1.NamespaceSystem)
+	current, err := daemonSets.Get(terminationHandlerName, metav1.GetOptions{})
+	switch {
+	case apierrors.IsNotFound(err):
+		if _, err := daemonSets.Create(desired); err != nil {
+			return errors.Wrap(err, "failed to create the node termination handler")
+		}
+		record.Eventf(scope.Cluster, "DeployedTerminationHandler", "Deployed the node termination handler for the spot instances of machine pools %v", pools)
+		return nil
+
+	case err != nil:
+		return errors.Wrap(err, "failed to get the node termination handler")
+	}
+
+	if reflect.DeepEqual(current.Spec.Template.Spec.Containers[0].Image, desired.Spec.Template.Spec.Containers[0].Image) &&
+		reflect.DeepEqual(current.Spec.Template.Spec.NodeSelector, desired.Spec.Template.Spec.NodeSelector) {
+		return nil
+	}
+
+	current.Spec.Template.Spec.Containers[0].Image = desired.Spec.Template.Spec.Containers[0].Image
+	current.Spec.Template.Spec.NodeSelector = desired.Spec.Template.Spec.NodeSelector
+	if _, err := daemonSets.Update(current); err != nil {
+		return errors.Wrap(err, "failed to update the node termination handler")
+	}
+
+	klog.Infof("Updated the node termination handler of cluster %q", scope.Name())
+	return nil
+}
+
+// ensureTerminationHandlerRBAC creates the service account of the node
+// termination handler, and grants it the permissions to cordon and drain
+// nodes.
+func ensureTerminationHandlerRBAC(client kubernetes.Interface) error {
+	meta := metav1.ObjectMeta{Name: terminationHandlerName, Namespace: metav1.NamespaceSystem}
+
+	_, err := client.CoreV1().ServiceAccounts(metav1.NamespaceSystem).Create(&corev1.ServiceAccount{ObjectMeta: meta})
+	if err != nil && !apierrors.IsAlreadyExists(err) {
+		return errors.Wrap(err, "failed to create the service account of the node termination handler")
+	}
+
+	_, err = client.RbacV1().ClusterRoles().Create(&rbacv1.ClusterRole{
+		ObjectMeta: metav1.ObjectMeta{Name: terminationHandlerName},
+		Rules: []rbacv1.PolicyRule{
+			{APIGroups: []string{""}, Resources: []string{"nodes"}, Verbs: []string{"get", "list", "patch", "update"}},
+			{APIGroups: []string{""}, Resources: []string{"pods"}, Verbs: []string{"get", "list"}},
+			{APIGroups: []string{""}, Resources: []string{"pods/eviction"}, Verbs: []string{"create"}},
+			{APIGroups: []string{""}, Resources: []string{"events"}, Verbs: []string{"create"}},
+			{APIGroups: []string{"apps", "extensions"}, Resources: []string{"daemonsets"}, Verbs: []string{"get"}},
+		},
+	})
+	if err != nil && !apierrors.IsAlreadyExists(err) {
+		return errors.Wrap(err, "failed to create the cluster role of the node termination handler")
+	}
+
+	_, err = client.RbacV1().ClusterRoleBindings().Create(&rbacv1.ClusterRoleBinding{
+		ObjectMeta: metav1.ObjectMeta{Name: terminationHandlerName},
+		RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: terminationHandlerName},
+		Subjects:   []rbacv1.Subject{{Kind: rbacv1.ServiceAccountKind, Name: terminationHandlerName, Namespace: metav1.NamespaceSystem}},
+	})
+	if err != nil && !apierrors.IsAlreadyExists(err) {
+		return errors.Wrap(err, "failed to create the cluster role binding of the node termination handler")
+	}
+
+	return nil
+}
+
+// terminationHandlerDaemonSet returns the daemon set of the node termination
+// handler, which reads the spot interruption notices and scheduled events of
+// its node from the instance metadata, so it needs neither AWS credentials
+// nor a queue.
+func terminationHandlerDaemonSet(handler *v1alpha1.TerminationHandler) *appsv1.DaemonSet {
+	image := handler.Image
+	if image == "" {
+		image = v1alpha1.DefaultTerminationHandlerImage
+	}
+
+	nodeSelector := handler.NodeSelector
+	if len(nodeSelector) == 0 {
+		nodeSelector = map[string]string{"beta.kubernetes.io/os": "linux"}
+	}
+
+	labels := map[string]string{"app.kubernetes.io/name": terminationHandlerName}
+	fieldEnv := func(name, path string) corev1.EnvVar {
+		return corev1.EnvVar{Name: name, ValueFrom: &corev1.EnvVarSource{FieldRef: &corev1.ObjectFieldSelector{FieldPath: path}}}
+	}
+
+	return &appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      terminationHandlerName,
+			Namespace: metav1.NamespaceSystem,
+			Labels:    labels,
+		},
+		Spec: appsv1.DaemonSetSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: labels},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec: corev1.PodSpec{
+					ServiceAccountName: terminationHandlerName,
+					HostNetwork:        true,
+					PriorityClassName:  "system-node-critical",
+					NodeSelector:       nodeSelector,
+					Tolerations:        []corev1.Toleration{{Operator: corev1.TolerationOpExists}},
+					Containers: []corev1.Container{{
+						Name:  terminationHandlerName,
+						Image: image,
+						Env: []corev1.EnvVar{
+							fieldEnv("NODE_NAME", "spec.nodeName"),
+							fieldEnv("POD_NAME", "metadata.name"),
+							fieldEnv("NAMESPACE", "metadata.namespace"),
+							{Name: "ENABLE_SPOT_INTERRUPTION_DRAINING", Value: "true"},
+							{Name: "ENABLE_SCHEDULED_EVENT_DRAINING", Value: "true"},
+							{Name: "DELETE_LOCAL_DATA", Value: "true"},
+							{Name: "IGNORE_DAEMON_SETS", Value: "true"},
+						},
+						Resources: corev1.ResourceRequirements{
+							Requests: corev1.ResourceList{
+								corev1.ResourceCPU:    resource.MustParse("50m"),
+								corev1.ResourceMemory: resource.MustParse("64Mi"),
+							},
+							Limits: corev1.ResourceList{
+								corev1.ResourceMemory: resource.MustParse("128Mi"),
+							},
+						},
+						SecurityContext: &corev1.SecurityContext{
+							RunAsUser:                aws.Int64(1000),
+							RunAsNonRoot:             aws.Bool(true),
+							ReadOnlyRootFilesystem:   aws.Bool(true),
+							AllowPrivilegeEscalation: aws.Bool(false),
+						},
+					}},
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,55 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"reflect"
+	"testing"
+
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+)
+
+func TestTerminationHandlerDaemonSet(t *testing.T) {
+	ds := terminationHandlerDaemonSet(&v1alpha1.TerminationHandler{})
+	spec := ds.Spec.Template.Spec
+
+	if image := spec.Containers[0].Image; image != v1alpha1.DefaultTerminationHandlerImage {
+		t.Fatalf("expected the default image, got %q", image)
+	}
+	if !reflect.DeepEqual(spec.NodeSelector, map[string]string{"beta.kubernetes.io/os": "linux"}) {
+		t.Fatalf("expected the handler to run on every Linux node, got %v", spec.NodeSelector)
+	}
+	if !reflect.DeepEqual(ds.Spec.Selector.MatchLabels, ds.Spec.Template.Labels) {
+		t.Fatalf("expected the selector %v to match the pods labels %v", ds.Spec.Selector.MatchLabels, ds.Spec.Template.Labels)
+	}
+	if spec.ServiceAccountName != terminationHandlerName || !spec.HostNetwork {
+		t.Fatalf("expected the handler to use its service account and the host network, got %q, %t", spec.ServiceAccountName, spec.HostNetwork)
+	}
+
+	ds = terminationHandlerDaemonSet(&v1alpha1.TerminationHandler{
+		Image:        "registry.example.com/nth:v1",
+		NodeSelector: map[string]string{"node.kubernetes.io/lifecycle": "spot"},
+	})
+	spec = ds.Spec.Template.Spec
+
+	if image := spec.Containers[0].Image; image != "registry.example.com/nth:v1" {
+		t.Fatalf("expected the configured image, got %q", image)
+	}
+	if !reflect.DeepEqual(spec.NodeSelector, map[string]string{"node.kubernetes.io/lifecycle": "spot"}) {
+		t.Fatalf("expected the configured node selector, got %v", spec.NodeSelector)
+	}
+}
@@ -0,0 +1,57 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actuators
+
+import (
+	"fmt"
+	"time"
+
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/metrics"
+)
+
+// Converge runs the reconciliation of a cluster component, e.g. "vpc", and
+// records how long the component takes to converge across reconciliations.
+func (s *Scope) Converge(component string, reconcile func() error) error {
+	start := time.Now()
+	err := reconcile()
+	metrics.Observe(s.metricsKey(), component, start, err)
+	return err
+}
+
+// ForgetConvergence drops the convergence times recorded for the cluster.
+func (s *Scope) ForgetConvergence() {
+	metrics.Forget(s.metricsKey())
+}
+
+func (s *Scope) metricsKey() string {
+	return fmt.Sprintf("cluster/%s/%s", s.Namespace(), s.Name())
+}
+
+// ObserveConvergence records an attempt at converging a machine component,
+// e.g. "node-ready", measured from the creation of the machine.
+func (m *MachineScope) ObserveConvergence(component string, err error) {
+	metrics.Observe(m.metricsKey(), component, m.Machine.CreationTimestamp.Time, err)
+}
+
+// ForgetConvergence drops the convergence times recorded for the machine.
+func (m *MachineScope) ForgetConvergence() {
+	metrics.Forget(m.metricsKey())
+}
+
+func (m *MachineScope) metricsKey() string {
+	return fmt.Sprintf("machine/%s/%s", m.Namespace(), m.Name())
+}
@@ -0,0 +1,84 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actuators
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+)
+
+// fileParametersPrefix prefixes the paths of the SSM parameters holding the
+// content of the files of machines read from Secrets.
+const fileParametersPrefix = "/cluster-api-provider-aws/machine-files"
+
+// FileParametersPath returns the path of the SSM parameters holding the
+// content of the files of the machine read from Secrets.
+func (m *MachineScope) FileParametersPath() string {
+	return fmt.Sprintf("%s/%s/%s/%s", fileParametersPrefix, m.Namespace(), m.Scope.Name(), m.Name())
+}
+
+// FileSourceClient reads the Secrets and ConfigMaps holding the content of
+// the files of machines.
+type FileSourceClient interface {
+	corev1client.SecretsGetter
+	corev1client.ConfigMapsGetter
+}
+
+// FileContent returns the content of a file of the machine, read from its
+// Secret or ConfigMap in the namespace of the machine if any.
+func (m *MachineScope) FileContent(file v1alpha1.File) ([]byte, error) {
+	source := file.ContentFrom
+	if source == nil {
+		return []byte(file.Content), nil
+	}
+
+	if m.fileSources == nil {
+		return nil, errors.Errorf("cannot read the content of file %q without a client", file.Path)
+	}
+
+	switch {
+	case source.Secret != nil:
+		secret, err := m.fileSources.Secrets(m.Namespace()).Get(source.Secret.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get secret %q of file %q", source.Secret.Name, file.Path)
+		}
+		content, ok := secret.Data[source.Secret.Key]
+		if !ok {
+			return nil, errors.Errorf("secret %q of file %q has no key %q", source.Secret.Name, file.Path, source.Secret.Key)
+		}
+		return content, nil
+
+	case source.ConfigMap != nil:
+		cm, err := m.fileSources.ConfigMaps(m.Namespace()).Get(source.ConfigMap.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get config map %q of file %q", source.ConfigMap.Name, file.Path)
+		}
+		if content, ok := cm.Data[source.ConfigMap.Key]; ok {
+			return []byte(content), nil
+		}
+		if content, ok := cm.BinaryData[source.ConfigMap.Key]; ok {
+			return content, nil
+		}
+		return nil, errors.Errorf("config map %q of file %q has no key %q", source.ConfigMap.Name, file.Path, source.ConfigMap.Key)
+	}
+
+	return nil, errors.Errorf("file %q must read its content from a secret or config map", file.Path)
+}
@@ -20,22 +20,26 @@ package machine
 import (
 	"context"
 	"fmt"
+	"reflect"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/pkg/errors"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
 	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
-	"k8s.io/client-go/tools/clientcmd"
-	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
 	"k8s.io/klog"
 	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
 	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/actuators"
+	service "sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services"
 	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/awserrors"
-	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/ec2"
-	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/elb"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/compatibility"
 	"sigs.k8s.io/cluster-api-provider-aws/pkg/deployer"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/features"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/record"
 	"sigs.k8s.io/cluster-api-provider-aws/pkg/tokens"
+	"sigs.k8s.io/cluster-api/pkg/apis/cluster/common"
 	clusterv1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
 	client "sigs.k8s.io/cluster-api/pkg/client/clientset_generated/clientset/typed/cluster/v1alpha1"
 	controllerError "sigs.k8s.io/cluster-api/pkg/controller/error"
@@ -45,19 +49,55 @@ import (
 type Actuator struct {
 	*deployer.Deployer
 
-	client client.ClusterV1alpha1Interface
+	client        client.ClusterV1alpha1Interface
+	selector      labels.Selector
+	readOnly      bool
+	compatibility compatibility.Source
+	fileSources   actuators.FileSourceClient
+	services      service.Getter
 }
 
 // ActuatorParams holds parameter information for Actuator.
 type ActuatorParams struct {
 	Client client.ClusterV1alpha1Interface
+
+	// Selector, if set, restricts the actuator to the clusters whose labels it matches,
+	// and to their machines. Other clusters are left to other controllers.
+	Selector labels.Selector
+
+	// ReadOnly, if set, prevents the actuator from modifying AWS resources.
+	// Existing resources are still observed and reported in the status.
+	ReadOnly bool
+
+	// Compatibility, if set, overrides the compatibility matrix embedded in
+	// the controller, which machines are checked against before creation.
+	Compatibility compatibility.Source
+
+	// FileSources, if set, reads the Secrets and ConfigMaps holding the
+	// content of the files of machines. Machines with such files cannot be
+	// created without it.
+	FileSources actuators.FileSourceClient
+
+	// Getter returns the EC2 and ELB services of the machines.
+	// Defaults to services.DefaultGetter.
+	Getter service.Getter
 }
 
 // NewActuator returns an actuator.
 func NewActuator(params ActuatorParams) *Actuator {
+	getter := params.Getter
+	if getter == nil {
+		getter = service.DefaultGetter
+	}
+
 	return &Actuator{
-		Deployer: deployer.New(deployer.Params{ScopeGetter: actuators.DefaultScopeGetter}),
-		client:   params.Client,
+		Deployer:      deployer.New(deployer.Params{ServicesGetter: getter}),
+		client:        params.Client,
+		selector:      params.Selector,
+		readOnly:      params.ReadOnly,
+		compatibility: params.Compatibility,
+		fileSources:   params.FileSources,
+		services:      getter,
 	}
 }
 
@@ -92,7 +132,7 @@ func (a *Actuator) isNodeJoin(controlPlaneMachines []*clusterv1.Machine, newMach
 				return false, errors.Wrapf(err, "failed to create machine scope for machine %q", cm.Name)
 			}
 
-			ec2svc := ec2.NewService(m.Scope)
+			ec2svc := a.services.EC2(m.Scope)
 			contolPlaneExists, err = ec2svc.MachineExists(m)
 			if err != nil {
 				return false, errors.Wrapf(err, "failed to verify existence of machine %q", m.Name())
@@ -106,24 +146,61 @@ func (a *Actuator) isNodeJoin(controlPlaneMachines []*clusterv1.Machine, newMach
 		return contolPlaneExists, nil
 	default:
 		errMsg := fmt.Sprintf("Unknown value %q for label \"set\" on machine %q, skipping machine creation", newMachine.ObjectMeta.Labels["set"], newMachine.Name)
-		klog.Errorf(errMsg)
-		err := errors.Errorf(errMsg)
+		klog.Error(errMsg)
+		err := errors.New(errMsg)
 		return false, err
 	}
 }
 
 // Create creates a machine and is invoked by the machine controller.
 func (a *Actuator) Create(ctx context.Context, cluster *clusterv1.Cluster, machine *clusterv1.Machine) error {
+	if !actuators.Selects(a.selector, cluster) {
+		klog.V(2).Infof("Cluster %v is not selected by this controller, skipping machine %v", cluster.Name, machine.Name)
+		return nil
+	}
+
+	// Failed machines are not recreated, they are left for inspection or replacement.
+	if machine.Status.ErrorReason != nil {
+		klog.V(2).Infof("Machine %v has failed, skipping creation: %s", machine.Name, aws.StringValue(machine.Status.ErrorMessage))
+		return nil
+	}
+
+	if a.readOnly {
+		klog.Infof("Controller is read-only, skipping the creation of machine %v for cluster %v", machine.Name, cluster.Name)
+		return nil
+	}
+
 	klog.Infof("Creating machine %v for cluster %v", machine.Name, cluster.Name)
 
-	scope, err := actuators.NewMachineScope(actuators.MachineScopeParams{Machine: machine, Cluster: cluster, Client: a.client})
+	scope, err := actuators.NewMachineScope(actuators.MachineScopeParams{Machine: machine, Cluster: cluster, Client: a.client, FileSources: a.fileSources})
 	if err != nil {
 		return errors.Errorf("failed to create scope: %+v", err)
 	}
 
 	defer scope.Close()
 
-	ec2svc := ec2.NewService(scope.Scope)
+	if err := scope.VerifyAccount(machine, &scope.MachineStatus.AccountID, &scope.MachineStatus.Region); err != nil {
+		return errors.Wrapf(err, "refusing to create machine %q", machine.Name)
+	}
+
+	ec2svc := a.services.EC2(scope.Scope)
+
+	// Instances found by their tags are adopted, and not launched again.
+	if _, err := a.adoptInstance(ec2svc, scope); err != nil {
+		return errors.Errorf("failed to look up the instance of machine %q by its tags: %+v", machine.Name, err)
+	}
+
+	// Versions are only checked before the instance is launched, so that
+	// existing machines are not failed by a change of the matrix.
+	if aws.StringValue(scope.MachineStatus.InstanceID) == "" {
+		unsupported, err := a.ensureSupportedVersions(scope, newNotifier(scope.Scope))
+		if err != nil {
+			return errors.Errorf("failed to check the versions of machine %q: %+v", machine.Name, err)
+		}
+		if unsupported {
+			return nil
+		}
+	}
 
 	controlPlaneURL, err := a.GetIP(cluster, nil)
 	if err != nil {
@@ -142,7 +219,7 @@ func (a *Actuator) Create(ctx context.Context, cluster *clusterv1.Cluster, machi
 
 	var bootstrapToken string
 	if isNodeJoin {
-		bootstrapToken, err = a.getNodeJoinToken(cluster, controlPlaneURL)
+		bootstrapToken, err = a.getNodeJoinToken(scope.Scope, controlPlaneURL)
 		if err != nil {
 			return errors.Wrapf(err, "failed to obtain token for node %q to join cluster %q", machine.Name, cluster.Name)
 		}
@@ -153,8 +230,40 @@ func (a *Actuator) Create(ctx context.Context, cluster *clusterv1.Cluster, machi
 		return errors.Wrapf(err, "failed to retrieve kubeconfig while creating machine %q", machine.Name)
 	}
 
+	// Custom provisioning steps run before the instance is launched.
+	if aws.StringValue(scope.MachineStatus.InstanceID) == "" {
+		if err := runLifecycleHooks(scope, v1alpha1.LifecyclePreCreate, nil); err != nil {
+			return errors.Errorf("failed to run pre-create hooks: %+v", err)
+		}
+	}
+
+	zoneFailures := scope.ClusterStatus.DeepCopy().ZoneLaunchFailures
 	i, err := ec2svc.CreateOrGetMachine(scope, bootstrapToken, kubeConfig)
+	scope.ObserveConvergence("instance-running", err)
+
+	// Launch failures are tracked per availability zone in the cluster status,
+	// which is not persisted by the machine scope.
+	if !reflect.DeepEqual(zoneFailures, scope.ClusterStatus.ZoneLaunchFailures) {
+		scope.Scope.StoreStatus()
+	}
+
+	exhausted := setCapacityReservationCondition(machine, scope.MachineConfig, scope.MachineStatus, err)
+	subscriptionRequired := setMarketplaceSubscriptionCondition(machine, scope.MachineStatus, err)
+	setBurstableControlPlaneCondition(scope.InstanceTypes, machine, scope.MachineConfig, scope.MachineStatus)
+
 	if err != nil {
+		// Machines which cannot be launched as configured are failed
+		// rather than retried.
+		if awserrors.IsInvalidConfiguration(errors.Cause(err)) {
+			reason := common.InvalidConfigurationMachineError
+			message := err.Error()
+			machine.Status.ErrorReason = &reason
+			machine.Status.ErrorMessage = &message
+			record.Warn(machine, "InvalidConfiguration", message)
+			newNotifier(scope.Scope).notify(machine, machineFailed, "", message)
+			return nil
+		}
+
 		if awserrors.IsFailedDependency(errors.Cause(err)) {
 			klog.Errorf("network not ready to launch instances yet: %+v", err)
 			return &controllerError.RequeueAfterError{
@@ -162,9 +271,27 @@ func (a *Actuator) Create(ctx context.Context, cluster *clusterv1.Cluster, machi
 			}
 		}
 
+		if exhausted {
+			klog.Errorf("capacity reservation of machine %q exhausted: %+v", machine.Name, err)
+			return &controllerError.RequeueAfterError{
+				RequeueAfter: capacityReservationRequeueAfter,
+			}
+		}
+
+		if subscriptionRequired {
+			klog.Errorf("AMI of machine %q requires an AWS Marketplace subscription: %+v", machine.Name, err)
+			return &controllerError.RequeueAfterError{
+				RequeueAfter: marketplaceSubscriptionRequeueAfter,
+			}
+		}
+
 		return errors.Errorf("failed to create or get machine: %+v", err)
 	}
 
+	if aws.StringValue(scope.MachineStatus.InstanceID) != i.ID {
+		newNotifier(scope.Scope).notify(machine, machineCreated, i.ID, "")
+	}
+
 	scope.MachineStatus.InstanceID = &i.ID
 	scope.MachineStatus.InstanceState = aws.String(string(i.State))
 
@@ -174,25 +301,44 @@ func (a *Actuator) Create(ctx context.Context, cluster *clusterv1.Cluster, machi
 
 	machine.Annotations["cluster-api-provider-aws"] = "true"
 
+	// The additional tags were applied when the instance was launched.
+	if a.machineAnnotation(machine, TagsLastAppliedAnnotation) == "" {
+		instanceTags, err := scope.InstanceTags()
+		if err != nil {
+			return errors.Errorf("failed to get instance tags: %+v", err)
+		}
+
+		if err := a.recordLaunchTags(machine, instanceTags); err != nil {
+			return errors.Errorf("failed to record launch tags: %+v", err)
+		}
+	}
+
+	if scope.MachineConfig.ElasticIP {
+		if err := ec2svc.AssociateMachineAddress(scope, i.ID); err != nil {
+			return errors.Errorf("failed to associate elastic IP: %+v", err)
+		}
+	}
+
+	if scope.ClusterConfig.APIServerElasticIP && scope.Role() == "controlplane" {
+		if err := ec2svc.AssociateAPIServerAddress(scope, i.ID); err != nil {
+			return errors.Errorf("failed to associate the elastic IP of the API server: %+v", err)
+		}
+	}
+
 	if err := a.reconcileLBAttachment(scope, machine, i); err != nil {
+		if _, ok := err.(*controllerError.RequeueAfterError); ok {
+			return err
+		}
 		return errors.Errorf("failed to reconcile LB attachment: %+v", err)
 	}
 
 	return nil
 }
 
-func (a *Actuator) getNodeJoinToken(cluster *clusterv1.Cluster, controlPlaneURL string) (string, error) {
-	kubeConfig, err := a.GetKubeConfig(cluster, nil)
-	if err != nil {
-		return "", errors.Wrapf(err, "failed to retrieve kubeconfig for cluster %q.", cluster.Name)
-	}
-
-	clientConfig, err := clientcmd.BuildConfigFromKubeconfigGetter(controlPlaneURL, func() (*clientcmdapi.Config, error) {
-		return clientcmd.Load([]byte(kubeConfig))
-	})
-
+func (a *Actuator) getNodeJoinToken(scope *actuators.Scope, controlPlaneURL string) (string, error) {
+	clientConfig, err := a.WorkloadConfig(scope, controlPlaneURL)
 	if err != nil {
-		return "", errors.Wrapf(err, "failed to get client config for cluster at %q", controlPlaneURL)
+		return "", err
 	}
 
 	coreClient, err := corev1.NewForConfig(clientConfig)
@@ -209,8 +355,23 @@ func (a *Actuator) getNodeJoinToken(cluster *clusterv1.Cluster, controlPlaneURL
 }
 
 func (a *Actuator) reconcileLBAttachment(scope *actuators.MachineScope, m *clusterv1.Machine, i *v1alpha1.Instance) error {
-	elbsvc := elb.NewService(scope.Scope)
+	if scope.Skips(actuators.SkipLoadBalancerAttachmentAnnotation) {
+		klog.V(2).Infof("Skipping load balancer attachment for machine %q", m.Name)
+		return nil
+	}
+
+	elbsvc := a.services.ELB(scope.Scope)
 	if m.ObjectMeta.Labels["set"] == "controlplane" {
+		// Instances still bootstrapping would fail the requests routed to them.
+		ready, err := a.apiServerWarmedUp(scope, m, i)
+		if err != nil {
+			return errors.Wrapf(err, "failed to check the API server of machine %q", m.Name)
+		}
+		if !ready {
+			klog.Infof("Waiting for the API server of machine %q to be ready before registering it with the load balancer", m.Name)
+			return &controllerError.RequeueAfterError{RequeueAfter: apiServerWarmUpRequeueAfter}
+		}
+
 		if err := elbsvc.RegisterInstanceWithAPIServerELB(i.ID); err != nil {
 			return errors.Wrapf(err, "could not register control plane instance %q with load balancer", i.ID)
 		}
@@ -221,6 +382,16 @@ func (a *Actuator) reconcileLBAttachment(scope *actuators.MachineScope, m *clust
 
 // Delete deletes a machine and is invoked by the Machine Controller
 func (a *Actuator) Delete(ctx context.Context, cluster *clusterv1.Cluster, machine *clusterv1.Machine) error {
+	if !actuators.Selects(a.selector, cluster) {
+		klog.V(2).Infof("Cluster %v is not selected by this controller, waiting for the deletion of machine %v", cluster.Name, machine.Name)
+		return &controllerError.RequeueAfterError{RequeueAfter: actuators.UnselectedRequeueAfter}
+	}
+
+	if a.readOnly {
+		klog.Infof("Controller is read-only, postponing the deletion of machine %v for cluster %v", machine.Name, cluster.Name)
+		return &controllerError.RequeueAfterError{RequeueAfter: actuators.ReadOnlyRequeueAfter}
+	}
+
 	klog.Infof("Deleting machine %v for cluster %v.", machine.Name, cluster.Name)
 
 	scope, err := actuators.NewMachineScope(actuators.MachineScopeParams{Machine: machine, Cluster: cluster, Client: a.client})
@@ -229,42 +400,149 @@ func (a *Actuator) Delete(ctx context.Context, cluster *clusterv1.Cluster, machi
 	}
 
 	defer scope.Close()
+	defer scope.ForgetConvergence()
 
-	ec2svc := ec2.NewService(scope.Scope)
+	if err := scope.VerifyAccount(machine, &scope.MachineStatus.AccountID, &scope.MachineStatus.Region); err != nil {
+		return errors.Wrapf(err, "refusing to delete machine %q", machine.Name)
+	}
 
-	instance, err := ec2svc.InstanceIfExists(*scope.MachineStatus.InstanceID)
+	if err := deleteFileParameters(scope); err != nil {
+		return err
+	}
+
+	ec2svc := a.services.EC2(scope.Scope)
+
+	instance, err := ec2svc.DeletedInstanceIfExists(*scope.MachineStatus.InstanceID)
 	if err != nil {
 		return errors.Errorf("failed to get instance: %+v", err)
 	}
 
+	workloadClient := func() (kubernetes.Interface, error) {
+		return a.WorkloadClient(scope.Scope)
+	}
+
 	if instance == nil {
-		// The machine hasn't been created yet
+		// The machine hasn't been created yet, or its instance is long gone.
 		klog.Info("Instance is nil and therefore does not exist")
-		return nil
+		if machine.Status.NodeRef != nil {
+			deleteNodes(workloadClient, []*deletion{{scope: scope, instance: &v1alpha1.Instance{ID: *scope.MachineStatus.InstanceID}}})
+		}
+		return releaseElasticIP(ec2svc, scope)
 	}
 
-	// Check the instance state. If it's already shutting down or terminated,
-	// do nothing. Otherwise attempt to delete it.
+	// Check the instance state. If it's already shutting down, wait for it to
+	// be terminated, and once terminated only clean up after it. Otherwise
+	// attempt to delete it. The node of the machine is deleted only once the
+	// instance is terminated, so that its pods are not started elsewhere while
+	// the kubelet still runs them.
 	// This decision is based on the ec2-instance-lifecycle graph at
 	// https://docs.aws.amazon.com/AWSEC2/latest/UserGuide/ec2-instance-lifecycle.html
 	switch instance.State {
-	case v1alpha1.InstanceStateShuttingDown, v1alpha1.InstanceStateTerminated:
-		klog.Infof("instance %q is shutting down or already terminated", machine.Name)
-		return nil
+	case v1alpha1.InstanceStateShuttingDown:
+		klog.Infof("Waiting for instance %q of machine %q to be terminated", instance.ID, machine.Name)
+		return &controllerError.RequeueAfterError{RequeueAfter: terminationRequeueAfter}
+	case v1alpha1.InstanceStateTerminated:
+		klog.Infof("Instance %q of machine %q is terminated", instance.ID, machine.Name)
+		deleteNodes(workloadClient, []*deletion{{scope: scope, instance: instance}})
+		return releaseElasticIP(ec2svc, scope)
 	default:
+		allowed, err := ensureNotLastControlPlane(scope)
+		if err != nil {
+			return errors.Errorf("failed to count the control plane machines: %+v", err)
+		}
+		if !allowed {
+			return &controllerError.RequeueAfterError{RequeueAfter: lastControlPlaneRequeueAfter}
+		}
+
+		// Parked instances are stopped rather than terminated. The instances
+		// of control plane and spot machines cannot be stopped, and are
+		// terminated instead.
+		park := scope.MachineConfig.DeletionPolicy == v1alpha1.InstanceDeletionPolicyStop
+		if err := actuators.ValidateDeletionPolicy(scope.Role(), scope.MachineConfig.DeletionPolicy, instance.Spot); err != nil {
+			record.Warnf(machine, "InvalidDeletionPolicy", "Terminating instance %q of machine %q rather than stopping it: %v", instance.ID, machine.Name, err)
+			park = false
+		}
+		if park {
+			if err := a.parkInstance(ec2svc, workloadClient, scope, instance); err != nil {
+				if _, ok := err.(*controllerError.RequeueAfterError); ok {
+					return err
+				}
+				return errors.Errorf("failed to stop instance: %+v", err)
+			}
+
+			if err := releaseElasticIP(ec2svc, scope); err != nil {
+				return err
+			}
+			break
+		}
+
+		if scope.Role() != "controlplane" {
+			// The instances of the other machines being deleted, such as
+			// when a machine set is scaled down, are terminated in the same call.
+			others, err := a.pendingDeletions(scope, ec2svc)
+			if err != nil {
+				return errors.Errorf("failed to get the other machines being deleted: %+v", err)
+			}
+
+			if err := a.terminateMachines(ec2svc, workloadClient, &deletion{scope: scope, instance: instance}, others); err != nil {
+				return err
+			}
+			break
+		}
+
+		deregistered, err := a.ensureDeregistered(a.services.ELB(scope.Scope), scope, instance.ID)
+		if err != nil {
+			return errors.Errorf("failed to deregister instance from load balancer: %+v", err)
+		}
+		if !deregistered {
+			return &controllerError.RequeueAfterError{RequeueAfter: deregistrationRequeueAfter}
+		}
+
+		if err := runLifecycleHooks(scope, v1alpha1.LifecyclePreDelete, instance); err != nil {
+			return errors.Errorf("failed to run pre-delete hooks: %+v", err)
+		}
+
+		// Control plane instances are launched with termination protection,
+		// which has to be lifted before they can be terminated.
+		if err := ec2svc.SetTerminationProtection(instance.ID, false); err != nil {
+			return errors.Errorf("failed to disable termination protection: %+v", err)
+		}
+
 		if err := ec2svc.TerminateInstance(aws.StringValue(scope.MachineStatus.InstanceID)); err != nil {
 			return errors.Errorf("failed to terminate instance: %+v", err)
 		}
+
+		newNotifier(scope.Scope).notify(machine, machineDeleted, instance.ID, "")
+		return &controllerError.RequeueAfterError{RequeueAfter: terminationRequeueAfter}
 	}
 
 	klog.Info("shutdown signal was sent. Shutting down machine.")
 	return nil
 }
 
+// releaseElasticIP releases the Elastic IP of a machine being deleted, if it
+// has one.
+func releaseElasticIP(svc service.EC2MachineInterface, scope *actuators.MachineScope) error {
+	if !scope.MachineConfig.ElasticIP {
+		return nil
+	}
+
+	if err := svc.ReleaseMachineAddress(scope); err != nil {
+		return errors.Errorf("failed to release elastic IP: %+v", err)
+	}
+
+	return nil
+}
+
 // Update updates a machine and is invoked by the Machine Controller.
 // If the Update attempts to mutate any immutable state, the method will error
 // and no updates will be performed.
 func (a *Actuator) Update(ctx context.Context, cluster *clusterv1.Cluster, machine *clusterv1.Machine) error {
+	if !actuators.Selects(a.selector, cluster) {
+		klog.V(2).Infof("Cluster %v is not selected by this controller, skipping machine %v", cluster.Name, machine.Name)
+		return nil
+	}
+
 	klog.Infof("Updating machine %v for cluster %v.", machine.Name, cluster.Name)
 
 	scope, err := actuators.NewMachineScope(actuators.MachineScopeParams{Machine: machine, Cluster: cluster, Client: a.client})
@@ -274,7 +552,7 @@ func (a *Actuator) Update(ctx context.Context, cluster *clusterv1.Cluster, machi
 
 	defer scope.Close()
 
-	ec2svc := ec2.NewService(scope.Scope)
+	ec2svc := a.services.EC2(scope.Scope)
 
 	// Get the current instance description from AWS.
 	instanceDescription, err := ec2svc.InstanceIfExists(*scope.MachineStatus.InstanceID)
@@ -282,34 +560,213 @@ func (a *Actuator) Update(ctx context.Context, cluster *clusterv1.Cluster, machi
 		return errors.Errorf("failed to get instance: %+v", err)
 	}
 
+	// In read-only mode, only the state of the instance is reported.
+	if a.readOnly {
+		klog.Infof("Controller is read-only, skipping the update of machine %v for cluster %v", machine.Name, cluster.Name)
+		if instanceDescription != nil {
+			scope.MachineStatus.InstanceState = aws.String(string(instanceDescription.State))
+		}
+		return nil
+	}
+
+	if err := scope.VerifyAccount(machine, &scope.MachineStatus.AccountID, &scope.MachineStatus.Region); err != nil {
+		return errors.Wrapf(err, "refusing to update machine %q", machine.Name)
+	}
+
+	// Stop the instance when requested, or start it again. There is nothing
+	// else to update while it is not running.
+	stopped, err := a.ensureStopped(ec2svc, machine, instanceDescription, scope.MachineStatus)
+	if err != nil {
+		if _, ok := err.(*controllerError.RequeueAfterError); ok {
+			return err
+		}
+		return errors.Errorf("failed to stop or start instance: %+v", err)
+	}
+	if stopped {
+		return nil
+	}
+
+	n := newNotifier(scope.Scope)
+
+	// Ensure that a machine which failed to join in time is terminated or quarantined.
+	// There is nothing left to update once it is.
+	expired, err := a.ensureBootstrapDeadline(ec2svc, a.services.ELB(scope.Scope), n, machine, instanceDescription, scope.MachineConfig, scope.MachineStatus)
+	if err != nil {
+		return errors.Errorf("failed to check bootstrap deadline: %+v", err)
+	}
+	if expired {
+		return nil
+	}
+
+	if machine.Status.NodeRef == nil {
+		scope.ObserveConvergence("node-ready", errors.Errorf("instance %q has not joined the cluster", *scope.MachineStatus.InstanceID))
+	} else {
+		scope.ObserveConvergence("node-ready", nil)
+	}
+
 	// We can now compare the various AWS state to the state we were passed.
 	// We will check immutable state first, in order to fail quickly before
 	// moving on to state that we can mutate.
 	// TODO: Implement immutable state check.
 
 	// Ensure that the security groups are correct.
-	_, err = a.ensureSecurityGroups(
-		ec2svc,
-		machine,
-		*scope.MachineStatus.InstanceID,
-		scope.MachineConfig.AdditionalSecurityGroups,
-		instanceDescription.SecurityGroupIDs,
-	)
-	if err != nil {
-		return errors.Errorf("failed to apply security groups: %+v", err)
+	if !scope.Skips(actuators.SkipSecurityGroupsAnnotation) {
+		_, err = a.ensureSecurityGroups(
+			ec2svc,
+			machine,
+			*scope.MachineStatus.InstanceID,
+			scope.MachineConfig.AdditionalSecurityGroups,
+			instanceDescription.SecurityGroupIDs,
+		)
+		if err != nil {
+			return errors.Errorf("failed to apply security groups: %+v", err)
+		}
 	}
 
 	// Ensure that the tags are correct.
-	_, err = a.ensureTags(ec2svc, machine, scope.MachineStatus.InstanceID, scope.MachineConfig.AdditionalTags)
+	if !scope.Skips(actuators.SkipTagsAnnotation) {
+		instanceTags, err := scope.InstanceTags()
+		if err != nil {
+			return errors.Errorf("failed to get instance tags: %+v", err)
+		}
+
+		_, err = a.ensureTags(ec2svc, machine, scope.MachineStatus.InstanceID, instanceTags)
+		if err != nil {
+			return errors.Errorf("failed to ensure tags: %+v", err)
+		}
+	}
+
+	workloadClient := func() (kubernetes.Interface, error) {
+		return a.WorkloadClient(scope.Scope)
+	}
+
+	// Ensure that the user data is scrubbed once the machine has joined.
+	_, err = a.ensureUserDataScrubbed(ec2svc, workloadClient, machine, instanceDescription, scope.MachineConfig, scope.MachineStatus)
 	if err != nil {
-		return errors.Errorf("failed to ensure tags: %+v", err)
+		if _, ok := err.(*controllerError.RequeueAfterError); ok {
+			return err
+		}
+		// The user data of instances which cannot be stopped is left as is.
+		if !awserrors.IsInvalidConfiguration(errors.Cause(err)) {
+			return errors.Errorf("failed to scrub user data: %+v", err)
+		}
+		record.Warn(machine, "InvalidConfiguration", err.Error())
 	}
 
-	return nil
+	// Ensure that the machine is flagged if its image is outdated.
+	_, err = a.ensureImageAge(ec2svc, machine, instanceDescription, scope.MachineConfig, scope.MachineStatus)
+	if err != nil {
+		return errors.Errorf("failed to check image age: %+v", err)
+	}
+
+	// Ensure that a failed connectivity preflight is reported.
+	_, err = a.ensureBootstrapPreflight(ec2svc, machine, scope.MachineConfig, scope.MachineStatus)
+	if err != nil {
+		return errors.Errorf("failed to check bootstrap preflight: %+v", err)
+	}
+
+	// Ensure that maintenance events scheduled by AWS are reflected on the machine.
+	_, err = a.ensureScheduledEvents(ec2svc, n, machine, *scope.MachineStatus.InstanceID, scope.MachineConfig, scope.MachineStatus)
+	if err != nil {
+		return errors.Errorf("failed to check scheduled events: %+v", err)
+	}
+
+	// Apply changes to the volumes of the machine in place. Volumes that cannot
+	// be modified yet are retried later without blocking the rest of the update.
+	var requeue error
+	_, err = a.ensureVolumes(ec2svc, machine, scope.MachineConfig, scope.MachineStatus)
+	if err != nil {
+		if _, ok := err.(*controllerError.RequeueAfterError); !ok {
+			return errors.Errorf("failed to modify volumes: %+v", err)
+		}
+		requeue = err
+	}
+
+	// Apply changes to the CPU credit option of the machine in place.
+	_, err = a.ensureCreditSpecification(ec2svc, machine, scope.MachineConfig, scope.MachineStatus)
+	if err != nil {
+		return errors.Errorf("failed to modify credit specification: %+v", err)
+	}
+	setBurstableControlPlaneCondition(scope.InstanceTypes, machine, scope.MachineConfig, scope.MachineStatus)
+
+	// Enable or disable the detailed monitoring of the machine in place.
+	_, err = a.ensureDetailedMonitoring(ec2svc, machine, instanceDescription, scope.MachineConfig)
+	if err != nil {
+		return errors.Errorf("failed to set detailed monitoring: %+v", err)
+	}
+
+	// Run the custom provisioning steps of the cluster once the node has joined.
+	_, err = a.ensurePostJoinHooks(scope, instanceDescription)
+	if err != nil {
+		return errors.Errorf("failed to run post-join hooks: %+v", err)
+	}
+
+	// Reflect the selected instance tags as annotations.
+	a.ensureTagAnnotations(machine, instanceDescription, scope.MachineConfig)
+
+	// Annotate the machine with the utilization and cost of its instance.
+	// Failures, such as missing CloudWatch permissions, are retried on the
+	// next update without blocking this one.
+	if scope.FeatureEnabled(features.ConsolidationHints) {
+		if _, err := a.ensureConsolidationHints(scope, instanceDescription, time.Now()); err != nil {
+			klog.Warningf("Failed to update the consolidation hints of machine %q: %v", machine.Name, err)
+			record.Warnf(machine, "FailedConsolidationHints", "Failed to update consolidation hints: %v", err)
+		}
+	}
+
+	// Associate the Elastic IP of the machine, such as once it is enabled.
+	if scope.MachineConfig.ElasticIP {
+		if err := ec2svc.AssociateMachineAddress(scope, instanceDescription.ID); err != nil {
+			return errors.Errorf("failed to associate elastic IP: %+v", err)
+		}
+	}
+
+	if scope.ClusterConfig.APIServerElasticIP && scope.Role() == "controlplane" {
+		if err := ec2svc.AssociateAPIServerAddress(scope, instanceDescription.ID); err != nil {
+			return errors.Errorf("failed to associate the elastic IP of the API server: %+v", err)
+		}
+	}
+
+	// Approve the serving certificate requests of the kubelet of the machine.
+	// Failures are retried on the next update without blocking this one.
+	if te := scope.ClusterConfig.TransitEncryption; te != nil && te.KubeletServingCertificates {
+		if err := a.ensureServingCertificates(workloadClient, machine, instanceDescription); err != nil {
+			klog.Warningf("Failed to approve serving certificates of machine %q: %v", machine.Name, err)
+			record.Warnf(machine, "FailedServingCertificates", "Failed to approve serving certificates: %v", err)
+		}
+	}
+
+	// Register control plane instances with the API server load balancer once
+	// their API server is ready. Reboots wait for the registration.
+	if instanceDescription.State == v1alpha1.InstanceStateRunning {
+		if err := a.reconcileLBAttachment(scope, machine, instanceDescription); err != nil {
+			if _, ok := err.(*controllerError.RequeueAfterError); ok {
+				return err
+			}
+			return errors.Errorf("failed to reconcile LB attachment: %+v", err)
+		}
+	}
+
+	// Reboot the machine when requested, draining its node first if requested.
+	_, err = a.ensureReboot(ec2svc, workloadClient, machine, scope.MachineStatus)
+	if err != nil {
+		if _, ok := err.(*controllerError.RequeueAfterError); ok {
+			return err
+		}
+		return errors.Errorf("failed to reboot machine: %+v", err)
+	}
+
+	return requeue
 }
 
 // Exists test for the existence of a machine and is invoked by the Machine Controller
 func (a *Actuator) Exists(ctx context.Context, cluster *clusterv1.Cluster, machine *clusterv1.Machine) (bool, error) {
+	// Machines of clusters handled by other controllers are reported as existing,
+	// so that they are never created by this controller.
+	if !actuators.Selects(a.selector, cluster) {
+		return true, nil
+	}
+
 	klog.Infof("Checking if machine %v for cluster %v exists", machine.Name, cluster.Name)
 
 	scope, err := actuators.NewMachineScope(actuators.MachineScopeParams{Machine: machine, Cluster: cluster, Client: a.client})
@@ -319,7 +776,7 @@ func (a *Actuator) Exists(ctx context.Context, cluster *clusterv1.Cluster, machi
 
 	defer scope.Close()
 
-	ec2svc := ec2.NewService(scope.Scope)
+	ec2svc := a.services.EC2(scope.Scope)
 
 	// TODO worry about pointers. instance if exists returns *any* instance
 	if scope.MachineStatus.InstanceID == nil {
@@ -342,13 +799,16 @@ func (a *Actuator) Exists(ctx context.Context, cluster *clusterv1.Cluster, machi
 		klog.Infof("Machine %v is running", scope.MachineStatus.InstanceID)
 	case v1alpha1.InstanceStatePending:
 		klog.Infof("Machine %v is pending", scope.MachineStatus.InstanceID)
+	case v1alpha1.InstanceStateStopping, v1alpha1.InstanceStateStopped:
+		// Stopped instances are started again rather than replaced.
+		klog.Infof("Machine %v is stopped", scope.MachineStatus.InstanceID)
 	default:
 		return false, nil
 	}
 
-	if err := a.reconcileLBAttachment(scope, machine, instance); err != nil {
-		return true, err
-	}
+	// Tags are reflected as annotations even in read-only mode, as only the
+	// machine object is modified.
+	a.ensureTagAnnotations(machine, instance, scope.MachineConfig)
 
 	return true, nil
 }
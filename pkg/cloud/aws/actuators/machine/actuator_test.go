@@ -17,10 +17,16 @@ limitations under the License.
 package machine
 
 import (
+	"context"
 	"testing"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/actuators"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/fake"
 	clusterv1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+	clusterclient "sigs.k8s.io/cluster-api/pkg/client/clientset_generated/clientset/typed/cluster/v1alpha1"
+	controllerError "sigs.k8s.io/cluster-api/pkg/controller/error"
 	"sigs.k8s.io/cluster-api/pkg/controller/machine"
 )
 
@@ -307,3 +313,85 @@ func TestMachineEqual(t *testing.T) {
 		}
 	}
 }
+
+func TestIsNodeJoin(t *testing.T) {
+	cloud := fake.NewCloud("test-cluster")
+	a := NewActuator(ActuatorParams{Getter: cloud})
+
+	cluster := &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"}}
+	controlPlane := &clusterv1.Machine{
+		ObjectMeta: metav1.ObjectMeta{Name: "controlplane-0", Labels: map[string]string{"set": "controlplane"}},
+		Spec:       clusterv1.MachineSpec{Versions: clusterv1.MachineVersionInfo{ControlPlane: "v1.13.0"}},
+	}
+	newMachine := &clusterv1.Machine{
+		ObjectMeta: metav1.ObjectMeta{Name: "controlplane-1", Labels: map[string]string{"set": "controlplane"}},
+	}
+
+	// The first control plane machine initializes the cluster.
+	join, err := a.isNodeJoin([]*clusterv1.Machine{controlPlane}, newMachine, cluster)
+	if err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+	if join {
+		t.Fatalf("expected the first control plane machine not to join")
+	}
+
+	scope, err := actuators.NewMachineScope(actuators.MachineScopeParams{Machine: controlPlane, Cluster: cluster})
+	if err != nil {
+		t.Fatalf("failed to create scope: %v", err)
+	}
+	ec2svc := cloud.EC2(scope.Scope)
+	if err := ec2svc.ReconcileNetwork(); err != nil {
+		t.Fatalf("failed to reconcile network: %v", err)
+	}
+	if _, err := ec2svc.CreateOrGetMachine(scope, "", ""); err != nil {
+		t.Fatalf("failed to create machine: %v", err)
+	}
+
+	// Once the instance of a control plane machine exists, the others join.
+	join, err = a.isNodeJoin([]*clusterv1.Machine{controlPlane}, newMachine, cluster)
+	if err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+	if !join {
+		t.Fatalf("expected the next control plane machine to join")
+	}
+}
+
+func TestReadOnly(t *testing.T) {
+	a := NewActuator(ActuatorParams{ReadOnly: true})
+	cluster := &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"}}
+	machine := &clusterv1.Machine{ObjectMeta: metav1.ObjectMeta{Name: "test-machine"}}
+
+	// No AWS client is available, so any attempt to modify resources fails.
+	if err := a.Create(context.Background(), cluster, machine); err != nil {
+		t.Fatalf("expected creation to be skipped, got: %v", err)
+	}
+
+	err := a.Delete(context.Background(), cluster, machine)
+	if _, ok := err.(*controllerError.RequeueAfterError); !ok {
+		t.Fatalf("expected deletion to be postponed, got: %v", err)
+	}
+}
+
+// fakeMachineClient lists machines from memory, honoring label selectors.
+type fakeMachineClient struct {
+	clusterclient.MachineInterface
+
+	machines []clusterv1.Machine
+}
+
+func (f *fakeMachineClient) List(opts metav1.ListOptions) (*clusterv1.MachineList, error) {
+	selector, err := labels.Parse(opts.LabelSelector)
+	if err != nil {
+		return nil, err
+	}
+
+	list := &clusterv1.MachineList{}
+	for _, m := range f.machines {
+		if selector.Matches(labels.Set(m.Labels)) {
+			list.Items = append(list.Items, m)
+		}
+	}
+	return list, nil
+}
@@ -0,0 +1,50 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machine
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/actuators"
+	service "sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/record"
+)
+
+// adoptInstance records in the status of a machine that lost the ID of its
+// instance, such as after etcd was restored from a backup or the provider was
+// redeployed, the instance launched for it, so that another one is not
+// launched. The instance is found by the cluster and name tags it was launched
+// with, and true is returned if one was adopted.
+func (a *Actuator) adoptInstance(svc service.EC2MachineInterface, scope *actuators.MachineScope) (bool, error) {
+	if aws.StringValue(scope.MachineStatus.InstanceID) != "" {
+		return false, nil
+	}
+
+	instance, err := svc.InstanceByTags(scope)
+	if err != nil {
+		return false, err
+	}
+
+	if instance == nil {
+		return false, nil
+	}
+
+	scope.MachineStatus.InstanceID = aws.String(instance.ID)
+	scope.MachineStatus.InstanceState = aws.String(string(instance.State))
+
+	record.Eventf(scope.Machine, "AdoptedInstance", "Adopted instance %q found by its tags", instance.ID)
+	return true, nil
+}
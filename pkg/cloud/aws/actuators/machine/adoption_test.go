@@ -0,0 +1,89 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machine
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/golang/mock/gomock"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/actuators"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/mocks"
+	clusterv1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+)
+
+func TestAdoptInstance(t *testing.T) {
+	testCases := []struct {
+		name          string
+		instanceID    *string
+		instance      *v1alpha1.Instance
+		expectLookup  bool
+		expectAdopted bool
+	}{
+		{
+			name:         "no instance",
+			expectLookup: true,
+		},
+		{
+			name:          "instance found by tags",
+			instance:      &v1alpha1.Instance{ID: "i-1", State: v1alpha1.InstanceStateRunning},
+			expectLookup:  true,
+			expectAdopted: true,
+		},
+		{
+			name:       "instance ID known",
+			instanceID: aws.String("i-2"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+
+			ec2Mock := mocks.NewMockEC2Interface(mockCtrl)
+			if tc.expectLookup {
+				ec2Mock.EXPECT().InstanceByTags(gomock.Any()).Return(tc.instance, nil)
+			}
+
+			scope, err := actuators.NewMachineScope(actuators.MachineScopeParams{
+				Cluster: &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"}},
+				Machine: &clusterv1.Machine{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"}},
+			})
+			if err != nil {
+				t.Fatalf("failed to create scope: %v", err)
+			}
+			scope.MachineStatus.InstanceID = tc.instanceID
+
+			a := &Actuator{}
+			adopted, err := a.adoptInstance(ec2Mock, scope)
+			if err != nil {
+				t.Fatalf("did not expect error: %v", err)
+			}
+
+			if adopted != tc.expectAdopted {
+				t.Fatalf("expected adopted %t, got %t", tc.expectAdopted, adopted)
+			}
+
+			if adopted && aws.StringValue(scope.MachineStatus.InstanceID) != tc.instance.ID {
+				t.Fatalf("expected instance ID %q, got %v", tc.instance.ID, aws.StringValue(scope.MachineStatus.InstanceID))
+			}
+		})
+	}
+}
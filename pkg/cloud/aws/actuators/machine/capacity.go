@@ -0,0 +1,62 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machine
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/awserrors"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/record"
+	clusterv1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+)
+
+// capacityReservationRequeueAfter is how long the creation of a machine is
+// postponed when its Capacity Reservation has no available capacity left.
+const capacityReservationRequeueAfter = 5 * time.Minute
+
+// Reports with the CapacityReservationExhausted condition whether the launch
+// of a machine targeting a Capacity Reservation failed because the reservation
+// has no available capacity left.
+// Returns true if the reservation is exhausted.
+func setCapacityReservationCondition(machine *clusterv1.Machine, config *v1alpha1.AWSMachineProviderSpec, status *v1alpha1.AWSMachineProviderStatus, launchErr error) bool {
+	if config.CapacityReservationID == "" {
+		return false
+	}
+
+	condition := v1alpha1.AWSMachineProviderCondition{
+		Type:   v1alpha1.CapacityReservationExhausted,
+		Status: corev1.ConditionFalse,
+	}
+
+	code, _ := awserrors.Code(errors.Cause(launchErr))
+	exhausted := code == awserrors.ReservationCapacityExceeded
+	if exhausted {
+		condition.Status = corev1.ConditionTrue
+		condition.Reason = awserrors.ReservationCapacityExceeded
+		condition.Message = fmt.Sprintf("Capacity reservation %q has no available capacity left for the instance", config.CapacityReservationID)
+	}
+
+	if setCondition(status, condition) && exhausted {
+		record.Warn(machine, "CapacityReservationExhausted", condition.Message)
+	}
+
+	return exhausted
+}
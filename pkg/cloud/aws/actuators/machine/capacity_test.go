@@ -0,0 +1,78 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machine
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	clusterv1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+)
+
+func TestSetCapacityReservationCondition(t *testing.T) {
+	testCases := []struct {
+		name              string
+		launchErr         error
+		expectedExhausted bool
+		expectedStatus    corev1.ConditionStatus
+	}{
+		{
+			name:           "launched",
+			expectedStatus: corev1.ConditionFalse,
+		},
+		{
+			name:              "reservation exhausted",
+			launchErr:         errors.Wrap(awserr.New("ReservationCapacityExceeded", "no capacity", nil), "failed to run instance"),
+			expectedExhausted: true,
+			expectedStatus:    corev1.ConditionTrue,
+		},
+		{
+			name:           "other launch failure",
+			launchErr:      awserr.New("InsufficientInstanceCapacity", "no capacity", nil),
+			expectedStatus: corev1.ConditionFalse,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			config := &v1alpha1.AWSMachineProviderSpec{CapacityReservationID: "cr-1"}
+			status := &v1alpha1.AWSMachineProviderStatus{}
+
+			exhausted := setCapacityReservationCondition(&clusterv1.Machine{}, config, status, tc.launchErr)
+			if exhausted != tc.expectedExhausted {
+				t.Fatalf("expected exhausted to be %v, got %v", tc.expectedExhausted, exhausted)
+			}
+
+			if len(status.Conditions) != 1 || status.Conditions[0].Type != v1alpha1.CapacityReservationExhausted {
+				t.Fatalf("expected a single %s condition, got %+v", v1alpha1.CapacityReservationExhausted, status.Conditions)
+			}
+
+			if status.Conditions[0].Status != tc.expectedStatus {
+				t.Fatalf("expected condition status %q, got %q", tc.expectedStatus, status.Conditions[0].Status)
+			}
+		})
+	}
+
+	// Machines without a capacity reservation are left without the condition.
+	status := &v1alpha1.AWSMachineProviderStatus{}
+	if setCapacityReservationCondition(&clusterv1.Machine{}, &v1alpha1.AWSMachineProviderSpec{}, status, nil) || len(status.Conditions) != 0 {
+		t.Fatalf("expected no condition without a capacity reservation, got %+v", status.Conditions)
+	}
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machine
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/actuators"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/compatibility"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/record"
+	"sigs.k8s.io/cluster-api/pkg/apis/cluster/common"
+)
+
+// ensureSupportedVersions checks the Kubernetes versions of a machine without
+// an instance against the compatibility matrix, and returns whether the
+// machine was marked as failed. Machines launched from the AMIs published for
+// this project are failed with guidance when their versions are unsupported,
+// before any resource is created for them, rather than failing to bootstrap.
+// The matrix is only advisory for machines with an image of their own, which
+// get the UnsupportedVersion condition and an event instead.
+func (a *Actuator) ensureSupportedVersions(scope *actuators.MachineScope, n *notifier) (bool, error) {
+	matrix := compatibility.Default()
+	if a.compatibility != nil {
+		m, err := a.compatibility.Matrix()
+		if err != nil {
+			return false, err
+		}
+		matrix = m
+	}
+
+	machine := scope.Machine
+	defaultImage := usesDefaultImage(scope.MachineConfig, scope.ClusterConfig)
+	err := matrix.Validate(compatibility.Machine{
+		Kubelet:      machine.Spec.Versions.Kubelet,
+		ControlPlane: machine.Spec.Versions.ControlPlane,
+		DefaultImage: defaultImage,
+	})
+	if err == nil {
+		if hasCondition(scope.MachineStatus, v1alpha1.UnsupportedVersion) {
+			setCondition(scope.MachineStatus, v1alpha1.AWSMachineProviderCondition{
+				Type:   v1alpha1.UnsupportedVersion,
+				Status: corev1.ConditionFalse,
+			})
+		}
+		return false, nil
+	}
+
+	if !defaultImage {
+		if setCondition(scope.MachineStatus, v1alpha1.AWSMachineProviderCondition{
+			Type:    v1alpha1.UnsupportedVersion,
+			Status:  corev1.ConditionTrue,
+			Reason:  "UnsupportedVersion",
+			Message: err.Error(),
+		}) {
+			record.Warn(machine, "UnsupportedVersion", err.Error())
+		}
+		return false, nil
+	}
+
+	reason := common.InvalidConfigurationMachineError
+	message := err.Error()
+	machine.Status.ErrorReason = &reason
+	machine.Status.ErrorMessage = &message
+	record.Warn(machine, "UnsupportedVersion", message)
+	n.notify(machine, machineFailed, "", message)
+
+	return true, nil
+}
+
+// usesDefaultImage returns true if a machine is launched from the AMIs
+// published for this project, having no image source of its own. Machines
+// of clusters building the AMIs which are not published are not.
+func usesDefaultImage(config *v1alpha1.AWSMachineProviderSpec, clusterConfig *v1alpha1.AWSClusterProviderSpec) bool {
+	return clusterConfig.ImageBuild == nil &&
+		config.AMI.ID == nil &&
+		config.ImageLookup == nil &&
+		config.ImageSSMParameter == "" &&
+		len(config.RegionAMIs) == 0 &&
+		config.LaunchTemplate == nil &&
+		len(clusterConfig.RegionAMIs) == 0
+}
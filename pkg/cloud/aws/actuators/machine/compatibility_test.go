@@ -0,0 +1,129 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machine
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/actuators"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/compatibility"
+	clusterv1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+)
+
+type fakeCompatibility struct {
+	matrix *compatibility.Matrix
+}
+
+func (f *fakeCompatibility) Matrix() (*compatibility.Matrix, error) {
+	return f.matrix, nil
+}
+
+func TestEnsureSupportedVersions(t *testing.T) {
+	override, err := compatibility.Parse([]byte(`
+releases:
+- kubernetes: "1.14"
+  amis: ["1.14.1"]
+  controlPlanes: ["1.14", "1.13"]
+`))
+	if err != nil {
+		t.Fatalf("failed to parse matrix: %v", err)
+	}
+
+	testCases := []struct {
+		name              string
+		versions          clusterv1.MachineVersionInfo
+		ami               *string
+		compatibility     compatibility.Source
+		expectUnsupported bool
+		expectCondition   bool
+	}{
+		{
+			name:     "supported versions",
+			versions: clusterv1.MachineVersionInfo{Kubelet: "v1.13.0", ControlPlane: "v1.13.0"},
+		},
+		{
+			name:              "version without a published AMI",
+			versions:          clusterv1.MachineVersionInfo{Kubelet: "v1.13.2"},
+			expectUnsupported: true,
+		},
+		{
+			name:     "version without a published AMI, with an AMI of its own",
+			versions: clusterv1.MachineVersionInfo{Kubelet: "v1.13.2"},
+			ami:      aws.String("ami-1"),
+		},
+		{
+			name:              "unsupported control plane version",
+			versions:          clusterv1.MachineVersionInfo{Kubelet: "v1.13.0", ControlPlane: "v1.14.0"},
+			expectUnsupported: true,
+		},
+		{
+			name:            "unknown version, with an AMI of its own",
+			versions:        clusterv1.MachineVersionInfo{Kubelet: "v1.15.0"},
+			ami:             aws.String("ami-1"),
+			expectCondition: true,
+		},
+		{
+			name:            "unsupported control plane version, with an AMI of its own",
+			versions:        clusterv1.MachineVersionInfo{Kubelet: "v1.13.0", ControlPlane: "v1.14.0"},
+			ami:             aws.String("ami-1"),
+			expectCondition: true,
+		},
+		{
+			name:          "version of an overriding matrix",
+			versions:      clusterv1.MachineVersionInfo{Kubelet: "v1.14.1", ControlPlane: "v1.14.1"},
+			compatibility: &fakeCompatibility{matrix: override},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			machine := &clusterv1.Machine{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-machine", Namespace: "default"},
+				Spec:       clusterv1.MachineSpec{Versions: tc.versions},
+			}
+			scope, err := actuators.NewMachineScope(actuators.MachineScopeParams{
+				Cluster: &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"}},
+				Machine: machine,
+			})
+			if err != nil {
+				t.Fatalf("failed to create scope: %v", err)
+			}
+			scope.MachineConfig.AMI.ID = tc.ami
+
+			a := &Actuator{compatibility: tc.compatibility}
+			unsupported, err := a.ensureSupportedVersions(scope, nil)
+			if err != nil {
+				t.Fatalf("did not expect error: %v", err)
+			}
+
+			if unsupported != tc.expectUnsupported {
+				t.Fatalf("expected unsupported: %t, got: %t", tc.expectUnsupported, unsupported)
+			}
+
+			if failed := machine.Status.ErrorReason != nil; failed != tc.expectUnsupported {
+				t.Fatalf("expected machine failed: %t, got error reason %v", tc.expectUnsupported, machine.Status.ErrorReason)
+			}
+
+			if condition := hasCondition(scope.MachineStatus, v1alpha1.UnsupportedVersion); condition != tc.expectCondition {
+				t.Fatalf("expected the UnsupportedVersion condition: %t, got: %t", tc.expectCondition, condition)
+			}
+		})
+	}
+}
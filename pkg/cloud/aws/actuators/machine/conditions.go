@@ -0,0 +1,63 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machine
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+)
+
+// setCondition sets the given condition on the machine provider status,
+// replacing any existing condition of the same type. The transition time is
+// only updated when the status of the condition changes.
+// Returns true if the status of the condition changed.
+func setCondition(status *v1alpha1.AWSMachineProviderStatus, condition v1alpha1.AWSMachineProviderCondition) bool {
+	now := metav1.Now()
+	condition.LastProbeTime = now
+
+	for i := range status.Conditions {
+		existing := &status.Conditions[i]
+		if existing.Type != condition.Type {
+			continue
+		}
+
+		changed := existing.Status != condition.Status
+		if changed {
+			condition.LastTransitionTime = now
+		} else {
+			condition.LastTransitionTime = existing.LastTransitionTime
+		}
+
+		*existing = condition
+		return changed
+	}
+
+	condition.LastTransitionTime = now
+	status.Conditions = append(status.Conditions, condition)
+	return true
+}
+
+// hasCondition returns true if the machine provider status has a condition of
+// the given type.
+func hasCondition(status *v1alpha1.AWSMachineProviderStatus, conditionType v1alpha1.AWSMachineProviderConditionType) bool {
+	for _, condition := range status.Conditions {
+		if condition.Type == conditionType {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,109 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machine
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/actuators"
+	clusterv1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+)
+
+const (
+	// CPUUtilizationAnnotation is the average CPU utilization of the instance
+	// of a machine over the last hour, in percent.
+	CPUUtilizationAnnotation = "consolidation.cluster-api-provider-aws.sigs.k8s.io/cpu-utilization"
+
+	// HourlyCostAnnotation is the hourly price of the instance of a machine,
+	// in USD: its spot price for spot instances, and its on-demand price
+	// otherwise.
+	HourlyCostAnnotation = "consolidation.cluster-api-provider-aws.sigs.k8s.io/hourly-cost"
+
+	// ConsolidationHintsUpdatedAnnotation is the time the consolidation
+	// hints of a machine were last refreshed, in RFC 3339 format.
+	ConsolidationHintsUpdatedAnnotation = "consolidation.cluster-api-provider-aws.sigs.k8s.io/updated"
+
+	// utilizationWindow is the window the CPU utilization is averaged over.
+	utilizationWindow = time.Hour
+
+	// consolidationHintsInterval is how often the hints are refreshed, as
+	// machines are updated far more often and CloudWatch requests are billed.
+	consolidationHintsInterval = 15 * time.Minute
+)
+
+// ensureConsolidationHints annotates a machine with the CPU utilization and
+// the hourly cost of its instance, for consolidation tooling to pick the
+// machines to remove, refreshing them every consolidationHintsInterval.
+// Hints which are not known yet, such as the utilization of instances
+// launched moments ago, are left out.
+// Returns true if the annotations were changed.
+func (a *Actuator) ensureConsolidationHints(scope *actuators.MachineScope, instance *v1alpha1.Instance, now time.Time) (bool, error) {
+	machine := scope.Machine
+	if instance == nil || instance.State != v1alpha1.InstanceStateRunning {
+		return false, nil
+	}
+
+	if updated, err := time.Parse(time.RFC3339, machine.Annotations[ConsolidationHintsUpdatedAnnotation]); err == nil && now.Sub(updated) < consolidationHintsInterval {
+		return false, nil
+	}
+
+	utilization, utilizationKnown, err := scope.Metrics.AverageCPUUtilization(instance.ID, utilizationWindow)
+	if err != nil {
+		return false, err
+	}
+
+	cost, costKnown, err := hourlyCost(scope.Scope, instance)
+	if err != nil {
+		return false, err
+	}
+
+	if machine.Annotations == nil {
+		machine.Annotations = map[string]string{}
+	}
+
+	setConsolidationHint(machine, CPUUtilizationAnnotation, strconv.FormatFloat(utilization, 'f', 1, 64), utilizationKnown)
+	setConsolidationHint(machine, HourlyCostAnnotation, strconv.FormatFloat(cost, 'f', -1, 64), costKnown)
+	machine.Annotations[ConsolidationHintsUpdatedAnnotation] = now.UTC().Format(time.RFC3339)
+	return true, nil
+}
+
+// hourlyCost returns the hourly price of an instance, in USD.
+func hourlyCost(scope *actuators.Scope, instance *v1alpha1.Instance) (float64, bool, error) {
+	if !instance.Spot {
+		return scope.Prices.OnDemandHourlyPrice(instance.Type)
+	}
+
+	subnet, ok := scope.Subnets().ToMap()[instance.SubnetID]
+	if !ok {
+		return 0, false, errors.Errorf("subnet %q of instance %q not found", instance.SubnetID, instance.ID)
+	}
+
+	return scope.Prices.SpotHourlyPrice(instance.Type, subnet.AvailabilityZone)
+}
+
+// setConsolidationHint sets a hint annotation if the hint is known, and
+// removes it otherwise, so that stale hints are not left behind.
+func setConsolidationHint(machine *clusterv1.Machine, annotation, value string, known bool) {
+	if known {
+		machine.Annotations[annotation] = value
+	} else {
+		delete(machine.Annotations, annotation)
+	}
+}
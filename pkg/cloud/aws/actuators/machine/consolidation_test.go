@@ -0,0 +1,146 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machine
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/actuators"
+	clusterv1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+)
+
+// utilizations returns the CPU utilization of i-busy, and none for other
+// instances.
+type utilizations struct {
+	requests int
+}
+
+func (u *utilizations) AverageCPUUtilization(instanceID string, window time.Duration) (float64, bool, error) {
+	u.requests++
+	if instanceID == "i-busy" {
+		return 72.25, true, nil
+	}
+	return 0, false, nil
+}
+
+// prices returns the prices of m5.large instances.
+type prices struct{}
+
+func (prices) OnDemandHourlyPrice(instanceType string) (float64, bool, error) {
+	return 0.096, instanceType == "m5.large", nil
+}
+
+func (prices) SpotHourlyPrice(instanceType, zone string) (float64, bool, error) {
+	return 0.035, instanceType == "m5.large" && zone == "eu-west-3a", nil
+}
+
+func TestEnsureConsolidationHints(t *testing.T) {
+	now := time.Date(2019, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name        string
+		instance    *v1alpha1.Instance
+		annotations map[string]string
+		expected    map[string]string
+		expectFetch bool
+	}{
+		{
+			name:     "on-demand instance",
+			instance: &v1alpha1.Instance{ID: "i-busy", Type: "m5.large", State: v1alpha1.InstanceStateRunning},
+			expected: map[string]string{
+				CPUUtilizationAnnotation:            "72.2",
+				HourlyCostAnnotation:                "0.096",
+				ConsolidationHintsUpdatedAnnotation: "2019-01-01T12:00:00Z",
+			},
+			expectFetch: true,
+		},
+		{
+			name:     "spot instance",
+			instance: &v1alpha1.Instance{ID: "i-busy", Type: "m5.large", State: v1alpha1.InstanceStateRunning, Spot: true, SubnetID: "subnet-a"},
+			expected: map[string]string{
+				CPUUtilizationAnnotation:            "72.2",
+				HourlyCostAnnotation:                "0.035",
+				ConsolidationHintsUpdatedAnnotation: "2019-01-01T12:00:00Z",
+			},
+			expectFetch: true,
+		},
+		{
+			name:     "unknown hints removed",
+			instance: &v1alpha1.Instance{ID: "i-new", Type: "x9.huge", State: v1alpha1.InstanceStateRunning},
+			annotations: map[string]string{
+				CPUUtilizationAnnotation:            "10.0",
+				HourlyCostAnnotation:                "1",
+				ConsolidationHintsUpdatedAnnotation: "2019-01-01T11:00:00Z",
+			},
+			expected: map[string]string{
+				ConsolidationHintsUpdatedAnnotation: "2019-01-01T12:00:00Z",
+			},
+			expectFetch: true,
+		},
+		{
+			name:     "recent hints",
+			instance: &v1alpha1.Instance{ID: "i-busy", Type: "m5.large", State: v1alpha1.InstanceStateRunning},
+			annotations: map[string]string{
+				CPUUtilizationAnnotation:            "10.0",
+				ConsolidationHintsUpdatedAnnotation: "2019-01-01T11:50:00Z",
+			},
+			expected: map[string]string{
+				CPUUtilizationAnnotation:            "10.0",
+				ConsolidationHintsUpdatedAnnotation: "2019-01-01T11:50:00Z",
+			},
+		},
+		{
+			name:     "stopped instance",
+			instance: &v1alpha1.Instance{ID: "i-busy", Type: "m5.large", State: v1alpha1.InstanceStateStopped},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			scope, err := actuators.NewMachineScope(actuators.MachineScopeParams{
+				Cluster: &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"}},
+				Machine: &clusterv1.Machine{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default", Annotations: tc.annotations}},
+			})
+			if err != nil {
+				t.Fatalf("failed to create scope: %v", err)
+			}
+
+			metrics := &utilizations{}
+			scope.Metrics = metrics
+			scope.Prices = prices{}
+			scope.ClusterStatus.Network.Subnets = v1alpha1.Subnets{{ID: "subnet-a", AvailabilityZone: "eu-west-3a"}}
+
+			a := &Actuator{}
+			changed, err := a.ensureConsolidationHints(scope, tc.instance, now)
+			if err != nil {
+				t.Fatalf("did not expect error: %v", err)
+			}
+
+			if changed != tc.expectFetch || (metrics.requests > 0) != tc.expectFetch {
+				t.Fatalf("expected the hints to be refreshed %t, got changed %t after %d requests", tc.expectFetch, changed, metrics.requests)
+			}
+
+			if annotations := scope.Machine.Annotations; len(annotations) != len(tc.expected) || (len(tc.expected) > 0 && !reflect.DeepEqual(annotations, tc.expected)) {
+				t.Fatalf("expected annotations %v, got %v", tc.expected, annotations)
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machine
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	service "sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/ec2"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/instancetypes"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/record"
+	clusterv1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+)
+
+// ensureCreditSpecification switches the CPU credit option of a burstable
+// machine instance to the one of the machine provider config, and reports
+// whether it had to be modified.
+func (a *Actuator) ensureCreditSpecification(svc service.EC2MachineInterface, machine *clusterv1.Machine, config *v1alpha1.AWSMachineProviderSpec, status *v1alpha1.AWSMachineProviderStatus) (bool, error) {
+	if config.CreditSpecification == "" {
+		return false, nil
+	}
+
+	modified, err := svc.ModifyInstanceCreditSpecification(aws.StringValue(status.InstanceID), config.CreditSpecification)
+	if err != nil {
+		return false, err
+	}
+
+	if !modified {
+		return false, nil
+	}
+
+	record.Eventf(machine, "ModifiedCreditSpecification", "Set CPU credit option of instance %q to %q", aws.StringValue(status.InstanceID), config.CreditSpecification)
+	return true, nil
+}
+
+// Reports with the BurstableControlPlane condition whether a control plane
+// machine uses a burstable instance type without unlimited CPU credits. The
+// API server and etcd of such machines slow down mysteriously under load once
+// the credits are spent. The condition is only cleared on machines already
+// reporting it, and left unchanged if the instance type cannot be described.
+// Returns true if the machine is credit limited.
+func setBurstableControlPlaneCondition(instanceTypes instancetypes.Describer, machine *clusterv1.Machine, config *v1alpha1.AWSMachineProviderSpec, status *v1alpha1.AWSMachineProviderStatus) bool {
+	if machine.Labels["set"] != "controlplane" && !hasCondition(status, v1alpha1.BurstableControlPlane) {
+		return false
+	}
+
+	limited, err := ec2.CreditLimited(instanceTypes, config.InstanceType, config.CreditSpecification)
+	if err != nil {
+		klog.Warningf("Failed to check whether machine %q is credit limited: %v", machine.Name, err)
+		return false
+	}
+	limited = limited && machine.Labels["set"] == "controlplane"
+
+	if !limited && !hasCondition(status, v1alpha1.BurstableControlPlane) {
+		return false
+	}
+
+	condition := v1alpha1.AWSMachineProviderCondition{
+		Type:   v1alpha1.BurstableControlPlane,
+		Status: corev1.ConditionFalse,
+	}
+
+	if limited {
+		condition.Status = corev1.ConditionTrue
+		condition.Reason = "CreditLimited"
+		condition.Message = fmt.Sprintf("Control plane instance type %q is throttled once its CPU credits are spent, set creditSpecification to %q or use a non-burstable instance type",
+			config.InstanceType, v1alpha1.CPUCreditsUnlimited)
+	}
+
+	if setCondition(status, condition) && limited {
+		record.Warn(machine, "BurstableControlPlane", condition.Message)
+	}
+
+	return limited
+}
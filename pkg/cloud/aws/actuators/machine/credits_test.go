@@ -0,0 +1,118 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machine
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/golang/mock/gomock"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/instancetypes"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/mocks"
+	clusterv1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+)
+
+func TestEnsureCreditSpecification(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	ec2Mock := mocks.NewMockEC2Interface(mockCtrl)
+	ec2Mock.EXPECT().ModifyInstanceCreditSpecification("i-1", v1alpha1.CPUCreditsUnlimited).Return(true, nil)
+
+	a := &Actuator{}
+	status := &v1alpha1.AWSMachineProviderStatus{InstanceID: aws.String("i-1")}
+
+	changed, err := a.ensureCreditSpecification(ec2Mock, &clusterv1.Machine{}, &v1alpha1.AWSMachineProviderSpec{}, status)
+	if err != nil || changed {
+		t.Fatalf("expected machines without credit specification to be left alone, got %t, %v", changed, err)
+	}
+
+	config := &v1alpha1.AWSMachineProviderSpec{CreditSpecification: v1alpha1.CPUCreditsUnlimited}
+	changed, err = a.ensureCreditSpecification(ec2Mock, &clusterv1.Machine{}, config, status)
+	if err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+
+	if !changed {
+		t.Fatalf("expected the credit specification to be modified")
+	}
+}
+
+// burstableInstanceTypes describes the t2.medium instance type as burstable,
+// and fails to describe the x9.huge instance type.
+type burstableInstanceTypes struct{}
+
+func (burstableInstanceTypes) Describe(instanceType string) (*instancetypes.Info, error) {
+	switch instanceType {
+	case "t2.medium":
+		return &instancetypes.Info{Burstable: true}, nil
+	case "x9.huge":
+		return nil, errors.New("access denied")
+	}
+	return nil, nil
+}
+
+func (burstableInstanceTypes) SupportedArchitectures(instanceType string) ([]string, error) {
+	return nil, nil
+}
+
+func (burstableInstanceTypes) OfferedInZone(instanceType, zone string) (bool, error) {
+	return false, nil
+}
+
+func TestSetBurstableControlPlaneCondition(t *testing.T) {
+	types := burstableInstanceTypes{}
+	controlPlane := &clusterv1.Machine{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"set": "controlplane"}}}
+	node := &clusterv1.Machine{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"set": "node"}}}
+	config := &v1alpha1.AWSMachineProviderSpec{InstanceType: "t2.medium"}
+
+	// Nodes do not get the condition.
+	status := &v1alpha1.AWSMachineProviderStatus{}
+	if setBurstableControlPlaneCondition(types, node, config, status) || len(status.Conditions) != 0 {
+		t.Fatalf("expected no condition, got %+v", status.Conditions)
+	}
+
+	if !setBurstableControlPlaneCondition(types, controlPlane, config, status) {
+		t.Fatalf("expected the control plane machine to be credit limited")
+	}
+	if len(status.Conditions) != 1 || status.Conditions[0].Type != v1alpha1.BurstableControlPlane || status.Conditions[0].Status != corev1.ConditionTrue {
+		t.Fatalf("expected a true %s condition, got %+v", v1alpha1.BurstableControlPlane, status.Conditions)
+	}
+
+	// The condition is cleared once the machine has unlimited credits.
+	config.CreditSpecification = v1alpha1.CPUCreditsUnlimited
+	if setBurstableControlPlaneCondition(types, controlPlane, config, status) {
+		t.Fatalf("expected the control plane machine not to be credit limited")
+	}
+	if len(status.Conditions) != 1 || status.Conditions[0].Status != corev1.ConditionFalse {
+		t.Fatalf("expected a false %s condition, got %+v", v1alpha1.BurstableControlPlane, status.Conditions)
+	}
+
+	// The condition is left unchanged if the instance type cannot be described.
+	config.InstanceType = "x9.huge"
+	config.CreditSpecification = ""
+	if setBurstableControlPlaneCondition(types, controlPlane, config, status) {
+		t.Fatalf("expected the control plane machine not to be credit limited")
+	}
+	if len(status.Conditions) != 1 || status.Conditions[0].Status != corev1.ConditionFalse {
+		t.Fatalf("expected the %s condition to be unchanged, got %+v", v1alpha1.BurstableControlPlane, status.Conditions)
+	}
+}
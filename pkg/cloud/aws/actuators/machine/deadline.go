@@ -0,0 +1,142 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machine
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	service "sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/tags"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/record"
+	"sigs.k8s.io/cluster-api/pkg/apis/cluster/common"
+	clusterv1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+)
+
+// ensureBootstrapDeadline terminates the instance of a machine which has not
+// joined the cluster within its bootstrap timeout, or quarantines it until the
+// end of its quarantine TTL, and marks the machine as failed so that it is not
+// created again. It returns true once the deadline has passed, as there is
+// then nothing left to reconcile for the machine.
+//
+// The deadline runs from the launch of the instance, so that the time spent
+// waiting for capacity or for the cluster infrastructure does not count, and
+// from the creation of the machine for instances which do not report it.
+func (a *Actuator) ensureBootstrapDeadline(svc service.EC2MachineInterface, elbsvc service.ELBInterface, n *notifier, machine *clusterv1.Machine, instance *v1alpha1.Instance, config *v1alpha1.AWSMachineProviderSpec, status *v1alpha1.AWSMachineProviderStatus) (bool, error) {
+	if config.BootstrapTimeout == nil || machine.Status.NodeRef != nil {
+		return false, nil
+	}
+
+	instanceID := aws.StringValue(status.InstanceID)
+
+	if status.QuarantinedAt != nil {
+		if config.Quarantine != nil && time.Since(status.QuarantinedAt.Time) < config.Quarantine.TTL.Duration {
+			return true, nil
+		}
+
+		klog.V(2).Infof("Quarantine of instance %q for machine %q has ended", instanceID, machine.Name)
+		if err := a.terminateFailedMachine(svc, n, machine, config, instanceID); err != nil {
+			return true, err
+		}
+
+		// The machine is then left failed, like one whose instance was
+		// terminated without quarantine, rather than terminated again.
+		status.QuarantinedAt = nil
+		return true, nil
+	}
+
+	if machine.Status.ErrorReason != nil {
+		return false, nil
+	}
+
+	started := machine.CreationTimestamp.Time
+	if instance != nil && instance.LaunchTime != nil {
+		started = instance.LaunchTime.Time
+	}
+
+	if time.Since(started) < config.BootstrapTimeout.Duration {
+		return false, nil
+	}
+
+	reason := common.CreateMachineError
+	message := fmt.Sprintf("Machine did not join the cluster within %s", config.BootstrapTimeout.Duration)
+	machine.Status.ErrorReason = &reason
+	machine.Status.ErrorMessage = &message
+	record.Warn(machine, "BootstrapTimeout", message)
+	n.notify(machine, machineFailed, instanceID, message)
+
+	if config.Quarantine != nil {
+		if err := a.quarantineInstance(svc, elbsvc, machine, config.Quarantine, instanceID); err != nil {
+			return true, err
+		}
+
+		now := metav1.Now()
+		status.QuarantinedAt = &now
+		return true, nil
+	}
+
+	return true, a.terminateFailedMachine(svc, n, machine, config, instanceID)
+}
+
+// quarantineInstance isolates the instance of a failed machine: it is tagged,
+// removed from the api server load balancer and, if the policy names one,
+// moved to the quarantine security group.
+func (a *Actuator) quarantineInstance(svc service.EC2MachineInterface, elbsvc service.ELBInterface, machine *clusterv1.Machine, policy *v1alpha1.QuarantinePolicy, instanceID string) error {
+	now := time.Now().UTC().Format(time.RFC3339)
+	if err := svc.UpdateResourceTags(aws.String(instanceID), map[string]string{tags.NameAWSProviderQuarantined: now}, nil); err != nil {
+		return err
+	}
+
+	if machine.Labels["set"] == "controlplane" {
+		if err := elbsvc.DeregisterInstanceFromAPIServerELB(instanceID); err != nil {
+			return err
+		}
+	}
+
+	if policy.SecurityGroup != nil && policy.SecurityGroup.ID != nil {
+		if err := svc.UpdateInstanceSecurityGroups(instanceID, []string{*policy.SecurityGroup.ID}); err != nil {
+			return err
+		}
+	}
+
+	record.Warnf(machine, "QuarantinedInstance", "Quarantined instance %q for %s", instanceID, policy.TTL.Duration)
+	return nil
+}
+
+// terminateFailedMachine terminates the instance of a failed machine and,
+// if configured, deletes the machine so that its MachineSet replaces it.
+func (a *Actuator) terminateFailedMachine(svc service.EC2MachineInterface, n *notifier, machine *clusterv1.Machine, config *v1alpha1.AWSMachineProviderSpec, instanceID string) error {
+	if machine.Labels["set"] == "controlplane" {
+		if err := svc.SetTerminationProtection(instanceID, false); err != nil {
+			return err
+		}
+	}
+
+	if err := svc.TerminateInstance(instanceID); err != nil {
+		return err
+	}
+
+	if config.ReplaceOnBootstrapTimeout {
+		return a.replaceMachine(n, machine, instanceID, "after it failed to join the cluster")
+	}
+
+	return nil
+}
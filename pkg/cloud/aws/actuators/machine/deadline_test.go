@@ -0,0 +1,206 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machine
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/golang/mock/gomock"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/mocks"
+	"sigs.k8s.io/cluster-api/pkg/apis/cluster/common"
+	clusterv1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+)
+
+func TestEnsureBootstrapDeadline(t *testing.T) {
+	created := metav1.NewTime(time.Now().Add(-time.Hour))
+	quarantined := metav1.NewTime(time.Now().Add(-30 * time.Minute))
+	launched := metav1.NewTime(time.Now().Add(-5 * time.Minute))
+	failed := common.CreateMachineError
+
+	testCases := []struct {
+		name                string
+		machine             *clusterv1.Machine
+		launched            *metav1.Time
+		timeout             time.Duration
+		quarantine          *v1alpha1.QuarantinePolicy
+		quarantinedAt       *metav1.Time
+		expect              func(m *mocks.MockEC2InterfaceMockRecorder, e *mocks.MockELBInterfaceMockRecorder)
+		expectedExpired     bool
+		expectedQuarantined bool
+	}{
+		{
+			name: "within deadline",
+			machine: &clusterv1.Machine{
+				ObjectMeta: metav1.ObjectMeta{CreationTimestamp: created},
+			},
+			timeout: 2 * time.Hour,
+			expect:  func(m *mocks.MockEC2InterfaceMockRecorder, e *mocks.MockELBInterfaceMockRecorder) {},
+		},
+		{
+			name: "instance launched within deadline",
+			machine: &clusterv1.Machine{
+				ObjectMeta: metav1.ObjectMeta{CreationTimestamp: created},
+			},
+			launched: &launched,
+			timeout:  10 * time.Minute,
+			expect:   func(m *mocks.MockEC2InterfaceMockRecorder, e *mocks.MockELBInterfaceMockRecorder) {},
+		},
+		{
+			name: "machine joined",
+			machine: &clusterv1.Machine{
+				ObjectMeta: metav1.ObjectMeta{CreationTimestamp: created},
+				Status:     clusterv1.MachineStatus{NodeRef: &corev1.ObjectReference{Name: "node-1"}},
+			},
+			timeout: 10 * time.Minute,
+			expect:  func(m *mocks.MockEC2InterfaceMockRecorder, e *mocks.MockELBInterfaceMockRecorder) {},
+		},
+		{
+			name: "deadline exceeded",
+			machine: &clusterv1.Machine{
+				ObjectMeta: metav1.ObjectMeta{CreationTimestamp: created},
+			},
+			timeout: 10 * time.Minute,
+			expect: func(m *mocks.MockEC2InterfaceMockRecorder, e *mocks.MockELBInterfaceMockRecorder) {
+				m.TerminateInstance("i-1").Return(nil)
+			},
+			expectedExpired: true,
+		},
+		{
+			name: "deadline exceeded for control plane",
+			machine: &clusterv1.Machine{
+				ObjectMeta: metav1.ObjectMeta{
+					CreationTimestamp: created,
+					Labels:            map[string]string{"set": "controlplane"},
+				},
+			},
+			timeout: 10 * time.Minute,
+			expect: func(m *mocks.MockEC2InterfaceMockRecorder, e *mocks.MockELBInterfaceMockRecorder) {
+				gomock.InOrder(
+					m.SetTerminationProtection("i-1", false).Return(nil),
+					m.TerminateInstance("i-1").Return(nil),
+				)
+			},
+			expectedExpired: true,
+		},
+		{
+			name: "deadline exceeded with quarantine",
+			machine: &clusterv1.Machine{
+				ObjectMeta: metav1.ObjectMeta{
+					CreationTimestamp: created,
+					Labels:            map[string]string{"set": "controlplane"},
+				},
+			},
+			timeout: 10 * time.Minute,
+			quarantine: &v1alpha1.QuarantinePolicy{
+				TTL:           metav1.Duration{Duration: time.Hour},
+				SecurityGroup: &v1alpha1.AWSResourceReference{ID: aws.String("sg-quarantine")},
+			},
+			expect: func(m *mocks.MockEC2InterfaceMockRecorder, e *mocks.MockELBInterfaceMockRecorder) {
+				m.UpdateResourceTags(aws.String("i-1"), gomock.Any(), gomock.Nil()).Return(nil)
+				e.DeregisterInstanceFromAPIServerELB("i-1").Return(nil)
+				m.UpdateInstanceSecurityGroups("i-1", []string{"sg-quarantine"}).Return(nil)
+			},
+			expectedExpired:     true,
+			expectedQuarantined: true,
+		},
+		{
+			name: "quarantined",
+			machine: &clusterv1.Machine{
+				ObjectMeta: metav1.ObjectMeta{CreationTimestamp: created},
+			},
+			timeout:             10 * time.Minute,
+			quarantine:          &v1alpha1.QuarantinePolicy{TTL: metav1.Duration{Duration: time.Hour}},
+			quarantinedAt:       &quarantined,
+			expect:              func(m *mocks.MockEC2InterfaceMockRecorder, e *mocks.MockELBInterfaceMockRecorder) {},
+			expectedExpired:     true,
+			expectedQuarantined: true,
+		},
+		{
+			name: "quarantine ended",
+			machine: &clusterv1.Machine{
+				ObjectMeta: metav1.ObjectMeta{CreationTimestamp: created},
+			},
+			timeout:       10 * time.Minute,
+			quarantine:    &v1alpha1.QuarantinePolicy{TTL: metav1.Duration{Duration: 10 * time.Minute}},
+			quarantinedAt: &quarantined,
+			expect: func(m *mocks.MockEC2InterfaceMockRecorder, e *mocks.MockELBInterfaceMockRecorder) {
+				m.TerminateInstance("i-1").Return(nil)
+			},
+			expectedExpired: true,
+		},
+		{
+			name: "terminated after quarantine",
+			machine: &clusterv1.Machine{
+				ObjectMeta: metav1.ObjectMeta{CreationTimestamp: created},
+				Status:     clusterv1.MachineStatus{ErrorReason: &failed},
+			},
+			timeout:    10 * time.Minute,
+			quarantine: &v1alpha1.QuarantinePolicy{TTL: metav1.Duration{Duration: 10 * time.Minute}},
+			expect:     func(m *mocks.MockEC2InterfaceMockRecorder, e *mocks.MockELBInterfaceMockRecorder) {},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+
+			ec2Mock := mocks.NewMockEC2Interface(mockCtrl)
+			elbMock := mocks.NewMockELBInterface(mockCtrl)
+			tc.expect(ec2Mock.EXPECT(), elbMock.EXPECT())
+
+			// Machines without a MachineSet owner are never deleted for replacement.
+			config := &v1alpha1.AWSMachineProviderSpec{
+				BootstrapTimeout:          &metav1.Duration{Duration: tc.timeout},
+				ReplaceOnBootstrapTimeout: true,
+				Quarantine:                tc.quarantine,
+			}
+			status := &v1alpha1.AWSMachineProviderStatus{
+				InstanceID:    aws.String("i-1"),
+				QuarantinedAt: tc.quarantinedAt,
+			}
+
+			alreadyFailed := tc.machine.Status.ErrorReason != nil
+
+			a := &Actuator{}
+			instance := &v1alpha1.Instance{ID: "i-1", LaunchTime: tc.launched}
+			expired, err := a.ensureBootstrapDeadline(ec2Mock, elbMock, nil, tc.machine, instance, config, status)
+			if err != nil {
+				t.Fatalf("did not expect error: %v", err)
+			}
+
+			if expired != tc.expectedExpired {
+				t.Fatalf("expected expired to be %t, got %t", tc.expectedExpired, expired)
+			}
+
+			if tc.quarantinedAt == nil && !alreadyFailed {
+				if failed := tc.machine.Status.ErrorReason != nil; failed != tc.expectedExpired {
+					t.Fatalf("expected the machine to be marked failed: %t, got reason %v", tc.expectedExpired, tc.machine.Status.ErrorReason)
+				}
+			}
+
+			if quarantined := status.QuarantinedAt != nil; quarantined != tc.expectedQuarantined {
+				t.Fatalf("expected the quarantine time to be recorded: %t, got %v", tc.expectedQuarantined, status.QuarantinedAt)
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machine
+
+import (
+	"github.com/pkg/errors"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/actuators"
+)
+
+// deleteFileParameters deletes the SSM parameters holding the content of the
+// files of a machine read from Secrets. The files are written when the
+// instance boots, so the parameters are not needed anymore once the machine
+// is being deleted.
+func deleteFileParameters(scope *actuators.MachineScope) error {
+	secretFiles := false
+	for _, f := range scope.MachineConfig.Files {
+		if f.ContentFrom != nil && f.ContentFrom.Secret != nil {
+			secretFiles = true
+		}
+	}
+	if !secretFiles {
+		return nil
+	}
+
+	if err := scope.SSM.DeleteParametersByPath(scope.FileParametersPath()); err != nil {
+		return errors.Wrapf(err, "failed to delete the file parameters of machine %q", scope.Name())
+	}
+	return nil
+}
@@ -0,0 +1,197 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machine
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/pkg/errors"
+	"k8s.io/klog"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/actuators"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/record"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/webhook"
+)
+
+const (
+	// PostJoinHooksAnnotation records when the post-join lifecycle hooks of
+	// a machine succeeded, so that they are only invoked once.
+	PostJoinHooksAnnotation = "sigs.k8s.io/cluster-api-provider-aws/post-join-hooks"
+
+	// defaultHookTimeout is how long lifecycle hooks may run by default.
+	defaultHookTimeout = 30 * time.Second
+
+	// hookOutputMaxLength is the length of the output of failed hooks
+	// included in errors.
+	hookOutputMaxLength = 1024
+)
+
+// hookClient returns the HTTP client posting to lifecycle hooks, and
+// validateHookURL validates their URLs. Both refuse non-public addresses,
+// and are only replaced in tests.
+var (
+	hookClient      = webhook.NewClient
+	validateHookURL = webhook.ValidateURL
+)
+
+// hookContext is the context of a machine passed to lifecycle hooks.
+type hookContext struct {
+	Point      v1alpha1.LifecyclePoint `json:"point"`
+	Cluster    string                  `json:"cluster"`
+	Namespace  string                  `json:"namespace"`
+	Machine    string                  `json:"machine"`
+	Role       string                  `json:"role,omitempty"`
+	Region     string                  `json:"region"`
+	InstanceID string                  `json:"instanceID,omitempty"`
+	PrivateIP  string                  `json:"privateIP,omitempty"`
+	Node       string                  `json:"node,omitempty"`
+}
+
+// lifecycleHooks returns the hooks of the cluster invoked at the given point.
+func lifecycleHooks(scope *actuators.MachineScope, point v1alpha1.LifecyclePoint) []v1alpha1.LifecycleHook {
+	var hooks []v1alpha1.LifecycleHook
+	for _, hook := range scope.ClusterConfig.LifecycleHooks {
+		for _, p := range hook.Points {
+			if p == point {
+				hooks = append(hooks, hook)
+				break
+			}
+		}
+	}
+	return hooks
+}
+
+// runLifecycleHooks invokes in order the hooks of the cluster registered for
+// the given point, with the context of the machine and of its instance if any.
+// Returns an error for the first failing hook whose failure policy is Fail.
+func runLifecycleHooks(scope *actuators.MachineScope, point v1alpha1.LifecyclePoint, instance *v1alpha1.Instance) error {
+	hooks := lifecycleHooks(scope, point)
+	if len(hooks) == 0 {
+		return nil
+	}
+
+	hctx := hookContext{
+		Point:     point,
+		Cluster:   scope.Scope.Name(),
+		Namespace: scope.Machine.Namespace,
+		Machine:   scope.Machine.Name,
+		Role:      scope.Role(),
+		Region:    scope.Scope.Region(),
+	}
+	if instance != nil {
+		hctx.InstanceID = instance.ID
+		hctx.PrivateIP = aws.StringValue(instance.PrivateIP)
+	}
+	if scope.Machine.Status.NodeRef != nil {
+		hctx.Node = scope.Machine.Status.NodeRef.Name
+	}
+
+	body, err := json.Marshal(hctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to encode lifecycle hook context")
+	}
+
+	for _, hook := range hooks {
+		err := runLifecycleHook(hook, body)
+		if err == nil {
+			klog.V(2).Infof("Ran %s hook %q for machine %q", point, hook.Name, scope.Machine.Name)
+			continue
+		}
+
+		if hook.FailurePolicy == v1alpha1.HookFailurePolicyIgnore {
+			record.Warnf(scope.Machine, "LifecycleHookFailed", "The %s hook %q failed: %v", point, hook.Name, err)
+			continue
+		}
+
+		return errors.Wrapf(err, "%s hook %q failed", point, hook.Name)
+	}
+
+	return nil
+}
+
+// runLifecycleHook posts the body to the URL of the hook.
+func runLifecycleHook(hook v1alpha1.LifecycleHook, body []byte) error {
+	if err := validateHookURL(hook.URL); err != nil {
+		return err
+	}
+
+	timeout := defaultHookTimeout
+	if hook.Timeout != nil {
+		timeout = hook.Timeout.Duration
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	req, err := http.NewRequest(http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := hookClient(timeout).Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		out, _ := ioutil.ReadAll(io.LimitReader(resp.Body, hookOutputMaxLength))
+		return errors.Errorf("endpoint returned status %d: %s", resp.StatusCode, truncateHookOutput(out))
+	}
+
+	return nil
+}
+
+func truncateHookOutput(out []byte) string {
+	if len(out) > hookOutputMaxLength {
+		out = out[:hookOutputMaxLength]
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// ensurePostJoinHooks invokes the post-join hooks of the cluster once the
+// node of the machine has joined the cluster, and returns true on the update
+// that invoked them. Hooks are invoked once per instance.
+func (a *Actuator) ensurePostJoinHooks(scope *actuators.MachineScope, instance *v1alpha1.Instance) (bool, error) {
+	machine := scope.Machine
+	if machine.Status.NodeRef == nil || a.machineAnnotation(machine, PostJoinHooksAnnotation) != "" {
+		return false, nil
+	}
+
+	if len(lifecycleHooks(scope, v1alpha1.LifecyclePostJoin)) == 0 {
+		return false, nil
+	}
+
+	if err := runLifecycleHooks(scope, v1alpha1.LifecyclePostJoin, instance); err != nil {
+		return false, err
+	}
+
+	if machine.Annotations == nil {
+		machine.Annotations = map[string]string{}
+	}
+	a.updateMachineAnnotation(machine, PostJoinHooksAnnotation, time.Now().UTC().Format(time.RFC3339))
+	return true, nil
+}
@@ -0,0 +1,169 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machine
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/actuators"
+	clusterv1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+)
+
+func TestLifecycleHookURL(t *testing.T) {
+	hook := v1alpha1.LifecycleHook{Name: "cmdb", URL: "https://169.254.169.254/latest/meta-data"}
+	if err := runLifecycleHook(hook, nil); err == nil {
+		t.Fatalf("expected hooks to the instance metadata service to be refused")
+	}
+
+	hook.URL = "http://cmdb.example.com/register"
+	if err := runLifecycleHook(hook, nil); err == nil {
+		t.Fatalf("expected plain http hooks to be refused")
+	}
+}
+
+func TestLifecycleHooks(t *testing.T) {
+	var received []hookContext
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hctx := hookContext{}
+		if err := json.NewDecoder(r.Body).Decode(&hctx); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		received = append(received, hctx)
+
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	// The test server listens on a loopback address, which hooks refuse.
+	defer func(client func(time.Duration) *http.Client, validate func(string) error) {
+		hookClient, validateHookURL = client, validate
+	}(hookClient, validateHookURL)
+	hookClient = func(time.Duration) *http.Client { return server.Client() }
+	validateHookURL = func(string) error { return nil }
+
+	testCases := []struct {
+		name             string
+		hooks            []v1alpha1.LifecycleHook
+		expectedReceived int
+		expectError      bool
+	}{
+		{
+			name: "http hook",
+			hooks: []v1alpha1.LifecycleHook{
+				{Name: "cmdb", Points: []v1alpha1.LifecyclePoint{v1alpha1.LifecyclePostJoin}, URL: server.URL + "/register"},
+			},
+			expectedReceived: 1,
+		},
+		{
+			name: "hook of another point",
+			hooks: []v1alpha1.LifecycleHook{
+				{Name: "cmdb", Points: []v1alpha1.LifecyclePoint{v1alpha1.LifecyclePreDelete}, URL: server.URL + "/register"},
+			},
+		},
+		{
+			name: "failing http hook",
+			hooks: []v1alpha1.LifecycleHook{
+				{Name: "cmdb", Points: []v1alpha1.LifecyclePoint{v1alpha1.LifecyclePostJoin}, URL: server.URL + "/fail"},
+				{Name: "ipam", Points: []v1alpha1.LifecyclePoint{v1alpha1.LifecyclePostJoin}, URL: server.URL + "/register"},
+			},
+			expectedReceived: 1,
+			expectError:      true,
+		},
+		{
+			name: "ignored failure",
+			hooks: []v1alpha1.LifecycleHook{
+				{Name: "cmdb", Points: []v1alpha1.LifecyclePoint{v1alpha1.LifecyclePostJoin}, URL: server.URL + "/fail", FailurePolicy: v1alpha1.HookFailurePolicyIgnore},
+				{Name: "ipam", Points: []v1alpha1.LifecyclePoint{v1alpha1.LifecyclePostJoin}, URL: server.URL + "/register"},
+			},
+			expectedReceived: 2,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			received = nil
+
+			machine := &clusterv1.Machine{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "test-machine",
+					Namespace:   "default",
+					Labels:      map[string]string{"set": "node"},
+					Annotations: map[string]string{},
+				},
+				Status: clusterv1.MachineStatus{NodeRef: &corev1.ObjectReference{Name: "node-1"}},
+			}
+			scope, err := actuators.NewMachineScope(actuators.MachineScopeParams{
+				Cluster: &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"}},
+				Machine: machine,
+			})
+			if err != nil {
+				t.Fatalf("failed to create scope: %v", err)
+			}
+			scope.ClusterConfig.Region = "us-east-1"
+			scope.ClusterConfig.LifecycleHooks = tc.hooks
+
+			a := &Actuator{}
+			_, err = a.ensurePostJoinHooks(scope, &v1alpha1.Instance{ID: "i-1", PrivateIP: aws.String("10.0.0.1")})
+			if tc.expectError != (err != nil) {
+				t.Fatalf("expected error: %t, got: %v", tc.expectError, err)
+			}
+
+			if len(received) != tc.expectedReceived {
+				t.Fatalf("expected %d hook calls, got %d", tc.expectedReceived, len(received))
+			}
+
+			if len(received) > 0 {
+				expected := hookContext{
+					Point:      v1alpha1.LifecyclePostJoin,
+					Cluster:    "test-cluster",
+					Namespace:  "default",
+					Machine:    "test-machine",
+					Role:       "node",
+					Region:     "us-east-1",
+					InstanceID: "i-1",
+					PrivateIP:  "10.0.0.1",
+					Node:       "node-1",
+				}
+				if received[0] != expected {
+					t.Fatalf("expected hook context %+v, got %+v", expected, received[0])
+				}
+			}
+
+			// Post-join hooks only run once.
+			invoked := machine.Annotations[PostJoinHooksAnnotation] != ""
+			if invoked != (!tc.expectError && len(lifecycleHooks(scope, v1alpha1.LifecyclePostJoin)) > 0) {
+				t.Fatalf("unexpected post-join annotation %q", machine.Annotations[PostJoinHooksAnnotation])
+			}
+			if invoked {
+				if ran, err := a.ensurePostJoinHooks(scope, nil); ran || err != nil {
+					t.Fatalf("expected hooks not to run again, got %t, %v", ran, err)
+				}
+			}
+		})
+	}
+}
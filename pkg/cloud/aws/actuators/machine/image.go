@@ -0,0 +1,69 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machine
+
+// should not need to import the ec2 sdk here
+import (
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	service "sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/awserrors"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/record"
+	clusterv1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+)
+
+// ensureImageAge sets the ImageOutdated condition of the machine when the AMI
+// backing its instance is older than the configured maximum age, clears it
+// otherwise, and reports whether the condition changed.
+func (a *Actuator) ensureImageAge(svc service.EC2MachineInterface, machine *clusterv1.Machine, instance *v1alpha1.Instance, config *v1alpha1.AWSMachineProviderSpec, status *v1alpha1.AWSMachineProviderStatus) (bool, error) {
+	if config.ImageMaxAge == nil || instance == nil {
+		return false, nil
+	}
+
+	created, err := svc.ImageCreationDate(instance.ImageID)
+	if err != nil {
+		// Deregistered images can no longer be described, their age is unknown.
+		if awserrors.IsNotFound(errors.Cause(err)) || awserrors.IsInvalidConfiguration(errors.Cause(err)) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	condition := v1alpha1.AWSMachineProviderCondition{
+		Type:   v1alpha1.ImageOutdated,
+		Status: corev1.ConditionFalse,
+	}
+
+	age := time.Since(created)
+	if age > config.ImageMaxAge.Duration {
+		condition.Status = corev1.ConditionTrue
+		condition.Reason = "ImageTooOld"
+		condition.Message = fmt.Sprintf("AMI %q was created %s ago, exceeding the maximum age of %s",
+			instance.ImageID, age.Round(time.Hour), config.ImageMaxAge.Duration)
+	}
+
+	changed := setCondition(status, condition)
+	if changed && condition.Status == corev1.ConditionTrue {
+		record.Warn(machine, "OutdatedImage", condition.Message)
+	}
+
+	return changed, nil
+}
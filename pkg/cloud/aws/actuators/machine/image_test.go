@@ -0,0 +1,113 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machine
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/awserrors"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/mocks"
+	clusterv1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+)
+
+func TestEnsureImageAge(t *testing.T) {
+	testCases := []struct {
+		name            string
+		config          *v1alpha1.AWSMachineProviderSpec
+		expect          func(m *mocks.MockEC2InterfaceMockRecorder)
+		expectedStatus  corev1.ConditionStatus
+		expectedChanged bool
+	}{
+		{
+			name:   "no maximum age",
+			config: &v1alpha1.AWSMachineProviderSpec{},
+			expect: func(m *mocks.MockEC2InterfaceMockRecorder) {},
+		},
+		{
+			name: "image within maximum age",
+			config: &v1alpha1.AWSMachineProviderSpec{
+				ImageMaxAge: &metav1.Duration{Duration: 24 * time.Hour},
+			},
+			expect: func(m *mocks.MockEC2InterfaceMockRecorder) {
+				m.ImageCreationDate("ami-1").Return(time.Now().Add(-time.Hour), nil)
+			},
+			expectedStatus:  corev1.ConditionFalse,
+			expectedChanged: true,
+		},
+		{
+			name: "image older than maximum age",
+			config: &v1alpha1.AWSMachineProviderSpec{
+				ImageMaxAge: &metav1.Duration{Duration: 24 * time.Hour},
+			},
+			expect: func(m *mocks.MockEC2InterfaceMockRecorder) {
+				m.ImageCreationDate("ami-1").Return(time.Now().Add(-48*time.Hour), nil)
+			},
+			expectedStatus:  corev1.ConditionTrue,
+			expectedChanged: true,
+		},
+		{
+			name: "deregistered image",
+			config: &v1alpha1.AWSMachineProviderSpec{
+				ImageMaxAge: &metav1.Duration{Duration: 24 * time.Hour},
+			},
+			expect: func(m *mocks.MockEC2InterfaceMockRecorder) {
+				m.ImageCreationDate("ami-1").Return(time.Time{}, awserrors.NewInvalidConfiguration(errors.New("ami \"ami-1\" not found")))
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+
+			ec2Mock := mocks.NewMockEC2Interface(mockCtrl)
+			tc.expect(ec2Mock.EXPECT())
+
+			status := &v1alpha1.AWSMachineProviderStatus{}
+			instance := &v1alpha1.Instance{ID: "i-1", ImageID: "ami-1"}
+
+			a := &Actuator{}
+			changed, err := a.ensureImageAge(ec2Mock, &clusterv1.Machine{}, instance, tc.config, status)
+			if err != nil {
+				t.Fatalf("did not expect error: %v", err)
+			}
+
+			if changed != tc.expectedChanged {
+				t.Fatalf("expected changed to be %t, got %t", tc.expectedChanged, changed)
+			}
+
+			if !tc.expectedChanged {
+				return
+			}
+
+			if len(status.Conditions) != 1 || status.Conditions[0].Type != v1alpha1.ImageOutdated {
+				t.Fatalf("expected a single %s condition, got %+v", v1alpha1.ImageOutdated, status.Conditions)
+			}
+
+			if status.Conditions[0].Status != tc.expectedStatus {
+				t.Fatalf("expected condition status %q, got %q", tc.expectedStatus, status.Conditions[0].Status)
+			}
+		})
+	}
+}
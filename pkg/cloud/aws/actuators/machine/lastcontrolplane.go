@@ -0,0 +1,76 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machine
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/actuators"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/record"
+)
+
+// lastControlPlaneRequeueAfter is how long the deletion of the last control
+// plane machine of a cluster is postponed before checking again whether it
+// is allowed.
+const lastControlPlaneRequeueAfter = time.Minute
+
+// ensureNotLastControlPlane prevents deleting a control plane machine from
+// taking down the last control plane of a cluster, which would leave it without an api server and
+// etcd. The machines of the cluster are counted rather than its instances, so
+// that control plane machines deleted together, e.g. with a DeleteCollection,
+// all wait for one another instead of all seeing the others still running.
+// Only the machines labelled with the name of the cluster are counted, as
+// other clusters may share its namespace.
+// The guard is lifted when the cluster is being deleted, when every machine
+// of the cluster is being deleted, as clusterctl does before deleting the
+// cluster, or with the SkipLastControlPlaneGuardAnnotation.
+// It returns true if the machine can be deleted.
+func ensureNotLastControlPlane(scope *actuators.MachineScope) (bool, error) {
+	if scope.Role() != "controlplane" || scope.Cluster.DeletionTimestamp != nil || scope.Skips(actuators.SkipLastControlPlaneGuardAnnotation) {
+		return true, nil
+	}
+
+	if scope.MachineClient == nil {
+		return true, nil
+	}
+
+	machines, err := scope.MachineClient.List(actuators.ClusterMachines(scope.Cluster))
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to list the machines of cluster %q", scope.Cluster.Name)
+	}
+
+	remaining, kept := 0, 0
+	for _, m := range machines.Items {
+		if m.DeletionTimestamp != nil {
+			continue
+		}
+		kept++
+		if m.Labels["set"] == "controlplane" {
+			remaining++
+		}
+	}
+
+	if remaining > 0 || kept == 0 {
+		return true, nil
+	}
+
+	record.Warnf(scope.Machine, "LastControlPlaneMachine",
+		"Refusing to delete machine %q, as no other control plane machine of cluster %q is left: set the %q annotation to \"true\" to delete it anyway",
+		scope.Name(), scope.Cluster.Name, actuators.SkipLastControlPlaneGuardAnnotation)
+	return false, nil
+}
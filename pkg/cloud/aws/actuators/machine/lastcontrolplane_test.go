@@ -0,0 +1,117 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machine
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/actuators"
+	clusterv1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+)
+
+func TestEnsureNotLastControlPlane(t *testing.T) {
+	now := metav1.Now()
+	machine := func(name, role string, deleting bool) clusterv1.Machine {
+		m := clusterv1.Machine{ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+			Labels:    map[string]string{"set": role, actuators.ClusterNameLabel: "test-cluster"},
+		}}
+		if deleting {
+			m.DeletionTimestamp = &now
+		}
+		return m
+	}
+	otherCluster := func(m clusterv1.Machine) clusterv1.Machine {
+		m.Labels[actuators.ClusterNameLabel] = "other-cluster"
+		return m
+	}
+
+	testCases := []struct {
+		name        string
+		cluster     *clusterv1.Cluster
+		annotations map[string]string
+		others      []clusterv1.Machine
+		expected    bool
+	}{
+		{
+			name:     "other control plane machines kept",
+			cluster:  &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"}},
+			others:   []clusterv1.Machine{machine("controlplane-1", "controlplane", false), machine("node-0", "node", false)},
+			expected: true,
+		},
+		{
+			name:    "last control plane machine",
+			cluster: &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"}},
+			others:  []clusterv1.Machine{machine("node-0", "node", false)},
+		},
+		{
+			name:    "control plane machine of another cluster kept",
+			cluster: &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"}},
+			others:  []clusterv1.Machine{otherCluster(machine("controlplane-1", "controlplane", false)), machine("node-0", "node", false)},
+		},
+		{
+			name:    "control plane machines deleted together",
+			cluster: &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"}},
+			others:  []clusterv1.Machine{machine("controlplane-1", "controlplane", true), machine("node-0", "node", false)},
+		},
+		{
+			name:     "every machine of the cluster being deleted",
+			cluster:  &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"}},
+			others:   []clusterv1.Machine{machine("controlplane-1", "controlplane", true), machine("node-0", "node", true)},
+			expected: true,
+		},
+		{
+			name:        "last control plane machine with override",
+			cluster:     &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"}},
+			annotations: map[string]string{actuators.SkipLastControlPlaneGuardAnnotation: "true"},
+			others:      []clusterv1.Machine{machine("node-0", "node", false)},
+			expected:    true,
+		},
+		{
+			name:     "last control plane machine of a cluster being deleted",
+			cluster:  &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test-cluster", DeletionTimestamp: &now}},
+			others:   []clusterv1.Machine{machine("node-0", "node", false)},
+			expected: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			m := machine("controlplane-0", "controlplane", true)
+			m.Annotations = tc.annotations
+
+			scope, err := actuators.NewMachineScope(actuators.MachineScopeParams{
+				Cluster: tc.cluster,
+				Machine: &m,
+			})
+			if err != nil {
+				t.Fatalf("failed to create scope: %v", err)
+			}
+			scope.MachineClient = &fakeMachineClient{machines: append(tc.others, m)}
+
+			allowed, err := ensureNotLastControlPlane(scope)
+			if err != nil {
+				t.Fatalf("did not expect error: %v", err)
+			}
+			if allowed != tc.expected {
+				t.Fatalf("expected allowed to be %t, got %t", tc.expected, allowed)
+			}
+		})
+	}
+}
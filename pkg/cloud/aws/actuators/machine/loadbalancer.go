@@ -0,0 +1,57 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machine
+
+import (
+	"time"
+
+	"k8s.io/klog"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/actuators"
+	service "sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/record"
+)
+
+// deregistrationRequeueAfter is how long the termination of a control plane
+// instance is postponed while the connections to it are drained.
+const deregistrationRequeueAfter = 10 * time.Second
+
+// ensureDeregistered deregisters a control plane instance being deleted from
+// the api server load balancer, so that it is not left as a dead target, and
+// returns true once the connections to it are drained and the instance can be
+// terminated.
+func (a *Actuator) ensureDeregistered(elbsvc service.ELBInterface, scope *actuators.MachineScope, instanceID string) (bool, error) {
+	if scope.Skips(actuators.SkipLoadBalancerAttachmentAnnotation) {
+		return true, nil
+	}
+
+	deregistered, err := elbsvc.InstanceDeregisteredFromAPIServerELB(instanceID)
+	if err != nil || deregistered {
+		return deregistered, err
+	}
+
+	if err := elbsvc.DeregisterInstanceFromAPIServerELB(instanceID); err != nil {
+		return false, err
+	}
+	record.Eventf(scope.Machine, "DeregisteredInstance", "Deregistered instance %q from the API server load balancer", instanceID)
+
+	// Without connection draining, the instance is deregistered right away.
+	deregistered, err = elbsvc.InstanceDeregisteredFromAPIServerELB(instanceID)
+	if err == nil && !deregistered {
+		klog.Infof("Waiting for the connections to instance %q of machine %q to be drained before terminating it", instanceID, scope.Name())
+	}
+	return deregistered, err
+}
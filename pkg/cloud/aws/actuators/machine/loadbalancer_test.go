@@ -0,0 +1,91 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machine
+
+import (
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/actuators"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/mocks"
+	clusterv1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+)
+
+func TestEnsureDeregistered(t *testing.T) {
+	testCases := []struct {
+		name     string
+		expect   func(m *mocks.MockELBInterfaceMockRecorder)
+		expected bool
+	}{
+		{
+			name: "already deregistered",
+			expect: func(m *mocks.MockELBInterfaceMockRecorder) {
+				m.InstanceDeregisteredFromAPIServerELB("i-1").Return(true, nil)
+			},
+			expected: true,
+		},
+		{
+			name: "deregistered right away",
+			expect: func(m *mocks.MockELBInterfaceMockRecorder) {
+				gomock.InOrder(
+					m.InstanceDeregisteredFromAPIServerELB("i-1").Return(false, nil),
+					m.DeregisterInstanceFromAPIServerELB("i-1").Return(nil),
+					m.InstanceDeregisteredFromAPIServerELB("i-1").Return(true, nil),
+				)
+			},
+			expected: true,
+		},
+		{
+			name: "connections being drained",
+			expect: func(m *mocks.MockELBInterfaceMockRecorder) {
+				gomock.InOrder(
+					m.InstanceDeregisteredFromAPIServerELB("i-1").Return(false, nil),
+					m.DeregisterInstanceFromAPIServerELB("i-1").Return(nil),
+					m.InstanceDeregisteredFromAPIServerELB("i-1").Return(false, nil),
+				)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+
+			elbMock := mocks.NewMockELBInterface(mockCtrl)
+			tc.expect(elbMock.EXPECT())
+
+			scope, err := actuators.NewMachineScope(actuators.MachineScopeParams{
+				Cluster: &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"}},
+				Machine: &clusterv1.Machine{ObjectMeta: metav1.ObjectMeta{Name: "controlplane-0", Labels: map[string]string{"set": "controlplane"}}},
+			})
+			if err != nil {
+				t.Fatalf("failed to create scope: %v", err)
+			}
+
+			a := &Actuator{}
+			deregistered, err := a.ensureDeregistered(elbMock, scope, "i-1")
+			if err != nil {
+				t.Fatalf("did not expect error: %v", err)
+			}
+			if deregistered != tc.expected {
+				t.Fatalf("expected deregistered to be %t, got %t", tc.expected, deregistered)
+			}
+		})
+	}
+}
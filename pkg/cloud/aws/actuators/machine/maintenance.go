@@ -0,0 +1,109 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machine
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	service "sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/record"
+	clusterv1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+)
+
+const (
+	// eventInstanceRetirement and eventInstanceStop are the scheduled events
+	// after which an instance is no longer running on its own.
+	eventInstanceRetirement = "instance-retirement"
+	eventInstanceStop       = "instance-stop"
+)
+
+func (a *Actuator) ensureScheduledEvents(svc service.EC2MachineInterface, n *notifier, machine *clusterv1.Machine, instanceID string, config *v1alpha1.AWSMachineProviderSpec, status *v1alpha1.AWSMachineProviderStatus) (bool, error) {
+	events, err := svc.InstanceEvents(instanceID)
+	if err != nil {
+		return false, err
+	}
+
+	condition := v1alpha1.AWSMachineProviderCondition{
+		Type:   v1alpha1.InstanceEventScheduled,
+		Status: corev1.ConditionFalse,
+	}
+
+	retiring := false
+	if len(events) > 0 {
+		descriptions := make([]string, 0, len(events))
+		for _, e := range events {
+			description := e.Code
+			if e.NotBefore != nil {
+				description = fmt.Sprintf("%s after %s", e.Code, e.NotBefore.UTC().Format("2006-01-02T15:04:05Z"))
+			}
+			descriptions = append(descriptions, description)
+
+			if e.Code == eventInstanceRetirement || e.Code == eventInstanceStop {
+				retiring = true
+			}
+		}
+
+		condition.Status = corev1.ConditionTrue
+		condition.Reason = "MaintenanceScheduled"
+		condition.Message = fmt.Sprintf("AWS scheduled events for instance %q: %s", instanceID, strings.Join(descriptions, ", "))
+	}
+
+	changed := setCondition(status, condition)
+	if changed && condition.Status == corev1.ConditionTrue {
+		record.Warn(machine, "ScheduledEvent", condition.Message)
+	}
+
+	if retiring && config.ReplaceOnRetirement {
+		if err := a.replaceMachine(n, machine, instanceID, "ahead of its instance retirement"); err != nil {
+			return changed, err
+		}
+	}
+
+	return changed, nil
+}
+
+// replaceMachine deletes a machine managed by a MachineSet, which then creates a replacement.
+// Machines without a MachineSet owner are left alone, as nothing would replace them.
+// The reason completes the event recorded on the machine and the notification.
+func (a *Actuator) replaceMachine(n *notifier, machine *clusterv1.Machine, instanceID, reason string) error {
+	if machine.DeletionTimestamp != nil || !ownedByMachineSet(machine) {
+		return nil
+	}
+
+	if err := a.client.Machines(machine.Namespace).Delete(machine.Name, &metav1.DeleteOptions{}); err != nil {
+		return errors.Wrapf(err, "failed to delete machine %q for replacement", machine.Name)
+	}
+
+	message := fmt.Sprintf("Deleted machine %q %s", machine.Name, reason)
+	record.Event(machine, "ReplacingMachine", message)
+	n.notify(machine, machineReplaced, instanceID, message)
+	return nil
+}
+
+func ownedByMachineSet(machine *clusterv1.Machine) bool {
+	for _, ref := range machine.OwnerReferences {
+		if ref.Kind == "MachineSet" {
+			return true
+		}
+	}
+	return false
+}
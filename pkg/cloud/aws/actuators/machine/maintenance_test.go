@@ -0,0 +1,88 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machine
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/mocks"
+	clusterv1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+)
+
+func TestEnsureScheduledEvents(t *testing.T) {
+	notBefore := metav1.NewTime(time.Now().Add(72 * time.Hour))
+
+	testCases := []struct {
+		name            string
+		events          []v1alpha1.InstanceEvent
+		expectedStatus  corev1.ConditionStatus
+		expectedReason  string
+		expectedChanged bool
+	}{
+		{
+			name:            "no scheduled events",
+			expectedStatus:  corev1.ConditionFalse,
+			expectedChanged: true,
+		},
+		{
+			name: "scheduled retirement",
+			events: []v1alpha1.InstanceEvent{
+				{Code: "instance-retirement", NotBefore: &notBefore},
+			},
+			expectedStatus:  corev1.ConditionTrue,
+			expectedReason:  "MaintenanceScheduled",
+			expectedChanged: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+
+			ec2Mock := mocks.NewMockEC2Interface(mockCtrl)
+			ec2Mock.EXPECT().InstanceEvents("i-1").Return(tc.events, nil)
+
+			// Machines without a MachineSet owner are never deleted for replacement.
+			config := &v1alpha1.AWSMachineProviderSpec{ReplaceOnRetirement: true}
+			status := &v1alpha1.AWSMachineProviderStatus{}
+
+			a := &Actuator{}
+			changed, err := a.ensureScheduledEvents(ec2Mock, nil, &clusterv1.Machine{}, "i-1", config, status)
+			if err != nil {
+				t.Fatalf("did not expect error: %v", err)
+			}
+
+			if changed != tc.expectedChanged {
+				t.Fatalf("expected changed to be %t, got %t", tc.expectedChanged, changed)
+			}
+
+			if len(status.Conditions) != 1 || status.Conditions[0].Type != v1alpha1.InstanceEventScheduled {
+				t.Fatalf("expected a single %s condition, got %+v", v1alpha1.InstanceEventScheduled, status.Conditions)
+			}
+
+			if status.Conditions[0].Status != tc.expectedStatus || status.Conditions[0].Reason != tc.expectedReason {
+				t.Fatalf("expected condition status %q and reason %q, got %+v", tc.expectedStatus, tc.expectedReason, status.Conditions[0])
+			}
+		})
+	}
+}
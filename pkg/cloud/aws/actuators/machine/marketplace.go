@@ -0,0 +1,65 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machine
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/awserrors"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/record"
+	clusterv1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+)
+
+// marketplaceSubscriptionRequeueAfter is how long the creation of a machine is
+// postponed when its AMI requires an AWS Marketplace subscription.
+const marketplaceSubscriptionRequeueAfter = 10 * time.Minute
+
+// Reports with the MarketplaceSubscriptionRequired condition whether the
+// launch of a machine failed because its AWS Marketplace AMI requires
+// subscribing to the product, which can only be done by a person accepting
+// its terms. The condition is only cleared on machines already reporting it.
+// Returns true if a subscription is required.
+func setMarketplaceSubscriptionCondition(machine *clusterv1.Machine, status *v1alpha1.AWSMachineProviderStatus, launchErr error) bool {
+	code, _ := awserrors.Code(errors.Cause(launchErr))
+	required := code == awserrors.OptInRequired
+
+	if !required && !hasCondition(status, v1alpha1.MarketplaceSubscriptionRequired) {
+		return false
+	}
+
+	condition := v1alpha1.AWSMachineProviderCondition{
+		Type:   v1alpha1.MarketplaceSubscriptionRequired,
+		Status: corev1.ConditionFalse,
+	}
+
+	if required {
+		condition.Status = corev1.ConditionTrue
+		condition.Reason = awserrors.OptInRequired
+		condition.Message = fmt.Sprintf("The AMI of the machine requires an AWS Marketplace subscription, accept the terms of the product and the launch will be retried: %s",
+			awserrors.Message(errors.Cause(launchErr)))
+	}
+
+	if setCondition(status, condition) && required {
+		record.Warn(machine, "MarketplaceSubscriptionRequired", condition.Message)
+	}
+
+	return required
+}
@@ -0,0 +1,56 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machine
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	clusterv1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+)
+
+func TestSetMarketplaceSubscriptionCondition(t *testing.T) {
+	optIn := errors.Wrap(awserr.New("OptInRequired", "In order to use this AWS Marketplace product you need to accept terms and subscribe.", nil), "failed to run instance")
+
+	// Launch failures unrelated to the Marketplace do not add the condition.
+	status := &v1alpha1.AWSMachineProviderStatus{}
+	if setMarketplaceSubscriptionCondition(&clusterv1.Machine{}, status, awserr.New("InsufficientInstanceCapacity", "no capacity", nil)) || len(status.Conditions) != 0 {
+		t.Fatalf("expected no condition, got %+v", status.Conditions)
+	}
+
+	if !setMarketplaceSubscriptionCondition(&clusterv1.Machine{}, status, optIn) {
+		t.Fatalf("expected a subscription to be required")
+	}
+	if len(status.Conditions) != 1 || status.Conditions[0].Type != v1alpha1.MarketplaceSubscriptionRequired || status.Conditions[0].Status != corev1.ConditionTrue {
+		t.Fatalf("expected a true %s condition, got %+v", v1alpha1.MarketplaceSubscriptionRequired, status.Conditions)
+	}
+	if !strings.Contains(status.Conditions[0].Message, "accept terms and subscribe") {
+		t.Fatalf("expected the condition message to include the AWS error message, got %q", status.Conditions[0].Message)
+	}
+
+	// The condition is cleared once the instance launches.
+	if setMarketplaceSubscriptionCondition(&clusterv1.Machine{}, status, nil) {
+		t.Fatalf("expected no subscription to be required")
+	}
+	if len(status.Conditions) != 1 || status.Conditions[0].Status != corev1.ConditionFalse {
+		t.Fatalf("expected a false %s condition, got %+v", v1alpha1.MarketplaceSubscriptionRequired, status.Conditions)
+	}
+}
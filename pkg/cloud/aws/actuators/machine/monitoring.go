@@ -0,0 +1,44 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machine
+
+import (
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	service "sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/record"
+	clusterv1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+)
+
+// ensureDetailedMonitoring enables or disables the detailed monitoring of the
+// machine instance as set in the machine provider config, and reports whether
+// it had to be toggled.
+func (a *Actuator) ensureDetailedMonitoring(svc service.EC2MachineInterface, machine *clusterv1.Machine, instance *v1alpha1.Instance, config *v1alpha1.AWSMachineProviderSpec) (bool, error) {
+	if instance.DetailedMonitoring == config.EnableDetailedMonitoring {
+		return false, nil
+	}
+
+	if err := svc.SetInstanceMonitoring(instance.ID, config.EnableDetailedMonitoring); err != nil {
+		return false, err
+	}
+
+	if config.EnableDetailedMonitoring {
+		record.Eventf(machine, "EnabledDetailedMonitoring", "Enabled detailed monitoring of instance %q", instance.ID)
+	} else {
+		record.Eventf(machine, "DisabledDetailedMonitoring", "Disabled detailed monitoring of instance %q", instance.ID)
+	}
+	return true, nil
+}
@@ -0,0 +1,53 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machine
+
+import (
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/mocks"
+	clusterv1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+)
+
+func TestEnsureDetailedMonitoring(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	ec2Mock := mocks.NewMockEC2Interface(mockCtrl)
+	ec2Mock.EXPECT().SetInstanceMonitoring("i-1", false).Return(nil)
+
+	a := &Actuator{}
+	instance := &v1alpha1.Instance{ID: "i-1"}
+	config := &v1alpha1.AWSMachineProviderSpec{}
+
+	changed, err := a.ensureDetailedMonitoring(ec2Mock, &clusterv1.Machine{}, instance, config)
+	if err != nil || changed {
+		t.Fatalf("expected unmonitored instances to be left alone, got %t, %v", changed, err)
+	}
+
+	instance.DetailedMonitoring = true
+	changed, err = a.ensureDetailedMonitoring(ec2Mock, &clusterv1.Machine{}, instance, config)
+	if err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+
+	if !changed {
+		t.Fatalf("expected the detailed monitoring to be disabled")
+	}
+}
@@ -0,0 +1,104 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machine
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"k8s.io/klog"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/actuators"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/sns"
+	clusterv1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+)
+
+// machineEvent is a lifecycle event of a machine published to the
+// notification topic of its cluster.
+type machineEvent string
+
+const (
+	machineCreated  = machineEvent("created")
+	machineFailed   = machineEvent("failed")
+	machineDeleted  = machineEvent("deleted")
+	machineReplaced = machineEvent("replaced")
+)
+
+// machineNotification is the message published for a lifecycle event.
+type machineNotification struct {
+	Event      machineEvent `json:"event"`
+	Time       time.Time    `json:"time"`
+	Cluster    string       `json:"cluster"`
+	Namespace  string       `json:"namespace"`
+	Machine    string       `json:"machine"`
+	Role       string       `json:"role,omitempty"`
+	InstanceID string       `json:"instanceID,omitempty"`
+	Message    string       `json:"message,omitempty"`
+}
+
+// notifier publishes the lifecycle events of the machines of a cluster.
+// A nil notifier publishes nothing.
+type notifier struct {
+	publisher sns.Publisher
+	topicARN  string
+	cluster   string
+}
+
+// newNotifier returns the notifier of the cluster of the scope, or nil if
+// the cluster has no notification topic.
+func newNotifier(scope *actuators.Scope) *notifier {
+	if scope.ClusterConfig.NotificationTopicARN == "" {
+		return nil
+	}
+
+	return &notifier{
+		publisher: scope.SNS,
+		topicARN:  scope.ClusterConfig.NotificationTopicARN,
+		cluster:   scope.Name(),
+	}
+}
+
+// notify publishes a lifecycle event of a machine. Notifications are best
+// effort: failures are logged and never fail the reconciliation.
+func (n *notifier) notify(machine *clusterv1.Machine, event machineEvent, instanceID, message string) {
+	if n == nil {
+		return
+	}
+
+	body, err := json.Marshal(machineNotification{
+		Event:      event,
+		Time:       time.Now().UTC(),
+		Cluster:    n.cluster,
+		Namespace:  machine.Namespace,
+		Machine:    machine.Name,
+		Role:       machine.Labels["set"],
+		InstanceID: instanceID,
+		Message:    message,
+	})
+	if err != nil {
+		klog.Warningf("Failed to encode %s notification for machine %q: %v", event, machine.Name, err)
+		return
+	}
+
+	subject := fmt.Sprintf("Machine %s of cluster %s %s", machine.Name, n.cluster, event)
+	if err := n.publisher.Publish(n.topicARN, subject, string(body)); err != nil {
+		klog.Warningf("Failed to publish %s notification for machine %q: %v", event, machine.Name, err)
+		return
+	}
+
+	klog.V(2).Infof("Published %s notification for machine %q", event, machine.Name)
+}
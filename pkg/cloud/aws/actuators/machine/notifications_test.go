@@ -0,0 +1,85 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machine
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+)
+
+// fakePublisher records the messages published to SNS topics.
+type fakePublisher struct {
+	topics   []string
+	subjects []string
+	messages []string
+}
+
+func (f *fakePublisher) Publish(topicARN, subject, message string) error {
+	f.topics = append(f.topics, topicARN)
+	f.subjects = append(f.subjects, subject)
+	f.messages = append(f.messages, message)
+	return nil
+}
+
+func TestNotify(t *testing.T) {
+	publisher := &fakePublisher{}
+	n := &notifier{publisher: publisher, topicARN: "arn:aws:sns:us-east-1:123456789012:machines", cluster: "test-cluster"}
+	machine := &clusterv1.Machine{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-machine",
+			Namespace: "default",
+			Labels:    map[string]string{"set": "node"},
+		},
+	}
+
+	n.notify(machine, machineFailed, "i-1", "Machine did not join the cluster within 10m0s")
+
+	if len(publisher.messages) != 1 || publisher.topics[0] != n.topicARN {
+		t.Fatalf("expected a single message to %q, got %v to %v", n.topicARN, publisher.messages, publisher.topics)
+	}
+
+	if expected := "Machine test-machine of cluster test-cluster failed"; publisher.subjects[0] != expected {
+		t.Fatalf("expected subject %q, got %q", expected, publisher.subjects[0])
+	}
+
+	notification := machineNotification{}
+	if err := json.Unmarshal([]byte(publisher.messages[0]), &notification); err != nil {
+		t.Fatalf("failed to decode message: %v", err)
+	}
+	notification.Time = time.Time{}
+
+	expected := machineNotification{
+		Event:      machineFailed,
+		Cluster:    "test-cluster",
+		Namespace:  "default",
+		Machine:    "test-machine",
+		Role:       "node",
+		InstanceID: "i-1",
+		Message:    "Machine did not join the cluster within 10m0s",
+	}
+	if notification != expected {
+		t.Fatalf("expected notification %+v, got %+v", expected, notification)
+	}
+
+	// Clusters without a notification topic have no notifier.
+	var none *notifier
+	none.notify(machine, machineCreated, "i-1", "")
+}
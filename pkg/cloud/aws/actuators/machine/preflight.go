@@ -0,0 +1,75 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machine
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	service "sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/userdata"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/record"
+	clusterv1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+)
+
+// ensureBootstrapPreflight reads the result of the connectivity preflight that
+// a machine which has not joined the cluster yet writes to its instance
+// console, sets the BootstrapBlocked condition when it failed, and reports
+// whether the condition changed.
+func (a *Actuator) ensureBootstrapPreflight(svc service.EC2MachineInterface, machine *clusterv1.Machine, config *v1alpha1.AWSMachineProviderSpec, status *v1alpha1.AWSMachineProviderStatus) (bool, error) {
+	if !config.ConnectivityPreflight {
+		return false, nil
+	}
+
+	condition := v1alpha1.AWSMachineProviderCondition{
+		Type:   v1alpha1.BootstrapBlocked,
+		Status: corev1.ConditionFalse,
+	}
+
+	if machine.Status.NodeRef == nil {
+		output, err := svc.GetConsoleOutput(aws.StringValue(status.InstanceID))
+		if err != nil {
+			return false, err
+		}
+
+		if endpoint := unreachableEndpoint(output); endpoint != "" {
+			condition.Status = corev1.ConditionTrue
+			condition.Reason = "NetworkUnreachable"
+			condition.Message = fmt.Sprintf("Bootstrap blocked by networking, endpoint %q is unreachable from the instance", endpoint)
+		}
+	}
+
+	changed := setCondition(status, condition)
+	if changed && condition.Status == corev1.ConditionTrue {
+		record.Warn(machine, "BootstrapBlocked", condition.Message)
+	}
+
+	return changed, nil
+}
+
+// unreachableEndpoint returns the endpoint reported by a failed preflight in the console output, if any.
+func unreachableEndpoint(output string) string {
+	for _, line := range strings.Split(output, "\n") {
+		if i := strings.Index(line, userdata.PreflightFailureMarker); i >= 0 {
+			return strings.TrimSpace(line[i+len(userdata.PreflightFailureMarker):])
+		}
+	}
+	return ""
+}
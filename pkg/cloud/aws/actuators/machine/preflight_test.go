@@ -0,0 +1,88 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machine
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/golang/mock/gomock"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/mocks"
+	clusterv1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+)
+
+func TestEnsureBootstrapPreflight(t *testing.T) {
+	testCases := []struct {
+		name           string
+		machine        *clusterv1.Machine
+		expect         func(m *mocks.MockEC2InterfaceMockRecorder)
+		expectedStatus corev1.ConditionStatus
+	}{
+		{
+			name:    "preflight passed",
+			machine: &clusterv1.Machine{},
+			expect: func(m *mocks.MockEC2InterfaceMockRecorder) {
+				m.GetConsoleOutput("i-1").Return("Cloud-init v. 18.4 running 'modules:final'\n", nil)
+			},
+			expectedStatus: corev1.ConditionFalse,
+		},
+		{
+			name:    "preflight failed",
+			machine: &clusterv1.Machine{},
+			expect: func(m *mocks.MockEC2InterfaceMockRecorder) {
+				m.GetConsoleOutput("i-1").Return("[   42.1] cloud-init[1024]: cluster-api-provider-aws preflight: unreachable endpoint api.ecr.us-east-1.amazonaws.com:443\n", nil)
+			},
+			expectedStatus: corev1.ConditionTrue,
+		},
+		{
+			name: "machine joined",
+			machine: &clusterv1.Machine{
+				Status: clusterv1.MachineStatus{NodeRef: &corev1.ObjectReference{Name: "node-1"}},
+			},
+			expect:         func(m *mocks.MockEC2InterfaceMockRecorder) {},
+			expectedStatus: corev1.ConditionFalse,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+
+			ec2Mock := mocks.NewMockEC2Interface(mockCtrl)
+			tc.expect(ec2Mock.EXPECT())
+
+			config := &v1alpha1.AWSMachineProviderSpec{ConnectivityPreflight: true}
+			status := &v1alpha1.AWSMachineProviderStatus{InstanceID: aws.String("i-1")}
+
+			a := &Actuator{}
+			if _, err := a.ensureBootstrapPreflight(ec2Mock, tc.machine, config, status); err != nil {
+				t.Fatalf("did not expect error: %v", err)
+			}
+
+			if len(status.Conditions) != 1 || status.Conditions[0].Type != v1alpha1.BootstrapBlocked {
+				t.Fatalf("expected a single %s condition, got %+v", v1alpha1.BootstrapBlocked, status.Conditions)
+			}
+
+			if status.Conditions[0].Status != tc.expectedStatus {
+				t.Fatalf("expected condition status %q, got %q", tc.expectedStatus, status.Conditions[0].Status)
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machine
+
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/actuators"
+	service "sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/drain"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/record"
+	clusterv1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+	controllerError "sigs.k8s.io/cluster-api/pkg/controller/error"
+)
+
+const (
+	// RebootBootIDAnnotation is the key for the machine object annotation
+	// which tracks the boot ID of a node drained before its instance was
+	// rebooted, or restarted to scrub its user data, to uncordon the node
+	// once it booted again.
+	RebootBootIDAnnotation = "sigs.k8s.io/cluster-api-provider-aws/reboot-boot-id"
+
+	rebootNow   = "true"
+	rebootDrain = "drain"
+
+	// drainRequeueAfter is how long the reboot of an instance is postponed
+	// while pods are being evicted from its node.
+	drainRequeueAfter = 20 * time.Second
+)
+
+// workloadClientFunc returns a client of the Kubernetes API of the cluster of a machine.
+type workloadClientFunc func() (kubernetes.Interface, error)
+
+// ensureReboot reboots the instance of the machine when the reboot annotation
+// is set, draining its node first if requested, and returns true on the update
+// that rebooted it. Draining is waited for with a RequeueAfterError.
+func (a *Actuator) ensureReboot(svc service.EC2MachineInterface, workloadClient workloadClientFunc, machine *clusterv1.Machine, status *v1alpha1.AWSMachineProviderStatus) (bool, error) {
+	mode := a.machineAnnotation(machine, actuators.RebootAnnotation)
+	bootID := a.machineAnnotation(machine, RebootBootIDAnnotation)
+
+	if mode == "" && bootID == "" {
+		return false, nil
+	}
+
+	if mode != "" && mode != rebootNow && mode != rebootDrain {
+		record.Warnf(machine, "InvalidReboot", "Ignoring unknown reboot mode %q, expected %q or %q", mode, rebootNow, rebootDrain)
+		delete(machine.Annotations, actuators.RebootAnnotation)
+		return true, nil
+	}
+
+	drainNode := mode == rebootDrain || bootID != ""
+	if drainNode && machine.Status.NodeRef == nil {
+		delete(machine.Annotations, RebootBootIDAnnotation)
+		if mode == "" {
+			return true, nil
+		}
+
+		klog.Infof("Machine %q has no node to drain, rebooting it right away", machine.Name)
+		drainNode = false
+	}
+
+	var client kubernetes.Interface
+	var node *corev1.Node
+	if drainNode {
+		var err error
+		if client, err = workloadClient(); err != nil {
+			return false, err
+		}
+
+		node, err = client.CoreV1().Nodes().Get(machine.Status.NodeRef.Name, metav1.GetOptions{})
+		if err != nil {
+			return false, errors.Wrapf(err, "failed to get node %q", machine.Status.NodeRef.Name)
+		}
+	}
+
+	// The instance was rebooted after its node was drained: uncordon the node
+	// once it booted again and is ready.
+	if mode == "" {
+		if node.Status.NodeInfo.BootID == bootID || !nodeReady(node) {
+			return false, nil
+		}
+
+		if err := drain.Cordon(client, node, false); err != nil {
+			return false, err
+		}
+
+		delete(machine.Annotations, RebootBootIDAnnotation)
+		record.Eventf(machine, "UncordonedNode", "Uncordoned node %q after its reboot", node.Name)
+		return true, nil
+	}
+
+	if drainNode {
+		if err := drain.Cordon(client, node, true); err != nil {
+			return false, err
+		}
+
+		drained, err := drain.Drain(client, node.Name)
+		if err != nil {
+			return false, err
+		}
+
+		if !drained {
+			klog.Infof("Waiting for the pods of node %q to be evicted before rebooting machine %q", node.Name, machine.Name)
+			return false, &controllerError.RequeueAfterError{RequeueAfter: drainRequeueAfter}
+		}
+	}
+
+	instanceID := aws.StringValue(status.InstanceID)
+	if err := svc.RebootInstance(instanceID); err != nil {
+		return false, err
+	}
+
+	delete(machine.Annotations, actuators.RebootAnnotation)
+	if drainNode {
+		a.updateMachineAnnotation(machine, RebootBootIDAnnotation, node.Status.NodeInfo.BootID)
+	}
+
+	record.Eventf(machine, "RebootedInstance", "Rebooted instance %q", instanceID)
+	return true, nil
+}
+
+// nodeReady returns true if the node reports the Ready condition.
+func nodeReady(node *corev1.Node) bool {
+	for _, c := range node.Status.Conditions {
+		if c.Type == corev1.NodeReady {
+			return c.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
@@ -0,0 +1,85 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machine
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/golang/mock/gomock"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/actuators"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/mocks"
+	clusterv1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+)
+
+func TestEnsureReboot(t *testing.T) {
+	noWorkloadClient := func() (kubernetes.Interface, error) {
+		return nil, errors.New("unexpected workload client")
+	}
+
+	testCases := []struct {
+		name         string
+		annotations  map[string]string
+		expectReboot bool
+	}{
+		{
+			name: "no reboot requested",
+		},
+		{
+			name:         "reboot requested",
+			annotations:  map[string]string{actuators.RebootAnnotation: "true"},
+			expectReboot: true,
+		},
+		{
+			name:         "drain without a node",
+			annotations:  map[string]string{actuators.RebootAnnotation: "drain"},
+			expectReboot: true,
+		},
+		{
+			name:        "unknown reboot mode",
+			annotations: map[string]string{actuators.RebootAnnotation: "now"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+
+			ec2Mock := mocks.NewMockEC2Interface(mockCtrl)
+			if tc.expectReboot {
+				ec2Mock.EXPECT().RebootInstance("i-1").Return(nil)
+			}
+
+			a := &Actuator{}
+			machine := &clusterv1.Machine{ObjectMeta: metav1.ObjectMeta{Name: "test", Annotations: tc.annotations}}
+			status := &v1alpha1.AWSMachineProviderStatus{InstanceID: aws.String("i-1")}
+
+			if _, err := a.ensureReboot(ec2Mock, noWorkloadClient, machine, status); err != nil {
+				t.Fatalf("did not expect error: %v", err)
+			}
+
+			if _, ok := machine.Annotations[actuators.RebootAnnotation]; ok {
+				t.Fatalf("expected the reboot annotation to be removed")
+			}
+		})
+	}
+}
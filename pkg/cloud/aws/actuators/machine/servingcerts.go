@@ -0,0 +1,182 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machine
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/pkg/errors"
+	certificatesv1beta1 "k8s.io/api/certificates/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/record"
+	clusterv1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+)
+
+// servingCertificateUsages are the key usages kubelets may request for their
+// serving certificates. Server auth and digital signature are required, key
+// encipherment is only requested for RSA keys.
+var servingCertificateUsages = sets.NewString(
+	string(certificatesv1beta1.UsageDigitalSignature),
+	string(certificatesv1beta1.UsageKeyEncipherment),
+	string(certificatesv1beta1.UsageServerAuth),
+)
+
+// requiredServingCertificateUsages are the key usages every serving
+// certificate request must have.
+var requiredServingCertificateUsages = sets.NewString(
+	string(certificatesv1beta1.UsageDigitalSignature),
+	string(certificatesv1beta1.UsageServerAuth),
+)
+
+// Approves the pending serving certificate signing requests of the kubelet of
+// the node of a machine. Kubelets request their serving certificates from the
+// cluster CA and rotate them before they expire, each rotation requiring a new
+// request to be approved. Requests are only approved when the addresses they
+// certify are addresses of the instance as described by EC2, and are left
+// pending for an administrator otherwise. The addresses reported by the node
+// are not trusted, since the kubelet sets them itself.
+func (a *Actuator) ensureServingCertificates(workloadClient workloadClientFunc, machine *clusterv1.Machine, instance *v1alpha1.Instance) error {
+	if machine.Status.NodeRef == nil {
+		return nil
+	}
+
+	client, err := workloadClient()
+	if err != nil {
+		return err
+	}
+
+	nodeName := machine.Status.NodeRef.Name
+	csrs, err := client.CertificatesV1beta1().CertificateSigningRequests().List(metav1.ListOptions{})
+	if err != nil {
+		return errors.Wrap(err, "failed to list certificate signing requests")
+	}
+
+	ips, dnsNames := instanceAddresses(instance)
+
+	for i := range csrs.Items {
+		csr := &csrs.Items[i]
+		if csr.Spec.Username != "system:node:"+nodeName || csrHandled(csr) {
+			continue
+		}
+
+		if err := validateServingCSR(csr, nodeName, ips, dnsNames); err != nil {
+			klog.Warningf("Not approving certificate signing request %q of node %q: %v", csr.Name, nodeName, err)
+			record.Warnf(machine, "UnapprovedServingCertificate", "Not approving certificate signing request %q: %v", csr.Name, err)
+			continue
+		}
+
+		csr.Status.Conditions = append(csr.Status.Conditions, certificatesv1beta1.CertificateSigningRequestCondition{
+			Type:           certificatesv1beta1.CertificateApproved,
+			Reason:         "AutoApproved",
+			Message:        fmt.Sprintf("Approved by the controller of machine %q", machine.Name),
+			LastUpdateTime: metav1.Now(),
+		})
+		if _, err := client.CertificatesV1beta1().CertificateSigningRequests().UpdateApproval(csr); err != nil {
+			return errors.Wrapf(err, "failed to approve certificate signing request %q", csr.Name)
+		}
+
+		record.Eventf(machine, "ApprovedServingCertificate", "Approved serving certificate signing request %q of node %q", csr.Name, nodeName)
+	}
+
+	return nil
+}
+
+// instanceAddresses returns the IP addresses and DNS names of an instance a
+// serving certificate of its node may certify.
+func instanceAddresses(instance *v1alpha1.Instance) (ips, dnsNames sets.String) {
+	ips = sets.NewString()
+	for _, ip := range []*string{instance.PrivateIP, instance.PublicIP} {
+		if aws.StringValue(ip) != "" {
+			ips.Insert(*ip)
+		}
+	}
+	dnsNames = sets.NewString()
+	for _, name := range []*string{instance.PrivateDNSName, instance.PublicDNSName} {
+		if aws.StringValue(name) != "" {
+			dnsNames.Insert(*name)
+		}
+	}
+	return ips, dnsNames
+}
+
+// csrHandled returns whether a certificate signing request was already
+// approved or denied.
+func csrHandled(csr *certificatesv1beta1.CertificateSigningRequest) bool {
+	for _, c := range csr.Status.Conditions {
+		if c.Type == certificatesv1beta1.CertificateApproved || c.Type == certificatesv1beta1.CertificateDenied {
+			return true
+		}
+	}
+	return false
+}
+
+// validateServingCSR checks that a certificate signing request is a kubelet
+// serving certificate request of the given node, for the given addresses only.
+func validateServingCSR(csr *certificatesv1beta1.CertificateSigningRequest, nodeName string, ips, dnsNames sets.String) error {
+	usages := sets.NewString()
+	for _, usage := range csr.Spec.Usages {
+		if !servingCertificateUsages.Has(string(usage)) {
+			return errors.Errorf("unexpected key usage %q", usage)
+		}
+		usages.Insert(string(usage))
+	}
+	if missing := requiredServingCertificateUsages.Difference(usages); missing.Len() > 0 {
+		return errors.Errorf("missing key usages %q", strings.Join(missing.List(), ","))
+	}
+
+	block, _ := pem.Decode(csr.Spec.Request)
+	if block == nil || block.Type != "CERTIFICATE REQUEST" {
+		return errors.New("request is not a PEM encoded certificate request")
+	}
+	req, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		return errors.Wrap(err, "failed to parse certificate request")
+	}
+
+	if req.Subject.CommonName != "system:node:"+nodeName {
+		return errors.Errorf("unexpected common name %q", req.Subject.CommonName)
+	}
+	if len(req.Subject.Organization) != 1 || req.Subject.Organization[0] != "system:nodes" {
+		return errors.Errorf("unexpected organization %q", strings.Join(req.Subject.Organization, ","))
+	}
+	if len(req.EmailAddresses) > 0 || len(req.URIs) > 0 {
+		return errors.New("request has email or URI subject alternative names")
+	}
+	if len(req.IPAddresses) == 0 && len(req.DNSNames) == 0 {
+		return errors.New("request has no subject alternative names")
+	}
+
+	for _, ip := range req.IPAddresses {
+		if !ips.Has(ip.String()) {
+			return errors.Errorf("IP address %s is not an address of the instance", ip)
+		}
+	}
+	for _, name := range req.DNSNames {
+		if !dnsNames.Has(name) {
+			return errors.Errorf("DNS name %q is not a name of the instance", name)
+		}
+	}
+
+	return nil
+}
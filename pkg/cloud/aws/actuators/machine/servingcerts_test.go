@@ -0,0 +1,199 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machine
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	certificatesv1beta1 "k8s.io/api/certificates/v1beta1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+)
+
+func TestValidateServingCSR(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	request := func(cn string, ips []string, dnsNames []string) []byte {
+		template := &x509.CertificateRequest{
+			Subject:  pkix.Name{CommonName: cn, Organization: []string{"system:nodes"}},
+			DNSNames: dnsNames,
+		}
+		for _, ip := range ips {
+			template.IPAddresses = append(template.IPAddresses, net.ParseIP(ip))
+		}
+		der, err := x509.CreateCertificateRequest(rand.Reader, template, key)
+		if err != nil {
+			t.Fatalf("failed to create certificate request: %v", err)
+		}
+		return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: der})
+	}
+
+	nodeName := "ip-10-0-0-1.ec2.internal"
+	publicName := "ec2-203-0-113-1.compute-1.amazonaws.com"
+	serving := []certificatesv1beta1.KeyUsage{
+		certificatesv1beta1.UsageDigitalSignature,
+		certificatesv1beta1.UsageKeyEncipherment,
+		certificatesv1beta1.UsageServerAuth,
+	}
+
+	testCases := []struct {
+		name        string
+		request     []byte
+		usages      []certificatesv1beta1.KeyUsage
+		expectError string
+	}{
+		{
+			name:    "addresses of the instance and node",
+			request: request("system:node:"+nodeName, []string{"10.0.0.1", "203.0.113.1"}, []string{nodeName}),
+			usages:  serving,
+		},
+		{
+			name:    "public DNS name of the instance",
+			request: request("system:node:"+nodeName, []string{"203.0.113.1"}, []string{nodeName, publicName}),
+			usages:  serving,
+		},
+		{
+			name:        "IP address of another instance",
+			request:     request("system:node:"+nodeName, []string{"10.0.0.2"}, []string{nodeName}),
+			usages:      serving,
+			expectError: "is not an address of the instance",
+		},
+		{
+			name:        "DNS name of another node",
+			request:     request("system:node:"+nodeName, []string{"10.0.0.1"}, []string{"kubernetes.default"}),
+			usages:      serving,
+			expectError: "is not a name of the instance",
+		},
+		{
+			name:        "common name of another node",
+			request:     request("system:node:ip-10-0-0-2.ec2.internal", []string{"10.0.0.1"}, nil),
+			usages:      serving,
+			expectError: "unexpected common name",
+		},
+		{
+			name:        "client certificate",
+			request:     request("system:node:"+nodeName, []string{"10.0.0.1"}, nil),
+			usages:      []certificatesv1beta1.KeyUsage{certificatesv1beta1.UsageClientAuth},
+			expectError: "unexpected key usage",
+		},
+		{
+			name:    "ECDSA serving certificate",
+			request: request("system:node:"+nodeName, []string{"10.0.0.1"}, nil),
+			usages: []certificatesv1beta1.KeyUsage{
+				certificatesv1beta1.UsageDigitalSignature,
+				certificatesv1beta1.UsageServerAuth,
+			},
+		},
+		{
+			name:        "no key usages",
+			request:     request("system:node:"+nodeName, []string{"10.0.0.1"}, nil),
+			expectError: "missing key usages",
+		},
+		{
+			name:        "signing only",
+			request:     request("system:node:"+nodeName, []string{"10.0.0.1"}, nil),
+			usages:      []certificatesv1beta1.KeyUsage{certificatesv1beta1.UsageDigitalSignature},
+			expectError: "missing key usages \"server auth\"",
+		},
+		{
+			name:        "not a certificate request",
+			request:     []byte("invalid"),
+			usages:      serving,
+			expectError: "not a PEM encoded certificate request",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			csr := &certificatesv1beta1.CertificateSigningRequest{
+				Spec: certificatesv1beta1.CertificateSigningRequestSpec{
+					Request:  tc.request,
+					Usages:   tc.usages,
+					Username: "system:node:" + nodeName,
+				},
+			}
+
+			err := validateServingCSR(csr, nodeName, sets.NewString("10.0.0.1", "203.0.113.1"), sets.NewString(nodeName, publicName))
+			if tc.expectError == "" {
+				if err != nil {
+					t.Fatalf("did not expect error: %v", err)
+				}
+				return
+			}
+
+			if err == nil || !strings.Contains(err.Error(), tc.expectError) {
+				t.Fatalf("expected error containing %q, got: %v", tc.expectError, err)
+			}
+		})
+	}
+}
+
+func TestInstanceAddresses(t *testing.T) {
+	testCases := []struct {
+		name             string
+		instance         *v1alpha1.Instance
+		expectedIPs      []string
+		expectedDNSNames []string
+	}{
+		{
+			name: "private instance",
+			instance: &v1alpha1.Instance{
+				PrivateIP:      aws.String("10.0.0.1"),
+				PrivateDNSName: aws.String("ip-10-0-0-1.ec2.internal"),
+				PublicDNSName:  aws.String(""),
+			},
+			expectedIPs:      []string{"10.0.0.1"},
+			expectedDNSNames: []string{"ip-10-0-0-1.ec2.internal"},
+		},
+		{
+			name: "public instance",
+			instance: &v1alpha1.Instance{
+				PrivateIP:      aws.String("10.0.0.1"),
+				PrivateDNSName: aws.String("ip-10-0-0-1.ec2.internal"),
+				PublicIP:       aws.String("203.0.113.1"),
+				PublicDNSName:  aws.String("ec2-203-0-113-1.compute-1.amazonaws.com"),
+			},
+			expectedIPs:      []string{"10.0.0.1", "203.0.113.1"},
+			expectedDNSNames: []string{"ec2-203-0-113-1.compute-1.amazonaws.com", "ip-10-0-0-1.ec2.internal"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ips, dnsNames := instanceAddresses(tc.instance)
+			if !reflect.DeepEqual(ips.List(), tc.expectedIPs) {
+				t.Fatalf("expected ips %v, got %v", tc.expectedIPs, ips.List())
+			}
+			if !reflect.DeepEqual(dnsNames.List(), tc.expectedDNSNames) {
+				t.Fatalf("expected dns names %v, got %v", tc.expectedDNSNames, dnsNames.List())
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machine
+
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"k8s.io/klog"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/actuators"
+	service "sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/record"
+	clusterv1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+	controllerError "sigs.k8s.io/cluster-api/pkg/controller/error"
+)
+
+// stopRequeueAfter is how long a machine waits for its instance to stop
+// before it is started again, or for its instance to start.
+const stopRequeueAfter = 20 * time.Second
+
+// ensureStopped stops the instance of the machine while the stop annotation
+// is set, and starts it again once it is removed. It returns true while the
+// instance is not running, as there is then nothing else to reconcile. The
+// annotation is ignored on control plane and spot machines, whose instances
+// cannot be stopped.
+func (a *Actuator) ensureStopped(svc service.EC2MachineInterface, machine *clusterv1.Machine, instance *v1alpha1.Instance, status *v1alpha1.AWSMachineProviderStatus) (bool, error) {
+	if instance == nil {
+		return false, nil
+	}
+
+	stop := a.machineAnnotation(machine, actuators.StopAnnotation) == "true"
+	status.InstanceState = aws.String(string(instance.State))
+
+	switch instance.State {
+	case v1alpha1.InstanceStatePending, v1alpha1.InstanceStateRunning:
+		if !stop {
+			return false, nil
+		}
+
+		if machine.Labels["set"] == "controlplane" || instance.Spot {
+			record.Warnf(machine, "InvalidStop", "Ignoring the %q annotation of machine %q: control plane and spot instances cannot be stopped",
+				actuators.StopAnnotation, machine.Name)
+			return false, nil
+		}
+
+		if err := svc.StopInstance(instance.ID); err != nil {
+			return false, err
+		}
+
+		status.InstanceState = aws.String(string(v1alpha1.InstanceStateStopping))
+		record.Eventf(machine, "StoppedInstance", "Stopped instance %q", instance.ID)
+		return true, nil
+
+	case v1alpha1.InstanceStateStopping:
+		if stop {
+			return true, nil
+		}
+
+		klog.Infof("Waiting for instance %q to stop before starting machine %q again", instance.ID, machine.Name)
+		return true, &controllerError.RequeueAfterError{RequeueAfter: stopRequeueAfter}
+
+	case v1alpha1.InstanceStateStopped:
+		if stop {
+			return true, nil
+		}
+
+		if err := svc.StartInstance(instance.ID); err != nil {
+			return false, err
+		}
+
+		status.InstanceState = aws.String(string(v1alpha1.InstanceStatePending))
+		record.Eventf(machine, "StartedInstance", "Started instance %q", instance.ID)
+
+		// The rest of the machine is reconciled once the instance is running.
+		return true, &controllerError.RequeueAfterError{RequeueAfter: stopRequeueAfter}
+	}
+
+	return false, nil
+}
+
+// parkInstance stops the instance of a machine being deleted rather than
+// terminating it, after draining the node of the machine. The instance is
+// started again by the next machine of the same name.
+func (a *Actuator) parkInstance(svc service.EC2MachineInterface, workloadClient workloadClientFunc, scope *actuators.MachineScope, instance *v1alpha1.Instance) error {
+	if instance.State == v1alpha1.InstanceStateStopping || instance.State == v1alpha1.InstanceStateStopped {
+		return nil
+	}
+
+	if drained := drainDeletedNodes(workloadClient, []*deletion{{scope: scope, instance: instance}}); !drained[0] {
+		klog.Infof("Waiting for the pods of the node of machine %q to be evicted before stopping its instance", scope.Name())
+		return &controllerError.RequeueAfterError{RequeueAfter: drainRequeueAfter}
+	}
+
+	if err := svc.StopInstance(instance.ID); err != nil {
+		return err
+	}
+
+	record.Eventf(scope.Machine, "StoppedInstance", "Stopped instance %q of deleted machine", instance.ID)
+	return nil
+}
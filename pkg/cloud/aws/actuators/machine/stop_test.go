@@ -0,0 +1,125 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machine
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/golang/mock/gomock"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/actuators"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/mocks"
+	clusterv1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+	controllerError "sigs.k8s.io/cluster-api/pkg/controller/error"
+)
+
+func TestEnsureStopped(t *testing.T) {
+	stop := map[string]string{actuators.StopAnnotation: "true"}
+
+	testCases := []struct {
+		name          string
+		annotations   map[string]string
+		role          string
+		spot          bool
+		state         v1alpha1.InstanceState
+		expect        func(m *mocks.MockEC2InterfaceMockRecorder)
+		expectStopped bool
+		expectRequeue bool
+	}{
+		{
+			name:  "running",
+			state: v1alpha1.InstanceStateRunning,
+		},
+		{
+			name:        "stop requested",
+			annotations: stop,
+			state:       v1alpha1.InstanceStateRunning,
+			expect: func(m *mocks.MockEC2InterfaceMockRecorder) {
+				m.StopInstance("i-1").Return(nil)
+			},
+			expectStopped: true,
+		},
+		{
+			name:        "stop requested on a control plane machine",
+			annotations: stop,
+			role:        "controlplane",
+			state:       v1alpha1.InstanceStateRunning,
+		},
+		{
+			name:        "stop requested on a spot machine",
+			annotations: stop,
+			spot:        true,
+			state:       v1alpha1.InstanceStateRunning,
+		},
+		{
+			name:          "stopped",
+			annotations:   stop,
+			state:         v1alpha1.InstanceStateStopped,
+			expectStopped: true,
+		},
+		{
+			name:          "start while stopping",
+			state:         v1alpha1.InstanceStateStopping,
+			expectStopped: true,
+			expectRequeue: true,
+		},
+		{
+			name:  "start requested",
+			state: v1alpha1.InstanceStateStopped,
+			expect: func(m *mocks.MockEC2InterfaceMockRecorder) {
+				m.StartInstance("i-1").Return(nil)
+			},
+			expectStopped: true,
+			expectRequeue: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+
+			ec2Mock := mocks.NewMockEC2Interface(mockCtrl)
+			if tc.expect != nil {
+				tc.expect(ec2Mock.EXPECT())
+			}
+
+			a := &Actuator{}
+			machine := &clusterv1.Machine{ObjectMeta: metav1.ObjectMeta{
+				Name:        "test",
+				Annotations: tc.annotations,
+				Labels:      map[string]string{"set": tc.role},
+			}}
+			instance := &v1alpha1.Instance{ID: "i-1", State: tc.state, Spot: tc.spot}
+			status := &v1alpha1.AWSMachineProviderStatus{InstanceID: aws.String("i-1")}
+
+			stopped, err := a.ensureStopped(ec2Mock, machine, instance, status)
+			if _, ok := err.(*controllerError.RequeueAfterError); ok != tc.expectRequeue {
+				t.Fatalf("expected requeue %t, got error: %v", tc.expectRequeue, err)
+			}
+			if err != nil && !tc.expectRequeue {
+				t.Fatalf("did not expect error: %v", err)
+			}
+
+			if stopped != tc.expectStopped {
+				t.Fatalf("expected stopped %t, got %t", tc.expectStopped, stopped)
+			}
+		})
+	}
+}
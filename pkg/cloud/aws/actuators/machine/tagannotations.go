@@ -0,0 +1,92 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machine
+
+import (
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/klog"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	clusterv1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+)
+
+// TagAnnotationPrefix prefixes the keys of the instance tags reflected as
+// annotations of the machine.
+const TagAnnotationPrefix = "tags.cluster-api-provider-aws.sigs.k8s.io/"
+
+// Ensures that the instance tags selected by the machine provider config are
+// reflected as annotations of the machine, and that the annotations of the
+// tags no longer selected or present are removed.
+// Returns true if the annotations were changed.
+func (a *Actuator) ensureTagAnnotations(machine *clusterv1.Machine, instance *v1alpha1.Instance, config *v1alpha1.AWSMachineProviderSpec) bool {
+	if instance == nil {
+		return false
+	}
+
+	want := map[string]string{}
+	for key, value := range instance.Tags {
+		if !tagSelected(key, config.TagAnnotations) {
+			continue
+		}
+
+		annotation := TagAnnotationPrefix + key
+		if errs := validation.IsQualifiedName(annotation); len(errs) > 0 {
+			klog.V(2).Infof("Not reflecting tag %q of machine %q as an annotation: %s", key, machine.Name, strings.Join(errs, ", "))
+			continue
+		}
+
+		want[annotation] = value
+	}
+
+	changed := false
+	for annotation := range machine.Annotations {
+		if _, ok := want[annotation]; !ok && strings.HasPrefix(annotation, TagAnnotationPrefix) {
+			delete(machine.Annotations, annotation)
+			changed = true
+		}
+	}
+
+	for annotation, value := range want {
+		if current, ok := machine.Annotations[annotation]; ok && current == value {
+			continue
+		}
+
+		if machine.Annotations == nil {
+			machine.Annotations = map[string]string{}
+		}
+		machine.Annotations[annotation] = value
+		changed = true
+	}
+
+	return changed
+}
+
+// tagSelected returns true if a tag key matches one of the selected keys,
+// which select all the keys with a prefix when they end with "*".
+func tagSelected(key string, selected []string) bool {
+	for _, s := range selected {
+		if prefix := strings.TrimSuffix(s, "*"); prefix != s {
+			if strings.HasPrefix(key, prefix) {
+				return true
+			}
+		} else if key == s {
+			return true
+		}
+	}
+	return false
+}
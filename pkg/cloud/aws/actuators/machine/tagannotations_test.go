@@ -0,0 +1,68 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machine
+
+import (
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	clusterv1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+)
+
+func TestEnsureTagAnnotations(t *testing.T) {
+	a := &Actuator{}
+	machine := &clusterv1.Machine{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "machine",
+			Annotations: map[string]string{
+				TagAnnotationPrefix + "removed": "true",
+				"unrelated":                     "true",
+			},
+		},
+	}
+	instance := &v1alpha1.Instance{
+		ID: "i-1",
+		Tags: map[string]string{
+			"owner":                         "team-a",
+			"backup.example.com-schedule":   "daily",
+			"aws:cloudformation:stack-name": "stack",
+			"ignored":                       "true",
+		},
+	}
+	config := &v1alpha1.AWSMachineProviderSpec{
+		TagAnnotations: []string{"owner", "backup.*", "aws:*"},
+	}
+
+	if !a.ensureTagAnnotations(machine, instance, config) {
+		t.Fatalf("expected the annotations to change")
+	}
+
+	expected := map[string]string{
+		TagAnnotationPrefix + "owner":                       "team-a",
+		TagAnnotationPrefix + "backup.example.com-schedule": "daily",
+		"unrelated": "true",
+	}
+	if !reflect.DeepEqual(machine.Annotations, expected) {
+		t.Fatalf("expected annotations %v, got %v", expected, machine.Annotations)
+	}
+
+	if a.ensureTagAnnotations(machine, instance, config) {
+		t.Fatalf("expected the annotations to be up to date")
+	}
+}
@@ -61,6 +61,17 @@ func (a *Actuator) ensureTags(svc service.EC2MachineInterface, machine *clusterv
 	return changed, nil
 }
 
+// recordLaunchTags records the additional tags applied to the instance at launch
+// as the last applied tags, so that ensureTags doesn't apply them again.
+func (a *Actuator) recordLaunchTags(machine *clusterv1.Machine, additionalTags map[string]string) error {
+	lastApplied := make(map[string]interface{}, len(additionalTags))
+	for k, v := range additionalTags {
+		lastApplied[k] = v
+	}
+
+	return a.updateMachineAnnotationJSON(machine, TagsLastAppliedAnnotation, lastApplied)
+}
+
 // tagsChanged determines which tags to delete and which to add.
 func (a *Actuator) tagsChanged(annotation map[string]interface{}, src map[string]string) (bool, map[string]string, map[string]string, map[string]interface{}) {
 	// Bool tracking if we found any changed state.
@@ -0,0 +1,55 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machine
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/golang/mock/gomock"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/mocks"
+	clusterv1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+)
+
+func TestEnsureTagsAfterLaunch(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	additionalTags := map[string]string{"team": "platform", "env": "prod"}
+	machine := &clusterv1.Machine{
+		ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{}},
+	}
+
+	// Tags applied at launch are not applied again.
+	ec2Mock := mocks.NewMockEC2Interface(mockCtrl)
+	ec2Mock.EXPECT().UpdateResourceTags(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+	a := &Actuator{}
+	if err := a.recordLaunchTags(machine, additionalTags); err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+
+	changed, err := a.ensureTags(ec2Mock, machine, aws.String("i-1"), additionalTags)
+	if err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+
+	if changed {
+		t.Fatal("expected tags applied at launch to be unchanged")
+	}
+}
@@ -0,0 +1,336 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machine
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/actuators"
+	service "sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/drain"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/features"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/record"
+	clusterv1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+	controllerError "sigs.k8s.io/cluster-api/pkg/controller/error"
+)
+
+const (
+	// deleteDrainConcurrency is how many nodes of deleted machines are
+	// drained at once.
+	deleteDrainConcurrency = 10
+
+	// defaultDrainTimeout is how long the termination of the instance of a
+	// deleted machine waits for its node to be drained, unless the machine
+	// sets a DrainTimeout.
+	defaultDrainTimeout = 5 * time.Minute
+
+	// terminationRequeueAfter is how long to wait before checking again
+	// whether the instance of a deleted machine is terminated.
+	terminationRequeueAfter = 20 * time.Second
+)
+
+// deletion is a machine being deleted whose instance is still running.
+type deletion struct {
+	scope    *actuators.MachineScope
+	instance *v1alpha1.Instance
+}
+
+// pendingDeletions returns the other node machines of the cluster being
+// deleted whose instance is still running, so that their instances are
+// terminated along with the instance of the machine being reconciled rather
+// than one per reconciliation. Only the machines labelled with the name of the
+// cluster are considered, as other clusters may share its namespace. Control
+// plane machines are left out, as their instances are protected from
+// termination and deleted one at a time, and so are the machines whose
+// instance is parked rather than terminated.
+func (a *Actuator) pendingDeletions(scope *actuators.MachineScope, svc service.EC2MachineInterface) ([]*deletion, error) {
+	if scope.MachineClient == nil {
+		return nil, nil
+	}
+
+	machines, err := scope.MachineClient.List(actuators.ClusterMachines(scope.Cluster))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list the machines of cluster %q", scope.Cluster.Name)
+	}
+
+	var scopes []*actuators.MachineScope
+	var ids []string
+	for i := range machines.Items {
+		m := &machines.Items[i]
+		if machinesEqual(m, scope.Machine) || m.DeletionTimestamp == nil {
+			continue
+		}
+
+		ms, err := actuators.NewMachineScope(actuators.MachineScopeParams{Machine: m, Cluster: scope.Cluster, Client: a.client})
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to create scope for machine %q", m.Name)
+		}
+
+		if ms.Role() == "controlplane" || ms.MachineStatus.InstanceID == nil {
+			continue
+		}
+
+		scopes = append(scopes, ms)
+		ids = append(ids, *ms.MachineStatus.InstanceID)
+	}
+
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	instances, err := svc.InstancesIfExist(ids)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get the instances of the machines being deleted")
+	}
+
+	var deletions []*deletion
+	for _, ms := range scopes {
+		instance := instances[*ms.MachineStatus.InstanceID]
+		if instance == nil {
+			continue
+		}
+
+		// Spot instances are terminated whatever the deletion policy.
+		if ms.MachineConfig.DeletionPolicy == v1alpha1.InstanceDeletionPolicyStop && !instance.Spot {
+			continue
+		}
+
+		deletions = append(deletions, &deletion{scope: ms, instance: instance})
+	}
+
+	return deletions, nil
+}
+
+// terminateMachines drains in parallel the nodes of the machines being
+// deleted, then terminates their instances in as few calls as possible.
+// Machines whose node is still being drained are left for a later
+// reconciliation, which is requeued if the machine being reconciled is one of
+// them.
+func (a *Actuator) terminateMachines(svc service.EC2MachineInterface, workloadClient workloadClientFunc, current *deletion, others []*deletion) error {
+	deletions := append([]*deletion{current}, others...)
+	drained := drainDeletedNodes(workloadClient, deletions)
+
+	var ready []*deletion
+	for i, d := range deletions {
+		if !drained[i] {
+			continue
+		}
+
+		if err := runLifecycleHooks(d.scope, v1alpha1.LifecyclePreDelete, d.instance); err != nil {
+			if d == current {
+				return errors.Errorf("failed to run pre-delete hooks: %+v", err)
+			}
+
+			klog.Warningf("Postponing the termination of machine %q: failed to run pre-delete hooks: %v", d.scope.Name(), err)
+			continue
+		}
+
+		ready = append(ready, d)
+	}
+
+	if len(ready) > 0 {
+		ids := make([]string, len(ready))
+		for i, d := range ready {
+			ids[i] = d.instance.ID
+		}
+
+		if err := svc.TerminateInstances(ids); err != nil {
+			return errors.Errorf("failed to terminate instances: %+v", err)
+		}
+
+		for _, d := range ready {
+			newNotifier(d.scope.Scope).notify(d.scope.Machine, machineDeleted, d.instance.ID, "")
+		}
+	}
+
+	if !drained[0] {
+		klog.Infof("Waiting for the pods of the node of machine %q to be evicted before terminating its instance", current.scope.Name())
+		return &controllerError.RequeueAfterError{RequeueAfter: drainRequeueAfter}
+	}
+
+	// The nodes are deleted by the next reconciliation of each machine, once
+	// its instance is terminated.
+	return &controllerError.RequeueAfterError{RequeueAfter: terminationRequeueAfter}
+}
+
+// drainDeletedNodes cordons and drains the nodes of the machines being
+// deleted, at most deleteDrainConcurrency at once, and returns whether the
+// instance of each machine can be terminated.
+// The nodes are not drained if the cluster cannot be reached, as happens
+// while it is being deleted, nor without the DrainBeforeDelete feature.
+func drainDeletedNodes(workloadClient workloadClientFunc, deletions []*deletion) []bool {
+	drained := make([]bool, len(deletions))
+
+	var client kubernetes.Interface
+	for _, d := range deletions {
+		if d.scope.Machine.Status.NodeRef != nil && d.scope.FeatureEnabled(features.DrainBeforeDelete) {
+			c, err := workloadClient()
+			if err != nil {
+				klog.Warningf("Terminating instances without draining their nodes: %v", err)
+			} else {
+				client = c
+			}
+			break
+		}
+	}
+
+	sem := make(chan struct{}, deleteDrainConcurrency)
+	var wg sync.WaitGroup
+	for i, d := range deletions {
+		wg.Add(1)
+		go func(i int, scope *actuators.MachineScope) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			if !scope.FeatureEnabled(features.DrainBeforeDelete) {
+				drained[i] = true
+				return
+			}
+			drained[i] = drainDeletedNode(client, scope.Machine, drainTimeout(scope.MachineConfig))
+		}(i, d.scope)
+	}
+	wg.Wait()
+
+	return drained
+}
+
+// drainTimeout returns how long the termination of the instance of a deleted
+// machine waits for its node to be drained.
+func drainTimeout(config *v1alpha1.AWSMachineProviderSpec) time.Duration {
+	if config.DrainTimeout != nil {
+		return config.DrainTimeout.Duration
+	}
+	return defaultDrainTimeout
+}
+
+// drainDeletedNode cordons and drains the node of a machine being deleted,
+// and returns true once it is drained, or when it should not be waited for
+// any longer than timeout.
+func drainDeletedNode(client kubernetes.Interface, machine *clusterv1.Machine, timeout time.Duration) bool {
+	if client == nil || machine.Status.NodeRef == nil {
+		return true
+	}
+
+	if machine.DeletionTimestamp != nil && time.Since(machine.DeletionTimestamp.Time) > timeout {
+		record.Warnf(machine, "DrainTimeout", "Terminating instance without waiting any longer for node %q to be drained", machine.Status.NodeRef.Name)
+		return true
+	}
+
+	node, err := client.CoreV1().Nodes().Get(machine.Status.NodeRef.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return true
+	}
+
+	if err == nil {
+		err = drain.Cordon(client, node, true)
+	}
+
+	var drained bool
+	if err == nil {
+		drained, err = drain.Drain(client, node.Name)
+	}
+
+	if err != nil {
+		record.Warnf(machine, "FailedDrain", "Failed to drain node %q: %v", machine.Status.NodeRef.Name, err)
+		return false
+	}
+
+	return drained
+}
+
+// deleteNodes deletes the nodes of the machines whose instance is
+// terminated, which would otherwise linger as NotReady in the cluster.
+// The node of a machine is the one it references, or else the one backed by
+// its instance. Failures are only reported, as the instances are gone anyway.
+func deleteNodes(workloadClient workloadClientFunc, deletions []*deletion) {
+	client, err := workloadClient()
+	if err != nil {
+		klog.Warningf("Not deleting the nodes of terminated instances: %v", err)
+		return
+	}
+
+	var nodes []corev1.Node
+	var listed bool
+	for _, d := range deletions {
+		var name string
+		if d.scope.Machine.Status.NodeRef != nil {
+			name = d.scope.Machine.Status.NodeRef.Name
+		} else {
+			if !listed {
+				list, err := client.CoreV1().Nodes().List(metav1.ListOptions{})
+				if err != nil {
+					record.Warnf(d.scope.Machine, "FailedDeleteNode", "Failed to list the nodes of the cluster: %v", err)
+					return
+				}
+				nodes, listed = list.Items, true
+			}
+			name = nodeOfInstance(nodes, d.instance)
+		}
+
+		if name == "" {
+			continue
+		}
+
+		if err := client.CoreV1().Nodes().Delete(name, &metav1.DeleteOptions{}); err != nil {
+			if !apierrors.IsNotFound(err) {
+				record.Warnf(d.scope.Machine, "FailedDeleteNode", "Failed to delete node %q: %v", name, err)
+			}
+			continue
+		}
+
+		record.Eventf(d.scope.Machine, "DeletedNode", "Deleted node %q of instance %q", name, d.instance.ID)
+	}
+}
+
+// nodeOfInstance returns the name of the node backed by an instance, found by
+// its provider ID or else by the private DNS name of the instance, or an
+// empty string if there is none.
+func nodeOfInstance(nodes []corev1.Node, instance *v1alpha1.Instance) string {
+	for _, node := range nodes {
+		if strings.HasSuffix(node.Spec.ProviderID, "/"+instance.ID) {
+			return node.Name
+		}
+	}
+
+	if instance.PrivateDNSName == nil || *instance.PrivateDNSName == "" {
+		return ""
+	}
+
+	for _, node := range nodes {
+		if node.Name == *instance.PrivateDNSName {
+			return node.Name
+		}
+
+		for _, address := range node.Status.Addresses {
+			if address.Type == corev1.NodeInternalDNS && address.Address == *instance.PrivateDNSName {
+				return node.Name
+			}
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,258 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machine
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/golang/mock/gomock"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/actuators"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/mocks"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/features"
+	clusterv1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+	controllerError "sigs.k8s.io/cluster-api/pkg/controller/error"
+)
+
+func TestTerminateMachines(t *testing.T) {
+	newDeletion := func(name, instanceID string, nodeRef *corev1.ObjectReference) *deletion {
+		machine := &clusterv1.Machine{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Labels: map[string]string{"set": "node"}},
+			Status:     clusterv1.MachineStatus{NodeRef: nodeRef},
+		}
+		scope, err := actuators.NewMachineScope(actuators.MachineScopeParams{
+			Cluster: &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"}},
+			Machine: machine,
+		})
+		if err != nil {
+			t.Fatalf("failed to create scope: %v", err)
+		}
+		return &deletion{scope: scope, instance: &v1alpha1.Instance{ID: instanceID}}
+	}
+
+	unreachable := func() (kubernetes.Interface, error) {
+		return nil, errors.New("cluster unreachable")
+	}
+
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	ec2Mock := mocks.NewMockEC2Interface(mockCtrl)
+	ec2Mock.EXPECT().TerminateInstances([]string{"i-1", "i-2", "i-3"}).Return(nil)
+
+	a := &Actuator{}
+	current := newDeletion("machine-1", "i-1", &corev1.ObjectReference{Name: "node-1"})
+	others := []*deletion{
+		newDeletion("machine-2", "i-2", nil),
+		newDeletion("machine-3", "i-3", &corev1.ObjectReference{Name: "node-3"}),
+	}
+
+	err := a.terminateMachines(ec2Mock, unreachable, current, others)
+	if requeue, ok := err.(*controllerError.RequeueAfterError); !ok || requeue.RequeueAfter != terminationRequeueAfter {
+		t.Fatalf("expected to wait for the termination of the instances, got: %v", err)
+	}
+}
+
+func TestDrainDeletedNodesFeature(t *testing.T) {
+	newDeletion := func(annotations map[string]string) *deletion {
+		scope, err := actuators.NewMachineScope(actuators.MachineScopeParams{
+			Cluster: &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"}},
+			Machine: &clusterv1.Machine{
+				ObjectMeta: metav1.ObjectMeta{Name: "machine-1", Namespace: "default", Annotations: annotations},
+				Status:     clusterv1.MachineStatus{NodeRef: &corev1.ObjectReference{Name: "node-1"}},
+			},
+		})
+		if err != nil {
+			t.Fatalf("failed to create scope: %v", err)
+		}
+		return &deletion{scope: scope, instance: &v1alpha1.Instance{ID: "i-1"}}
+	}
+
+	testCases := []struct {
+		name          string
+		annotations   map[string]string
+		expectConnect bool
+	}{
+		{
+			name: "disabled",
+		},
+		{
+			name:          "enabled on the machine",
+			annotations:   map[string]string{features.FeatureGatesAnnotation: string(features.DrainBeforeDelete) + "=true"},
+			expectConnect: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			connected := false
+			workloadClient := func() (kubernetes.Interface, error) {
+				connected = true
+				return nil, errors.New("cluster unreachable")
+			}
+
+			// Nodes are not drained without the feature, nor when the
+			// cluster cannot be reached.
+			drained := drainDeletedNodes(workloadClient, []*deletion{newDeletion(tc.annotations)})
+			if !reflect.DeepEqual(drained, []bool{true}) {
+				t.Fatalf("expected the instance to be terminated, got %v", drained)
+			}
+			if connected != tc.expectConnect {
+				t.Fatalf("expected connection to the cluster %t, got %t", tc.expectConnect, connected)
+			}
+		})
+	}
+}
+
+func TestPendingDeletions(t *testing.T) {
+	now := metav1.Now()
+	newMachine := func(name, cluster, instanceID string, policy v1alpha1.InstanceDeletionPolicy) clusterv1.Machine {
+		spec, err := v1alpha1.EncodeMachineSpec(&v1alpha1.AWSMachineProviderSpec{DeletionPolicy: policy})
+		if err != nil {
+			t.Fatalf("failed to encode spec: %v", err)
+		}
+		status, err := v1alpha1.EncodeMachineStatus(&v1alpha1.AWSMachineProviderStatus{InstanceID: aws.String(instanceID)})
+		if err != nil {
+			t.Fatalf("failed to encode status: %v", err)
+		}
+		return clusterv1.Machine{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              name,
+				Namespace:         "default",
+				Labels:            map[string]string{"set": "node", actuators.ClusterNameLabel: cluster},
+				DeletionTimestamp: &now,
+			},
+			Spec:   clusterv1.MachineSpec{ProviderSpec: clusterv1.ProviderSpec{Value: spec}},
+			Status: clusterv1.MachineStatus{ProviderStatus: status},
+		}
+	}
+
+	current := newMachine("machine-1", "test-cluster", "i-1", "")
+	scope, err := actuators.NewMachineScope(actuators.MachineScopeParams{
+		Cluster: &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"}},
+		Machine: &current,
+	})
+	if err != nil {
+		t.Fatalf("failed to create scope: %v", err)
+	}
+	scope.MachineClient = &fakeMachineClient{machines: []clusterv1.Machine{
+		current,
+		newMachine("machine-2", "test-cluster", "i-2", ""),
+		newMachine("machine-3", "other-cluster", "i-3", ""),
+		newMachine("machine-4", "test-cluster", "i-4", v1alpha1.InstanceDeletionPolicyStop),
+		newMachine("machine-5", "test-cluster", "i-5", v1alpha1.InstanceDeletionPolicyStop),
+	}}
+
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	ec2Mock := mocks.NewMockEC2Interface(mockCtrl)
+	ec2Mock.EXPECT().InstancesIfExist([]string{"i-2", "i-4", "i-5"}).Return(map[string]*v1alpha1.Instance{
+		"i-2": {ID: "i-2"},
+		"i-4": {ID: "i-4"},
+		"i-5": {ID: "i-5", Spot: true},
+	}, nil)
+
+	a := &Actuator{}
+	deletions, err := a.pendingDeletions(scope, ec2Mock)
+	if err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+
+	// Parked instances are left out, unless they are spot instances.
+	var names []string
+	for _, d := range deletions {
+		names = append(names, d.scope.Name())
+	}
+	if expected := []string{"machine-2", "machine-5"}; !reflect.DeepEqual(names, expected) {
+		t.Fatalf("expected deletions %v, got %v", expected, names)
+	}
+}
+
+func TestNodeOfInstance(t *testing.T) {
+	nodes := []corev1.Node{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+			Spec:       corev1.NodeSpec{ProviderID: "aws:///us-east-1a/i-1"},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "ip-10-0-0-2.ec2.internal"},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "node-3"},
+			Status: corev1.NodeStatus{Addresses: []corev1.NodeAddress{
+				{Type: corev1.NodeInternalDNS, Address: "ip-10-0-0-3.ec2.internal"},
+			}},
+		},
+	}
+
+	testCases := []struct {
+		name     string
+		instance *v1alpha1.Instance
+		expected string
+	}{
+		{
+			name:     "by provider ID",
+			instance: &v1alpha1.Instance{ID: "i-1", PrivateDNSName: aws.String("ip-10-0-0-1.ec2.internal")},
+			expected: "node-1",
+		},
+		{
+			name:     "by node name",
+			instance: &v1alpha1.Instance{ID: "i-2", PrivateDNSName: aws.String("ip-10-0-0-2.ec2.internal")},
+			expected: "ip-10-0-0-2.ec2.internal",
+		},
+		{
+			name:     "by internal DNS address",
+			instance: &v1alpha1.Instance{ID: "i-3", PrivateDNSName: aws.String("ip-10-0-0-3.ec2.internal")},
+			expected: "node-3",
+		},
+		{
+			name:     "no node",
+			instance: &v1alpha1.Instance{ID: "i-4", PrivateDNSName: aws.String("ip-10-0-0-4.ec2.internal")},
+		},
+		{
+			name:     "no private DNS name",
+			instance: &v1alpha1.Instance{ID: "i-5"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if name := nodeOfInstance(nodes, tc.instance); name != tc.expected {
+				t.Fatalf("expected node %q, got %q", tc.expected, name)
+			}
+		})
+	}
+}
+
+func TestDrainTimeout(t *testing.T) {
+	if timeout := drainTimeout(&v1alpha1.AWSMachineProviderSpec{}); timeout != defaultDrainTimeout {
+		t.Fatalf("expected the default drain timeout %s, got %s", defaultDrainTimeout, timeout)
+	}
+
+	config := &v1alpha1.AWSMachineProviderSpec{DrainTimeout: &metav1.Duration{Duration: 30 * time.Minute}}
+	if timeout := drainTimeout(config); timeout != 30*time.Minute {
+		t.Fatalf("expected a drain timeout of 30m, got %s", timeout)
+	}
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machine
+
+// should not need to import the ec2 sdk here
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/actuators"
+	service "sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/awserrors"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/drain"
+	clusterv1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+	controllerError "sigs.k8s.io/cluster-api/pkg/controller/error"
+)
+
+// ensureUserDataScrubbed overwrites the user data of the machine instance, so
+// that its bootstrap secrets can no longer be retrieved, if requested by the
+// machine provider config. The user data is only scrubbed once the machine has
+// joined the cluster, as the bootstrap process depends on it. It returns true
+// on the update that scrubbed it.
+//
+// Scrubbing stops and starts the instance, so its node is drained first, and
+// uncordoned by ensureReboot once the instance booted again. Machines whose
+// instance cannot be stopped, such as spot instances, are rejected with an
+// invalid configuration error.
+func (a *Actuator) ensureUserDataScrubbed(svc service.EC2MachineInterface, workloadClient workloadClientFunc, machine *clusterv1.Machine, instance *v1alpha1.Instance, config *v1alpha1.AWSMachineProviderSpec, status *v1alpha1.AWSMachineProviderStatus) (bool, error) {
+	if !config.ScrubUserData || status.UserDataScrubbed {
+		return false, nil
+	}
+
+	if err := actuators.ValidateScrubUserData(machine.Labels["set"], config, instance.Spot); err != nil {
+		return false, awserrors.NewInvalidConfiguration(errors.Wrapf(err, "invalid user data scrubbing for machine %q", machine.Name))
+	}
+
+	// The node has not joined the cluster yet.
+	if machine.Status.NodeRef == nil {
+		return false, nil
+	}
+
+	client, err := workloadClient()
+	if err != nil {
+		return false, err
+	}
+
+	node, err := client.CoreV1().Nodes().Get(machine.Status.NodeRef.Name, metav1.GetOptions{})
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to get node %q", machine.Status.NodeRef.Name)
+	}
+
+	if err := drain.Cordon(client, node, true); err != nil {
+		return false, err
+	}
+
+	drained, err := drain.Drain(client, node.Name)
+	if err != nil {
+		return false, err
+	}
+
+	if !drained {
+		klog.Infof("Waiting for the pods of node %q to be evicted before scrubbing the user data of machine %q", node.Name, machine.Name)
+		return false, &controllerError.RequeueAfterError{RequeueAfter: drainRequeueAfter}
+	}
+
+	if err := svc.ScrubInstanceUserData(aws.StringValue(status.InstanceID)); err != nil {
+		return false, err
+	}
+
+	if machine.Annotations == nil {
+		machine.Annotations = map[string]string{}
+	}
+	a.updateMachineAnnotation(machine, RebootBootIDAnnotation, node.Status.NodeInfo.BootID)
+
+	status.UserDataScrubbed = true
+	return true, nil
+}
@@ -0,0 +1,142 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machine
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/golang/mock/gomock"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/awserrors"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/mocks"
+	clusterv1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+)
+
+func TestEnsureUserDataScrubbed(t *testing.T) {
+	testCases := []struct {
+		name            string
+		role            string
+		spot            bool
+		config          *v1alpha1.AWSMachineProviderSpec
+		status          *v1alpha1.AWSMachineProviderStatus
+		nodeRef         *corev1.ObjectReference
+		expect          func(m *mocks.MockEC2InterfaceMockRecorder)
+		expectedChanged bool
+		expectError     bool
+	}{
+		{
+			name:   "scrubbing disabled",
+			config: &v1alpha1.AWSMachineProviderSpec{},
+			status: &v1alpha1.AWSMachineProviderStatus{InstanceID: aws.String("i-1")},
+			nodeRef: &corev1.ObjectReference{
+				Name: "node-1",
+			},
+			expect: func(m *mocks.MockEC2InterfaceMockRecorder) {},
+		},
+		{
+			name:   "node has not joined yet",
+			config: &v1alpha1.AWSMachineProviderSpec{ScrubUserData: true},
+			status: &v1alpha1.AWSMachineProviderStatus{InstanceID: aws.String("i-1")},
+			expect: func(m *mocks.MockEC2InterfaceMockRecorder) {},
+		},
+		{
+			name:   "already scrubbed",
+			config: &v1alpha1.AWSMachineProviderSpec{ScrubUserData: true},
+			status: &v1alpha1.AWSMachineProviderStatus{InstanceID: aws.String("i-1"), UserDataScrubbed: true},
+			nodeRef: &corev1.ObjectReference{
+				Name: "node-1",
+			},
+			expect: func(m *mocks.MockEC2InterfaceMockRecorder) {},
+		},
+		{
+			name:   "node joined but cannot be drained",
+			config: &v1alpha1.AWSMachineProviderSpec{ScrubUserData: true},
+			status: &v1alpha1.AWSMachineProviderStatus{InstanceID: aws.String("i-1")},
+			nodeRef: &corev1.ObjectReference{
+				Name: "node-1",
+			},
+			expect:      func(m *mocks.MockEC2InterfaceMockRecorder) {},
+			expectError: true,
+		},
+		{
+			name:   "spot instance",
+			spot:   true,
+			config: &v1alpha1.AWSMachineProviderSpec{ScrubUserData: true},
+			status: &v1alpha1.AWSMachineProviderStatus{InstanceID: aws.String("i-1")},
+			nodeRef: &corev1.ObjectReference{
+				Name: "node-1",
+			},
+			expect:      func(m *mocks.MockEC2InterfaceMockRecorder) {},
+			expectError: true,
+		},
+		{
+			name:   "control plane machine",
+			role:   "controlplane",
+			config: &v1alpha1.AWSMachineProviderSpec{ScrubUserData: true},
+			status: &v1alpha1.AWSMachineProviderStatus{InstanceID: aws.String("i-1")},
+			nodeRef: &corev1.ObjectReference{
+				Name: "node-1",
+			},
+			expect:      func(m *mocks.MockEC2InterfaceMockRecorder) {},
+			expectError: true,
+		},
+	}
+
+	// The instance is only stopped to scrub its user data once its node is
+	// drained, which requires the workload cluster.
+	noWorkloadClient := func() (kubernetes.Interface, error) {
+		return nil, errors.New("workload cluster unreachable")
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+
+			ec2Mock := mocks.NewMockEC2Interface(mockCtrl)
+			tc.expect(ec2Mock.EXPECT())
+
+			machine := &clusterv1.Machine{
+				ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"set": tc.role}},
+				Status:     clusterv1.MachineStatus{NodeRef: tc.nodeRef},
+			}
+			instance := &v1alpha1.Instance{ID: "i-1", Spot: tc.spot}
+
+			a := &Actuator{}
+			changed, err := a.ensureUserDataScrubbed(ec2Mock, noWorkloadClient, machine, instance, tc.config, tc.status)
+			if (err != nil) != tc.expectError {
+				t.Fatalf("expected error %t, got %v", tc.expectError, err)
+			}
+			if (tc.spot || tc.role == "controlplane") && !awserrors.IsInvalidConfiguration(errors.Cause(err)) {
+				t.Fatalf("expected an invalid configuration error, got %v", err)
+			}
+
+			if changed != tc.expectedChanged {
+				t.Fatalf("expected changed to be %t, got %t", tc.expectedChanged, changed)
+			}
+
+			if tc.expectedChanged && !tc.status.UserDataScrubbed {
+				t.Fatalf("expected status to record the scrubbed user data")
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machine
+
+// should not need to import the ec2 sdk here
+import (
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	service "sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/awserrors"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/record"
+	clusterv1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+	controllerError "sigs.k8s.io/cluster-api/pkg/controller/error"
+)
+
+// volumeModificationRequeueAfter is how long a machine waits before retrying
+// the modification of volumes that AWS refused to modify yet.
+const volumeModificationRequeueAfter = 30 * time.Minute
+
+// ensureVolumes modifies in place the volumes of the machine instance,
+// including the volume dedicated to etcd, whose size, type, IOPS or
+// throughput differ from the machine provider config, and reports whether
+// any volume was modified.
+//
+// Volumes that cannot be modified yet, because they were modified less than
+// six hours ago, are retried later with a RequeueAfterError, which callers
+// should not treat as a failure of the rest of the update.
+func (a *Actuator) ensureVolumes(svc service.EC2MachineInterface, machine *clusterv1.Machine, config *v1alpha1.AWSMachineProviderSpec, status *v1alpha1.AWSMachineProviderStatus) (bool, error) {
+	volumes := config.AdditionalVolumes
+	if config.EtcdVolume != nil && config.EtcdVolume.Volume != nil {
+		volumes = append(append([]v1alpha1.Volume{}, volumes...), *config.EtcdVolume.Volume)
+	}
+
+	if config.RootVolume == nil && len(volumes) == 0 {
+		return false, nil
+	}
+
+	modified, err := svc.ModifyInstanceVolumes(aws.StringValue(status.InstanceID), config.RootVolume, volumes)
+	if err != nil && !awserrors.IsConflict(errors.Cause(err)) {
+		return false, err
+	}
+
+	if len(modified) > 0 {
+		record.Eventf(machine, "ModifiedVolumes", "Modified volumes %s of instance %q", strings.Join(modified, ", "), aws.StringValue(status.InstanceID))
+	}
+
+	if err != nil {
+		record.Warnf(machine, "VolumeModificationDeferred", "Deferring volume modification: %v", err)
+		return len(modified) > 0, &controllerError.RequeueAfterError{RequeueAfter: volumeModificationRequeueAfter}
+	}
+
+	return len(modified) > 0, nil
+}
@@ -0,0 +1,83 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machine
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/golang/mock/gomock"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/awserrors"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/mocks"
+	clusterv1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+	controllerError "sigs.k8s.io/cluster-api/pkg/controller/error"
+)
+
+func TestEnsureVolumes(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	root := &v1alpha1.RootVolume{Size: 50, Type: "gp3"}
+	ec2Mock := mocks.NewMockEC2Interface(mockCtrl)
+	ec2Mock.EXPECT().ModifyInstanceVolumes("i-1", root, nil).Return([]string{"vol-root"}, nil)
+
+	a := &Actuator{}
+	status := &v1alpha1.AWSMachineProviderStatus{InstanceID: aws.String("i-1")}
+
+	changed, err := a.ensureVolumes(ec2Mock, &clusterv1.Machine{}, &v1alpha1.AWSMachineProviderSpec{}, status)
+	if err != nil || changed {
+		t.Fatalf("expected machines without volume configuration to be left alone, got %t, %v", changed, err)
+	}
+
+	changed, err = a.ensureVolumes(ec2Mock, &clusterv1.Machine{}, &v1alpha1.AWSMachineProviderSpec{RootVolume: root}, status)
+	if err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+
+	if !changed {
+		t.Fatalf("expected the root volume to be modified")
+	}
+}
+
+func TestEnsureVolumesCooldown(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	root := &v1alpha1.RootVolume{Size: 50}
+	ec2Mock := mocks.NewMockEC2Interface(mockCtrl)
+	ec2Mock.EXPECT().ModifyInstanceVolumes("i-1", root, nil).
+		Return(nil, awserrors.NewConflict(errors.New("volumes vol-root of instance \"i-1\" cannot be modified yet")))
+
+	a := &Actuator{}
+	status := &v1alpha1.AWSMachineProviderStatus{InstanceID: aws.String("i-1")}
+
+	changed, err := a.ensureVolumes(ec2Mock, &clusterv1.Machine{}, &v1alpha1.AWSMachineProviderSpec{RootVolume: root}, status)
+	if changed {
+		t.Fatalf("did not expect volumes to be modified")
+	}
+
+	requeue, ok := err.(*controllerError.RequeueAfterError)
+	if !ok {
+		t.Fatalf("expected a requeue, got %v", err)
+	}
+
+	if requeue.RequeueAfter != volumeModificationRequeueAfter {
+		t.Fatalf("expected a requeue after %v, got %v", volumeModificationRequeueAfter, requeue.RequeueAfter)
+	}
+}
@@ -0,0 +1,159 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machine
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/pkg/errors"
+	"k8s.io/klog"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/actuators"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/ssm/tunnel"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/record"
+	clusterv1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+)
+
+const (
+	// apiServerWarmUpRequeueAfter is how long the registration of a control
+	// plane instance with the API server load balancer is postponed while its
+	// API server is starting.
+	apiServerWarmUpRequeueAfter = 20 * time.Second
+
+	// apiServerProbeTimeout bounds the health check of the API server of an
+	// instance, including the Session Manager session it goes through.
+	apiServerProbeTimeout = 5 * time.Second
+
+	// apiServerWarmUpTimeout is how long after its launch a control plane
+	// instance whose API server never answered the health check is registered
+	// with the API server load balancer anyway, leaving it to the health check
+	// of the load balancer, e.g. when the controller cannot reach the instance.
+	apiServerWarmUpTimeout = 10 * time.Minute
+
+	// apiServerPort is the port the API servers of the instances listen on.
+	apiServerPort = 6443
+)
+
+// dialFunc opens the connection to the API server of an instance.
+type dialFunc func(ctx context.Context, network, address string) (net.Conn, error)
+
+// probeAPIServer checks the health of the API server at the given address; it
+// is replaced in tests.
+var probeAPIServer = probeHealthz
+
+// apiServerWarmedUp returns whether the API server of a control plane machine
+// is ready to serve the traffic of the API server load balancer, as reported
+// by its /healthz endpoint on the private IP address of its instance. The
+// API servers of clusters with a private API server are reached through a
+// Session Manager port forwarding session to the instance, as the API server
+// of the cluster is.
+//
+// The first control plane machine is registered right away, as it reaches its
+// own API server through the load balancer while bootstrapping the cluster.
+// So is a machine whose API server did not answer within
+// apiServerWarmUpTimeout of the launch of its instance.
+func (a *Actuator) apiServerWarmedUp(scope *actuators.MachineScope, machine *clusterv1.Machine, instance *v1alpha1.Instance) (bool, error) {
+	machines, err := scope.MachineClient.List(actuators.ClusterMachines(scope.Cluster))
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to list machines of cluster %q", scope.Cluster.Name)
+	}
+
+	serving := false
+	for _, m := range a.getControlPlaneMachines(machines) {
+		if m.Name != machine.Name && m.Status.NodeRef != nil {
+			serving = true
+			break
+		}
+	}
+	if !serving {
+		return true, nil
+	}
+
+	if aws.StringValue(instance.PrivateIP) == "" {
+		return false, nil
+	}
+
+	var dial dialFunc
+	if scope.ClusterConfig.PrivateAPIServer {
+		dial = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return tunnel.Dial(ctx, scope.Sessions, instance.ID, *instance.PrivateIP, apiServerPort)
+		}
+	}
+
+	address := net.JoinHostPort(*instance.PrivateIP, strconv.Itoa(apiServerPort))
+	err = probeAPIServer(scope.ClusterConfig.CACertificate, address, dial)
+	if err == nil {
+		return true, nil
+	}
+
+	if instance.LaunchTime != nil && time.Since(instance.LaunchTime.Time) > apiServerWarmUpTimeout {
+		record.Warnf(machine, "APIServerProbeTimeout",
+			"Registering machine %q with the API server load balancer, as its API server did not answer the health check within %v: %v",
+			machine.Name, apiServerWarmUpTimeout, err)
+		return true, nil
+	}
+
+	klog.V(2).Infof("API server of machine %q is not ready yet: %v", machine.Name, err)
+	return false, nil
+}
+
+// probeHealthz requests the /healthz endpoint of the API server at the given
+// address, verifying its serving certificate against the cluster CA. The
+// connection is opened with dial, if not nil.
+func probeHealthz(caCert []byte, address string, dial dialFunc) error {
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caCert) {
+		return errors.New("no valid CA certificate")
+	}
+
+	transport := &http.Transport{
+		TLSClientConfig: &tls.Config{RootCAs: pool},
+	}
+	if dial != nil {
+		transport.DialContext = dial
+	}
+	defer transport.CloseIdleConnections()
+
+	req, err := http.NewRequest(http.MethodGet, "https://"+address+"/healthz", nil)
+	if err != nil {
+		return err
+	}
+
+	// The deadline of the context also bounds the dial, unlike the timeout of
+	// the client.
+	ctx, cancel := context.WithTimeout(context.Background(), apiServerProbeTimeout)
+	defer cancel()
+
+	client := &http.Client{Transport: transport}
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("health check returned %s", resp.Status)
+	}
+	return nil
+}
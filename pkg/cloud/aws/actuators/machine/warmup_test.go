@@ -0,0 +1,172 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machine
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/actuators"
+	clusterv1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+)
+
+func TestProbeHealthz(t *testing.T) {
+	healthy := true
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/healthz" {
+			t.Errorf("unexpected path %q", r.URL.Path)
+		}
+		if !healthy {
+			http.Error(w, "etcd failed", http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	caCert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	address := server.Listener.Addr().String()
+
+	if err := probeHealthz(caCert, address, nil); err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+
+	healthy = false
+	if err := probeHealthz(caCert, address, nil); err == nil {
+		t.Fatalf("expected error for an unhealthy API server")
+	}
+
+	// The serving certificate must be signed by the cluster CA.
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{SerialNumber: big.NewInt(1), Subject: pkix.Name{CommonName: "other-ca"}, NotAfter: time.Now().Add(time.Hour), IsCA: true}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	healthy = true
+	if err := probeHealthz(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), address, nil); err == nil {
+		t.Fatalf("expected error for an API server not signed by the cluster CA")
+	}
+
+	// Connections opened by the dial function, like Session Manager tunnels,
+	// reach API servers at addresses the controller cannot.
+	dial := func(ctx context.Context, network, _ string) (net.Conn, error) {
+		var d net.Dialer
+		return d.DialContext(ctx, network, address)
+	}
+	if err := probeHealthz(caCert, net.JoinHostPort("127.0.0.1", "1"), dial); err != nil {
+		t.Fatalf("did not expect error through the dial function: %v", err)
+	}
+}
+
+func TestAPIServerWarmedUp(t *testing.T) {
+	defer func(probe func([]byte, string, dialFunc) error) { probeAPIServer = probe }(probeAPIServer)
+	probeAPIServer = func([]byte, string, dialFunc) error {
+		return errors.New("connection refused")
+	}
+
+	controlPlane := func(name, cluster string, joined bool) clusterv1.Machine {
+		m := clusterv1.Machine{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "default",
+				Labels:    map[string]string{"set": "controlplane", actuators.ClusterNameLabel: cluster},
+			},
+			Spec: clusterv1.MachineSpec{Versions: clusterv1.MachineVersionInfo{ControlPlane: "1.13.0"}},
+		}
+		if joined {
+			m.Status.NodeRef = &corev1.ObjectReference{Name: name}
+		}
+		return m
+	}
+
+	testCases := []struct {
+		name     string
+		others   []clusterv1.Machine
+		launched time.Duration
+		expected bool
+	}{
+		{
+			name:     "first control plane machine",
+			others:   []clusterv1.Machine{controlPlane("controlplane-1", "test-cluster", false)},
+			launched: time.Minute,
+			expected: true,
+		},
+		{
+			name:     "only a control plane machine of another cluster joined",
+			others:   []clusterv1.Machine{controlPlane("controlplane-1", "other-cluster", true)},
+			launched: time.Minute,
+			expected: true,
+		},
+		{
+			name:     "API server not ready",
+			others:   []clusterv1.Machine{controlPlane("controlplane-1", "test-cluster", true)},
+			launched: time.Minute,
+		},
+		{
+			name:     "API server not answering past the warm up timeout",
+			others:   []clusterv1.Machine{controlPlane("controlplane-1", "test-cluster", true)},
+			launched: apiServerWarmUpTimeout + time.Minute,
+			expected: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			m := controlPlane("controlplane-0", "test-cluster", false)
+			scope, err := actuators.NewMachineScope(actuators.MachineScopeParams{
+				Cluster: &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"}},
+				Machine: &m,
+			})
+			if err != nil {
+				t.Fatalf("failed to create scope: %v", err)
+			}
+			scope.MachineClient = &fakeMachineClient{machines: append(tc.others, m)}
+
+			launched := metav1.NewTime(time.Now().Add(-tc.launched))
+			instance := &v1alpha1.Instance{ID: "i-1", PrivateIP: aws.String("10.0.0.1"), LaunchTime: &launched}
+
+			a := &Actuator{}
+			ready, err := a.apiServerWarmedUp(scope, &m, instance)
+			if err != nil {
+				t.Fatalf("did not expect error: %v", err)
+			}
+			if ready != tc.expected {
+				t.Fatalf("expected ready to be %t, got %t", tc.expected, ready)
+			}
+		})
+	}
+}
@@ -18,8 +18,11 @@ package actuators
 
 import (
 	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/klog"
 	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/tags"
 	clusterv1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
 	client "sigs.k8s.io/cluster-api/pkg/client/clientset_generated/clientset/typed/cluster/v1alpha1"
 )
@@ -30,6 +33,10 @@ type MachineScopeParams struct {
 	Cluster *clusterv1.Cluster
 	Machine *clusterv1.Machine
 	Client  client.ClusterV1alpha1Interface
+
+	// FileSources reads the Secrets and ConfigMaps holding the content of
+	// the files of the machine.
+	FileSources FileSourceClient
 }
 
 // NewMachineScope creates a new MachineScope from the supplied parameters.
@@ -51,16 +58,20 @@ func NewMachineScope(params MachineScopeParams) (*MachineScope, error) {
 	}
 
 	var machineClient client.MachineInterface
+	var machineSetClient client.MachineSetInterface
 	if params.Client != nil {
 		machineClient = params.Client.Machines(params.Machine.Namespace)
+		machineSetClient = params.Client.MachineSets(params.Machine.Namespace)
 	}
 
 	return &MachineScope{
-		Scope:         scope,
-		Machine:       params.Machine,
-		MachineClient: machineClient,
-		MachineConfig: machineConfig,
-		MachineStatus: machineStatus,
+		Scope:            scope,
+		Machine:          params.Machine,
+		MachineClient:    machineClient,
+		MachineSetClient: machineSetClient,
+		MachineConfig:    machineConfig,
+		MachineStatus:    machineStatus,
+		fileSources:      params.FileSources,
 	}, nil
 }
 
@@ -68,10 +79,16 @@ func NewMachineScope(params MachineScopeParams) (*MachineScope, error) {
 type MachineScope struct {
 	*Scope
 
-	Machine       *clusterv1.Machine
-	MachineClient client.MachineInterface
-	MachineConfig *v1alpha1.AWSMachineProviderSpec
-	MachineStatus *v1alpha1.AWSMachineProviderStatus
+	Machine          *clusterv1.Machine
+	MachineClient    client.MachineInterface
+	MachineSetClient client.MachineSetInterface
+	MachineConfig    *v1alpha1.AWSMachineProviderSpec
+	MachineStatus    *v1alpha1.AWSMachineProviderStatus
+
+	// instanceTags caches the additional tags of the instance.
+	instanceTags tags.Map
+
+	fileSources FileSourceClient
 }
 
 // Name returns the machine name.
@@ -94,6 +111,45 @@ func (m *MachineScope) Region() string {
 	return m.Scope.Region()
 }
 
+// InstanceTags returns the additional tags of the instance of the machine:
+// the tags reflecting its Kubernetes version and the machine set and machine
+// deployment it belongs to, along with the additional tags of its provider
+// spec, which take precedence.
+func (m *MachineScope) InstanceTags() (tags.Map, error) {
+	if m.instanceTags != nil {
+		return m.instanceTags, nil
+	}
+
+	instanceTags := tags.Map{}
+	if version := m.Machine.Spec.Versions.Kubelet; version != "" {
+		instanceTags[tags.NameAWSProviderKubernetesVersion] = version
+	}
+
+	if owner := metav1.GetControllerOf(m.Machine); owner != nil && owner.Kind == "MachineSet" {
+		instanceTags[tags.NameAWSProviderMachineSet] = owner.Name
+
+		if m.MachineSetClient != nil {
+			ms, err := m.MachineSetClient.Get(owner.Name, metav1.GetOptions{})
+			switch {
+			case apierrors.IsNotFound(err):
+			case err != nil:
+				return nil, errors.Wrapf(err, "failed to get machine set %q", owner.Name)
+			default:
+				if owner := metav1.GetControllerOf(ms); owner != nil && owner.Kind == "MachineDeployment" {
+					instanceTags[tags.NameAWSProviderMachineDeployment] = owner.Name
+				}
+			}
+		}
+	}
+
+	for k, v := range m.MachineConfig.AdditionalTags {
+		instanceTags[k] = v
+	}
+
+	m.instanceTags = instanceTags
+	return instanceTags, nil
+}
+
 func (m *MachineScope) storeMachineSpec(machine *clusterv1.Machine) (*clusterv1.Machine, error) {
 	ext, err := v1alpha1.EncodeMachineSpec(m.MachineConfig)
 	if err != nil {
@@ -111,6 +167,8 @@ func (m *MachineScope) storeMachineStatus(machine *clusterv1.Machine) (*clusterv
 	}
 
 	machine.Status.ProviderStatus = ext
+	machine.Status.ErrorReason = m.Machine.Status.ErrorReason
+	machine.Status.ErrorMessage = m.Machine.Status.ErrorMessage
 	return m.MachineClient.UpdateStatus(machine)
 }
 
@@ -0,0 +1,241 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actuators
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/tags"
+	clusterv1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+	client "sigs.k8s.io/cluster-api/pkg/client/clientset_generated/clientset/typed/cluster/v1alpha1"
+)
+
+// machineSets is a machine set client serving machine sets from memory.
+type machineSets struct {
+	client.MachineSetInterface
+
+	sets map[string]*clusterv1.MachineSet
+}
+
+func (m machineSets) Get(name string, _ metav1.GetOptions) (*clusterv1.MachineSet, error) {
+	ms, ok := m.sets[name]
+	if !ok {
+		return nil, apierrors.NewNotFound(schema.GroupResource{Resource: "machinesets"}, name)
+	}
+	return ms, nil
+}
+
+func TestInstanceTags(t *testing.T) {
+	controlledBy := func(kind, name string) []metav1.OwnerReference {
+		return []metav1.OwnerReference{{Kind: kind, Name: name, Controller: func(b bool) *bool { return &b }(true)}}
+	}
+
+	sets := machineSets{sets: map[string]*clusterv1.MachineSet{
+		"workers-abc": {ObjectMeta: metav1.ObjectMeta{Name: "workers-abc", OwnerReferences: controlledBy("MachineDeployment", "workers")}},
+		"standalone":  {ObjectMeta: metav1.ObjectMeta{Name: "standalone"}},
+	}}
+
+	testCases := []struct {
+		name       string
+		owners     []metav1.OwnerReference
+		additional map[string]string
+		expect     tags.Map
+	}{
+		{
+			name:   "standalone machine",
+			expect: tags.Map{tags.NameAWSProviderKubernetesVersion: "1.13.3"},
+		},
+		{
+			name:   "machine deployment",
+			owners: controlledBy("MachineSet", "workers-abc"),
+			expect: tags.Map{
+				tags.NameAWSProviderKubernetesVersion: "1.13.3",
+				tags.NameAWSProviderMachineSet:        "workers-abc",
+				tags.NameAWSProviderMachineDeployment: "workers",
+			},
+		},
+		{
+			name:   "machine set without deployment",
+			owners: controlledBy("MachineSet", "standalone"),
+			expect: tags.Map{
+				tags.NameAWSProviderKubernetesVersion: "1.13.3",
+				tags.NameAWSProviderMachineSet:        "standalone",
+			},
+		},
+		{
+			name:   "deleted machine set",
+			owners: controlledBy("MachineSet", "deleted"),
+			expect: tags.Map{
+				tags.NameAWSProviderKubernetesVersion: "1.13.3",
+				tags.NameAWSProviderMachineSet:        "deleted",
+			},
+		},
+		{
+			name:       "additional tags take precedence",
+			additional: map[string]string{"team": "ml", tags.NameAWSProviderKubernetesVersion: "custom"},
+			expect: tags.Map{
+				tags.NameAWSProviderKubernetesVersion: "custom",
+				"team":                                "ml",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			scope := &MachineScope{
+				Machine: &clusterv1.Machine{
+					ObjectMeta: metav1.ObjectMeta{Name: "machine", OwnerReferences: tc.owners},
+					Spec: clusterv1.MachineSpec{
+						Versions: clusterv1.MachineVersionInfo{Kubelet: "1.13.3"},
+					},
+				},
+				MachineSetClient: sets,
+				MachineConfig:    &v1alpha1.AWSMachineProviderSpec{AdditionalTags: tc.additional},
+			}
+
+			got, err := scope.InstanceTags()
+			if err != nil {
+				t.Fatalf("did not expect error: %v", err)
+			}
+			if !got.Equals(tc.expect) {
+				t.Fatalf("expected tags %v, got %v", tc.expect, got)
+			}
+		})
+	}
+}
+
+// fileSources is a client serving secrets and config maps from memory.
+type fileSources struct {
+	secrets    map[string]*corev1.Secret
+	configMaps map[string]*corev1.ConfigMap
+}
+
+type fakeSecrets struct {
+	corev1client.SecretInterface
+
+	secrets map[string]*corev1.Secret
+}
+
+func (f fakeSecrets) Get(name string, _ metav1.GetOptions) (*corev1.Secret, error) {
+	if s, ok := f.secrets[name]; ok {
+		return s, nil
+	}
+	return nil, apierrors.NewNotFound(corev1.Resource("secrets"), name)
+}
+
+type fakeConfigMaps struct {
+	corev1client.ConfigMapInterface
+
+	configMaps map[string]*corev1.ConfigMap
+}
+
+func (f fakeConfigMaps) Get(name string, _ metav1.GetOptions) (*corev1.ConfigMap, error) {
+	if cm, ok := f.configMaps[name]; ok {
+		return cm, nil
+	}
+	return nil, apierrors.NewNotFound(corev1.Resource("configmaps"), name)
+}
+
+func (f fileSources) Secrets(string) corev1client.SecretInterface {
+	return fakeSecrets{secrets: f.secrets}
+}
+
+func (f fileSources) ConfigMaps(string) corev1client.ConfigMapInterface {
+	return fakeConfigMaps{configMaps: f.configMaps}
+}
+
+func TestFileContent(t *testing.T) {
+	sources := fileSources{
+		secrets: map[string]*corev1.Secret{
+			"registry": {Data: map[string][]byte{"config.json": []byte(`{"auths":{}}`)}},
+		},
+		configMaps: map[string]*corev1.ConfigMap{
+			"audit": {Data: map[string]string{"policy.yaml": "rules: []"}},
+		},
+	}
+
+	testCases := []struct {
+		name          string
+		file          v1alpha1.File
+		expectContent string
+		expectError   bool
+	}{
+		{
+			name:          "inline content",
+			file:          v1alpha1.File{Path: "/etc/motd", Content: "hello"},
+			expectContent: "hello",
+		},
+		{
+			name: "secret",
+			file: v1alpha1.File{Path: "/root/.docker/config.json", ContentFrom: &v1alpha1.FileSource{
+				Secret: &v1alpha1.FileSourceKey{Name: "registry", Key: "config.json"},
+			}},
+			expectContent: `{"auths":{}}`,
+		},
+		{
+			name: "config map",
+			file: v1alpha1.File{Path: "/etc/kubernetes/audit.yaml", ContentFrom: &v1alpha1.FileSource{
+				ConfigMap: &v1alpha1.FileSourceKey{Name: "audit", Key: "policy.yaml"},
+			}},
+			expectContent: "rules: []",
+		},
+		{
+			name: "missing secret",
+			file: v1alpha1.File{Path: "/etc/secret", ContentFrom: &v1alpha1.FileSource{
+				Secret: &v1alpha1.FileSourceKey{Name: "missing", Key: "key"},
+			}},
+			expectError: true,
+		},
+		{
+			name: "missing key",
+			file: v1alpha1.File{Path: "/etc/kubernetes/audit.yaml", ContentFrom: &v1alpha1.FileSource{
+				ConfigMap: &v1alpha1.FileSourceKey{Name: "audit", Key: "missing"},
+			}},
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			scope := &MachineScope{
+				Machine:     &clusterv1.Machine{ObjectMeta: metav1.ObjectMeta{Name: "machine", Namespace: "default"}},
+				fileSources: sources,
+			}
+
+			content, err := scope.FileContent(tc.file)
+			if tc.expectError {
+				if err == nil {
+					t.Fatalf("expected error, got content %q", content)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("did not expect error: %v", err)
+			}
+			if string(content) != tc.expectContent {
+				t.Fatalf("expected content %q, got %q", tc.expectContent, content)
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actuators
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/tags"
+)
+
+// machinePoolNameMaxLength is the maximum length of the names of launch
+// templates, which is shorter than the one of Auto Scaling groups.
+const machinePoolNameMaxLength = 128
+
+// MachinePoolName returns the name of the Auto Scaling group and launch
+// template of a machine pool of the cluster.
+func (s *Scope) MachinePoolName(pool *v1alpha1.AWSMachinePool) string {
+	return s.ResourceName("pool-"+pool.Name, machinePoolNameMaxLength)
+}
+
+// MachinePoolTags returns the tags of the Auto Scaling group of a machine
+// pool of the cluster, which are propagated to its instances and volumes:
+// the tags of the nodes of the cluster, the tags reflecting the pool and its
+// Kubernetes version, and the additional tags of the pool.
+func (s *Scope) MachinePoolTags(pool *v1alpha1.AWSMachinePool) tags.Map {
+	additional := tags.Map{}
+	for k, v := range pool.Spec.AdditionalTags {
+		additional[k] = v
+	}
+	additional[tags.NameAWSProviderMachinePool] = pool.Name
+	if version := pool.Spec.KubernetesVersion; version != "" {
+		additional[tags.NameAWSProviderKubernetesVersion] = version
+	}
+
+	return tags.Build(tags.BuildParams{
+		ClusterName: s.Name(),
+		Lifecycle:   tags.ResourceLifecycleOwned,
+		Name:        aws.String(pool.Name),
+		Role:        aws.String("node"),
+		Additional:  additional,
+	})
+}
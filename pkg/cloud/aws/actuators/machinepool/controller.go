@@ -0,0 +1,230 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package machinepool reconciles the AWSMachinePools, backing each of them
+// with an Auto Scaling group.
+package machinepool
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/actuators"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/awserrors"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/deployer"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/record"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/tokens"
+	clusterv1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+	"sigs.k8s.io/cluster-api/pkg/util"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+const (
+	// requeueAfter is how often machine pools are reconciled, as their
+	// instances are launched and terminated by their groups.
+	requeueAfter = time.Minute
+
+	// notReadyRequeueAfter is how long the reconciliation of machine pools
+	// is postponed while their cluster is not ready for nodes to join.
+	notReadyRequeueAfter = time.Minute
+
+	// deletionRequeueAfter is how often the deletion of the groups of
+	// deleted machine pools is checked.
+	deletionRequeueAfter = 30 * time.Second
+)
+
+// Reconciler reconciles the AWSMachinePools.
+type Reconciler struct {
+	*deployer.Deployer
+
+	client   client.Client
+	selector labels.Selector
+	readOnly bool
+	services services.Getter
+
+	// bootstrapToken creates a bootstrap token for nodes to join a cluster.
+	bootstrapToken func(scope *actuators.Scope, ttl time.Duration) (string, error)
+}
+
+// ReconcilerParams holds parameter information for Reconciler.
+type ReconcilerParams struct {
+	// Client reads and updates the machine pools, and reads their clusters.
+	Client client.Client
+
+	// Selector, if set, restricts the reconciler to the machine pools of
+	// the clusters whose labels it matches.
+	Selector labels.Selector
+
+	// ReadOnly, if set, prevents the reconciler from modifying AWS resources.
+	ReadOnly bool
+
+	// Getter returns the EC2 services of the clusters.
+	// Defaults to services.DefaultGetter.
+	Getter services.Getter
+}
+
+// NewReconciler creates a new Reconciler.
+func NewReconciler(params ReconcilerParams) *Reconciler {
+	getter := params.Getter
+	if getter == nil {
+		getter = services.DefaultGetter
+	}
+
+	r := &Reconciler{
+		Deployer: deployer.New(deployer.Params{ServicesGetter: getter}),
+		client:   params.Client,
+		selector: params.Selector,
+		readOnly: params.ReadOnly,
+		services: getter,
+	}
+	r.bootstrapToken = r.newBootstrapToken
+
+	return r
+}
+
+// Add registers a controller of the AWSMachinePools with the manager.
+func Add(mgr manager.Manager, r *Reconciler) error {
+	c, err := controller.New("awsmachinepool-controller", mgr, controller.Options{Reconciler: r})
+	if err != nil {
+		return err
+	}
+
+	return c.Watch(&source.Kind{Type: &v1alpha1.AWSMachinePool{}}, &handler.EnqueueRequestForObject{})
+}
+
+// Reconcile reconciles a machine pool with its Auto Scaling group.
+func (r *Reconciler) Reconcile(request reconcile.Request) (reconcile.Result, error) {
+	ctx := context.TODO()
+
+	pool := &v1alpha1.AWSMachinePool{}
+	if err := r.client.Get(ctx, request.NamespacedName, pool); err != nil {
+		if apierrors.IsNotFound(err) {
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, err
+	}
+
+	deleting := !pool.DeletionTimestamp.IsZero()
+	if deleting && !util.Contains(pool.Finalizers, v1alpha1.MachinePoolFinalizer) {
+		return reconcile.Result{}, nil
+	}
+
+	cluster := &clusterv1.Cluster{}
+	if err := r.client.Get(ctx, types.NamespacedName{Namespace: pool.Namespace, Name: pool.Spec.ClusterName}, cluster); err != nil {
+		if apierrors.IsNotFound(err) && deleting {
+			klog.Warningf("Cluster %v of machine pool %v is gone, its Auto Scaling group is left behind", pool.Spec.ClusterName, pool.Name)
+			return reconcile.Result{}, r.removeFinalizer(ctx, pool)
+		}
+		return reconcile.Result{}, errors.Wrapf(err, "failed to get cluster %q of machine pool %q", pool.Spec.ClusterName, pool.Name)
+	}
+
+	if !actuators.Selects(r.selector, cluster) {
+		klog.V(2).Infof("Cluster %v is not selected by this controller, skipping machine pool %v", cluster.Name, pool.Name)
+		return reconcile.Result{}, nil
+	}
+
+	if r.readOnly {
+		klog.Infof("Controller is read-only, skipping the reconciliation of machine pool %v", pool.Name)
+		return reconcile.Result{}, nil
+	}
+
+	if !deleting && !util.Contains(pool.Finalizers, v1alpha1.MachinePoolFinalizer) {
+		pool.Finalizers = append(pool.Finalizers, v1alpha1.MachinePoolFinalizer)
+		return reconcile.Result{}, r.client.Update(ctx, pool)
+	}
+
+	scope, err := actuators.NewScope(actuators.ScopeParams{Cluster: cluster})
+	if err != nil {
+		return reconcile.Result{}, errors.Errorf("failed to create scope: %+v", err)
+	}
+
+	if deleting {
+		deleted, err := r.delete(scope, pool)
+		if err != nil {
+			return reconcile.Result{}, errors.Wrapf(err, "failed to delete machine pool %q", pool.Name)
+		}
+		if !deleted {
+			return reconcile.Result{RequeueAfter: deletionRequeueAfter}, nil
+		}
+		return reconcile.Result{}, r.removeFinalizer(ctx, pool)
+	}
+
+	klog.Infof("Reconciling machine pool %v of cluster %v", pool.Name, cluster.Name)
+
+	result := reconcile.Result{RequeueAfter: requeueAfter}
+	err = r.reconcile(scope, pool)
+	switch {
+	case err == nil:
+		pool.Status.ErrorMessage = nil
+	case awserrors.IsInvalidConfiguration(errors.Cause(err)):
+		// Pools which cannot be launched as configured are retried once
+		// their spec changes.
+		message := err.Error()
+		pool.Status.ErrorMessage = &message
+		record.Warn(pool, "InvalidConfiguration", message)
+		result = reconcile.Result{}
+	case awserrors.IsFailedDependency(errors.Cause(err)):
+		klog.Infof("Cluster %v not ready for machine pool %v yet: %v", cluster.Name, pool.Name, err)
+		result = reconcile.Result{RequeueAfter: notReadyRequeueAfter}
+	default:
+		message := err.Error()
+		pool.Status.ErrorMessage = &message
+		if serr := r.client.Status().Update(ctx, pool); serr != nil {
+			klog.Errorf("Failed to update the status of machine pool %v: %v", pool.Name, serr)
+		}
+		return reconcile.Result{}, errors.Wrapf(err, "failed to reconcile machine pool %q", pool.Name)
+	}
+
+	if err := r.client.Status().Update(ctx, pool); err != nil {
+		return reconcile.Result{}, errors.Wrapf(err, "failed to update the status of machine pool %q", pool.Name)
+	}
+
+	return result, nil
+}
+
+// removeFinalizer lets a deleted machine pool be removed.
+func (r *Reconciler) removeFinalizer(ctx context.Context, pool *v1alpha1.AWSMachinePool) error {
+	pool.Finalizers = util.Filter(pool.Finalizers, v1alpha1.MachinePoolFinalizer)
+	return r.client.Update(ctx, pool)
+}
+
+// newBootstrapToken creates a bootstrap token in the workload cluster of
+// the scope.
+func (r *Reconciler) newBootstrapToken(scope *actuators.Scope, ttl time.Duration) (string, error) {
+	client, err := r.WorkloadClient(scope)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to create a client of cluster %q", scope.Name())
+	}
+
+	token, err := tokens.NewBootstrap(client.CoreV1(), ttl)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to create a bootstrap token in cluster %q", scope.Name())
+	}
+
+	return token, nil
+}
@@ -0,0 +1,190 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machinepool
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/actuators"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/autoscaling"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/awserrors"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/record"
+)
+
+const (
+	// bootstrapTokenTTL is the lifetime of the bootstrap tokens in the user
+	// data of the launch templates of machine pools.
+	bootstrapTokenTTL = 24 * time.Hour
+
+	// bootstrapTokenRenewal is the remaining lifetime of the bootstrap token
+	// of a launch template under which a new version of the template is
+	// created with a new token.
+	bootstrapTokenRenewal = bootstrapTokenTTL / 2
+)
+
+// reconcile launches the instances of a machine pool from a launch template
+// with the bootstrap user data of the nodes of its cluster, and sizes its
+// group after its replicas.
+func (r *Reconciler) reconcile(scope *actuators.Scope, pool *v1alpha1.AWSMachinePool) error {
+	// Nodes join through the API server load balancer with the cluster CA.
+	if scope.APIServerEndpoint() == "" || len(scope.ClusterConfig.CACertificate) == 0 {
+		return awserrors.NewFailedDependency(errors.Errorf("cluster %q has no API server endpoint yet", scope.Name()))
+	}
+
+	ec2svc := r.services.EC2(scope)
+
+	if launchTemplateOutdated(pool, time.Now()) {
+		expiration := metav1.NewTime(time.Now().Add(bootstrapTokenTTL))
+		token, err := r.bootstrapToken(scope, bootstrapTokenTTL)
+		if err != nil {
+			return err
+		}
+
+		id, version, err := ec2svc.CreateMachinePoolLaunchTemplateVersion(pool, token)
+		if err != nil {
+			return err
+		}
+
+		pool.Status.LaunchTemplateID = id
+		pool.Status.LaunchTemplateVersion = version
+		pool.Status.ObservedGeneration = pool.Generation
+		pool.Status.BootstrapTokenExpiration = &expiration
+	}
+
+	subnets, err := ec2svc.MachinePoolSubnets(pool)
+	if err != nil {
+		return err
+	}
+
+	desired := desiredGroup(scope, pool, subnets)
+	groups, err := scope.AutoScaling.DescribeGroups([]string{desired.Name})
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case len(groups) == 0:
+		if err := scope.AutoScaling.CreateGroup(desired); err != nil {
+			return err
+		}
+		record.Eventf(pool, "CreatedAutoScalingGroup", "Created Auto Scaling group %q", desired.Name)
+	case groupChanged(groups[0], desired):
+		if err := scope.AutoScaling.UpdateGroup(desired); err != nil {
+			return err
+		}
+		klog.V(2).Infof("Updated Auto Scaling group %q of machine pool %q", desired.Name, pool.Name)
+	}
+
+	pool.Status.AutoScalingGroupName = desired.Name
+	pool.Status.Replicas = 0
+	if len(groups) > 0 {
+		pool.Status.Replicas = inServiceInstances(groups[0])
+	}
+
+	return nil
+}
+
+// delete deletes the Auto Scaling group of a machine pool, along with its
+// instances, then its launch template. It returns true once both are gone.
+func (r *Reconciler) delete(scope *actuators.Scope, pool *v1alpha1.AWSMachinePool) (bool, error) {
+	name := scope.MachinePoolName(pool)
+	groups, err := scope.AutoScaling.DescribeGroups([]string{name})
+	if err != nil {
+		return false, err
+	}
+
+	if len(groups) > 0 {
+		if groups[0].Status != autoscaling.GroupStatusDeleting {
+			if err := scope.AutoScaling.DeleteGroup(name); err != nil {
+				return false, err
+			}
+			record.Eventf(pool, "DeletedAutoScalingGroup", "Deleting Auto Scaling group %q", name)
+		}
+		return false, nil
+	}
+
+	if err := r.services.EC2(scope).DeleteMachinePoolLaunchTemplate(pool); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// launchTemplateOutdated returns true if a machine pool needs a new version
+// of its launch template: when it has none, when its spec changed since the
+// version was created, or when the bootstrap token of the version is about to
+// expire.
+func launchTemplateOutdated(pool *v1alpha1.AWSMachinePool, now time.Time) bool {
+	status := pool.Status
+	if status.LaunchTemplateID == "" || status.LaunchTemplateVersion == 0 || status.BootstrapTokenExpiration == nil {
+		return true
+	}
+
+	if status.ObservedGeneration != pool.Generation {
+		return true
+	}
+
+	return status.BootstrapTokenExpiration.Time.Sub(now) < bootstrapTokenRenewal
+}
+
+// desiredGroup returns the Auto Scaling group of a machine pool, whose size
+// is fixed to the replicas of the pool.
+func desiredGroup(scope *actuators.Scope, pool *v1alpha1.AWSMachinePool, subnets []string) *autoscaling.GroupSpec {
+	replicas := int64(1)
+	if pool.Spec.Replicas != nil {
+		replicas = int64(*pool.Spec.Replicas)
+	}
+
+	return &autoscaling.GroupSpec{
+		Name:                  scope.MachinePoolName(pool),
+		LaunchTemplateID:      pool.Status.LaunchTemplateID,
+		LaunchTemplateVersion: strconv.FormatInt(pool.Status.LaunchTemplateVersion, 10),
+		MinSize:               replicas,
+		MaxSize:               replicas,
+		DesiredCapacity:       replicas,
+		Subnets:               subnets,
+		Tags:                  scope.MachinePoolTags(pool),
+	}
+}
+
+// groupChanged returns true if a group differs from its desired launch
+// template, sizes or subnets.
+func groupChanged(group *autoscaling.Group, desired *autoscaling.GroupSpec) bool {
+	return group.LaunchTemplateID != desired.LaunchTemplateID ||
+		group.LaunchTemplateVersion != desired.LaunchTemplateVersion ||
+		group.MinSize != desired.MinSize ||
+		group.MaxSize != desired.MaxSize ||
+		group.DesiredCapacity != desired.DesiredCapacity ||
+		!sets.NewString(group.Subnets...).Equal(sets.NewString(desired.Subnets...))
+}
+
+// inServiceInstances returns the number of instances of a group in service.
+func inServiceInstances(group *autoscaling.Group) int32 {
+	var count int32
+	for _, i := range group.Instances {
+		if i.LifecycleState == autoscaling.LifecycleStateInService {
+			count++
+		}
+	}
+	return count
+}
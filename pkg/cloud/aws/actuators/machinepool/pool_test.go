@@ -0,0 +1,276 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machinepool
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/actuators"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/autoscaling"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/awserrors"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/fake"
+	clusterv1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+)
+
+// groups is an Auto Scaling account with the groups created by the tests.
+type groups struct {
+	autoscaling.Groups
+
+	groups  map[string]*autoscaling.Group
+	updates int
+}
+
+func (g *groups) DescribeGroups(names []string) ([]*autoscaling.Group, error) {
+	var result []*autoscaling.Group
+	for _, n := range names {
+		if group, ok := g.groups[n]; ok {
+			result = append(result, group)
+		}
+	}
+	return result, nil
+}
+
+func (g *groups) CreateGroup(spec *autoscaling.GroupSpec) error {
+	g.groups[spec.Name] = group(spec)
+	return nil
+}
+
+func (g *groups) UpdateGroup(spec *autoscaling.GroupSpec) error {
+	g.groups[spec.Name] = group(spec)
+	g.updates++
+	return nil
+}
+
+func (g *groups) DeleteGroup(name string) error {
+	g.groups[name].Status = autoscaling.GroupStatusDeleting
+	return nil
+}
+
+func group(spec *autoscaling.GroupSpec) *autoscaling.Group {
+	return &autoscaling.Group{
+		Name:                  spec.Name,
+		MinSize:               spec.MinSize,
+		MaxSize:               spec.MaxSize,
+		DesiredCapacity:       spec.DesiredCapacity,
+		LaunchTemplateID:      spec.LaunchTemplateID,
+		LaunchTemplateVersion: spec.LaunchTemplateVersion,
+		Subnets:               spec.Subnets,
+	}
+}
+
+func newTestReconciler(t *testing.T, ready bool) (*Reconciler, *actuators.Scope, *groups) {
+	cluster := &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"}}
+	asg := &groups{groups: map[string]*autoscaling.Group{}}
+	scope, err := actuators.NewScope(actuators.ScopeParams{Cluster: cluster, AutoScaling: asg})
+	if err != nil {
+		t.Fatalf("failed to create scope: %v", err)
+	}
+
+	cloud := fake.NewCloud(cluster.Name)
+	if ready {
+		if err := cloud.EC2(scope).ReconcileNetwork(); err != nil {
+			t.Fatalf("failed to reconcile network: %v", err)
+		}
+		scope.ClusterConfig.CACertificate = []byte("ca")
+		scope.Network().APIServerELB.DNSName = "api.example.com"
+	}
+
+	r := NewReconciler(ReconcilerParams{Getter: cloud})
+	r.bootstrapToken = func(*actuators.Scope, time.Duration) (string, error) { return "abcdef.0123456789abcdef", nil }
+	return r, scope, asg
+}
+
+func TestReconcile(t *testing.T) {
+	r, scope, asg := newTestReconciler(t, true)
+
+	replicas := int32(3)
+	pool := &v1alpha1.AWSMachinePool{
+		ObjectMeta: metav1.ObjectMeta{Name: "workers", Generation: 1},
+		Spec:       v1alpha1.AWSMachinePoolSpec{Replicas: &replicas},
+	}
+
+	if err := r.reconcile(scope, pool); err != nil {
+		t.Fatalf("failed to reconcile: %v", err)
+	}
+
+	name := scope.MachinePoolName(pool)
+	created := asg.groups[name]
+	if created == nil {
+		t.Fatalf("expected group %q to be created", name)
+	}
+	if created.DesiredCapacity != 3 || created.LaunchTemplateID != pool.Status.LaunchTemplateID || created.LaunchTemplateVersion != "1" {
+		t.Fatalf("unexpected group %+v", created)
+	}
+	if pool.Status.AutoScalingGroupName != name || pool.Status.ObservedGeneration != 1 {
+		t.Fatalf("unexpected status %+v", pool.Status)
+	}
+
+	// Reconciling an unchanged pool leaves its group and template alone.
+	created.Instances = []autoscaling.Instance{
+		{LifecycleState: autoscaling.LifecycleStateInService},
+		{LifecycleState: autoscaling.LifecycleStateInService},
+		{LifecycleState: "Pending"},
+	}
+	if err := r.reconcile(scope, pool); err != nil {
+		t.Fatalf("failed to reconcile: %v", err)
+	}
+	if asg.updates != 0 || pool.Status.LaunchTemplateVersion != 1 {
+		t.Fatalf("expected no update, got %d updates and version %d", asg.updates, pool.Status.LaunchTemplateVersion)
+	}
+	if pool.Status.Replicas != 2 {
+		t.Fatalf("expected 2 replicas in service, got %d", pool.Status.Replicas)
+	}
+
+	// Scaling the pool updates the group with a new template version.
+	replicas = 5
+	pool.Generation = 2
+	if err := r.reconcile(scope, pool); err != nil {
+		t.Fatalf("failed to reconcile: %v", err)
+	}
+	if updated := asg.groups[name]; asg.updates != 1 || updated.DesiredCapacity != 5 || updated.LaunchTemplateVersion != "2" {
+		t.Fatalf("unexpected group %+v after %d updates", updated, asg.updates)
+	}
+}
+
+func TestReconcileNotReady(t *testing.T) {
+	r, scope, asg := newTestReconciler(t, false)
+
+	pool := &v1alpha1.AWSMachinePool{ObjectMeta: metav1.ObjectMeta{Name: "workers"}}
+	err := r.reconcile(scope, pool)
+	if !awserrors.IsFailedDependency(errors.Cause(err)) {
+		t.Fatalf("expected a failed dependency, got %v", err)
+	}
+	if len(asg.groups) != 0 {
+		t.Fatalf("expected no group, got %v", asg.groups)
+	}
+}
+
+func TestDelete(t *testing.T) {
+	r, scope, asg := newTestReconciler(t, true)
+
+	pool := &v1alpha1.AWSMachinePool{ObjectMeta: metav1.ObjectMeta{Name: "workers"}}
+	if err := r.reconcile(scope, pool); err != nil {
+		t.Fatalf("failed to reconcile: %v", err)
+	}
+
+	name := scope.MachinePoolName(pool)
+	for i := 0; i < 2; i++ {
+		deleted, err := r.delete(scope, pool)
+		if err != nil {
+			t.Fatalf("failed to delete: %v", err)
+		}
+		if deleted {
+			t.Fatalf("expected the deletion to wait for the group")
+		}
+		if asg.groups[name].Status != autoscaling.GroupStatusDeleting {
+			t.Fatalf("expected group %q to be deleting", name)
+		}
+	}
+
+	delete(asg.groups, name)
+	deleted, err := r.delete(scope, pool)
+	if err != nil {
+		t.Fatalf("failed to delete: %v", err)
+	}
+	if !deleted {
+		t.Fatalf("expected the pool to be deleted once its group is gone")
+	}
+}
+
+func TestLaunchTemplateOutdated(t *testing.T) {
+	now := time.Now()
+	current := v1alpha1.AWSMachinePool{
+		ObjectMeta: metav1.ObjectMeta{Generation: 2},
+		Status: v1alpha1.AWSMachinePoolStatus{
+			LaunchTemplateID:         "lt-0123",
+			LaunchTemplateVersion:    3,
+			ObservedGeneration:       2,
+			BootstrapTokenExpiration: &metav1.Time{Time: now.Add(20 * time.Hour)},
+		},
+	}
+
+	testCases := []struct {
+		name     string
+		mutate   func(*v1alpha1.AWSMachinePool)
+		outdated bool
+	}{
+		{
+			name:   "current",
+			mutate: func(*v1alpha1.AWSMachinePool) {},
+		},
+		{
+			name:     "no template",
+			mutate:   func(p *v1alpha1.AWSMachinePool) { p.Status.LaunchTemplateID = "" },
+			outdated: true,
+		},
+		{
+			name:     "spec changed",
+			mutate:   func(p *v1alpha1.AWSMachinePool) { p.Generation = 3 },
+			outdated: true,
+		},
+		{
+			name: "token expiring",
+			mutate: func(p *v1alpha1.AWSMachinePool) {
+				p.Status.BootstrapTokenExpiration = &metav1.Time{Time: now.Add(time.Hour)}
+			},
+			outdated: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pool := current.DeepCopy()
+			tc.mutate(pool)
+			if outdated := launchTemplateOutdated(pool, now); outdated != tc.outdated {
+				t.Fatalf("expected outdated %v, got %v", tc.outdated, outdated)
+			}
+		})
+	}
+}
+
+func TestGroupChanged(t *testing.T) {
+	desired := &autoscaling.GroupSpec{
+		LaunchTemplateID:      "lt-0123",
+		LaunchTemplateVersion: "3",
+		MinSize:               2,
+		MaxSize:               2,
+		DesiredCapacity:       2,
+		Subnets:               []string{"subnet-a", "subnet-b"},
+	}
+
+	same := group(desired)
+	same.Subnets = []string{"subnet-b", "subnet-a"}
+	if groupChanged(same, desired) {
+		t.Fatalf("did not expect a change for subnets in another order")
+	}
+
+	scaled := group(desired)
+	scaled.DesiredCapacity = 1
+	if !groupChanged(scaled, desired) {
+		t.Fatalf("expected a change of capacity")
+	}
+
+	moved := group(desired)
+	moved.Subnets = []string{"subnet-a"}
+	if !groupChanged(moved, desired) {
+		t.Fatalf("expected a change of subnets")
+	}
+}
@@ -0,0 +1,61 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actuators
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"strings"
+)
+
+// nameHashLength is the number of hexadecimal characters of the hash
+// that makes truncated resource names unique.
+const nameHashLength = 8
+
+// ResourceName returns the name of the cluster resource of the given kind,
+// following the naming scheme of the cluster provider spec.
+// Names longer than maxLength, or than the maximum length of the naming scheme,
+// are truncated and suffixed with a hash of the full name. A maxLength of 0 means
+// the resource has no length limit of its own.
+func (s *Scope) ResourceName(kind string, maxLength int) string {
+	name := fmt.Sprintf("%s-%s", s.Name(), kind)
+
+	if naming := s.ClusterConfig.ResourceNaming; naming != nil {
+		name = naming.Prefix + name + naming.Suffix
+		if naming.MaxLength > 0 && (maxLength == 0 || naming.MaxLength < maxLength) {
+			maxLength = naming.MaxLength
+		}
+	}
+
+	return truncateName(name, maxLength)
+}
+
+// truncateName shortens the name to maxLength characters, replacing its end
+// with a hash of the full name so that distinct names stay distinct.
+func truncateName(name string, maxLength int) string {
+	if maxLength == 0 || len(name) <= maxLength {
+		return name
+	}
+
+	hash := fmt.Sprintf("%x", sha256.Sum256([]byte(name)))[:nameHashLength]
+	if maxLength <= nameHashLength {
+		return hash[:maxLength]
+	}
+
+	keep := maxLength - nameHashLength - 1
+	return strings.TrimRight(name[:keep], "-") + "-" + hash
+}
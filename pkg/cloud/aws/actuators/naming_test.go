@@ -0,0 +1,79 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actuators
+
+import (
+	"testing"
+
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	clusterv1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+)
+
+func TestResourceName(t *testing.T) {
+	testCases := []struct {
+		name      string
+		cluster   string
+		naming    *v1alpha1.ResourceNaming
+		kind      string
+		maxLength int
+		expect    string
+	}{
+		{
+			name:    "default naming",
+			cluster: "test",
+			kind:    "vpc",
+			expect:  "test-vpc",
+		},
+		{
+			name:    "prefix and suffix",
+			cluster: "test",
+			naming:  &v1alpha1.ResourceNaming{Prefix: "acme-", Suffix: "-prod"},
+			kind:    "vpc",
+			expect:  "acme-test-vpc-prod",
+		},
+		{
+			name:      "resource limit",
+			cluster:   "a-very-long-cluster-name-for-testing",
+			kind:      "apiserver",
+			maxLength: 32,
+			expect:    "a-very-long-cluster-nam-7f03ccba",
+		},
+		{
+			name:      "naming scheme limit",
+			cluster:   "a-very-long-cluster-name-for-testing",
+			naming:    &v1alpha1.ResourceNaming{MaxLength: 20},
+			kind:      "apiserver",
+			maxLength: 32,
+			expect:    "a-very-long-7f03ccba",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := &Scope{
+				Cluster:       &clusterv1.Cluster{},
+				ClusterConfig: &v1alpha1.AWSClusterProviderSpec{ResourceNaming: tc.naming},
+			}
+			s.Cluster.Name = tc.cluster
+
+			got := s.ResourceName(tc.kind, tc.maxLength)
+			if got != tc.expect {
+				t.Fatalf("expected name %q, got %q", tc.expect, got)
+			}
+		})
+	}
+}
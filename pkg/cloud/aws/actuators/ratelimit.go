@@ -0,0 +1,46 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actuators
+
+import (
+	"github.com/aws/aws-sdk-go/aws/request"
+	"k8s.io/client-go/util/flowcontrol"
+)
+
+const (
+	// awsRequestQPS is the sustained rate of AWS API requests the controllers
+	// issue, shared by all clusters they reconcile.
+	awsRequestQPS = 10
+
+	// awsRequestBurst is the number of AWS API requests that can be issued
+	// at once before awsRequestQPS applies.
+	awsRequestBurst = 20
+)
+
+// awsRequestRateLimiter keeps the controllers within the API request limits of
+// the account, which are shared with every other client of the account and
+// otherwise cause throttling errors when describing many pages of resources.
+var awsRequestRateLimiter = flowcontrol.NewTokenBucketRateLimiter(awsRequestQPS, awsRequestBurst)
+
+// rateLimitHandler blocks a request, and every retry of it, until the rate
+// limiter allows it to be sent.
+var rateLimitHandler = request.NamedHandler{
+	Name: "cluster-api-provider-aws/RateLimit",
+	Fn: func(r *request.Request) {
+		awsRequestRateLimiter.Accept()
+	},
+}
@@ -17,13 +17,28 @@ limitations under the License.
 package actuators
 
 import (
+	"path"
+
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/aws/aws-sdk-go/service/elb"
+	"github.com/aws/aws-sdk-go/service/sts"
 	"github.com/pkg/errors"
 	"k8s.io/klog"
 	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/autoscaling"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/awserrors"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/cloudwatch"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/ebs"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/elbv2"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/instancetypes"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/ipam"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/pricing"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/secrets"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/sns"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/sqs"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/ssm"
 	clusterv1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
 	client "sigs.k8s.io/cluster-api/pkg/client/clientset_generated/clientset/typed/cluster/v1alpha1"
 )
@@ -33,6 +48,48 @@ type ScopeParams struct {
 	AWSClients
 	Cluster *clusterv1.Cluster
 	Client  client.ClusterV1alpha1Interface
+
+	// Secrets overrides the secret backend configured for the cluster.
+	Secrets secrets.Backend
+
+	// SSM overrides the parameter store of the region of the cluster.
+	SSM ssm.ParameterStore
+
+	// Automation overrides the runner of the Automation runbooks of the region of the cluster.
+	Automation ssm.Automation
+
+	// Sessions overrides the Session Manager sessions of the region of the cluster.
+	Sessions ssm.Sessions
+
+	// Commands overrides the runner of the Run Command scripts of the region of the cluster.
+	Commands ssm.Commands
+
+	// SNS overrides the publisher of the notifications of the cluster.
+	SNS sns.Publisher
+
+	// IPAM overrides the allocator of the network CIDR blocks configured for the cluster.
+	IPAM ipam.Allocator
+
+	// InstanceTypes overrides the describer of the instance types of the region of the cluster.
+	InstanceTypes instancetypes.Describer
+
+	// AutoScaling overrides the Auto Scaling groups of the region of the cluster.
+	AutoScaling autoscaling.Groups
+
+	// Queue overrides the receiver of the SQS messages of the region of the cluster.
+	Queue sqs.Queue
+
+	// Volumes overrides the provisioned throughput of the EBS volumes of the region of the cluster.
+	Volumes ebs.Volumes
+
+	// Metrics overrides the reader of the CloudWatch metrics of the region of the cluster.
+	Metrics cloudwatch.Metrics
+
+	// Prices overrides the prices of the instance types of the region of the cluster.
+	Prices pricing.Prices
+
+	// NetworkLoadBalancers overrides the network load balancers of the region of the cluster.
+	NetworkLoadBalancers elbv2.LoadBalancers
 }
 
 // NewScope creates a new Scope from the supplied parameters.
@@ -56,6 +113,7 @@ func NewScope(params ScopeParams) (*Scope, error) {
 	if err != nil {
 		return nil, errors.Errorf("failed to create aws session: %v", err)
 	}
+	session.Handlers.Sign.PushFrontNamed(rateLimitHandler)
 
 	if params.AWSClients.EC2 == nil {
 		params.AWSClients.EC2 = ec2.New(session)
@@ -65,18 +123,108 @@ func NewScope(params ScopeParams) (*Scope, error) {
 		params.AWSClients.ELB = elb.New(session)
 	}
 
+	if params.AWSClients.STS == nil {
+		params.AWSClients.STS = sts.New(session)
+	}
+
+	if params.SSM == nil {
+		params.SSM = ssm.NewService(session)
+	}
+
+	if params.Automation == nil {
+		params.Automation = ssm.NewService(session)
+	}
+
+	if params.Sessions == nil {
+		params.Sessions = ssm.NewService(session)
+	}
+
+	if params.Commands == nil {
+		params.Commands = ssm.NewService(session)
+	}
+
+	if params.SNS == nil {
+		params.SNS = sns.NewService(session)
+	}
+
+	if params.InstanceTypes == nil {
+		params.InstanceTypes = instancetypes.NewService(session)
+	}
+
+	if params.AutoScaling == nil {
+		params.AutoScaling = autoscaling.NewService(session)
+	}
+
+	if params.Queue == nil {
+		params.Queue = sqs.NewService(session)
+	}
+
+	if params.Volumes == nil {
+		params.Volumes = ebs.NewService(session)
+	}
+
+	if params.Metrics == nil {
+		params.Metrics = cloudwatch.NewService(session)
+	}
+
+	if params.Prices == nil {
+		params.Prices = pricing.NewService(session)
+	}
+
+	if params.NetworkLoadBalancers == nil {
+		params.NetworkLoadBalancers = elbv2.NewService(session)
+	}
+
+	if params.Secrets == nil && clusterConfig.SecretBackend != nil {
+		params.Secrets, err = secrets.NewBackend(clusterConfig.SecretBackend, session)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to create secret backend")
+		}
+	}
+
+	// An invalid IPAM configuration is reported when the cluster is
+	// reconciled, see ValidateIPAM, and must not keep it from being deleted.
+	if params.IPAM == nil && clusterConfig.IPAM != nil {
+		params.IPAM, err = ipam.NewAllocator(clusterConfig.IPAM, session)
+		if err != nil {
+			klog.Warningf("Ignoring the invalid ipam configuration of cluster %q: %v", params.Cluster.Name, err)
+			params.IPAM = nil
+		}
+	}
+
 	var clusterClient client.ClusterInterface
 	if params.Client != nil {
 		clusterClient = params.Client.Clusters(params.Cluster.Namespace)
 	}
 
-	return &Scope{
+	scope := &Scope{
 		AWSClients:    params.AWSClients,
 		Cluster:       params.Cluster,
 		ClusterClient: clusterClient,
 		ClusterConfig: clusterConfig,
 		ClusterStatus: clusterStatus,
-	}, nil
+		Secrets:       params.Secrets,
+		SSM:           params.SSM,
+		Automation:    params.Automation,
+		Sessions:      params.Sessions,
+		Commands:      params.Commands,
+		SNS:           params.SNS,
+		IPAM:          params.IPAM,
+		InstanceTypes: params.InstanceTypes,
+		AutoScaling:   params.AutoScaling,
+		Queue:         params.Queue,
+		Volumes:       params.Volumes,
+		Metrics:       params.Metrics,
+		Prices:        params.Prices,
+
+		NetworkLoadBalancers: params.NetworkLoadBalancers,
+	}
+
+	if err := scope.loadCAPrivateKey(); err != nil {
+		return nil, err
+	}
+
+	return scope, nil
 }
 
 // Scope defines the basic context for an actuator to operate upon.
@@ -86,6 +234,56 @@ type Scope struct {
 	ClusterClient client.ClusterInterface
 	ClusterConfig *v1alpha1.AWSClusterProviderSpec
 	ClusterStatus *v1alpha1.AWSClusterProviderStatus
+
+	// Secrets is the backend storing the CA private key, if any.
+	Secrets secrets.Backend
+
+	// SSM is the parameter store of the region of the cluster.
+	SSM ssm.ParameterStore
+
+	// Automation runs the Automation runbooks of the region of the cluster.
+	Automation ssm.Automation
+
+	// Sessions starts Session Manager sessions in the region of the cluster.
+	Sessions ssm.Sessions
+
+	// Commands runs scripts on the instances of the cluster.
+	Commands ssm.Commands
+
+	// SNS publishes the notifications of the cluster.
+	SNS sns.Publisher
+
+	// IPAM allocates the network CIDR blocks of the cluster, if configured.
+	IPAM ipam.Allocator
+
+	// InstanceTypes describes the instance types of the region of the cluster.
+	InstanceTypes instancetypes.Describer
+
+	// AutoScaling manages the Auto Scaling groups of the machine pools of the cluster.
+	AutoScaling autoscaling.Groups
+
+	// Queue receives the notifications of the lifecycle hooks of the machine pools.
+	Queue sqs.Queue
+
+	// Volumes reads and modifies the provisioned throughput of EBS volumes.
+	Volumes ebs.Volumes
+
+	// Metrics reads the CPU utilization of the instances of the machines.
+	Metrics cloudwatch.Metrics
+
+	// Prices looks up the hourly prices of the instances of the machines.
+	Prices pricing.Prices
+
+	// NetworkLoadBalancers manages the network load balancer exposing the
+	// API server with PrivateLink.
+	NetworkLoadBalancers elbv2.LoadBalancers
+
+	// accountID caches the ID of the AWS account of the credentials.
+	accountID string
+
+	// caKeyStored is true once the CA private key is known to be in Secrets,
+	// and must no longer be persisted in the cluster object.
+	caKeyStored bool
 }
 
 // Network returns the cluster network object.
@@ -93,6 +291,19 @@ func (s *Scope) Network() *v1alpha1.Network {
 	return &s.ClusterStatus.Network
 }
 
+// APIServerEndpoint returns the host the API server of the cluster is reached
+// at: the DNS name of its load balancer, or the Elastic IP replacing it. It is
+// empty until either is provisioned.
+func (s *Scope) APIServerEndpoint() string {
+	if s.ClusterConfig.APIServerElasticIP {
+		if eip := s.Network().APIServerElasticIP; eip != nil {
+			return eip.PublicIP
+		}
+		return ""
+	}
+	return s.Network().APIServerELB.DNSName
+}
+
 // VPC returns the cluster VPC.
 func (s *Scope) VPC() *v1alpha1.VPC {
 	return &s.ClusterStatus.Network.VPC
@@ -123,8 +334,72 @@ func (s *Scope) Region() string {
 	return s.ClusterConfig.Region
 }
 
+// caPrivateKeySecret is the key of the CA private key in the secret backend.
+func (s *Scope) caPrivateKeySecret() string {
+	return path.Join(s.Namespace(), s.Name(), "ca.key")
+}
+
+// loadCAPrivateKey reads the CA private key from the secret backend, unless
+// it is still recorded in the cluster object.
+func (s *Scope) loadCAPrivateKey() error {
+	if s.Secrets == nil || len(s.ClusterConfig.CAPrivateKey) > 0 || len(s.ClusterConfig.CACertificate) == 0 {
+		return nil
+	}
+
+	key, err := s.Secrets.Get(s.caPrivateKeySecret())
+	if awserrors.IsNotFound(err) {
+		// Consumers of the key fail on their own when it is missing, while
+		// the cluster can still be deleted.
+		klog.Warningf("CA private key of cluster %q not found in the secret backend", s.Name())
+		return nil
+	}
+	if err != nil {
+		return errors.Wrapf(err, "failed to load CA private key of cluster %q", s.Name())
+	}
+
+	s.ClusterConfig.CAPrivateKey = key
+	s.caKeyStored = true
+	return nil
+}
+
+// StoreCAPrivateKey moves the CA private key to the secret backend, if the
+// cluster has one. The key is then no longer persisted in the cluster object.
+func (s *Scope) StoreCAPrivateKey() error {
+	if s.Secrets == nil || s.caKeyStored || len(s.ClusterConfig.CAPrivateKey) == 0 {
+		return nil
+	}
+
+	if err := s.Secrets.Put(s.caPrivateKeySecret(), s.ClusterConfig.CAPrivateKey); err != nil {
+		return errors.Wrapf(err, "failed to store CA private key of cluster %q", s.Name())
+	}
+
+	klog.V(2).Infof("Stored CA private key of cluster %q in the secret backend", s.Name())
+	s.caKeyStored = true
+	return nil
+}
+
+// DeleteCAPrivateKey deletes the CA private key from the secret backend, if the cluster has one.
+func (s *Scope) DeleteCAPrivateKey() error {
+	if s.Secrets == nil {
+		return nil
+	}
+
+	if err := s.Secrets.Delete(s.caPrivateKeySecret()); err != nil {
+		return errors.Wrapf(err, "failed to delete CA private key of cluster %q", s.Name())
+	}
+
+	s.caKeyStored = false
+	return nil
+}
+
 func (s *Scope) storeClusterConfig(cluster *clusterv1.Cluster) (*clusterv1.Cluster, error) {
-	ext, err := v1alpha1.EncodeClusterSpec(s.ClusterConfig)
+	config := s.ClusterConfig
+	if s.caKeyStored {
+		config = config.DeepCopy()
+		config.CAPrivateKey = nil
+	}
+
+	ext, err := v1alpha1.EncodeClusterSpec(config)
 	if err != nil {
 		return nil, err
 	}
@@ -159,3 +434,42 @@ func (s *Scope) Close() {
 		klog.Errorf("[scope] failed to store provider status for cluster %q in namespace %q: %v", s.Cluster.Name, s.Cluster.Namespace, err)
 	}
 }
+
+// StoreStatus persists the cluster status, leaving the cluster configuration untouched.
+func (s *Scope) StoreStatus() {
+	if s.ClusterClient == nil {
+		return
+	}
+
+	latest, err := s.storeClusterStatus(s.Cluster)
+	if err != nil {
+		klog.Errorf("[scope] failed to store provider status for cluster %q in namespace %q: %v", s.Cluster.Name, s.Cluster.Namespace, err)
+		return
+	}
+
+	// The cluster is stored again when the scope is closed.
+	s.Cluster.ResourceVersion = latest.ResourceVersion
+}
+
+// Store persists the cluster, including its configuration and status, before
+// the scope is closed. It records the resources allocated outside of AWS, such
+// as CIDR blocks, which would otherwise be allocated again if the
+// reconciliation failed before the scope is closed.
+func (s *Scope) Store() error {
+	if s.ClusterClient == nil {
+		return nil
+	}
+
+	latest, err := s.storeClusterConfig(s.Cluster)
+	if err != nil {
+		return errors.Wrapf(err, "failed to store cluster %q", s.Cluster.Name)
+	}
+
+	latest, err = s.storeClusterStatus(latest)
+	if err != nil {
+		return errors.Wrapf(err, "failed to store the status of cluster %q", s.Cluster.Name)
+	}
+
+	s.Cluster.ResourceVersion = latest.ResourceVersion
+	return nil
+}
@@ -0,0 +1,107 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actuators
+
+import (
+	"errors"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/awserrors"
+	clusterv1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+)
+
+// memorySecrets is a secret backend keeping secrets in memory.
+type memorySecrets map[string][]byte
+
+func (m memorySecrets) Get(key string) ([]byte, error) {
+	value, ok := m[key]
+	if !ok {
+		return nil, awserrors.NewNotFound(errors.New("secret not found"))
+	}
+	return value, nil
+}
+
+func (m memorySecrets) Put(key string, value []byte) error {
+	m[key] = value
+	return nil
+}
+
+func (m memorySecrets) Delete(key string) error {
+	delete(m, key)
+	return nil
+}
+
+func newSecretsTestScope(t *testing.T, config *v1alpha1.AWSClusterProviderSpec, backend memorySecrets) *Scope {
+	ext, err := v1alpha1.EncodeClusterSpec(config)
+	if err != nil {
+		t.Fatalf("failed to encode cluster spec: %v", err)
+	}
+
+	scope, err := NewScope(ScopeParams{
+		Cluster: &clusterv1.Cluster{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-cluster", Namespace: "default"},
+			Spec: clusterv1.ClusterSpec{
+				ProviderSpec: clusterv1.ProviderSpec{Value: ext},
+			},
+		},
+		Secrets: backend,
+	})
+	if err != nil {
+		t.Fatalf("failed to create scope: %v", err)
+	}
+	return scope
+}
+
+func TestCAPrivateKeySecret(t *testing.T) {
+	backend := memorySecrets{}
+
+	// A key recorded in the cluster object is moved to the backend.
+	scope := newSecretsTestScope(t, &v1alpha1.AWSClusterProviderSpec{
+		CACertificate: []byte("cert"),
+		CAPrivateKey:  []byte("key"),
+	}, backend)
+	if err := scope.StoreCAPrivateKey(); err != nil {
+		t.Fatalf("failed to store CA private key: %v", err)
+	}
+	if string(backend["default/test-cluster/ca.key"]) != "key" {
+		t.Fatalf("expected CA private key in the backend, got %v", backend)
+	}
+	if !scope.caKeyStored {
+		t.Fatalf("expected CA private key to no longer be persisted in the cluster object")
+	}
+
+	// The key is then loaded from the backend.
+	scope = newSecretsTestScope(t, &v1alpha1.AWSClusterProviderSpec{CACertificate: []byte("cert")}, backend)
+	if string(scope.ClusterConfig.CAPrivateKey) != "key" {
+		t.Fatalf("expected CA private key to be loaded from the backend, got %q", scope.ClusterConfig.CAPrivateKey)
+	}
+
+	if err := scope.DeleteCAPrivateKey(); err != nil {
+		t.Fatalf("failed to delete CA private key: %v", err)
+	}
+	if len(backend) != 0 {
+		t.Fatalf("expected CA private key to be deleted from the backend, got %v", backend)
+	}
+
+	// A missing key does not prevent the scope from being created.
+	scope = newSecretsTestScope(t, &v1alpha1.AWSClusterProviderSpec{CACertificate: []byte("cert")}, backend)
+	if len(scope.ClusterConfig.CAPrivateKey) != 0 {
+		t.Fatalf("expected no CA private key, got %q", scope.ClusterConfig.CAPrivateKey)
+	}
+}
@@ -0,0 +1,48 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actuators
+
+import (
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	clusterv1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+)
+
+// UnselectedRequeueAfter is how long the deletion of a cluster or machine handled
+// by another controller is postponed, to leave its finalizer in place until the
+// other controller has deleted it.
+const UnselectedRequeueAfter = 5 * time.Minute
+
+// ClusterNameLabel is the label of the machines that names their cluster.
+// Machines of other clusters in the same namespace are ignored when looking
+// at the machines of a cluster.
+const ClusterNameLabel = "cluster.k8s.io/cluster-name"
+
+// Selects returns true if a controller restricted to the given label selector
+// handles the cluster and its machines. A nil selector selects every cluster.
+func Selects(selector labels.Selector, cluster *clusterv1.Cluster) bool {
+	return selector == nil || selector.Matches(labels.Set(cluster.Labels))
+}
+
+// ClusterMachines returns the options listing the machines of the cluster.
+func ClusterMachines(cluster *clusterv1.Cluster) metav1.ListOptions {
+	return metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(labels.Set{ClusterNameLabel: cluster.Name}).String(),
+	}
+}
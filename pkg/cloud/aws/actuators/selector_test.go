@@ -0,0 +1,81 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actuators
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	clusterv1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+)
+
+func TestSelects(t *testing.T) {
+	cluster := &clusterv1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "test-cluster",
+			Labels: map[string]string{"controller": "canary"},
+		},
+	}
+
+	testCases := []struct {
+		name     string
+		selector string
+		expected bool
+	}{
+		{name: "empty selector", selector: "", expected: true},
+		{name: "matching selector", selector: "controller=canary", expected: true},
+		{name: "other selector", selector: "controller=stable", expected: false},
+		{name: "negated selector", selector: "controller!=canary", expected: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			selector, err := labels.Parse(tc.selector)
+			if err != nil {
+				t.Fatalf("failed to parse selector: %v", err)
+			}
+
+			if actual := Selects(selector, cluster); actual != tc.expected {
+				t.Fatalf("expected %v, got %v", tc.expected, actual)
+			}
+		})
+	}
+
+	if !Selects(nil, cluster) {
+		t.Fatalf("expected a nil selector to select every cluster")
+	}
+}
+
+func TestClusterMachines(t *testing.T) {
+	cluster := &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"}}
+
+	selector, err := labels.Parse(ClusterMachines(cluster).LabelSelector)
+	if err != nil {
+		t.Fatalf("failed to parse selector: %v", err)
+	}
+
+	if !selector.Matches(labels.Set{ClusterNameLabel: "test-cluster"}) {
+		t.Fatalf("expected the machines of the cluster to be selected")
+	}
+	if selector.Matches(labels.Set{ClusterNameLabel: "other-cluster"}) {
+		t.Fatalf("expected the machines of another cluster not to be selected")
+	}
+	if selector.Matches(labels.Set{}) {
+		t.Fatalf("expected unlabelled machines not to be selected")
+	}
+}
@@ -0,0 +1,306 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actuators
+
+import (
+	"net"
+	"regexp"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/ipam"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/tags"
+)
+
+const (
+	// tagKeyMaxLength is the maximum length of the keys of AWS tags.
+	tagKeyMaxLength = 127
+
+	// tagValueMaxLength is the maximum length of the values of AWS tags.
+	tagValueMaxLength = 255
+
+	// untruncatedKindMaxLength is the length of the longest resource kind
+	// appended to the names of resources without a length limit of their own,
+	// whose names are only recorded in their Name tag.
+	untruncatedKindMaxLength = len("-management-peering")
+)
+
+// loadBalancerNameChars matches the characters allowed in load balancer names,
+// the most restrictive of the names derived from the cluster name.
+var loadBalancerNameChars = regexp.MustCompile(`^[a-zA-Z0-9-]*$`)
+
+// etcdCipherSuites lists the TLS 1.2 cipher suites accepted for etcd
+// connections when transit encryption is enforced, those with forward secrecy
+// and authenticated encryption recommended by security benchmarks.
+var etcdCipherSuites = map[string]bool{
+	"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256":       true,
+	"TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384":       true,
+	"TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305":        true,
+	"TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256": true,
+	"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256":         true,
+	"TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384":         true,
+	"TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305":          true,
+	"TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256":   true,
+}
+
+// ValidateClusterNames checks that the names of the AWS resources of a cluster,
+// derived from the cluster name and naming scheme, are accepted by AWS.
+//
+// Names longer than the limit of a resource are truncated by ResourceName, so
+// only the limits applying to untruncated names, like the length of the
+// cluster tag key, are checked.
+func ValidateClusterNames(name string, naming *v1alpha1.ResourceNaming) error {
+	var errs field.ErrorList
+
+	namePath := field.NewPath("metadata", "name")
+	if max := tagKeyMaxLength - len(tags.ClusterKey("")); len(name) > max {
+		errs = append(errs, field.TooLong(namePath, name, max))
+	}
+	if !loadBalancerNameChars.MatchString(name) {
+		errs = append(errs, field.Invalid(namePath, name, "must only contain alphanumeric characters and hyphens to be used in load balancer names"))
+	}
+
+	var prefix, suffix string
+	maxLength := 0
+	namingPath := field.NewPath("spec", "providerSpec", "value", "resourceNaming")
+	if naming != nil {
+		prefix, suffix, maxLength = naming.Prefix, naming.Suffix, naming.MaxLength
+		if !loadBalancerNameChars.MatchString(prefix) {
+			errs = append(errs, field.Invalid(namingPath.Child("prefix"), prefix, "must only contain alphanumeric characters and hyphens to be used in load balancer names"))
+		}
+		if !loadBalancerNameChars.MatchString(suffix) {
+			errs = append(errs, field.Invalid(namingPath.Child("suffix"), suffix, "must only contain alphanumeric characters and hyphens to be used in load balancer names"))
+		}
+		if strings.HasSuffix(suffix, "-") {
+			errs = append(errs, field.Invalid(namingPath.Child("suffix"), suffix, "must not end with a hyphen, as load balancer names cannot"))
+		}
+	}
+
+	switch start := prefix + name; {
+	case strings.HasPrefix(start, "-"):
+		errs = append(errs, field.Invalid(namePath, start, "resource names must not begin with a hyphen, as load balancer names cannot"))
+	case strings.HasPrefix(start, "internal-"):
+		errs = append(errs, field.Invalid(namePath, start, `resource names must not begin with "internal-", as load balancer names cannot`))
+	case strings.HasPrefix(start, "sg-"):
+		errs = append(errs, field.Invalid(namePath, start, `resource names must not begin with "sg-", as security group names cannot`))
+	}
+
+	if maxLength == 0 || maxLength > tagValueMaxLength {
+		if length := len(prefix+name+suffix) + untruncatedKindMaxLength; length > tagValueMaxLength {
+			errs = append(errs, field.Invalid(namingPath, prefix+name+suffix,
+				"resource names must fit in the 255 characters of tag values, shorten the cluster name, prefix or suffix, or set maxLength"))
+		}
+	}
+
+	return errs.ToAggregate()
+}
+
+// ValidateTransitEncryption checks the transit encryption settings of a
+// cluster. The cipher suites are rendered in the etcd flags of the control
+// plane machines, where an unknown name would keep etcd from starting.
+func ValidateTransitEncryption(encryption *v1alpha1.TransitEncryption) error {
+	if encryption == nil {
+		return nil
+	}
+
+	var errs field.ErrorList
+	suitesPath := field.NewPath("spec", "providerSpec", "value", "transitEncryption", "etcdCipherSuites")
+	seen := map[string]bool{}
+	for i, suite := range encryption.EtcdCipherSuites {
+		switch {
+		case !etcdCipherSuites[suite]:
+			errs = append(errs, field.NotSupported(suitesPath.Index(i), suite, sortedKeys(etcdCipherSuites)))
+		case seen[suite]:
+			errs = append(errs, field.Duplicate(suitesPath.Index(i), suite))
+		}
+		seen[suite] = true
+	}
+
+	return errs.ToAggregate()
+}
+
+// ValidateAPIServerElasticIP checks that a cluster whose API server is reached
+// at an Elastic IP doesn't also make it private, nor expose it with PrivateLink.
+func ValidateAPIServerElasticIP(config *v1alpha1.AWSClusterProviderSpec) error {
+	if !config.APIServerElasticIP {
+		return nil
+	}
+
+	path := field.NewPath("spec", "providerSpec", "value", "apiServerElasticIP")
+	if config.PrivateAPIServer {
+		return field.Forbidden(path, "cannot be combined with privateAPIServer")
+	}
+	if config.APIServerPrivateLink != nil {
+		return field.Forbidden(path, "cannot be combined with apiServerPrivateLink")
+	}
+	return nil
+}
+
+// ValidateScrubUserData checks that the user data of a machine is only
+// scrubbed if its instance can be stopped and started again. Control plane
+// instances cannot, as their etcd member would be unavailable meanwhile, and
+// an etcd data directory on the instance store would be lost. Nor can spot
+// instances, which are only known once launched from their launch template.
+func ValidateScrubUserData(role string, config *v1alpha1.AWSMachineProviderSpec, spot bool) error {
+	if !config.ScrubUserData {
+		return nil
+	}
+
+	scrubPath := field.NewPath("spec", "providerSpec", "value", "scrubUserData")
+	switch {
+	case config.EtcdVolume != nil && config.EtcdVolume.InstanceStore:
+		return field.Forbidden(scrubPath, "cannot be combined with an etcd volume on the instance store, which is lost when the instance is stopped")
+	case role == "controlplane":
+		return field.Forbidden(scrubPath, "control plane instances cannot be stopped")
+	case spot:
+		return field.Forbidden(scrubPath, "spot instances cannot be stopped")
+	}
+	return nil
+}
+
+// ValidateDeletionPolicy checks that only the instances of machines which can
+// be stopped are parked when the machine is deleted. Control plane members
+// would be left in etcd, and spot instances cannot be stopped, which is only
+// known once they are launched from their launch template.
+func ValidateDeletionPolicy(role string, policy v1alpha1.InstanceDeletionPolicy, spot bool) error {
+	if policy != v1alpha1.InstanceDeletionPolicyStop {
+		return nil
+	}
+
+	policyPath := field.NewPath("spec", "providerSpec", "value", "deletionPolicy")
+	switch {
+	case role == "controlplane":
+		return field.Forbidden(policyPath, "control plane instances cannot be stopped")
+	case spot:
+		return field.Forbidden(policyPath, "spot instances cannot be stopped")
+	}
+	return nil
+}
+
+// ValidateNTPServers checks that the NTP servers of a cluster are IP
+// addresses or host names, as they are rendered in the configuration of the
+// time daemon of the machines.
+func ValidateNTPServers(servers []string) error {
+	var errs field.ErrorList
+	serversPath := field.NewPath("spec", "providerSpec", "value", "ntpServers")
+	seen := map[string]bool{}
+	for i, server := range servers {
+		switch {
+		case net.ParseIP(server) == nil && len(validation.IsDNS1123Subdomain(server)) > 0:
+			errs = append(errs, field.Invalid(serversPath.Index(i), server, "must be an IP address or a host name"))
+		case seen[server]:
+			errs = append(errs, field.Duplicate(serversPath.Index(i), server))
+		}
+		seen[server] = true
+	}
+
+	return errs.ToAggregate()
+}
+
+// ValidateIPAM checks the IPAM configuration of a cluster, which is otherwise
+// ignored so that the cluster can still be deleted.
+func ValidateIPAM(spec *v1alpha1.IPAM) error {
+	if spec == nil {
+		return nil
+	}
+
+	_, err := ipam.NewAllocator(spec, nil)
+	return err
+}
+
+// ValidateMachinePools checks that the machine pools of a cluster name their
+// Auto Scaling group, each once.
+func ValidateMachinePools(pools []v1alpha1.MachinePool) error {
+	var errs field.ErrorList
+	poolsPath := field.NewPath("spec", "providerSpec", "value", "machinePools")
+	seen := map[string]bool{}
+	for i, pool := range pools {
+		namePath := poolsPath.Index(i).Child("autoScalingGroupName")
+		switch {
+		case pool.AutoScalingGroupName == "":
+			errs = append(errs, field.Required(namePath, "must name an Auto Scaling group"))
+		case seen[pool.AutoScalingGroupName]:
+			errs = append(errs, field.Duplicate(namePath, pool.AutoScalingGroupName))
+		}
+		seen[pool.AutoScalingGroupName] = true
+
+		errs = append(errs, validateScalingSchedules(pool.Schedules, poolsPath.Index(i).Child("schedules"))...)
+	}
+
+	return errs.ToAggregate()
+}
+
+func validateScalingSchedules(schedules []v1alpha1.ScalingSchedule, path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	seen := map[string]bool{}
+	for i, schedule := range schedules {
+		schedulePath := path.Index(i)
+		namePath := schedulePath.Child("name")
+		switch {
+		case schedule.Name == "":
+			errs = append(errs, field.Required(namePath, "must name the schedule"))
+		case seen[schedule.Name]:
+			errs = append(errs, field.Duplicate(namePath, schedule.Name))
+		default:
+			for _, msg := range validation.IsDNS1123Label(schedule.Name) {
+				errs = append(errs, field.Invalid(namePath, schedule.Name, msg))
+			}
+		}
+		seen[schedule.Name] = true
+
+		// Cron expressions of scheduled actions have five fields.
+		if len(strings.Fields(schedule.Recurrence)) != 5 {
+			errs = append(errs, field.Invalid(schedulePath.Child("recurrence"), schedule.Recurrence, `must be a cron expression of the form "minute hour day-of-month month day-of-week"`))
+		}
+
+		if schedule.MinSize == nil && schedule.MaxSize == nil && schedule.DesiredCapacity == nil {
+			errs = append(errs, field.Required(schedulePath, "must set at least one of minSize, maxSize and desiredCapacity"))
+		}
+
+		sizes := []struct {
+			name string
+			size *int64
+		}{
+			{"minSize", schedule.MinSize},
+			{"maxSize", schedule.MaxSize},
+			{"desiredCapacity", schedule.DesiredCapacity},
+		}
+		for _, s := range sizes {
+			if s.size != nil && *s.size < 0 {
+				errs = append(errs, field.Invalid(schedulePath.Child(s.name), *s.size, "must not be negative"))
+			}
+		}
+
+		if schedule.MinSize != nil && schedule.MaxSize != nil && *schedule.MinSize > *schedule.MaxSize {
+			errs = append(errs, field.Invalid(schedulePath.Child("minSize"), *schedule.MinSize, "must not exceed maxSize"))
+		}
+	}
+
+	return errs
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
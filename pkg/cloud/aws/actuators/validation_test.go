@@ -0,0 +1,383 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actuators
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+)
+
+func TestValidateClusterNames(t *testing.T) {
+	testCases := []struct {
+		name        string
+		cluster     string
+		naming      *v1alpha1.ResourceNaming
+		expectError string
+	}{
+		{
+			name:    "valid name",
+			cluster: "test-cluster",
+		},
+		{
+			name:    "long name truncated in resource names",
+			cluster: strings.Repeat("a", 64),
+		},
+		{
+			name:        "name too long for the cluster tag",
+			cluster:     strings.Repeat("a", 106),
+			expectError: "must have at most 105 characters",
+		},
+		{
+			name:        "dots are not allowed in load balancer names",
+			cluster:     "test.example.com",
+			expectError: "must only contain alphanumeric characters and hyphens",
+		},
+		{
+			name:        "security group prefix",
+			cluster:     "sg-cluster",
+			expectError: `must not begin with "sg-"`,
+		},
+		{
+			name:    "valid naming scheme",
+			cluster: "test",
+			naming:  &v1alpha1.ResourceNaming{Prefix: "acme-", Suffix: "-prod"},
+		},
+		{
+			name:        "invalid prefix",
+			cluster:     "test",
+			naming:      &v1alpha1.ResourceNaming{Prefix: "acme_"},
+			expectError: "resourceNaming.prefix",
+		},
+		{
+			name:        "suffix ending with a hyphen",
+			cluster:     "test",
+			naming:      &v1alpha1.ResourceNaming{Suffix: "-prod-"},
+			expectError: "must not end with a hyphen",
+		},
+		{
+			name:        "internal prefix",
+			cluster:     "test",
+			naming:      &v1alpha1.ResourceNaming{Prefix: "internal-"},
+			expectError: `must not begin with "internal-"`,
+		},
+		{
+			name:        "names too long for tag values",
+			cluster:     strings.Repeat("a", 100),
+			naming:      &v1alpha1.ResourceNaming{Prefix: strings.Repeat("p", 80), Suffix: strings.Repeat("s", 80)},
+			expectError: "must fit in the 255 characters of tag values",
+		},
+		{
+			name:    "long names truncated by the naming scheme",
+			cluster: strings.Repeat("a", 100),
+			naming:  &v1alpha1.ResourceNaming{Prefix: strings.Repeat("p", 80), Suffix: strings.Repeat("s", 80), MaxLength: 63},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateClusterNames(tc.cluster, tc.naming)
+			if tc.expectError == "" {
+				if err != nil {
+					t.Fatalf("did not expect error: %v", err)
+				}
+				return
+			}
+
+			if err == nil || !strings.Contains(err.Error(), tc.expectError) {
+				t.Fatalf("expected error containing %q, got: %v", tc.expectError, err)
+			}
+		})
+	}
+}
+
+func TestValidateTransitEncryption(t *testing.T) {
+	testCases := []struct {
+		name        string
+		encryption  *v1alpha1.TransitEncryption
+		expectError string
+	}{
+		{
+			name: "not set",
+		},
+		{
+			name:       "default cipher suites",
+			encryption: &v1alpha1.TransitEncryption{KubeletServingCertificates: true},
+		},
+		{
+			name: "valid cipher suites",
+			encryption: &v1alpha1.TransitEncryption{EtcdCipherSuites: []string{
+				"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256",
+				"TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384",
+			}},
+		},
+		{
+			name:        "cipher suite without forward secrecy",
+			encryption:  &v1alpha1.TransitEncryption{EtcdCipherSuites: []string{"TLS_RSA_WITH_AES_128_GCM_SHA256"}},
+			expectError: "etcdCipherSuites[0]: Unsupported value",
+		},
+		{
+			name: "duplicate cipher suite",
+			encryption: &v1alpha1.TransitEncryption{EtcdCipherSuites: []string{
+				"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256",
+				"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256",
+			}},
+			expectError: "etcdCipherSuites[1]: Duplicate value",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateTransitEncryption(tc.encryption)
+			if tc.expectError == "" {
+				if err != nil {
+					t.Fatalf("did not expect error: %v", err)
+				}
+				return
+			}
+
+			if err == nil || !strings.Contains(err.Error(), tc.expectError) {
+				t.Fatalf("expected error containing %q, got: %v", tc.expectError, err)
+			}
+		})
+	}
+}
+
+func TestValidateNTPServers(t *testing.T) {
+	testCases := []struct {
+		name        string
+		servers     []string
+		expectError string
+	}{
+		{
+			name: "default servers",
+		},
+		{
+			name:    "addresses and host names",
+			servers: []string{"169.254.169.123", "fd00:ec2::123", "ntp.example.com"},
+		},
+		{
+			name:        "invalid host name",
+			servers:     []string{"ntp.example.com iburst"},
+			expectError: "ntpServers[0]: Invalid value",
+		},
+		{
+			name:        "duplicate server",
+			servers:     []string{"ntp.example.com", "ntp.example.com"},
+			expectError: "ntpServers[1]: Duplicate value",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateNTPServers(tc.servers)
+			if tc.expectError == "" {
+				if err != nil {
+					t.Fatalf("did not expect error: %v", err)
+				}
+				return
+			}
+
+			if err == nil || !strings.Contains(err.Error(), tc.expectError) {
+				t.Fatalf("expected error containing %q, got: %v", tc.expectError, err)
+			}
+		})
+	}
+}
+
+func TestValidateIPAM(t *testing.T) {
+	if err := ValidateIPAM(nil); err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+	if err := ValidateIPAM(&v1alpha1.IPAM{Pool: &v1alpha1.IPAMPool{CidrBlock: "10.0.0.0/8"}}); err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+	if err := ValidateIPAM(&v1alpha1.IPAM{Pool: &v1alpha1.IPAMPool{CidrBlock: "10.0.0.0"}}); err == nil {
+		t.Fatalf("expected error for an invalid pool")
+	}
+	if err := ValidateIPAM(&v1alpha1.IPAM{Webhook: &v1alpha1.IPAMWebhook{URL: "http://10.0.0.1/allocate"}}); err == nil {
+		t.Fatalf("expected error for a private webhook")
+	}
+}
+
+func TestValidateAPIServerElasticIP(t *testing.T) {
+	if err := ValidateAPIServerElasticIP(&v1alpha1.AWSClusterProviderSpec{APIServerElasticIP: true}); err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+	if err := ValidateAPIServerElasticIP(&v1alpha1.AWSClusterProviderSpec{APIServerElasticIP: true, PrivateAPIServer: true}); err == nil {
+		t.Fatalf("expected error for a private API server")
+	}
+	if err := ValidateAPIServerElasticIP(&v1alpha1.AWSClusterProviderSpec{APIServerElasticIP: true, APIServerPrivateLink: &v1alpha1.PrivateLink{}}); err == nil {
+		t.Fatalf("expected error for an API server exposed with PrivateLink")
+	}
+}
+
+func TestValidateDeletionPolicy(t *testing.T) {
+	if err := ValidateDeletionPolicy("node", v1alpha1.InstanceDeletionPolicyStop, false); err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+	if err := ValidateDeletionPolicy("controlplane", v1alpha1.InstanceDeletionPolicyTerminate, true); err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+	if err := ValidateDeletionPolicy("controlplane", v1alpha1.InstanceDeletionPolicyStop, false); err == nil {
+		t.Fatalf("expected error for a control plane machine")
+	}
+	if err := ValidateDeletionPolicy("node", v1alpha1.InstanceDeletionPolicyStop, true); err == nil {
+		t.Fatalf("expected error for a spot instance")
+	}
+}
+
+func TestValidateScrubUserData(t *testing.T) {
+	testCases := []struct {
+		name          string
+		role          string
+		config        *v1alpha1.AWSMachineProviderSpec
+		spot          bool
+		expectedError bool
+	}{
+		{
+			name:   "scrubbing disabled on a control plane machine",
+			role:   "controlplane",
+			config: &v1alpha1.AWSMachineProviderSpec{},
+		},
+		{
+			name:   "node machine",
+			role:   "node",
+			config: &v1alpha1.AWSMachineProviderSpec{ScrubUserData: true},
+		},
+		{
+			name:          "control plane machine",
+			role:          "controlplane",
+			config:        &v1alpha1.AWSMachineProviderSpec{ScrubUserData: true},
+			expectedError: true,
+		},
+		{
+			name: "etcd on the instance store",
+			role: "controlplane",
+			config: &v1alpha1.AWSMachineProviderSpec{
+				ScrubUserData: true,
+				EtcdVolume:    &v1alpha1.EtcdVolume{InstanceStore: true},
+			},
+			expectedError: true,
+		},
+		{
+			name:          "spot instance",
+			role:          "node",
+			config:        &v1alpha1.AWSMachineProviderSpec{ScrubUserData: true},
+			spot:          true,
+			expectedError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateScrubUserData(tc.role, tc.config, tc.spot)
+			if (err != nil) != tc.expectedError {
+				t.Fatalf("expected error %t, got %v", tc.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestValidateMachinePools(t *testing.T) {
+	testCases := []struct {
+		name        string
+		pools       []v1alpha1.MachinePool
+		expectError string
+	}{
+		{
+			name: "no pools",
+		},
+		{
+			name:  "pools",
+			pools: []v1alpha1.MachinePool{{AutoScalingGroupName: "pool-a"}, {AutoScalingGroupName: "pool-b"}},
+		},
+		{
+			name:        "missing group",
+			pools:       []v1alpha1.MachinePool{{}},
+			expectError: "machinePools[0].autoScalingGroupName: Required value",
+		},
+		{
+			name:        "duplicate group",
+			pools:       []v1alpha1.MachinePool{{AutoScalingGroupName: "pool-a"}, {AutoScalingGroupName: "pool-a"}},
+			expectError: "machinePools[1].autoScalingGroupName: Duplicate value",
+		},
+		{
+			name: "schedules",
+			pools: []v1alpha1.MachinePool{{
+				AutoScalingGroupName: "pool-a",
+				Schedules: []v1alpha1.ScalingSchedule{
+					{Name: "working-hours", Recurrence: "0 8 * * 1-5", TimeZone: "Europe/Paris", MinSize: aws.Int64(3), MaxSize: aws.Int64(10)},
+					{Name: "night", Recurrence: "0 20 * * *", DesiredCapacity: aws.Int64(0)},
+				},
+			}},
+		},
+		{
+			name: "duplicate schedule",
+			pools: []v1alpha1.MachinePool{{
+				AutoScalingGroupName: "pool-a",
+				Schedules: []v1alpha1.ScalingSchedule{
+					{Name: "night", Recurrence: "0 20 * * *", DesiredCapacity: aws.Int64(0)},
+					{Name: "night", Recurrence: "0 22 * * *", DesiredCapacity: aws.Int64(0)},
+				},
+			}},
+			expectError: "machinePools[0].schedules[1].name: Duplicate value",
+		},
+		{
+			name: "invalid recurrence",
+			pools: []v1alpha1.MachinePool{{
+				AutoScalingGroupName: "pool-a",
+				Schedules:            []v1alpha1.ScalingSchedule{{Name: "night", Recurrence: "@daily", DesiredCapacity: aws.Int64(0)}},
+			}},
+			expectError: "machinePools[0].schedules[0].recurrence: Invalid value",
+		},
+		{
+			name: "no size",
+			pools: []v1alpha1.MachinePool{{
+				AutoScalingGroupName: "pool-a",
+				Schedules:            []v1alpha1.ScalingSchedule{{Name: "night", Recurrence: "0 20 * * *"}},
+			}},
+			expectError: "machinePools[0].schedules[0]: Required value",
+		},
+		{
+			name: "min size exceeding max size",
+			pools: []v1alpha1.MachinePool{{
+				AutoScalingGroupName: "pool-a",
+				Schedules:            []v1alpha1.ScalingSchedule{{Name: "night", Recurrence: "0 20 * * *", MinSize: aws.Int64(3), MaxSize: aws.Int64(1)}},
+			}},
+			expectError: "machinePools[0].schedules[0].minSize: Invalid value",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateMachinePools(tc.pools)
+			if tc.expectError == "" {
+				if err != nil {
+					t.Fatalf("did not expect error: %v", err)
+				}
+				return
+			}
+
+			if err == nil || !strings.Contains(err.Error(), tc.expectError) {
+				t.Fatalf("expected error containing %q, got: %v", tc.expectError, err)
+			}
+		})
+	}
+}
@@ -19,21 +19,50 @@ package converters
 import (
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/ec2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
 )
 
 func SDKToInstance(v *ec2.Instance) *v1alpha1.Instance {
 	i := &v1alpha1.Instance{
-		ID:           aws.StringValue(v.InstanceId),
-		State:        v1alpha1.InstanceState(*v.State.Name),
-		Type:         aws.StringValue(v.InstanceType),
-		SubnetID:     aws.StringValue(v.SubnetId),
-		ImageID:      aws.StringValue(v.ImageId),
-		KeyName:      v.KeyName,
-		PrivateIP:    v.PrivateIpAddress,
-		PublicIP:     v.PublicIpAddress,
-		ENASupport:   v.EnaSupport,
-		EBSOptimized: v.EbsOptimized,
+		ID:             aws.StringValue(v.InstanceId),
+		State:          v1alpha1.InstanceState(*v.State.Name),
+		Type:           aws.StringValue(v.InstanceType),
+		SubnetID:       aws.StringValue(v.SubnetId),
+		ImageID:        aws.StringValue(v.ImageId),
+		KeyName:        v.KeyName,
+		PrivateIP:      v.PrivateIpAddress,
+		PrivateDNSName: v.PrivateDnsName,
+		PublicIP:       v.PublicIpAddress,
+		PublicDNSName:  v.PublicDnsName,
+		ENASupport:     v.EnaSupport,
+		EBSOptimized:   v.EbsOptimized,
+		Spot:           aws.StringValue(v.InstanceLifecycle) == ec2.InstanceLifecycleTypeSpot,
+
+		CapacityReservationID: aws.StringValue(v.CapacityReservationId),
+	}
+
+	if v.LaunchTime != nil {
+		launched := metav1.NewTime(*v.LaunchTime)
+		i.LaunchTime = &launched
+	}
+
+	if v.Placement != nil {
+		i.PlacementGroupName = aws.StringValue(v.Placement.GroupName)
+		i.Tenancy = v1alpha1.Tenancy(aws.StringValue(v.Placement.Tenancy))
+		i.HostID = aws.StringValue(v.Placement.HostId)
+	}
+
+	if v.CpuOptions != nil {
+		i.CPUOptions = &v1alpha1.CPUOptions{
+			CoreCount:      aws.Int64Value(v.CpuOptions.CoreCount),
+			ThreadsPerCore: aws.Int64Value(v.CpuOptions.ThreadsPerCore),
+		}
+	}
+
+	if v.Monitoring != nil {
+		state := aws.StringValue(v.Monitoring.State)
+		i.DetailedMonitoring = state == ec2.MonitoringStateEnabled || state == ec2.MonitoringStatePending
 	}
 
 	for _, sg := range v.SecurityGroups {
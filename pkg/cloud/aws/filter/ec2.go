@@ -29,6 +29,8 @@ const (
 	filterNameVpcID         = "vpc-id"
 	filterNameState         = "state"
 	filterNameVpcAttachment = "attachment.vpc-id"
+	filterNameRequesterVpc  = "requester-vpc-info.vpc-id"
+	filterNameAccepterVpc   = "accepter-vpc-info.vpc-id"
 )
 
 var (
@@ -88,6 +90,14 @@ func (ec2Filters) ProviderRole(role string) *ec2.Filter {
 	}
 }
 
+// SourceAMI returns a filter based on the AMI an image was copied from.
+func (ec2Filters) SourceAMI(imageID string) *ec2.Filter {
+	return &ec2.Filter{
+		Name:   aws.String(fmt.Sprintf("tag:%s", tags.NameAWSProviderSourceAMI)),
+		Values: aws.StringSlice([]string{imageID}),
+	}
+}
+
 // VPC returns a filter based on the id of the VPC.
 func (ec2Filters) VPC(vpcID string) *ec2.Filter {
 	return &ec2.Filter{
@@ -104,6 +114,22 @@ func (ec2Filters) VPCAttachment(vpcID string) *ec2.Filter {
 	}
 }
 
+// RequesterVPC returns a filter based on the id of the VPC requesting a peering connection.
+func (ec2Filters) RequesterVPC(vpcID string) *ec2.Filter {
+	return &ec2.Filter{
+		Name:   aws.String(filterNameRequesterVpc),
+		Values: aws.StringSlice([]string{vpcID}),
+	}
+}
+
+// AccepterVPC returns a filter based on the id of the VPC accepting a peering connection.
+func (ec2Filters) AccepterVPC(vpcID string) *ec2.Filter {
+	return &ec2.Filter{
+		Name:   aws.String(filterNameAccepterVpc),
+		Values: aws.StringSlice([]string{vpcID}),
+	}
+}
+
 // Available returns a filter based on the state being available.
 func (ec2Filters) Available() *ec2.Filter {
 	return &ec2.Filter{
@@ -128,6 +154,57 @@ func (ec2Filters) InstanceStates(states ...string) *ec2.Filter {
 	}
 }
 
+// InstanceIDs returns a filter based on the IDs of instances. Unlike the
+// InstanceIds parameter, it doesn't fail for instances which don't exist.
+func (ec2Filters) InstanceIDs(ids ...string) *ec2.Filter {
+	return &ec2.Filter{
+		Name:   aws.String("instance-id"),
+		Values: aws.StringSlice(ids),
+	}
+}
+
+// InstanceLifecycle returns a filter based on the lifecycle of instances,
+// e.g. "spot".
+func (ec2Filters) InstanceLifecycle(lifecycle string) *ec2.Filter {
+	return &ec2.Filter{
+		Name:   aws.String("instance-lifecycle"),
+		Values: aws.StringSlice([]string{lifecycle}),
+	}
+}
+
+// AutoScalingGroups returns a filter based on the Auto Scaling groups
+// instances were launched by.
+func (ec2Filters) AutoScalingGroups(names ...string) *ec2.Filter {
+	return &ec2.Filter{
+		Name:   aws.String("tag:aws:autoscaling:groupName"),
+		Values: aws.StringSlice(names),
+	}
+}
+
+// PlacementGroupNames returns a filter based on the names of placement groups.
+func (ec2Filters) PlacementGroupNames(names ...string) *ec2.Filter {
+	return &ec2.Filter{
+		Name:   aws.String("group-name"),
+		Values: aws.StringSlice(names),
+	}
+}
+
+// PlacementGroupStates returns a filter based on the list of states passed in.
+func (ec2Filters) PlacementGroupStates(states ...string) *ec2.Filter {
+	return &ec2.Filter{
+		Name:   aws.String("state"),
+		Values: aws.StringSlice(states),
+	}
+}
+
+// ReservedInstanceStates returns a filter based on the list of states passed in.
+func (ec2Filters) ReservedInstanceStates(states ...string) *ec2.Filter {
+	return &ec2.Filter{
+		Name:   aws.String("state"),
+		Values: aws.StringSlice(states),
+	}
+}
+
 // VPCStates returns a filter based on the list of states passed in.
 func (ec2Filters) VPCStates(states ...string) *ec2.Filter {
 	return &ec2.Filter{
@@ -143,3 +220,11 @@ func (ec2Filters) SubnetStates(states ...string) *ec2.Filter {
 		Values: aws.StringSlice(states),
 	}
 }
+
+// VPCPeeringStates returns a filter based on the list of states passed in.
+func (ec2Filters) VPCPeeringStates(states ...string) *ec2.Filter {
+	return &ec2.Filter{
+		Name:   aws.String("status-code"),
+		Values: aws.StringSlice(states),
+	}
+}
@@ -0,0 +1,657 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package autoscaling coordinates the Auto Scaling groups of machine pools.
+package autoscaling
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/queryprotocol"
+)
+
+const (
+	serviceName = "autoscaling"
+	apiVersion  = "2011-01-01"
+
+	// LifecycleStateInService is the lifecycle state of the instances of a
+	// group serving traffic.
+	LifecycleStateInService = "InService"
+
+	// GroupStatusDeleting is the status of the groups being deleted.
+	GroupStatusDeleting = "Delete in progress"
+
+	// LifecycleTransitionTerminating is the transition notified by the
+	// lifecycle hooks of instances being terminated.
+	LifecycleTransitionTerminating = "autoscaling:EC2_INSTANCE_TERMINATING"
+
+	// LifecycleActionContinue is the result of lifecycle actions letting the
+	// group carry on with the transition.
+	LifecycleActionContinue = "CONTINUE"
+)
+
+// Group is an Auto Scaling group.
+type Group struct {
+	// Name is the name of the group.
+	Name string
+
+	// MinSize is the minimum number of instances of the group.
+	MinSize int64
+
+	// MaxSize is the maximum number of instances of the group.
+	MaxSize int64
+
+	// DesiredCapacity is the number of instances the group maintains.
+	DesiredCapacity int64
+
+	// Instances are the instances of the group.
+	Instances []Instance
+
+	// LaunchTemplateID is the ID of the launch template of the group, if it
+	// launches its instances from one.
+	LaunchTemplateID string
+
+	// LaunchTemplateVersion is the version of the launch template.
+	LaunchTemplateVersion string
+
+	// Subnets are the IDs of the subnets the group launches instances in.
+	Subnets []string
+
+	// Status is GroupStatusDeleting while the group is being deleted, and
+	// empty otherwise.
+	Status string
+}
+
+// GroupSpec is the configuration of a group launching its instances from a
+// launch template.
+type GroupSpec struct {
+	// Name is the name of the group.
+	Name string
+
+	// LaunchTemplateID is the ID of the launch template of the group.
+	LaunchTemplateID string
+
+	// LaunchTemplateVersion is the version of the launch template.
+	LaunchTemplateVersion string
+
+	// MinSize is the minimum number of instances of the group.
+	MinSize int64
+
+	// MaxSize is the maximum number of instances of the group.
+	MaxSize int64
+
+	// DesiredCapacity is the number of instances the group maintains.
+	DesiredCapacity int64
+
+	// Subnets are the IDs of the subnets the group launches instances in.
+	Subnets []string
+
+	// Tags are the tags of the group, propagated to the instances it
+	// launches. They are only set when the group is created.
+	Tags map[string]string
+}
+
+// Instance is an instance of an Auto Scaling group.
+type Instance struct {
+	// ID is the ID of the instance.
+	ID string
+
+	// LifecycleState is the state of the instance in the group, e.g.
+	// "InService" or "Terminating:Wait".
+	LifecycleState string
+
+	// ProtectedFromScaleIn is true if the group does not select the
+	// instance for termination when scaling in.
+	ProtectedFromScaleIn bool
+}
+
+// ScheduledAction resizes a group on a recurring schedule. The sizes left
+// nil are not changed.
+type ScheduledAction struct {
+	// Name is the name of the action, unique within the group.
+	Name string
+
+	// Recurrence is the cron expression of the schedule of the action.
+	Recurrence string
+
+	// TimeZone is the IANA time zone of the recurrence, UTC if empty.
+	TimeZone string
+
+	// MinSize is the minimum number of instances of the group.
+	MinSize *int64
+
+	// MaxSize is the maximum number of instances of the group.
+	MaxSize *int64
+
+	// DesiredCapacity is the number of instances the group maintains.
+	DesiredCapacity *int64
+}
+
+// LifecycleAction is the pending action of an instance of a group, notified
+// by a lifecycle hook of the group. The transition of the instance waits for
+// the action to be completed, or for the heartbeat timeout of the hook.
+type LifecycleAction struct {
+	// Group is the name of the group.
+	Group string `json:"AutoScalingGroupName"`
+
+	// Hook is the name of the lifecycle hook.
+	Hook string `json:"LifecycleHookName"`
+
+	// Token identifies the action.
+	Token string `json:"LifecycleActionToken"`
+
+	// InstanceID is the ID of the instance.
+	InstanceID string `json:"EC2InstanceId"`
+
+	// Transition is the transition of the instance, e.g.
+	// LifecycleTransitionTerminating.
+	Transition string `json:"LifecycleTransition"`
+}
+
+// ParseLifecycleAction parses the notification of a lifecycle hook. It
+// returns nil for the other notifications of groups, such as the test
+// notifications sent when a hook is created.
+func ParseLifecycleAction(notification string) (*LifecycleAction, error) {
+	action := &LifecycleAction{}
+	if err := json.Unmarshal([]byte(notification), action); err != nil {
+		return nil, errors.Wrap(err, "failed to parse lifecycle hook notification")
+	}
+
+	if action.Transition == "" {
+		return nil, nil
+	}
+
+	return action, nil
+}
+
+// Groups describes and coordinates Auto Scaling groups.
+type Groups interface {
+	// DescribeGroups returns the groups with the given names. Groups which
+	// do not exist are left out.
+	DescribeGroups(names []string) ([]*Group, error)
+
+	// SetInstanceProtection protects instances of a group from being
+	// selected for termination when the group scales in, or lifts the
+	// protection.
+	SetInstanceProtection(group string, instanceIDs []string, protected bool) error
+
+	// RecordLifecycleActionHeartbeat restarts the heartbeat timeout of a
+	// lifecycle action, to keep the transition of its instance waiting.
+	RecordLifecycleActionHeartbeat(action *LifecycleAction) error
+
+	// CompleteLifecycleAction completes a lifecycle action with the given
+	// result, e.g. LifecycleActionContinue.
+	CompleteLifecycleAction(action *LifecycleAction, result string) error
+
+	// DescribeScheduledActions returns the scheduled actions of a group.
+	DescribeScheduledActions(group string) ([]*ScheduledAction, error)
+
+	// PutScheduledAction creates or updates a scheduled action of a group.
+	PutScheduledAction(group string, action *ScheduledAction) error
+
+	// DeleteScheduledAction deletes a scheduled action of a group.
+	DeleteScheduledAction(group, name string) error
+
+	// CreateGroup creates a group.
+	CreateGroup(spec *GroupSpec) error
+
+	// UpdateGroup updates the launch template, sizes and subnets of a group.
+	UpdateGroup(spec *GroupSpec) error
+
+	// DeleteGroup deletes a group along with its instances. The group is
+	// deleted asynchronously, and is described until then.
+	DeleteGroup(name string) error
+}
+
+// Service coordinates the Auto Scaling groups of a region.
+//
+// The SDK does not vendor the Auto Scaling client, so requests are sent with
+// a generic SDK client speaking the query protocol. Requests still go through
+// the handlers of the session, for signing, retries and rate limiting.
+type Service struct {
+	client *client.Client
+}
+
+// NewService returns a service coordinating the Auto Scaling groups of the
+// region of the session.
+func NewService(sess *session.Session) *Service {
+	return &Service{client: queryprotocol.NewClient(sess, serviceName, apiVersion)}
+}
+
+func (s *Service) send(operation string, in, out interface{}) error {
+	return queryprotocol.Send(s.client, operation, in, out)
+}
+
+type describeAutoScalingGroupsInput struct {
+	_ struct{} `type:"structure"`
+
+	AutoScalingGroupNames []*string `type:"list"`
+	NextToken             *string   `type:"string"`
+}
+
+type describeAutoScalingGroupsOutput struct {
+	_ struct{} `type:"structure"`
+
+	AutoScalingGroups []*autoScalingGroup `type:"list"`
+	NextToken         *string             `type:"string"`
+}
+
+type autoScalingGroup struct {
+	_ struct{} `type:"structure"`
+
+	AutoScalingGroupName *string                      `type:"string"`
+	MinSize              *int64                       `type:"integer"`
+	MaxSize              *int64                       `type:"integer"`
+	DesiredCapacity      *int64                       `type:"integer"`
+	Instances            []*groupInstance             `type:"list"`
+	LaunchTemplate       *launchTemplateSpecification `type:"structure"`
+	VPCZoneIdentifier    *string                      `type:"string"`
+	Status               *string                      `type:"string"`
+}
+
+type launchTemplateSpecification struct {
+	_ struct{} `type:"structure"`
+
+	LaunchTemplateId *string `type:"string"`
+	Version          *string `type:"string"`
+}
+
+type groupTag struct {
+	_ struct{} `type:"structure"`
+
+	Key               *string `type:"string"`
+	PropagateAtLaunch *bool   `type:"boolean"`
+	ResourceId        *string `type:"string"`
+	ResourceType      *string `type:"string"`
+	Value             *string `type:"string"`
+}
+
+type groupInstance struct {
+	_ struct{} `type:"structure"`
+
+	InstanceId           *string `type:"string"`
+	LifecycleState       *string `type:"string"`
+	ProtectedFromScaleIn *bool   `type:"boolean"`
+}
+
+type setInstanceProtectionInput struct {
+	_ struct{} `type:"structure"`
+
+	AutoScalingGroupName *string   `type:"string"`
+	InstanceIds          []*string `type:"list"`
+	ProtectedFromScaleIn *bool     `type:"boolean"`
+}
+
+type setInstanceProtectionOutput struct {
+	_ struct{} `type:"structure"`
+}
+
+type recordLifecycleActionHeartbeatInput struct {
+	_ struct{} `type:"structure"`
+
+	AutoScalingGroupName *string `type:"string"`
+	InstanceId           *string `type:"string"`
+	LifecycleActionToken *string `type:"string"`
+	LifecycleHookName    *string `type:"string"`
+}
+
+type recordLifecycleActionHeartbeatOutput struct {
+	_ struct{} `type:"structure"`
+}
+
+type completeLifecycleActionInput struct {
+	_ struct{} `type:"structure"`
+
+	AutoScalingGroupName  *string `type:"string"`
+	InstanceId            *string `type:"string"`
+	LifecycleActionResult *string `type:"string"`
+	LifecycleActionToken  *string `type:"string"`
+	LifecycleHookName     *string `type:"string"`
+}
+
+type completeLifecycleActionOutput struct {
+	_ struct{} `type:"structure"`
+}
+
+type describeScheduledActionsInput struct {
+	_ struct{} `type:"structure"`
+
+	AutoScalingGroupName *string `type:"string"`
+	NextToken            *string `type:"string"`
+}
+
+type describeScheduledActionsOutput struct {
+	_ struct{} `type:"structure"`
+
+	NextToken                   *string                       `type:"string"`
+	ScheduledUpdateGroupActions []*scheduledUpdateGroupAction `type:"list"`
+}
+
+type scheduledUpdateGroupAction struct {
+	_ struct{} `type:"structure"`
+
+	DesiredCapacity     *int64  `type:"integer"`
+	MaxSize             *int64  `type:"integer"`
+	MinSize             *int64  `type:"integer"`
+	Recurrence          *string `type:"string"`
+	ScheduledActionName *string `type:"string"`
+	TimeZone            *string `type:"string"`
+}
+
+type putScheduledUpdateGroupActionInput struct {
+	_ struct{} `type:"structure"`
+
+	AutoScalingGroupName *string `type:"string"`
+	DesiredCapacity      *int64  `type:"integer"`
+	MaxSize              *int64  `type:"integer"`
+	MinSize              *int64  `type:"integer"`
+	Recurrence           *string `type:"string"`
+	ScheduledActionName  *string `type:"string"`
+	TimeZone             *string `type:"string"`
+}
+
+type putScheduledUpdateGroupActionOutput struct {
+	_ struct{} `type:"structure"`
+}
+
+type deleteScheduledActionInput struct {
+	_ struct{} `type:"structure"`
+
+	AutoScalingGroupName *string `type:"string"`
+	ScheduledActionName  *string `type:"string"`
+}
+
+type deleteScheduledActionOutput struct {
+	_ struct{} `type:"structure"`
+}
+
+type createAutoScalingGroupInput struct {
+	_ struct{} `type:"structure"`
+
+	AutoScalingGroupName *string                      `type:"string"`
+	DesiredCapacity      *int64                       `type:"integer"`
+	LaunchTemplate       *launchTemplateSpecification `type:"structure"`
+	MaxSize              *int64                       `type:"integer"`
+	MinSize              *int64                       `type:"integer"`
+	Tags                 []*groupTag                  `type:"list"`
+	VPCZoneIdentifier    *string                      `type:"string"`
+}
+
+type createAutoScalingGroupOutput struct {
+	_ struct{} `type:"structure"`
+}
+
+type updateAutoScalingGroupInput struct {
+	_ struct{} `type:"structure"`
+
+	AutoScalingGroupName *string                      `type:"string"`
+	DesiredCapacity      *int64                       `type:"integer"`
+	LaunchTemplate       *launchTemplateSpecification `type:"structure"`
+	MaxSize              *int64                       `type:"integer"`
+	MinSize              *int64                       `type:"integer"`
+	VPCZoneIdentifier    *string                      `type:"string"`
+}
+
+type updateAutoScalingGroupOutput struct {
+	_ struct{} `type:"structure"`
+}
+
+type deleteAutoScalingGroupInput struct {
+	_ struct{} `type:"structure"`
+
+	AutoScalingGroupName *string `type:"string"`
+	ForceDelete          *bool   `type:"boolean"`
+}
+
+type deleteAutoScalingGroupOutput struct {
+	_ struct{} `type:"structure"`
+}
+
+// DescribeGroups implements Groups.
+func (s *Service) DescribeGroups(names []string) ([]*Group, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	input := &describeAutoScalingGroupsInput{AutoScalingGroupNames: aws.StringSlice(names)}
+
+	var groups []*Group
+	for {
+		out := &describeAutoScalingGroupsOutput{}
+		if err := s.send("DescribeAutoScalingGroups", input, out); err != nil {
+			return nil, errors.Wrapf(err, "failed to describe Auto Scaling groups %v", names)
+		}
+
+		for _, g := range out.AutoScalingGroups {
+			group := &Group{
+				Name:            aws.StringValue(g.AutoScalingGroupName),
+				MinSize:         aws.Int64Value(g.MinSize),
+				MaxSize:         aws.Int64Value(g.MaxSize),
+				DesiredCapacity: aws.Int64Value(g.DesiredCapacity),
+				Status:          aws.StringValue(g.Status),
+			}
+			if lt := g.LaunchTemplate; lt != nil {
+				group.LaunchTemplateID = aws.StringValue(lt.LaunchTemplateId)
+				group.LaunchTemplateVersion = aws.StringValue(lt.Version)
+			}
+			if zones := aws.StringValue(g.VPCZoneIdentifier); zones != "" {
+				group.Subnets = strings.Split(zones, ",")
+			}
+			for _, i := range g.Instances {
+				group.Instances = append(group.Instances, Instance{
+					ID:                   aws.StringValue(i.InstanceId),
+					LifecycleState:       aws.StringValue(i.LifecycleState),
+					ProtectedFromScaleIn: aws.BoolValue(i.ProtectedFromScaleIn),
+				})
+			}
+			groups = append(groups, group)
+		}
+
+		if aws.StringValue(out.NextToken) == "" {
+			return groups, nil
+		}
+		input.NextToken = out.NextToken
+	}
+}
+
+// SetInstanceProtection implements Groups.
+func (s *Service) SetInstanceProtection(group string, instanceIDs []string, protected bool) error {
+	input := &setInstanceProtectionInput{
+		AutoScalingGroupName: aws.String(group),
+		InstanceIds:          aws.StringSlice(instanceIDs),
+		ProtectedFromScaleIn: aws.Bool(protected),
+	}
+
+	if err := s.send("SetInstanceProtection", input, &setInstanceProtectionOutput{}); err != nil {
+		return errors.Wrapf(err, "failed to set the scale-in protection of instances %v of Auto Scaling group %q to %t", instanceIDs, group, protected)
+	}
+
+	return nil
+}
+
+// RecordLifecycleActionHeartbeat implements Groups.
+func (s *Service) RecordLifecycleActionHeartbeat(action *LifecycleAction) error {
+	input := &recordLifecycleActionHeartbeatInput{
+		AutoScalingGroupName: aws.String(action.Group),
+		InstanceId:           aws.String(action.InstanceID),
+		LifecycleActionToken: aws.String(action.Token),
+		LifecycleHookName:    aws.String(action.Hook),
+	}
+
+	if err := s.send("RecordLifecycleActionHeartbeat", input, &recordLifecycleActionHeartbeatOutput{}); err != nil {
+		return errors.Wrapf(err, "failed to record the heartbeat of lifecycle hook %q for instance %q", action.Hook, action.InstanceID)
+	}
+
+	return nil
+}
+
+// CompleteLifecycleAction implements Groups.
+func (s *Service) CompleteLifecycleAction(action *LifecycleAction, result string) error {
+	input := &completeLifecycleActionInput{
+		AutoScalingGroupName:  aws.String(action.Group),
+		InstanceId:            aws.String(action.InstanceID),
+		LifecycleActionResult: aws.String(result),
+		LifecycleActionToken:  aws.String(action.Token),
+		LifecycleHookName:     aws.String(action.Hook),
+	}
+
+	if err := s.send("CompleteLifecycleAction", input, &completeLifecycleActionOutput{}); err != nil {
+		return errors.Wrapf(err, "failed to complete lifecycle hook %q for instance %q", action.Hook, action.InstanceID)
+	}
+
+	return nil
+}
+
+// DescribeScheduledActions implements Groups.
+func (s *Service) DescribeScheduledActions(group string) ([]*ScheduledAction, error) {
+	input := &describeScheduledActionsInput{AutoScalingGroupName: aws.String(group)}
+
+	var actions []*ScheduledAction
+	for {
+		out := &describeScheduledActionsOutput{}
+		if err := s.send("DescribeScheduledActions", input, out); err != nil {
+			return nil, errors.Wrapf(err, "failed to describe the scheduled actions of Auto Scaling group %q", group)
+		}
+
+		for _, a := range out.ScheduledUpdateGroupActions {
+			actions = append(actions, &ScheduledAction{
+				Name:            aws.StringValue(a.ScheduledActionName),
+				Recurrence:      aws.StringValue(a.Recurrence),
+				TimeZone:        aws.StringValue(a.TimeZone),
+				MinSize:         a.MinSize,
+				MaxSize:         a.MaxSize,
+				DesiredCapacity: a.DesiredCapacity,
+			})
+		}
+
+		if aws.StringValue(out.NextToken) == "" {
+			return actions, nil
+		}
+		input.NextToken = out.NextToken
+	}
+}
+
+// PutScheduledAction implements Groups.
+func (s *Service) PutScheduledAction(group string, action *ScheduledAction) error {
+	input := &putScheduledUpdateGroupActionInput{
+		AutoScalingGroupName: aws.String(group),
+		DesiredCapacity:      action.DesiredCapacity,
+		MaxSize:              action.MaxSize,
+		MinSize:              action.MinSize,
+		Recurrence:           aws.String(action.Recurrence),
+		ScheduledActionName:  aws.String(action.Name),
+	}
+	if action.TimeZone != "" {
+		input.TimeZone = aws.String(action.TimeZone)
+	}
+
+	if err := s.send("PutScheduledUpdateGroupAction", input, &putScheduledUpdateGroupActionOutput{}); err != nil {
+		return errors.Wrapf(err, "failed to put scheduled action %q of Auto Scaling group %q", action.Name, group)
+	}
+
+	return nil
+}
+
+// DeleteScheduledAction implements Groups.
+func (s *Service) DeleteScheduledAction(group, name string) error {
+	input := &deleteScheduledActionInput{
+		AutoScalingGroupName: aws.String(group),
+		ScheduledActionName:  aws.String(name),
+	}
+
+	if err := s.send("DeleteScheduledAction", input, &deleteScheduledActionOutput{}); err != nil {
+		return errors.Wrapf(err, "failed to delete scheduled action %q of Auto Scaling group %q", name, group)
+	}
+
+	return nil
+}
+
+// CreateGroup implements Groups.
+func (s *Service) CreateGroup(spec *GroupSpec) error {
+	input := &createAutoScalingGroupInput{
+		AutoScalingGroupName: aws.String(spec.Name),
+		DesiredCapacity:      aws.Int64(spec.DesiredCapacity),
+		LaunchTemplate: &launchTemplateSpecification{
+			LaunchTemplateId: aws.String(spec.LaunchTemplateID),
+			Version:          aws.String(spec.LaunchTemplateVersion),
+		},
+		MaxSize:           aws.Int64(spec.MaxSize),
+		MinSize:           aws.Int64(spec.MinSize),
+		VPCZoneIdentifier: aws.String(strings.Join(spec.Subnets, ",")),
+	}
+
+	keys := make([]string, 0, len(spec.Tags))
+	for k := range spec.Tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		input.Tags = append(input.Tags, &groupTag{
+			Key:               aws.String(k),
+			PropagateAtLaunch: aws.Bool(true),
+			ResourceId:        aws.String(spec.Name),
+			ResourceType:      aws.String("auto-scaling-group"),
+			Value:             aws.String(spec.Tags[k]),
+		})
+	}
+
+	if err := s.send("CreateAutoScalingGroup", input, &createAutoScalingGroupOutput{}); err != nil {
+		return errors.Wrapf(err, "failed to create Auto Scaling group %q", spec.Name)
+	}
+
+	return nil
+}
+
+// UpdateGroup implements Groups.
+func (s *Service) UpdateGroup(spec *GroupSpec) error {
+	input := &updateAutoScalingGroupInput{
+		AutoScalingGroupName: aws.String(spec.Name),
+		DesiredCapacity:      aws.Int64(spec.DesiredCapacity),
+		LaunchTemplate: &launchTemplateSpecification{
+			LaunchTemplateId: aws.String(spec.LaunchTemplateID),
+			Version:          aws.String(spec.LaunchTemplateVersion),
+		},
+		MaxSize:           aws.Int64(spec.MaxSize),
+		MinSize:           aws.Int64(spec.MinSize),
+		VPCZoneIdentifier: aws.String(strings.Join(spec.Subnets, ",")),
+	}
+
+	if err := s.send("UpdateAutoScalingGroup", input, &updateAutoScalingGroupOutput{}); err != nil {
+		return errors.Wrapf(err, "failed to update Auto Scaling group %q", spec.Name)
+	}
+
+	return nil
+}
+
+// DeleteGroup implements Groups.
+func (s *Service) DeleteGroup(name string) error {
+	input := &deleteAutoScalingGroupInput{
+		AutoScalingGroupName: aws.String(name),
+		ForceDelete:          aws.Bool(true),
+	}
+
+	if err := s.send("DeleteAutoScalingGroup", input, &deleteAutoScalingGroupOutput{}); err != nil {
+		return errors.Wrapf(err, "failed to delete Auto Scaling group %q", name)
+	}
+
+	return nil
+}
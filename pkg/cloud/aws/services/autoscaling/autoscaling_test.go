@@ -0,0 +1,313 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package autoscaling
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/pkg/errors"
+)
+
+// newTestService returns a service sending its requests to the given
+// handler, and a function stopping the test server.
+func newTestService(t *testing.T, handler http.HandlerFunc) (*Service, func()) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256") {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		if err := r.ParseForm(); err != nil || r.Form.Get("Version") != apiVersion {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		handler(w, r)
+	}))
+
+	sess, err := session.NewSession(aws.NewConfig().
+		WithRegion("us-east-1").
+		WithEndpoint(server.URL).
+		WithMaxRetries(0).
+		WithCredentials(credentials.NewStaticCredentials("id", "secret", "")))
+	if err != nil {
+		server.Close()
+		t.Fatalf("failed to create session: %v", err)
+	}
+
+	return NewService(sess), server.Close
+}
+
+func TestDescribeGroups(t *testing.T) {
+	s, closeServer := newTestService(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Form.Get("Action") != "DescribeAutoScalingGroups" || r.Form.Get("AutoScalingGroupNames.member.1") != "pool-a" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		if r.Form.Get("NextToken") == "" {
+			w.Write([]byte(`<DescribeAutoScalingGroupsResponse><DescribeAutoScalingGroupsResult><AutoScalingGroups><member>
+<AutoScalingGroupName>pool-a</AutoScalingGroupName><MinSize>1</MinSize><MaxSize>5</MaxSize><DesiredCapacity>2</DesiredCapacity>
+<Instances>
+<member><InstanceId>i-1</InstanceId><LifecycleState>InService</LifecycleState><ProtectedFromScaleIn>true</ProtectedFromScaleIn></member>
+<member><InstanceId>i-2</InstanceId><LifecycleState>Terminating:Wait</LifecycleState><ProtectedFromScaleIn>false</ProtectedFromScaleIn></member>
+</Instances>
+<LaunchTemplate><LaunchTemplateId>lt-1</LaunchTemplateId><Version>3</Version></LaunchTemplate>
+<VPCZoneIdentifier>subnet-1,subnet-2</VPCZoneIdentifier>
+</member></AutoScalingGroups><NextToken>next</NextToken></DescribeAutoScalingGroupsResult><ResponseMetadata><RequestId>1</RequestId></ResponseMetadata></DescribeAutoScalingGroupsResponse>`))
+			return
+		}
+
+		w.Write([]byte(`<DescribeAutoScalingGroupsResponse><DescribeAutoScalingGroupsResult><AutoScalingGroups><member>
+<AutoScalingGroupName>pool-b</AutoScalingGroupName><MinSize>0</MinSize><MaxSize>1</MaxSize><DesiredCapacity>0</DesiredCapacity>
+</member></AutoScalingGroups></DescribeAutoScalingGroupsResult><ResponseMetadata><RequestId>2</RequestId></ResponseMetadata></DescribeAutoScalingGroupsResponse>`))
+	})
+	defer closeServer()
+
+	groups, err := s.DescribeGroups([]string{"pool-a", "pool-b"})
+	if err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+
+	expected := []*Group{
+		{
+			Name:            "pool-a",
+			MinSize:         1,
+			MaxSize:         5,
+			DesiredCapacity: 2,
+			Instances: []Instance{
+				{ID: "i-1", LifecycleState: LifecycleStateInService, ProtectedFromScaleIn: true},
+				{ID: "i-2", LifecycleState: "Terminating:Wait"},
+			},
+			LaunchTemplateID:      "lt-1",
+			LaunchTemplateVersion: "3",
+			Subnets:               []string{"subnet-1", "subnet-2"},
+		},
+		{Name: "pool-b", MaxSize: 1},
+	}
+	if !reflect.DeepEqual(groups, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, groups)
+	}
+}
+
+func TestSetInstanceProtection(t *testing.T) {
+	var protected []string
+	s, closeServer := newTestService(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Form.Get("Action") != "SetInstanceProtection" || r.Form.Get("AutoScalingGroupName") != "pool-a" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		if r.Form.Get("InstanceIds.member.1") == "i-gone" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`<ErrorResponse><Error><Type>Sender</Type><Code>ValidationError</Code><Message>The instance i-gone is not part of Auto Scaling group pool-a.</Message></Error><RequestId>1</RequestId></ErrorResponse>`))
+			return
+		}
+
+		protected = append(protected, r.Form.Get("InstanceIds.member.1")+"="+r.Form.Get("ProtectedFromScaleIn"))
+		w.Write([]byte(`<SetInstanceProtectionResponse><SetInstanceProtectionResult/><ResponseMetadata><RequestId>1</RequestId></ResponseMetadata></SetInstanceProtectionResponse>`))
+	})
+	defer closeServer()
+
+	if err := s.SetInstanceProtection("pool-a", []string{"i-1"}, true); err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+	if err := s.SetInstanceProtection("pool-a", []string{"i-1"}, false); err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+	if !reflect.DeepEqual(protected, []string{"i-1=true", "i-1=false"}) {
+		t.Fatalf("unexpected protection requests %v", protected)
+	}
+
+	err := s.SetInstanceProtection("pool-a", []string{"i-gone"}, true)
+	if aerr, ok := errors.Cause(err).(awserr.Error); !ok || aerr.Code() != "ValidationError" {
+		t.Fatalf("expected a validation error, got %v", err)
+	}
+}
+
+func TestLifecycleActions(t *testing.T) {
+	var requests []string
+	s, closeServer := newTestService(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Form.Get("AutoScalingGroupName") != "pool-a" || r.Form.Get("LifecycleHookName") != "drain" ||
+			r.Form.Get("LifecycleActionToken") != "token" || r.Form.Get("InstanceId") != "i-1" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		action := r.Form.Get("Action")
+		requests = append(requests, action+r.Form.Get("LifecycleActionResult"))
+		w.Write([]byte("<" + action + "Response><" + action + "Result/><ResponseMetadata><RequestId>1</RequestId></ResponseMetadata></" + action + "Response>"))
+	})
+	defer closeServer()
+
+	action := &LifecycleAction{Group: "pool-a", Hook: "drain", Token: "token", InstanceID: "i-1"}
+	if err := s.RecordLifecycleActionHeartbeat(action); err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+	if err := s.CompleteLifecycleAction(action, LifecycleActionContinue); err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+
+	if expected := []string{"RecordLifecycleActionHeartbeat", "CompleteLifecycleActionCONTINUE"}; !reflect.DeepEqual(requests, expected) {
+		t.Fatalf("expected requests %v, got %v", expected, requests)
+	}
+}
+
+func TestParseLifecycleAction(t *testing.T) {
+	action, err := ParseLifecycleAction(`{"Origin":"AutoScalingGroup","LifecycleHookName":"drain","AccountId":"123456789012",
+"RequestId":"r","LifecycleTransition":"autoscaling:EC2_INSTANCE_TERMINATING","AutoScalingGroupName":"pool-a",
+"Service":"AWS Auto Scaling","Time":"2019-05-01T10:00:00.000Z","EC2InstanceId":"i-1","LifecycleActionToken":"token"}`)
+	if err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+
+	expected := &LifecycleAction{Group: "pool-a", Hook: "drain", Token: "token", InstanceID: "i-1", Transition: LifecycleTransitionTerminating}
+	if !reflect.DeepEqual(action, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, action)
+	}
+
+	action, err = ParseLifecycleAction(`{"AccountId":"123456789012","AutoScalingGroupName":"pool-a","Event":"autoscaling:TEST_NOTIFICATION"}`)
+	if err != nil || action != nil {
+		t.Fatalf("expected test notifications to be ignored, got %+v, %v", action, err)
+	}
+
+	if _, err := ParseLifecycleAction("not json"); err == nil {
+		t.Fatalf("expected an error for an invalid notification")
+	}
+}
+
+func TestScheduledActions(t *testing.T) {
+	var requests []string
+	s, closeServer := newTestService(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Form.Get("AutoScalingGroupName") != "pool-a" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		action := r.Form.Get("Action")
+		switch action {
+		case "DescribeScheduledActions":
+			w.Write([]byte(`<DescribeScheduledActionsResponse><DescribeScheduledActionsResult><ScheduledUpdateGroupActions>
+<member><ScheduledActionName>night</ScheduledActionName><Recurrence>0 20 * * *</Recurrence><DesiredCapacity>0</DesiredCapacity></member>
+<member><ScheduledActionName>day</ScheduledActionName><Recurrence>0 8 * * 1-5</Recurrence><TimeZone>Europe/Paris</TimeZone><MinSize>3</MinSize><MaxSize>10</MaxSize></member>
+</ScheduledUpdateGroupActions></DescribeScheduledActionsResult><ResponseMetadata><RequestId>1</RequestId></ResponseMetadata></DescribeScheduledActionsResponse>`))
+			return
+
+		case "PutScheduledUpdateGroupAction":
+			requests = append(requests, action+" "+r.Form.Get("ScheduledActionName")+" "+r.Form.Get("Recurrence")+" "+r.Form.Get("TimeZone")+" "+
+				r.Form.Get("MinSize")+"/"+r.Form.Get("MaxSize")+"/"+r.Form.Get("DesiredCapacity"))
+
+		case "DeleteScheduledAction":
+			requests = append(requests, action+" "+r.Form.Get("ScheduledActionName"))
+		}
+
+		w.Write([]byte("<" + action + "Response><ResponseMetadata><RequestId>1</RequestId></ResponseMetadata></" + action + "Response>"))
+	})
+	defer closeServer()
+
+	actions, err := s.DescribeScheduledActions("pool-a")
+	if err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+
+	expected := []*ScheduledAction{
+		{Name: "night", Recurrence: "0 20 * * *", DesiredCapacity: aws.Int64(0)},
+		{Name: "day", Recurrence: "0 8 * * 1-5", TimeZone: "Europe/Paris", MinSize: aws.Int64(3), MaxSize: aws.Int64(10)},
+	}
+	if !reflect.DeepEqual(actions, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, actions)
+	}
+
+	if err := s.PutScheduledAction("pool-a", expected[1]); err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+	if err := s.DeleteScheduledAction("pool-a", "night"); err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+
+	if expectedRequests := []string{"PutScheduledUpdateGroupAction day 0 8 * * 1-5 Europe/Paris 3/10/", "DeleteScheduledAction night"}; !reflect.DeepEqual(requests, expectedRequests) {
+		t.Fatalf("expected requests %v, got %v", expectedRequests, requests)
+	}
+}
+
+func TestCreateGroup(t *testing.T) {
+	var form string
+	s, closeServer := newTestService(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Form.Get("Action") != "CreateAutoScalingGroup" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		form = strings.Join([]string{
+			r.Form.Get("AutoScalingGroupName"),
+			r.Form.Get("LaunchTemplate.LaunchTemplateId") + ":" + r.Form.Get("LaunchTemplate.Version"),
+			r.Form.Get("MinSize") + "/" + r.Form.Get("DesiredCapacity") + "/" + r.Form.Get("MaxSize"),
+			r.Form.Get("VPCZoneIdentifier"),
+			r.Form.Get("Tags.member.1.Key") + "=" + r.Form.Get("Tags.member.1.Value") + "," + r.Form.Get("Tags.member.1.PropagateAtLaunch"),
+			r.Form.Get("Tags.member.2.Key") + "=" + r.Form.Get("Tags.member.2.Value"),
+		}, " ")
+		w.Write([]byte(`<CreateAutoScalingGroupResponse><ResponseMetadata><RequestId>1</RequestId></ResponseMetadata></CreateAutoScalingGroupResponse>`))
+	})
+	defer closeServer()
+
+	err := s.CreateGroup(&GroupSpec{
+		Name:                  "pool-a",
+		LaunchTemplateID:      "lt-1",
+		LaunchTemplateVersion: "2",
+		MinSize:               3,
+		MaxSize:               3,
+		DesiredCapacity:       3,
+		Subnets:               []string{"subnet-1", "subnet-2"},
+		Tags:                  map[string]string{"b": "2", "a": "1"},
+	})
+	if err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+
+	if expected := "pool-a lt-1:2 3/3/3 subnet-1,subnet-2 a=1,true b=2"; form != expected {
+		t.Fatalf("expected request %q, got %q", expected, form)
+	}
+}
+
+func TestDeleteGroup(t *testing.T) {
+	var deleted string
+	s, closeServer := newTestService(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Form.Get("Action") != "DeleteAutoScalingGroup" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		deleted = r.Form.Get("AutoScalingGroupName") + " force=" + r.Form.Get("ForceDelete")
+		w.Write([]byte(`<DeleteAutoScalingGroupResponse><ResponseMetadata><RequestId>1</RequestId></ResponseMetadata></DeleteAutoScalingGroupResponse>`))
+	})
+	defer closeServer()
+
+	if err := s.DeleteGroup("pool-a"); err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+	if deleted != "pool-a force=true" {
+		t.Fatalf("unexpected delete request %q", deleted)
+	}
+}
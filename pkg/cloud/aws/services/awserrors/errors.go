@@ -23,10 +23,22 @@ import (
 )
 
 const (
-	AuthFailure        = "AuthFailure"
-	InUseIPAddress     = "InvalidIPAddress.InUse"
-	GroupNotFound      = "InvalidGroup.NotFound"
-	PermissionNotFound = "InvalidPermission.NotFound"
+	AMIIDNotFound                = "InvalidAMIID.NotFound"
+	AllocationIDNotFound         = "InvalidAllocationID.NotFound"
+	AuthFailure                  = "AuthFailure"
+	DependencyViolation          = "DependencyViolation"
+	InUseIPAddress               = "InvalidIPAddress.InUse"
+	GroupNotFound                = "InvalidGroup.NotFound"
+	IncorrectModificationState   = "IncorrectModificationState"
+	PermissionNotFound           = "InvalidPermission.NotFound"
+	SnapshotNotFound             = "InvalidSnapshot.NotFound"
+	InsufficientInstanceCapacity = "InsufficientInstanceCapacity"
+	OptInRequired                = "OptInRequired"
+	ReservationCapacityExceeded  = "ReservationCapacityExceeded"
+	RouteNotFound                = "InvalidRoute.NotFound"
+	Unsupported                  = "Unsupported"
+	ValidationError              = "ValidationError"
+	VolumeModificationRateLimit  = "VolumeModificationRateExceeded"
 )
 
 var _ error = &EC2Error{}
@@ -83,6 +95,24 @@ func NewFailedDependency(err error) error {
 	}
 }
 
+// NewInvalidConfiguration returns a new error which indicates that a resource
+// cannot be created as configured, and that retrying would not help.
+func NewInvalidConfiguration(err error) error {
+	return &EC2Error{
+		err:  err,
+		Code: http.StatusUnprocessableEntity,
+	}
+}
+
+// NewInUse returns a new error which indicates that a resource cannot be
+// deleted while other resources use it.
+func NewInUse(err error) error {
+	return &EC2Error{
+		err:  err,
+		Code: http.StatusLocked,
+	}
+}
+
 // IsFailedDependency checks if the error is pf http.StatusFailedDependency
 func IsFailedDependency(err error) bool {
 	if ReasonForError(err) == http.StatusFailedDependency {
@@ -104,6 +134,16 @@ func IsConflict(err error) bool {
 	return ReasonForError(err) == http.StatusConflict
 }
 
+// IsInvalidConfiguration returns true if the error was created by NewInvalidConfiguration.
+func IsInvalidConfiguration(err error) bool {
+	return ReasonForError(err) == http.StatusUnprocessableEntity
+}
+
+// IsInUse returns true if the error was created by NewInUse.
+func IsInUse(err error) bool {
+	return ReasonForError(err) == http.StatusLocked
+}
+
 // IsSDKError returns true if the error is of type awserr.Error.
 func IsSDKError(err error) (ok bool) {
 	_, ok = err.(awserr.Error)
@@ -121,6 +161,30 @@ func IsInvalidNotFoundError(err error) bool {
 	return false
 }
 
+// IsZoneLaunchError tests for launch errors that are specific to an availability zone.
+func IsZoneLaunchError(err error) bool {
+	if code, ok := Code(err); ok {
+		switch code {
+		case InsufficientInstanceCapacity, Unsupported:
+			return true
+		}
+	}
+	return false
+}
+
+// IsVolumeModificationCooldown tests for errors returned when a volume cannot
+// be modified yet, either because its previous modification is still in
+// progress or because it was modified less than six hours ago.
+func IsVolumeModificationCooldown(err error) bool {
+	if code, ok := Code(err); ok {
+		switch code {
+		case IncorrectModificationState, VolumeModificationRateLimit:
+			return true
+		}
+	}
+	return false
+}
+
 // ReasonForError returns the HTTP status for a particular error.
 func ReasonForError(err error) int {
 	switch t := err.(type) {
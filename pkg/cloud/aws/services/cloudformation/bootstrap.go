@@ -146,45 +146,130 @@ func controllersPolicy(accountID string) *iam.PolicyDocument {
 				Effect:   iam.EffectAllow,
 				Resource: iam.Resources{"*"},
 				Action: iam.Actions{
+					"autoscaling:CompleteLifecycleAction",
+					"autoscaling:CreateAutoScalingGroup",
+					"autoscaling:DeleteAutoScalingGroup",
+					"autoscaling:DeleteScheduledAction",
+					"autoscaling:DescribeAutoScalingGroups",
+					"autoscaling:DescribeScheduledActions",
+					"autoscaling:PutScheduledUpdateGroupAction",
+					"autoscaling:RecordLifecycleActionHeartbeat",
+					"autoscaling:SetInstanceProtection",
+					"autoscaling:UpdateAutoScalingGroup",
+					"cloudwatch:GetMetricStatistics",
+					"ec2:AcceptVpcPeeringConnection",
 					"ec2:AllocateAddress",
+					"ec2:AssociateAddress",
 					"ec2:AssociateRouteTable",
 					"ec2:AttachInternetGateway",
+					"ec2:AuthorizeSecurityGroupEgress",
 					"ec2:AuthorizeSecurityGroupIngress",
 					"ec2:CreateInternetGateway",
+					"ec2:CreateLaunchTemplate",
+					"ec2:CreateLaunchTemplateVersion",
 					"ec2:CreateNatGateway",
+					"ec2:CreatePlacementGroup",
 					"ec2:CreateRoute",
 					"ec2:CreateRouteTable",
 					"ec2:CreateSecurityGroup",
 					"ec2:CreateSubnet",
 					"ec2:CreateTags",
 					"ec2:CreateVpc",
+					"ec2:CreateVpcEndpointServiceConfiguration",
+					"ec2:CreateVpcPeeringConnection",
+					"ec2:DeleteDhcpOptions",
 					"ec2:DeleteInternetGateway",
+					"ec2:DeleteLaunchTemplate",
 					"ec2:DeleteNatGateway",
+					"ec2:DeletePlacementGroup",
+					"ec2:DeleteRoute",
 					"ec2:DeleteRouteTable",
 					"ec2:DeleteSecurityGroup",
+					"ec2:DeleteSnapshot",
 					"ec2:DeleteSubnet",
 					"ec2:DeleteVpc",
+					"ec2:DeleteVpcEndpointServiceConfigurations",
+					"ec2:DeleteVpcPeeringConnection",
+					"ec2:DeregisterImage",
 					"ec2:DescribeAddresses",
 					"ec2:DescribeAvailabilityZones",
+					"ec2:DescribeDhcpOptions",
+					"ec2:DescribeImages",
+					"ec2:DescribeInstanceCreditSpecifications",
+					"ec2:DescribeInstanceTypeOfferings",
+					"ec2:DescribeInstanceTypes",
 					"ec2:DescribeInstances",
 					"ec2:DescribeInternetGateways",
+					"ec2:DescribeLaunchTemplates",
 					"ec2:DescribeNatGateways",
+					"ec2:DescribePlacementGroups",
 					"ec2:DescribeRouteTables",
 					"ec2:DescribeSecurityGroups",
+					"ec2:DescribeSnapshots",
+					"ec2:DescribeSpotPriceHistory",
 					"ec2:DescribeSubnets",
+					"ec2:DescribeVpcEndpointServiceConfigurations",
+					"ec2:DescribeVpcEndpointServicePermissions",
+					"ec2:DescribeVpcPeeringConnections",
+					"ec2:DescribeVolumes",
+					"ec2:DescribeVolumesModifications",
 					"ec2:DescribeVpcs",
 					"ec2:DetachInternetGateway",
+					"ec2:DisassociateAddress",
 					"ec2:DisassociateRouteTable",
+					"ec2:GetConsoleOutput",
+					"ec2:ModifyInstanceAttribute",
+					"ec2:ModifyInstanceCreditSpecification",
 					"ec2:ModifySubnetAttribute",
+					"ec2:ModifyVolume",
+					"ec2:ModifyVpcEndpointServiceConfiguration",
+					"ec2:ModifyVpcEndpointServicePermissions",
+					"ec2:MonitorInstances",
+					"ec2:RebootInstances",
 					"ec2:ReleaseAddress",
+					"ec2:RevokeSecurityGroupEgress",
 					"ec2:RevokeSecurityGroupIngress",
 					"ec2:RunInstances",
 					"ec2:TerminateInstances",
+					"ec2:UnmonitorInstances",
+					"elasticloadbalancing:AddTags",
+					"elasticloadbalancing:ApplySecurityGroupsToLoadBalancer",
+					"elasticloadbalancing:CreateListener",
 					"elasticloadbalancing:CreateLoadBalancer",
 					"elasticloadbalancing:ConfigureHealthCheck",
+					"elasticloadbalancing:CreateTargetGroup",
 					"elasticloadbalancing:DeleteLoadBalancer",
+					"elasticloadbalancing:DeleteTargetGroup",
+					"elasticloadbalancing:DeregisterInstancesFromLoadBalancer",
+					"elasticloadbalancing:DeregisterTargets",
+					"elasticloadbalancing:DescribeInstanceHealth",
+					"elasticloadbalancing:DescribeListeners",
 					"elasticloadbalancing:DescribeLoadBalancers",
+					"elasticloadbalancing:DescribeTags",
+					"elasticloadbalancing:DescribeTargetGroups",
+					"elasticloadbalancing:ModifyLoadBalancerAttributes",
 					"elasticloadbalancing:RegisterInstancesWithLoadBalancer",
+					"elasticloadbalancing:RegisterTargets",
+					"pricing:GetProducts",
+					"secretsmanager:CreateSecret",
+					"secretsmanager:DeleteSecret",
+					"secretsmanager:GetSecretValue",
+					"secretsmanager:PutSecretValue",
+					"sns:Publish",
+					"sqs:DeleteMessage",
+					"sqs:ReceiveMessage",
+					"ssm:AddTagsToResource",
+					"ssm:DeleteParameters",
+					"ssm:DescribeAutomationExecutions",
+					"ssm:GetAutomationExecution",
+					"ssm:GetParameter",
+					"ssm:GetParametersByPath",
+					"ssm:ListCommands",
+					"ssm:PutParameter",
+					"ssm:SendCommand",
+					"ssm:StartAutomationExecution",
+					"ssm:StartSession",
+					"ssm:TerminateSession",
 				},
 			},
 			{
@@ -267,6 +352,19 @@ func cloudProviderControlPlaneAwsPolicy() *iam.PolicyDocument {
 					"kms:DescribeKey",
 				},
 			},
+			{
+				// The SSM agent of control plane instances forwards the
+				// traffic to private API servers.
+				Effect:   iam.EffectAllow,
+				Resource: iam.Resources{"*"},
+				Action: iam.Actions{
+					"ssm:UpdateInstanceInformation",
+					"ssmmessages:CreateControlChannel",
+					"ssmmessages:CreateDataChannel",
+					"ssmmessages:OpenControlChannel",
+					"ssmmessages:OpenDataChannel",
+				},
+			},
 		},
 	}
 }
@@ -291,6 +389,30 @@ func cloudProviderNodeAwsPolicy() *iam.PolicyDocument {
 					"ecr:BatchGetImage",
 				},
 			},
+			{
+				// Instances read the content of the files of their machine
+				// read from Secrets.
+				Effect:   iam.EffectAllow,
+				Resource: iam.Resources{"arn:aws:ssm:*:*:parameter/cluster-api-provider-aws/machine-files/*"},
+				Action: iam.Actions{
+					"ssm:GetParameter",
+				},
+			},
+			{
+				// The SSM agent of the instances runs the scripts moving
+				// them to a new API server load balancer.
+				Effect:   iam.EffectAllow,
+				Resource: iam.Resources{"*"},
+				Action: iam.Actions{
+					"ec2messages:AcknowledgeMessage",
+					"ec2messages:DeleteMessage",
+					"ec2messages:FailMessage",
+					"ec2messages:GetEndpoint",
+					"ec2messages:GetMessages",
+					"ec2messages:SendReply",
+					"ssm:UpdateInstanceInformation",
+				},
+			},
 		},
 	}
 }
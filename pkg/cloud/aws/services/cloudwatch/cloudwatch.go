@@ -0,0 +1,149 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package cloudwatch reads the metrics of instances from CloudWatch.
+package cloudwatch
+
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/queryprotocol"
+)
+
+const (
+	cloudWatchServiceName = "monitoring"
+	cloudWatchAPIVersion  = "2010-08-01"
+
+	ec2Namespace         = "AWS/EC2"
+	cpuUtilizationMetric = "CPUUtilization"
+	instanceIDDimension  = "InstanceId"
+	averageStatistic     = "Average"
+
+	// minimumPeriodSeconds is the period of the detailed monitoring of
+	// instances, which the periods of datapoints are multiples of.
+	minimumPeriodSeconds = 60
+)
+
+// Metrics reads the metrics of instances.
+type Metrics interface {
+	// AverageCPUUtilization returns the average CPU utilization of an
+	// instance over the given window ending now, in percent. It returns
+	// false if CloudWatch has no datapoint for the window, e.g. for instances
+	// launched moments ago.
+	AverageCPUUtilization(instanceID string, window time.Duration) (float64, bool, error)
+}
+
+// Service reads the metrics of the instances of a region.
+//
+// The vendored SDK has no CloudWatch client, so requests are sent with a
+// generic SDK client speaking the query protocol of the service. Requests
+// still go through the handlers of the session, for signing, retries and rate
+// limiting.
+type Service struct {
+	client *client.Client
+}
+
+type dimension struct {
+	_ struct{} `type:"structure"`
+
+	Name  *string `type:"string" required:"true"`
+	Value *string `type:"string" required:"true"`
+}
+
+type getMetricStatisticsInput struct {
+	_ struct{} `type:"structure"`
+
+	Dimensions []*dimension `type:"list"`
+	EndTime    *time.Time   `type:"timestamp" required:"true"`
+	MetricName *string      `type:"string" required:"true"`
+	Namespace  *string      `type:"string" required:"true"`
+	Period     *int64       `type:"integer" required:"true"`
+	StartTime  *time.Time   `type:"timestamp" required:"true"`
+	Statistics []*string    `type:"list"`
+}
+
+type getMetricStatisticsOutput struct {
+	_ struct{} `type:"structure"`
+
+	Datapoints []*datapoint `type:"list"`
+}
+
+type datapoint struct {
+	_ struct{} `type:"structure"`
+
+	Average     *float64 `type:"double"`
+	SampleCount *float64 `type:"double"`
+}
+
+// NewService returns a service reading the metrics of the region of the session.
+func NewService(sess *session.Session) *Service {
+	return &Service{client: queryprotocol.NewClient(sess, cloudWatchServiceName, cloudWatchAPIVersion)}
+}
+
+// AverageCPUUtilization implements Metrics.
+func (s *Service) AverageCPUUtilization(instanceID string, window time.Duration) (float64, bool, error) {
+	end := time.Now().UTC().Truncate(time.Minute)
+	start := end.Add(-window)
+
+	input := &getMetricStatisticsInput{
+		Dimensions: []*dimension{{Name: aws.String(instanceIDDimension), Value: aws.String(instanceID)}},
+		EndTime:    aws.Time(end),
+		MetricName: aws.String(cpuUtilizationMetric),
+		Namespace:  aws.String(ec2Namespace),
+		Period:     aws.Int64(period(window)),
+		StartTime:  aws.Time(start),
+		Statistics: aws.StringSlice([]string{averageStatistic}),
+	}
+
+	out := &getMetricStatisticsOutput{}
+	if err := queryprotocol.Send(s.client, "GetMetricStatistics", input, out); err != nil {
+		return 0, false, errors.Wrapf(err, "failed to get the CPU utilization of instance %q", instanceID)
+	}
+
+	// The datapoints are averaged by their sample counts, as the first and
+	// last periods of the window may be partial.
+	var sum, samples float64
+	for _, d := range out.Datapoints {
+		count := aws.Float64Value(d.SampleCount)
+		sum += aws.Float64Value(d.Average) * count
+		samples += count
+	}
+
+	if samples == 0 {
+		return 0, false, nil
+	}
+
+	return sum / samples, true, nil
+}
+
+// period returns the period of the datapoints requested for a window, in
+// seconds: the window itself, rounded up to a whole number of minutes, so
+// that the window spans at most two datapoints.
+func period(window time.Duration) int64 {
+	seconds := int64(window / time.Second)
+	if seconds < minimumPeriodSeconds {
+		return minimumPeriodSeconds
+	}
+
+	if r := seconds % minimumPeriodSeconds; r != 0 {
+		seconds += minimumPeriodSeconds - r
+	}
+	return seconds
+}
@@ -0,0 +1,104 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudwatch
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+)
+
+func TestAverageCPUUtilization(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256") {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		if err := r.ParseForm(); err != nil || r.Form.Get("Action") != "GetMetricStatistics" || r.Form.Get("Version") != cloudWatchAPIVersion {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		if r.Form.Get("Namespace") != "AWS/EC2" || r.Form.Get("MetricName") != "CPUUtilization" ||
+			r.Form.Get("Dimensions.member.1.Name") != "InstanceId" || r.Form.Get("Statistics.member.1") != "Average" ||
+			r.Form.Get("Period") != "3600" || r.Form.Get("StartTime") == "" || r.Form.Get("EndTime") == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		switch r.Form.Get("Dimensions.member.1.Value") {
+		case "i-busy":
+			w.Write([]byte(`<GetMetricStatisticsResponse><GetMetricStatisticsResult><Label>CPUUtilization</Label><Datapoints>
+<member><Timestamp>2019-01-01T00:00:00Z</Timestamp><Average>80</Average><SampleCount>5</SampleCount><Unit>Percent</Unit></member>
+<member><Timestamp>2019-01-01T01:00:00Z</Timestamp><Average>20</Average><SampleCount>15</SampleCount><Unit>Percent</Unit></member>
+</Datapoints></GetMetricStatisticsResult><ResponseMetadata><RequestId>1</RequestId></ResponseMetadata></GetMetricStatisticsResponse>`))
+		default:
+			w.Write([]byte(`<GetMetricStatisticsResponse><GetMetricStatisticsResult><Label>CPUUtilization</Label><Datapoints/></GetMetricStatisticsResult><ResponseMetadata><RequestId>2</RequestId></ResponseMetadata></GetMetricStatisticsResponse>`))
+		}
+	}))
+	defer server.Close()
+
+	sess, err := session.NewSession(aws.NewConfig().
+		WithRegion("us-east-1").
+		WithEndpoint(server.URL).
+		WithCredentials(credentials.NewStaticCredentials("id", "secret", "")))
+	if err != nil {
+		t.Fatalf("failed to create session: %v", err)
+	}
+
+	s := NewService(sess)
+
+	utilization, ok, err := s.AverageCPUUtilization("i-busy", time.Hour)
+	if err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+	if !ok || utilization != 35 {
+		t.Fatalf("expected an average utilization of 35, got %v, %v", utilization, ok)
+	}
+
+	_, ok, err = s.AverageCPUUtilization("i-new", time.Hour)
+	if err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+	if ok {
+		t.Fatalf("expected no utilization without datapoints")
+	}
+}
+
+func TestPeriod(t *testing.T) {
+	testCases := []struct {
+		window   time.Duration
+		expected int64
+	}{
+		{window: 10 * time.Second, expected: 60},
+		{window: time.Hour, expected: 3600},
+		{window: 90 * time.Second, expected: 120},
+	}
+
+	for _, tc := range testCases {
+		if p := period(tc.window); p != tc.expected {
+			t.Errorf("expected a period of %d for a window of %v, got %d", tc.expected, tc.window, p)
+		}
+	}
+}
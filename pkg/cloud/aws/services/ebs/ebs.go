@@ -0,0 +1,127 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package ebs reads and modifies the attributes of EBS volumes missing from
+// the vendored SDK.
+package ebs
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/queryprotocol"
+)
+
+// Volumes reads and modifies the provisioned throughput of EBS volumes.
+type Volumes interface {
+	// Throughputs returns the throughput provisioned for volumes, in MiB/s,
+	// by volume ID. Volumes without provisioned throughput are left out.
+	Throughputs(volumeIDs []string) (map[string]int64, error)
+
+	// ModifyVolume modifies a volume as the ModifyVolume call of the SDK,
+	// and also provisions the given throughput, in MiB/s, unless zero.
+	ModifyVolume(input *ec2.ModifyVolumeInput, throughput int64) error
+}
+
+// Service reads and modifies the EBS volumes of a region.
+//
+// The vendored SDK predates gp3 volumes and their provisioned throughput,
+// so requests are sent with a generic SDK client speaking the query protocol
+// of EC2. Requests still go through the handlers of the session, for signing,
+// retries and rate limiting.
+type Service struct {
+	client *client.Client
+}
+
+type describeVolumesInput struct {
+	_ struct{} `type:"structure"`
+
+	VolumeIds []*string `locationName:"VolumeId" locationNameList:"VolumeId" type:"list"`
+}
+
+type describeVolumesOutput struct {
+	_ struct{} `type:"structure"`
+
+	Volumes []*volume `locationName:"volumeSet" locationNameList:"item" type:"list"`
+}
+
+type volume struct {
+	_ struct{} `type:"structure"`
+
+	Throughput *int64  `locationName:"throughput" type:"integer"`
+	VolumeId   *string `locationName:"volumeId" type:"string"`
+}
+
+type modifyVolumeInput struct {
+	_ struct{} `type:"structure"`
+
+	Iops       *int64  `type:"integer"`
+	Size       *int64  `type:"integer"`
+	Throughput *int64  `type:"integer"`
+	VolumeId   *string `type:"string" required:"true"`
+	VolumeType *string `type:"string"`
+}
+
+type modifyVolumeOutput struct {
+	_ struct{} `type:"structure"`
+}
+
+// NewService returns a service for the EBS volumes of the region of the session.
+func NewService(sess *session.Session) *Service {
+	return &Service{client: queryprotocol.NewEC2Client(sess)}
+}
+
+func (s *Service) send(operation string, in, out interface{}) error {
+	return queryprotocol.Send(s.client, operation, in, out)
+}
+
+// Throughputs implements Volumes.
+func (s *Service) Throughputs(volumeIDs []string) (map[string]int64, error) {
+	out := &describeVolumesOutput{}
+	if err := s.send("DescribeVolumes", &describeVolumesInput{VolumeIds: aws.StringSlice(volumeIDs)}, out); err != nil {
+		return nil, errors.Wrapf(err, "failed to describe the throughput of volumes %v", volumeIDs)
+	}
+
+	throughputs := map[string]int64{}
+	for _, v := range out.Volumes {
+		if v.Throughput != nil {
+			throughputs[aws.StringValue(v.VolumeId)] = *v.Throughput
+		}
+	}
+
+	return throughputs, nil
+}
+
+// ModifyVolume implements Volumes.
+func (s *Service) ModifyVolume(input *ec2.ModifyVolumeInput, throughput int64) error {
+	in := &modifyVolumeInput{
+		Iops:       input.Iops,
+		Size:       input.Size,
+		VolumeId:   input.VolumeId,
+		VolumeType: input.VolumeType,
+	}
+	if throughput != 0 {
+		in.Throughput = aws.Int64(throughput)
+	}
+
+	if err := s.send("ModifyVolume", in, &modifyVolumeOutput{}); err != nil {
+		return errors.Wrapf(err, "failed to modify volume %q", aws.StringValue(input.VolumeId))
+	}
+
+	return nil
+}
@@ -0,0 +1,104 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ebs
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/queryprotocol"
+)
+
+func TestVolumes(t *testing.T) {
+	var modified map[string]string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256") {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		if err := r.ParseForm(); err != nil || r.Form.Get("Version") != queryprotocol.EC2APIVersion {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		switch r.Form.Get("Action") {
+		case "DescribeVolumes":
+			if r.Form.Get("VolumeId.1") != "vol-gp3" || r.Form.Get("VolumeId.2") != "vol-gp2" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.Write([]byte(`<DescribeVolumesResponse><requestId>1</requestId><volumeSet>
+<item><volumeId>vol-gp3</volumeId><volumeType>gp3</volumeType><iops>3000</iops><throughput>125</throughput></item>
+<item><volumeId>vol-gp2</volumeId><volumeType>gp2</volumeType><iops>100</iops></item>
+</volumeSet></DescribeVolumesResponse>`))
+
+		case "ModifyVolume":
+			modified = map[string]string{}
+			for key := range r.Form {
+				modified[key] = r.Form.Get(key)
+			}
+			w.Write([]byte(`<ModifyVolumeResponse><requestId>1</requestId><volumeModification><volumeId>vol-gp3</volumeId></volumeModification></ModifyVolumeResponse>`))
+
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	sess, err := session.NewSession(aws.NewConfig().
+		WithRegion("us-east-1").
+		WithEndpoint(server.URL).
+		WithMaxRetries(0).
+		WithCredentials(credentials.NewStaticCredentials("id", "secret", "")))
+	if err != nil {
+		t.Fatalf("failed to create session: %v", err)
+	}
+
+	s := NewService(sess)
+
+	throughputs, err := s.Throughputs([]string{"vol-gp3", "vol-gp2"})
+	if err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+	if expected := map[string]int64{"vol-gp3": 125}; !reflect.DeepEqual(throughputs, expected) {
+		t.Fatalf("expected throughputs %v, got %v", expected, throughputs)
+	}
+
+	if err := s.ModifyVolume(&ec2.ModifyVolumeInput{VolumeId: aws.String("vol-gp3"), Iops: aws.Int64(4000)}, 250); err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+
+	expected := map[string]string{
+		"Action":     "ModifyVolume",
+		"Version":    queryprotocol.EC2APIVersion,
+		"VolumeId":   "vol-gp3",
+		"Iops":       "4000",
+		"Throughput": "250",
+	}
+	if !reflect.DeepEqual(modified, expected) {
+		t.Fatalf("expected modification %v, got %v", expected, modified)
+	}
+}
@@ -19,12 +19,19 @@ package ec2
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"k8s.io/klog"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/pkg/errors"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/actuators"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/filter"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/awserrors"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/tags"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/record"
 )
 
 const (
@@ -32,6 +39,9 @@ const (
 	// https://github.com/kubernetes-sigs/cluster-api-provider-aws/issues/487
 	machineAMIOwnerID = "258751437250"
 
+	// marketplaceAMIOwner is the owner alias of AWS Marketplace AMIs.
+	marketplaceAMIOwner = "aws-marketplace"
+
 	// amiNameFormat is defined in the build/ directory of this project.
 	// The pattern is:
 	// 1. the string value `ami-`
@@ -40,48 +50,349 @@ const (
 	// 4. the kubernetes version as defined by the packages produced by kubernetes/release, for example: 1.13.0-00, 1.12.5-01
 	// 5. the timestamp that the AMI was built
 	amiNameFormat = "ami-%s-%s-%s-??-??????????"
+
+	// armAMINameFormat is the name pattern of the arm64 AMIs, which have
+	// the architecture after the version of the baseOS, for example:
+	// ami-ubuntu-18.04-arm64-1.13.0-00-1550000000
+	armAMINameFormat = "ami-%s-%s-arm64-%s-??-??????????"
+
+	// amiNameMaxLength is the maximum length of an AMI name.
+	amiNameMaxLength = 128
 )
 
-func amiName(baseOS, baseOSVersion, kubernetesVersion string) string {
-	return fmt.Sprintf(amiNameFormat, baseOS, baseOSVersion, strings.TrimPrefix(kubernetesVersion, "v"))
+func amiName(baseOS, baseOSVersion, kubernetesVersion, architecture string) string {
+	format := amiNameFormat
+	if architecture == architectureARM64 {
+		format = armAMINameFormat
+	}
+	return fmt.Sprintf(format, baseOS, baseOSVersion, strings.TrimPrefix(kubernetesVersion, "v"))
+}
+
+// machineImage returns the AMI of a machine, from the image source of its
+// provider spec, or else from the AMIs of the cluster per region or the
+// default lookup. An empty ID is returned for machines launched from a
+// launch template without an image source, the template providing the image.
+func (s *Service) machineImage(machine *actuators.MachineScope, launchTemplate bool) (string, error) {
+	config := machine.MachineConfig
+
+	sources := 0
+	for _, set := range []bool{config.AMI.ID != nil, config.ImageLookup != nil, config.ImageSSMParameter != "", len(config.RegionAMIs) > 0} {
+		if set {
+			sources++
+		}
+	}
+	if sources > 1 {
+		return "", errors.Errorf("machine %q must specify only one of an AMI, an image lookup, an image SSM parameter or AMIs per region", machine.Name())
+	}
+
+	switch {
+	case config.AMI.ID != nil:
+		return *config.AMI.ID, nil
+	case len(config.RegionAMIs) > 0:
+		return s.regionAMI(config.RegionAMIs, fmt.Sprintf("machine %q", machine.Name()))
+	case config.ImageSSMParameter != "":
+		return s.ssmAMILookup(config.ImageSSMParameter)
+	case config.ImageLookup != nil:
+		return s.imageLookup(config.ImageLookup, machine.Machine.Spec.Versions.Kubelet, config.InstanceType)
+	case launchTemplate:
+		return "", nil
+	case len(s.scope.ClusterConfig.RegionAMIs) > 0:
+		return s.regionAMI(s.scope.ClusterConfig.RegionAMIs, fmt.Sprintf("cluster %q", s.scope.Name()))
+	default:
+		return s.imageLookup(nil, machine.Machine.Spec.Versions.Kubelet, config.InstanceType)
+	}
 }
 
-// defaultAMILookup returns the default AMI based on region
-func (s *Service) defaultAMILookup(baseOS, baseOSVersion, kubernetesVersion string) (string, error) {
+// regionAMI returns the AMI of the region of the cluster in the given map
+// of AMIs per region, set by the given owner.
+func (s *Service) regionAMI(amis map[string]string, owner string) (string, error) {
+	imageID, ok := amis[s.scope.Region()]
+	if !ok || imageID == "" {
+		return "", errors.Errorf("%s has no AMI for region %q in its regionAMIs", owner, s.scope.Region())
+	}
+
+	klog.V(2).Infof("Using AMI %q of region %q", imageID, s.scope.Region())
+	return imageID, nil
+}
+
+// imageLookup returns the newest available AMI matching the given lookup,
+// whose unset fields default to the AMIs published for this project, built
+// for the architecture of the given instance type.
+func (s *Service) imageLookup(lookup *v1alpha1.ImageLookup, kubernetesVersion, instanceType string) (string, error) {
+	l := v1alpha1.ImageLookup{}
+	if lookup != nil {
+		l = *lookup
+	}
+
+	if l.Owner == "" {
+		l.Owner = machineAMIOwnerID
+		if l.ProductCode != "" {
+			l.Owner = marketplaceAMIOwner
+		}
+	}
+	if l.Architecture == "" {
+		architecture, err := s.instanceTypeArchitecture(instanceType)
+		if err != nil {
+			return "", err
+		}
+		l.Architecture = architecture
+	}
+	if l.BaseOS == "" {
+		l.BaseOS = "ubuntu"
+	}
+	if l.BaseOSVersion == "" {
+		l.BaseOSVersion = "18.04"
+	}
+	if l.KubernetesVersion == "" {
+		l.KubernetesVersion = kubernetesVersion
+	}
+
+	// Marketplace AMIs are not named after this project's AMIs, so they are
+	// only filtered by name when one is given.
+	name := l.Name
+	if name == "" && l.ProductCode == "" {
+		name = amiName(l.BaseOS, l.BaseOSVersion, l.KubernetesVersion, l.Architecture)
+	}
+
+	var filters []*ec2.Filter
+	if name != "" {
+		filters = append(filters, &ec2.Filter{
+			Name:   aws.String("name"),
+			Values: []*string{aws.String(name)},
+		})
+	}
+	filters = append(filters,
+		&ec2.Filter{
+			Name:   aws.String("architecture"),
+			Values: []*string{aws.String(l.Architecture)},
+		},
+		&ec2.Filter{
+			Name:   aws.String("state"),
+			Values: []*string{aws.String("available")},
+		},
+		&ec2.Filter{
+			Name:   aws.String("virtualization-type"),
+			Values: []*string{aws.String("hvm")},
+		},
+	)
+	if l.ProductCode != "" {
+		filters = append(filters, &ec2.Filter{
+			Name:   aws.String("product-code"),
+			Values: []*string{aws.String(l.ProductCode)},
+		})
+	}
+
 	describeImageInput := &ec2.DescribeImagesInput{
+		Owners:  aws.StringSlice([]string{l.Owner}),
+		Filters: filters,
+	}
+
+	out, err := s.scope.EC2.DescribeImages(describeImageInput)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to find ami: %q", name)
+	}
+	if len(out.Images) == 0 {
+		// Only the AMIs described by their Kubernetes version can be built.
+		if s.scope.ClusterConfig.ImageBuild != nil && l.Name == "" && l.ProductCode == "" {
+			return s.builtImage(l)
+		}
+		if l.ProductCode != "" {
+			return "", errors.Errorf("found no %s AMIs owned by %q with the product code %q and the name: %q", l.Architecture, l.Owner, l.ProductCode, name)
+		}
+		return "", errors.Errorf("found no %s AMIs owned by %q with the name: %q", l.Architecture, l.Owner, name)
+	}
+
+	// Creation dates are RFC 3339 timestamps in UTC, which sort lexically.
+	newest := out.Images[0]
+	for _, image := range out.Images[1:] {
+		if aws.StringValue(image.CreationDate) > aws.StringValue(newest.CreationDate) {
+			newest = image
+		}
+	}
+
+	klog.V(2).Infof("Using AMI: %q", aws.StringValue(newest.ImageId))
+	return aws.StringValue(newest.ImageId), nil
+}
+
+// ssmAMILookup returns the AMI whose ID is the value of the given SSM parameter.
+func (s *Service) ssmAMILookup(name string) (string, error) {
+	imageID, err := s.scope.SSM.GetParameter(name)
+	if err != nil {
+		return "", err
+	}
+
+	if !strings.HasPrefix(imageID, "ami-") {
+		return "", errors.Errorf("ssm parameter %q does not hold an AMI ID: %q", name, imageID)
+	}
+
+	klog.V(2).Infof("Using AMI %q from SSM parameter %q", imageID, name)
+	return imageID, nil
+}
+
+// ImageCreationDate returns the time at which the given AMI was created.
+func (s *Service) ImageCreationDate(imageID string) (time.Time, error) {
+	input := &ec2.DescribeImagesInput{
+		ImageIds: aws.StringSlice([]string{imageID}),
+	}
+
+	out, err := s.scope.EC2.DescribeImages(input)
+	if err != nil {
+		if code, _ := awserrors.Code(err); code == awserrors.AMIIDNotFound {
+			return time.Time{}, awserrors.NewInvalidConfiguration(errors.Wrapf(err, "ami %q not found", imageID))
+		}
+		return time.Time{}, errors.Wrapf(err, "failed to describe ami %q", imageID)
+	}
+
+	if len(out.Images) == 0 {
+		return time.Time{}, awserrors.NewNotFound(errors.Errorf("ami %q not found", imageID))
+	}
+
+	created, err := time.Parse(time.RFC3339, aws.StringValue(out.Images[0].CreationDate))
+	if err != nil {
+		return time.Time{}, errors.Wrapf(err, "failed to parse creation date of ami %q", imageID)
+	}
+
+	return created, nil
+}
+
+// encryptedAMILookup returns the encrypted copy of the given AMI owned by the cluster.
+// If no copy exists yet, one is started and a failed dependency error is returned
+// until the copy becomes available.
+func (s *Service) encryptedAMILookup(sourceID string, encryption *v1alpha1.ImageEncryption) (string, error) {
+	input := &ec2.DescribeImagesInput{
+		Owners: aws.StringSlice([]string{"self"}),
 		Filters: []*ec2.Filter{
-			{
-				Name:   aws.String("owner-id"),
-				Values: []*string{aws.String(machineAMIOwnerID)},
-			},
-			{
-				Name:   aws.String("name"),
-				Values: []*string{aws.String(amiName(baseOS, baseOSVersion, kubernetesVersion))},
-			},
-			{
-				Name:   aws.String("architecture"),
-				Values: []*string{aws.String("x86_64")},
-			},
-			{
-				Name:   aws.String("state"),
-				Values: []*string{aws.String("available")},
-			},
-			{
-				Name:   aws.String("virtualization-type"),
-				Values: []*string{aws.String("hvm")},
+			filter.EC2.ClusterOwned(s.scope.Name()),
+			filter.EC2.SourceAMI(sourceID),
+		},
+	}
+
+	out, err := s.scope.EC2.DescribeImages(input)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to describe encrypted copies of ami %q", sourceID)
+	}
+
+	for _, image := range out.Images {
+		switch aws.StringValue(image.State) {
+		case ec2.ImageStateAvailable:
+			klog.V(2).Infof("Using encrypted copy %q of AMI %q", aws.StringValue(image.ImageId), sourceID)
+			return aws.StringValue(image.ImageId), nil
+		case ec2.ImageStatePending:
+			return "", awserrors.NewFailedDependency(
+				errors.Errorf("encrypted copy %q of ami %q is not available yet", aws.StringValue(image.ImageId), sourceID),
+			)
+		}
+	}
+
+	imageID, err := s.copyEncryptedAMI(sourceID, encryption)
+	if err != nil {
+		return "", err
+	}
+
+	return "", awserrors.NewFailedDependency(
+		errors.Errorf("encrypted copy %q of ami %q is not available yet", imageID, sourceID),
+	)
+}
+
+func (s *Service) copyEncryptedAMI(sourceID string, encryption *v1alpha1.ImageEncryption) (string, error) {
+	name := s.scope.ResourceName(fmt.Sprintf("%s-encrypted", sourceID), amiNameMaxLength)
+
+	input := &ec2.CopyImageInput{
+		ClientToken:   aws.String(name),
+		Name:          aws.String(name),
+		Description:   aws.String(fmt.Sprintf("Encrypted copy of %s for cluster %s", sourceID, s.scope.Name())),
+		SourceImageId: aws.String(sourceID),
+		SourceRegion:  aws.String(s.scope.Region()),
+		Encrypted:     aws.Bool(true),
+	}
+
+	if encryption.KMSKeyID != "" {
+		input.KmsKeyId = aws.String(encryption.KMSKeyID)
+	}
+
+	out, err := s.scope.EC2.CopyImage(input)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to copy ami %q", sourceID)
+	}
+
+	imageID := aws.StringValue(out.ImageId)
+
+	applyTagsParams := &tags.ApplyParams{
+		EC2Client: s.scope.EC2,
+		BuildParams: tags.BuildParams{
+			ClusterName: s.scope.Name(),
+			ResourceID:  imageID,
+			Lifecycle:   tags.ResourceLifecycleOwned,
+			Name:        aws.String(name),
+			Additional: tags.Map{
+				tags.NameAWSProviderSourceAMI: sourceID,
 			},
 		},
 	}
 
-	out, err := s.scope.EC2.DescribeImages(describeImageInput)
+	if err := tags.Apply(applyTagsParams); err != nil {
+		return "", err
+	}
+
+	klog.V(2).Infof("Started encrypted copy %q of AMI %q", imageID, sourceID)
+	record.Eventf(s.scope.Cluster, "CopiedImage", "Started encrypted copy %q of image %q", imageID, sourceID)
+	return imageID, nil
+}
+
+// DeleteImages deregisters the images owned by the cluster, such as the
+// encrypted copies of AMIs, and deletes their snapshots along with any
+// other snapshot owned by the cluster.
+func (s *Service) DeleteImages() error {
+	out, err := s.scope.EC2.DescribeImages(&ec2.DescribeImagesInput{
+		Owners:  aws.StringSlice([]string{"self"}),
+		Filters: []*ec2.Filter{filter.EC2.ClusterOwned(s.scope.Name())},
+	})
 	if err != nil {
-		return "", errors.Wrapf(err, "failed to find ami: %q", amiName(baseOS, baseOSVersion, kubernetesVersion))
+		return errors.Wrapf(err, "failed to describe images for cluster %q", s.scope.Name())
 	}
-	if len(out.Images) == 0 {
-		return "", errors.Errorf("found no AMIs with the name: %q", amiName(baseOS, baseOSVersion, kubernetesVersion))
+
+	snapshotIDs := map[string]bool{}
+	for _, image := range out.Images {
+		if _, err := s.scope.EC2.DeregisterImage(&ec2.DeregisterImageInput{ImageId: image.ImageId}); err != nil {
+			return errors.Wrapf(err, "failed to deregister image %q", aws.StringValue(image.ImageId))
+		}
+
+		klog.V(2).Infof("Deregistered image %q", aws.StringValue(image.ImageId))
+		record.Eventf(s.scope.Cluster, "DeletedImage", "Deregistered image %q", aws.StringValue(image.ImageId))
+
+		for _, bdm := range image.BlockDeviceMappings {
+			if bdm.Ebs != nil && bdm.Ebs.SnapshotId != nil {
+				snapshotIDs[*bdm.Ebs.SnapshotId] = true
+			}
+		}
 	}
-	klog.V(2).Infof("Using AMI: %q", aws.StringValue(out.Images[0].ImageId))
-	return aws.StringValue(out.Images[0].ImageId), nil
+
+	// Snapshots created by the copy of an image do not carry its tags.
+	err = s.scope.EC2.DescribeSnapshotsPages(&ec2.DescribeSnapshotsInput{
+		OwnerIds: aws.StringSlice([]string{"self"}),
+		Filters:  []*ec2.Filter{filter.EC2.ClusterOwned(s.scope.Name())},
+	}, func(page *ec2.DescribeSnapshotsOutput, lastPage bool) bool {
+		for _, snapshot := range page.Snapshots {
+			snapshotIDs[aws.StringValue(snapshot.SnapshotId)] = true
+		}
+		return true
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to describe snapshots for cluster %q", s.scope.Name())
+	}
+
+	for id := range snapshotIDs {
+		if _, err := s.scope.EC2.DeleteSnapshot(&ec2.DeleteSnapshotInput{SnapshotId: aws.String(id)}); err != nil {
+			if code, _ := awserrors.Code(err); code == awserrors.SnapshotNotFound {
+				continue
+			}
+			return errors.Wrapf(err, "failed to delete snapshot %q", id)
+		}
+
+		klog.V(2).Infof("Deleted snapshot %q", id)
+	}
+
+	return nil
 }
 
 func (s *Service) defaultBastionAMILookup(region string) string {
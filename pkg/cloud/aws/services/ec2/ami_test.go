@@ -0,0 +1,561 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ec2
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/golang/mock/gomock"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/actuators"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/awserrors"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/ec2/mock_ec2iface"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/elb/mock_elbiface"
+	clusterv1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+)
+
+func TestImageLookup(t *testing.T) {
+	testCases := []struct {
+		name          string
+		lookup        *v1alpha1.ImageLookup
+		instanceType  string
+		expectedInput *ec2.DescribeImagesInput
+	}{
+		{
+			name: "defaults",
+			expectedInput: &ec2.DescribeImagesInput{
+				Owners: aws.StringSlice([]string{machineAMIOwnerID}),
+				Filters: []*ec2.Filter{
+					{Name: aws.String("name"), Values: aws.StringSlice([]string{"ami-ubuntu-18.04-1.13.0-??-??????????"})},
+					{Name: aws.String("architecture"), Values: aws.StringSlice([]string{"x86_64"})},
+					{Name: aws.String("state"), Values: aws.StringSlice([]string{"available"})},
+					{Name: aws.String("virtualization-type"), Values: aws.StringSlice([]string{"hvm"})},
+				},
+			},
+		},
+		{
+			name:         "graviton instance type",
+			instanceType: "m6g.large",
+			expectedInput: &ec2.DescribeImagesInput{
+				Owners: aws.StringSlice([]string{machineAMIOwnerID}),
+				Filters: []*ec2.Filter{
+					{Name: aws.String("name"), Values: aws.StringSlice([]string{"ami-ubuntu-18.04-arm64-1.13.0-??-??????????"})},
+					{Name: aws.String("architecture"), Values: aws.StringSlice([]string{"arm64"})},
+					{Name: aws.String("state"), Values: aws.StringSlice([]string{"available"})},
+					{Name: aws.String("virtualization-type"), Values: aws.StringSlice([]string{"hvm"})},
+				},
+			},
+		},
+		{
+			name: "base os and kubernetes version",
+			lookup: &v1alpha1.ImageLookup{
+				BaseOS:            "centos",
+				BaseOSVersion:     "7",
+				KubernetesVersion: "v1.12.5",
+			},
+			expectedInput: &ec2.DescribeImagesInput{
+				Owners: aws.StringSlice([]string{machineAMIOwnerID}),
+				Filters: []*ec2.Filter{
+					{Name: aws.String("name"), Values: aws.StringSlice([]string{"ami-centos-7-1.12.5-??-??????????"})},
+					{Name: aws.String("architecture"), Values: aws.StringSlice([]string{"x86_64"})},
+					{Name: aws.String("state"), Values: aws.StringSlice([]string{"available"})},
+					{Name: aws.String("virtualization-type"), Values: aws.StringSlice([]string{"hvm"})},
+				},
+			},
+		},
+		{
+			name: "owner and name pattern",
+			lookup: &v1alpha1.ImageLookup{
+				Owner:        "self",
+				Name:         "k8s-node-*",
+				Architecture: "arm64",
+			},
+			instanceType: "m5.large",
+			expectedInput: &ec2.DescribeImagesInput{
+				Owners: aws.StringSlice([]string{"self"}),
+				Filters: []*ec2.Filter{
+					{Name: aws.String("name"), Values: aws.StringSlice([]string{"k8s-node-*"})},
+					{Name: aws.String("architecture"), Values: aws.StringSlice([]string{"arm64"})},
+					{Name: aws.String("state"), Values: aws.StringSlice([]string{"available"})},
+					{Name: aws.String("virtualization-type"), Values: aws.StringSlice([]string{"hvm"})},
+				},
+			},
+		},
+		{
+			name: "marketplace product code",
+			lookup: &v1alpha1.ImageLookup{
+				ProductCode: "aw0evgkw8e5c1q413zgy5pjce",
+			},
+			expectedInput: &ec2.DescribeImagesInput{
+				Owners: aws.StringSlice([]string{"aws-marketplace"}),
+				Filters: []*ec2.Filter{
+					{Name: aws.String("architecture"), Values: aws.StringSlice([]string{"x86_64"})},
+					{Name: aws.String("state"), Values: aws.StringSlice([]string{"available"})},
+					{Name: aws.String("virtualization-type"), Values: aws.StringSlice([]string{"hvm"})},
+					{Name: aws.String("product-code"), Values: aws.StringSlice([]string{"aw0evgkw8e5c1q413zgy5pjce"})},
+				},
+			},
+		},
+		{
+			name: "marketplace product code and name pattern",
+			lookup: &v1alpha1.ImageLookup{
+				Name:        "CentOS Linux 7*",
+				ProductCode: "aw0evgkw8e5c1q413zgy5pjce",
+			},
+			expectedInput: &ec2.DescribeImagesInput{
+				Owners: aws.StringSlice([]string{"aws-marketplace"}),
+				Filters: []*ec2.Filter{
+					{Name: aws.String("name"), Values: aws.StringSlice([]string{"CentOS Linux 7*"})},
+					{Name: aws.String("architecture"), Values: aws.StringSlice([]string{"x86_64"})},
+					{Name: aws.String("state"), Values: aws.StringSlice([]string{"available"})},
+					{Name: aws.String("virtualization-type"), Values: aws.StringSlice([]string{"hvm"})},
+					{Name: aws.String("product-code"), Values: aws.StringSlice([]string{"aw0evgkw8e5c1q413zgy5pjce"})},
+				},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+
+			// The newest of the matching images is picked.
+			ec2Mock := mock_ec2iface.NewMockEC2API(mockCtrl)
+			ec2Mock.EXPECT().
+				DescribeImages(tc.expectedInput).
+				Return(&ec2.DescribeImagesOutput{
+					Images: []*ec2.Image{
+						{ImageId: aws.String("ami-old"), CreationDate: aws.String("2019-01-10T12:00:00.000Z")},
+						{ImageId: aws.String("ami-new"), CreationDate: aws.String("2019-03-02T08:00:00.000Z")},
+						{ImageId: aws.String("ami-older"), CreationDate: aws.String("2018-12-01T12:00:00.000Z")},
+					},
+				}, nil)
+
+			scope, err := actuators.NewScope(actuators.ScopeParams{
+				Cluster: &clusterv1.Cluster{},
+				AWSClients: actuators.AWSClients{
+					EC2: ec2Mock,
+				},
+				InstanceTypes: &fakeInstanceTypes{
+					architectures: map[string][]string{
+						"m5.large":  {"x86_64"},
+						"m6g.large": {"arm64"},
+					},
+				},
+			})
+			if err != nil {
+				t.Fatalf("Failed to create test context: %v", err)
+			}
+
+			imageID, err := NewService(scope).imageLookup(tc.lookup, "v1.13.0", tc.instanceType)
+			if err != nil {
+				t.Fatalf("did not expect error: %v", err)
+			}
+
+			if imageID != "ami-new" {
+				t.Fatalf("expected ami-new but got: %v", imageID)
+			}
+		})
+	}
+}
+
+func TestMachineImage(t *testing.T) {
+	regionAMIs := map[string]string{"us-east-1": "ami-us-east-1", "eu-west-1": "ami-eu-west-1"}
+
+	testCases := []struct {
+		name           string
+		machineConfig  *v1alpha1.AWSMachineProviderSpec
+		clusterConfig  *v1alpha1.AWSClusterProviderSpec
+		launchTemplate bool
+		expectedID     string
+		expectError    bool
+	}{
+		{
+			name:          "machine AMI",
+			machineConfig: &v1alpha1.AWSMachineProviderSpec{AMI: v1alpha1.AWSResourceReference{ID: aws.String("ami-1")}},
+			clusterConfig: &v1alpha1.AWSClusterProviderSpec{Region: "us-east-1", RegionAMIs: regionAMIs},
+			expectedID:    "ami-1",
+		},
+		{
+			name:          "machine AMIs per region",
+			machineConfig: &v1alpha1.AWSMachineProviderSpec{RegionAMIs: regionAMIs},
+			clusterConfig: &v1alpha1.AWSClusterProviderSpec{Region: "eu-west-1"},
+			expectedID:    "ami-eu-west-1",
+		},
+		{
+			name:          "machine AMIs missing the region",
+			machineConfig: &v1alpha1.AWSMachineProviderSpec{RegionAMIs: regionAMIs},
+			clusterConfig: &v1alpha1.AWSClusterProviderSpec{Region: "us-west-2"},
+			expectError:   true,
+		},
+		{
+			name: "several image sources",
+			machineConfig: &v1alpha1.AWSMachineProviderSpec{
+				AMI:        v1alpha1.AWSResourceReference{ID: aws.String("ami-1")},
+				RegionAMIs: regionAMIs,
+			},
+			clusterConfig: &v1alpha1.AWSClusterProviderSpec{Region: "us-east-1"},
+			expectError:   true,
+		},
+		{
+			name:          "cluster AMIs per region",
+			machineConfig: &v1alpha1.AWSMachineProviderSpec{},
+			clusterConfig: &v1alpha1.AWSClusterProviderSpec{Region: "us-east-1", RegionAMIs: regionAMIs},
+			expectedID:    "ami-us-east-1",
+		},
+		{
+			name:          "cluster AMIs missing the region",
+			machineConfig: &v1alpha1.AWSMachineProviderSpec{},
+			clusterConfig: &v1alpha1.AWSClusterProviderSpec{Region: "us-west-2", RegionAMIs: regionAMIs},
+			expectError:   true,
+		},
+		{
+			name:           "launch template",
+			machineConfig:  &v1alpha1.AWSMachineProviderSpec{},
+			clusterConfig:  &v1alpha1.AWSClusterProviderSpec{Region: "us-east-1", RegionAMIs: regionAMIs},
+			launchTemplate: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			scope, err := actuators.NewMachineScope(actuators.MachineScopeParams{
+				Cluster: &clusterv1.Cluster{},
+				Machine: &clusterv1.Machine{},
+			})
+			if err != nil {
+				t.Fatalf("Failed to create test context: %v", err)
+			}
+
+			scope.MachineConfig = tc.machineConfig
+			scope.Scope.ClusterConfig = tc.clusterConfig
+
+			imageID, err := NewService(scope.Scope).machineImage(scope, tc.launchTemplate)
+			if tc.expectError {
+				if err == nil {
+					t.Fatalf("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("did not expect error: %v", err)
+			}
+
+			if imageID != tc.expectedID {
+				t.Fatalf("expected %q but got: %q", tc.expectedID, imageID)
+			}
+		})
+	}
+}
+
+// fakeParameterStore serves SSM parameters from memory.
+type fakeParameterStore map[string]string
+
+func (f fakeParameterStore) GetParameter(name string) (string, error) {
+	value, ok := f[name]
+	if !ok {
+		return "", awserrors.NewNotFound(errors.Errorf("ssm parameter %q not found", name))
+	}
+	return value, nil
+}
+
+func (f fakeParameterStore) PutSecureParameter(name, value string) error {
+	f[name] = value
+	return nil
+}
+
+func (f fakeParameterStore) DeleteParametersByPath(path string) error {
+	for name := range f {
+		if strings.HasPrefix(name, path+"/") {
+			delete(f, name)
+		}
+	}
+	return nil
+}
+
+func TestSSMAMILookup(t *testing.T) {
+	testCases := []struct {
+		name        string
+		parameter   string
+		expectedID  string
+		expectError bool
+	}{
+		{
+			name:       "parameter holding an AMI ID",
+			parameter:  "/aws/service/ami-amazon-linux-latest/amzn2-ami-hvm-x86_64-gp2",
+			expectedID: "ami-0123456789abcdef0",
+		},
+		{
+			name:        "parameter holding the metadata of an AMI",
+			parameter:   "/aws/service/eks/optimized-ami/1.13/amazon-linux-2/recommended",
+			expectError: true,
+		},
+		{
+			name:        "missing parameter",
+			parameter:   "/missing",
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			scope, err := actuators.NewScope(actuators.ScopeParams{
+				Cluster: &clusterv1.Cluster{},
+				SSM: fakeParameterStore{
+					"/aws/service/ami-amazon-linux-latest/amzn2-ami-hvm-x86_64-gp2":  "ami-0123456789abcdef0",
+					"/aws/service/eks/optimized-ami/1.13/amazon-linux-2/recommended": `{"image_id":"ami-0123456789abcdef0"}`,
+				},
+			})
+			if err != nil {
+				t.Fatalf("Failed to create test context: %v", err)
+			}
+
+			imageID, err := NewService(scope).ssmAMILookup(tc.parameter)
+			if tc.expectError {
+				if err == nil {
+					t.Fatalf("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("did not expect error: %v", err)
+			}
+
+			if imageID != tc.expectedID {
+				t.Fatalf("expected %v but got: %v", tc.expectedID, imageID)
+			}
+		})
+	}
+}
+
+func TestEncryptedAMILookup(t *testing.T) {
+	testCases := []struct {
+		name       string
+		encryption *v1alpha1.ImageEncryption
+		expect     func(m *mock_ec2iface.MockEC2APIMockRecorder)
+		check      func(imageID string, err error)
+	}{
+		{
+			name:       "encrypted copy available",
+			encryption: &v1alpha1.ImageEncryption{},
+			expect: func(m *mock_ec2iface.MockEC2APIMockRecorder) {
+				m.DescribeImages(gomock.AssignableToTypeOf(&ec2.DescribeImagesInput{})).
+					Return(&ec2.DescribeImagesOutput{
+						Images: []*ec2.Image{
+							{
+								ImageId: aws.String("ami-encrypted"),
+								State:   aws.String(ec2.ImageStateAvailable),
+							},
+						},
+					}, nil)
+			},
+			check: func(imageID string, err error) {
+				if err != nil {
+					t.Fatalf("did not expect error: %v", err)
+				}
+
+				if imageID != "ami-encrypted" {
+					t.Fatalf("expected ami-encrypted but got: %v", imageID)
+				}
+			},
+		},
+		{
+			name:       "encrypted copy pending",
+			encryption: &v1alpha1.ImageEncryption{},
+			expect: func(m *mock_ec2iface.MockEC2APIMockRecorder) {
+				m.DescribeImages(gomock.AssignableToTypeOf(&ec2.DescribeImagesInput{})).
+					Return(&ec2.DescribeImagesOutput{
+						Images: []*ec2.Image{
+							{
+								ImageId: aws.String("ami-encrypted"),
+								State:   aws.String(ec2.ImageStatePending),
+							},
+						},
+					}, nil)
+			},
+			check: func(imageID string, err error) {
+				if !awserrors.IsFailedDependency(errors.Cause(err)) {
+					t.Fatalf("expected failed dependency error but got: %v", err)
+				}
+			},
+		},
+		{
+			name:       "no encrypted copy, starts one",
+			encryption: &v1alpha1.ImageEncryption{KMSKeyID: "alias/cluster"},
+			expect: func(m *mock_ec2iface.MockEC2APIMockRecorder) {
+				m.DescribeImages(gomock.AssignableToTypeOf(&ec2.DescribeImagesInput{})).
+					Return(&ec2.DescribeImagesOutput{}, nil)
+
+				m.CopyImage(gomock.Eq(&ec2.CopyImageInput{
+					ClientToken:   aws.String("test-cluster-ami-source-encrypted"),
+					Name:          aws.String("test-cluster-ami-source-encrypted"),
+					Description:   aws.String("Encrypted copy of ami-source for cluster test-cluster"),
+					SourceImageId: aws.String("ami-source"),
+					SourceRegion:  aws.String("us-east-1"),
+					Encrypted:     aws.Bool(true),
+					KmsKeyId:      aws.String("alias/cluster"),
+				})).
+					Return(&ec2.CopyImageOutput{ImageId: aws.String("ami-encrypted")}, nil)
+
+				m.CreateTags(gomock.AssignableToTypeOf(&ec2.CreateTagsInput{})).
+					Return(nil, nil)
+			},
+			check: func(imageID string, err error) {
+				if !awserrors.IsFailedDependency(errors.Cause(err)) {
+					t.Fatalf("expected failed dependency error but got: %v", err)
+				}
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+
+			ec2Mock := mock_ec2iface.NewMockEC2API(mockCtrl)
+			elbMock := mock_elbiface.NewMockELBAPI(mockCtrl)
+
+			scope, err := actuators.NewScope(actuators.ScopeParams{
+				Cluster: &clusterv1.Cluster{
+					ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"},
+				},
+				AWSClients: actuators.AWSClients{
+					EC2: ec2Mock,
+					ELB: elbMock,
+				},
+			})
+
+			if err != nil {
+				t.Fatalf("Failed to create test context: %v", err)
+			}
+
+			scope.ClusterConfig = &v1alpha1.AWSClusterProviderSpec{
+				Region:          "us-east-1",
+				ImageEncryption: tc.encryption,
+			}
+
+			tc.expect(ec2Mock.EXPECT())
+
+			s := NewService(scope)
+			imageID, err := s.encryptedAMILookup("ami-source", tc.encryption)
+			tc.check(imageID, err)
+		})
+	}
+}
+
+func TestImageCreationDate(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	ec2Mock := mock_ec2iface.NewMockEC2API(mockCtrl)
+	ec2Mock.EXPECT().
+		DescribeImages(&ec2.DescribeImagesInput{ImageIds: aws.StringSlice([]string{"ami-1"})}).
+		Return(&ec2.DescribeImagesOutput{
+			Images: []*ec2.Image{{ImageId: aws.String("ami-1"), CreationDate: aws.String("2019-01-02T15:04:05.000Z")}},
+		}, nil)
+	ec2Mock.EXPECT().
+		DescribeImages(&ec2.DescribeImagesInput{ImageIds: aws.StringSlice([]string{"ami-missing"})}).
+		Return(nil, awserr.New(awserrors.AMIIDNotFound, "The image id '[ami-missing]' does not exist", nil))
+
+	scope, err := actuators.NewScope(actuators.ScopeParams{
+		Cluster: &clusterv1.Cluster{},
+		AWSClients: actuators.AWSClients{
+			EC2: ec2Mock,
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create test context: %v", err)
+	}
+
+	s := NewService(scope)
+	created, err := s.ImageCreationDate("ami-1")
+	if err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+
+	if created.Year() != 2019 || created.YearDay() != 2 {
+		t.Fatalf("expected the image creation date to be parsed, got %v", created)
+	}
+
+	if _, err := s.ImageCreationDate("ami-missing"); !awserrors.IsInvalidConfiguration(err) {
+		t.Fatalf("expected an invalid configuration error for a missing ami, got %v", err)
+	}
+}
+
+func TestDeleteImages(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	ec2Mock := mock_ec2iface.NewMockEC2API(mockCtrl)
+	ec2Mock.EXPECT().
+		DescribeImages(gomock.AssignableToTypeOf(&ec2.DescribeImagesInput{})).
+		Return(&ec2.DescribeImagesOutput{
+			Images: []*ec2.Image{
+				{
+					ImageId: aws.String("ami-encrypted"),
+					BlockDeviceMappings: []*ec2.BlockDeviceMapping{
+						{DeviceName: aws.String("/dev/sda1"), Ebs: &ec2.EbsBlockDevice{SnapshotId: aws.String("snap-1")}},
+						{DeviceName: aws.String("/dev/sdb"), VirtualName: aws.String("ephemeral0")},
+					},
+				},
+			},
+		}, nil)
+	ec2Mock.EXPECT().
+		DeregisterImage(&ec2.DeregisterImageInput{ImageId: aws.String("ami-encrypted")}).
+		Return(&ec2.DeregisterImageOutput{}, nil)
+	ec2Mock.EXPECT().
+		DescribeSnapshotsPages(gomock.AssignableToTypeOf(&ec2.DescribeSnapshotsInput{}), gomock.Any()).
+		Do(func(_ *ec2.DescribeSnapshotsInput, fn func(*ec2.DescribeSnapshotsOutput, bool) bool) {
+			fn(&ec2.DescribeSnapshotsOutput{
+				Snapshots: []*ec2.Snapshot{
+					{SnapshotId: aws.String("snap-1")},
+					{SnapshotId: aws.String("snap-2")},
+				},
+			}, true)
+		}).
+		Return(nil)
+	ec2Mock.EXPECT().
+		DeleteSnapshot(&ec2.DeleteSnapshotInput{SnapshotId: aws.String("snap-1")}).
+		Return(&ec2.DeleteSnapshotOutput{}, nil)
+	ec2Mock.EXPECT().
+		DeleteSnapshot(&ec2.DeleteSnapshotInput{SnapshotId: aws.String("snap-2")}).
+		Return(&ec2.DeleteSnapshotOutput{}, nil)
+
+	scope, err := actuators.NewScope(actuators.ScopeParams{
+		Cluster: &clusterv1.Cluster{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"},
+		},
+		AWSClients: actuators.AWSClients{
+			EC2: ec2Mock,
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create test context: %v", err)
+	}
+
+	if err := NewService(scope).DeleteImages(); err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+}
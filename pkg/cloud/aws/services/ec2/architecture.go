@@ -0,0 +1,107 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ec2
+
+import (
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/pkg/errors"
+	"k8s.io/klog"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/awserrors"
+)
+
+const (
+	architectureX8664 = "x86_64"
+	architectureARM64 = "arm64"
+)
+
+// instanceTypeArchitecture returns the architecture of the images run by an
+// instance type, as described by EC2: arm64 for the instance types which only
+// support it, such as the Graviton ones, x86_64 otherwise, including when the
+// instance type is unknown.
+func (s *Service) instanceTypeArchitecture(instanceType string) (string, error) {
+	if instanceType == "" {
+		return architectureX8664, nil
+	}
+
+	supported, err := s.scope.InstanceTypes.SupportedArchitectures(instanceType)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to get the architecture of instance type %q", instanceType)
+	}
+
+	for _, a := range supported {
+		if a == architectureX8664 {
+			return architectureX8664, nil
+		}
+	}
+	for _, a := range supported {
+		if a == architectureARM64 {
+			return architectureARM64, nil
+		}
+	}
+	return architectureX8664, nil
+}
+
+// validateImageArchitecture checks that the instance type supports the
+// architecture of the image, so that a mismatch fails with a descriptive
+// error rather than an opaque InvalidParameterValue from RunInstances.
+//
+// The check is skipped when either architecture is unknown, and when the
+// instance type cannot be described, since RunInstances rejects mismatches
+// anyway.
+func (s *Service) validateImageArchitecture(instanceType, imageID string) error {
+	if instanceType == "" || imageID == "" {
+		return nil
+	}
+
+	out, err := s.scope.EC2.DescribeImages(&ec2.DescribeImagesInput{
+		ImageIds: aws.StringSlice([]string{imageID}),
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to describe ami %q", imageID)
+	}
+
+	if len(out.Images) == 0 {
+		return awserrors.NewInvalidConfiguration(errors.Errorf("ami %q not found", imageID))
+	}
+
+	architecture := aws.StringValue(out.Images[0].Architecture)
+	if architecture == "" {
+		return nil
+	}
+
+	supported, err := s.scope.InstanceTypes.SupportedArchitectures(instanceType)
+	if err != nil {
+		klog.Warningf("Skipping architecture validation of ami %q: %v", imageID, err)
+		return nil
+	}
+
+	if len(supported) == 0 {
+		return nil
+	}
+
+	for _, a := range supported {
+		if a == architecture {
+			return nil
+		}
+	}
+
+	return awserrors.NewInvalidConfiguration(errors.Errorf("ami %q has architecture %q, but instance type %q only supports %s",
+		imageID, architecture, instanceType, strings.Join(supported, ", ")))
+}
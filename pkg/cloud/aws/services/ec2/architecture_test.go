@@ -0,0 +1,219 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ec2
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/golang/mock/gomock"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/actuators"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/awserrors"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/ec2/mock_ec2iface"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/instancetypes"
+	clusterv1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+)
+
+// fakeInstanceTypes describes instance types from static tables, and fails
+// to describe the x9.huge instance type.
+type fakeInstanceTypes struct {
+	architectures map[string][]string
+	zones         map[string][]string
+	infos         map[string]*instancetypes.Info
+}
+
+func (f *fakeInstanceTypes) Describe(instanceType string) (*instancetypes.Info, error) {
+	if instanceType == "x9.huge" {
+		return nil, errors.New("access denied")
+	}
+	return f.infos[instanceType], nil
+}
+
+func (f *fakeInstanceTypes) SupportedArchitectures(instanceType string) ([]string, error) {
+	if instanceType == "x9.huge" {
+		return nil, errors.New("access denied")
+	}
+	return f.architectures[instanceType], nil
+}
+
+func (f *fakeInstanceTypes) OfferedInZone(instanceType, zone string) (bool, error) {
+	if instanceType == "x9.huge" {
+		return false, errors.New("access denied")
+	}
+	for _, z := range f.zones[instanceType] {
+		if z == zone {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func TestValidateImageArchitecture(t *testing.T) {
+	testCases := []struct {
+		name         string
+		instanceType string
+		architecture string
+		expectError  bool
+	}{
+		{
+			name:         "matching architecture",
+			instanceType: "m5.large",
+			architecture: "x86_64",
+		},
+		{
+			name:         "arm image on an x86 instance type",
+			instanceType: "m5.large",
+			architecture: "arm64",
+			expectError:  true,
+		},
+		{
+			name:         "x86 image on an arm instance type",
+			instanceType: "a1.large",
+			architecture: "x86_64",
+			expectError:  true,
+		},
+		{
+			name:         "unknown image architecture",
+			instanceType: "a1.large",
+		},
+		{
+			name:         "unknown instance type",
+			instanceType: "z9.large",
+			architecture: "arm64",
+		},
+		{
+			name:         "instance type cannot be described",
+			instanceType: "x9.huge",
+			architecture: "arm64",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+
+			ec2Mock := mock_ec2iface.NewMockEC2API(mockCtrl)
+			ec2Mock.EXPECT().
+				DescribeImages(&ec2.DescribeImagesInput{ImageIds: aws.StringSlice([]string{"ami-1"})}).
+				Return(&ec2.DescribeImagesOutput{
+					Images: []*ec2.Image{{ImageId: aws.String("ami-1"), Architecture: aws.String(tc.architecture)}},
+				}, nil)
+
+			scope, err := actuators.NewScope(actuators.ScopeParams{
+				Cluster: &clusterv1.Cluster{
+					ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"},
+				},
+				AWSClients: actuators.AWSClients{
+					EC2: ec2Mock,
+				},
+				InstanceTypes: &fakeInstanceTypes{
+					architectures: map[string][]string{
+						"m5.large": {"i386", "x86_64"},
+						"a1.large": {"arm64"},
+					},
+				},
+			})
+			if err != nil {
+				t.Fatalf("Failed to create test context: %v", err)
+			}
+
+			err = NewService(scope).validateImageArchitecture(tc.instanceType, "ami-1")
+			if tc.expectError && err == nil {
+				t.Fatalf("expected error")
+			}
+			if !tc.expectError && err != nil {
+				t.Fatalf("did not expect error: %v", err)
+			}
+			if tc.expectError && !awserrors.IsInvalidConfiguration(err) {
+				t.Fatalf("expected an invalid configuration error, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateImageArchitectureNotFound(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	ec2Mock := mock_ec2iface.NewMockEC2API(mockCtrl)
+	ec2Mock.EXPECT().
+		DescribeImages(&ec2.DescribeImagesInput{ImageIds: aws.StringSlice([]string{"ami-1"})}).
+		Return(&ec2.DescribeImagesOutput{}, nil)
+
+	scope, err := actuators.NewScope(actuators.ScopeParams{
+		Cluster: &clusterv1.Cluster{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"},
+		},
+		AWSClients: actuators.AWSClients{
+			EC2: ec2Mock,
+		},
+		InstanceTypes: &fakeInstanceTypes{},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create test context: %v", err)
+	}
+
+	err = NewService(scope).validateImageArchitecture("m5.large", "ami-1")
+	if !awserrors.IsInvalidConfiguration(err) {
+		t.Fatalf("expected an invalid configuration error, got: %v", err)
+	}
+}
+
+func TestInstanceTypeArchitecture(t *testing.T) {
+	scope, err := actuators.NewScope(actuators.ScopeParams{
+		Cluster: &clusterv1.Cluster{},
+		InstanceTypes: &fakeInstanceTypes{
+			architectures: map[string][]string{
+				"t2.micro":   {"i386", "x86_64"},
+				"m5.large":   {"x86_64"},
+				"m6g.large":  {"arm64"},
+				"c7gn.large": {"arm64"},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create test context: %v", err)
+	}
+	s := NewService(scope)
+
+	testCases := map[string]string{
+		"":           "x86_64",
+		"t2.micro":   "x86_64",
+		"m5.large":   "x86_64",
+		"m6g.large":  "arm64",
+		"c7gn.large": "arm64",
+		"x1.unknown": "x86_64",
+	}
+
+	for instanceType, expected := range testCases {
+		architecture, err := s.instanceTypeArchitecture(instanceType)
+		if err != nil {
+			t.Fatalf("did not expect error: %v", err)
+		}
+		if architecture != expected {
+			t.Fatalf("expected architecture %q for %q, got %q", expected, instanceType, architecture)
+		}
+	}
+
+	if _, err := s.instanceTypeArchitecture("x9.huge"); err == nil {
+		t.Fatalf("expected error when the instance type cannot be described")
+	}
+}
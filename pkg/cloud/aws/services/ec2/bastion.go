@@ -18,7 +18,6 @@ package ec2
 
 import (
 	"encoding/base64"
-	"fmt"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/ec2"
@@ -44,8 +43,11 @@ func (s *Service) ReconcileBastion() error {
 	if len(subnets.FilterPrivate()) == 0 {
 		klog.V(2).Info("No private subnets available, skipping bastion host")
 		return nil
+	} else if s.scope.SecurityGroups()[v1alpha1.SecurityGroupBastion] == nil {
+		klog.V(2).Info("No bastion security group available, skipping bastion host")
+		return nil
 	} else if len(subnets.FilterPublic()) == 0 {
-		return errors.New("failed to reconcile bastion host, no public subnets are available")
+		return awserrors.NewInvalidConfiguration(errors.New("failed to reconcile bastion host, no public subnets are available"))
 	}
 
 	spec := s.getDefaultBastion()
@@ -53,7 +55,7 @@ func (s *Service) ReconcileBastion() error {
 	// Describe bastion instance, if any.
 	instance, err := s.describeBastionInstance()
 	if awserrors.IsNotFound(err) {
-		instance, err = s.runInstance("bastion", spec)
+		instance, err = s.runInstance(spec, false)
 		if err != nil {
 			return err
 		}
@@ -98,26 +100,35 @@ func (s *Service) describeBastionInstance() (*v1alpha1.Instance, error) {
 		},
 	}
 
-	out, err := s.scope.EC2.DescribeInstances(input)
+	// TODO: properly handle multiple bastions found rather than just returning
+	// the first non-terminated.
+	var bastion *v1alpha1.Instance
+	err := s.scope.EC2.DescribeInstancesPages(input,
+		func(page *ec2.DescribeInstancesOutput, lastPage bool) bool {
+			for _, res := range page.Reservations {
+				for _, instance := range res.Instances {
+					if aws.StringValue(instance.State.Name) != ec2.InstanceStateNameTerminated {
+						bastion = converters.SDKToInstance(instance)
+						return false
+					}
+				}
+			}
+			return !lastPage
+		})
+
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to describe bastion host")
 	}
 
-	// TODO: properly handle multiple bastions found rather than just returning
-	// the first non-terminated.
-	for _, res := range out.Reservations {
-		for _, instance := range res.Instances {
-			if aws.StringValue(instance.State.Name) != ec2.InstanceStateNameTerminated {
-				return converters.SDKToInstance(instance), nil
-			}
-		}
+	if bastion == nil {
+		return nil, awserrors.NewNotFound(errors.New("bastion host not found"))
 	}
 
-	return nil, awserrors.NewNotFound(errors.New("bastion host not found"))
+	return bastion, nil
 }
 
 func (s *Service) getDefaultBastion() *v1alpha1.Instance {
-	name := fmt.Sprintf("%s-bastion", s.scope.Name())
+	name := s.scope.ResourceName("bastion", 0)
 	userData, _ := userdata.NewBastion(&userdata.BastionInput{})
 
 	keyName := defaultSSHKeyName
@@ -0,0 +1,86 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ec2
+
+import (
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/actuators"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/awserrors"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/ec2/mock_ec2iface"
+	clusterv1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+)
+
+func TestReconcileBastionWithoutPublicSubnets(t *testing.T) {
+	testCases := []struct {
+		name           string
+		securityGroups map[v1alpha1.SecurityGroupRole]*v1alpha1.SecurityGroup
+		invalid        bool
+	}{
+		{
+			name: "no bastion security group",
+		},
+		{
+			name: "bastion security group",
+			securityGroups: map[v1alpha1.SecurityGroupRole]*v1alpha1.SecurityGroup{
+				v1alpha1.SecurityGroupBastion: {ID: "sg-bastion"},
+			},
+			invalid: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+
+			// No EC2 calls are expected, the bastion is never looked up.
+			ec2Mock := mock_ec2iface.NewMockEC2API(mockCtrl)
+
+			scope, err := actuators.NewScope(actuators.ScopeParams{
+				Cluster: &clusterv1.Cluster{
+					ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"},
+				},
+				AWSClients: actuators.AWSClients{
+					EC2: ec2Mock,
+				},
+			})
+			if err != nil {
+				t.Fatalf("Failed to create test context: %v", err)
+			}
+
+			scope.ClusterStatus.Network.Subnets = v1alpha1.Subnets{
+				{ID: "subnet-priv", IsPublic: false},
+			}
+			scope.ClusterStatus.Network.SecurityGroups = tc.securityGroups
+
+			err = NewService(scope).ReconcileBastion()
+			if tc.invalid {
+				if !awserrors.IsInvalidConfiguration(err) {
+					t.Fatalf("expected an invalid configuration error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("did not expect error: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ec2
+
+import (
+	"github.com/pkg/errors"
+	"k8s.io/klog"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/awserrors"
+)
+
+// validateCPUOptions checks that the CPU options of an instance are
+// supported by its instance type, as described by EC2. The instance type
+// check is skipped when it cannot be described, since RunInstances rejects
+// unsupported options anyway.
+func (s *Service) validateCPUOptions(instanceType string, opts *v1alpha1.CPUOptions) error {
+	if opts == nil {
+		return nil
+	}
+
+	if opts.CoreCount < 1 {
+		return awserrors.NewInvalidConfiguration(errors.Errorf("core count must be at least 1, got %d", opts.CoreCount))
+	}
+
+	if opts.ThreadsPerCore != 1 && opts.ThreadsPerCore != 2 {
+		return awserrors.NewInvalidConfiguration(errors.Errorf("threads per core must be 1 or 2, got %d", opts.ThreadsPerCore))
+	}
+
+	// The instance type may come from a launch template.
+	if instanceType == "" {
+		return nil
+	}
+
+	info, err := s.scope.InstanceTypes.Describe(instanceType)
+	if err != nil {
+		klog.Warningf("Skipping cpu options validation of instance type %q: %v", instanceType, err)
+		return nil
+	}
+	if info == nil {
+		return nil
+	}
+
+	// Burstable and bare metal instance types have no valid cpu options.
+	if len(info.ValidCores) == 0 && len(info.ValidThreadsPerCore) == 0 {
+		return awserrors.NewInvalidConfiguration(errors.Errorf("instance type %q does not support cpu options", instanceType))
+	}
+
+	if len(info.ValidCores) > 0 && !containsInt64(info.ValidCores, opts.CoreCount) {
+		return awserrors.NewInvalidConfiguration(errors.Errorf("instance type %q supports core counts %v, got %d", instanceType, info.ValidCores, opts.CoreCount))
+	}
+
+	if len(info.ValidThreadsPerCore) > 0 && !containsInt64(info.ValidThreadsPerCore, opts.ThreadsPerCore) {
+		return awserrors.NewInvalidConfiguration(errors.Errorf("instance type %q supports %v threads per core, got %d", instanceType, info.ValidThreadsPerCore, opts.ThreadsPerCore))
+	}
+
+	return nil
+}
+
+func containsInt64(values []int64, v int64) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,141 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ec2
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/actuators"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/awserrors"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/instancetypes"
+	clusterv1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+)
+
+func TestValidateCPUOptions(t *testing.T) {
+	scope, err := actuators.NewScope(actuators.ScopeParams{
+		Cluster: &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"}},
+		InstanceTypes: &fakeInstanceTypes{
+			infos: map[string]*instancetypes.Info{
+				"m5.large":   {VCPUs: 2, ValidCores: []int64{1}, ValidThreadsPerCore: []int64{1, 2}},
+				"m5.xlarge":  {VCPUs: 4, ValidCores: []int64{2}, ValidThreadsPerCore: []int64{1, 2}},
+				"m5.2xlarge": {VCPUs: 8, ValidCores: []int64{2, 4}, ValidThreadsPerCore: []int64{1, 2}},
+				"c5.9xlarge": {VCPUs: 36, ValidCores: []int64{2, 4, 6, 8, 10, 12, 14, 16, 18}, ValidThreadsPerCore: []int64{1, 2}},
+				"m6g.xlarge": {VCPUs: 4, ValidCores: []int64{1, 2, 3, 4}, ValidThreadsPerCore: []int64{1}},
+				"t3.large":   {VCPUs: 2},
+				"i3.metal":   {VCPUs: 72},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create test context: %v", err)
+	}
+
+	testCases := []struct {
+		name         string
+		instanceType string
+		opts         *v1alpha1.CPUOptions
+		expectError  bool
+	}{
+		{
+			name:         "no cpu options",
+			instanceType: "t3.large",
+		},
+		{
+			name:         "hyperthreading disabled",
+			instanceType: "m5.2xlarge",
+			opts:         &v1alpha1.CPUOptions{CoreCount: 4, ThreadsPerCore: 1},
+		},
+		{
+			name:         "restricted core count",
+			instanceType: "c5.9xlarge",
+			opts:         &v1alpha1.CPUOptions{CoreCount: 8, ThreadsPerCore: 2},
+		},
+		{
+			name: "instance type from a launch template",
+			opts: &v1alpha1.CPUOptions{CoreCount: 64, ThreadsPerCore: 1},
+		},
+		{
+			name:         "unknown instance type",
+			instanceType: "m5.huge",
+			opts:         &v1alpha1.CPUOptions{CoreCount: 64, ThreadsPerCore: 2},
+		},
+		{
+			name:         "instance type not described",
+			instanceType: "x9.huge",
+			opts:         &v1alpha1.CPUOptions{CoreCount: 64, ThreadsPerCore: 2},
+		},
+		{
+			name:         "missing core count",
+			instanceType: "m5.large",
+			opts:         &v1alpha1.CPUOptions{ThreadsPerCore: 1},
+			expectError:  true,
+		},
+		{
+			name:         "too many threads per core",
+			instanceType: "m5.large",
+			opts:         &v1alpha1.CPUOptions{CoreCount: 1, ThreadsPerCore: 4},
+			expectError:  true,
+		},
+		{
+			name:         "too many cores",
+			instanceType: "m5.xlarge",
+			opts:         &v1alpha1.CPUOptions{CoreCount: 4, ThreadsPerCore: 2},
+			expectError:  true,
+		},
+		{
+			name:         "core count not accepted",
+			instanceType: "c5.9xlarge",
+			opts:         &v1alpha1.CPUOptions{CoreCount: 7, ThreadsPerCore: 2},
+			expectError:  true,
+		},
+		{
+			name:         "burstable instance type",
+			instanceType: "t3.large",
+			opts:         &v1alpha1.CPUOptions{CoreCount: 1, ThreadsPerCore: 1},
+			expectError:  true,
+		},
+		{
+			name:         "bare metal instance type",
+			instanceType: "i3.metal",
+			opts:         &v1alpha1.CPUOptions{CoreCount: 36, ThreadsPerCore: 1},
+			expectError:  true,
+		},
+		{
+			name:         "single thread instance type",
+			instanceType: "m6g.xlarge",
+			opts:         &v1alpha1.CPUOptions{CoreCount: 4, ThreadsPerCore: 2},
+			expectError:  true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := NewService(scope).validateCPUOptions(tc.instanceType, tc.opts)
+			if tc.expectError && err == nil {
+				t.Fatalf("expected error")
+			}
+			if !tc.expectError && err != nil {
+				t.Fatalf("did not expect error: %v", err)
+			}
+			if tc.expectError && !awserrors.IsInvalidConfiguration(err) {
+				t.Fatalf("expected an invalid configuration error, got: %v", err)
+			}
+		})
+	}
+}
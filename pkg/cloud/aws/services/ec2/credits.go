@@ -0,0 +1,131 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ec2
+
+import (
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/pkg/errors"
+	"k8s.io/klog"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/awserrors"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/instancetypes"
+)
+
+// validateCreditSpecification checks that the CPU credit option of an
+// instance is known, and only set for burstable instance types, as described
+// by EC2. The instance type check is skipped when it cannot be described.
+func (s *Service) validateCreditSpecification(instanceType string, credits v1alpha1.CPUCredits) error {
+	switch credits {
+	case "":
+		return nil
+	case v1alpha1.CPUCreditsStandard, v1alpha1.CPUCreditsUnlimited:
+	default:
+		return awserrors.NewInvalidConfiguration(errors.Errorf("unknown cpu credit option %q", credits))
+	}
+
+	// The instance type may come from a launch template.
+	if instanceType == "" {
+		return nil
+	}
+
+	burstable, err := isBurstable(s.scope.InstanceTypes, instanceType)
+	if err != nil {
+		klog.Warningf("Skipping cpu credit option validation of instance type %q: %v", instanceType, err)
+		return nil
+	}
+
+	if !burstable {
+		return awserrors.NewInvalidConfiguration(errors.Errorf("cpu credit option %q requires a burstable instance type, got %q", credits, instanceType))
+	}
+
+	return nil
+}
+
+// isBurstable returns true if the CPU usage of an instance type is governed
+// by credits, and false if the instance type is unknown.
+func isBurstable(types instancetypes.Describer, instanceType string) (bool, error) {
+	if instanceType == "" {
+		return false, nil
+	}
+
+	info, err := types.Describe(instanceType)
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to describe instance type %q", instanceType)
+	}
+	return info != nil && info.Burstable, nil
+}
+
+// CreditLimited returns true if an instance of the given type is throttled
+// once its CPU credits are spent, i.e. it is burstable and launched with the
+// standard credit option. The t2 family defaults to the standard option, and
+// the later burstable families to the unlimited one.
+func CreditLimited(types instancetypes.Describer, instanceType string, credits v1alpha1.CPUCredits) (bool, error) {
+	burstable, err := isBurstable(types, instanceType)
+	if err != nil || !burstable {
+		return false, err
+	}
+
+	switch credits {
+	case v1alpha1.CPUCreditsStandard:
+		return true, nil
+	case "":
+		return strings.HasPrefix(instanceType, "t2."), nil
+	default:
+		return false, nil
+	}
+}
+
+// ModifyInstanceCreditSpecification sets the CPU credit option of a
+// burstable instance, if it differs. It returns true if the option was modified.
+func (s *Service) ModifyInstanceCreditSpecification(instanceID string, credits v1alpha1.CPUCredits) (bool, error) {
+	out, err := s.scope.EC2.DescribeInstanceCreditSpecifications(&ec2.DescribeInstanceCreditSpecificationsInput{
+		InstanceIds: aws.StringSlice([]string{instanceID}),
+	})
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to describe credit specification of instance %q", instanceID)
+	}
+
+	for _, spec := range out.InstanceCreditSpecifications {
+		if aws.StringValue(spec.CpuCredits) == string(credits) {
+			return false, nil
+		}
+	}
+
+	modifyOut, err := s.scope.EC2.ModifyInstanceCreditSpecification(&ec2.ModifyInstanceCreditSpecificationInput{
+		InstanceCreditSpecifications: []*ec2.InstanceCreditSpecificationRequest{{
+			InstanceId: aws.String(instanceID),
+			CpuCredits: aws.String(string(credits)),
+		}},
+	})
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to modify credit specification of instance %q", instanceID)
+	}
+
+	// Failures are reported per instance rather than as an error of the request.
+	for _, item := range modifyOut.UnsuccessfulInstanceCreditSpecifications {
+		if item.Error != nil {
+			return false, errors.Errorf("failed to modify credit specification of instance %q: %s: %s",
+				instanceID, aws.StringValue(item.Error.Code), aws.StringValue(item.Error.Message))
+		}
+	}
+
+	klog.V(2).Infof("Set CPU credit option of instance %q to %q", instanceID, credits)
+	return true, nil
+}
@@ -0,0 +1,240 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ec2
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/golang/mock/gomock"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/actuators"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/awserrors"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/ec2/mock_ec2iface"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/instancetypes"
+	clusterv1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+)
+
+// burstableInstanceTypes describes burstable and standard instance types.
+var burstableInstanceTypes = &fakeInstanceTypes{
+	infos: map[string]*instancetypes.Info{
+		"t2.micro":  {Burstable: true},
+		"t2.medium": {Burstable: true},
+		"t3.medium": {Burstable: true},
+		"m5.large":  {},
+	},
+}
+
+func TestValidateCreditSpecification(t *testing.T) {
+	scope, err := actuators.NewScope(actuators.ScopeParams{
+		Cluster:       &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"}},
+		InstanceTypes: burstableInstanceTypes,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create test context: %v", err)
+	}
+
+	testCases := []struct {
+		name         string
+		instanceType string
+		credits      v1alpha1.CPUCredits
+		expectError  bool
+	}{
+		{
+			name:         "no credit specification",
+			instanceType: "m5.large",
+		},
+		{
+			name:         "unlimited burstable instance",
+			instanceType: "t3.medium",
+			credits:      v1alpha1.CPUCreditsUnlimited,
+		},
+		{
+			name:    "instance type from a launch template",
+			credits: v1alpha1.CPUCreditsStandard,
+		},
+		{
+			name:         "unknown credit option",
+			instanceType: "t2.micro",
+			credits:      "infinite",
+			expectError:  true,
+		},
+		{
+			name:         "non-burstable instance",
+			instanceType: "m5.large",
+			credits:      v1alpha1.CPUCreditsUnlimited,
+			expectError:  true,
+		},
+		{
+			name:         "unknown instance type",
+			instanceType: "t9.medium",
+			credits:      v1alpha1.CPUCreditsUnlimited,
+			expectError:  true,
+		},
+		{
+			name:         "instance type not described",
+			instanceType: "x9.huge",
+			credits:      v1alpha1.CPUCreditsUnlimited,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := NewService(scope).validateCreditSpecification(tc.instanceType, tc.credits)
+			if tc.expectError && err == nil {
+				t.Fatalf("expected error")
+			}
+			if !tc.expectError && err != nil {
+				t.Fatalf("did not expect error: %v", err)
+			}
+			if tc.expectError && !awserrors.IsInvalidConfiguration(err) {
+				t.Fatalf("expected an invalid configuration error, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestCreditLimited(t *testing.T) {
+	testCases := []struct {
+		instanceType string
+		credits      v1alpha1.CPUCredits
+		expected     bool
+	}{
+		{instanceType: "t2.medium", expected: true},
+		{instanceType: "t2.medium", credits: v1alpha1.CPUCreditsUnlimited},
+		{instanceType: "t3.medium"},
+		{instanceType: "t3.medium", credits: v1alpha1.CPUCreditsStandard, expected: true},
+		{instanceType: "m5.large", credits: v1alpha1.CPUCreditsStandard},
+	}
+
+	for _, tc := range testCases {
+		limited, err := CreditLimited(burstableInstanceTypes, tc.instanceType, tc.credits)
+		if err != nil {
+			t.Fatalf("did not expect error: %v", err)
+		}
+		if limited != tc.expected {
+			t.Errorf("expected %s with credit option %q to be credit limited: %t, got %t", tc.instanceType, tc.credits, tc.expected, limited)
+		}
+	}
+
+	if _, err := CreditLimited(burstableInstanceTypes, "x9.huge", ""); err == nil {
+		t.Fatalf("expected error when the instance type cannot be described")
+	}
+}
+
+func TestModifyInstanceCreditSpecification(t *testing.T) {
+	describe := &ec2.DescribeInstanceCreditSpecificationsInput{
+		InstanceIds: aws.StringSlice([]string{"i-1"}),
+	}
+
+	testCases := []struct {
+		name         string
+		expect       func(m *mock_ec2iface.MockEC2APIMockRecorder)
+		expectChange bool
+		expectError  bool
+	}{
+		{
+			name: "credit option unchanged",
+			expect: func(m *mock_ec2iface.MockEC2APIMockRecorder) {
+				m.DescribeInstanceCreditSpecifications(describe).
+					Return(&ec2.DescribeInstanceCreditSpecificationsOutput{
+						InstanceCreditSpecifications: []*ec2.InstanceCreditSpecification{{
+							InstanceId: aws.String("i-1"),
+							CpuCredits: aws.String("unlimited"),
+						}},
+					}, nil)
+			},
+		},
+		{
+			name: "credit option modified",
+			expect: func(m *mock_ec2iface.MockEC2APIMockRecorder) {
+				m.DescribeInstanceCreditSpecifications(describe).
+					Return(&ec2.DescribeInstanceCreditSpecificationsOutput{
+						InstanceCreditSpecifications: []*ec2.InstanceCreditSpecification{{
+							InstanceId: aws.String("i-1"),
+							CpuCredits: aws.String("standard"),
+						}},
+					}, nil)
+				m.ModifyInstanceCreditSpecification(&ec2.ModifyInstanceCreditSpecificationInput{
+					InstanceCreditSpecifications: []*ec2.InstanceCreditSpecificationRequest{{
+						InstanceId: aws.String("i-1"),
+						CpuCredits: aws.String("unlimited"),
+					}},
+				}).
+					Return(&ec2.ModifyInstanceCreditSpecificationOutput{}, nil)
+			},
+			expectChange: true,
+		},
+		{
+			name: "credit option rejected",
+			expect: func(m *mock_ec2iface.MockEC2APIMockRecorder) {
+				m.DescribeInstanceCreditSpecifications(describe).
+					Return(&ec2.DescribeInstanceCreditSpecificationsOutput{}, nil)
+				m.ModifyInstanceCreditSpecification(gomock.Any()).
+					Return(&ec2.ModifyInstanceCreditSpecificationOutput{
+						UnsuccessfulInstanceCreditSpecifications: []*ec2.UnsuccessfulInstanceCreditSpecificationItem{{
+							InstanceId: aws.String("i-1"),
+							Error: &ec2.UnsuccessfulInstanceCreditSpecificationItemError{
+								Code:    aws.String("IncorrectInstanceState"),
+								Message: aws.String("The instance is not running"),
+							},
+						}},
+					}, nil)
+			},
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+
+			ec2Mock := mock_ec2iface.NewMockEC2API(mockCtrl)
+			tc.expect(ec2Mock.EXPECT())
+
+			scope, err := actuators.NewScope(actuators.ScopeParams{
+				Cluster: &clusterv1.Cluster{
+					ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"},
+				},
+				AWSClients: actuators.AWSClients{
+					EC2: ec2Mock,
+				},
+			})
+			if err != nil {
+				t.Fatalf("Failed to create test context: %v", err)
+			}
+
+			changed, err := NewService(scope).ModifyInstanceCreditSpecification("i-1", v1alpha1.CPUCreditsUnlimited)
+			if tc.expectError {
+				if err == nil {
+					t.Fatalf("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("did not expect error: %v", err)
+			}
+
+			if changed != tc.expectChange {
+				t.Fatalf("expected modified to be %t, got %t", tc.expectChange, changed)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ec2
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/pkg/errors"
+	"k8s.io/klog"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/filter"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/awserrors"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/record"
+)
+
+// deleteDHCPOptions deletes the DHCP option sets owned by the cluster.
+// Option sets still associated with a VPC, which is not deleted along with
+// the cluster, are left in place.
+func (s *Service) deleteDHCPOptions() error {
+	out, err := s.scope.EC2.DescribeDhcpOptions(&ec2.DescribeDhcpOptionsInput{
+		Filters: []*ec2.Filter{filter.EC2.ClusterOwned(s.scope.Name())},
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to describe DHCP option sets for cluster %q", s.scope.Name())
+	}
+
+	for _, options := range out.DhcpOptions {
+		id := aws.StringValue(options.DhcpOptionsId)
+
+		_, err := s.scope.EC2.DeleteDhcpOptions(&ec2.DeleteDhcpOptionsInput{
+			DhcpOptionsId: options.DhcpOptionsId,
+		})
+		if err != nil {
+			if code, _ := awserrors.Code(err); code == awserrors.DependencyViolation {
+				klog.V(2).Infof("DHCP option set %q is still associated with a VPC, skipping", id)
+				continue
+			}
+			return errors.Wrapf(err, "failed to delete DHCP option set %q", id)
+		}
+
+		klog.V(2).Infof("Deleted DHCP option set %q", id)
+		record.Eventf(s.scope.Cluster, "DeletedDHCPOptions", "Deleted DHCP option set %q", id)
+	}
+
+	return nil
+}
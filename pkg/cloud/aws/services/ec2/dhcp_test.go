@@ -0,0 +1,70 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ec2
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/golang/mock/gomock"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/actuators"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/awserrors"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/ec2/mock_ec2iface"
+	clusterv1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+)
+
+func TestDeleteDHCPOptions(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	ec2Mock := mock_ec2iface.NewMockEC2API(mockCtrl)
+	ec2Mock.EXPECT().
+		DescribeDhcpOptions(gomock.AssignableToTypeOf(&ec2.DescribeDhcpOptionsInput{})).
+		Return(&ec2.DescribeDhcpOptionsOutput{
+			DhcpOptions: []*ec2.DhcpOptions{
+				{DhcpOptionsId: aws.String("dopt-orphaned")},
+				{DhcpOptionsId: aws.String("dopt-associated")},
+			},
+		}, nil)
+	ec2Mock.EXPECT().
+		DeleteDhcpOptions(&ec2.DeleteDhcpOptionsInput{DhcpOptionsId: aws.String("dopt-orphaned")}).
+		Return(&ec2.DeleteDhcpOptionsOutput{}, nil)
+
+	// Option sets still in use are left in place.
+	ec2Mock.EXPECT().
+		DeleteDhcpOptions(&ec2.DeleteDhcpOptionsInput{DhcpOptionsId: aws.String("dopt-associated")}).
+		Return(nil, awserr.New(awserrors.DependencyViolation, "in use", nil))
+
+	scope, err := actuators.NewScope(actuators.ScopeParams{
+		Cluster: &clusterv1.Cluster{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"},
+		},
+		AWSClients: actuators.AWSClients{
+			EC2: ec2Mock,
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create test context: %v", err)
+	}
+
+	if err := NewService(scope).deleteDHCPOptions(); err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+}
@@ -0,0 +1,74 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ec2
+
+import (
+	"net/url"
+
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/pkg/errors"
+	"k8s.io/klog"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/awserrors"
+)
+
+// validateNetworkInterfaceType checks that the primary network interface
+// of an instance can be of the requested type. Elastic fabric adapters
+// require an instance type supporting them, as described by EC2; the check
+// is skipped when the instance type cannot be described.
+func (s *Service) validateNetworkInterfaceType(role, instanceType string, t v1alpha1.NetworkInterfaceType, clusterEFA bool) error {
+	switch t {
+	case "", v1alpha1.NetworkInterfaceTypeInterface:
+		return nil
+	case v1alpha1.NetworkInterfaceTypeEFA:
+	default:
+		return awserrors.NewInvalidConfiguration(errors.Errorf("unknown network interface type %q", t))
+	}
+
+	if role != "node" {
+		return awserrors.NewInvalidConfiguration(errors.Errorf("only nodes can use an elastic fabric adapter, got %s", role))
+	}
+
+	if !clusterEFA {
+		return awserrors.NewInvalidConfiguration(errors.New("the cluster must enable EFA for nodes to use an elastic fabric adapter"))
+	}
+
+	// The instance type may come from a launch template.
+	if instanceType == "" {
+		return nil
+	}
+
+	info, err := s.scope.InstanceTypes.Describe(instanceType)
+	if err != nil {
+		klog.Warningf("Skipping elastic fabric adapter validation of instance type %q: %v", instanceType, err)
+		return nil
+	}
+
+	if info == nil || !info.EFASupported {
+		return awserrors.NewInvalidConfiguration(errors.Errorf("instance type %q does not support elastic fabric adapters", instanceType))
+	}
+
+	return nil
+}
+
+// withEFA makes the primary network interface of the instances launched by
+// a RunInstances request an elastic fabric adapter.
+func withEFA() request.Option {
+	return withQuery(func(query url.Values) {
+		query.Set("NetworkInterface.1.InterfaceType", string(v1alpha1.NetworkInterfaceTypeEFA))
+	})
+}
@@ -0,0 +1,182 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ec2
+
+import (
+	"io/ioutil"
+	"net/url"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/actuators"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/instancetypes"
+	clusterv1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+)
+
+func TestValidateNetworkInterfaceType(t *testing.T) {
+	scope, err := actuators.NewScope(actuators.ScopeParams{
+		Cluster: &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"}},
+		InstanceTypes: &fakeInstanceTypes{
+			infos: map[string]*instancetypes.Info{
+				"c5n.18xlarge": {EFASupported: true},
+				"c5n.9xlarge":  {},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create test context: %v", err)
+	}
+
+	testCases := []struct {
+		name         string
+		role         string
+		instanceType string
+		t            v1alpha1.NetworkInterfaceType
+		clusterEFA   bool
+		expectError  bool
+	}{
+		{
+			name:         "default network interface",
+			role:         "controlplane",
+			instanceType: "m5.large",
+		},
+		{
+			name:         "efa node",
+			role:         "node",
+			instanceType: "c5n.18xlarge",
+			t:            v1alpha1.NetworkInterfaceTypeEFA,
+			clusterEFA:   true,
+		},
+		{
+			name:       "instance type from a launch template",
+			role:       "node",
+			t:          v1alpha1.NetworkInterfaceTypeEFA,
+			clusterEFA: true,
+		},
+		{
+			name:         "unknown type",
+			role:         "node",
+			instanceType: "m5.large",
+			t:            "infiniband",
+			expectError:  true,
+		},
+		{
+			name:         "efa control plane",
+			role:         "controlplane",
+			instanceType: "c5n.18xlarge",
+			t:            v1alpha1.NetworkInterfaceTypeEFA,
+			clusterEFA:   true,
+			expectError:  true,
+		},
+		{
+			name:         "efa not enabled by the cluster",
+			role:         "node",
+			instanceType: "c5n.18xlarge",
+			t:            v1alpha1.NetworkInterfaceTypeEFA,
+			expectError:  true,
+		},
+		{
+			name:         "unsupported instance type",
+			role:         "node",
+			instanceType: "c5n.9xlarge",
+			t:            v1alpha1.NetworkInterfaceTypeEFA,
+			clusterEFA:   true,
+			expectError:  true,
+		},
+		{
+			name:         "unknown instance type",
+			role:         "node",
+			instanceType: "c5n.unknown",
+			t:            v1alpha1.NetworkInterfaceTypeEFA,
+			clusterEFA:   true,
+			expectError:  true,
+		},
+		{
+			name:         "instance type not described",
+			role:         "node",
+			instanceType: "x9.huge",
+			t:            v1alpha1.NetworkInterfaceTypeEFA,
+			clusterEFA:   true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := NewService(scope).validateNetworkInterfaceType(tc.role, tc.instanceType, tc.t, tc.clusterEFA)
+			if tc.expectError && err == nil {
+				t.Fatalf("expected error")
+			}
+			if !tc.expectError && err != nil {
+				t.Fatalf("did not expect error: %v", err)
+			}
+		})
+	}
+}
+
+func TestWithEFA(t *testing.T) {
+	sess, err := session.NewSession(&aws.Config{
+		Region:      aws.String("us-east-1"),
+		Credentials: credentials.NewStaticCredentials("id", "secret", ""),
+	})
+	if err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+
+	input := &ec2.RunInstancesInput{
+		ImageId:          aws.String("ami-1"),
+		SubnetId:         aws.String("subnet-1"),
+		SecurityGroupIds: aws.StringSlice([]string{"sg-1"}),
+		MinCount:         aws.Int64(1),
+		MaxCount:         aws.Int64(1),
+	}
+	primaryNetworkInterface(input)
+
+	req, _ := ec2.New(sess).RunInstancesRequest(input)
+	req.ApplyOptions(withEFA())
+	if err := req.Build(); err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		t.Fatalf("Failed to read request: %v", err)
+	}
+	query, err := url.ParseQuery(string(body))
+	if err != nil {
+		t.Fatalf("Failed to parse request: %v", err)
+	}
+
+	expected := map[string]string{
+		"SubnetId":                               "",
+		"SecurityGroupId.1":                      "",
+		"NetworkInterface.1.DeviceIndex":         "0",
+		"NetworkInterface.1.SubnetId":            "subnet-1",
+		"NetworkInterface.1.SecurityGroupId.1":   "sg-1",
+		"NetworkInterface.1.InterfaceType":       "efa",
+		"NetworkInterface.1.DeleteOnTermination": "true",
+	}
+	for k, v := range expected {
+		if query.Get(k) != v {
+			t.Errorf("expected %s to be %q, got %q", k, v, query.Get(k))
+		}
+	}
+}
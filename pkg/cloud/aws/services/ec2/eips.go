@@ -26,8 +26,11 @@ import (
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/pkg/errors"
 	"k8s.io/klog"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/actuators"
 	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/awserrors"
 	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/wait"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/record"
 )
 
 func (s *Service) getOrAllocateAddress(role string) (string, error) {
@@ -47,6 +50,10 @@ func (s *Service) getOrAllocateAddress(role string) (string, error) {
 }
 
 func (s *Service) allocateAddress(role string) (string, error) {
+	return s.allocateNamedAddress(role, s.scope.ResourceName(fmt.Sprintf("eip-%s", role), 0))
+}
+
+func (s *Service) allocateNamedAddress(role, name string) (string, error) {
 	out, err := s.scope.EC2.AllocateAddress(&ec2.AllocateAddressInput{
 		Domain: aws.String("vpc"),
 	})
@@ -55,8 +62,6 @@ func (s *Service) allocateAddress(role string) (string, error) {
 		return "", errors.Wrap(err, "failed to create Elastic IP address")
 	}
 
-	name := fmt.Sprintf("%s-eip-%s", s.scope.Name(), role)
-
 	applyTagsParams := &tags.ApplyParams{
 		EC2Client: s.scope.EC2,
 		BuildParams: tags.BuildParams{
@@ -127,3 +132,220 @@ func (s *Service) releaseAddresses() error {
 	}
 	return nil
 }
+
+// AssociateMachineAddress associates the Elastic IP of a machine with its
+// instance, allocating the address first if the machine has none yet.
+// Addresses are tagged with the name and role of their machine.
+func (s *Service) AssociateMachineAddress(machine *actuators.MachineScope, instanceID string) error {
+	addresses, err := s.machineAddresses(machine)
+	if err != nil {
+		return err
+	}
+
+	var allocationID string
+	if len(addresses) > 0 {
+		allocationID = aws.StringValue(addresses[0].AllocationId)
+		machine.MachineStatus.ElasticIPAllocationID = allocationID
+		if aws.StringValue(addresses[0].InstanceId) == instanceID {
+			return nil
+		}
+	} else {
+		allocationID, err = s.allocateNamedAddress(machine.Role(), machine.Name())
+		if err != nil {
+			return errors.Wrapf(err, "failed to allocate Elastic IP for machine %q", machine.Name())
+		}
+		machine.MachineStatus.ElasticIPAllocationID = allocationID
+	}
+
+	_, err = s.scope.EC2.AssociateAddress(&ec2.AssociateAddressInput{
+		AllocationId: aws.String(allocationID),
+		InstanceId:   aws.String(instanceID),
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to associate Elastic IP %q with instance %q", allocationID, instanceID)
+	}
+
+	klog.V(2).Infof("Associated Elastic IP %q with instance %q of machine %q", allocationID, instanceID, machine.Name())
+	record.Eventf(machine.Machine, "AssociatedElasticIP", "Associated Elastic IP %q with instance %q", allocationID, instanceID)
+	return nil
+}
+
+// ReleaseMachineAddress disassociates and releases the Elastic IP of a
+// machine, if any.
+func (s *Service) ReleaseMachineAddress(machine *actuators.MachineScope) error {
+	addresses, err := s.machineAddresses(machine)
+	if err != nil {
+		return err
+	}
+
+	for _, address := range addresses {
+		if address.AssociationId != nil {
+			_, err := s.scope.EC2.DisassociateAddress(&ec2.DisassociateAddressInput{
+				AssociationId: address.AssociationId,
+			})
+			if err != nil && !awserrors.IsNotFound(err) {
+				return errors.Wrapf(err, "failed to disassociate Elastic IP %q", aws.StringValue(address.AllocationId))
+			}
+		}
+
+		_, err := s.scope.EC2.ReleaseAddress(&ec2.ReleaseAddressInput{
+			AllocationId: address.AllocationId,
+		})
+		if err != nil {
+			return errors.Wrapf(err, "failed to release Elastic IP %q", aws.StringValue(address.AllocationId))
+		}
+
+		klog.V(2).Infof("Released Elastic IP %q of machine %q", aws.StringValue(address.AllocationId), machine.Name())
+		record.Eventf(machine.Machine, "ReleasedElasticIP", "Released Elastic IP %q", aws.StringValue(address.AllocationId))
+	}
+
+	machine.MachineStatus.ElasticIPAllocationID = ""
+	return nil
+}
+
+// machineAddresses returns the Elastic IPs allocated for a machine: the one
+// recorded in its status, or else the addresses owned by the cluster and
+// tagged with the name and role of the machine, for machines whose status
+// predates the record.
+func (s *Service) machineAddresses(machine *actuators.MachineScope) ([]*ec2.Address, error) {
+	if id := machine.MachineStatus.ElasticIPAllocationID; id != "" {
+		address, err := s.addressByAllocationID(id)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to describe Elastic IP of machine %q", machine.Name())
+		}
+		if address != nil {
+			return []*ec2.Address{address}, nil
+		}
+	}
+
+	out, err := s.scope.EC2.DescribeAddresses(&ec2.DescribeAddressesInput{
+		Filters: []*ec2.Filter{
+			filter.EC2.ClusterOwned(s.scope.Name()),
+			filter.EC2.ProviderRole(machine.Role()),
+			filter.EC2.Name(machine.Name()),
+		},
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to describe Elastic IPs of machine %q", machine.Name())
+	}
+
+	return out.Addresses, nil
+}
+
+// addressByAllocationID returns the Elastic IP of an allocation ID, or nil if
+// it was released.
+func (s *Service) addressByAllocationID(id string) (*ec2.Address, error) {
+	out, err := s.scope.EC2.DescribeAddresses(&ec2.DescribeAddressesInput{
+		AllocationIds: aws.StringSlice([]string{id}),
+	})
+	if code, _ := awserrors.Code(err); code == awserrors.AllocationIDNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if len(out.Addresses) == 0 {
+		return nil, nil
+	}
+	return out.Addresses[0], nil
+}
+
+// ReconcileAPIServerAddress allocates the Elastic IP the API server of a
+// cluster without load balancer is reached at, and records it in the cluster
+// status. The address is looked up by the allocation ID recorded in the
+// status, or else by its role, so that it stays the same across
+// reconciliations, as the certificates of the API server are issued for it.
+func (s *Service) ReconcileAPIServerAddress() error {
+	if !s.scope.ClusterConfig.APIServerElasticIP {
+		return nil
+	}
+
+	var address *ec2.Address
+	if eip := s.scope.Network().APIServerElasticIP; eip != nil {
+		var err error
+		address, err = s.addressByAllocationID(eip.AllocationID)
+		if err != nil {
+			return errors.Wrapf(err, "failed to describe Elastic IP %q of the API server", eip.AllocationID)
+		}
+	}
+
+	if address == nil {
+		out, err := s.scope.EC2.DescribeAddresses(&ec2.DescribeAddressesInput{
+			Filters: []*ec2.Filter{
+				filter.EC2.ClusterOwned(s.scope.Name()),
+				filter.EC2.ProviderRole(tags.ValueAPIServerRole),
+			},
+		})
+		if err != nil {
+			return errors.Wrap(err, "failed to describe the Elastic IP of the API server")
+		}
+		if len(out.Addresses) > 0 {
+			address = out.Addresses[0]
+		}
+	}
+
+	if address == nil {
+		allocationID, err := s.allocateAddress(tags.ValueAPIServerRole)
+		if err != nil {
+			return errors.Wrap(err, "failed to allocate the Elastic IP of the API server")
+		}
+
+		address, err = s.addressByAllocationID(allocationID)
+		if err != nil {
+			return errors.Wrapf(err, "failed to describe Elastic IP %q of the API server", allocationID)
+		}
+		if address == nil {
+			return errors.Errorf("Elastic IP %q of the API server not found after its allocation", allocationID)
+		}
+
+		record.Eventf(s.scope.Cluster, "AllocatedElasticIP", "Allocated Elastic IP %q for the API server", aws.StringValue(address.PublicIp))
+	}
+
+	s.scope.Network().APIServerElasticIP = &v1alpha1.ElasticIP{
+		AllocationID: aws.StringValue(address.AllocationId),
+		PublicIP:     aws.StringValue(address.PublicIp),
+	}
+	return nil
+}
+
+// AssociateAPIServerAddress associates the Elastic IP the API server of a
+// cluster without load balancer is reached at with the instance of its
+// control plane machine. The address is not moved from another instance, as
+// such a cluster supports a single control plane machine.
+func (s *Service) AssociateAPIServerAddress(machine *actuators.MachineScope, instanceID string) error {
+	eip := s.scope.Network().APIServerElasticIP
+	if eip == nil {
+		return awserrors.NewFailedDependency(errors.New("the Elastic IP of the API server is not allocated yet"))
+	}
+
+	address, err := s.addressByAllocationID(eip.AllocationID)
+	if err != nil {
+		return errors.Wrapf(err, "failed to describe Elastic IP %q of the API server", eip.AllocationID)
+	}
+	if address == nil {
+		return awserrors.NewFailedDependency(errors.Errorf("Elastic IP %q of the API server not found", eip.AllocationID))
+	}
+
+	switch associated := aws.StringValue(address.InstanceId); associated {
+	case instanceID:
+		return nil
+	case "":
+	default:
+		return awserrors.NewInvalidConfiguration(errors.Errorf(
+			"Elastic IP %q of the API server is associated with instance %q, a cluster without API server load balancer supports a single control plane machine",
+			eip.PublicIP, associated))
+	}
+
+	_, err = s.scope.EC2.AssociateAddress(&ec2.AssociateAddressInput{
+		AllocationId: address.AllocationId,
+		InstanceId:   aws.String(instanceID),
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to associate Elastic IP %q with instance %q", eip.AllocationID, instanceID)
+	}
+
+	klog.V(2).Infof("Associated the Elastic IP of the API server with instance %q of machine %q", instanceID, machine.Name())
+	record.Eventf(machine.Machine, "AssociatedElasticIP", "Associated Elastic IP %q of the API server with instance %q", eip.PublicIP, instanceID)
+	return nil
+}
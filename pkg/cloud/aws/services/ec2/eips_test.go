@@ -0,0 +1,305 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ec2
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/golang/mock/gomock"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/actuators"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/filter"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/awserrors"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/ec2/mock_ec2iface"
+	clusterv1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+)
+
+func newAddressTestScope(t *testing.T, ec2Mock *mock_ec2iface.MockEC2API) *actuators.MachineScope {
+	scope, err := actuators.NewMachineScope(actuators.MachineScopeParams{
+		Cluster: &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"}},
+		Machine: &clusterv1.Machine{
+			ObjectMeta: metav1.ObjectMeta{Name: "controlplane-0", Labels: map[string]string{"set": "controlplane"}},
+		},
+		AWSClients: actuators.AWSClients{EC2: ec2Mock},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create test context: %v", err)
+	}
+	return scope
+}
+
+func TestAssociateMachineAddress(t *testing.T) {
+	testCases := []struct {
+		name      string
+		addresses []*ec2.Address
+		expect    func(m *mock_ec2iface.MockEC2APIMockRecorder)
+	}{
+		{
+			name: "no address yet",
+			expect: func(m *mock_ec2iface.MockEC2APIMockRecorder) {
+				m.AllocateAddress(&ec2.AllocateAddressInput{Domain: aws.String("vpc")}).
+					Return(&ec2.AllocateAddressOutput{AllocationId: aws.String("eipalloc-1")}, nil)
+				m.CreateTags(gomock.Any()).Return(&ec2.CreateTagsOutput{}, nil)
+				m.AssociateAddress(&ec2.AssociateAddressInput{AllocationId: aws.String("eipalloc-1"), InstanceId: aws.String("i-1")}).
+					Return(&ec2.AssociateAddressOutput{}, nil)
+			},
+		},
+		{
+			name:      "address of a previous instance",
+			addresses: []*ec2.Address{{AllocationId: aws.String("eipalloc-1")}},
+			expect: func(m *mock_ec2iface.MockEC2APIMockRecorder) {
+				m.AssociateAddress(&ec2.AssociateAddressInput{AllocationId: aws.String("eipalloc-1"), InstanceId: aws.String("i-1")}).
+					Return(&ec2.AssociateAddressOutput{}, nil)
+			},
+		},
+		{
+			name:      "address already associated",
+			addresses: []*ec2.Address{{AllocationId: aws.String("eipalloc-1"), InstanceId: aws.String("i-1")}},
+			expect:    func(m *mock_ec2iface.MockEC2APIMockRecorder) {},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+
+			ec2Mock := mock_ec2iface.NewMockEC2API(mockCtrl)
+			ec2Mock.EXPECT().DescribeAddresses(gomock.Any()).Return(&ec2.DescribeAddressesOutput{Addresses: tc.addresses}, nil)
+			tc.expect(ec2Mock.EXPECT())
+
+			scope := newAddressTestScope(t, ec2Mock)
+			if err := NewService(scope.Scope).AssociateMachineAddress(scope, "i-1"); err != nil {
+				t.Fatalf("did not expect error: %v", err)
+			}
+
+			if scope.MachineStatus.ElasticIPAllocationID != "eipalloc-1" {
+				t.Fatalf("expected the allocation ID to be recorded, got %q", scope.MachineStatus.ElasticIPAllocationID)
+			}
+		})
+	}
+}
+
+func TestMachineAddresses(t *testing.T) {
+	byTags := &ec2.DescribeAddressesInput{
+		Filters: []*ec2.Filter{
+			filter.EC2.ClusterOwned("test-cluster"),
+			filter.EC2.ProviderRole("controlplane"),
+			filter.EC2.Name("controlplane-0"),
+		},
+	}
+	byAllocationID := &ec2.DescribeAddressesInput{AllocationIds: aws.StringSlice([]string{"eipalloc-1"})}
+
+	testCases := []struct {
+		name         string
+		allocationID string
+		expect       func(m *mock_ec2iface.MockEC2APIMockRecorder)
+		expectID     string
+	}{
+		{
+			name: "looked up by tags",
+			expect: func(m *mock_ec2iface.MockEC2APIMockRecorder) {
+				m.DescribeAddresses(byTags).
+					Return(&ec2.DescribeAddressesOutput{Addresses: []*ec2.Address{{AllocationId: aws.String("eipalloc-2")}}}, nil)
+			},
+			expectID: "eipalloc-2",
+		},
+		{
+			name:         "looked up by recorded allocation ID",
+			allocationID: "eipalloc-1",
+			expect: func(m *mock_ec2iface.MockEC2APIMockRecorder) {
+				m.DescribeAddresses(byAllocationID).
+					Return(&ec2.DescribeAddressesOutput{Addresses: []*ec2.Address{{AllocationId: aws.String("eipalloc-1")}}}, nil)
+			},
+			expectID: "eipalloc-1",
+		},
+		{
+			name:         "recorded allocation ID released",
+			allocationID: "eipalloc-1",
+			expect: func(m *mock_ec2iface.MockEC2APIMockRecorder) {
+				gomock.InOrder(
+					m.DescribeAddresses(byAllocationID).
+						Return(nil, awserr.New(awserrors.AllocationIDNotFound, "not found", nil)),
+					m.DescribeAddresses(byTags).
+						Return(&ec2.DescribeAddressesOutput{}, nil),
+				)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+
+			ec2Mock := mock_ec2iface.NewMockEC2API(mockCtrl)
+			tc.expect(ec2Mock.EXPECT())
+
+			scope := newAddressTestScope(t, ec2Mock)
+			scope.MachineStatus.ElasticIPAllocationID = tc.allocationID
+
+			addresses, err := NewService(scope.Scope).machineAddresses(scope)
+			if err != nil {
+				t.Fatalf("did not expect error: %v", err)
+			}
+
+			var id string
+			if len(addresses) > 0 {
+				id = aws.StringValue(addresses[0].AllocationId)
+			}
+			if id != tc.expectID {
+				t.Fatalf("expected address %q, got %q", tc.expectID, id)
+			}
+		})
+	}
+}
+
+func TestReconcileAPIServerAddress(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	ec2Mock := mock_ec2iface.NewMockEC2API(mockCtrl)
+	scope := newAddressTestScope(t, ec2Mock)
+	s := NewService(scope.Scope)
+
+	// Clusters with a load balancer have no address.
+	if err := s.ReconcileAPIServerAddress(); err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+
+	scope.ClusterConfig.APIServerElasticIP = true
+	address := &ec2.Address{AllocationId: aws.String("eipalloc-1"), PublicIp: aws.String("203.0.113.10")}
+	gomock.InOrder(
+		ec2Mock.EXPECT().DescribeAddresses(&ec2.DescribeAddressesInput{
+			Filters: []*ec2.Filter{
+				filter.EC2.ClusterOwned("test-cluster"),
+				filter.EC2.ProviderRole("apiserver"),
+			},
+		}).Return(&ec2.DescribeAddressesOutput{}, nil),
+		ec2Mock.EXPECT().AllocateAddress(&ec2.AllocateAddressInput{Domain: aws.String("vpc")}).
+			Return(&ec2.AllocateAddressOutput{AllocationId: aws.String("eipalloc-1")}, nil),
+		ec2Mock.EXPECT().CreateTags(gomock.Any()).Return(&ec2.CreateTagsOutput{}, nil),
+		ec2Mock.EXPECT().DescribeAddresses(&ec2.DescribeAddressesInput{AllocationIds: aws.StringSlice([]string{"eipalloc-1"})}).
+			Return(&ec2.DescribeAddressesOutput{Addresses: []*ec2.Address{address}}, nil),
+	)
+
+	if err := s.ReconcileAPIServerAddress(); err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+
+	expected := &v1alpha1.ElasticIP{AllocationID: "eipalloc-1", PublicIP: "203.0.113.10"}
+	if eip := scope.Network().APIServerElasticIP; eip == nil || *eip != *expected {
+		t.Fatalf("expected %+v to be recorded, got %+v", expected, eip)
+	}
+	if endpoint := scope.APIServerEndpoint(); endpoint != "203.0.113.10" {
+		t.Fatalf("expected the API server to be reached at the Elastic IP, got %q", endpoint)
+	}
+
+	// The recorded address is kept.
+	ec2Mock.EXPECT().DescribeAddresses(&ec2.DescribeAddressesInput{AllocationIds: aws.StringSlice([]string{"eipalloc-1"})}).
+		Return(&ec2.DescribeAddressesOutput{Addresses: []*ec2.Address{address}}, nil)
+	if err := s.ReconcileAPIServerAddress(); err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+}
+
+func TestAssociateAPIServerAddress(t *testing.T) {
+	testCases := []struct {
+		name        string
+		instanceID  string
+		expect      func(m *mock_ec2iface.MockEC2APIMockRecorder)
+		expectError bool
+	}{
+		{
+			name: "not associated",
+			expect: func(m *mock_ec2iface.MockEC2APIMockRecorder) {
+				m.AssociateAddress(&ec2.AssociateAddressInput{AllocationId: aws.String("eipalloc-1"), InstanceId: aws.String("i-1")}).
+					Return(&ec2.AssociateAddressOutput{}, nil)
+			},
+		},
+		{
+			name:       "already associated",
+			instanceID: "i-1",
+			expect:     func(m *mock_ec2iface.MockEC2APIMockRecorder) {},
+		},
+		{
+			name:        "associated with another control plane instance",
+			instanceID:  "i-2",
+			expect:      func(m *mock_ec2iface.MockEC2APIMockRecorder) {},
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+
+			ec2Mock := mock_ec2iface.NewMockEC2API(mockCtrl)
+			address := &ec2.Address{AllocationId: aws.String("eipalloc-1")}
+			if tc.instanceID != "" {
+				address.InstanceId = aws.String(tc.instanceID)
+			}
+			ec2Mock.EXPECT().DescribeAddresses(&ec2.DescribeAddressesInput{AllocationIds: aws.StringSlice([]string{"eipalloc-1"})}).
+				Return(&ec2.DescribeAddressesOutput{Addresses: []*ec2.Address{address}}, nil)
+			tc.expect(ec2Mock.EXPECT())
+
+			scope := newAddressTestScope(t, ec2Mock)
+			scope.ClusterConfig.APIServerElasticIP = true
+			scope.Network().APIServerElasticIP = &v1alpha1.ElasticIP{AllocationID: "eipalloc-1", PublicIP: "203.0.113.10"}
+
+			err := NewService(scope.Scope).AssociateAPIServerAddress(scope, "i-1")
+			if tc.expectError {
+				if !awserrors.IsInvalidConfiguration(err) {
+					t.Fatalf("expected an invalid configuration error, got: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("did not expect error: %v", err)
+			}
+		})
+	}
+}
+
+func TestReleaseMachineAddress(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	ec2Mock := mock_ec2iface.NewMockEC2API(mockCtrl)
+	ec2Mock.EXPECT().DescribeAddresses(gomock.Any()).
+		Return(&ec2.DescribeAddressesOutput{Addresses: []*ec2.Address{{
+			AllocationId:  aws.String("eipalloc-1"),
+			AssociationId: aws.String("eipassoc-1"),
+			InstanceId:    aws.String("i-1"),
+		}}}, nil)
+	gomock.InOrder(
+		ec2Mock.EXPECT().DisassociateAddress(&ec2.DisassociateAddressInput{AssociationId: aws.String("eipassoc-1")}).
+			Return(&ec2.DisassociateAddressOutput{}, nil),
+		ec2Mock.EXPECT().ReleaseAddress(&ec2.ReleaseAddressInput{AllocationId: aws.String("eipalloc-1")}).
+			Return(&ec2.ReleaseAddressOutput{}, nil),
+	)
+
+	scope := newAddressTestScope(t, ec2Mock)
+	if err := NewService(scope.Scope).ReleaseMachineAddress(scope); err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+}
@@ -0,0 +1,109 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ec2
+
+import (
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/filter"
+)
+
+// validateEncryptionKeys checks the KMS keys of the given volumes before
+// launch. Keys must only be set on encrypted volumes, and keys referenced by
+// ARN must be in the given region.
+func validateEncryptionKeys(region string, root *v1alpha1.RootVolume, volumes []v1alpha1.Volume) error {
+	if root != nil {
+		if err := validateEncryptionKey(region, root.EncryptionKeyID, root.Encrypted); err != nil {
+			return errors.Wrap(err, "root volume")
+		}
+	}
+
+	for _, v := range volumes {
+		if err := validateEncryptionKey(region, v.EncryptionKeyID, v.Encrypted); err != nil {
+			return errors.Wrapf(err, "volume %q", v.DeviceName)
+		}
+	}
+
+	return nil
+}
+
+func validateEncryptionKey(region, keyID string, encrypted bool) error {
+	if keyID == "" {
+		return nil
+	}
+
+	if !encrypted {
+		return errors.Errorf("encryption key %q requires the volume to be encrypted", keyID)
+	}
+
+	// arn:partition:kms:region:account:key/id or alias/name
+	if !strings.HasPrefix(keyID, "arn:") {
+		return nil
+	}
+
+	parts := strings.SplitN(keyID, ":", 6)
+	if len(parts) != 6 || parts[2] != "kms" {
+		return errors.Errorf("encryption key %q is not a KMS key ARN", keyID)
+	}
+
+	if parts[3] != region {
+		return errors.Errorf("encryption key %q is in region %q, not in the region of the cluster %q", keyID, parts[3], region)
+	}
+
+	return nil
+}
+
+// usesEncryptionKeys returns true if any of the given volumes is encrypted
+// with a specific KMS key.
+func usesEncryptionKeys(root *v1alpha1.RootVolume, volumes []v1alpha1.Volume) bool {
+	if root != nil && root.EncryptionKeyID != "" {
+		return true
+	}
+
+	for _, v := range volumes {
+		if v.EncryptionKeyID != "" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// encryptedLaunchFailure explains why an instance with volumes encrypted
+// under specific KMS keys did not start. Inaccessible keys are only detected
+// by EC2 after launch, and cause the instance to be terminated.
+func (s *Service) encryptedLaunchFailure(instanceID string, waitErr error) error {
+	out, err := s.scope.EC2.DescribeInstances(&ec2.DescribeInstancesInput{
+		InstanceIds: aws.StringSlice([]string{instanceID}),
+		Filters:     []*ec2.Filter{filter.EC2.Cluster(s.scope.Name())},
+	})
+	if err != nil || len(out.Reservations) == 0 || len(out.Reservations[0].Instances) == 0 {
+		return errors.Wrapf(waitErr, "failed to wait for instance %q to run", instanceID)
+	}
+
+	instance := out.Reservations[0].Instances[0]
+	if aws.StringValue(instance.State.Name) != ec2.InstanceStateNameTerminated || instance.StateReason == nil {
+		return errors.Wrapf(waitErr, "failed to wait for instance %q to run", instanceID)
+	}
+
+	return errors.Errorf("instance %q was terminated at launch: %s, check that the KMS keys of its volumes exist, are enabled and can be used by the controller",
+		instanceID, aws.StringValue(instance.StateReason.Message))
+}
@@ -0,0 +1,76 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ec2
+
+import (
+	"testing"
+
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+)
+
+func TestValidateEncryptionKeys(t *testing.T) {
+	testCases := []struct {
+		name        string
+		root        *v1alpha1.RootVolume
+		volumes     []v1alpha1.Volume
+		expectError bool
+	}{
+		{
+			name: "default key",
+			root: &v1alpha1.RootVolume{Size: 50, Encrypted: true},
+		},
+		{
+			name: "key id",
+			root: &v1alpha1.RootVolume{Size: 50, Encrypted: true, EncryptionKeyID: "1234abcd-12ab-34cd-56ef-1234567890ab"},
+		},
+		{
+			name: "key arn in the cluster region",
+			volumes: []v1alpha1.Volume{
+				{DeviceName: "/dev/sdb", Size: 100, Encrypted: true, EncryptionKeyID: "arn:aws:kms:us-east-1:123456789012:key/1234abcd-12ab-34cd-56ef-1234567890ab"},
+			},
+		},
+		{
+			name: "key arn in another region",
+			volumes: []v1alpha1.Volume{
+				{DeviceName: "/dev/sdb", Size: 100, Encrypted: true, EncryptionKeyID: "arn:aws:kms:eu-west-1:123456789012:alias/etcd"},
+			},
+			expectError: true,
+		},
+		{
+			name:        "not a kms arn",
+			root:        &v1alpha1.RootVolume{Size: 50, Encrypted: true, EncryptionKeyID: "arn:aws:iam::123456789012:role/nodes"},
+			expectError: true,
+		},
+		{
+			name:        "key on an unencrypted volume",
+			root:        &v1alpha1.RootVolume{Size: 50, EncryptionKeyID: "alias/nodes"},
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateEncryptionKeys("us-east-1", tc.root, tc.volumes)
+			if tc.expectError && err == nil {
+				t.Fatalf("expected error")
+			}
+			if !tc.expectError && err != nil {
+				t.Fatalf("did not expect error: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ec2
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/pkg/errors"
+	"k8s.io/klog"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/converters"
+)
+
+// reconcileExternalNetwork records the network resources referenced by the
+// cluster provider spec in the cluster status, without modifying them.
+func (s *Service) reconcileExternalNetwork() error {
+	network := s.scope.ClusterConfig.ExternalNetwork
+
+	klog.V(2).Infof("Using externally managed network in VPC %q", network.VPCID)
+
+	securityGroupIDs := network.SecurityGroupIDs()
+	for _, role := range []v1alpha1.SecurityGroupRole{v1alpha1.SecurityGroupControlPlane, v1alpha1.SecurityGroupNode} {
+		if securityGroupIDs[role] == "" {
+			return errors.Errorf("external network requires a security group for role %q", role)
+		}
+	}
+
+	if len(network.PrivateSubnetIDs) == 0 {
+		return errors.New("external network requires at least one private subnet")
+	}
+
+	vpcs, err := s.scope.EC2.DescribeVpcs(&ec2.DescribeVpcsInput{
+		VpcIds: aws.StringSlice([]string{network.VPCID}),
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to describe external vpc %q", network.VPCID)
+	}
+
+	if len(vpcs.Vpcs) == 0 {
+		return errors.Errorf("external vpc %q not found", network.VPCID)
+	}
+
+	*s.scope.VPC() = v1alpha1.VPC{
+		ID:        aws.StringValue(vpcs.Vpcs[0].VpcId),
+		CidrBlock: aws.StringValue(vpcs.Vpcs[0].CidrBlock),
+		Tags:      converters.TagsToMap(vpcs.Vpcs[0].Tags),
+	}
+
+	public := make(map[string]bool, len(network.PublicSubnetIDs))
+	for _, id := range network.PublicSubnetIDs {
+		public[id] = true
+	}
+
+	subnetIDs := make([]string, 0, len(network.PublicSubnetIDs)+len(network.PrivateSubnetIDs))
+	subnetIDs = append(subnetIDs, network.PublicSubnetIDs...)
+	subnetIDs = append(subnetIDs, network.PrivateSubnetIDs...)
+
+	subnets, err := s.scope.EC2.DescribeSubnets(&ec2.DescribeSubnetsInput{
+		SubnetIds: aws.StringSlice(subnetIDs),
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to describe external subnets in vpc %q", network.VPCID)
+	}
+
+	s.scope.Network().Subnets = nil
+	for _, ec2sn := range subnets.Subnets {
+		if aws.StringValue(ec2sn.VpcId) != network.VPCID {
+			return errors.Errorf("external subnet %q is not in vpc %q", aws.StringValue(ec2sn.SubnetId), network.VPCID)
+		}
+
+		s.scope.Network().Subnets = append(s.scope.Network().Subnets, &v1alpha1.Subnet{
+			ID:               aws.StringValue(ec2sn.SubnetId),
+			VpcID:            aws.StringValue(ec2sn.VpcId),
+			CidrBlock:        aws.StringValue(ec2sn.CidrBlock),
+			AvailabilityZone: aws.StringValue(ec2sn.AvailabilityZone),
+			IsPublic:         public[aws.StringValue(ec2sn.SubnetId)],
+			Tags:             converters.TagsToMap(ec2sn.Tags),
+		})
+	}
+
+	roles := make(map[string]v1alpha1.SecurityGroupRole, len(securityGroupIDs))
+	groupIDs := make([]string, 0, len(securityGroupIDs))
+	for role, id := range securityGroupIDs {
+		roles[id] = role
+		groupIDs = append(groupIDs, id)
+	}
+
+	sgs, err := s.scope.EC2.DescribeSecurityGroups(&ec2.DescribeSecurityGroupsInput{
+		GroupIds: aws.StringSlice(groupIDs),
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to describe external security groups in vpc %q", network.VPCID)
+	}
+
+	s.scope.Network().SecurityGroups = make(map[v1alpha1.SecurityGroupRole]*v1alpha1.SecurityGroup)
+	for _, ec2sg := range sgs.SecurityGroups {
+		sg := &v1alpha1.SecurityGroup{
+			ID:   aws.StringValue(ec2sg.GroupId),
+			Name: aws.StringValue(ec2sg.GroupName),
+			Tags: converters.TagsToMap(ec2sg.Tags),
+		}
+
+		for _, ec2rule := range ec2sg.IpPermissions {
+			sg.IngressRules = append(sg.IngressRules, ingressRuleFromSDKType(ec2rule))
+		}
+
+		s.scope.SecurityGroups()[roles[sg.ID]] = sg
+	}
+
+	return nil
+}
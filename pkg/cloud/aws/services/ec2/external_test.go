@@ -0,0 +1,101 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ec2
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/golang/mock/gomock"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/actuators"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/ec2/mock_ec2iface"
+	clusterv1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+)
+
+func TestReconcileExternalNetwork(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	// Only lookups are expected, no resource is created, tagged or modified.
+	ec2Mock := mock_ec2iface.NewMockEC2API(mockCtrl)
+	ec2Mock.EXPECT().
+		DescribeVpcs(&ec2.DescribeVpcsInput{VpcIds: aws.StringSlice([]string{"vpc-ext"})}).
+		Return(&ec2.DescribeVpcsOutput{
+			Vpcs: []*ec2.Vpc{{VpcId: aws.String("vpc-ext"), CidrBlock: aws.String("10.1.0.0/16")}},
+		}, nil)
+	ec2Mock.EXPECT().
+		DescribeSubnets(&ec2.DescribeSubnetsInput{SubnetIds: aws.StringSlice([]string{"subnet-pub", "subnet-priv"})}).
+		Return(&ec2.DescribeSubnetsOutput{
+			Subnets: []*ec2.Subnet{
+				{SubnetId: aws.String("subnet-pub"), VpcId: aws.String("vpc-ext"), AvailabilityZone: aws.String("us-east-1a"), CidrBlock: aws.String("10.1.0.0/24")},
+				{SubnetId: aws.String("subnet-priv"), VpcId: aws.String("vpc-ext"), AvailabilityZone: aws.String("us-east-1a"), CidrBlock: aws.String("10.1.1.0/24")},
+			},
+		}, nil)
+	ec2Mock.EXPECT().
+		DescribeSecurityGroups(gomock.AssignableToTypeOf(&ec2.DescribeSecurityGroupsInput{})).
+		Return(&ec2.DescribeSecurityGroupsOutput{
+			SecurityGroups: []*ec2.SecurityGroup{
+				{GroupId: aws.String("sg-cp"), GroupName: aws.String("control-plane")},
+				{GroupId: aws.String("sg-node"), GroupName: aws.String("nodes")},
+			},
+		}, nil)
+
+	scope, err := actuators.NewScope(actuators.ScopeParams{
+		Cluster: &clusterv1.Cluster{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"},
+		},
+		AWSClients: actuators.AWSClients{
+			EC2: ec2Mock,
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create test context: %v", err)
+	}
+
+	scope.ClusterConfig = &v1alpha1.AWSClusterProviderSpec{
+		ExternalNetwork: &v1alpha1.ExternalNetwork{
+			VPCID:                       "vpc-ext",
+			PublicSubnetIDs:             []string{"subnet-pub"},
+			PrivateSubnetIDs:            []string{"subnet-priv"},
+			ControlPlaneSecurityGroupID: "sg-cp",
+			NodeSecurityGroupID:         "sg-node",
+		},
+	}
+
+	if err := NewService(scope).ReconcileNetwork(); err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+
+	if scope.VPC().ID != "vpc-ext" || scope.VPC().CidrBlock != "10.1.0.0/16" {
+		t.Fatalf("expected the external vpc to be recorded, got %v", scope.VPC())
+	}
+
+	if public := scope.Subnets().FilterPublic(); len(public) != 1 || public[0].ID != "subnet-pub" {
+		t.Fatalf("expected a single public subnet, got %v", public)
+	}
+
+	if private := scope.Subnets().FilterPrivate(); len(private) != 1 || private[0].ID != "subnet-priv" {
+		t.Fatalf("expected a single private subnet, got %v", private)
+	}
+
+	if sg := scope.SecurityGroups()[v1alpha1.SecurityGroupControlPlane]; sg == nil || sg.ID != "sg-cp" {
+		t.Fatalf("expected the control plane security group to be recorded, got %v", sg)
+	}
+}
@@ -0,0 +1,122 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ec2
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/actuators"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/userdata"
+)
+
+const (
+	// defaultFilePermissions are the permissions of the files of machines.
+	defaultFilePermissions = "0644"
+
+	// defaultSecretFilePermissions are the permissions of the files of
+	// machines whose content is read from a Secret.
+	defaultSecretFilePermissions = "0600"
+)
+
+// filePermissions matches octal file permissions.
+var filePermissions = regexp.MustCompile(`^0?[0-7]{3}$`)
+
+// validateFiles checks the files written on an instance.
+func validateFiles(files []v1alpha1.File) error {
+	paths := map[string]bool{}
+	for _, f := range files {
+		if !path.IsAbs(f.Path) || path.Clean(f.Path) != f.Path || f.Path == "/" {
+			return errors.Errorf("file path %q must be a clean absolute path", f.Path)
+		}
+		if strings.ContainsAny(f.Path, "'\n") {
+			return errors.Errorf("file path %q must not contain single quotes or newlines", f.Path)
+		}
+		if paths[f.Path] {
+			return errors.Errorf("file %q is declared more than once", f.Path)
+		}
+		paths[f.Path] = true
+
+		if f.Permissions != "" && !filePermissions.MatchString(f.Permissions) {
+			return errors.Errorf("permissions %q of file %q must be octal, e.g. 0644", f.Permissions, f.Path)
+		}
+
+		if source := f.ContentFrom; source != nil {
+			if f.Content != "" {
+				return errors.Errorf("file %q must not set both content and contentFrom", f.Path)
+			}
+			if (source.Secret == nil) == (source.ConfigMap == nil) {
+				return errors.Errorf("file %q must read its content from exactly one of a secret or config map", f.Path)
+			}
+		}
+	}
+
+	return nil
+}
+
+// filesInput returns the files written by the user data of the instance of a
+// machine, reading their content from Secrets and ConfigMaps. The content of
+// Secrets is stored in SecureString SSM parameters read by the instance, so
+// that it is not readable from the user data of the instance.
+func filesInput(machine *actuators.MachineScope) ([]userdata.FileInput, error) {
+	var files []userdata.FileInput
+	for _, f := range machine.MachineConfig.Files {
+		content, err := machine.FileContent(f)
+		if err != nil {
+			return nil, err
+		}
+
+		secret := f.ContentFrom != nil && f.ContentFrom.Secret != nil
+
+		permissions := f.Permissions
+		if permissions == "" {
+			permissions = defaultFilePermissions
+			if secret {
+				permissions = defaultSecretFilePermissions
+			}
+		}
+
+		input := userdata.FileInput{
+			Path:        f.Path,
+			Permissions: permissions,
+		}
+		if secret {
+			input.Parameter = fileParameterName(machine, f.Path)
+			if err := machine.SSM.PutSecureParameter(input.Parameter, base64.StdEncoding.EncodeToString(content)); err != nil {
+				return nil, errors.Wrapf(err, "failed to store the content of file %q", f.Path)
+			}
+		} else {
+			input.Content = base64.StdEncoding.EncodeToString(content)
+		}
+
+		files = append(files, input)
+	}
+
+	return files, nil
+}
+
+// fileParameterName returns the name of the SSM parameter holding the content
+// of the file of a machine at the given path.
+func fileParameterName(machine *actuators.MachineScope, filePath string) string {
+	return fmt.Sprintf("%s/%x", machine.FileParametersPath(), sha256.Sum256([]byte(filePath)))
+}
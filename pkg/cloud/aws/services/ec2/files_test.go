@@ -0,0 +1,147 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ec2
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/actuators"
+	clusterv1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+)
+
+func TestValidateFiles(t *testing.T) {
+	secret := &v1alpha1.FileSource{Secret: &v1alpha1.FileSourceKey{Name: "registry", Key: "config.json"}}
+
+	testCases := []struct {
+		name        string
+		files       []v1alpha1.File
+		expectError bool
+	}{
+		{
+			name: "no files",
+		},
+		{
+			name: "inline and secret files",
+			files: []v1alpha1.File{
+				{Path: "/etc/sysctl.d/90-custom.conf", Content: "vm.max_map_count=262144", Permissions: "0644"},
+				{Path: "/root/.docker/config.json", ContentFrom: secret},
+			},
+		},
+		{
+			name:        "relative path",
+			files:       []v1alpha1.File{{Path: "etc/motd"}},
+			expectError: true,
+		},
+		{
+			name:        "path with a single quote",
+			files:       []v1alpha1.File{{Path: "/etc/it's"}},
+			expectError: true,
+		},
+		{
+			name:        "duplicate path",
+			files:       []v1alpha1.File{{Path: "/etc/motd"}, {Path: "/etc/motd"}},
+			expectError: true,
+		},
+		{
+			name:        "invalid permissions",
+			files:       []v1alpha1.File{{Path: "/etc/motd", Permissions: "rw-r--r--"}},
+			expectError: true,
+		},
+		{
+			name:        "content and content from",
+			files:       []v1alpha1.File{{Path: "/etc/motd", Content: "hello", ContentFrom: secret}},
+			expectError: true,
+		},
+		{
+			name:        "content from without source",
+			files:       []v1alpha1.File{{Path: "/etc/motd", ContentFrom: &v1alpha1.FileSource{}}},
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateFiles(tc.files)
+			if tc.expectError != (err != nil) {
+				t.Fatalf("expected error: %v, got: %v", tc.expectError, err)
+			}
+		})
+	}
+}
+
+// fakeFileSources serves the Secrets of the files of machines from memory.
+type fakeFileSources struct {
+	corev1client.SecretInterface
+	corev1client.ConfigMapsGetter
+
+	secrets map[string]*corev1.Secret
+}
+
+func (f *fakeFileSources) Secrets(namespace string) corev1client.SecretInterface {
+	return f
+}
+
+func (f *fakeFileSources) Get(name string, options metav1.GetOptions) (*corev1.Secret, error) {
+	return f.secrets[name], nil
+}
+
+func TestFilesInput(t *testing.T) {
+	sources := &fakeFileSources{secrets: map[string]*corev1.Secret{
+		"registry": {Data: map[string][]byte{"config.json": []byte(`{"auths":{}}`)}},
+	}}
+
+	machine, err := actuators.NewMachineScope(actuators.MachineScopeParams{
+		Cluster:     &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"}},
+		Machine:     &clusterv1.Machine{ObjectMeta: metav1.ObjectMeta{Name: "node-0", Namespace: "default"}},
+		FileSources: sources,
+	})
+	if err != nil {
+		t.Fatalf("failed to create scope: %v", err)
+	}
+	parameters := fakeParameterStore{}
+	machine.SSM = parameters
+	machine.MachineConfig.Files = []v1alpha1.File{
+		{Path: "/etc/sysctl.d/90-test.conf", Content: "vm.max_map_count = 262144\n"},
+		{Path: "/var/lib/kubelet/config.json", ContentFrom: &v1alpha1.FileSource{Secret: &v1alpha1.FileSourceKey{Name: "registry", Key: "config.json"}}},
+	}
+
+	files, err := filesInput(machine)
+	if err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+
+	if files[0].Content == "" || files[0].Parameter != "" || files[0].Permissions != defaultFilePermissions {
+		t.Fatalf("expected the inline file to be written from the user data, got %+v", files[0])
+	}
+
+	secret := files[1]
+	if secret.Content != "" || secret.Permissions != defaultSecretFilePermissions {
+		t.Fatalf("expected the secret file not to be part of the user data, got %+v", secret)
+	}
+	if !strings.HasPrefix(secret.Parameter, "/cluster-api-provider-aws/machine-files/default/test-cluster/node-0/") {
+		t.Fatalf("unexpected parameter name %q", secret.Parameter)
+	}
+	if parameters[secret.Parameter] != base64.StdEncoding.EncodeToString([]byte(`{"auths":{}}`)) {
+		t.Fatalf("expected the secret to be stored in its parameter, got %v", parameters)
+	}
+}
@@ -17,8 +17,6 @@ limitations under the License.
 package ec2
 
 import (
-	"fmt"
-
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/pkg/errors"
@@ -136,7 +134,7 @@ func (s *Service) describeVpcInternetGateways() ([]*ec2.InternetGateway, error)
 }
 
 func (s *Service) getGatewayTagParams(id string) tags.BuildParams {
-	name := fmt.Sprintf("%s-igw", s.scope.Name())
+	name := s.scope.ResourceName("igw", 0)
 
 	return tags.BuildParams{
 		ClusterName: s.scope.Name(),
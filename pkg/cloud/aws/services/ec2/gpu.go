@@ -0,0 +1,112 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ec2
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/klog"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/awserrors"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/instancetypes"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/userdata"
+)
+
+const (
+	// defaultGPUDriverVersion is the branch of the NVIDIA driver installed
+	// when the GPU configuration of a machine does not set one, the current
+	// long-term support branch.
+	defaultGPUDriverVersion = "580"
+
+	// gpuNodeLabel labels the nodes of GPU instance types.
+	gpuNodeLabel = "nvidia.com/gpu.present=true"
+
+	// nvidiaManufacturer is the manufacturer of NVIDIA GPUs, as described by EC2.
+	nvidiaManufacturer = "NVIDIA"
+)
+
+// hasNVIDIAGPUs returns true if an instance type described by EC2 has NVIDIA
+// GPUs. The accelerators of an instance type list its GPUs, and its
+// inference accelerators, which are made by AWS.
+func hasNVIDIAGPUs(info *instancetypes.Info) bool {
+	for _, accelerator := range info.Accelerators {
+		if accelerator.Manufacturer == nvidiaManufacturer && accelerator.Count > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// isGPUInstanceType returns true if an instance type has NVIDIA GPUs, and
+// false if it cannot be described.
+func (s *Service) isGPUInstanceType(instanceType string) bool {
+	if instanceType == "" {
+		return false
+	}
+
+	info, err := s.scope.InstanceTypes.Describe(instanceType)
+	if err != nil {
+		klog.Warningf("Not labeling the nodes of instance type %q as GPU nodes: %v", instanceType, err)
+		return false
+	}
+	return info != nil && hasNVIDIAGPUs(info)
+}
+
+// validateGPU checks that the drivers are only installed on instance types
+// with NVIDIA GPUs.
+func (s *Service) validateGPU(instanceType string, gpu *v1alpha1.GPUConfig) error {
+	if gpu == nil || !gpu.InstallDrivers || instanceType == "" {
+		return nil
+	}
+
+	info, err := s.scope.InstanceTypes.Describe(instanceType)
+	if err != nil {
+		return errors.Wrapf(err, "failed to describe instance type %q", instanceType)
+	}
+	if info == nil {
+		return awserrors.NewInvalidConfiguration(errors.Errorf("instance type %q is not offered in region %q", instanceType, s.scope.Region()))
+	}
+	if !hasNVIDIAGPUs(info) {
+		return awserrors.NewInvalidConfiguration(errors.Errorf("instance type %q has no NVIDIA GPUs to install drivers for", instanceType))
+	}
+
+	return nil
+}
+
+// gpuInput returns the NVIDIA driver installed by the user data of an
+// instance, if any.
+func gpuInput(gpu *v1alpha1.GPUConfig) *userdata.GPUInput {
+	if gpu == nil || !gpu.InstallDrivers {
+		return nil
+	}
+
+	version := gpu.DriverVersion
+	if version == "" {
+		version = defaultGPUDriverVersion
+	}
+
+	return &userdata.GPUInput{DriverVersion: version}
+}
+
+// addNodeLabels adds labels to the node-labels kubelet argument.
+func addNodeLabels(args map[string]string, labels ...string) {
+	if existing := args["node-labels"]; existing != "" {
+		labels = append([]string{existing}, labels...)
+	}
+	args["node-labels"] = strings.Join(labels, ",")
+}
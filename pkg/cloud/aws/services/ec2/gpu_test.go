@@ -0,0 +1,167 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ec2
+
+import (
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/actuators"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/awserrors"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/instancetypes"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/userdata"
+	clusterv1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+)
+
+func gpuTestService(t *testing.T) *Service {
+	scope, err := actuators.NewScope(actuators.ScopeParams{
+		Cluster: &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"}},
+		InstanceTypes: &fakeInstanceTypes{
+			infos: map[string]*instancetypes.Info{
+				"g4dn.xlarge": {Accelerators: []instancetypes.Accelerator{{Manufacturer: "NVIDIA", Name: "T4", Count: 1}}},
+				"g4ad.xlarge": {Accelerators: []instancetypes.Accelerator{{Manufacturer: "AMD", Name: "Radeon Pro V520", Count: 1}}},
+				"inf1.xlarge": {Accelerators: []instancetypes.Accelerator{{Manufacturer: "AWS", Name: "Inferentia", Count: 1}}},
+				"m5.large":    {VCPUs: 2},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create test context: %v", err)
+	}
+	return NewService(scope)
+}
+
+func TestValidateGPU(t *testing.T) {
+	testCases := []struct {
+		name          string
+		instanceType  string
+		gpu           *v1alpha1.GPUConfig
+		expectError   bool
+		expectInvalid bool
+	}{
+		{
+			name:         "no gpu configuration",
+			instanceType: "m5.large",
+		},
+		{
+			name:         "drivers on a gpu instance type",
+			instanceType: "g4dn.xlarge",
+			gpu:          &v1alpha1.GPUConfig{InstallDrivers: true},
+		},
+		{
+			name: "instance type from a launch template",
+			gpu:  &v1alpha1.GPUConfig{InstallDrivers: true},
+		},
+		{
+			name:         "drivers not installed",
+			instanceType: "m5.large",
+			gpu:          &v1alpha1.GPUConfig{DriverVersion: "418"},
+		},
+		{
+			name:          "drivers on an instance type without gpus",
+			instanceType:  "m5.large",
+			gpu:           &v1alpha1.GPUConfig{InstallDrivers: true},
+			expectError:   true,
+			expectInvalid: true,
+		},
+		{
+			name:          "drivers on an instance type with AMD gpus",
+			instanceType:  "g4ad.xlarge",
+			gpu:           &v1alpha1.GPUConfig{InstallDrivers: true},
+			expectError:   true,
+			expectInvalid: true,
+		},
+		{
+			name:          "unknown instance type",
+			instanceType:  "x1.unknown",
+			gpu:           &v1alpha1.GPUConfig{InstallDrivers: true},
+			expectError:   true,
+			expectInvalid: true,
+		},
+		{
+			name:         "instance type not described",
+			instanceType: "x9.huge",
+			gpu:          &v1alpha1.GPUConfig{InstallDrivers: true},
+			expectError:  true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := gpuTestService(t).validateGPU(tc.instanceType, tc.gpu)
+			if tc.expectError && err == nil {
+				t.Fatalf("expected error")
+			}
+			if !tc.expectError && err != nil {
+				t.Fatalf("did not expect error: %v", err)
+			}
+			if tc.expectInvalid != awserrors.IsInvalidConfiguration(err) {
+				t.Fatalf("expected invalid configuration: %v, got: %v", tc.expectInvalid, err)
+			}
+		})
+	}
+}
+
+func TestIsGPUInstanceType(t *testing.T) {
+	s := gpuTestService(t)
+	for instanceType, expected := range map[string]bool{
+		"g4dn.xlarge": true,
+		"g4ad.xlarge": false,
+		"inf1.xlarge": false,
+		"m5.large":    false,
+		"x9.huge":     false,
+		"":            false,
+	} {
+		if gpu := s.isGPUInstanceType(instanceType); gpu != expected {
+			t.Errorf("expected instance type %q to have NVIDIA gpus: %v, got %v", instanceType, expected, gpu)
+		}
+	}
+}
+
+func TestGPUUserData(t *testing.T) {
+	if input := gpuInput(&v1alpha1.GPUConfig{}); input != nil {
+		t.Fatalf("expected no driver installation, got %+v", input)
+	}
+
+	input := gpuInput(&v1alpha1.GPUConfig{InstallDrivers: true})
+	if input == nil || input.DriverVersion != defaultGPUDriverVersion {
+		t.Fatalf("expected driver version %q, got %+v", defaultGPUDriverVersion, input)
+	}
+
+	args := map[string]string{"node-labels": "pool=gpu"}
+	addNodeLabels(args, gpuNodeLabel)
+	if expected := "pool=gpu,nvidia.com/gpu.present=true"; args["node-labels"] != expected {
+		t.Fatalf("expected node labels %q, got %q", expected, args["node-labels"])
+	}
+
+	out, err := userdata.NewNode(&userdata.NodeInput{KubeletExtraArgs: args, GPU: input})
+	if err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+
+	for _, expected := range []string{
+		`"cuda-drivers-580"`,
+		"nvidia-ctk runtime configure --runtime=containerd --set-as-default",
+		`node-labels: "pool=gpu,nvidia.com/gpu.present=true"`,
+	} {
+		if !strings.Contains(out, expected) {
+			t.Fatalf("expected user data to contain %q, got:\n%s", expected, out)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ec2
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"k8s.io/klog"
+)
+
+// hardwareLabelPrefix prefixes the labels describing the hardware of the
+// instance type of a node. It is outside the kubernetes.io and k8s.io
+// namespaces, which kubelets may not label their own nodes with.
+const hardwareLabelPrefix = "aws.cluster.x-k8s.io/"
+
+// invalidLabelValueChars matches the characters label values may not contain.
+var invalidLabelValueChars = regexp.MustCompile(`[^a-z0-9._-]+`)
+
+// hardwareLabels returns the node labels describing the accelerators, NVMe
+// instance store disks and network bandwidth of an instance type, so that
+// workloads and autoscalers can target hardware without labeling nodes by
+// hand. No labels are returned if the instance type cannot be described.
+func (s *Service) hardwareLabels(instanceType string) []string {
+	if instanceType == "" {
+		return nil
+	}
+
+	info, err := s.scope.InstanceTypes.Describe(instanceType)
+	if err != nil {
+		klog.Warningf("Not labeling the nodes of instance type %q with their hardware: %v", instanceType, err)
+		return nil
+	}
+	if info == nil {
+		return nil
+	}
+
+	var labels []string
+	label := func(name, value string) {
+		if value = labelValue(value); value != "" {
+			labels = append(labels, hardwareLabelPrefix+name+"="+value)
+		}
+	}
+
+	// Instance types only have accelerators of a single kind, but the GPUs
+	// come first should there be several.
+	if len(info.Accelerators) > 0 {
+		accelerator := info.Accelerators[0]
+		label("accelerator-manufacturer", accelerator.Manufacturer)
+		label("accelerator-name", accelerator.Name)
+		label("accelerator-count", fmt.Sprint(accelerator.Count))
+	}
+
+	if info.NVMeDisks > 0 {
+		label("nvme-disks", fmt.Sprint(info.NVMeDisks))
+	}
+
+	label("network-performance", info.NetworkPerformance)
+
+	return labels
+}
+
+// labelValue turns a description into a valid label value, e.g.
+// "Up to 10 Gigabit" into "up-to-10-gigabit".
+func labelValue(s string) string {
+	s = invalidLabelValueChars.ReplaceAllString(strings.ToLower(s), "-")
+	if len(s) > 63 {
+		s = s[:63]
+	}
+	return strings.Trim(s, "._-")
+}
@@ -0,0 +1,84 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ec2
+
+import (
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/actuators"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/instancetypes"
+	clusterv1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+)
+
+func TestHardwareLabels(t *testing.T) {
+	testCases := []struct {
+		name         string
+		instanceType string
+		expected     []string
+	}{
+		{
+			name:         "GPU instance type",
+			instanceType: "g4dn.xlarge",
+			expected: []string{
+				"aws.cluster.x-k8s.io/accelerator-manufacturer=nvidia",
+				"aws.cluster.x-k8s.io/accelerator-name=t4",
+				"aws.cluster.x-k8s.io/accelerator-count=1",
+				"aws.cluster.x-k8s.io/nvme-disks=1",
+				"aws.cluster.x-k8s.io/network-performance=up-to-25-gigabit",
+			},
+		},
+		{
+			name:         "instance type without accelerators",
+			instanceType: "m5.large",
+			expected: []string{
+				"aws.cluster.x-k8s.io/network-performance=up-to-10-gigabit",
+			},
+		},
+		{
+			name:         "instance type not described",
+			instanceType: "x9.huge",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			scope, err := actuators.NewScope(actuators.ScopeParams{
+				Cluster: &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"}},
+				InstanceTypes: &fakeInstanceTypes{
+					infos: map[string]*instancetypes.Info{
+						"g4dn.xlarge": {
+							Accelerators:       []instancetypes.Accelerator{{Manufacturer: "NVIDIA", Name: "T4", Count: 1}},
+							NVMeDisks:          1,
+							NetworkPerformance: "Up to 25 Gigabit",
+						},
+						"m5.large": {NetworkPerformance: "Up to 10 Gigabit"},
+					},
+				},
+			})
+			if err != nil {
+				t.Fatalf("Failed to create test context: %v", err)
+			}
+
+			labels := NewService(scope).hardwareLabels(tc.instanceType)
+			if !reflect.DeepEqual(labels, tc.expected) {
+				t.Fatalf("expected labels %v, got %v", tc.expected, labels)
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ec2
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"k8s.io/klog"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/awserrors"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/ssm"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/record"
+)
+
+const (
+	// imageBuildTagKeyPrefix prefixes the tag keys identifying the
+	// Automation executions building an AMI.
+	imageBuildTagKeyPrefix = "cluster-api-provider-aws/image-build/"
+
+	// imageBuildRetryPeriod is how long a failed image build is reported
+	// before another one is started.
+	imageBuildRetryPeriod = time.Hour
+)
+
+// builtImage returns the AMI built for an image lookup by the image build of
+// the cluster. A build is started if there is none yet, and a failed
+// dependency error is returned until it succeeds.
+func (s *Service) builtImage(l v1alpha1.ImageLookup) (string, error) {
+	build := s.scope.ClusterConfig.ImageBuild
+	version := strings.TrimPrefix(l.KubernetesVersion, "v")
+
+	parameters := map[string][]string{}
+	for k, v := range build.Parameters {
+		parameters[k] = []string{v}
+	}
+	parameters["KubernetesVersion"] = []string{version}
+	parameters["BaseOS"] = []string{l.BaseOS}
+	parameters["BaseOSVersion"] = []string{l.BaseOSVersion}
+	parameters["Architecture"] = []string{l.Architecture}
+
+	tagKey := imageBuildTagKey(build.DocumentName, parameters)
+
+	execution, err := s.scope.Automation.LatestAutomationExecution(tagKey)
+	if err != nil {
+		return "", err
+	}
+
+	switch {
+	case execution == nil:
+	case execution.Status == ssm.AutomationStatusSuccess:
+		ids := execution.Outputs[build.ImageIDOutput]
+		if len(ids) == 0 || !strings.HasPrefix(ids[0], "ami-") {
+			return "", awserrors.NewInvalidConfiguration(errors.Errorf("image build %q has no AMI ID in its %q output: %v", execution.ID, build.ImageIDOutput, ids))
+		}
+		klog.V(2).Infof("Using AMI %q built by image build %q", ids[0], execution.ID)
+		return ids[0], nil
+	case execution.Status == ssm.AutomationStatusFailed:
+		if time.Since(execution.EndTime) < imageBuildRetryPeriod {
+			return "", errors.Errorf("image build %q of kubernetes %s failed: %s", execution.ID, version, execution.FailureMessage)
+		}
+	default:
+		return "", awserrors.NewFailedDependency(errors.Errorf("image build %q of kubernetes %s is %s", execution.ID, version, execution.Status))
+	}
+
+	id, err := s.scope.Automation.StartAutomationExecution(build.DocumentName, parameters, tagKey)
+	if err != nil {
+		return "", err
+	}
+
+	klog.V(2).Infof("Started image build %q of Kubernetes %s for %s %s (%s)", id, version, l.BaseOS, l.BaseOSVersion, l.Architecture)
+	record.Eventf(s.scope.Cluster, "StartedImageBuild", "Started image build %q of Kubernetes %s for %s %s (%s)", id, version, l.BaseOS, l.BaseOSVersion, l.Architecture)
+	return "", awserrors.NewFailedDependency(errors.Errorf("image build %q of kubernetes %s started", id, version))
+}
+
+// imageBuildTagKey returns the tag key identifying the builds of an AMI by a
+// runbook with the given parameters.
+func imageBuildTagKey(documentName string, parameters map[string][]string) string {
+	keys := make([]string, 0, len(parameters))
+	for k := range parameters {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	h := sha256.New()
+	fmt.Fprintln(h, documentName)
+	for _, k := range keys {
+		fmt.Fprintln(h, k, strings.Join(parameters[k], ","))
+	}
+
+	return imageBuildTagKeyPrefix + hex.EncodeToString(h.Sum(nil))[:16]
+}
@@ -0,0 +1,156 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ec2
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/actuators"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/awserrors"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/ssm"
+	clusterv1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+)
+
+type fakeAutomation struct {
+	latest  *ssm.AutomationExecution
+	started map[string][]string
+}
+
+func (f *fakeAutomation) StartAutomationExecution(documentName string, parameters map[string][]string, tagKey string) (string, error) {
+	if documentName != "BuildImage" {
+		return "", errors.Errorf("unexpected document %q", documentName)
+	}
+	f.started = parameters
+	return "started", nil
+}
+
+func (f *fakeAutomation) LatestAutomationExecution(tagKey string) (*ssm.AutomationExecution, error) {
+	return f.latest, nil
+}
+
+func TestBuiltImage(t *testing.T) {
+	testCases := []struct {
+		name          string
+		latest        *ssm.AutomationExecution
+		expectedImage string
+		expectStarted bool
+		expectError   func(error) bool
+	}{
+		{
+			name:          "no build",
+			expectStarted: true,
+			expectError:   awserrors.IsFailedDependency,
+		},
+		{
+			name:        "build in progress",
+			latest:      &ssm.AutomationExecution{ID: "1", Status: "InProgress"},
+			expectError: awserrors.IsFailedDependency,
+		},
+		{
+			name: "build succeeded",
+			latest: &ssm.AutomationExecution{
+				ID:      "1",
+				Status:  ssm.AutomationStatusSuccess,
+				Outputs: map[string][]string{"createImage.ImageId": {"ami-built"}},
+			},
+			expectedImage: "ami-built",
+		},
+		{
+			name:        "build without AMI output",
+			latest:      &ssm.AutomationExecution{ID: "1", Status: ssm.AutomationStatusSuccess},
+			expectError: awserrors.IsInvalidConfiguration,
+		},
+		{
+			name:        "build failed recently",
+			latest:      &ssm.AutomationExecution{ID: "1", Status: ssm.AutomationStatusFailed, EndTime: time.Now()},
+			expectError: func(err error) bool { return !awserrors.IsFailedDependency(err) },
+		},
+		{
+			name:          "build failed long ago",
+			latest:        &ssm.AutomationExecution{ID: "1", Status: ssm.AutomationStatusFailed, EndTime: time.Now().Add(-2 * time.Hour)},
+			expectStarted: true,
+			expectError:   awserrors.IsFailedDependency,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			automation := &fakeAutomation{latest: tc.latest}
+			scope, err := actuators.NewScope(actuators.ScopeParams{
+				Cluster:    &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"}},
+				Automation: automation,
+			})
+			if err != nil {
+				t.Fatalf("Failed to create test context: %v", err)
+			}
+
+			scope.ClusterConfig.ImageBuild = &v1alpha1.ImageBuild{
+				DocumentName:  "BuildImage",
+				ImageIDOutput: "createImage.ImageId",
+				Parameters:    map[string]string{"AutomationAssumeRole": "arn:aws:iam::123456789012:role/image-build"},
+			}
+
+			imageID, err := NewService(scope).builtImage(v1alpha1.ImageLookup{
+				BaseOS:            "ubuntu",
+				BaseOSVersion:     "18.04",
+				Architecture:      "x86_64",
+				KubernetesVersion: "v1.13.0",
+			})
+			if tc.expectError == nil && err != nil {
+				t.Fatalf("did not expect error: %v", err)
+			}
+			if tc.expectError != nil && (err == nil || !tc.expectError(err)) {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if imageID != tc.expectedImage {
+				t.Fatalf("expected image %q, got %q", tc.expectedImage, imageID)
+			}
+
+			if tc.expectStarted != (automation.started != nil) {
+				t.Fatalf("expected build started: %v, got parameters %v", tc.expectStarted, automation.started)
+			}
+			if tc.expectStarted {
+				if v := automation.started["KubernetesVersion"]; len(v) != 1 || v[0] != "1.13.0" {
+					t.Errorf("expected the kubernetes version to be passed to the build, got %v", automation.started)
+				}
+				if v := automation.started["AutomationAssumeRole"]; len(v) != 1 {
+					t.Errorf("expected the parameters of the build to be passed, got %v", automation.started)
+				}
+			}
+		})
+	}
+}
+
+func TestImageBuildTagKey(t *testing.T) {
+	a := imageBuildTagKey("BuildImage", map[string][]string{"KubernetesVersion": {"1.13.0"}, "BaseOS": {"ubuntu"}})
+	b := imageBuildTagKey("BuildImage", map[string][]string{"BaseOS": {"ubuntu"}, "KubernetesVersion": {"1.13.0"}})
+	c := imageBuildTagKey("BuildImage", map[string][]string{"BaseOS": {"ubuntu"}, "KubernetesVersion": {"1.13.1"}})
+
+	if a != b {
+		t.Errorf("expected the same tag key for the same parameters, got %q and %q", a, b)
+	}
+	if a == c {
+		t.Errorf("expected different tag keys for different parameters, got %q", a)
+	}
+	if len(a) > 128 {
+		t.Errorf("expected a tag key of at most 128 characters, got %q", a)
+	}
+}
@@ -18,10 +18,13 @@ package ec2
 
 import (
 	"encoding/base64"
+	"strings"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/klog"
 	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
 	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/actuators"
@@ -31,9 +34,37 @@ import (
 	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/certificates"
 	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/userdata"
 	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/tags"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/features"
 	"sigs.k8s.io/cluster-api-provider-aws/pkg/record"
 )
 
+const (
+	// scrubbedUserData replaces the bootstrap user data of an instance once it
+	// has joined the cluster.
+	scrubbedUserData = "#!/usr/bin/env bash\n# user data removed after bootstrap\n"
+
+	// amazonTimeSyncService is the link-local address of the Amazon Time
+	// Sync Service, reachable from every instance without internet access.
+	amazonTimeSyncService = "169.254.169.123"
+
+	// maxTerminateInstancesBatch is how many instances a single call to
+	// TerminateInstances accepts.
+	maxTerminateInstancesBatch = 1000
+
+	// maxDescribeInstancesBatch is how many values a single filter of
+	// DescribeInstances accepts.
+	maxDescribeInstancesBatch = 200
+)
+
+// existingInstanceStates are the states of the instances of machines, which
+// include stopped instances as they are started again rather than replaced.
+var existingInstanceStates = []string{
+	ec2.InstanceStateNamePending,
+	ec2.InstanceStateNameRunning,
+	ec2.InstanceStateNameStopping,
+	ec2.InstanceStateNameStopped,
+}
+
 // InstanceByTags returns the existing instance or nothing if it doesn't exist.
 func (s *Service) InstanceByTags(machine *actuators.MachineScope) (*v1alpha1.Instance, error) {
 	klog.V(2).Infof("Looking for existing instance for machine %q in cluster %q", machine.Name(), s.scope.Name())
@@ -42,11 +73,21 @@ func (s *Service) InstanceByTags(machine *actuators.MachineScope) (*v1alpha1.Ins
 		Filters: []*ec2.Filter{
 			filter.EC2.ClusterOwned(s.scope.Name()),
 			filter.EC2.Name(machine.Name()),
-			filter.EC2.InstanceStates(ec2.InstanceStateNamePending, ec2.InstanceStateNameRunning),
+			filter.EC2.InstanceStates(existingInstanceStates...),
 		},
 	}
 
-	out, err := s.scope.EC2.DescribeInstances(input)
+	var instances []*v1alpha1.Instance
+	err := s.scope.EC2.DescribeInstancesPages(input,
+		func(page *ec2.DescribeInstancesOutput, lastPage bool) bool {
+			for _, res := range page.Reservations {
+				for _, inst := range res.Instances {
+					instances = append(instances, converters.SDKToInstance(inst))
+				}
+			}
+			return true
+		})
+
 	switch {
 	case awserrors.IsNotFound(err):
 		return nil, nil
@@ -54,25 +95,80 @@ func (s *Service) InstanceByTags(machine *actuators.MachineScope) (*v1alpha1.Ins
 		return nil, errors.Wrap(err, "failed to describe instances by tags")
 	}
 
-	// TODO: currently just returns the first matched instance, need to
-	// better rationalize how to find the right instance to return if multiple
-	// match
-	for _, res := range out.Reservations {
-		for _, inst := range res.Instances {
-			return converters.SDKToInstance(inst), nil
+	// Picking one of several instances would leave the others running
+	// unmanaged, so duplicates have to be terminated by hand.
+	if len(instances) > 1 {
+		ids := make([]string, len(instances))
+		for i, instance := range instances {
+			ids[i] = instance.ID
 		}
+		return nil, errors.Errorf("found %d instances for machine %q: %s", len(instances), machine.Name(), strings.Join(ids, ", "))
 	}
 
-	return nil, nil
+	if len(instances) == 0 {
+		return nil, nil
+	}
+
+	return instances[0], nil
 }
 
 // InstanceIfExists returns the existing instance or nothing if it doesn't exist.
 func (s *Service) InstanceIfExists(id string) (*v1alpha1.Instance, error) {
+	return s.instanceIfExists(id, existingInstanceStates)
+}
+
+// DeletedInstanceIfExists returns the instance, including when it is shutting
+// down or terminated, or nothing if it is gone.
+func (s *Service) DeletedInstanceIfExists(id string) (*v1alpha1.Instance, error) {
+	states := append([]string{ec2.InstanceStateNameShuttingDown, ec2.InstanceStateNameTerminated}, existingInstanceStates...)
+	return s.instanceIfExists(id, states)
+}
+
+// InstancesIfExist returns the existing instances among the given ones, by
+// ID, describing them in as few calls as possible.
+func (s *Service) InstancesIfExist(ids []string) (map[string]*v1alpha1.Instance, error) {
+	instances := map[string]*v1alpha1.Instance{}
+	for len(ids) > 0 {
+		batch := ids
+		if len(batch) > maxDescribeInstancesBatch {
+			batch = batch[:maxDescribeInstancesBatch]
+		}
+		ids = ids[len(batch):]
+
+		input := &ec2.DescribeInstancesInput{
+			Filters: []*ec2.Filter{
+				filter.EC2.Cluster(s.scope.Name()),
+				filter.EC2.InstanceIDs(batch...),
+				filter.EC2.InstanceStates(existingInstanceStates...),
+			},
+		}
+
+		err := s.scope.EC2.DescribeInstancesPages(input,
+			func(page *ec2.DescribeInstancesOutput, lastPage bool) bool {
+				for _, res := range page.Reservations {
+					for _, inst := range res.Instances {
+						instances[aws.StringValue(inst.InstanceId)] = converters.SDKToInstance(inst)
+					}
+				}
+				return true
+			})
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to describe instances %v", batch)
+		}
+	}
+
+	return instances, nil
+}
+
+func (s *Service) instanceIfExists(id string, states []string) (*v1alpha1.Instance, error) {
 	klog.V(2).Infof("Looking for instance %q", id)
 
 	input := &ec2.DescribeInstancesInput{
 		InstanceIds: []*string{aws.String(id)},
-		Filters:     []*ec2.Filter{filter.EC2.InstanceStates(ec2.InstanceStateNamePending, ec2.InstanceStateNameRunning)},
+		Filters: []*ec2.Filter{
+			filter.EC2.Cluster(s.scope.Name()),
+			filter.EC2.InstanceStates(states...),
+		},
 	}
 
 	out, err := s.scope.EC2.DescribeInstances(input)
@@ -95,39 +191,218 @@ func (s *Service) createInstance(machine *actuators.MachineScope, bootstrapToken
 	klog.V(2).Infof("Creating a new instance for machine %q", machine.Name())
 
 	input := &v1alpha1.Instance{
-		Type:       machine.MachineConfig.InstanceType,
-		IAMProfile: machine.MachineConfig.IAMInstanceProfile,
+		Type:                 machine.MachineConfig.InstanceType,
+		IAMProfile:           machine.MachineConfig.IAMInstanceProfile,
+		VolumeDeletionPolicy: machine.MachineConfig.VolumeDeletionPolicy,
+		LaunchTemplate:       machine.MachineConfig.LaunchTemplate,
+		RootVolume:           machine.MachineConfig.RootVolume,
+		AdditionalVolumes:    machine.MachineConfig.AdditionalVolumes,
+		Tenancy:              machine.MachineConfig.Tenancy,
+		HostID:               machine.MachineConfig.HostID,
+
+		CapacityReservationID:         machine.MachineConfig.CapacityReservationID,
+		CapacityReservationPreference: machine.MachineConfig.CapacityReservationPreference,
+		CPUOptions:                    machine.MachineConfig.CPUOptions,
+		CreditSpecification:           machine.MachineConfig.CreditSpecification,
+		DetailedMonitoring:            machine.MachineConfig.EnableDetailedMonitoring,
+		MetadataOptions:               machine.MachineConfig.InstanceMetadataOptions,
+		NetworkInterfaceType:          machine.MachineConfig.NetworkInterfaceType,
+		SecondaryPrivateIPCount:       machine.MachineConfig.SecondaryPrivateIPCount,
+		AdditionalNetworkInterfaces:   machine.MachineConfig.AdditionalNetworkInterfaces,
+		ENAExpress:                    machine.MachineConfig.ENAExpress,
+		AssociatePublicIPAddress:      machine.MachineConfig.PublicIP,
+	}
+
+	if lt := input.LaunchTemplate; lt != nil && (lt.ID == nil) == (lt.Name == nil) {
+		return nil, errors.Errorf("launch template for machine %q must specify exactly one of id or name", machine.Name())
+	}
+
+	if err := validateTenancy(input.Tenancy, input.HostID); err != nil {
+		return nil, errors.Wrapf(err, "invalid tenancy for machine %q", machine.Name())
+	}
+
+	if err := validateCapacityReservation(input.CapacityReservationID, input.CapacityReservationPreference); err != nil {
+		return nil, errors.Wrapf(err, "invalid capacity reservation for machine %q", machine.Name())
+	}
+
+	if err := s.validateCPUOptions(input.Type, input.CPUOptions); err != nil {
+		return nil, errors.Wrapf(err, "invalid cpu options for machine %q", machine.Name())
+	}
+
+	if err := s.validateCreditSpecification(input.Type, input.CreditSpecification); err != nil {
+		return nil, errors.Wrapf(err, "invalid credit specification for machine %q", machine.Name())
+	}
+
+	if err := validateMetadataOptions(input.MetadataOptions); err != nil {
+		return nil, errors.Wrapf(err, "invalid instance metadata options for machine %q", machine.Name())
+	}
+
+	if err := s.validateSerialConsole(input.Type, machine.MachineConfig.SerialConsole); err != nil {
+		return nil, errors.Wrapf(err, "invalid serial console for machine %q", machine.Name())
+	}
+
+	if err := s.validateGPU(input.Type, machine.MachineConfig.GPU); err != nil {
+		return nil, errors.Wrapf(err, "invalid gpu configuration for machine %q", machine.Name())
+	}
+
+	if err := s.validateNetworkInterfaceType(machine.Role(), input.Type, input.NetworkInterfaceType, s.scope.ClusterConfig.EnableEFA); err != nil {
+		return nil, errors.Wrapf(err, "invalid network interface type for machine %q", machine.Name())
 	}
 
+	// The control plane machine of a cluster without API server load
+	// balancer is reached at the Elastic IP of the cluster.
+	apiServerElasticIP := s.scope.ClusterConfig.APIServerElasticIP && machine.Role() == "controlplane"
+
+	if err := validateNetworkInterfaces(input.SecondaryPrivateIPCount, input.AdditionalNetworkInterfaces, machine.MachineConfig.ElasticIP || apiServerElasticIP, input.AssociatePublicIPAddress); err != nil {
+		return nil, errors.Wrapf(err, "invalid network interfaces for machine %q", machine.Name())
+	}
+
+	if err := s.validateNetworkCards(input.Type, input.AdditionalNetworkInterfaces, input.ENAExpress); err != nil {
+		return nil, errors.Wrapf(err, "invalid network cards for machine %q", machine.Name())
+	}
+
+	if err := validateFiles(machine.MachineConfig.Files); err != nil {
+		return nil, errors.Wrapf(err, "invalid files for machine %q", machine.Name())
+	}
+
+	if err := validateKubeletReserved(machine.MachineConfig.KubeletReserved); err != nil {
+		return nil, errors.Wrapf(err, "invalid kubelet reservations for machine %q", machine.Name())
+	}
+
+	if err := actuators.ValidateNTPServers(s.scope.ClusterConfig.NTPServers); err != nil {
+		return nil, errors.Wrapf(err, "invalid NTP servers for machine %q", machine.Name())
+	}
+
+	instanceTags, err := machine.InstanceTags()
+	if err != nil {
+		return nil, err
+	}
+
+	// Additional tags are applied at launch along with the cluster tags,
+	// so that the instance and its volumes are never left untagged.
 	input.Tags = tags.Build(tags.BuildParams{
 		ClusterName: s.scope.Name(),
 		Lifecycle:   tags.ResourceLifecycleOwned,
 		Name:        aws.String(machine.Name()),
 		Role:        aws.String(machine.Role()),
+		Additional:  instanceTags,
 	})
 
-	var err error
-	// Pick image from the machine configuration, or use a default one.
-	if machine.MachineConfig.AMI.ID != nil {
-		input.ImageID = *machine.MachineConfig.AMI.ID
-	} else {
-		input.ImageID, err = s.defaultAMILookup("ubuntu", "18.04", machine.Machine.Spec.Versions.Kubelet)
+	// Pick image from the machine configuration, or fall back to the cluster
+	// defaults unless the launch template provides it.
+	input.ImageID, err = s.machineImage(machine, input.LaunchTemplate != nil)
+	if err != nil {
+		return nil, err
+	}
+
+	etcd := machine.MachineConfig.EtcdVolume
+	if err := validateEtcdVolume(machine.Role(), etcd); err != nil {
+		return nil, errors.Wrapf(err, "invalid etcd volume for machine %q", machine.Name())
+	}
+
+	// The volume dedicated to etcd is created at launch along with the additional volumes.
+	if etcd != nil && etcd.Volume != nil {
+		input.AdditionalVolumes = append(append([]v1alpha1.Volume{}, input.AdditionalVolumes...), *etcd.Volume)
+	}
+
+	if err := validateEncryptionKeys(s.scope.Region(), input.RootVolume, input.AdditionalVolumes); err != nil {
+		return nil, errors.Wrapf(err, "invalid volumes for machine %q", machine.Name())
+	}
+
+	for _, v := range input.AdditionalVolumes {
+		if err := validateVolumePerformance(v.Type, v.IOPS, v.Throughput); err != nil {
+			return nil, awserrors.NewInvalidConfiguration(errors.Wrapf(err, "invalid volume %q for machine %q", v.DeviceName, machine.Name()))
+		}
+	}
+
+	// The image of a launch template is only known at launch.
+	if input.ImageID == "" && (s.scope.ClusterConfig.ImageEncryption != nil || input.VolumeDeletionPolicy != nil || input.RootVolume != nil) {
+		return nil, errors.Errorf("machine %q must specify an AMI to use image encryption, a root volume or a volume deletion policy with a launch template", machine.Name())
+	}
+
+	// Use an encrypted copy of the image, if the cluster requires it.
+	if s.scope.ClusterConfig.ImageEncryption != nil {
+		input.ImageID, err = s.encryptedAMILookup(input.ImageID, s.scope.ClusterConfig.ImageEncryption)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.validateImageArchitecture(input.Type, input.ImageID); err != nil {
+		return nil, errors.Wrapf(err, "invalid instance type for machine %q", machine.Name())
+	}
+
+	if err := actuators.ValidateDeletionPolicy(machine.Role(), machine.MachineConfig.DeletionPolicy, false); err != nil {
+		return nil, awserrors.NewInvalidConfiguration(errors.Wrapf(err, "invalid deletion policy for machine %q", machine.Name()))
+	}
+
+	if err := actuators.ValidateScrubUserData(machine.Role(), machine.MachineConfig, false); err != nil {
+		return nil, awserrors.NewInvalidConfiguration(errors.Wrapf(err, "invalid user data scrubbing for machine %q", machine.Name()))
+	}
+
+	if machine.MachineConfig.ElasticIP && machine.Role() != "controlplane" {
+		return nil, awserrors.NewInvalidConfiguration(
+			errors.Errorf("invalid elastic IP for machine %q: only control plane machines support it", machine.Name()),
+		)
+	}
+
+	if machine.MachineConfig.ElasticIP && apiServerElasticIP {
+		return nil, awserrors.NewInvalidConfiguration(
+			errors.Errorf("invalid elastic IP for machine %q: the cluster reaches its API server at an elastic IP already", machine.Name()),
+		)
+	}
+
+	// Pick subnet from the machine configuration, or default to the first private available
+	// in an availability zone without recent launch failures. Machines with an
+	// Elastic IP or requesting a public IP default to public subnets, to be
+	// reachable through it.
+	// Control plane machines are spread across zones if the cluster enforces it.
+	var controlPlaneZones map[string]int
+	if machine.Role() == "controlplane" && s.scope.ClusterConfig.SpreadControlPlane {
+		if err := s.validateSpreadPlacement(machine.MachineConfig.PlacementGroup); err != nil {
+			return nil, errors.Wrapf(err, "invalid placement group for machine %q", machine.Name())
+		}
+
+		controlPlaneZones, err = s.ControlPlaneZones()
 		if err != nil {
 			return nil, err
 		}
 	}
 
-	// Pick subnet from the machine configuration, or default to the first private available.
 	if machine.MachineConfig.Subnet != nil && machine.MachineConfig.Subnet.ID != nil {
 		input.SubnetID = *machine.MachineConfig.Subnet.ID
+		if err := s.validateZoneOffering(input.Type, input.SubnetID); err != nil {
+			return nil, errors.Wrapf(err, "invalid instance type for machine %q", machine.Name())
+		}
+
+		if controlPlaneZones != nil {
+			zone, err := s.subnetZone(input.SubnetID)
+			if err != nil {
+				return nil, err
+			}
+			if err := validateControlPlaneSpread(zone, s.scope.Subnets(), controlPlaneZones); err != nil {
+				return nil, errors.Wrapf(err, "invalid subnet for machine %q", machine.Name())
+			}
+		}
 	} else {
 		sns := s.scope.Subnets().FilterPrivate()
+		if machine.MachineConfig.ElasticIP || apiServerElasticIP || aws.BoolValue(input.AssociatePublicIPAddress) {
+			sns = s.scope.Subnets().FilterPublic()
+		}
 		if len(sns) == 0 {
 			return nil, awserrors.NewFailedDependency(
 				errors.Errorf("failed to run machine %q, no subnets available", machine.Name()),
 			)
 		}
-		input.SubnetID = sns[0].ID
+
+		sns, err = s.offeringSubnets(input.Type, sns)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid instance type for machine %q", machine.Name())
+		}
+		if controlPlaneZones != nil {
+			sns = spreadSubnets(sns, controlPlaneZones)
+		}
+		input.SubnetID = s.healthySubnets(sns)[0].ID
 	}
 
 	if len(s.scope.ClusterConfig.CACertificate) == 0 {
@@ -136,9 +411,9 @@ func (s *Service) createInstance(machine *actuators.MachineScope, bootstrapToken
 		)
 	}
 
-	if s.scope.Network().APIServerELB.DNSName == "" {
+	if s.scope.APIServerEndpoint() == "" {
 		return nil, awserrors.NewFailedDependency(
-			errors.New("failed to run controlplane, APIServer ELB not available"),
+			errors.New("failed to run controlplane, APIServer endpoint not available"),
 		)
 	}
 
@@ -147,6 +422,35 @@ func (s *Service) createInstance(machine *actuators.MachineScope, bootstrapToken
 		return input, err
 	}
 
+	// Check connectivity to the required endpoints before bootstrapping, if enabled.
+	preflight := func(includeAPIServer bool) []string {
+		if !machine.MachineConfig.ConnectivityPreflight {
+			return nil
+		}
+		return s.preflightEndpoints(includeAPIServer)
+	}
+
+	kubeletArgs := kubeletDNSArgs(machine.MachineConfig.KubeletDNS)
+	if s.isGPUInstanceType(input.Type) {
+		addNodeLabels(kubeletArgs, gpuNodeLabel)
+	}
+	if labels := s.hardwareLabels(input.Type); len(labels) > 0 {
+		addNodeLabels(kubeletArgs, labels...)
+	}
+	s.addKubeletReservedArgs(kubeletArgs, input.Type, machine.MachineConfig.KubeletReserved, machine.FeatureEnabled(features.KubeletReservedDefaults))
+
+	switch machine.MachineConfig.HardeningProfile {
+	case "", v1alpha1.HardeningProfileBaseline:
+	default:
+		return nil, errors.Errorf("unknown hardening profile %q for machine %q", machine.MachineConfig.HardeningProfile, machine.Name())
+	}
+
+	// Files are read from their Secrets and ConfigMaps at launch.
+	files, err := filesInput(machine)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read the files of machine %q", machine.Name())
+	}
+
 	// apply values based on the role of the machine
 	switch machine.Role() {
 	case "controlplane":
@@ -167,8 +471,23 @@ func (s *Service) createInstance(machine *actuators.MachineScope, bootstrapToken
 				CAKey:          string(s.scope.ClusterConfig.CAPrivateKey),
 				CACertHash:     caCertHash,
 				BootstrapToken: bootstrapToken,
-				ELBAddress:     s.scope.Network().APIServerELB.DNSName,
+				ELBAddress:     s.scope.APIServerEndpoint(),
 				KubeConfig:     kubeConfig,
+
+				PreflightEndpoints: preflight(true),
+				KubeletExtraArgs:   kubeletArgs,
+				FIPSMode:           machine.MachineConfig.FIPSMode,
+				HardeningProfile:   string(machine.MachineConfig.HardeningProfile),
+				SerialConsole:      serialConsoleInput(machine.MachineConfig.SerialConsole),
+				GPU:                gpuInput(machine.MachineConfig.GPU),
+				EtcdInstanceStore:  etcd != nil && etcd.InstanceStore,
+				EtcdDevice:         etcdDevice(etcd),
+				TransitEncryption:  transitEncryptionInput(s.scope.ClusterConfig.TransitEncryption),
+
+				PreKubeadmCommands:  machine.MachineConfig.PreKubeadmCommands,
+				PostKubeadmCommands: machine.MachineConfig.PostKubeadmCommands,
+				Files:               files,
+				NTPServers:          ntpServers(s.scope.ClusterConfig.NTPServers),
 			})
 			if err != nil {
 				return input, err
@@ -184,12 +503,27 @@ func (s *Service) createInstance(machine *actuators.MachineScope, bootstrapToken
 			userData, err = userdata.NewControlPlane(&userdata.ControlPlaneInput{
 				CACert:            string(s.scope.ClusterConfig.CACertificate),
 				CAKey:             string(s.scope.ClusterConfig.CAPrivateKey),
-				ELBAddress:        s.scope.Network().APIServerELB.DNSName,
+				ELBAddress:        s.scope.APIServerEndpoint(),
 				ClusterName:       s.scope.Name(),
 				PodSubnet:         s.scope.Cluster.Spec.ClusterNetwork.Pods.CIDRBlocks[0],
 				ServiceSubnet:     s.scope.Cluster.Spec.ClusterNetwork.Services.CIDRBlocks[0],
 				ServiceDomain:     s.scope.Cluster.Spec.ClusterNetwork.ServiceDomain,
 				KubernetesVersion: machine.Machine.Spec.Versions.ControlPlane,
+
+				PreflightEndpoints: preflight(false),
+				KubeletExtraArgs:   kubeletArgs,
+				FIPSMode:           machine.MachineConfig.FIPSMode,
+				HardeningProfile:   string(machine.MachineConfig.HardeningProfile),
+				SerialConsole:      serialConsoleInput(machine.MachineConfig.SerialConsole),
+				GPU:                gpuInput(machine.MachineConfig.GPU),
+				EtcdInstanceStore:  etcd != nil && etcd.InstanceStore,
+				EtcdDevice:         etcdDevice(etcd),
+				TransitEncryption:  transitEncryptionInput(s.scope.ClusterConfig.TransitEncryption),
+
+				PreKubeadmCommands:  machine.MachineConfig.PreKubeadmCommands,
+				PostKubeadmCommands: machine.MachineConfig.PostKubeadmCommands,
+				Files:               files,
+				NTPServers:          ntpServers(s.scope.ClusterConfig.NTPServers),
 			})
 
 			if err != nil {
@@ -205,7 +539,19 @@ func (s *Service) createInstance(machine *actuators.MachineScope, bootstrapToken
 		userData, err := userdata.NewNode(&userdata.NodeInput{
 			CACertHash:     caCertHash,
 			BootstrapToken: bootstrapToken,
-			ELBAddress:     s.scope.Network().APIServerELB.DNSName,
+			ELBAddress:     s.scope.APIServerEndpoint(),
+
+			PreflightEndpoints: preflight(true),
+			KubeletExtraArgs:   kubeletArgs,
+			FIPSMode:           machine.MachineConfig.FIPSMode,
+			HardeningProfile:   string(machine.MachineConfig.HardeningProfile),
+			SerialConsole:      serialConsoleInput(machine.MachineConfig.SerialConsole),
+			GPU:                gpuInput(machine.MachineConfig.GPU),
+
+			PreKubeadmCommands:  machine.MachineConfig.PreKubeadmCommands,
+			PostKubeadmCommands: machine.MachineConfig.PostKubeadmCommands,
+			Files:               files,
+			NTPServers:          ntpServers(s.scope.ClusterConfig.NTPServers),
 		})
 
 		if err != nil {
@@ -221,19 +567,152 @@ func (s *Service) createInstance(machine *actuators.MachineScope, bootstrapToken
 	// Pick SSH key, if any.
 	if machine.MachineConfig.KeyName != "" {
 		input.KeyName = aws.String(machine.MachineConfig.KeyName)
-	} else {
+	} else if input.LaunchTemplate == nil {
 		input.KeyName = aws.String(defaultSSHKeyName)
 	}
 
-	out, err := s.runInstance(machine.Role(), input)
+	// The placement group is only created once the machine is known to be valid.
+	input.PlacementGroupName, err = s.reconcilePlacementGroup(machine.MachineConfig.PlacementGroup)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to reconcile placement group for machine %q", machine.Name())
+	}
+
+	protected := machine.Role() == "controlplane" && machine.FeatureEnabled(features.TerminationProtection)
+	out, err := s.runInstance(input, protected)
 	if err != nil {
+		if awserrors.IsZoneLaunchError(errors.Cause(err)) {
+			s.recordZoneLaunchFailure(input.SubnetID)
+		}
 		return nil, err
 	}
 
+	s.recordZoneLaunchSuccess(input.SubnetID)
+	if machine.Role() == "controlplane" {
+		s.forgetControlPlaneZones()
+	}
 	record.Eventf(machine.Machine, "CreatedInstance", "Created new %s instance with id %q", machine.Role(), out.ID)
 	return out, nil
 }
 
+// validateTenancy checks that the tenancy of an instance is known, and that
+// a Dedicated Host is only requested with the host tenancy.
+func validateTenancy(tenancy v1alpha1.Tenancy, hostID string) error {
+	switch tenancy {
+	case "", v1alpha1.TenancyDefault, v1alpha1.TenancyDedicated, v1alpha1.TenancyHost:
+	default:
+		return errors.Errorf("unknown tenancy %q", tenancy)
+	}
+
+	if hostID != "" && tenancy != v1alpha1.TenancyHost {
+		return errors.Errorf("dedicated host %q requires the %q tenancy", hostID, v1alpha1.TenancyHost)
+	}
+
+	return nil
+}
+
+// validateCapacityReservation checks that an instance either targets a
+// Capacity Reservation or sets a known Capacity Reservation preference.
+func validateCapacityReservation(id string, preference v1alpha1.CapacityReservationPreference) error {
+	switch preference {
+	case "", v1alpha1.CapacityReservationPreferenceOpen, v1alpha1.CapacityReservationPreferenceNone:
+	default:
+		return errors.Errorf("unknown capacity reservation preference %q", preference)
+	}
+
+	if id != "" && preference != "" {
+		return errors.Errorf("capacity reservation %q cannot be combined with a capacity reservation preference", id)
+	}
+
+	return nil
+}
+
+// validateEtcdVolume checks that a volume dedicated to etcd is only requested
+// for control plane machines, and is either the instance store or an EBS volume.
+func validateEtcdVolume(role string, etcd *v1alpha1.EtcdVolume) error {
+	if etcd == nil {
+		return nil
+	}
+
+	if role != "controlplane" {
+		return errors.Errorf("etcd only runs on control plane machines, not on %s machines", role)
+	}
+
+	if etcd.InstanceStore == (etcd.Volume != nil) {
+		return errors.New("exactly one of instanceStore or volume must be specified")
+	}
+
+	if etcd.Volume != nil && etcd.Volume.DeviceName == "" {
+		return errors.New("the device name of the volume must be specified")
+	}
+
+	return nil
+}
+
+// etcdDevice returns the device name of the EBS volume dedicated to etcd, if any.
+func etcdDevice(etcd *v1alpha1.EtcdVolume) string {
+	if etcd == nil || etcd.Volume == nil {
+		return ""
+	}
+	return etcd.Volume.DeviceName
+}
+
+// transitEncryptionInput returns the encryption in transit enforced by the user
+// data of a control plane instance.
+func transitEncryptionInput(encryption *v1alpha1.TransitEncryption) *userdata.TransitEncryptionInput {
+	if encryption == nil {
+		return nil
+	}
+
+	return &userdata.TransitEncryptionInput{
+		KubeletServingCertificates: encryption.KubeletServingCertificates,
+		EtcdCipherSuites:           strings.Join(encryption.EtcdCipherSuites, ","),
+	}
+}
+
+// ntpServers returns the NTP servers of the machines of a cluster, the Amazon
+// Time Sync Service unless others are configured.
+func ntpServers(servers []string) []string {
+	if len(servers) == 0 {
+		return []string{amazonTimeSyncService}
+	}
+	return servers
+}
+
+// kubeletDNSArgs returns the kubelet flags for the given DNS configuration.
+func kubeletDNSArgs(dns *v1alpha1.KubeletDNS) map[string]string {
+	args := map[string]string{}
+	if dns == nil {
+		return args
+	}
+
+	if len(dns.ClusterDNS) > 0 {
+		args["cluster-dns"] = strings.Join(dns.ClusterDNS, ",")
+	}
+
+	if dns.ResolvConf != "" {
+		args["resolv-conf"] = dns.ResolvConf
+	}
+
+	return args
+}
+
+// SetTerminationProtection enables or disables the termination protection
+// of an EC2 instance, which prevents it from being terminated through the
+// console or the API.
+func (s *Service) SetTerminationProtection(instanceID string, enabled bool) error {
+	input := &ec2.ModifyInstanceAttributeInput{
+		InstanceId:            aws.String(instanceID),
+		DisableApiTermination: &ec2.AttributeBooleanValue{Value: aws.Bool(enabled)},
+	}
+
+	if _, err := s.scope.EC2.ModifyInstanceAttribute(input); err != nil {
+		return errors.Wrapf(err, "failed to set termination protection of instance %q to %t", instanceID, enabled)
+	}
+
+	klog.V(2).Infof("Set termination protection of instance %q to %t", instanceID, enabled)
+	return nil
+}
+
 // TerminateInstance terminates an EC2 instance.
 // Returns nil on success, error in all other cases.
 func (s *Service) TerminateInstance(instanceID string) error {
@@ -252,6 +731,130 @@ func (s *Service) TerminateInstance(instanceID string) error {
 	return nil
 }
 
+// TerminateInstances terminates EC2 instances, with as few calls as the
+// API allows.
+func (s *Service) TerminateInstances(instanceIDs []string) error {
+	for len(instanceIDs) > 0 {
+		batch := instanceIDs
+		if len(batch) > maxTerminateInstancesBatch {
+			batch = batch[:maxTerminateInstancesBatch]
+		}
+		instanceIDs = instanceIDs[len(batch):]
+
+		input := &ec2.TerminateInstancesInput{
+			InstanceIds: aws.StringSlice(batch),
+		}
+
+		if _, err := s.scope.EC2.TerminateInstances(input); err != nil {
+			return errors.Wrapf(err, "failed to terminate instances %v", batch)
+		}
+
+		klog.V(2).Infof("Terminated instances %v", batch)
+		for _, id := range batch {
+			record.Eventf(s.scope.Cluster, "DeletedInstance", "Terminated instance %q", id)
+		}
+	}
+
+	return nil
+}
+
+// RebootInstance reboots an EC2 instance.
+func (s *Service) RebootInstance(instanceID string) error {
+	input := &ec2.RebootInstancesInput{
+		InstanceIds: aws.StringSlice([]string{instanceID}),
+	}
+
+	if _, err := s.scope.EC2.RebootInstances(input); err != nil {
+		return errors.Wrapf(err, "failed to reboot instance with id %q", instanceID)
+	}
+
+	klog.V(2).Infof("Rebooted instance with id %q", instanceID)
+	return nil
+}
+
+// StopInstance stops an EC2 instance, which keeps its volumes.
+func (s *Service) StopInstance(instanceID string) error {
+	input := &ec2.StopInstancesInput{
+		InstanceIds: aws.StringSlice([]string{instanceID}),
+	}
+
+	if _, err := s.scope.EC2.StopInstances(input); err != nil {
+		return errors.Wrapf(err, "failed to stop instance with id %q", instanceID)
+	}
+
+	klog.V(2).Infof("Stopped instance with id %q", instanceID)
+	return nil
+}
+
+// StartInstance starts a stopped EC2 instance.
+func (s *Service) StartInstance(instanceID string) error {
+	input := &ec2.StartInstancesInput{
+		InstanceIds: aws.StringSlice([]string{instanceID}),
+	}
+
+	if _, err := s.scope.EC2.StartInstances(input); err != nil {
+		return errors.Wrapf(err, "failed to start instance with id %q", instanceID)
+	}
+
+	klog.V(2).Infof("Started instance with id %q", instanceID)
+	return nil
+}
+
+// DeleteStoppedInstances terminates the stopped instances owned by the
+// cluster, such as the instances parked by deleted machines, and waits for
+// them to be terminated, as they would otherwise prevent its placement
+// groups and network from being deleted.
+func (s *Service) DeleteStoppedInstances() error {
+	input := &ec2.DescribeInstancesInput{
+		Filters: []*ec2.Filter{
+			filter.EC2.ClusterOwned(s.scope.Name()),
+			filter.EC2.InstanceStates(ec2.InstanceStateNameStopping, ec2.InstanceStateNameStopped, ec2.InstanceStateNameShuttingDown),
+		},
+	}
+
+	// Instances already shutting down, e.g. terminated by a previous
+	// attempt, are only waited for.
+	var stopped, all []string
+	err := s.scope.EC2.DescribeInstancesPages(input,
+		func(page *ec2.DescribeInstancesOutput, lastPage bool) bool {
+			for _, res := range page.Reservations {
+				for _, inst := range res.Instances {
+					id := aws.StringValue(inst.InstanceId)
+					all = append(all, id)
+					if inst.State == nil || aws.StringValue(inst.State.Name) != ec2.InstanceStateNameShuttingDown {
+						stopped = append(stopped, id)
+					}
+				}
+			}
+			return true
+		})
+	if err != nil {
+		return errors.Wrap(err, "failed to describe stopped instances")
+	}
+
+	if len(all) == 0 {
+		return nil
+	}
+
+	// Parked control plane instances keep their termination protection.
+	for _, id := range stopped {
+		if err := s.SetTerminationProtection(id, false); err != nil {
+			return err
+		}
+	}
+
+	if err := s.TerminateInstances(stopped); err != nil {
+		return err
+	}
+
+	klog.V(2).Infof("Waiting for stopped instances %v to terminate", all)
+	if err := s.scope.EC2.WaitUntilInstanceTerminated(&ec2.DescribeInstancesInput{InstanceIds: aws.StringSlice(all)}); err != nil {
+		return errors.Wrapf(err, "failed to wait for the termination of stopped instances %v", all)
+	}
+
+	return nil
+}
+
 // TerminateInstanceAndWait terminates and waits
 // for an EC2 instance to terminate.
 func (s *Service) TerminateInstanceAndWait(instanceID string) error {
@@ -312,13 +915,23 @@ func (s *Service) CreateOrGetMachine(machine *actuators.MachineScope, bootstrapT
 	if err != nil && !awserrors.IsNotFound(err) {
 		return nil, errors.Wrapf(err, "failed to query machine %q instance by tags", machine.Name())
 	} else if err == nil && instance != nil {
+		// The instance was parked by a deleted machine of the same name.
+		if instance.State == v1alpha1.InstanceStateStopped {
+			if err := s.StartInstance(instance.ID); err != nil {
+				return nil, err
+			}
+			instance.State = v1alpha1.InstanceStatePending
+			record.Eventf(s.scope.Cluster, "StartedInstance", "Started stopped instance %q for machine %q", instance.ID, machine.Name())
+		}
 		return instance, nil
 	}
 
 	return s.createInstance(machine, bootstrapToken, kubeConfig)
 }
 
-func (s *Service) runInstance(role string, i *v1alpha1.Instance) (*v1alpha1.Instance, error) {
+// runInstance launches an instance, protected against terminations through
+// the console or the API if requested.
+func (s *Service) runInstance(i *v1alpha1.Instance, protected bool) (*v1alpha1.Instance, error) {
 	input := &ec2.RunInstancesInput{
 		InstanceType: aws.String(i.Type),
 		SubnetId:     aws.String(i.SubnetID),
@@ -330,6 +943,23 @@ func (s *Service) runInstance(role string, i *v1alpha1.Instance) (*v1alpha1.Inst
 		UserData:     i.UserData,
 	}
 
+	// The instance type and image are left to the launch template, if not set.
+	if i.LaunchTemplate != nil {
+		input.LaunchTemplate = &ec2.LaunchTemplateSpecification{
+			LaunchTemplateId:   i.LaunchTemplate.ID,
+			LaunchTemplateName: i.LaunchTemplate.Name,
+			Version:            i.LaunchTemplate.Version,
+		}
+
+		if i.Type == "" {
+			input.InstanceType = nil
+		}
+
+		if i.ImageID == "" {
+			input.ImageId = nil
+		}
+	}
+
 	if i.UserData != nil {
 		input.UserData = aws.String(base64.StdEncoding.EncodeToString([]byte(*i.UserData)))
 	}
@@ -338,26 +968,128 @@ func (s *Service) runInstance(role string, i *v1alpha1.Instance) (*v1alpha1.Inst
 		input.SecurityGroupIds = aws.StringSlice(i.SecurityGroupIDs)
 	}
 
+	if i.PlacementGroupName != "" || i.Tenancy != "" || i.HostID != "" {
+		input.Placement = &ec2.Placement{}
+		if i.PlacementGroupName != "" {
+			input.Placement.GroupName = aws.String(i.PlacementGroupName)
+		}
+		if i.Tenancy != "" {
+			input.Placement.Tenancy = aws.String(string(i.Tenancy))
+		}
+		if i.HostID != "" {
+			input.Placement.HostId = aws.String(i.HostID)
+		}
+	}
+
+	if i.CapacityReservationID != "" {
+		input.CapacityReservationSpecification = &ec2.CapacityReservationSpecification{
+			CapacityReservationTarget: &ec2.CapacityReservationTarget{
+				CapacityReservationId: aws.String(i.CapacityReservationID),
+			},
+		}
+	} else if i.CapacityReservationPreference != "" {
+		input.CapacityReservationSpecification = &ec2.CapacityReservationSpecification{
+			CapacityReservationPreference: aws.String(string(i.CapacityReservationPreference)),
+		}
+	}
+
+	if i.CPUOptions != nil {
+		input.CpuOptions = &ec2.CpuOptionsRequest{
+			CoreCount:      aws.Int64(i.CPUOptions.CoreCount),
+			ThreadsPerCore: aws.Int64(i.CPUOptions.ThreadsPerCore),
+		}
+	}
+
+	// The protection against accidental terminations is disabled right
+	// before the machine is deliberately deleted.
+	if protected {
+		input.DisableApiTermination = aws.Bool(true)
+	}
+
+	if i.DetailedMonitoring {
+		input.Monitoring = &ec2.RunInstancesMonitoringEnabled{Enabled: aws.Bool(true)}
+	}
+
+	if i.CreditSpecification != "" {
+		input.CreditSpecification = &ec2.CreditSpecificationRequest{
+			CpuCredits: aws.String(string(i.CreditSpecification)),
+		}
+	}
+
 	if i.IAMProfile != "" {
 		input.IamInstanceProfile = &ec2.IamInstanceProfileSpecification{
 			Name: aws.String(i.IAMProfile),
 		}
 	}
 
+	if i.VolumeDeletionPolicy != nil || i.RootVolume != nil {
+		mappings, err := s.blockDeviceMappings(i.ImageID, i.VolumeDeletionPolicy, i.RootVolume)
+		if err != nil {
+			return nil, err
+		}
+		input.BlockDeviceMappings = mappings
+	}
+
+	input.BlockDeviceMappings = append(input.BlockDeviceMappings, additionalVolumeMappings(i.AdditionalVolumes, i.VolumeDeletionPolicy)...)
+
+	if i.SecondaryPrivateIPCount > 0 || len(i.AdditionalNetworkInterfaces) > 0 {
+		additionalNetworkInterfaces(input, i.SecondaryPrivateIPCount, i.AdditionalNetworkInterfaces)
+	}
+
+	if i.AssociatePublicIPAddress != nil {
+		associatePublicIPAddress(input, aws.BoolValue(i.AssociatePublicIPAddress))
+	}
+
 	if len(i.Tags) > 0 {
-		spec := &ec2.TagSpecification{ResourceType: aws.String(ec2.ResourceTypeInstance)}
-		for key, value := range i.Tags {
-			spec.Tags = append(spec.Tags, &ec2.Tag{
-				Key:   aws.String(key),
-				Value: aws.String(value),
+		// Volumes created at launch carry the same tags as the instance,
+		// so that storage costs can be attributed to the cluster and machine.
+		// So do the additional network interfaces, to be found by their machine.
+		resourceTypes := []string{ec2.ResourceTypeInstance, ec2.ResourceTypeVolume}
+		if len(i.AdditionalNetworkInterfaces) > 0 {
+			resourceTypes = append(resourceTypes, ec2.ResourceTypeNetworkInterface)
+		}
+		for _, resourceType := range resourceTypes {
+			input.TagSpecifications = append(input.TagSpecifications, &ec2.TagSpecification{
+				ResourceType: aws.String(resourceType),
+				Tags:         converters.MapToTags(i.Tags),
 			})
 		}
+	}
+
+	var opts []request.Option
+	if i.MetadataOptions != nil {
+		opts = append(opts, withMetadataOptions(i.MetadataOptions))
+	}
+
+	if i.NetworkInterfaceType == v1alpha1.NetworkInterfaceTypeEFA {
+		if len(input.NetworkInterfaces) == 0 {
+			primaryNetworkInterface(input)
+		}
+		opts = append(opts, withEFA())
+	}
 
-		input.TagSpecifications = append(input.TagSpecifications, spec)
+	if networkCards(i.AdditionalNetworkInterfaces) > 1 {
+		opts = append(opts, withNetworkCards(i.AdditionalNetworkInterfaces))
 	}
 
-	out, err := s.scope.EC2.RunInstances(input)
+	if i.ENAExpress != nil {
+		if len(input.NetworkInterfaces) == 0 {
+			primaryNetworkInterface(input)
+		}
+		opts = append(opts, withENAExpress(i.ENAExpress, len(input.NetworkInterfaces)))
+	}
+
+	var out *ec2.Reservation
+	var err error
+	if len(opts) > 0 {
+		out, err = s.scope.EC2.RunInstancesWithContext(aws.BackgroundContext(), input, opts...)
+	} else {
+		out, err = s.scope.EC2.RunInstances(input)
+	}
 	if err != nil {
+		if code, _ := awserrors.Code(err); code == awserrors.AMIIDNotFound {
+			return nil, awserrors.NewInvalidConfiguration(errors.Wrapf(err, "failed to run instance: %v", i))
+		}
 		return nil, errors.Wrapf(err, "failed to run instance: %v", i)
 	}
 
@@ -365,10 +1097,137 @@ func (s *Service) runInstance(role string, i *v1alpha1.Instance) (*v1alpha1.Inst
 		return nil, errors.Errorf("no instance returned for reservation %v", out.GoString())
 	}
 
-	s.scope.EC2.WaitUntilInstanceRunning(&ec2.DescribeInstancesInput{InstanceIds: []*string{out.Instances[0].InstanceId}})
+	// An instance whose volumes cannot be encrypted with the requested keys
+	// is terminated right after launch.
+	err = s.scope.EC2.WaitUntilInstanceRunning(&ec2.DescribeInstancesInput{InstanceIds: []*string{out.Instances[0].InstanceId}})
+	if err != nil && usesEncryptionKeys(i.RootVolume, i.AdditionalVolumes) {
+		return nil, s.encryptedLaunchFailure(aws.StringValue(out.Instances[0].InstanceId), err)
+	}
+
 	return converters.SDKToInstance(out.Instances[0]), nil
 }
 
+// blockDeviceMappings returns the block device mappings overriding the
+// DeleteOnTermination setting of the EBS volumes defined by the given AMI,
+// and the configuration of its root volume.
+func (s *Service) blockDeviceMappings(imageID string, policy *v1alpha1.VolumeDeletionPolicy, root *v1alpha1.RootVolume) ([]*ec2.BlockDeviceMapping, error) {
+	out, err := s.scope.EC2.DescribeImages(&ec2.DescribeImagesInput{
+		ImageIds: aws.StringSlice([]string{imageID}),
+	})
+	if err != nil {
+		if code, _ := awserrors.Code(err); code == awserrors.AMIIDNotFound {
+			return nil, awserrors.NewInvalidConfiguration(errors.Wrapf(err, "ami %q not found", imageID))
+		}
+		return nil, errors.Wrapf(err, "failed to describe ami %q", imageID)
+	}
+
+	if len(out.Images) == 0 {
+		return nil, awserrors.NewInvalidConfiguration(errors.Errorf("ami %q not found", imageID))
+	}
+
+	image := out.Images[0]
+
+	var mappings []*ec2.BlockDeviceMapping
+	for _, bdm := range image.BlockDeviceMappings {
+		if bdm.Ebs == nil {
+			continue
+		}
+
+		isRoot := aws.StringValue(bdm.DeviceName) == aws.StringValue(image.RootDeviceName)
+
+		ebs := &ec2.EbsBlockDevice{}
+		if policy != nil {
+			ebs.DeleteOnTermination = policy.NonRoot
+			if isRoot {
+				ebs.DeleteOnTermination = policy.Root
+			}
+		}
+
+		if isRoot && root != nil {
+			if root.Size != 0 && root.Size < aws.Int64Value(bdm.Ebs.VolumeSize) {
+				return nil, awserrors.NewInvalidConfiguration(
+					errors.Errorf("root volume of %d GiB is smaller than the %d GiB root snapshot of ami %q", root.Size, aws.Int64Value(bdm.Ebs.VolumeSize), imageID),
+				)
+			}
+
+			volumeType := root.Type
+			if volumeType == "" {
+				volumeType = aws.StringValue(bdm.Ebs.VolumeType)
+			}
+			if err := validateVolumePerformance(volumeType, root.IOPS, root.Throughput); err != nil {
+				return nil, awserrors.NewInvalidConfiguration(errors.Wrap(err, "invalid root volume"))
+			}
+
+			// The root volume defaults to the size of the root snapshot of the AMI.
+			if root.Size != 0 {
+				ebs.VolumeSize = aws.Int64(root.Size)
+			}
+			if root.Type != "" {
+				ebs.VolumeType = aws.String(root.Type)
+			}
+			if root.IOPS != 0 {
+				ebs.Iops = aws.Int64(root.IOPS)
+			}
+			if root.Encrypted {
+				ebs.Encrypted = aws.Bool(true)
+			}
+			if root.EncryptionKeyID != "" {
+				ebs.KmsKeyId = aws.String(root.EncryptionKeyID)
+			}
+		}
+
+		if ebs.DeleteOnTermination == nil && (!isRoot || root == nil) {
+			continue
+		}
+
+		mappings = append(mappings, &ec2.BlockDeviceMapping{
+			DeviceName: bdm.DeviceName,
+			Ebs:        ebs,
+		})
+	}
+
+	return mappings, nil
+}
+
+// additionalVolumeMappings returns the block device mappings creating the given
+// EBS volumes at launch. The volumes are subject to the non root volume
+// deletion policy, if any.
+func additionalVolumeMappings(volumes []v1alpha1.Volume, policy *v1alpha1.VolumeDeletionPolicy) []*ec2.BlockDeviceMapping {
+	var mappings []*ec2.BlockDeviceMapping
+	for _, v := range volumes {
+		ebs := &ec2.EbsBlockDevice{
+			VolumeSize: aws.Int64(v.Size),
+		}
+
+		if v.Type != "" {
+			ebs.VolumeType = aws.String(v.Type)
+		}
+
+		if v.IOPS != 0 {
+			ebs.Iops = aws.Int64(v.IOPS)
+		}
+
+		if v.Encrypted {
+			ebs.Encrypted = aws.Bool(true)
+		}
+
+		if v.EncryptionKeyID != "" {
+			ebs.KmsKeyId = aws.String(v.EncryptionKeyID)
+		}
+
+		if policy != nil {
+			ebs.DeleteOnTermination = policy.NonRoot
+		}
+
+		mappings = append(mappings, &ec2.BlockDeviceMapping{
+			DeviceName: aws.String(v.DeviceName),
+			Ebs:        ebs,
+		})
+	}
+
+	return mappings
+}
+
 // UpdateInstanceSecurityGroups modifies the security groups of the given
 // EC2 instance.
 func (s *Service) UpdateInstanceSecurityGroups(instanceID string, ids []string) error {
@@ -386,6 +1245,82 @@ func (s *Service) UpdateInstanceSecurityGroups(instanceID string, ids []string)
 	return nil
 }
 
+// ScrubInstanceUserData overwrites the user data of an EC2 instance so that
+// bootstrap secrets are no longer retrievable from the instance metadata
+// service or the EC2 API. User data can only be modified while an instance
+// is stopped, so the instance is stopped, updated and started again.
+func (s *Service) ScrubInstanceUserData(instanceID string) error {
+	klog.V(2).Infof("Attempting to scrub user data on instance %q", instanceID)
+
+	ids := aws.StringSlice([]string{instanceID})
+
+	if _, err := s.scope.EC2.StopInstances(&ec2.StopInstancesInput{InstanceIds: ids}); err != nil {
+		return errors.Wrapf(err, "failed to stop instance %q", instanceID)
+	}
+
+	if err := s.scope.EC2.WaitUntilInstanceStopped(&ec2.DescribeInstancesInput{InstanceIds: ids}); err != nil {
+		return errors.Wrapf(err, "failed to wait for instance %q to stop", instanceID)
+	}
+
+	input := &ec2.ModifyInstanceAttributeInput{
+		InstanceId: aws.String(instanceID),
+		UserData:   &ec2.BlobAttributeValue{Value: []byte(scrubbedUserData)},
+	}
+
+	if _, err := s.scope.EC2.ModifyInstanceAttribute(input); err != nil {
+		return errors.Wrapf(err, "failed to modify instance %q user data", instanceID)
+	}
+
+	if _, err := s.scope.EC2.StartInstances(&ec2.StartInstancesInput{InstanceIds: ids}); err != nil {
+		return errors.Wrapf(err, "failed to start instance %q", instanceID)
+	}
+
+	if err := s.scope.EC2.WaitUntilInstanceRunning(&ec2.DescribeInstancesInput{InstanceIds: ids}); err != nil {
+		return errors.Wrapf(err, "failed to wait for instance %q to start", instanceID)
+	}
+
+	record.Eventf(s.scope.Cluster, "ScrubbedInstanceUserData", "Scrubbed user data of instance %q", instanceID)
+	return nil
+}
+
+// InstanceEvents returns the maintenance events that AWS has scheduled for an EC2 instance.
+// Events that have already completed or were canceled are left out.
+func (s *Service) InstanceEvents(instanceID string) ([]v1alpha1.InstanceEvent, error) {
+	input := &ec2.DescribeInstanceStatusInput{
+		InstanceIds:         aws.StringSlice([]string{instanceID}),
+		IncludeAllInstances: aws.Bool(true),
+	}
+
+	out, err := s.scope.EC2.DescribeInstanceStatus(input)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to describe status of instance %q", instanceID)
+	}
+
+	var events []v1alpha1.InstanceEvent
+	for _, status := range out.InstanceStatuses {
+		for _, e := range status.Events {
+			description := aws.StringValue(e.Description)
+			if strings.HasPrefix(description, "[Completed]") || strings.HasPrefix(description, "[Canceled]") {
+				continue
+			}
+
+			event := v1alpha1.InstanceEvent{
+				Code:        aws.StringValue(e.Code),
+				Description: description,
+			}
+
+			if e.NotBefore != nil {
+				notBefore := metav1.NewTime(*e.NotBefore)
+				event.NotBefore = &notBefore
+			}
+
+			events = append(events, event)
+		}
+	}
+
+	return events, nil
+}
+
 // UpdateResourceTags updates the tags for an instance.
 // This will be called if there is anything to create (update) or delete.
 // We may not always have to perform each action, so we check what we're
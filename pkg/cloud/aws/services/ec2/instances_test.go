@@ -17,6 +17,9 @@ limitations under the License.
 package ec2
 
 import (
+	"fmt"
+	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/aws/aws-sdk-go/aws"
@@ -28,9 +31,11 @@ import (
 
 	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
 	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/actuators"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/filter"
 	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/awserrors"
 	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/ec2/mock_ec2iface"
 	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/elb/mock_elbiface"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/userdata"
 )
 
 func TestInstanceIfExists(t *testing.T) {
@@ -50,9 +55,13 @@ func TestInstanceIfExists(t *testing.T) {
 				m.DescribeInstances(gomock.Eq(&ec2.DescribeInstancesInput{
 					InstanceIds: []*string{aws.String("hello")},
 					Filters: []*ec2.Filter{
+						{
+							Name:   aws.String("tag-key"),
+							Values: []*string{aws.String("kubernetes.io/cluster/test-cluster")},
+						},
 						{
 							Name:   aws.String("instance-state-name"),
-							Values: []*string{aws.String("pending"), aws.String("running")},
+							Values: []*string{aws.String("pending"), aws.String("running"), aws.String("stopping"), aws.String("stopped")},
 						},
 					},
 				})).
@@ -75,9 +84,13 @@ func TestInstanceIfExists(t *testing.T) {
 				m.DescribeInstances(gomock.Eq(&ec2.DescribeInstancesInput{
 					InstanceIds: []*string{aws.String("id-1")},
 					Filters: []*ec2.Filter{
+						{
+							Name:   aws.String("tag-key"),
+							Values: []*string{aws.String("kubernetes.io/cluster/test-cluster")},
+						},
 						{
 							Name:   aws.String("instance-state-name"),
-							Values: []*string{aws.String("pending"), aws.String("running")},
+							Values: []*string{aws.String("pending"), aws.String("running"), aws.String("stopping"), aws.String("stopped")},
 						},
 					},
 				})).
@@ -114,6 +127,35 @@ func TestInstanceIfExists(t *testing.T) {
 				}
 			},
 		},
+		{
+			name:       "instance of another cluster",
+			instanceID: "id-other",
+			expect: func(m *mock_ec2iface.MockEC2APIMockRecorder) {
+				m.DescribeInstances(gomock.Eq(&ec2.DescribeInstancesInput{
+					InstanceIds: []*string{aws.String("id-other")},
+					Filters: []*ec2.Filter{
+						{
+							Name:   aws.String("tag-key"),
+							Values: []*string{aws.String("kubernetes.io/cluster/test-cluster")},
+						},
+						{
+							Name:   aws.String("instance-state-name"),
+							Values: []*string{aws.String("pending"), aws.String("running"), aws.String("stopping"), aws.String("stopped")},
+						},
+					},
+				})).
+					Return(&ec2.DescribeInstancesOutput{}, nil)
+			},
+			check: func(instance *v1alpha1.Instance, err error) {
+				if err != nil {
+					t.Fatalf("did not expect error: %v", err)
+				}
+
+				if instance != nil {
+					t.Fatalf("expected the instance of another cluster to be ignored, got: %+v", instance)
+				}
+			},
+		},
 		{
 			name:       "error describing instances",
 			instanceID: "one",
@@ -121,9 +163,13 @@ func TestInstanceIfExists(t *testing.T) {
 				m.DescribeInstances(&ec2.DescribeInstancesInput{
 					InstanceIds: []*string{aws.String("one")},
 					Filters: []*ec2.Filter{
+						{
+							Name:   aws.String("tag-key"),
+							Values: []*string{aws.String("kubernetes.io/cluster/test-cluster")},
+						},
 						{
 							Name:   aws.String("instance-state-name"),
-							Values: []*string{aws.String("pending"), aws.String("running")},
+							Values: []*string{aws.String("pending"), aws.String("running"), aws.String("stopping"), aws.String("stopped")},
 						},
 					},
 				}).
@@ -143,7 +189,9 @@ func TestInstanceIfExists(t *testing.T) {
 			elbMock := mock_elbiface.NewMockELBAPI(mockCtrl)
 
 			scope, err := actuators.NewScope(actuators.ScopeParams{
-				Cluster: &clusterv1.Cluster{},
+				Cluster: &clusterv1.Cluster{
+					ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"},
+				},
 				AWSClients: actuators.AWSClients{
 					EC2: ec2Mock,
 					ELB: elbMock,
@@ -163,6 +211,130 @@ func TestInstanceIfExists(t *testing.T) {
 	}
 }
 
+func TestInstancesIfExist(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	ids := make([]string, maxDescribeInstancesBatch+1)
+	for i := range ids {
+		ids[i] = fmt.Sprintf("i-%d", i)
+	}
+
+	m := mock_ec2iface.NewMockEC2API(mockCtrl)
+	expect := func(batch []string, existing ...string) *gomock.Call {
+		return m.EXPECT().
+			DescribeInstancesPages(&ec2.DescribeInstancesInput{
+				Filters: []*ec2.Filter{
+					filter.EC2.Cluster("test-cluster"),
+					filter.EC2.InstanceIDs(batch...),
+					filter.EC2.InstanceStates(existingInstanceStates...),
+				},
+			}, gomock.Any()).
+			Do(func(_ *ec2.DescribeInstancesInput, fn func(*ec2.DescribeInstancesOutput, bool) bool) {
+				var instances []*ec2.Instance
+				for _, id := range existing {
+					instances = append(instances, &ec2.Instance{
+						InstanceId: aws.String(id),
+						State:      &ec2.InstanceState{Name: aws.String(ec2.InstanceStateNameRunning)},
+					})
+				}
+				fn(&ec2.DescribeInstancesOutput{Reservations: []*ec2.Reservation{{Instances: instances}}}, true)
+			}).
+			Return(nil)
+	}
+	gomock.InOrder(
+		expect(ids[:maxDescribeInstancesBatch], "i-0"),
+		expect(ids[maxDescribeInstancesBatch:], ids[maxDescribeInstancesBatch]),
+	)
+
+	scope, err := actuators.NewScope(actuators.ScopeParams{
+		Cluster:    &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"}},
+		AWSClients: actuators.AWSClients{EC2: m},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create test context: %v", err)
+	}
+
+	instances, err := NewService(scope).InstancesIfExist(ids)
+	if err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+
+	if len(instances) != 2 || instances["i-0"] == nil || instances[ids[maxDescribeInstancesBatch]] == nil {
+		t.Fatalf("expected the two existing instances, got %v", instances)
+	}
+}
+
+func TestInstanceByTags(t *testing.T) {
+	running := &ec2.InstanceState{Name: aws.String(ec2.InstanceStateNameRunning)}
+
+	testCases := []struct {
+		name        string
+		instances   []*ec2.Instance
+		expectID    string
+		expectError bool
+	}{
+		{
+			name: "no instance",
+		},
+		{
+			name:      "one instance",
+			instances: []*ec2.Instance{{InstanceId: aws.String("i-1"), State: running}},
+			expectID:  "i-1",
+		},
+		{
+			name:        "duplicate instances",
+			instances:   []*ec2.Instance{{InstanceId: aws.String("i-1"), State: running}, {InstanceId: aws.String("i-2"), State: running}},
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+
+			ec2Mock := mock_ec2iface.NewMockEC2API(mockCtrl)
+			ec2Mock.EXPECT().
+				DescribeInstancesPages(gomock.AssignableToTypeOf(&ec2.DescribeInstancesInput{}), gomock.Any()).
+				Do(func(_ *ec2.DescribeInstancesInput, fn func(*ec2.DescribeInstancesOutput, bool) bool) {
+					fn(&ec2.DescribeInstancesOutput{
+						Reservations: []*ec2.Reservation{{Instances: tc.instances}},
+					}, true)
+				}).
+				Return(nil)
+
+			scope, err := actuators.NewMachineScope(actuators.MachineScopeParams{
+				Cluster:    &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"}},
+				Machine:    &clusterv1.Machine{ObjectMeta: metav1.ObjectMeta{Name: "node-0"}},
+				AWSClients: actuators.AWSClients{EC2: ec2Mock},
+			})
+			if err != nil {
+				t.Fatalf("Failed to create test context: %v", err)
+			}
+
+			instance, err := NewService(scope.Scope).InstanceByTags(scope)
+			if tc.expectError {
+				if err == nil {
+					t.Fatalf("expected an error for duplicate instances")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("did not expect error: %v", err)
+			}
+
+			var id string
+			if instance != nil {
+				id = instance.ID
+			}
+			if id != tc.expectID {
+				t.Fatalf("expected instance %q, got %q", tc.expectID, id)
+			}
+		})
+	}
+}
+
 func TestTerminateInstance(t *testing.T) {
 	mockCtrl := gomock.NewController(t)
 	defer mockCtrl.Finish()
@@ -233,6 +405,111 @@ func TestTerminateInstance(t *testing.T) {
 	}
 }
 
+func TestTerminateInstances(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	ids := make([]string, maxTerminateInstancesBatch+1)
+	for i := range ids {
+		ids[i] = fmt.Sprintf("i-%d", i)
+	}
+
+	ec2Mock := mock_ec2iface.NewMockEC2API(mockCtrl)
+	gomock.InOrder(
+		ec2Mock.EXPECT().
+			TerminateInstances(&ec2.TerminateInstancesInput{InstanceIds: aws.StringSlice(ids[:maxTerminateInstancesBatch])}).
+			Return(&ec2.TerminateInstancesOutput{}, nil),
+		ec2Mock.EXPECT().
+			TerminateInstances(&ec2.TerminateInstancesInput{InstanceIds: aws.StringSlice(ids[maxTerminateInstancesBatch:])}).
+			Return(&ec2.TerminateInstancesOutput{}, nil),
+	)
+
+	scope, err := actuators.NewScope(actuators.ScopeParams{
+		Cluster:    &clusterv1.Cluster{},
+		AWSClients: actuators.AWSClients{EC2: ec2Mock},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create test context: %v", err)
+	}
+
+	if err := NewService(scope).TerminateInstances(ids); err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+}
+
+func TestSetTerminationProtection(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	ec2Mock := mock_ec2iface.NewMockEC2API(mockCtrl)
+	ec2Mock.EXPECT().
+		ModifyInstanceAttribute(&ec2.ModifyInstanceAttributeInput{
+			InstanceId:            aws.String("i-1"),
+			DisableApiTermination: &ec2.AttributeBooleanValue{Value: aws.Bool(false)},
+		}).
+		Return(&ec2.ModifyInstanceAttributeOutput{}, nil)
+
+	scope, err := actuators.NewScope(actuators.ScopeParams{
+		Cluster: &clusterv1.Cluster{},
+		AWSClients: actuators.AWSClients{
+			EC2: ec2Mock,
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create test context: %v", err)
+	}
+
+	if err := NewService(scope).SetTerminationProtection("i-1", false); err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+}
+
+func TestDeleteStoppedInstances(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	ec2Mock := mock_ec2iface.NewMockEC2API(mockCtrl)
+	gomock.InOrder(
+		ec2Mock.EXPECT().
+			DescribeInstancesPages(gomock.AssignableToTypeOf(&ec2.DescribeInstancesInput{}), gomock.Any()).
+			Do(func(input *ec2.DescribeInstancesInput, fn func(*ec2.DescribeInstancesOutput, bool) bool) {
+				fn(&ec2.DescribeInstancesOutput{
+					Reservations: []*ec2.Reservation{{
+						Instances: []*ec2.Instance{
+							{InstanceId: aws.String("i-stopped"), State: &ec2.InstanceState{Name: aws.String(ec2.InstanceStateNameStopped)}},
+							{InstanceId: aws.String("i-shutting-down"), State: &ec2.InstanceState{Name: aws.String(ec2.InstanceStateNameShuttingDown)}},
+						},
+					}},
+				}, true)
+			}).
+			Return(nil),
+		ec2Mock.EXPECT().
+			ModifyInstanceAttribute(&ec2.ModifyInstanceAttributeInput{
+				InstanceId:            aws.String("i-stopped"),
+				DisableApiTermination: &ec2.AttributeBooleanValue{Value: aws.Bool(false)},
+			}).
+			Return(&ec2.ModifyInstanceAttributeOutput{}, nil),
+		ec2Mock.EXPECT().
+			TerminateInstances(&ec2.TerminateInstancesInput{InstanceIds: aws.StringSlice([]string{"i-stopped"})}).
+			Return(&ec2.TerminateInstancesOutput{}, nil),
+		ec2Mock.EXPECT().
+			WaitUntilInstanceTerminated(&ec2.DescribeInstancesInput{InstanceIds: aws.StringSlice([]string{"i-stopped", "i-shutting-down"})}).
+			Return(nil),
+	)
+
+	scope, err := actuators.NewScope(actuators.ScopeParams{
+		Cluster:    &clusterv1.Cluster{},
+		AWSClients: actuators.AWSClients{EC2: ec2Mock},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create test context: %v", err)
+	}
+
+	if err := NewService(scope).DeleteStoppedInstances(); err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+}
+
 func TestCreateInstance(t *testing.T) {
 	testCaCert := []byte(`
 -----BEGIN CERTIFICATE-----
@@ -360,6 +637,48 @@ vuO9LYxDXLVY9F7W4ccyCqe27Cj1xyAvdZxwhITrib8Wg5CMqoRpqTw5V3+TpA==
 				}
 			},
 		},
+		{
+			name: "control plane with stop deletion policy",
+			machine: clusterv1.Machine{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{"set": "controlplane"},
+				},
+			},
+			machineConfig: &v1alpha1.AWSMachineProviderSpec{
+				AMI: v1alpha1.AWSResourceReference{
+					ID: aws.String("abc"),
+				},
+				InstanceType:   "m5.large",
+				DeletionPolicy: v1alpha1.InstanceDeletionPolicyStop,
+			},
+			clusterStatus: &v1alpha1.AWSClusterProviderStatus{},
+			clusterConfig: &v1alpha1.AWSClusterProviderSpec{
+				CACertificate: testCaCert,
+				CAPrivateKey:  []byte("y"),
+			},
+			cluster: clusterv1.Cluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "test1",
+				},
+			},
+			expect: func(m *mock_ec2iface.MockEC2APIMockRecorder) {
+				m.
+					DescribeImages(gomock.Any()).
+					Return(&ec2.DescribeImagesOutput{
+						Images: []*ec2.Image{
+							{
+								Name: aws.String("ami-1"),
+							},
+						},
+					}, nil).
+					AnyTimes()
+			},
+			check: func(instance *v1alpha1.Instance, err error) {
+				if !awserrors.IsInvalidConfiguration(errors.Cause(err)) {
+					t.Fatalf("expected an invalid configuration error, got: %v", err)
+				}
+			},
+		},
 	}
 
 	for _, tc := range testcases {
@@ -371,19 +690,16 @@ vuO9LYxDXLVY9F7W4ccyCqe27Cj1xyAvdZxwhITrib8Wg5CMqoRpqTw5V3+TpA==
 
 			scope, err := actuators.NewMachineScope(actuators.MachineScopeParams{
 				Cluster: &tc.cluster,
-				Machine: &clusterv1.Machine{
-					ObjectMeta: metav1.ObjectMeta{
-						Labels: map[string]string{
-							"set": "node",
-						},
-					},
-				},
+				Machine: &tc.machine,
 				AWSClients: actuators.AWSClients{
 					EC2: ec2Mock,
 					ELB: elbMock,
 				},
 			})
 
+			if tc.machineConfig != nil {
+				scope.MachineConfig = tc.machineConfig
+			}
 			scope.Scope.ClusterConfig = tc.clusterConfig
 			scope.Scope.ClusterStatus = tc.clusterStatus
 
@@ -399,3 +715,547 @@ vuO9LYxDXLVY9F7W4ccyCqe27Cj1xyAvdZxwhITrib8Wg5CMqoRpqTw5V3+TpA==
 		})
 	}
 }
+
+func TestVolumeDeletionMappings(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	ec2Mock := mock_ec2iface.NewMockEC2API(mockCtrl)
+	elbMock := mock_elbiface.NewMockELBAPI(mockCtrl)
+
+	scope, err := actuators.NewScope(actuators.ScopeParams{
+		Cluster: &clusterv1.Cluster{},
+		AWSClients: actuators.AWSClients{
+			EC2: ec2Mock,
+			ELB: elbMock,
+		},
+	})
+
+	if err != nil {
+		t.Fatalf("Failed to create test context: %v", err)
+	}
+
+	ec2Mock.EXPECT().
+		DescribeImages(gomock.Eq(&ec2.DescribeImagesInput{
+			ImageIds: []*string{aws.String("ami-1")},
+		})).
+		Return(&ec2.DescribeImagesOutput{
+			Images: []*ec2.Image{
+				{
+					ImageId:        aws.String("ami-1"),
+					RootDeviceName: aws.String("/dev/sda1"),
+					BlockDeviceMappings: []*ec2.BlockDeviceMapping{
+						{
+							DeviceName: aws.String("/dev/sda1"),
+							Ebs:        &ec2.EbsBlockDevice{DeleteOnTermination: aws.Bool(true)},
+						},
+						{
+							DeviceName: aws.String("/dev/sdb"),
+							Ebs:        &ec2.EbsBlockDevice{DeleteOnTermination: aws.Bool(true)},
+						},
+						{
+							DeviceName:  aws.String("/dev/sdc"),
+							VirtualName: aws.String("ephemeral0"),
+						},
+					},
+				},
+			},
+		}, nil)
+
+	s := NewService(scope)
+	mappings, err := s.blockDeviceMappings("ami-1", &v1alpha1.VolumeDeletionPolicy{
+		NonRoot: aws.Bool(false),
+	}, nil)
+	if err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+
+	expected := []*ec2.BlockDeviceMapping{
+		{
+			DeviceName: aws.String("/dev/sdb"),
+			Ebs:        &ec2.EbsBlockDevice{DeleteOnTermination: aws.Bool(false)},
+		},
+	}
+
+	if !reflect.DeepEqual(mappings, expected) {
+		t.Fatalf("expected mappings %v, got %v", expected, mappings)
+	}
+}
+
+func TestRootVolumeMappings(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	ec2Mock := mock_ec2iface.NewMockEC2API(mockCtrl)
+
+	scope, err := actuators.NewScope(actuators.ScopeParams{
+		Cluster: &clusterv1.Cluster{},
+		AWSClients: actuators.AWSClients{
+			EC2: ec2Mock,
+		},
+	})
+
+	if err != nil {
+		t.Fatalf("Failed to create test context: %v", err)
+	}
+
+	ec2Mock.EXPECT().
+		DescribeImages(gomock.Eq(&ec2.DescribeImagesInput{
+			ImageIds: []*string{aws.String("ami-1")},
+		})).
+		Return(&ec2.DescribeImagesOutput{
+			Images: []*ec2.Image{
+				{
+					ImageId:        aws.String("ami-1"),
+					RootDeviceName: aws.String("/dev/sda1"),
+					BlockDeviceMappings: []*ec2.BlockDeviceMapping{
+						{
+							DeviceName: aws.String("/dev/sda1"),
+							Ebs:        &ec2.EbsBlockDevice{VolumeSize: aws.Int64(8)},
+						},
+						{
+							DeviceName: aws.String("/dev/sdb"),
+							Ebs:        &ec2.EbsBlockDevice{VolumeSize: aws.Int64(8)},
+						},
+					},
+				},
+			},
+		}, nil).
+		Times(4)
+
+	s := NewService(scope)
+	mappings, err := s.blockDeviceMappings("ami-1", nil, &v1alpha1.RootVolume{
+		Size:      100,
+		Type:      "io1",
+		IOPS:      1000,
+		Encrypted: true,
+	})
+	if err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+
+	expected := []*ec2.BlockDeviceMapping{
+		{
+			DeviceName: aws.String("/dev/sda1"),
+			Ebs: &ec2.EbsBlockDevice{
+				VolumeSize: aws.Int64(100),
+				VolumeType: aws.String("io1"),
+				Iops:       aws.Int64(1000),
+				Encrypted:  aws.Bool(true),
+			},
+		},
+	}
+
+	if !reflect.DeepEqual(mappings, expected) {
+		t.Fatalf("expected mappings %v, got %v", expected, mappings)
+	}
+
+	// The root volume defaults to the size of the root snapshot.
+	mappings, err = s.blockDeviceMappings("ami-1", nil, &v1alpha1.RootVolume{Type: "gp3", Throughput: 250})
+	if err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+
+	expected = []*ec2.BlockDeviceMapping{
+		{
+			DeviceName: aws.String("/dev/sda1"),
+			Ebs:        &ec2.EbsBlockDevice{VolumeType: aws.String("gp3")},
+		},
+	}
+
+	if !reflect.DeepEqual(mappings, expected) {
+		t.Fatalf("expected mappings %v, got %v", expected, mappings)
+	}
+
+	if _, err := s.blockDeviceMappings("ami-1", nil, &v1alpha1.RootVolume{Size: 4}); !awserrors.IsInvalidConfiguration(err) {
+		t.Fatalf("expected an invalid configuration error for a root volume smaller than the snapshot, got %v", err)
+	}
+
+	if _, err := s.blockDeviceMappings("ami-1", nil, &v1alpha1.RootVolume{Throughput: 250}); !awserrors.IsInvalidConfiguration(err) {
+		t.Fatalf("expected an invalid configuration error for the throughput of a gp2 root volume, got %v", err)
+	}
+}
+
+func TestAdditionalVolumeMappings(t *testing.T) {
+	volumes := []v1alpha1.Volume{
+		{DeviceName: "/dev/sdb", Size: 100, Type: "gp2", Encrypted: true},
+		{DeviceName: "/dev/sdc", Size: 500, Type: "io1", IOPS: 2000},
+	}
+
+	mappings := additionalVolumeMappings(volumes, &v1alpha1.VolumeDeletionPolicy{NonRoot: aws.Bool(false)})
+
+	expected := []*ec2.BlockDeviceMapping{
+		{
+			DeviceName: aws.String("/dev/sdb"),
+			Ebs: &ec2.EbsBlockDevice{
+				VolumeSize:          aws.Int64(100),
+				VolumeType:          aws.String("gp2"),
+				Encrypted:           aws.Bool(true),
+				DeleteOnTermination: aws.Bool(false),
+			},
+		},
+		{
+			DeviceName: aws.String("/dev/sdc"),
+			Ebs: &ec2.EbsBlockDevice{
+				VolumeSize:          aws.Int64(500),
+				VolumeType:          aws.String("io1"),
+				Iops:                aws.Int64(2000),
+				DeleteOnTermination: aws.Bool(false),
+			},
+		},
+	}
+
+	if !reflect.DeepEqual(mappings, expected) {
+		t.Fatalf("expected mappings %v, got %v", expected, mappings)
+	}
+}
+
+func TestRunInstanceFromLaunchTemplate(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	ec2Mock := mock_ec2iface.NewMockEC2API(mockCtrl)
+
+	scope, err := actuators.NewScope(actuators.ScopeParams{
+		Cluster: &clusterv1.Cluster{},
+		AWSClients: actuators.AWSClients{
+			EC2: ec2Mock,
+		},
+	})
+
+	if err != nil {
+		t.Fatalf("Failed to create test context: %v", err)
+	}
+
+	ec2Mock.EXPECT().
+		RunInstances(gomock.AssignableToTypeOf(&ec2.RunInstancesInput{})).
+		Do(func(input *ec2.RunInstancesInput) {
+			expected := &ec2.LaunchTemplateSpecification{
+				LaunchTemplateName: aws.String("approved"),
+				Version:            aws.String("3"),
+			}
+
+			if !reflect.DeepEqual(input.LaunchTemplate, expected) {
+				t.Fatalf("expected launch template %v, got %v", expected, input.LaunchTemplate)
+			}
+
+			// Left to the launch template.
+			if input.ImageId != nil || input.InstanceType != nil || input.KeyName != nil {
+				t.Fatalf("expected the image, instance type and key to be taken from the template, got %v", input)
+			}
+
+			if aws.StringValue(input.SubnetId) != "subnet-1" {
+				t.Fatalf("expected subnet %q, got %q", "subnet-1", aws.StringValue(input.SubnetId))
+			}
+
+			if input.DisableApiTermination != nil {
+				t.Fatalf("expected an unprotected instance, got %v", input.DisableApiTermination)
+			}
+		}).
+		Return(&ec2.Reservation{
+			Instances: []*ec2.Instance{
+				{
+					State:        &ec2.InstanceState{Name: aws.String(ec2.InstanceStateNamePending)},
+					InstanceId:   aws.String("i-1"),
+					InstanceType: aws.String("m5.large"),
+					SubnetId:     aws.String("subnet-1"),
+					ImageId:      aws.String("ami-1"),
+				},
+			},
+		}, nil)
+	ec2Mock.EXPECT().
+		WaitUntilInstanceRunning(gomock.Any()).
+		Return(nil)
+
+	s := NewService(scope)
+	instance, err := s.runInstance(&v1alpha1.Instance{
+		SubnetID: "subnet-1",
+		LaunchTemplate: &v1alpha1.LaunchTemplateReference{
+			Name:    aws.String("approved"),
+			Version: aws.String("3"),
+		},
+	}, false)
+	if err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+
+	if instance.ImageID != "ami-1" {
+		t.Fatalf("expected the image of the template to be recorded, got %q", instance.ImageID)
+	}
+}
+
+func TestRunInstanceOnDedicatedHost(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	ec2Mock := mock_ec2iface.NewMockEC2API(mockCtrl)
+
+	scope, err := actuators.NewScope(actuators.ScopeParams{
+		Cluster: &clusterv1.Cluster{},
+		AWSClients: actuators.AWSClients{
+			EC2: ec2Mock,
+		},
+	})
+
+	if err != nil {
+		t.Fatalf("Failed to create test context: %v", err)
+	}
+
+	ec2Mock.EXPECT().
+		RunInstances(gomock.AssignableToTypeOf(&ec2.RunInstancesInput{})).
+		Do(func(input *ec2.RunInstancesInput) {
+			expected := &ec2.Placement{
+				GroupName: aws.String("test-cluster-placement-cluster"),
+				Tenancy:   aws.String("host"),
+				HostId:    aws.String("h-1"),
+			}
+
+			if !reflect.DeepEqual(input.Placement, expected) {
+				t.Fatalf("expected placement %v, got %v", expected, input.Placement)
+			}
+
+			if !aws.BoolValue(input.DisableApiTermination) {
+				t.Fatalf("expected the instance to be protected against terminations")
+			}
+		}).
+		Return(&ec2.Reservation{
+			Instances: []*ec2.Instance{
+				{
+					State:        &ec2.InstanceState{Name: aws.String(ec2.InstanceStateNamePending)},
+					InstanceId:   aws.String("i-1"),
+					InstanceType: aws.String("m5.large"),
+					SubnetId:     aws.String("subnet-1"),
+					ImageId:      aws.String("ami-1"),
+					Placement: &ec2.Placement{
+						GroupName: aws.String("test-cluster-placement-cluster"),
+						Tenancy:   aws.String("host"),
+						HostId:    aws.String("h-1"),
+					},
+				},
+			},
+		}, nil)
+	ec2Mock.EXPECT().
+		WaitUntilInstanceRunning(gomock.Any()).
+		Return(nil)
+
+	s := NewService(scope)
+	instance, err := s.runInstance(&v1alpha1.Instance{
+		Type:               "m5.large",
+		SubnetID:           "subnet-1",
+		ImageID:            "ami-1",
+		PlacementGroupName: "test-cluster-placement-cluster",
+		Tenancy:            v1alpha1.TenancyHost,
+		HostID:             "h-1",
+	}, true)
+	if err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+
+	if instance.Tenancy != v1alpha1.TenancyHost || instance.HostID != "h-1" {
+		t.Fatalf("expected the tenancy and host to be recorded, got %q and %q", instance.Tenancy, instance.HostID)
+	}
+}
+
+func TestValidateCapacityReservation(t *testing.T) {
+	testCases := []struct {
+		name        string
+		id          string
+		preference  v1alpha1.CapacityReservationPreference
+		expectError bool
+	}{
+		{
+			name: "no capacity reservation",
+		},
+		{
+			name: "targeted capacity reservation",
+			id:   "cr-1",
+		},
+		{
+			name:       "no capacity reservations",
+			preference: v1alpha1.CapacityReservationPreferenceNone,
+		},
+		{
+			name:        "targeted capacity reservation with a preference",
+			id:          "cr-1",
+			preference:  v1alpha1.CapacityReservationPreferenceOpen,
+			expectError: true,
+		},
+		{
+			name:        "unknown preference",
+			preference:  "targeted",
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateCapacityReservation(tc.id, tc.preference)
+			if tc.expectError && err == nil {
+				t.Fatalf("expected error")
+			}
+			if !tc.expectError && err != nil {
+				t.Fatalf("did not expect error: %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateTenancy(t *testing.T) {
+	testCases := []struct {
+		name        string
+		tenancy     v1alpha1.Tenancy
+		hostID      string
+		expectError bool
+	}{
+		{
+			name: "default tenancy",
+		},
+		{
+			name:    "dedicated instance",
+			tenancy: v1alpha1.TenancyDedicated,
+		},
+		{
+			name:    "any dedicated host",
+			tenancy: v1alpha1.TenancyHost,
+		},
+		{
+			name:    "specific dedicated host",
+			tenancy: v1alpha1.TenancyHost,
+			hostID:  "h-1",
+		},
+		{
+			name:        "dedicated host without host tenancy",
+			tenancy:     v1alpha1.TenancyDedicated,
+			hostID:      "h-1",
+			expectError: true,
+		},
+		{
+			name:        "unknown tenancy",
+			tenancy:     "shared",
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateTenancy(tc.tenancy, tc.hostID)
+			if tc.expectError && err == nil {
+				t.Fatalf("expected error")
+			}
+			if !tc.expectError && err != nil {
+				t.Fatalf("did not expect error: %v", err)
+			}
+		})
+	}
+}
+
+func TestKubeletDNSArgs(t *testing.T) {
+	testCases := []struct {
+		name   string
+		dns    *v1alpha1.KubeletDNS
+		expect map[string]string
+	}{
+		{
+			name:   "no dns configuration",
+			expect: map[string]string{},
+		},
+		{
+			name: "node-local cache and custom resolv.conf",
+			dns: &v1alpha1.KubeletDNS{
+				ClusterDNS: []string{"169.254.20.10", "10.96.0.10"},
+				ResolvConf: "/run/systemd/resolve/resolv.conf",
+			},
+			expect: map[string]string{
+				"cluster-dns": "169.254.20.10,10.96.0.10",
+				"resolv-conf": "/run/systemd/resolve/resolv.conf",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := kubeletDNSArgs(tc.dns); !reflect.DeepEqual(got, tc.expect) {
+				t.Fatalf("expected kubelet args %v, got %v", tc.expect, got)
+			}
+		})
+	}
+}
+
+func TestValidateEtcdVolume(t *testing.T) {
+	testCases := []struct {
+		name        string
+		role        string
+		etcd        *v1alpha1.EtcdVolume
+		expectError bool
+	}{
+		{
+			name: "no etcd volume",
+			role: "node",
+		},
+		{
+			name: "instance store",
+			role: "controlplane",
+			etcd: &v1alpha1.EtcdVolume{InstanceStore: true},
+		},
+		{
+			name: "ebs volume",
+			role: "controlplane",
+			etcd: &v1alpha1.EtcdVolume{Volume: &v1alpha1.Volume{DeviceName: "/dev/sdf", Size: 20, Type: "io1", IOPS: 1000}},
+		},
+		{
+			name:        "node machine",
+			role:        "node",
+			etcd:        &v1alpha1.EtcdVolume{InstanceStore: true},
+			expectError: true,
+		},
+		{
+			name:        "both instance store and ebs volume",
+			role:        "controlplane",
+			etcd:        &v1alpha1.EtcdVolume{InstanceStore: true, Volume: &v1alpha1.Volume{DeviceName: "/dev/sdf", Size: 20}},
+			expectError: true,
+		},
+		{
+			name:        "ebs volume without device name",
+			role:        "controlplane",
+			etcd:        &v1alpha1.EtcdVolume{Volume: &v1alpha1.Volume{Size: 20}},
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateEtcdVolume(tc.role, tc.etcd)
+			if tc.expectError && err == nil {
+				t.Fatalf("expected error")
+			}
+			if !tc.expectError && err != nil {
+				t.Fatalf("did not expect error: %v", err)
+			}
+		})
+	}
+}
+
+func TestFIPSUserData(t *testing.T) {
+	for _, fips := range []bool{false, true} {
+		out, err := userdata.NewNode(&userdata.NodeInput{FIPSMode: fips})
+		if err != nil {
+			t.Fatalf("did not expect error: %v", err)
+		}
+
+		for _, expected := range []string{
+			"fips-mode-setup --enable",
+			"ExecStart=/var/lib/cluster-api/bootstrap.sh",
+			"DefaultEnvironment=AWS_USE_FIPS_ENDPOINT=true",
+		} {
+			if strings.Contains(out, expected) != fips {
+				t.Fatalf("expected user data with FIPS mode %v to contain %q: %v, got:\n%s", fips, expected, fips, out)
+			}
+		}
+
+		// FIPS mode is enabled before anything else, since the instance
+		// reboots into it.
+		if fips && strings.Index(out, "fips-mode-setup") > strings.Index(out, "kubeadm join") {
+			t.Fatalf("expected FIPS mode to be enabled before kubeadm runs, got:\n%s", out)
+		}
+	}
+}
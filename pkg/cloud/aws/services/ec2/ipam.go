@@ -0,0 +1,117 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ec2
+
+import (
+	"github.com/pkg/errors"
+	"k8s.io/klog"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/ipam"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/record"
+)
+
+// ipamCluster returns the cluster CIDR blocks are allocated for.
+func (s *Service) ipamCluster() ipam.Cluster {
+	return ipam.Cluster{
+		Name:      s.scope.Name(),
+		Namespace: s.scope.Namespace(),
+		Region:    s.scope.Region(),
+	}
+}
+
+// allocateSubnetCidrBlocks allocates with the IPAM of the cluster the CIDR
+// blocks of the subnets which have none, and records them in the cluster
+// status before the subnets are created.
+func (s *Service) allocateSubnetCidrBlocks(subnets v1alpha1.Subnets) error {
+	var used []string
+	var pending v1alpha1.Subnets
+	for _, sn := range subnets {
+		if sn.CidrBlock != "" {
+			used = append(used, sn.CidrBlock)
+		} else {
+			pending = append(pending, sn)
+		}
+	}
+
+	if len(pending) == 0 {
+		return nil
+	}
+
+	if s.scope.IPAM == nil {
+		return errors.New("subnets without a cidr block require ipam to be configured for the cluster")
+	}
+
+	blocks, err := s.scope.IPAM.AllocateSubnets(s.ipamCluster(), s.scope.VPC().CidrBlock, used, len(pending))
+	if err != nil {
+		return errors.Wrap(err, "failed to allocate subnet cidr blocks")
+	}
+
+	for i, sn := range pending {
+		sn.CidrBlock = blocks[i]
+	}
+
+	klog.V(2).Infof("Allocated subnet cidr blocks %v for cluster %q", blocks, s.scope.Name())
+
+	s.scope.Network().Subnets = subnets
+	if err := s.scope.Store(); err != nil {
+		return errors.Wrapf(err, "failed to record subnet cidr blocks %v", blocks)
+	}
+	return nil
+}
+
+// allocatePodCidrBlock allocates with the IPAM of the cluster the pod CIDR
+// block of a cluster network which has none. The block is recorded in the
+// cluster spec, from which the control plane machines configure kubeadm, and
+// in a tag of the VPC.
+func (s *Service) allocatePodCidrBlock() error {
+	pods := &s.scope.Cluster.Spec.ClusterNetwork.Pods
+	if s.scope.IPAM == nil || len(pods.CIDRBlocks) > 0 {
+		return nil
+	}
+
+	cidr, err := s.scope.IPAM.AllocatePods(s.ipamCluster())
+	if err != nil {
+		return errors.Wrap(err, "failed to allocate pod cidr block")
+	}
+	if cidr == "" {
+		return nil
+	}
+
+	pods.CIDRBlocks = []string{cidr}
+	if err := s.scope.Store(); err != nil {
+		return errors.Wrapf(err, "failed to record pod cidr block %q", cidr)
+	}
+
+	klog.V(2).Infof("Allocated pod cidr block %q for cluster %q", cidr, s.scope.Name())
+	record.Eventf(s.scope.Cluster, "AllocatedPodCidrBlock", "Allocated pod cidr block %q", cidr)
+	return nil
+}
+
+// releaseCidrBlocks releases the CIDR blocks allocated to the cluster once
+// its network is deleted.
+func (s *Service) releaseCidrBlocks() error {
+	if s.scope.IPAM == nil {
+		return nil
+	}
+
+	if err := s.scope.IPAM.Release(s.ipamCluster()); err != nil {
+		return errors.Wrap(err, "failed to release cidr blocks")
+	}
+
+	record.Eventf(s.scope.Cluster, "ReleasedCidrBlocks", "Released the network cidr blocks of the cluster")
+	return nil
+}
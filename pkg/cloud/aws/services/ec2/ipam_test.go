@@ -0,0 +1,117 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ec2
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/actuators"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/ipam"
+	clusterv1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+)
+
+func TestAllocateSubnetCidrBlocks(t *testing.T) {
+	allocator, err := ipam.NewPoolAllocator(&v1alpha1.IPAMPool{CidrBlock: "10.0.0.0/8"}, nil)
+	if err != nil {
+		t.Fatalf("failed to create allocator: %v", err)
+	}
+
+	scope, err := actuators.NewScope(actuators.ScopeParams{
+		Cluster: &clusterv1.Cluster{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"},
+		},
+		IPAM: allocator,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create test context: %v", err)
+	}
+	scope.VPC().CidrBlock = "10.5.0.0/16"
+
+	// Only the subnets without a cidr block are allocated one.
+	subnets := v1alpha1.Subnets{
+		{ID: "subnet-1", CidrBlock: "10.5.0.0/24"},
+		{IsPublic: false},
+		{IsPublic: true},
+	}
+	if err := NewService(scope).allocateSubnetCidrBlocks(subnets); err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+
+	for i, expected := range []string{"10.5.0.0/24", "10.5.1.0/24", "10.5.2.0/24"} {
+		if subnets[i].CidrBlock != expected {
+			t.Fatalf("expected subnet %d cidr block %q, got %q", i, expected, subnets[i].CidrBlock)
+		}
+	}
+
+	// Without IPAM, subnets must specify their cidr block.
+	scope.IPAM = nil
+	if err := NewService(scope).allocateSubnetCidrBlocks(v1alpha1.Subnets{{}}); err == nil {
+		t.Fatalf("expected error without ipam")
+	}
+}
+
+func TestAllocatePodCidrBlock(t *testing.T) {
+	// The region has no VPC.
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<DescribeVpcsResponse><requestId>1</requestId><vpcSet></vpcSet></DescribeVpcsResponse>`))
+	}))
+	defer server.Close()
+
+	sess, err := session.NewSession(aws.NewConfig().
+		WithRegion("us-east-1").
+		WithEndpoint(server.URL).
+		WithMaxRetries(0).
+		WithCredentials(credentials.NewStaticCredentials("id", "secret", "")))
+	if err != nil {
+		t.Fatalf("failed to create session: %v", err)
+	}
+
+	allocator, err := ipam.NewPoolAllocator(&v1alpha1.IPAMPool{CidrBlock: "10.0.0.0/8", PodCidrBlock: "100.64.0.0/10"}, sess)
+	if err != nil {
+		t.Fatalf("failed to create allocator: %v", err)
+	}
+
+	scope, err := actuators.NewScope(actuators.ScopeParams{
+		Cluster: &clusterv1.Cluster{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"},
+		},
+		IPAM: allocator,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create test context: %v", err)
+	}
+
+	if err := NewService(scope).allocatePodCidrBlock(); err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+	blocks := scope.Cluster.Spec.ClusterNetwork.Pods.CIDRBlocks
+	if len(blocks) != 1 || blocks[0] != "100.64.0.0/16" {
+		t.Fatalf("expected pod cidr blocks [100.64.0.0/16], got %v", blocks)
+	}
+
+	// The pod cidr block of the cluster is not allocated again.
+	if err := NewService(scope).allocatePodCidrBlock(); err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+}
@@ -0,0 +1,154 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ec2
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+)
+
+// reservableResources are the resources the kubelet reserves for daemons.
+var reservableResources = sets.NewString("cpu", "ephemeral-storage", "memory", "pid")
+
+// defaultSystemReserved are the resources reserved for the system daemons
+// of every instance type.
+var defaultSystemReserved = map[string]string{
+	"cpu":               "100m",
+	"memory":            "100Mi",
+	"ephemeral-storage": "1Gi",
+}
+
+// reservedCPUTiers are the shares of the vCPUs of an instance type reserved
+// for the Kubernetes daemons, in tenths of millicores per vCPU, as on EKS:
+// 6% of the first vCPU, 1% of the second, 0.5% of the next two and 0.25% of
+// the others.
+var reservedCPUTiers = []struct {
+	vcpus, tenths int64
+}{
+	{1, 600},
+	{1, 100},
+	{2, 50},
+	{-1, 25},
+}
+
+const (
+	// reservedMemoryPerPodMiB and reservedMemoryBaseMiB are the memory
+	// reserved for the Kubernetes daemons, as on EKS: 11 MiB per pod the
+	// instance can run, plus 255 MiB.
+	reservedMemoryPerPodMiB = 11
+	reservedMemoryBaseMiB   = 255
+
+	// maxPods is the maximum number of pods of the kubelet, which is not
+	// configured. Pods get their addresses from Calico, not from the network
+	// interfaces of the instance, so it does not depend on the instance type.
+	maxPods = 110
+)
+
+// validateKubeletReserved checks the resources reserved for the daemons of
+// a machine, which are rendered in the kubelet flags.
+func validateKubeletReserved(reserved *v1alpha1.KubeletReserved) error {
+	if reserved == nil {
+		return nil
+	}
+
+	for _, resources := range []map[string]string{reserved.SystemReserved, reserved.KubeReserved} {
+		for k, v := range resources {
+			if !reservableResources.Has(k) {
+				return errors.Errorf("resource %q cannot be reserved, expected one of %s", k, strings.Join(reservableResources.List(), ", "))
+			}
+			if _, err := resource.ParseQuantity(v); err != nil {
+				return errors.Errorf("invalid quantity %q of reserved resource %q", v, k)
+			}
+		}
+	}
+
+	return nil
+}
+
+// addKubeletReservedArgs adds the kubelet flags reserving resources for the
+// system and Kubernetes daemons of a machine. With defaults, the reservations
+// not set on the machine are defaulted, those of the Kubernetes daemons being
+// computed from the instance type, unless it cannot be described.
+func (s *Service) addKubeletReservedArgs(args map[string]string, instanceType string, reserved *v1alpha1.KubeletReserved, defaults bool) {
+	if reserved == nil {
+		reserved = &v1alpha1.KubeletReserved{}
+	}
+
+	systemReserved := reserved.SystemReserved
+	if len(systemReserved) == 0 && defaults {
+		systemReserved = defaultSystemReserved
+	}
+	if len(systemReserved) > 0 {
+		args["system-reserved"] = formatReserved(systemReserved)
+	}
+
+	kubeReserved := reserved.KubeReserved
+	if len(kubeReserved) == 0 && defaults && instanceType != "" {
+		info, err := s.scope.InstanceTypes.Describe(instanceType)
+		switch {
+		case err != nil:
+			klog.Warningf("Not reserving resources for the Kubernetes daemons of instance type %q: %v", instanceType, err)
+		case info != nil && info.VCPUs > 0:
+			kubeReserved = map[string]string{
+				"cpu":               fmt.Sprintf("%dm", reservedCPU(info.VCPUs)),
+				"memory":            fmt.Sprintf("%dMi", reservedMemory(maxPods)),
+				"ephemeral-storage": "1Gi",
+			}
+		}
+	}
+	if len(kubeReserved) > 0 {
+		args["kube-reserved"] = formatReserved(kubeReserved)
+	}
+}
+
+// reservedCPU returns the millicores reserved for the Kubernetes daemons of
+// an instance type with the given vCPUs.
+func reservedCPU(vcpus int64) int64 {
+	var tenths int64
+	for _, tier := range reservedCPUTiers {
+		n := vcpus
+		if tier.vcpus >= 0 && n > tier.vcpus {
+			n = tier.vcpus
+		}
+		tenths += n * tier.tenths
+		vcpus -= n
+	}
+	return tenths / 10
+}
+
+// reservedMemory returns the MiB of memory reserved for the Kubernetes
+// daemons of an instance running at most the given number of pods.
+func reservedMemory(maxPods int64) int64 {
+	return reservedMemoryPerPodMiB*maxPods + reservedMemoryBaseMiB
+}
+
+// formatReserved formats reserved resources as a kubelet flag value.
+func formatReserved(resources map[string]string) string {
+	pairs := make([]string, 0, len(resources))
+	for k, v := range resources {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
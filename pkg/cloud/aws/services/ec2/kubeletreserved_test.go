@@ -0,0 +1,159 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ec2
+
+import (
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/actuators"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/instancetypes"
+	clusterv1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+)
+
+func TestReservedResources(t *testing.T) {
+	cpu := map[int64]int64{1: 60, 2: 70, 4: 80, 8: 90, 96: 310}
+	for vcpus, expected := range cpu {
+		if reserved := reservedCPU(vcpus); reserved != expected {
+			t.Errorf("expected %dm reserved for %d vCPUs, got %dm", expected, vcpus, reserved)
+		}
+	}
+
+	memory := map[int64]int64{4: 299, 29: 574, 110: 1465}
+	for pods, expected := range memory {
+		if reserved := reservedMemory(pods); reserved != expected {
+			t.Errorf("expected %dMi reserved for %d pods, got %dMi", expected, pods, reserved)
+		}
+	}
+}
+
+func TestAddKubeletReservedArgs(t *testing.T) {
+	testCases := []struct {
+		name         string
+		instanceType string
+		reserved     *v1alpha1.KubeletReserved
+		defaults     bool
+		expected     map[string]string
+	}{
+		{
+			name:         "computed from the instance type",
+			instanceType: "m5.large",
+			defaults:     true,
+			expected: map[string]string{
+				"system-reserved": "cpu=100m,ephemeral-storage=1Gi,memory=100Mi",
+				"kube-reserved":   "cpu=70m,ephemeral-storage=1Gi,memory=1465Mi",
+			},
+		},
+		{
+			name:         "set on the machine",
+			instanceType: "m5.large",
+			reserved: &v1alpha1.KubeletReserved{
+				SystemReserved: map[string]string{"memory": "200Mi"},
+				KubeReserved:   map[string]string{"cpu": "500m", "memory": "1Gi"},
+			},
+			expected: map[string]string{
+				"system-reserved": "memory=200Mi",
+				"kube-reserved":   "cpu=500m,memory=1Gi",
+			},
+		},
+		{
+			name:         "instance type not described",
+			instanceType: "x9.huge",
+			defaults:     true,
+			expected: map[string]string{
+				"system-reserved": "cpu=100m,ephemeral-storage=1Gi,memory=100Mi",
+			},
+		},
+		{
+			name:         "without defaults",
+			instanceType: "m5.large",
+			expected:     map[string]string{},
+		},
+		{
+			name:         "partly set on the machine without defaults",
+			instanceType: "m5.large",
+			reserved: &v1alpha1.KubeletReserved{
+				KubeReserved: map[string]string{"cpu": "500m"},
+			},
+			expected: map[string]string{
+				"kube-reserved": "cpu=500m",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			scope, err := actuators.NewScope(actuators.ScopeParams{
+				Cluster: &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"}},
+				InstanceTypes: &fakeInstanceTypes{
+					infos: map[string]*instancetypes.Info{
+						"m5.large": {VCPUs: 2, MemoryMiB: 8192},
+					},
+				},
+			})
+			if err != nil {
+				t.Fatalf("Failed to create test context: %v", err)
+			}
+
+			args := map[string]string{}
+			NewService(scope).addKubeletReservedArgs(args, tc.instanceType, tc.reserved, tc.defaults)
+			if !reflect.DeepEqual(args, tc.expected) {
+				t.Fatalf("expected kubelet args %v, got %v", tc.expected, args)
+			}
+		})
+	}
+}
+
+func TestValidateKubeletReserved(t *testing.T) {
+	testCases := []struct {
+		name        string
+		reserved    *v1alpha1.KubeletReserved
+		expectError bool
+	}{
+		{
+			name: "not set",
+		},
+		{
+			name:     "valid reservations",
+			reserved: &v1alpha1.KubeletReserved{SystemReserved: map[string]string{"cpu": "100m", "pid": "1000"}},
+		},
+		{
+			name:        "unknown resource",
+			reserved:    &v1alpha1.KubeletReserved{KubeReserved: map[string]string{"gpu": "1"}},
+			expectError: true,
+		},
+		{
+			name:        "invalid quantity",
+			reserved:    &v1alpha1.KubeletReserved{KubeReserved: map[string]string{"memory": "1Gi,cpu=2"}},
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateKubeletReserved(tc.reserved)
+			if tc.expectError && err == nil {
+				t.Fatalf("expected an error")
+			}
+			if !tc.expectError && err != nil {
+				t.Fatalf("did not expect error: %v", err)
+			}
+		})
+	}
+}
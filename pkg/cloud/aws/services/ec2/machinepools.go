@@ -0,0 +1,274 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ec2
+
+import (
+	"encoding/base64"
+	"fmt"
+	"sort"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/pkg/errors"
+	"k8s.io/klog"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/awserrors"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/certificates"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/userdata"
+)
+
+const (
+	// launchTemplateNameExists is the error code of the creation of a
+	// launch template whose name is taken.
+	launchTemplateNameExists = "InvalidLaunchTemplateName.AlreadyExistsException"
+
+	// launchTemplateNameNotFound is the error code of requests for a launch
+	// template which does not exist, by name.
+	launchTemplateNameNotFound = "InvalidLaunchTemplateName.NotFoundException"
+)
+
+// CreateMachinePoolLaunchTemplateVersion creates a version of the launch
+// template of a machine pool, with user data joining the instances to the
+// cluster with the given bootstrap token. The template is created along
+// with its first version if the pool has none yet. It returns the ID of the
+// template and the number of the version.
+func (s *Service) CreateMachinePoolLaunchTemplateVersion(pool *v1alpha1.AWSMachinePool, bootstrapToken string) (string, int64, error) {
+	data, err := s.machinePoolLaunchTemplateData(pool, bootstrapToken)
+	if err != nil {
+		return "", 0, err
+	}
+
+	name := s.scope.MachinePoolName(pool)
+	id := pool.Status.LaunchTemplateID
+	if id == "" {
+		out, err := s.scope.EC2.CreateLaunchTemplate(&ec2.CreateLaunchTemplateInput{
+			LaunchTemplateName: aws.String(name),
+			LaunchTemplateData: data,
+		})
+		if err == nil {
+			klog.V(2).Infof("Created launch template %q of machine pool %q", name, pool.Name)
+			return aws.StringValue(out.LaunchTemplate.LaunchTemplateId), aws.Int64Value(out.LaunchTemplate.LatestVersionNumber), nil
+		}
+
+		// The template of a pool whose status was lost is found by its name.
+		if code, _ := awserrors.Code(errors.Cause(err)); code != launchTemplateNameExists {
+			return "", 0, errors.Wrapf(err, "failed to create launch template %q", name)
+		}
+
+		id, err = s.launchTemplateID(name)
+		if err != nil {
+			return "", 0, err
+		}
+	}
+
+	out, err := s.scope.EC2.CreateLaunchTemplateVersion(&ec2.CreateLaunchTemplateVersionInput{
+		LaunchTemplateId:   aws.String(id),
+		LaunchTemplateData: data,
+	})
+	if err != nil {
+		return "", 0, errors.Wrapf(err, "failed to create a version of launch template %q", name)
+	}
+
+	version := aws.Int64Value(out.LaunchTemplateVersion.VersionNumber)
+	klog.V(2).Infof("Created version %d of launch template %q of machine pool %q", version, name, pool.Name)
+	return id, version, nil
+}
+
+// DeleteMachinePoolLaunchTemplate deletes the launch template of a machine
+// pool, if it exists. The template is deleted by name, so that templates
+// whose ID was not recorded in the status of the pool are deleted too.
+func (s *Service) DeleteMachinePoolLaunchTemplate(pool *v1alpha1.AWSMachinePool) error {
+	name := s.scope.MachinePoolName(pool)
+	_, err := s.scope.EC2.DeleteLaunchTemplate(&ec2.DeleteLaunchTemplateInput{LaunchTemplateName: aws.String(name)})
+	if code, _ := awserrors.Code(errors.Cause(err)); err != nil && code != launchTemplateNameNotFound {
+		return errors.Wrapf(err, "failed to delete launch template %q", name)
+	}
+
+	klog.V(2).Infof("Deleted launch template %q of machine pool %q", name, pool.Name)
+	return nil
+}
+
+// MachinePoolSubnets returns the IDs of the subnets a machine pool launches
+// instances in, which default to the private subnets of the cluster.
+func (s *Service) MachinePoolSubnets(pool *v1alpha1.AWSMachinePool) ([]string, error) {
+	if len(pool.Spec.Subnets) > 0 {
+		return pool.Spec.Subnets, nil
+	}
+
+	var ids []string
+	for _, sn := range s.scope.Subnets().FilterPrivate() {
+		ids = append(ids, sn.ID)
+	}
+
+	if len(ids) == 0 {
+		return nil, awserrors.NewFailedDependency(
+			errors.Errorf("failed to launch machine pool %q, no subnets available", pool.Name),
+		)
+	}
+
+	return ids, nil
+}
+
+// launchTemplateID returns the ID of the launch template of the given name.
+func (s *Service) launchTemplateID(name string) (string, error) {
+	out, err := s.scope.EC2.DescribeLaunchTemplates(&ec2.DescribeLaunchTemplatesInput{
+		LaunchTemplateNames: aws.StringSlice([]string{name}),
+	})
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to describe launch template %q", name)
+	}
+
+	if len(out.LaunchTemplates) == 0 {
+		return "", errors.Errorf("launch template %q not found", name)
+	}
+
+	return aws.StringValue(out.LaunchTemplates[0].LaunchTemplateId), nil
+}
+
+// machinePoolLaunchTemplateData returns the launch template data of the
+// instances of a machine pool, which bootstrap as the node machines of the
+// cluster.
+func (s *Service) machinePoolLaunchTemplateData(pool *v1alpha1.AWSMachinePool, bootstrapToken string) (*ec2.RequestLaunchTemplateData, error) {
+	spec := pool.Spec
+
+	if spec.InstanceType == "" {
+		return nil, awserrors.NewInvalidConfiguration(errors.Errorf("machine pool %q must specify an instance type", pool.Name))
+	}
+
+	if spec.AMI.ID == nil && spec.KubernetesVersion == "" {
+		return nil, awserrors.NewInvalidConfiguration(errors.Errorf("machine pool %q must specify an AMI or a Kubernetes version", pool.Name))
+	}
+
+	if len(s.scope.ClusterConfig.CACertificate) == 0 {
+		return nil, awserrors.NewFailedDependency(
+			errors.Errorf("failed to launch machine pool %q, missing CACertificate", pool.Name),
+		)
+	}
+
+	if s.scope.APIServerEndpoint() == "" {
+		return nil, awserrors.NewFailedDependency(
+			errors.Errorf("failed to launch machine pool %q, APIServer endpoint not available", pool.Name),
+		)
+	}
+
+	nodeGroup := s.scope.SecurityGroups()[v1alpha1.SecurityGroupNode]
+	if nodeGroup == nil {
+		return nil, awserrors.NewFailedDependency(
+			errors.Errorf("failed to launch machine pool %q, security group not available", pool.Name),
+		)
+	}
+
+	var imageID string
+	var err error
+	switch {
+	case spec.AMI.ID != nil:
+		imageID = *spec.AMI.ID
+	case len(s.scope.ClusterConfig.RegionAMIs) > 0:
+		imageID, err = s.regionAMI(s.scope.ClusterConfig.RegionAMIs, fmt.Sprintf("cluster %q", s.scope.Name()))
+	default:
+		imageID, err = s.imageLookup(nil, spec.KubernetesVersion, spec.InstanceType)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if s.scope.ClusterConfig.ImageEncryption != nil {
+		imageID, err = s.encryptedAMILookup(imageID, s.scope.ClusterConfig.ImageEncryption)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.validateImageArchitecture(spec.InstanceType, imageID); err != nil {
+		return nil, errors.Wrapf(err, "invalid instance type for machine pool %q", pool.Name)
+	}
+
+	caCertHash, err := certificates.GenerateCertificateHash(s.scope.ClusterConfig.CACertificate)
+	if err != nil {
+		return nil, err
+	}
+
+	kubeletArgs := map[string]string{}
+	if labels := machinePoolNodeLabels(spec.Labels); len(labels) > 0 {
+		addNodeLabels(kubeletArgs, labels...)
+	}
+	if s.isGPUInstanceType(spec.InstanceType) {
+		addNodeLabels(kubeletArgs, gpuNodeLabel)
+	}
+	if labels := s.hardwareLabels(spec.InstanceType); len(labels) > 0 {
+		addNodeLabels(kubeletArgs, labels...)
+	}
+
+	userData, err := userdata.NewNode(&userdata.NodeInput{
+		CACertHash:       caCertHash,
+		BootstrapToken:   bootstrapToken,
+		ELBAddress:       s.scope.APIServerEndpoint(),
+		KubeletExtraArgs: kubeletArgs,
+		FIPSMode:         spec.FIPSMode,
+		NTPServers:       ntpServers(s.scope.ClusterConfig.NTPServers),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	keyName := spec.KeyName
+	if keyName == "" {
+		keyName = defaultSSHKeyName
+	}
+
+	// The instances are tagged by the group, their volumes by the template.
+	var volumeTags []*ec2.Tag
+	for k, v := range s.scope.MachinePoolTags(pool) {
+		volumeTags = append(volumeTags, &ec2.Tag{Key: aws.String(k), Value: aws.String(v)})
+	}
+	sort.Slice(volumeTags, func(i, j int) bool { return *volumeTags[i].Key < *volumeTags[j].Key })
+
+	data := &ec2.RequestLaunchTemplateData{
+		ImageId:          aws.String(imageID),
+		InstanceType:     aws.String(spec.InstanceType),
+		KeyName:          aws.String(keyName),
+		SecurityGroupIds: aws.StringSlice(append([]string{nodeGroup.ID}, spec.AdditionalSecurityGroupIDs...)),
+		UserData:         aws.String(base64.StdEncoding.EncodeToString([]byte(userData))),
+		TagSpecifications: []*ec2.LaunchTemplateTagSpecificationRequest{
+			{ResourceType: aws.String(ec2.ResourceTypeVolume), Tags: volumeTags},
+		},
+	}
+
+	if spec.IAMInstanceProfile != "" {
+		data.IamInstanceProfile = &ec2.LaunchTemplateIamInstanceProfileSpecificationRequest{
+			Name: aws.String(spec.IAMInstanceProfile),
+		}
+	}
+
+	return data, nil
+}
+
+// machinePoolNodeLabels returns the labels of the nodes of a machine pool in
+// the key=value form of the kubelet flag, sorted by key.
+func machinePoolNodeLabels(labels map[string]string) []string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var result []string
+	for _, k := range keys {
+		result = append(result, k+"="+labels[k])
+	}
+	return result
+}
@@ -0,0 +1,67 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ec2
+
+import (
+	"net/url"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+)
+
+// validateMetadataOptions checks the instance metadata service options of an instance.
+func validateMetadataOptions(opts *v1alpha1.InstanceMetadataOptions) error {
+	if opts == nil {
+		return nil
+	}
+
+	switch opts.HTTPTokens {
+	case "", v1alpha1.HTTPTokensStateOptional, v1alpha1.HTTPTokensStateRequired:
+	default:
+		return errors.Errorf("unknown http tokens state %q", opts.HTTPTokens)
+	}
+
+	if opts.HTTPPutResponseHopLimit != 0 && (opts.HTTPPutResponseHopLimit < 1 || opts.HTTPPutResponseHopLimit > 64) {
+		return errors.Errorf("http put response hop limit must be between 1 and 64, got %d", opts.HTTPPutResponseHopLimit)
+	}
+
+	switch opts.HTTPEndpoint {
+	case "", v1alpha1.InstanceMetadataEndpointStateEnabled, v1alpha1.InstanceMetadataEndpointStateDisabled:
+	default:
+		return errors.Errorf("unknown http endpoint state %q", opts.HTTPEndpoint)
+	}
+
+	return nil
+}
+
+// withMetadataOptions sets the instance metadata service options of the
+// instances launched by a RunInstances request.
+func withMetadataOptions(opts *v1alpha1.InstanceMetadataOptions) request.Option {
+	return withQuery(func(query url.Values) {
+		if opts.HTTPTokens != "" {
+			query.Set("MetadataOptions.HttpTokens", string(opts.HTTPTokens))
+		}
+		if opts.HTTPPutResponseHopLimit != 0 {
+			query.Set("MetadataOptions.HttpPutResponseHopLimit", strconv.FormatInt(opts.HTTPPutResponseHopLimit, 10))
+		}
+		if opts.HTTPEndpoint != "" {
+			query.Set("MetadataOptions.HttpEndpoint", string(opts.HTTPEndpoint))
+		}
+	})
+}
@@ -0,0 +1,117 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ec2
+
+import (
+	"io/ioutil"
+	"net/url"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+)
+
+func TestValidateMetadataOptions(t *testing.T) {
+	testCases := []struct {
+		name        string
+		opts        *v1alpha1.InstanceMetadataOptions
+		expectError bool
+	}{
+		{
+			name: "no metadata options",
+		},
+		{
+			name: "IMDSv2 required",
+			opts: &v1alpha1.InstanceMetadataOptions{HTTPTokens: v1alpha1.HTTPTokensStateRequired, HTTPPutResponseHopLimit: 2},
+		},
+		{
+			name:        "unknown tokens state",
+			opts:        &v1alpha1.InstanceMetadataOptions{HTTPTokens: "always"},
+			expectError: true,
+		},
+		{
+			name:        "hop limit too high",
+			opts:        &v1alpha1.InstanceMetadataOptions{HTTPPutResponseHopLimit: 65},
+			expectError: true,
+		},
+		{
+			name:        "unknown endpoint state",
+			opts:        &v1alpha1.InstanceMetadataOptions{HTTPEndpoint: "on"},
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateMetadataOptions(tc.opts)
+			if tc.expectError && err == nil {
+				t.Fatalf("expected error")
+			}
+			if !tc.expectError && err != nil {
+				t.Fatalf("did not expect error: %v", err)
+			}
+		})
+	}
+}
+
+func TestWithMetadataOptions(t *testing.T) {
+	sess, err := session.NewSession(&aws.Config{
+		Region:      aws.String("us-east-1"),
+		Credentials: credentials.NewStaticCredentials("id", "secret", ""),
+	})
+	if err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+
+	req, _ := ec2.New(sess).RunInstancesRequest(&ec2.RunInstancesInput{
+		ImageId:  aws.String("ami-1"),
+		MinCount: aws.Int64(1),
+		MaxCount: aws.Int64(1),
+	})
+	req.ApplyOptions(withMetadataOptions(&v1alpha1.InstanceMetadataOptions{
+		HTTPTokens:              v1alpha1.HTTPTokensStateRequired,
+		HTTPPutResponseHopLimit: 2,
+	}))
+	if err := req.Build(); err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		t.Fatalf("Failed to read request: %v", err)
+	}
+	query, err := url.ParseQuery(string(body))
+	if err != nil {
+		t.Fatalf("Failed to parse request: %v", err)
+	}
+
+	expected := map[string]string{
+		"Action":                     "RunInstances",
+		"ImageId":                    "ami-1",
+		"MetadataOptions.HttpTokens": "required",
+		"MetadataOptions.HttpPutResponseHopLimit": "2",
+		"MetadataOptions.HttpEndpoint":            "",
+	}
+	for k, v := range expected {
+		if query.Get(k) != v {
+			t.Errorf("expected %s to be %q, got %q", k, v, query.Get(k))
+		}
+	}
+}
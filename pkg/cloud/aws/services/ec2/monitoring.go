@@ -0,0 +1,43 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ec2
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/pkg/errors"
+	"k8s.io/klog"
+)
+
+// SetInstanceMonitoring enables or disables the detailed CloudWatch monitoring of an instance.
+func (s *Service) SetInstanceMonitoring(instanceID string, enabled bool) error {
+	ids := aws.StringSlice([]string{instanceID})
+
+	if enabled {
+		if _, err := s.scope.EC2.MonitorInstances(&ec2.MonitorInstancesInput{InstanceIds: ids}); err != nil {
+			return errors.Wrapf(err, "failed to enable detailed monitoring of instance %q", instanceID)
+		}
+		klog.V(2).Infof("Enabled detailed monitoring of instance %q", instanceID)
+		return nil
+	}
+
+	if _, err := s.scope.EC2.UnmonitorInstances(&ec2.UnmonitorInstancesInput{InstanceIds: ids}); err != nil {
+		return errors.Wrapf(err, "failed to disable detailed monitoring of instance %q", instanceID)
+	}
+	klog.V(2).Infof("Disabled detailed monitoring of instance %q", instanceID)
+	return nil
+}
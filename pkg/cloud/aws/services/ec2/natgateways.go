@@ -17,8 +17,6 @@ limitations under the License.
 package ec2
 
 import (
-	"fmt"
-
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/pkg/errors"
@@ -47,6 +45,7 @@ func (s *Service) reconcileNatGateways() error {
 		return err
 	}
 
+	batch := tags.NewBatch(s.scope.EC2)
 	for _, sn := range s.scope.Subnets().FilterPublic() {
 		if sn.ID == "" {
 			continue
@@ -54,15 +53,7 @@ func (s *Service) reconcileNatGateways() error {
 
 		if ngw, ok := existing[sn.ID]; ok {
 			// Make sure tags are up to date.
-			err := tags.Ensure(converters.TagsToMap(ngw.Tags), &tags.ApplyParams{
-				EC2Client:   s.scope.EC2,
-				BuildParams: s.getNatGatewayTagParams(*ngw.NatGatewayId),
-			})
-
-			if err != nil {
-				return errors.Wrapf(err, "failed to tag nat gateway %q", *ngw.NatGatewayId)
-			}
-
+			batch.Ensure(converters.TagsToMap(ngw.Tags), s.getNatGatewayTagParams(*ngw.NatGatewayId))
 			continue
 		}
 
@@ -74,6 +65,10 @@ func (s *Service) reconcileNatGateways() error {
 		sn.NatGatewayID = ng.NatGatewayId
 	}
 
+	if err := batch.Apply(); err != nil {
+		return errors.Wrap(err, "failed to ensure tags on nat gateways")
+	}
+
 	return nil
 }
 
@@ -133,7 +128,7 @@ func (s *Service) describeNatGatewaysBySubnet() (map[string]*ec2.NatGateway, err
 }
 
 func (s *Service) getNatGatewayTagParams(id string) tags.BuildParams {
-	name := fmt.Sprintf("%s-nat", s.scope.Name())
+	name := s.scope.ResourceName("nat", 0)
 
 	return tags.BuildParams{
 		ClusterName: s.scope.Name(),
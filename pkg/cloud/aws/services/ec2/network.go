@@ -24,33 +24,42 @@ import (
 func (s *Service) ReconcileNetwork() (err error) {
 	klog.V(2).Info("Reconciling network")
 
+	if s.scope.ClusterConfig.ExternalNetwork != nil {
+		return s.reconcileExternalNetwork()
+	}
+
 	// VPC.
-	if err := s.reconcileVPC(); err != nil {
+	if err := s.scope.Converge("vpc", s.reconcileVPC); err != nil {
 		return err
 	}
 
 	// Subnets.
-	if err := s.reconcileSubnets(); err != nil {
+	if err := s.scope.Converge("subnets", s.reconcileSubnets); err != nil {
 		return err
 	}
 
 	// Internet Gateways.
-	if err := s.reconcileInternetGateways(); err != nil {
+	if err := s.scope.Converge("internet-gateways", s.reconcileInternetGateways); err != nil {
 		return err
 	}
 
 	// NAT Gateways.
-	if err := s.reconcileNatGateways(); err != nil {
+	if err := s.scope.Converge("nat-gateways", s.reconcileNatGateways); err != nil {
 		return err
 	}
 
 	// Routing tables.
-	if err := s.reconcileRouteTables(); err != nil {
+	if err := s.scope.Converge("route-tables", s.reconcileRouteTables); err != nil {
 		return err
 	}
 
 	// Security groups.
-	if err := s.reconcileSecurityGroups(); err != nil {
+	if err := s.scope.Converge("security-groups", s.reconcileSecurityGroups); err != nil {
+		return err
+	}
+
+	// Management VPC peering.
+	if err := s.scope.Converge("management-peering", s.reconcileManagementPeering); err != nil {
 		return err
 	}
 
@@ -62,11 +71,21 @@ func (s *Service) ReconcileNetwork() (err error) {
 func (s *Service) DeleteNetwork() (err error) {
 	klog.V(2).Info("Deleting network")
 
+	if s.scope.ClusterConfig.ExternalNetwork != nil {
+		klog.V(2).Info("Network is managed externally, skipping deletion")
+		return nil
+	}
+
 	// Security groups.
 	if err := s.deleteSecurityGroups(); err != nil {
 		return err
 	}
 
+	// Management VPC peering.
+	if err := s.deleteManagementPeering(); err != nil {
+		return err
+	}
+
 	// Routing tables.
 	if err := s.deleteRouteTables(); err != nil {
 		return err
@@ -97,6 +116,16 @@ func (s *Service) DeleteNetwork() (err error) {
 		return err
 	}
 
+	// DHCP option sets, once no longer associated with the VPC.
+	if err := s.deleteDHCPOptions(); err != nil {
+		return err
+	}
+
+	// CIDR blocks allocated by IPAM.
+	if err := s.releaseCidrBlocks(); err != nil {
+		return err
+	}
+
 	klog.V(2).Info("Delete network completed successfully")
 	return nil
 }
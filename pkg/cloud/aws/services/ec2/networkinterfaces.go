@@ -0,0 +1,192 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ec2
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/pkg/errors"
+	"k8s.io/klog"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/awserrors"
+)
+
+// validateNetworkInterfaces checks the secondary private IP addresses and
+// additional network interfaces requested for an instance.
+func validateNetworkInterfaces(secondaryPrivateIPCount int64, interfaces []v1alpha1.NetworkInterface, elasticIP bool, publicIP *bool) error {
+	if secondaryPrivateIPCount < 0 {
+		return errors.Errorf("secondary private IP count must not be negative, got %d", secondaryPrivateIPCount)
+	}
+
+	for i, ni := range interfaces {
+		if ni.SecondaryPrivateIPCount < 0 {
+			return errors.Errorf("secondary private IP count of additional network interface %d must not be negative, got %d", i, ni.SecondaryPrivateIPCount)
+		}
+		if ni.NetworkCardIndex < 0 {
+			return errors.Errorf("network card index of additional network interface %d must not be negative, got %d", i, ni.NetworkCardIndex)
+		}
+	}
+
+	// Elastic IPs are associated with the instance, which is ambiguous once
+	// it has several network interfaces.
+	if elasticIP && len(interfaces) > 0 {
+		return errors.New("machines with an elastic IP cannot have additional network interfaces")
+	}
+
+	// EC2 only assigns public IP addresses to instances with a single
+	// network interface.
+	if aws.BoolValue(publicIP) && len(interfaces) > 0 {
+		return errors.New("machines with a public IP cannot have additional network interfaces")
+	}
+
+	return nil
+}
+
+// validateNetworkCards checks that an instance type has the network cards
+// the additional network interfaces of an instance are attached to, and
+// supports ENA Express if it is requested. Instance types which cannot be
+// described are left for EC2 to validate.
+func (s *Service) validateNetworkCards(instanceType string, interfaces []v1alpha1.NetworkInterface, enaExpress *v1alpha1.ENAExpress) error {
+	cards := networkCards(interfaces)
+
+	// The instance type may come from a launch template.
+	if instanceType == "" || (cards == 1 && enaExpress == nil) {
+		return nil
+	}
+
+	info, err := s.scope.InstanceTypes.Describe(instanceType)
+	if err != nil {
+		klog.Warningf("Skipping network card validation of instance type %q: %v", instanceType, err)
+		return nil
+	}
+	if info == nil {
+		return nil
+	}
+
+	if info.MaximumNetworkCards > 0 && cards > info.MaximumNetworkCards {
+		return awserrors.NewInvalidConfiguration(errors.Errorf("instance type %q has %d network cards, but network card index %d is requested",
+			instanceType, info.MaximumNetworkCards, cards-1))
+	}
+
+	if enaExpress != nil && !info.ENAExpressSupported {
+		return awserrors.NewInvalidConfiguration(errors.Errorf("instance type %q does not support ENA Express", instanceType))
+	}
+
+	return nil
+}
+
+// networkCards returns the number of network cards needed by the network
+// interfaces of an instance, the primary one being on the first card.
+func networkCards(interfaces []v1alpha1.NetworkInterface) int64 {
+	cards := int64(1)
+	for _, ni := range interfaces {
+		if ni.NetworkCardIndex >= cards {
+			cards = ni.NetworkCardIndex + 1
+		}
+	}
+	return cards
+}
+
+// primaryNetworkInterface moves the subnet and security groups of a
+// RunInstances request to the specification of its primary network
+// interface, so that it can be further configured.
+func primaryNetworkInterface(input *ec2.RunInstancesInput) {
+	input.NetworkInterfaces = []*ec2.InstanceNetworkInterfaceSpecification{
+		{
+			DeviceIndex:         aws.Int64(0),
+			DeleteOnTermination: aws.Bool(true),
+			SubnetId:            input.SubnetId,
+			Groups:              input.SecurityGroupIds,
+		},
+	}
+	input.SubnetId = nil
+	input.SecurityGroupIds = nil
+}
+
+// additionalNetworkInterfaces assigns secondary private IP addresses to the
+// primary network interface of a RunInstances request, and appends its
+// additional network interfaces at the following device indexes. The
+// additional interfaces share the security groups of the primary interface,
+// and are deleted when the instance is terminated.
+func additionalNetworkInterfaces(input *ec2.RunInstancesInput, secondaryPrivateIPCount int64, interfaces []v1alpha1.NetworkInterface) {
+	primaryNetworkInterface(input)
+	primary := input.NetworkInterfaces[0]
+	if secondaryPrivateIPCount > 0 {
+		primary.SecondaryPrivateIpAddressCount = aws.Int64(secondaryPrivateIPCount)
+	}
+
+	for i, ni := range interfaces {
+		spec := &ec2.InstanceNetworkInterfaceSpecification{
+			DeviceIndex:         aws.Int64(int64(i + 1)),
+			DeleteOnTermination: aws.Bool(true),
+			SubnetId:            primary.SubnetId,
+			Groups:              primary.Groups,
+		}
+		if ni.SubnetID != "" {
+			spec.SubnetId = aws.String(ni.SubnetID)
+		}
+		if ni.SecondaryPrivateIPCount > 0 {
+			spec.SecondaryPrivateIpAddressCount = aws.Int64(ni.SecondaryPrivateIPCount)
+		}
+		input.NetworkInterfaces = append(input.NetworkInterfaces, spec)
+	}
+}
+
+// associatePublicIPAddress overrides the public IP address assignment of the
+// subnet of the primary network interface of a RunInstances request. EC2
+// refuses the override for instances with several network interfaces, which
+// never get a public IP address, so it is only set with a single interface.
+func associatePublicIPAddress(input *ec2.RunInstancesInput, publicIP bool) {
+	if len(input.NetworkInterfaces) == 0 {
+		primaryNetworkInterface(input)
+	}
+	if len(input.NetworkInterfaces) != 1 {
+		return
+	}
+	input.NetworkInterfaces[0].AssociatePublicIpAddress = aws.Bool(publicIP)
+}
+
+// withNetworkCards attaches the additional network interfaces of the
+// instances launched by a RunInstances request to their network cards. They
+// follow the primary network interface in the request.
+func withNetworkCards(interfaces []v1alpha1.NetworkInterface) request.Option {
+	return withQuery(func(query url.Values) {
+		for i, ni := range interfaces {
+			if ni.NetworkCardIndex > 0 {
+				query.Set(fmt.Sprintf("NetworkInterface.%d.NetworkCardIndex", i+2), fmt.Sprint(ni.NetworkCardIndex))
+			}
+		}
+	})
+}
+
+// withENAExpress enables ENA Express on the given number of network
+// interfaces of the instances launched by a RunInstances request.
+func withENAExpress(enaExpress *v1alpha1.ENAExpress, count int) request.Option {
+	return withQuery(func(query url.Values) {
+		for i := 1; i <= count; i++ {
+			prefix := fmt.Sprintf("NetworkInterface.%d.EnaSrdSpecification.", i)
+			query.Set(prefix+"EnaSrdEnabled", "true")
+			if enaExpress.UDP {
+				query.Set(prefix+"EnaSrdUdpSpecification.EnaSrdUdpEnabled", "true")
+			}
+		}
+	})
+}
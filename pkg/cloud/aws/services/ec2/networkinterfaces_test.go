@@ -0,0 +1,313 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ec2
+
+import (
+	"io/ioutil"
+	"net/url"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/actuators"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/awserrors"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/instancetypes"
+	clusterv1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+)
+
+func TestValidateNetworkInterfaces(t *testing.T) {
+	testCases := []struct {
+		name                    string
+		secondaryPrivateIPCount int64
+		interfaces              []v1alpha1.NetworkInterface
+		elasticIP               bool
+		publicIP                *bool
+		expectError             bool
+	}{
+		{
+			name: "no additional network interfaces",
+		},
+		{
+			name:                    "secondary private IPs and interfaces",
+			secondaryPrivateIPCount: 2,
+			interfaces:              []v1alpha1.NetworkInterface{{SubnetID: "subnet-2", SecondaryPrivateIPCount: 4}},
+		},
+		{
+			name:                    "negative secondary private IP count",
+			secondaryPrivateIPCount: -1,
+			expectError:             true,
+		},
+		{
+			name:        "negative secondary private IP count of an interface",
+			interfaces:  []v1alpha1.NetworkInterface{{SecondaryPrivateIPCount: -1}},
+			expectError: true,
+		},
+		{
+			name:        "negative network card index",
+			interfaces:  []v1alpha1.NetworkInterface{{NetworkCardIndex: -1}},
+			expectError: true,
+		},
+		{
+			name:                    "elastic IP with secondary private IPs",
+			secondaryPrivateIPCount: 2,
+			elasticIP:               true,
+		},
+		{
+			name:        "elastic IP with additional interfaces",
+			interfaces:  []v1alpha1.NetworkInterface{{}},
+			elasticIP:   true,
+			expectError: true,
+		},
+		{
+			name:     "public IP",
+			publicIP: aws.Bool(true),
+		},
+		{
+			name:       "no public IP with additional interfaces",
+			interfaces: []v1alpha1.NetworkInterface{{}},
+			publicIP:   aws.Bool(false),
+		},
+		{
+			name:        "public IP with additional interfaces",
+			interfaces:  []v1alpha1.NetworkInterface{{}},
+			publicIP:    aws.Bool(true),
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateNetworkInterfaces(tc.secondaryPrivateIPCount, tc.interfaces, tc.elasticIP, tc.publicIP)
+			if tc.expectError != (err != nil) {
+				t.Fatalf("expected error: %v, got: %v", tc.expectError, err)
+			}
+		})
+	}
+}
+
+func TestAdditionalNetworkInterfaces(t *testing.T) {
+	input := &ec2.RunInstancesInput{
+		SubnetId:         aws.String("subnet-1"),
+		SecurityGroupIds: aws.StringSlice([]string{"sg-1"}),
+	}
+
+	additionalNetworkInterfaces(input, 2, []v1alpha1.NetworkInterface{
+		{},
+		{SubnetID: "subnet-2", SecondaryPrivateIPCount: 4},
+	})
+
+	if input.SubnetId != nil || input.SecurityGroupIds != nil {
+		t.Fatalf("expected the subnet and security groups to be moved to the network interfaces, got %v", input)
+	}
+
+	if len(input.NetworkInterfaces) != 3 {
+		t.Fatalf("expected 3 network interfaces, got %v", input.NetworkInterfaces)
+	}
+
+	expected := []struct {
+		subnet       string
+		secondaryIPs int64
+	}{
+		{"subnet-1", 2},
+		{"subnet-1", 0},
+		{"subnet-2", 4},
+	}
+	for i, ni := range input.NetworkInterfaces {
+		if aws.Int64Value(ni.DeviceIndex) != int64(i) {
+			t.Errorf("expected network interface %d at device index %d, got %d", i, i, aws.Int64Value(ni.DeviceIndex))
+		}
+		if aws.StringValue(ni.SubnetId) != expected[i].subnet {
+			t.Errorf("expected network interface %d in subnet %q, got %q", i, expected[i].subnet, aws.StringValue(ni.SubnetId))
+		}
+		if aws.Int64Value(ni.SecondaryPrivateIpAddressCount) != expected[i].secondaryIPs {
+			t.Errorf("expected %d secondary private IPs on network interface %d, got %d", expected[i].secondaryIPs, i, aws.Int64Value(ni.SecondaryPrivateIpAddressCount))
+		}
+		if !aws.BoolValue(ni.DeleteOnTermination) {
+			t.Errorf("expected network interface %d to be deleted on termination", i)
+		}
+		if len(ni.Groups) != 1 || aws.StringValue(ni.Groups[0]) != "sg-1" {
+			t.Errorf("expected network interface %d to have the security groups of the instance, got %v", i, aws.StringValueSlice(ni.Groups))
+		}
+	}
+}
+
+func TestAssociatePublicIPAddress(t *testing.T) {
+	input := &ec2.RunInstancesInput{
+		SubnetId:         aws.String("subnet-1"),
+		SecurityGroupIds: aws.StringSlice([]string{"sg-1"}),
+	}
+
+	associatePublicIPAddress(input, false)
+
+	if input.SubnetId != nil || len(input.NetworkInterfaces) != 1 {
+		t.Fatalf("expected the subnet to be moved to the primary network interface, got %v", input)
+	}
+
+	ni := input.NetworkInterfaces[0]
+	if aws.StringValue(ni.SubnetId) != "subnet-1" {
+		t.Errorf("expected the primary network interface in subnet %q, got %q", "subnet-1", aws.StringValue(ni.SubnetId))
+	}
+	if ni.AssociatePublicIpAddress == nil || aws.BoolValue(ni.AssociatePublicIpAddress) {
+		t.Errorf("expected no public IP to be associated, got %v", ni.AssociatePublicIpAddress)
+	}
+
+	// The override is refused by EC2 with additional network interfaces.
+	input = &ec2.RunInstancesInput{
+		SubnetId:         aws.String("subnet-1"),
+		SecurityGroupIds: aws.StringSlice([]string{"sg-1"}),
+	}
+	additionalNetworkInterfaces(input, 0, []v1alpha1.NetworkInterface{{}})
+	associatePublicIPAddress(input, false)
+
+	for i, ni := range input.NetworkInterfaces {
+		if ni.AssociatePublicIpAddress != nil {
+			t.Errorf("expected no public IP override on network interface %d, got %v", i, aws.BoolValue(ni.AssociatePublicIpAddress))
+		}
+	}
+}
+
+func TestValidateNetworkCards(t *testing.T) {
+	testCases := []struct {
+		name         string
+		instanceType string
+		interfaces   []v1alpha1.NetworkInterface
+		enaExpress   *v1alpha1.ENAExpress
+		expectError  bool
+	}{
+		{
+			name:         "first network card",
+			instanceType: "m5.large",
+			interfaces:   []v1alpha1.NetworkInterface{{}},
+		},
+		{
+			name:         "second network card",
+			instanceType: "p4d.24xlarge",
+			interfaces:   []v1alpha1.NetworkInterface{{NetworkCardIndex: 1}, {NetworkCardIndex: 3}},
+		},
+		{
+			name:         "network card out of range",
+			instanceType: "p4d.24xlarge",
+			interfaces:   []v1alpha1.NetworkInterface{{NetworkCardIndex: 4}},
+			expectError:  true,
+		},
+		{
+			name:         "several network cards on an instance type with one",
+			instanceType: "m5.large",
+			interfaces:   []v1alpha1.NetworkInterface{{NetworkCardIndex: 1}},
+			expectError:  true,
+		},
+		{
+			name:         "ENA Express",
+			instanceType: "p4d.24xlarge",
+			enaExpress:   &v1alpha1.ENAExpress{UDP: true},
+		},
+		{
+			name:         "ENA Express unsupported",
+			instanceType: "m5.large",
+			enaExpress:   &v1alpha1.ENAExpress{},
+			expectError:  true,
+		},
+		{
+			name:       "instance type from a launch template",
+			interfaces: []v1alpha1.NetworkInterface{{NetworkCardIndex: 1}},
+			enaExpress: &v1alpha1.ENAExpress{},
+		},
+		{
+			name:         "instance type not described",
+			instanceType: "x9.huge",
+			enaExpress:   &v1alpha1.ENAExpress{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			scope, err := actuators.NewScope(actuators.ScopeParams{
+				Cluster: &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"}},
+				InstanceTypes: &fakeInstanceTypes{
+					infos: map[string]*instancetypes.Info{
+						"m5.large":     {MaximumNetworkCards: 1},
+						"p4d.24xlarge": {MaximumNetworkCards: 4, ENAExpressSupported: true},
+					},
+				},
+			})
+			if err != nil {
+				t.Fatalf("Failed to create test context: %v", err)
+			}
+
+			err = NewService(scope).validateNetworkCards(tc.instanceType, tc.interfaces, tc.enaExpress)
+			if tc.expectError != (err != nil) {
+				t.Fatalf("expected error: %v, got: %v", tc.expectError, err)
+			}
+			if err != nil && !awserrors.IsInvalidConfiguration(err) {
+				t.Fatalf("expected an invalid configuration error, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestWithNetworkCardsAndENAExpress(t *testing.T) {
+	sess, err := session.NewSession(&aws.Config{
+		Region:      aws.String("us-east-1"),
+		Credentials: credentials.NewStaticCredentials("id", "secret", ""),
+	})
+	if err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+
+	interfaces := []v1alpha1.NetworkInterface{{NetworkCardIndex: 1}, {}}
+	input := &ec2.RunInstancesInput{
+		ImageId:  aws.String("ami-1"),
+		MinCount: aws.Int64(1),
+		MaxCount: aws.Int64(1),
+		SubnetId: aws.String("subnet-1"),
+	}
+	additionalNetworkInterfaces(input, 0, interfaces)
+
+	req, _ := ec2.New(sess).RunInstancesRequest(input)
+	req.ApplyOptions(withNetworkCards(interfaces), withENAExpress(&v1alpha1.ENAExpress{UDP: true}, len(input.NetworkInterfaces)))
+	if err := req.Build(); err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		t.Fatalf("Failed to read request: %v", err)
+	}
+	query, err := url.ParseQuery(string(body))
+	if err != nil {
+		t.Fatalf("Failed to parse request: %v", err)
+	}
+
+	expected := map[string]string{
+		"NetworkInterface.1.NetworkCardIndex":                                            "",
+		"NetworkInterface.2.DeviceIndex":                                                 "1",
+		"NetworkInterface.2.NetworkCardIndex":                                            "1",
+		"NetworkInterface.3.NetworkCardIndex":                                            "",
+		"NetworkInterface.1.EnaSrdSpecification.EnaSrdEnabled":                           "true",
+		"NetworkInterface.3.EnaSrdSpecification.EnaSrdEnabled":                           "true",
+		"NetworkInterface.3.EnaSrdSpecification.EnaSrdUdpSpecification.EnaSrdUdpEnabled": "true",
+	}
+	for k, v := range expected {
+		if query.Get(k) != v {
+			t.Errorf("expected %s to be %q, got %q", k, v, query.Get(k))
+		}
+	}
+}
@@ -0,0 +1,121 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ec2
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/pkg/errors"
+	"k8s.io/klog"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/awserrors"
+)
+
+// offeringSubnets returns the subnets in availability zones where the
+// instance type is offered, so that instances are not launched in zones
+// rejecting them. It fails with a terminal error if the instance type is
+// offered in none of them.
+//
+// Subnets whose zone is unknown, or whose offerings cannot be described, are
+// kept, since RunInstances rejects the instance type anyway.
+func (s *Service) offeringSubnets(instanceType string, subnets v1alpha1.Subnets) (v1alpha1.Subnets, error) {
+	if instanceType == "" {
+		return subnets, nil
+	}
+
+	var res v1alpha1.Subnets
+	var zones []string
+	for _, sn := range subnets {
+		if sn.AvailabilityZone == "" {
+			res = append(res, sn)
+			continue
+		}
+
+		offered, err := s.scope.InstanceTypes.OfferedInZone(instanceType, sn.AvailabilityZone)
+		if err != nil {
+			klog.Warningf("Skipping the offering validation of instance type %q: %v", instanceType, err)
+			return subnets, nil
+		}
+
+		if offered {
+			res = append(res, sn)
+		} else {
+			zones = append(zones, sn.AvailabilityZone)
+		}
+	}
+
+	if len(res) == 0 {
+		return nil, awserrors.NewInvalidConfiguration(
+			errors.Errorf("instance type %q is not offered in availability zones %v of the cluster", instanceType, zones),
+		)
+	}
+
+	return res, nil
+}
+
+// validateZoneOffering checks that an instance type is offered in the
+// availability zone of a subnet, so that launching an instance there fails
+// with a terminal error rather than being retried.
+func (s *Service) validateZoneOffering(instanceType, subnetID string) error {
+	if instanceType == "" {
+		return nil
+	}
+
+	zone, err := s.subnetZone(subnetID)
+	if err != nil {
+		return err
+	}
+
+	if zone == "" {
+		return nil
+	}
+
+	offered, err := s.scope.InstanceTypes.OfferedInZone(instanceType, zone)
+	if err != nil {
+		klog.Warningf("Skipping the offering validation of instance type %q: %v", instanceType, err)
+		return nil
+	}
+
+	if !offered {
+		return awserrors.NewInvalidConfiguration(
+			errors.Errorf("instance type %q is not offered in availability zone %q of subnet %q", instanceType, zone, subnetID),
+		)
+	}
+
+	return nil
+}
+
+// subnetZone returns the availability zone of a subnet, which is described
+// if it is not part of the network of the cluster.
+func (s *Service) subnetZone(subnetID string) (string, error) {
+	if sn, ok := s.scope.Subnets().ToMap()[subnetID]; ok && sn.AvailabilityZone != "" {
+		return sn.AvailabilityZone, nil
+	}
+
+	out, err := s.scope.EC2.DescribeSubnets(&ec2.DescribeSubnetsInput{
+		SubnetIds: aws.StringSlice([]string{subnetID}),
+	})
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to describe subnet %q", subnetID)
+	}
+
+	if len(out.Subnets) == 0 {
+		return "", errors.Errorf("subnet %q not found", subnetID)
+	}
+
+	return aws.StringValue(out.Subnets[0].AvailabilityZone), nil
+}
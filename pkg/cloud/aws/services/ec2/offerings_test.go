@@ -0,0 +1,135 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ec2
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/golang/mock/gomock"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/actuators"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/awserrors"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/ec2/mock_ec2iface"
+	clusterv1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+)
+
+func TestOfferingSubnets(t *testing.T) {
+	subnets := v1alpha1.Subnets{
+		{ID: "subnet-a", AvailabilityZone: "us-east-1a"},
+		{ID: "subnet-b", AvailabilityZone: "us-east-1b"},
+		{ID: "subnet-e", AvailabilityZone: "us-east-1e"},
+	}
+
+	testCases := []struct {
+		name         string
+		instanceType string
+		expected     []string
+		expectError  bool
+	}{
+		{
+			name:         "offered in every zone",
+			instanceType: "m5.large",
+			expected:     []string{"subnet-a", "subnet-b", "subnet-e"},
+		},
+		{
+			name:         "offered in some zones",
+			instanceType: "p4d.24xlarge",
+			expected:     []string{"subnet-b"},
+		},
+		{
+			name:         "offered in no zone",
+			instanceType: "u-6tb1.metal",
+			expectError:  true,
+		},
+		{
+			name:         "offerings cannot be described",
+			instanceType: "x9.huge",
+			expected:     []string{"subnet-a", "subnet-b", "subnet-e"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			scope, err := actuators.NewScope(actuators.ScopeParams{
+				Cluster: &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"}},
+				InstanceTypes: &fakeInstanceTypes{
+					zones: map[string][]string{
+						"m5.large":     {"us-east-1a", "us-east-1b", "us-east-1e"},
+						"p4d.24xlarge": {"us-east-1b"},
+					},
+				},
+			})
+			if err != nil {
+				t.Fatalf("Failed to create test context: %v", err)
+			}
+
+			res, err := NewService(scope).offeringSubnets(tc.instanceType, subnets)
+			if tc.expectError {
+				if !awserrors.IsInvalidConfiguration(err) {
+					t.Fatalf("expected an invalid configuration error, got: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("did not expect error: %v", err)
+			}
+
+			var ids []string
+			for _, sn := range res {
+				ids = append(ids, sn.ID)
+			}
+			if !reflect.DeepEqual(ids, tc.expected) {
+				t.Fatalf("expected subnets %v, got %v", tc.expected, ids)
+			}
+		})
+	}
+}
+
+func TestValidateZoneOffering(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	ec2Mock := mock_ec2iface.NewMockEC2API(mockCtrl)
+	ec2Mock.EXPECT().
+		DescribeSubnets(&ec2.DescribeSubnetsInput{SubnetIds: aws.StringSlice([]string{"subnet-shared"})}).
+		Return(&ec2.DescribeSubnetsOutput{Subnets: []*ec2.Subnet{{AvailabilityZone: aws.String("us-east-1e")}}}, nil)
+
+	scope, err := actuators.NewScope(actuators.ScopeParams{
+		Cluster:    &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"}},
+		AWSClients: actuators.AWSClients{EC2: ec2Mock},
+		InstanceTypes: &fakeInstanceTypes{
+			zones: map[string][]string{"p4d.24xlarge": {"us-east-1a"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create test context: %v", err)
+	}
+	scope.ClusterStatus.Network.Subnets = v1alpha1.Subnets{{ID: "subnet-a", AvailabilityZone: "us-east-1a"}}
+
+	s := NewService(scope)
+	if err := s.validateZoneOffering("p4d.24xlarge", "subnet-a"); err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+
+	if err := s.validateZoneOffering("p4d.24xlarge", "subnet-shared"); !awserrors.IsInvalidConfiguration(err) {
+		t.Fatalf("expected an invalid configuration error, got: %v", err)
+	}
+}
@@ -0,0 +1,229 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ec2
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/pkg/errors"
+	"k8s.io/klog"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/filter"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/awserrors"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/tags"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/record"
+)
+
+func (s *Service) reconcileManagementPeering() error {
+	peering := s.scope.ClusterConfig.ManagementPeering
+	if peering == nil {
+		return nil
+	}
+
+	klog.V(2).Infof("Reconciling peering with management VPC %q", peering.VPCID)
+
+	pcx, err := s.describeManagementPeering()
+	if err != nil {
+		return err
+	}
+
+	if pcx == nil {
+		pcx, err = s.createManagementPeering(peering.VPCID)
+		if err != nil {
+			return err
+		}
+	}
+
+	id := aws.StringValue(pcx.VpcPeeringConnectionId)
+	s.scope.Network().ManagementPeeringID = aws.String(id)
+
+	var status string
+	if pcx.Status != nil {
+		status = aws.StringValue(pcx.Status.Code)
+	}
+
+	switch status {
+	case ec2.VpcPeeringConnectionStateReasonCodePendingAcceptance:
+		if _, err := s.scope.EC2.AcceptVpcPeeringConnection(&ec2.AcceptVpcPeeringConnectionInput{VpcPeeringConnectionId: aws.String(id)}); err != nil {
+			return errors.Wrapf(err, "failed to accept vpc peering connection %q", id)
+		}
+
+		klog.V(2).Infof("Accepted vpc peering connection %q", id)
+	case ec2.VpcPeeringConnectionStateReasonCodeProvisioning, ec2.VpcPeeringConnectionStateReasonCodeActive:
+	default:
+		return awserrors.NewFailedDependency(
+			errors.Errorf("vpc peering connection %q is not ready to be accepted yet", id),
+		)
+	}
+
+	// Route traffic for the management VPC from the cluster subnets through the peering connection.
+	clusterRouteTables, err := s.describeVpcRouteTables()
+	if err != nil {
+		return err
+	}
+
+	if err := s.ensurePeeringRoutes(clusterRouteTables, peering.CidrBlock, id); err != nil {
+		return err
+	}
+
+	if len(peering.RouteTableIDs) == 0 {
+		return nil
+	}
+
+	// Route traffic for the cluster VPC from the management VPC through the peering connection.
+	out, err := s.scope.EC2.DescribeRouteTables(&ec2.DescribeRouteTablesInput{
+		RouteTableIds: aws.StringSlice(peering.RouteTableIDs),
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to describe route tables of management vpc %q", peering.VPCID)
+	}
+
+	return s.ensurePeeringRoutes(out.RouteTables, s.scope.VPC().CidrBlock, id)
+}
+
+func (s *Service) deleteManagementPeering() error {
+	pcx, err := s.describeManagementPeering()
+	if err != nil {
+		return err
+	}
+
+	if pcx == nil {
+		return nil
+	}
+
+	id := aws.StringValue(pcx.VpcPeeringConnectionId)
+
+	// Remove the routes to the cluster VPC from the management VPC, the ones in
+	// the cluster VPC are deleted along with its route tables. Routes removed
+	// by an earlier, interrupted deletion are skipped.
+	if peering := s.scope.ClusterConfig.ManagementPeering; peering != nil {
+		for _, rt := range peering.RouteTableIDs {
+			input := &ec2.DeleteRouteInput{
+				RouteTableId:         aws.String(rt),
+				DestinationCidrBlock: aws.String(s.scope.VPC().CidrBlock),
+			}
+
+			if _, err := s.scope.EC2.DeleteRoute(input); err != nil {
+				if code, _ := awserrors.Code(err); code == awserrors.RouteNotFound {
+					continue
+				}
+				return errors.Wrapf(err, "failed to delete route to %q from route table %q", s.scope.VPC().CidrBlock, rt)
+			}
+		}
+	}
+
+	if _, err := s.scope.EC2.DeleteVpcPeeringConnection(&ec2.DeleteVpcPeeringConnectionInput{VpcPeeringConnectionId: aws.String(id)}); err != nil {
+		return errors.Wrapf(err, "failed to delete vpc peering connection %q", id)
+	}
+
+	klog.Infof("Deleted vpc peering connection %q", id)
+	record.Eventf(s.scope.Cluster, "DeletedVPCPeeringConnection", "Deleted VPC peering connection %q", id)
+	s.scope.Network().ManagementPeeringID = nil
+	return nil
+}
+
+func (s *Service) describeManagementPeering() (*ec2.VpcPeeringConnection, error) {
+	input := &ec2.DescribeVpcPeeringConnectionsInput{
+		Filters: []*ec2.Filter{
+			filter.EC2.RequesterVPC(s.scope.VPC().ID),
+			filter.EC2.ClusterOwned(s.scope.Name()),
+			filter.EC2.VPCPeeringStates(
+				ec2.VpcPeeringConnectionStateReasonCodeInitiatingRequest,
+				ec2.VpcPeeringConnectionStateReasonCodePendingAcceptance,
+				ec2.VpcPeeringConnectionStateReasonCodeProvisioning,
+				ec2.VpcPeeringConnectionStateReasonCodeActive,
+			),
+		},
+	}
+
+	out, err := s.scope.EC2.DescribeVpcPeeringConnections(input)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to describe vpc peering connections of vpc %q", s.scope.VPC().ID)
+	}
+
+	if len(out.VpcPeeringConnections) == 0 {
+		return nil, nil
+	}
+
+	return out.VpcPeeringConnections[0], nil
+}
+
+func (s *Service) createManagementPeering(peerVPCID string) (*ec2.VpcPeeringConnection, error) {
+	out, err := s.scope.EC2.CreateVpcPeeringConnection(&ec2.CreateVpcPeeringConnectionInput{
+		VpcId:     aws.String(s.scope.VPC().ID),
+		PeerVpcId: aws.String(peerVPCID),
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create vpc peering connection between %q and %q", s.scope.VPC().ID, peerVPCID)
+	}
+
+	id := aws.StringValue(out.VpcPeeringConnection.VpcPeeringConnectionId)
+
+	applyTagsParams := &tags.ApplyParams{
+		EC2Client: s.scope.EC2,
+		BuildParams: tags.BuildParams{
+			ClusterName: s.scope.Name(),
+			ResourceID:  id,
+			Lifecycle:   tags.ResourceLifecycleOwned,
+			Name:        aws.String(s.scope.ResourceName("management-peering", 0)),
+			Role:        aws.String(tags.ValueCommonRole),
+		},
+	}
+
+	if err := tags.Apply(applyTagsParams); err != nil {
+		return nil, errors.Wrapf(err, "failed to tag vpc peering connection %q", id)
+	}
+
+	klog.V(2).Infof("Created vpc peering connection %q with management vpc %q", id, peerVPCID)
+	record.Eventf(s.scope.Cluster, "CreatedVPCPeeringConnection", "Created VPC peering connection %q with management VPC %q", id, peerVPCID)
+	return out.VpcPeeringConnection, nil
+}
+
+// ensurePeeringRoutes adds a route to the destination through the peering connection
+// to each of the route tables that doesn't have a route for the destination yet.
+func (s *Service) ensurePeeringRoutes(routeTables []*ec2.RouteTable, destination, pcxID string) error {
+	for _, rt := range routeTables {
+		found := false
+		for _, r := range rt.Routes {
+			if aws.StringValue(r.DestinationCidrBlock) != destination {
+				continue
+			}
+
+			found = true
+			if aws.StringValue(r.VpcPeeringConnectionId) != pcxID {
+				klog.Warningf("Route table %q already routes %q elsewhere, skipping peering route", aws.StringValue(rt.RouteTableId), destination)
+			}
+		}
+
+		if found {
+			continue
+		}
+
+		input := &ec2.CreateRouteInput{
+			RouteTableId:           rt.RouteTableId,
+			DestinationCidrBlock:   aws.String(destination),
+			VpcPeeringConnectionId: aws.String(pcxID),
+		}
+
+		if _, err := s.scope.EC2.CreateRoute(input); err != nil {
+			return errors.Wrapf(err, "failed to create route to %q in route table %q", destination, aws.StringValue(rt.RouteTableId))
+		}
+
+		klog.V(2).Infof("Routed %q through vpc peering connection %q in route table %q", destination, pcxID, aws.StringValue(rt.RouteTableId))
+	}
+
+	return nil
+}
@@ -0,0 +1,231 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ec2
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/golang/mock/gomock"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/actuators"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/awserrors"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/ec2/mock_ec2iface"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/elb/mock_elbiface"
+	clusterv1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+)
+
+func TestReconcileManagementPeering(t *testing.T) {
+	testCases := []struct {
+		name   string
+		expect func(m *mock_ec2iface.MockEC2APIMockRecorder)
+	}{
+		{
+			name: "creates, accepts and routes a new peering connection",
+			expect: func(m *mock_ec2iface.MockEC2APIMockRecorder) {
+				m.DescribeVpcPeeringConnections(gomock.AssignableToTypeOf(&ec2.DescribeVpcPeeringConnectionsInput{})).
+					Return(&ec2.DescribeVpcPeeringConnectionsOutput{}, nil)
+				m.CreateVpcPeeringConnection(&ec2.CreateVpcPeeringConnectionInput{
+					VpcId:     aws.String("vpc-cluster"),
+					PeerVpcId: aws.String("vpc-mgmt"),
+				}).Return(&ec2.CreateVpcPeeringConnectionOutput{
+					VpcPeeringConnection: &ec2.VpcPeeringConnection{
+						VpcPeeringConnectionId: aws.String("pcx-1"),
+						Status:                 &ec2.VpcPeeringConnectionStateReason{Code: aws.String("pending-acceptance")},
+					},
+				}, nil)
+				m.CreateTags(gomock.AssignableToTypeOf(&ec2.CreateTagsInput{})).Return(nil, nil)
+				m.AcceptVpcPeeringConnection(&ec2.AcceptVpcPeeringConnectionInput{
+					VpcPeeringConnectionId: aws.String("pcx-1"),
+				}).Return(&ec2.AcceptVpcPeeringConnectionOutput{}, nil)
+				m.DescribeRouteTablesPages(gomock.AssignableToTypeOf(&ec2.DescribeRouteTablesInput{}), gomock.Any()).
+					Do(func(_ *ec2.DescribeRouteTablesInput, fn func(*ec2.DescribeRouteTablesOutput, bool) bool) {
+						fn(&ec2.DescribeRouteTablesOutput{
+							RouteTables: []*ec2.RouteTable{{RouteTableId: aws.String("rtb-cluster")}},
+						}, true)
+					}).
+					Return(nil)
+				m.CreateRoute(&ec2.CreateRouteInput{
+					RouteTableId:           aws.String("rtb-cluster"),
+					DestinationCidrBlock:   aws.String("10.100.0.0/16"),
+					VpcPeeringConnectionId: aws.String("pcx-1"),
+				}).Return(&ec2.CreateRouteOutput{}, nil)
+				m.DescribeRouteTables(&ec2.DescribeRouteTablesInput{
+					RouteTableIds: aws.StringSlice([]string{"rtb-mgmt"}),
+				}).Return(&ec2.DescribeRouteTablesOutput{
+					RouteTables: []*ec2.RouteTable{{RouteTableId: aws.String("rtb-mgmt")}},
+				}, nil)
+				m.CreateRoute(&ec2.CreateRouteInput{
+					RouteTableId:           aws.String("rtb-mgmt"),
+					DestinationCidrBlock:   aws.String("10.0.0.0/16"),
+					VpcPeeringConnectionId: aws.String("pcx-1"),
+				}).Return(&ec2.CreateRouteOutput{}, nil)
+			},
+		},
+		{
+			name: "active peering connection with existing routes",
+			expect: func(m *mock_ec2iface.MockEC2APIMockRecorder) {
+				m.DescribeVpcPeeringConnections(gomock.AssignableToTypeOf(&ec2.DescribeVpcPeeringConnectionsInput{})).
+					Return(&ec2.DescribeVpcPeeringConnectionsOutput{
+						VpcPeeringConnections: []*ec2.VpcPeeringConnection{
+							{
+								VpcPeeringConnectionId: aws.String("pcx-1"),
+								Status:                 &ec2.VpcPeeringConnectionStateReason{Code: aws.String("active")},
+							},
+						},
+					}, nil)
+				m.DescribeRouteTablesPages(gomock.AssignableToTypeOf(&ec2.DescribeRouteTablesInput{}), gomock.Any()).
+					Do(func(_ *ec2.DescribeRouteTablesInput, fn func(*ec2.DescribeRouteTablesOutput, bool) bool) {
+						fn(&ec2.DescribeRouteTablesOutput{
+							RouteTables: []*ec2.RouteTable{
+								{
+									RouteTableId: aws.String("rtb-cluster"),
+									Routes: []*ec2.Route{
+										{DestinationCidrBlock: aws.String("10.100.0.0/16"), VpcPeeringConnectionId: aws.String("pcx-1")},
+									},
+								},
+							},
+						}, true)
+					}).
+					Return(nil)
+				m.DescribeRouteTables(&ec2.DescribeRouteTablesInput{
+					RouteTableIds: aws.StringSlice([]string{"rtb-mgmt"}),
+				}).Return(&ec2.DescribeRouteTablesOutput{
+					RouteTables: []*ec2.RouteTable{
+						{
+							RouteTableId: aws.String("rtb-mgmt"),
+							Routes: []*ec2.Route{
+								{DestinationCidrBlock: aws.String("10.0.0.0/16"), VpcPeeringConnectionId: aws.String("pcx-1")},
+							},
+						},
+					},
+				}, nil)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+
+			ec2Mock := mock_ec2iface.NewMockEC2API(mockCtrl)
+			elbMock := mock_elbiface.NewMockELBAPI(mockCtrl)
+
+			scope, err := actuators.NewScope(actuators.ScopeParams{
+				Cluster: &clusterv1.Cluster{
+					ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"},
+				},
+				AWSClients: actuators.AWSClients{
+					EC2: ec2Mock,
+					ELB: elbMock,
+				},
+			})
+			if err != nil {
+				t.Fatalf("Failed to create test context: %v", err)
+			}
+
+			scope.ClusterConfig = &v1alpha1.AWSClusterProviderSpec{
+				ManagementPeering: &v1alpha1.VPCPeering{
+					VPCID:         "vpc-mgmt",
+					CidrBlock:     "10.100.0.0/16",
+					RouteTableIDs: []string{"rtb-mgmt"},
+				},
+			}
+			scope.ClusterStatus = &v1alpha1.AWSClusterProviderStatus{
+				Network: v1alpha1.Network{
+					VPC: v1alpha1.VPC{ID: "vpc-cluster", CidrBlock: "10.0.0.0/16"},
+				},
+			}
+
+			tc.expect(ec2Mock.EXPECT())
+
+			s := NewService(scope)
+			if err := s.reconcileManagementPeering(); err != nil {
+				t.Fatalf("got an unexpected error: %v", err)
+			}
+
+			if id := aws.StringValue(scope.Network().ManagementPeeringID); id != "pcx-1" {
+				t.Fatalf("expected peering connection %q in status, got %q", "pcx-1", id)
+			}
+		})
+	}
+}
+
+func TestDeleteManagementPeering(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	ec2Mock := mock_ec2iface.NewMockEC2API(mockCtrl)
+	ec2Mock.EXPECT().
+		DescribeVpcPeeringConnections(gomock.AssignableToTypeOf(&ec2.DescribeVpcPeeringConnectionsInput{})).
+		Return(&ec2.DescribeVpcPeeringConnectionsOutput{
+			VpcPeeringConnections: []*ec2.VpcPeeringConnection{
+				{
+					VpcPeeringConnectionId: aws.String("pcx-1"),
+					Status:                 &ec2.VpcPeeringConnectionStateReason{Code: aws.String("active")},
+				},
+			},
+		}, nil)
+	// The route was already removed by an earlier, interrupted deletion.
+	ec2Mock.EXPECT().
+		DeleteRoute(&ec2.DeleteRouteInput{
+			RouteTableId:         aws.String("rtb-mgmt"),
+			DestinationCidrBlock: aws.String("10.0.0.0/16"),
+		}).
+		Return(nil, awserr.New(awserrors.RouteNotFound, "route not found", nil))
+	ec2Mock.EXPECT().
+		DeleteVpcPeeringConnection(&ec2.DeleteVpcPeeringConnectionInput{VpcPeeringConnectionId: aws.String("pcx-1")}).
+		Return(&ec2.DeleteVpcPeeringConnectionOutput{}, nil)
+
+	scope, err := actuators.NewScope(actuators.ScopeParams{
+		Cluster: &clusterv1.Cluster{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"},
+		},
+		AWSClients: actuators.AWSClients{
+			EC2: ec2Mock,
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create test context: %v", err)
+	}
+
+	scope.ClusterConfig = &v1alpha1.AWSClusterProviderSpec{
+		ManagementPeering: &v1alpha1.VPCPeering{
+			VPCID:         "vpc-mgmt",
+			CidrBlock:     "10.100.0.0/16",
+			RouteTableIDs: []string{"rtb-mgmt"},
+		},
+	}
+	scope.ClusterStatus = &v1alpha1.AWSClusterProviderStatus{
+		Network: v1alpha1.Network{
+			VPC:                 v1alpha1.VPC{ID: "vpc-cluster", CidrBlock: "10.0.0.0/16"},
+			ManagementPeeringID: aws.String("pcx-1"),
+		},
+	}
+
+	if err := NewService(scope).deleteManagementPeering(); err != nil {
+		t.Fatalf("got an unexpected error: %v", err)
+	}
+
+	if scope.Network().ManagementPeeringID != nil {
+		t.Fatalf("expected the peering connection to be removed from status")
+	}
+}
@@ -0,0 +1,147 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ec2
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/pkg/errors"
+	"k8s.io/klog"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/filter"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/awserrors"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/record"
+)
+
+const (
+	// placementGroupNameMaxLength is the maximum length of placement group names.
+	placementGroupNameMaxLength = 255
+
+	// errCodeDuplicatePlacementGroup is returned when creating a placement
+	// group which already exists, e.g. when another machine created it first.
+	errCodeDuplicatePlacementGroup = "InvalidPlacementGroup.Duplicate"
+)
+
+// placementStrategies are the strategies of the placement groups created for clusters.
+var placementStrategies = []v1alpha1.PlacementStrategy{
+	v1alpha1.PlacementStrategyCluster,
+	v1alpha1.PlacementStrategySpread,
+	v1alpha1.PlacementStrategyPartition,
+}
+
+// placementGroupName returns the name of the placement group created for the
+// cluster with the given strategy. Placement groups cannot be tagged, so the
+// groups created for the cluster are only known by their name.
+func (s *Service) placementGroupName(strategy v1alpha1.PlacementStrategy) string {
+	return s.scope.ResourceName(fmt.Sprintf("placement-%s", strategy), placementGroupNameMaxLength)
+}
+
+// reconcilePlacementGroup returns the name of the placement group a machine is
+// launched in, creating the placement group of the cluster with the requested
+// strategy if it does not exist yet.
+func (s *Service) reconcilePlacementGroup(pg *v1alpha1.PlacementGroup) (string, error) {
+	if pg == nil {
+		return "", nil
+	}
+
+	if (pg.Name == "") == (pg.Strategy == "") {
+		return "", errors.New("placement group must specify exactly one of name or strategy")
+	}
+
+	if pg.Name != "" {
+		return pg.Name, nil
+	}
+
+	known := false
+	for _, strategy := range placementStrategies {
+		known = known || pg.Strategy == strategy
+	}
+	if !known {
+		return "", errors.Errorf("unknown placement strategy %q", pg.Strategy)
+	}
+
+	name := s.placementGroupName(pg.Strategy)
+
+	groups, err := s.describePlacementGroups(name)
+	if err != nil {
+		return "", err
+	}
+	if len(groups) > 0 {
+		return name, nil
+	}
+
+	input := &ec2.CreatePlacementGroupInput{
+		GroupName: aws.String(name),
+		Strategy:  aws.String(string(pg.Strategy)),
+	}
+
+	if _, err := s.scope.EC2.CreatePlacementGroup(input); err != nil {
+		if code, _ := awserrors.Code(err); code == errCodeDuplicatePlacementGroup {
+			return name, nil
+		}
+		return "", errors.Wrapf(err, "failed to create placement group %q", name)
+	}
+
+	klog.V(2).Infof("Created %s placement group %q for cluster %q", pg.Strategy, name, s.scope.Name())
+	record.Eventf(s.scope.Cluster, "CreatedPlacementGroup", "Created new %s placement group %q", pg.Strategy, name)
+	return name, nil
+}
+
+// DeletePlacementGroups deletes the placement groups created for the cluster.
+// Placement groups referenced by name by the machines are left untouched.
+func (s *Service) DeletePlacementGroups() error {
+	names := make([]string, 0, len(placementStrategies))
+	for _, strategy := range placementStrategies {
+		names = append(names, s.placementGroupName(strategy))
+	}
+
+	groups, err := s.describePlacementGroups(names...)
+	if err != nil {
+		return err
+	}
+
+	for _, pg := range groups {
+		name := aws.StringValue(pg.GroupName)
+		if _, err := s.scope.EC2.DeletePlacementGroup(&ec2.DeletePlacementGroupInput{GroupName: pg.GroupName}); err != nil {
+			return errors.Wrapf(err, "failed to delete placement group %q", name)
+		}
+
+		klog.V(2).Infof("Deleted placement group %q", name)
+		record.Eventf(s.scope.Cluster, "DeletedPlacementGroup", "Deleted placement group %q", name)
+	}
+
+	return nil
+}
+
+// describePlacementGroups returns the pending and available placement groups with the given names.
+func (s *Service) describePlacementGroups(names ...string) ([]*ec2.PlacementGroup, error) {
+	input := &ec2.DescribePlacementGroupsInput{
+		Filters: []*ec2.Filter{
+			filter.EC2.PlacementGroupNames(names...),
+			filter.EC2.PlacementGroupStates(ec2.PlacementGroupStatePending, ec2.PlacementGroupStateAvailable),
+		},
+	}
+
+	out, err := s.scope.EC2.DescribePlacementGroups(input)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to describe placement groups %v", names)
+	}
+
+	return out.PlacementGroups, nil
+}
@@ -0,0 +1,184 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ec2
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/golang/mock/gomock"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/actuators"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/filter"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/ec2/mock_ec2iface"
+	clusterv1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+)
+
+func TestReconcilePlacementGroup(t *testing.T) {
+	describeSpread := &ec2.DescribePlacementGroupsInput{
+		Filters: []*ec2.Filter{
+			filter.EC2.PlacementGroupNames("test-cluster-placement-spread"),
+			filter.EC2.PlacementGroupStates("pending", "available"),
+		},
+	}
+
+	testCases := []struct {
+		name        string
+		pg          *v1alpha1.PlacementGroup
+		expect      func(m *mock_ec2iface.MockEC2APIMockRecorder)
+		expectName  string
+		expectError bool
+	}{
+		{
+			name: "no placement group",
+		},
+		{
+			name:       "existing placement group",
+			pg:         &v1alpha1.PlacementGroup{Name: "shared"},
+			expectName: "shared",
+		},
+		{
+			name:        "both name and strategy",
+			pg:          &v1alpha1.PlacementGroup{Name: "shared", Strategy: v1alpha1.PlacementStrategySpread},
+			expectError: true,
+		},
+		{
+			name:        "unknown strategy",
+			pg:          &v1alpha1.PlacementGroup{Strategy: "random"},
+			expectError: true,
+		},
+		{
+			name: "cluster placement group exists",
+			pg:   &v1alpha1.PlacementGroup{Strategy: v1alpha1.PlacementStrategySpread},
+			expect: func(m *mock_ec2iface.MockEC2APIMockRecorder) {
+				m.DescribePlacementGroups(describeSpread).
+					Return(&ec2.DescribePlacementGroupsOutput{
+						PlacementGroups: []*ec2.PlacementGroup{{GroupName: aws.String("test-cluster-placement-spread")}},
+					}, nil)
+			},
+			expectName: "test-cluster-placement-spread",
+		},
+		{
+			name: "cluster placement group created",
+			pg:   &v1alpha1.PlacementGroup{Strategy: v1alpha1.PlacementStrategySpread},
+			expect: func(m *mock_ec2iface.MockEC2APIMockRecorder) {
+				m.DescribePlacementGroups(describeSpread).
+					Return(&ec2.DescribePlacementGroupsOutput{}, nil)
+				m.CreatePlacementGroup(&ec2.CreatePlacementGroupInput{
+					GroupName: aws.String("test-cluster-placement-spread"),
+					Strategy:  aws.String("spread"),
+				}).
+					Return(&ec2.CreatePlacementGroupOutput{}, nil)
+			},
+			expectName: "test-cluster-placement-spread",
+		},
+		{
+			name: "cluster placement group created by another machine",
+			pg:   &v1alpha1.PlacementGroup{Strategy: v1alpha1.PlacementStrategySpread},
+			expect: func(m *mock_ec2iface.MockEC2APIMockRecorder) {
+				m.DescribePlacementGroups(describeSpread).
+					Return(&ec2.DescribePlacementGroupsOutput{}, nil)
+				m.CreatePlacementGroup(gomock.Any()).
+					Return(nil, awserr.New(errCodeDuplicatePlacementGroup, "duplicate", nil))
+			},
+			expectName: "test-cluster-placement-spread",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+
+			ec2Mock := mock_ec2iface.NewMockEC2API(mockCtrl)
+			if tc.expect != nil {
+				tc.expect(ec2Mock.EXPECT())
+			}
+
+			scope, err := actuators.NewScope(actuators.ScopeParams{
+				Cluster: &clusterv1.Cluster{
+					ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"},
+				},
+				AWSClients: actuators.AWSClients{
+					EC2: ec2Mock,
+				},
+			})
+			if err != nil {
+				t.Fatalf("Failed to create test context: %v", err)
+			}
+
+			name, err := NewService(scope).reconcilePlacementGroup(tc.pg)
+			if tc.expectError {
+				if err == nil {
+					t.Fatalf("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("did not expect error: %v", err)
+			}
+
+			if name != tc.expectName {
+				t.Fatalf("expected placement group %q, got %q", tc.expectName, name)
+			}
+		})
+	}
+}
+
+func TestDeletePlacementGroups(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	// Only the placement groups named after the cluster are deleted.
+	ec2Mock := mock_ec2iface.NewMockEC2API(mockCtrl)
+	ec2Mock.EXPECT().
+		DescribePlacementGroups(&ec2.DescribePlacementGroupsInput{
+			Filters: []*ec2.Filter{
+				filter.EC2.PlacementGroupNames(
+					"test-cluster-placement-cluster",
+					"test-cluster-placement-spread",
+					"test-cluster-placement-partition",
+				),
+				filter.EC2.PlacementGroupStates("pending", "available"),
+			},
+		}).
+		Return(&ec2.DescribePlacementGroupsOutput{
+			PlacementGroups: []*ec2.PlacementGroup{{GroupName: aws.String("test-cluster-placement-cluster")}},
+		}, nil)
+	ec2Mock.EXPECT().
+		DeletePlacementGroup(&ec2.DeletePlacementGroupInput{GroupName: aws.String("test-cluster-placement-cluster")}).
+		Return(&ec2.DeletePlacementGroupOutput{}, nil)
+
+	scope, err := actuators.NewScope(actuators.ScopeParams{
+		Cluster: &clusterv1.Cluster{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"},
+		},
+		AWSClients: actuators.AWSClients{
+			EC2: ec2Mock,
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create test context: %v", err)
+	}
+
+	if err := NewService(scope).DeletePlacementGroups(); err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+}
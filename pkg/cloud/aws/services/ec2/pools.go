@@ -0,0 +1,77 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ec2
+
+import (
+	"sort"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/filter"
+)
+
+// autoScalingGroupTag is the tag of the instances launched by an Auto
+// Scaling group, holding the name of the group.
+const autoScalingGroupTag = "aws:autoscaling:groupName"
+
+// SpotMachinePools returns the names of the Auto Scaling groups of the
+// machine pools of the cluster running spot instances.
+func (s *Service) SpotMachinePools() ([]string, error) {
+	var names []string
+	for _, pool := range s.scope.ClusterConfig.MachinePools {
+		names = append(names, pool.AutoScalingGroupName)
+	}
+
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	input := &ec2.DescribeInstancesInput{
+		Filters: []*ec2.Filter{
+			filter.EC2.AutoScalingGroups(names...),
+			filter.EC2.InstanceLifecycle(ec2.InstanceLifecycleTypeSpot),
+			filter.EC2.InstanceStates(ec2.InstanceStateNamePending, ec2.InstanceStateNameRunning),
+		},
+	}
+
+	spot := map[string]bool{}
+	err := s.scope.EC2.DescribeInstancesPages(input,
+		func(page *ec2.DescribeInstancesOutput, lastPage bool) bool {
+			for _, res := range page.Reservations {
+				for _, inst := range res.Instances {
+					for _, tag := range inst.Tags {
+						if aws.StringValue(tag.Key) == autoScalingGroupTag {
+							spot[aws.StringValue(tag.Value)] = true
+						}
+					}
+				}
+			}
+			return true
+		})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to describe the spot instances of the machine pools")
+	}
+
+	pools := make([]string, 0, len(spot))
+	for name := range spot {
+		pools = append(pools, name)
+	}
+	sort.Strings(pools)
+
+	return pools, nil
+}
@@ -0,0 +1,80 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ec2
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/golang/mock/gomock"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/actuators"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/ec2/mock_ec2iface"
+	clusterv1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+)
+
+func TestSpotMachinePools(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	ec2Mock := mock_ec2iface.NewMockEC2API(mockCtrl)
+	ec2Mock.EXPECT().
+		DescribeInstancesPages(gomock.AssignableToTypeOf(&ec2.DescribeInstancesInput{}), gomock.Any()).
+		Do(func(input *ec2.DescribeInstancesInput, fn func(*ec2.DescribeInstancesOutput, bool) bool) {
+			if groups := aws.StringValueSlice(input.Filters[0].Values); !reflect.DeepEqual(groups, []string{"pool-a", "pool-b"}) {
+				t.Fatalf("expected the instances of the machine pools to be described, got %v", groups)
+			}
+			fn(&ec2.DescribeInstancesOutput{
+				Reservations: []*ec2.Reservation{{Instances: []*ec2.Instance{
+					{InstanceId: aws.String("i-1"), Tags: []*ec2.Tag{{Key: aws.String(autoScalingGroupTag), Value: aws.String("pool-b")}}},
+					{InstanceId: aws.String("i-2"), Tags: []*ec2.Tag{{Key: aws.String(autoScalingGroupTag), Value: aws.String("pool-b")}}},
+				}}},
+			}, true)
+		}).
+		Return(nil)
+
+	scope, err := actuators.NewScope(actuators.ScopeParams{
+		Cluster:    &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"}},
+		AWSClients: actuators.AWSClients{EC2: ec2Mock},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create test context: %v", err)
+	}
+
+	s := NewService(scope)
+
+	pools, err := s.SpotMachinePools()
+	if err != nil || len(pools) != 0 {
+		t.Fatalf("expected no spot pools without machine pools, got %v, %v", pools, err)
+	}
+
+	scope.ClusterConfig.MachinePools = []v1alpha1.MachinePool{
+		{AutoScalingGroupName: "pool-a"},
+		{AutoScalingGroupName: "pool-b"},
+	}
+
+	pools, err = s.SpotMachinePools()
+	if err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+	if !reflect.DeepEqual(pools, []string{"pool-b"}) {
+		t.Fatalf("expected pool-b to run spot instances, got %v", pools)
+	}
+}
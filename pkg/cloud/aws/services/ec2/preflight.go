@@ -0,0 +1,43 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ec2
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// preflightEndpoints returns the endpoints an instance must reach to bootstrap.
+// The API server endpoint is left out for the first control plane machine,
+// which serves it.
+func (s *Service) preflightEndpoints(includeAPIServer bool) []string {
+	domain := "amazonaws.com"
+	if strings.HasPrefix(s.scope.Region(), "cn-") {
+		domain = "amazonaws.com.cn"
+	}
+
+	var endpoints []string
+	if includeAPIServer {
+		endpoints = append(endpoints, net.JoinHostPort(s.scope.APIServerEndpoint(), "6443"))
+	}
+
+	return append(endpoints,
+		fmt.Sprintf("ec2.%s.%s:443", s.scope.Region(), domain),
+		fmt.Sprintf("api.ecr.%s.%s:443", s.scope.Region(), domain),
+	)
+}
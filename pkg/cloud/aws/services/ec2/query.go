@@ -0,0 +1,52 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ec2
+
+import (
+	"io/ioutil"
+	"net/url"
+
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/pkg/errors"
+)
+
+// withQuery modifies the query string of a request once it is built, before
+// it is signed, with parameters the vendored SDK predates.
+func withQuery(set func(query url.Values)) request.Option {
+	return func(r *request.Request) {
+		r.Handlers.Build.PushBack(func(r *request.Request) {
+			if r.Error != nil {
+				return
+			}
+
+			body, err := ioutil.ReadAll(r.Body)
+			if err != nil {
+				r.Error = errors.Wrapf(err, "failed to read %s request", r.Operation.Name)
+				return
+			}
+
+			query, err := url.ParseQuery(string(body))
+			if err != nil {
+				r.Error = errors.Wrapf(err, "failed to parse %s request", r.Operation.Name)
+				return
+			}
+
+			set(query)
+			r.SetBufferBody([]byte(query.Encode()))
+		})
+	}
+}
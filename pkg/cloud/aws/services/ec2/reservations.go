@@ -0,0 +1,115 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ec2
+
+import (
+	"sort"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/pkg/errors"
+	"k8s.io/klog"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/filter"
+)
+
+// ReconcileReservedInstanceCoverage compares the running cluster instances with
+// the active reserved instances of the account, and records the result in the
+// cluster status.
+func (s *Service) ReconcileReservedInstanceCoverage() error {
+	if !s.scope.ClusterConfig.ReportReservedInstanceCoverage {
+		s.scope.ClusterStatus.ReservedInstanceCoverage = nil
+		return nil
+	}
+
+	klog.V(2).Info("Reconciling reserved instance coverage")
+
+	running, err := s.runningInstanceTypes()
+	if err != nil {
+		return err
+	}
+
+	reserved, err := s.reservedInstanceTypes()
+	if err != nil {
+		return err
+	}
+
+	coverage := make([]v1alpha1.ReservedInstanceCoverage, 0, len(running))
+	for instanceType, count := range running {
+		coverage = append(coverage, v1alpha1.ReservedInstanceCoverage{
+			InstanceType: instanceType,
+			Running:      count,
+			Reserved:     reserved[instanceType],
+		})
+	}
+
+	sort.Slice(coverage, func(i, j int) bool {
+		return coverage[i].InstanceType < coverage[j].InstanceType
+	})
+
+	s.scope.ClusterStatus.ReservedInstanceCoverage = coverage
+	klog.V(2).Info("Reconcile reserved instance coverage completed successfully")
+	return nil
+}
+
+// runningInstanceTypes returns the number of running cluster instances per instance type.
+func (s *Service) runningInstanceTypes() (map[string]int64, error) {
+	input := &ec2.DescribeInstancesInput{
+		Filters: []*ec2.Filter{
+			filter.EC2.Cluster(s.scope.Name()),
+			filter.EC2.InstanceStates(ec2.InstanceStateNamePending, ec2.InstanceStateNameRunning),
+		},
+	}
+
+	res := map[string]int64{}
+	err := s.scope.EC2.DescribeInstancesPages(input,
+		func(page *ec2.DescribeInstancesOutput, lastPage bool) bool {
+			for _, reservation := range page.Reservations {
+				for _, instance := range reservation.Instances {
+					res[aws.StringValue(instance.InstanceType)]++
+				}
+			}
+			return !lastPage
+		})
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to describe instances in cluster %q", s.scope.Name())
+	}
+
+	return res, nil
+}
+
+// reservedInstanceTypes returns the number of active reserved instances per instance type.
+func (s *Service) reservedInstanceTypes() (map[string]int64, error) {
+	input := &ec2.DescribeReservedInstancesInput{
+		Filters: []*ec2.Filter{
+			filter.EC2.ReservedInstanceStates(ec2.ReservedInstanceStateActive),
+		},
+	}
+
+	out, err := s.scope.EC2.DescribeReservedInstances(input)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to describe reserved instances")
+	}
+
+	res := map[string]int64{}
+	for _, ri := range out.ReservedInstances {
+		res[aws.StringValue(ri.InstanceType)] += aws.Int64Value(ri.InstanceCount)
+	}
+
+	return res, nil
+}
@@ -0,0 +1,98 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ec2
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/golang/mock/gomock"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/actuators"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/ec2/mock_ec2iface"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/elb/mock_elbiface"
+	clusterv1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+)
+
+func TestReconcileReservedInstanceCoverage(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	ec2Mock := mock_ec2iface.NewMockEC2API(mockCtrl)
+	elbMock := mock_elbiface.NewMockELBAPI(mockCtrl)
+
+	scope, err := actuators.NewScope(actuators.ScopeParams{
+		Cluster: &clusterv1.Cluster{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"},
+		},
+		AWSClients: actuators.AWSClients{
+			EC2: ec2Mock,
+			ELB: elbMock,
+		},
+	})
+
+	if err != nil {
+		t.Fatalf("Failed to create test context: %v", err)
+	}
+
+	scope.ClusterConfig = &v1alpha1.AWSClusterProviderSpec{
+		ReportReservedInstanceCoverage: true,
+	}
+
+	ec2Mock.EXPECT().
+		DescribeInstancesPages(gomock.AssignableToTypeOf(&ec2.DescribeInstancesInput{}), gomock.Any()).
+		Do(func(_ *ec2.DescribeInstancesInput, fn func(*ec2.DescribeInstancesOutput, bool) bool) {
+			fn(&ec2.DescribeInstancesOutput{
+				Reservations: []*ec2.Reservation{
+					{
+						Instances: []*ec2.Instance{
+							{InstanceType: aws.String("m5.large")},
+							{InstanceType: aws.String("m5.large")},
+							{InstanceType: aws.String("t3.medium")},
+						},
+					},
+				},
+			}, true)
+		}).
+		Return(nil)
+
+	ec2Mock.EXPECT().
+		DescribeReservedInstances(gomock.AssignableToTypeOf(&ec2.DescribeReservedInstancesInput{})).
+		Return(&ec2.DescribeReservedInstancesOutput{
+			ReservedInstances: []*ec2.ReservedInstances{
+				{InstanceType: aws.String("m5.large"), InstanceCount: aws.Int64(1)},
+				{InstanceType: aws.String("c5.xlarge"), InstanceCount: aws.Int64(4)},
+			},
+		}, nil)
+
+	s := NewService(scope)
+	if err := s.ReconcileReservedInstanceCoverage(); err != nil {
+		t.Fatalf("got an unexpected error: %v", err)
+	}
+
+	expected := []v1alpha1.ReservedInstanceCoverage{
+		{InstanceType: "m5.large", Running: 2, Reserved: 1},
+		{InstanceType: "t3.medium", Running: 1, Reserved: 0},
+	}
+
+	if !reflect.DeepEqual(scope.ClusterStatus.ReservedInstanceCoverage, expected) {
+		t.Fatalf("expected coverage %+v, got %+v", expected, scope.ClusterStatus.ReservedInstanceCoverage)
+	}
+}
@@ -145,18 +145,25 @@ func (s *Service) deleteRouteTables() error {
 }
 
 func (s *Service) describeVpcRouteTables() ([]*ec2.RouteTable, error) {
-	out, err := s.scope.EC2.DescribeRouteTables(&ec2.DescribeRouteTablesInput{
+	input := &ec2.DescribeRouteTablesInput{
 		Filters: []*ec2.Filter{
 			filter.EC2.VPC(s.scope.VPC().ID),
 			filter.EC2.Cluster(s.scope.Name()),
 		},
-	})
+	}
+
+	var routeTables []*ec2.RouteTable
+	err := s.scope.EC2.DescribeRouteTablesPages(input,
+		func(page *ec2.DescribeRouteTablesOutput, lastPage bool) bool {
+			routeTables = append(routeTables, page.RouteTables...)
+			return !lastPage
+		})
 
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to describe route tables in vpc %q", s.scope.VPC().ID)
 	}
 
-	return out.RouteTables, nil
+	return routeTables, nil
 }
 
 func (s *Service) createRouteTableWithRoutes(routes []*ec2.Route, isPublic bool) (*v1alpha1.RouteTable, error) {
@@ -66,8 +66,8 @@ func TestReconcileRouteTables(t *testing.T) {
 				},
 			},
 			expect: func(m *mock_ec2iface.MockEC2APIMockRecorder) {
-				m.DescribeRouteTables(gomock.AssignableToTypeOf(&ec2.DescribeRouteTablesInput{})).
-					Return(&ec2.DescribeRouteTablesOutput{}, nil)
+				m.DescribeRouteTablesPages(gomock.AssignableToTypeOf(&ec2.DescribeRouteTablesInput{}), gomock.Any()).
+					Return(nil)
 
 				privateRouteTable := m.CreateRouteTable(gomock.Eq(&ec2.CreateRouteTableInput{VpcId: aws.String("vpc-routetables")})).
 					Return(&ec2.CreateRouteTableOutput{RouteTable: &ec2.RouteTable{RouteTableId: aws.String("rt-1")}}, nil)
@@ -134,8 +134,8 @@ func TestReconcileRouteTables(t *testing.T) {
 				},
 			},
 			expect: func(m *mock_ec2iface.MockEC2APIMockRecorder) {
-				m.DescribeRouteTables(gomock.AssignableToTypeOf(&ec2.DescribeRouteTablesInput{})).
-					Return(&ec2.DescribeRouteTablesOutput{}, nil)
+				m.DescribeRouteTablesPages(gomock.AssignableToTypeOf(&ec2.DescribeRouteTablesInput{}), gomock.Any()).
+					Return(nil)
 			},
 			err: errors.New(`no nat gateways available in "us-east-1a"`),
 		},
@@ -18,6 +18,7 @@ package ec2
 
 import (
 	"fmt"
+	"strings"
 
 	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/converters"
 	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/filter"
@@ -26,11 +27,18 @@ import (
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/klog"
 	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/actuators"
 	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/awserrors"
 )
 
+const (
+	// securityGroupNameMaxLength is the maximum length of a security group name.
+	securityGroupNameMaxLength = 255
+)
+
 func (s *Service) reconcileSecurityGroups() error {
 	klog.V(2).Infof("Reconciling security groups")
 
@@ -49,20 +57,38 @@ func (s *Service) reconcileSecurityGroups() error {
 		v1alpha1.SecurityGroupControlPlane,
 		v1alpha1.SecurityGroupNode,
 	}
+	if s.scope.ClusterConfig.RestrictedEgress {
+		roles = append(roles, v1alpha1.SecurityGroupAPIServerLB)
+	}
+
+	// Security groups managed outside of the cluster are only looked up.
+	if s.scope.Skips(actuators.SkipSecurityGroupsAnnotation) {
+		for _, role := range roles {
+			if existing, ok := sgs[s.securityGroupName(role)]; ok {
+				s.scope.SecurityGroups()[role] = existing
+			}
+		}
+
+		klog.V(2).Infof("Skipping reconciliation of security groups for cluster %q", s.scope.Name())
+		return nil
+	}
 
 	// First iteration makes sure that the security group are valid and fully created.
+	batch := tags.NewBatch(s.scope.EC2)
 	for _, role := range roles {
 		sg := s.getDefaultSecurityGroup(role)
-		existing, ok := sgs[*sg.GroupName]
+		existing, ok := sgs[s.securityGroupName(role)]
 
 		if !ok {
 			if err := s.createSecurityGroup(role, sg); err != nil {
 				return err
 			}
 
+			// New security groups allow all egress traffic.
 			s.scope.SecurityGroups()[role] = &v1alpha1.SecurityGroup{
-				ID:   *sg.GroupId,
-				Name: *sg.GroupName,
+				ID:          *sg.GroupId,
+				Name:        *sg.GroupName,
+				EgressRules: defaultEgressRules(),
 			}
 			klog.V(2).Infof("Security group for role %q: %v", role, s.scope.SecurityGroups()[role])
 			continue
@@ -72,14 +98,13 @@ func (s *Service) reconcileSecurityGroups() error {
 		s.scope.SecurityGroups()[role] = existing
 
 		// Make sure tags are up to date.
-		err := tags.Ensure(existing.Tags, &tags.ApplyParams{
-			EC2Client:   s.scope.EC2,
-			BuildParams: s.getSecurityGroupTagParams(existing.Name, role),
-		})
+		params := s.getSecurityGroupTagParams(existing.Name, role)
+		params.ResourceID = existing.ID
+		batch.Ensure(existing.Tags, params)
+	}
 
-		if err != nil {
-			return errors.Wrapf(err, "failed to ensure tags on security group %q", existing.ID)
-		}
+	if err := batch.Apply(); err != nil {
+		return errors.Wrap(err, "failed to ensure tags on security groups")
 	}
 
 	// Second iteration creates or updates all permissions on the security group to match
@@ -109,12 +134,56 @@ func (s *Service) reconcileSecurityGroups() error {
 
 			klog.V(2).Infof("Authorized ingress rules %v in security group %q", toAuthorize, sg)
 		}
+
+		if err := s.reconcileSecurityGroupEgressRules(role, sg); err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
+// reconcileSecurityGroupEgressRules updates the egress rules of a security
+// group, which are only restricted when configured for the cluster. The
+// egress of the other security groups, and of the bastion, is left as is,
+// including rules added outside of the cluster.
+func (s *Service) reconcileSecurityGroupEgressRules(role v1alpha1.SecurityGroupRole, sg *v1alpha1.SecurityGroup) error {
+	if !s.scope.ClusterConfig.RestrictedEgress || role == v1alpha1.SecurityGroupBastion {
+		return nil
+	}
+
+	current := sg.EgressRules
+	want := s.getSecurityGroupEgressRules(role)
+
+	// Rules are authorized first, so that traffic is never fully blocked in between.
+	toAuthorize := want.Difference(current)
+	if len(toAuthorize) > 0 {
+		if err := s.authorizeSecurityGroupEgressRules(sg.ID, toAuthorize); err != nil {
+			return err
+		}
+
+		klog.V(2).Infof("Authorized egress rules %v in security group %q", toAuthorize, sg)
+	}
+
+	toRevoke := current.Difference(want)
+	if len(toRevoke) > 0 {
+		if err := s.revokeSecurityGroupEgressRules(sg.ID, toRevoke); err != nil {
+			return err
+		}
+
+		klog.V(2).Infof("Revoked egress rules %v from security group %q", toRevoke, sg)
+	}
+
+	sg.EgressRules = want
+	return nil
+}
+
 func (s *Service) deleteSecurityGroups() error {
+	if s.scope.Skips(actuators.SkipSecurityGroupsAnnotation) {
+		klog.V(2).Infof("Skipping deletion of security groups for cluster %q", s.scope.Name())
+		return nil
+	}
+
 	for _, sg := range s.scope.SecurityGroups() {
 		current := sg.IngressRules
 
@@ -123,6 +192,19 @@ func (s *Service) deleteSecurityGroups() error {
 		}
 
 		klog.V(2).Infof("Revoked ingress rules %v from security group %q", current, sg.ID)
+
+		// Restricted egress rules reference the other security groups of the cluster.
+		if len(sg.EgressRules) > 0 {
+			if err := s.revokeSecurityGroupEgressRules(sg.ID, sg.EgressRules); awserrors.IsIgnorableSecurityGroupError(err) != nil {
+				return err
+			}
+		}
+	}
+
+	// Report everything still using the security groups at once, rather than
+	// what uses the first one that cannot be deleted.
+	if err := s.securityGroupsInUseError(); err != nil {
+		return err
 	}
 
 	for _, sg := range s.scope.SecurityGroups() {
@@ -140,32 +222,165 @@ func (s *Service) deleteSecurityGroups() error {
 	return nil
 }
 
-func (s *Service) describeSecurityGroupsByName() (map[string]*v1alpha1.SecurityGroup, error) {
-	input := &ec2.DescribeSecurityGroupsInput{
+// securityGroupsInUseError explains why the security groups of the cluster
+// cannot be deleted, by listing the network interfaces and the security groups
+// of other clusters which reference them, such as those of instances launched
+// in the security groups by hand. The network interfaces of the instances of
+// the cluster are left out, as they go away with the instances. It returns nil
+// if nothing else uses the security groups.
+func (s *Service) securityGroupsInUseError() error {
+	own := sets.NewString()
+	for _, sg := range s.scope.SecurityGroups() {
+		own.Insert(sg.ID)
+	}
+
+	if own.Len() == 0 {
+		return nil
+	}
+
+	instances, err := s.clusterInstanceIDs()
+	if err != nil {
+		return err
+	}
+
+	blockers := map[string]sets.String{}
+	block := func(id, blocker string) {
+		if !own.Has(id) {
+			return
+		}
+		if blockers[id] == nil {
+			blockers[id] = sets.NewString()
+		}
+		blockers[id].Insert(blocker)
+	}
+
+	input := &ec2.DescribeNetworkInterfacesInput{
+		Filters: []*ec2.Filter{{Name: aws.String("group-id"), Values: aws.StringSlice(own.List())}},
+	}
+
+	err = s.scope.EC2.DescribeNetworkInterfacesPages(input,
+		func(page *ec2.DescribeNetworkInterfacesOutput, lastPage bool) bool {
+			for _, eni := range page.NetworkInterfaces {
+				blocker := fmt.Sprintf("network interface %q", aws.StringValue(eni.NetworkInterfaceId))
+				if eni.Attachment != nil && eni.Attachment.InstanceId != nil {
+					if instances.Has(aws.StringValue(eni.Attachment.InstanceId)) {
+						continue
+					}
+					blocker += fmt.Sprintf(" of instance %q", aws.StringValue(eni.Attachment.InstanceId))
+				} else if description := aws.StringValue(eni.Description); description != "" {
+					blocker += fmt.Sprintf(" (%s)", description)
+				}
+
+				for _, group := range eni.Groups {
+					block(aws.StringValue(group.GroupId), blocker)
+				}
+			}
+			return true
+		})
+	if err != nil {
+		return errors.Wrapf(err, "failed to describe the network interfaces of security groups %v", own.List())
+	}
+
+	for _, name := range []string{"ip-permission.group-id", "egress.ip-permission.group-id"} {
+		input := &ec2.DescribeSecurityGroupsInput{
+			Filters: []*ec2.Filter{{Name: aws.String(name), Values: aws.StringSlice(own.List())}},
+		}
+
+		err := s.scope.EC2.DescribeSecurityGroupsPages(input,
+			func(page *ec2.DescribeSecurityGroupsOutput, lastPage bool) bool {
+				for _, sg := range page.SecurityGroups {
+					if own.Has(aws.StringValue(sg.GroupId)) {
+						continue
+					}
+
+					blocker := fmt.Sprintf("security group %q (%s)", aws.StringValue(sg.GroupId), aws.StringValue(sg.GroupName))
+					for _, permission := range append(sg.IpPermissions, sg.IpPermissionsEgress...) {
+						for _, pair := range permission.UserIdGroupPairs {
+							block(aws.StringValue(pair.GroupId), blocker)
+						}
+					}
+				}
+				return true
+			})
+		if err != nil {
+			return errors.Wrapf(err, "failed to describe the security groups referencing security groups %v", own.List())
+		}
+	}
+
+	if len(blockers) == 0 {
+		return nil
+	}
+
+	var inUse []string
+	for _, id := range own.List() {
+		if b, ok := blockers[id]; ok {
+			inUse = append(inUse, fmt.Sprintf("security group %q is in use by %s", id, strings.Join(b.List(), ", ")))
+		}
+	}
+
+	return awserrors.NewInUse(errors.New(strings.Join(inUse, "; ")))
+}
+
+// clusterInstanceIDs returns the IDs of the instances of the cluster which
+// have not been terminated yet.
+func (s *Service) clusterInstanceIDs() (sets.String, error) {
+	input := &ec2.DescribeInstancesInput{
 		Filters: []*ec2.Filter{
-			filter.EC2.VPC(s.scope.Network().VPC.ID),
 			filter.EC2.Cluster(s.scope.Name()),
+			filter.EC2.InstanceStates(append([]string{ec2.InstanceStateNameShuttingDown}, existingInstanceStates...)...),
 		},
 	}
 
-	out, err := s.scope.EC2.DescribeSecurityGroups(input)
+	ids := sets.NewString()
+	err := s.scope.EC2.DescribeInstancesPages(input,
+		func(page *ec2.DescribeInstancesOutput, lastPage bool) bool {
+			for _, res := range page.Reservations {
+				for _, inst := range res.Instances {
+					ids.Insert(aws.StringValue(inst.InstanceId))
+				}
+			}
+			return true
+		})
 	if err != nil {
-		return nil, errors.Wrapf(err, "failed to describe security groups in vpc %q", s.scope.Network().VPC.ID)
+		return nil, errors.Wrapf(err, "failed to describe the instances of cluster %q", s.scope.Name())
 	}
 
-	res := make(map[string]*v1alpha1.SecurityGroup, len(out.SecurityGroups))
-	for _, ec2sg := range out.SecurityGroups {
-		sg := &v1alpha1.SecurityGroup{
-			ID:   *ec2sg.GroupId,
-			Name: *ec2sg.GroupName,
-			Tags: converters.TagsToMap(ec2sg.Tags),
-		}
+	return ids, nil
+}
 
-		for _, ec2rule := range ec2sg.IpPermissions {
-			sg.IngressRules = append(sg.IngressRules, ingressRuleFromSDKType(ec2rule))
-		}
+func (s *Service) describeSecurityGroupsByName() (map[string]*v1alpha1.SecurityGroup, error) {
+	input := &ec2.DescribeSecurityGroupsInput{
+		Filters: []*ec2.Filter{
+			filter.EC2.VPC(s.scope.Network().VPC.ID),
+			filter.EC2.Cluster(s.scope.Name()),
+		},
+	}
 
-		res[sg.Name] = sg
+	res := make(map[string]*v1alpha1.SecurityGroup)
+	err := s.scope.EC2.DescribeSecurityGroupsPages(input,
+		func(page *ec2.DescribeSecurityGroupsOutput, lastPage bool) bool {
+			for _, ec2sg := range page.SecurityGroups {
+				sg := &v1alpha1.SecurityGroup{
+					ID:   *ec2sg.GroupId,
+					Name: *ec2sg.GroupName,
+					Tags: converters.TagsToMap(ec2sg.Tags),
+				}
+
+				for _, ec2rule := range ec2sg.IpPermissions {
+					sg.IngressRules = append(sg.IngressRules, ingressRuleFromSDKType(ec2rule))
+				}
+
+				for _, ec2rule := range ec2sg.IpPermissionsEgress {
+					sg.EgressRules = append(sg.EgressRules, ingressRuleFromSDKType(ec2rule))
+				}
+
+				res[sg.Name] = sg
+			}
+			return !lastPage
+		})
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to describe security groups in vpc %q", s.scope.Network().VPC.ID)
 	}
 
 	return res, nil
@@ -219,6 +434,32 @@ func (s *Service) revokeSecurityGroupIngressRules(id string, rules v1alpha1.Ingr
 	return nil
 }
 
+func (s *Service) authorizeSecurityGroupEgressRules(id string, rules v1alpha1.IngressRules) error {
+	input := &ec2.AuthorizeSecurityGroupEgressInput{GroupId: aws.String(id)}
+	for _, rule := range rules {
+		input.IpPermissions = append(input.IpPermissions, ingressRuleToSDKType(rule))
+	}
+
+	if _, err := s.scope.EC2.AuthorizeSecurityGroupEgress(input); err != nil {
+		return errors.Wrapf(err, "failed to authorize security group %q egress rules: %v", id, rules)
+	}
+
+	return nil
+}
+
+func (s *Service) revokeSecurityGroupEgressRules(id string, rules v1alpha1.IngressRules) error {
+	input := &ec2.RevokeSecurityGroupEgressInput{GroupId: aws.String(id)}
+	for _, rule := range rules {
+		input.IpPermissions = append(input.IpPermissions, ingressRuleToSDKType(rule))
+	}
+
+	if _, err := s.scope.EC2.RevokeSecurityGroupEgress(input); err != nil {
+		return errors.Wrapf(err, "failed to revoke security group %q egress rules: %v", id, rules)
+	}
+
+	return nil
+}
+
 func (s *Service) defaultSSHIngressRule(sourceSecurityGroupID string) *v1alpha1.IngressRule {
 	return &v1alpha1.IngressRule{
 		Description:            "SSH",
@@ -244,13 +485,7 @@ func (s *Service) getSecurityGroupIngressRules(role v1alpha1.SecurityGroupRole)
 	case v1alpha1.SecurityGroupControlPlane:
 		return v1alpha1.IngressRules{
 			s.defaultSSHIngressRule(s.scope.SecurityGroups()[v1alpha1.SecurityGroupBastion].ID),
-			{
-				Description: "Kubernetes API",
-				Protocol:    v1alpha1.SecurityGroupProtocolTCP,
-				FromPort:    6443,
-				ToPort:      6443,
-				CidrBlocks:  []string{anyIPv4CidrBlock},
-			},
+			s.controlPlaneAPIIngressRule(),
 			{
 				Description:            "etcd",
 				Protocol:               v1alpha1.SecurityGroupProtocolTCP,
@@ -278,7 +513,7 @@ func (s *Service) getSecurityGroupIngressRules(role v1alpha1.SecurityGroupRole)
 		}, nil
 
 	case v1alpha1.SecurityGroupNode:
-		return v1alpha1.IngressRules{
+		rules := v1alpha1.IngressRules{
 			s.defaultSSHIngressRule(s.scope.SecurityGroups()[v1alpha1.SecurityGroupBastion].ID),
 			{
 				Description: "Node Port Services",
@@ -304,18 +539,168 @@ func (s *Service) getSecurityGroupIngressRules(role v1alpha1.SecurityGroupRole)
 					s.scope.SecurityGroups()[v1alpha1.SecurityGroupNode].ID,
 				},
 			},
+		}
+
+		// Elastic fabric adapters require all the traffic between the nodes to be allowed.
+		if s.scope.ClusterConfig.EnableEFA {
+			rules = append(rules, &v1alpha1.IngressRule{
+				Description:            "EFA",
+				Protocol:               v1alpha1.SecurityGroupProtocolAll,
+				SourceSecurityGroupIDs: []string{s.scope.SecurityGroups()[v1alpha1.SecurityGroupNode].ID},
+			})
+		}
+
+		return rules, nil
+
+	case v1alpha1.SecurityGroupAPIServerLB:
+		return v1alpha1.IngressRules{
+			{
+				Description: "Kubernetes API",
+				Protocol:    v1alpha1.SecurityGroupProtocolTCP,
+				FromPort:    6443,
+				ToPort:      6443,
+				CidrBlocks:  []string{anyIPv4CidrBlock},
+			},
 		}, nil
 	}
 
 	return nil, errors.Errorf("Cannot determine ingress rules for unknown security group role %q", role)
 }
 
-func (s *Service) getSecurityGroupName(clusterName string, role v1alpha1.SecurityGroupRole) string {
-	return fmt.Sprintf("%s-%v", clusterName, role)
+// controlPlaneAPIIngressRule returns the rule allowing traffic to the API
+// server, which only comes through the cluster security groups when egress
+// is restricted, as the load balancer then has a security group of its own.
+func (s *Service) controlPlaneAPIIngressRule() *v1alpha1.IngressRule {
+	rule := &v1alpha1.IngressRule{
+		Description: "Kubernetes API",
+		Protocol:    v1alpha1.SecurityGroupProtocolTCP,
+		FromPort:    6443,
+		ToPort:      6443,
+	}
+
+	lb, ok := s.scope.SecurityGroups()[v1alpha1.SecurityGroupAPIServerLB]
+	if !s.scope.ClusterConfig.RestrictedEgress || !ok {
+		rule.CidrBlocks = []string{anyIPv4CidrBlock}
+		return rule
+	}
+
+	rule.SourceSecurityGroupIDs = []string{
+		lb.ID,
+		s.scope.SecurityGroups()[v1alpha1.SecurityGroupControlPlane].ID,
+		s.scope.SecurityGroups()[v1alpha1.SecurityGroupNode].ID,
+	}
+
+	// The PrivateLink load balancer has no security group, the traffic of
+	// its endpoints comes from its addresses in the VPC.
+	if s.scope.ClusterConfig.APIServerPrivateLink != nil {
+		rule.CidrBlocks = []string{s.scope.VPC().CidrBlock}
+	}
+	return rule
+}
+
+// defaultEgressRules returns the egress rules AWS creates with security
+// groups, allowing all outbound traffic.
+func defaultEgressRules() v1alpha1.IngressRules {
+	return v1alpha1.IngressRules{
+		{
+			Protocol:   v1alpha1.SecurityGroupProtocolAll,
+			CidrBlocks: []string{anyIPv4CidrBlock},
+		},
+	}
+}
+
+// getSecurityGroupEgressRules returns the egress rules of a security group.
+// When egress is restricted, the control plane and nodes can only reach each
+// other, the API server load balancer, DNS and NTP servers, and HTTP and
+// HTTPS endpoints such as AWS APIs, package mirrors and image registries, and
+// the load balancer can only reach the API server of the control plane.
+func (s *Service) getSecurityGroupEgressRules(role v1alpha1.SecurityGroupRole) v1alpha1.IngressRules {
+	sgs := s.scope.SecurityGroups()
+
+	switch {
+	case role == v1alpha1.SecurityGroupAPIServerLB:
+		return v1alpha1.IngressRules{
+			{
+				Description:            "Kubernetes API",
+				Protocol:               v1alpha1.SecurityGroupProtocolTCP,
+				FromPort:               6443,
+				ToPort:                 6443,
+				SourceSecurityGroupIDs: []string{sgs[v1alpha1.SecurityGroupControlPlane].ID},
+			},
+		}
+	case !s.scope.ClusterConfig.RestrictedEgress, role == v1alpha1.SecurityGroupBastion, sgs[v1alpha1.SecurityGroupAPIServerLB] == nil:
+		return defaultEgressRules()
+	}
+
+	return v1alpha1.IngressRules{
+		{
+			Description:            "Cluster",
+			Protocol:               v1alpha1.SecurityGroupProtocolAll,
+			SourceSecurityGroupIDs: []string{sgs[v1alpha1.SecurityGroupControlPlane].ID, sgs[v1alpha1.SecurityGroupNode].ID},
+		},
+		{
+			Description:            "Kubernetes API",
+			Protocol:               v1alpha1.SecurityGroupProtocolTCP,
+			FromPort:               6443,
+			ToPort:                 6443,
+			SourceSecurityGroupIDs: []string{sgs[v1alpha1.SecurityGroupAPIServerLB].ID},
+		},
+		{
+			Description: "HTTP",
+			Protocol:    v1alpha1.SecurityGroupProtocolTCP,
+			FromPort:    80,
+			ToPort:      80,
+			CidrBlocks:  []string{anyIPv4CidrBlock},
+		},
+		{
+			Description: "HTTPS",
+			Protocol:    v1alpha1.SecurityGroupProtocolTCP,
+			FromPort:    443,
+			ToPort:      443,
+			CidrBlocks:  []string{anyIPv4CidrBlock},
+		},
+		{
+			Description: "DNS",
+			Protocol:    v1alpha1.SecurityGroupProtocolUDP,
+			FromPort:    53,
+			ToPort:      53,
+			CidrBlocks:  []string{anyIPv4CidrBlock},
+		},
+		{
+			Description: "DNS",
+			Protocol:    v1alpha1.SecurityGroupProtocolTCP,
+			FromPort:    53,
+			ToPort:      53,
+			CidrBlocks:  []string{anyIPv4CidrBlock},
+		},
+		{
+			Description: "NTP",
+			Protocol:    v1alpha1.SecurityGroupProtocolUDP,
+			FromPort:    123,
+			ToPort:      123,
+			CidrBlocks:  []string{anyIPv4CidrBlock},
+		},
+	}
+}
+
+func (s *Service) getSecurityGroupName(role v1alpha1.SecurityGroupRole) string {
+	return s.scope.ResourceName(string(role), securityGroupNameMaxLength)
+}
+
+// securityGroupName returns the name of the security group with the given role
+// recorded in the cluster status, so that existing security groups keep being found
+// even if the naming scheme of the cluster changes. Before the security group is
+// created, the name is generated from the naming scheme.
+func (s *Service) securityGroupName(role v1alpha1.SecurityGroupRole) string {
+	if sg, ok := s.scope.SecurityGroups()[role]; ok && sg != nil && sg.Name != "" {
+		return sg.Name
+	}
+
+	return s.getSecurityGroupName(role)
 }
 
 func (s *Service) getDefaultSecurityGroup(role v1alpha1.SecurityGroupRole) *ec2.SecurityGroup {
-	name := s.getSecurityGroupName(s.scope.Name(), role)
+	name := s.getSecurityGroupName(role)
 
 	return &ec2.SecurityGroup{
 		GroupName: aws.String(name),
@@ -336,8 +721,12 @@ func (s *Service) getSecurityGroupTagParams(name string, role v1alpha1.SecurityG
 func ingressRuleToSDKType(i *v1alpha1.IngressRule) *ec2.IpPermission {
 	res := &ec2.IpPermission{
 		IpProtocol: aws.String(string(i.Protocol)),
-		FromPort:   aws.Int64(i.FromPort),
-		ToPort:     aws.Int64(i.ToPort),
+	}
+
+	// Rules for all protocols apply to all ports.
+	if i.Protocol != v1alpha1.SecurityGroupProtocolAll {
+		res.FromPort = aws.Int64(i.FromPort)
+		res.ToPort = aws.Int64(i.ToPort)
 	}
 
 	for _, cidr := range i.CidrBlocks {
@@ -347,6 +736,20 @@ func ingressRuleToSDKType(i *v1alpha1.IngressRule) *ec2.IpPermission {
 		})
 	}
 
+	for _, cidr := range i.IPv6CidrBlocks {
+		res.Ipv6Ranges = append(res.Ipv6Ranges, &ec2.Ipv6Range{
+			Description: aws.String(i.Description),
+			CidrIpv6:    aws.String(cidr),
+		})
+	}
+
+	for _, id := range i.PrefixListIDs {
+		res.PrefixListIds = append(res.PrefixListIds, &ec2.PrefixListId{
+			Description:  aws.String(i.Description),
+			PrefixListId: aws.String(id),
+		})
+	}
+
 	for _, groupID := range i.SourceSecurityGroupIDs {
 		res.UserIdGroupPairs = append(res.UserIdGroupPairs, &ec2.UserIdGroupPair{
 			Description: aws.String(i.Description),
@@ -360,8 +763,8 @@ func ingressRuleToSDKType(i *v1alpha1.IngressRule) *ec2.IpPermission {
 func ingressRuleFromSDKType(v *ec2.IpPermission) *v1alpha1.IngressRule {
 	res := &v1alpha1.IngressRule{
 		Protocol: v1alpha1.SecurityGroupProtocol(*v.IpProtocol),
-		FromPort: *v.FromPort,
-		ToPort:   *v.ToPort,
+		FromPort: aws.Int64Value(v.FromPort),
+		ToPort:   aws.Int64Value(v.ToPort),
 	}
 
 	for _, ec2range := range v.IpRanges {
@@ -372,6 +775,22 @@ func ingressRuleFromSDKType(v *ec2.IpPermission) *v1alpha1.IngressRule {
 		res.CidrBlocks = append(res.CidrBlocks, *ec2range.CidrIp)
 	}
 
+	for _, ec2range := range v.Ipv6Ranges {
+		if ec2range.Description != nil && *ec2range.Description != "" {
+			res.Description = *ec2range.Description
+		}
+
+		res.IPv6CidrBlocks = append(res.IPv6CidrBlocks, aws.StringValue(ec2range.CidrIpv6))
+	}
+
+	for _, prefixList := range v.PrefixListIds {
+		if prefixList.Description != nil && *prefixList.Description != "" {
+			res.Description = *prefixList.Description
+		}
+
+		res.PrefixListIDs = append(res.PrefixListIDs, aws.StringValue(prefixList.PrefixListId))
+	}
+
 	for _, pair := range v.UserIdGroupPairs {
 		if pair.GroupId == nil {
 			continue
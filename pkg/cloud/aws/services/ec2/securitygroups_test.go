@@ -0,0 +1,450 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ec2
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/golang/mock/gomock"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/actuators"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/awserrors"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/ec2/mock_ec2iface"
+	clusterv1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+)
+
+func TestSecurityGroupName(t *testing.T) {
+	testCases := []struct {
+		name   string
+		sgs    map[v1alpha1.SecurityGroupRole]*v1alpha1.SecurityGroup
+		expect string
+	}{
+		{
+			name:   "not created yet",
+			expect: "acme-test-cluster-node",
+		},
+		{
+			name: "recorded in status",
+			sgs: map[v1alpha1.SecurityGroupRole]*v1alpha1.SecurityGroup{
+				v1alpha1.SecurityGroupNode: {ID: "sg-1", Name: "test-cluster-node"},
+			},
+			expect: "test-cluster-node",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			scope, err := actuators.NewScope(actuators.ScopeParams{
+				Cluster: &clusterv1.Cluster{
+					ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"},
+				},
+			})
+			if err != nil {
+				t.Fatalf("Failed to create test context: %v", err)
+			}
+
+			scope.ClusterConfig = &v1alpha1.AWSClusterProviderSpec{
+				ResourceNaming: &v1alpha1.ResourceNaming{Prefix: "acme-"},
+			}
+			scope.ClusterStatus = &v1alpha1.AWSClusterProviderStatus{
+				Network: v1alpha1.Network{SecurityGroups: tc.sgs},
+			}
+
+			s := NewService(scope)
+			if got := s.securityGroupName(v1alpha1.SecurityGroupNode); got != tc.expect {
+				t.Fatalf("expected security group name %q, got %q", tc.expect, got)
+			}
+		})
+	}
+}
+
+func TestDescribeSecurityGroupsByName(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	ec2Mock := mock_ec2iface.NewMockEC2API(mockCtrl)
+	ec2Mock.EXPECT().
+		DescribeSecurityGroupsPages(gomock.AssignableToTypeOf(&ec2.DescribeSecurityGroupsInput{}), gomock.Any()).
+		Do(func(_ *ec2.DescribeSecurityGroupsInput, fn func(*ec2.DescribeSecurityGroupsOutput, bool) bool) {
+			pages := []*ec2.DescribeSecurityGroupsOutput{
+				{SecurityGroups: []*ec2.SecurityGroup{{GroupId: aws.String("sg-1"), GroupName: aws.String("test-cluster-bastion")}}},
+				{SecurityGroups: []*ec2.SecurityGroup{{GroupId: aws.String("sg-2"), GroupName: aws.String("test-cluster-node")}}},
+			}
+			for i, page := range pages {
+				if !fn(page, i == len(pages)-1) {
+					return
+				}
+			}
+		}).
+		Return(nil)
+
+	scope, err := actuators.NewScope(actuators.ScopeParams{
+		Cluster: &clusterv1.Cluster{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"},
+		},
+		AWSClients: actuators.AWSClients{
+			EC2: ec2Mock,
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create test context: %v", err)
+	}
+
+	sgs, err := NewService(scope).describeSecurityGroupsByName()
+	if err != nil {
+		t.Fatalf("failed to describe security groups: %v", err)
+	}
+
+	if len(sgs) != 2 || sgs["test-cluster-node"] == nil || sgs["test-cluster-node"].ID != "sg-2" {
+		t.Fatalf("expected security groups from all pages, got %v", sgs)
+	}
+}
+
+func TestReconcileSecurityGroupsSkipped(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	// Only the lookup is expected, security groups are never created or modified.
+	ec2Mock := mock_ec2iface.NewMockEC2API(mockCtrl)
+	ec2Mock.EXPECT().
+		DescribeSecurityGroupsPages(gomock.AssignableToTypeOf(&ec2.DescribeSecurityGroupsInput{}), gomock.Any()).
+		Do(func(_ *ec2.DescribeSecurityGroupsInput, fn func(*ec2.DescribeSecurityGroupsOutput, bool) bool) {
+			fn(&ec2.DescribeSecurityGroupsOutput{
+				SecurityGroups: []*ec2.SecurityGroup{{GroupId: aws.String("sg-1"), GroupName: aws.String("test-cluster-node")}},
+			}, true)
+		}).
+		Return(nil)
+
+	scope, err := actuators.NewScope(actuators.ScopeParams{
+		Cluster: &clusterv1.Cluster{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "test-cluster",
+				Annotations: map[string]string{actuators.SkipSecurityGroupsAnnotation: "true"},
+			},
+		},
+		AWSClients: actuators.AWSClients{
+			EC2: ec2Mock,
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create test context: %v", err)
+	}
+
+	if err := NewService(scope).reconcileSecurityGroups(); err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+
+	sgs := scope.SecurityGroups()
+	if len(sgs) != 1 || sgs[v1alpha1.SecurityGroupNode] == nil || sgs[v1alpha1.SecurityGroupNode].ID != "sg-1" {
+		t.Fatalf("expected the existing node security group to be recorded, got %v", sgs)
+	}
+}
+
+func TestNodeIngressRulesEFA(t *testing.T) {
+	scope, err := actuators.NewScope(actuators.ScopeParams{
+		Cluster: &clusterv1.Cluster{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create test context: %v", err)
+	}
+
+	scope.ClusterConfig = &v1alpha1.AWSClusterProviderSpec{EnableEFA: true}
+	scope.ClusterStatus = &v1alpha1.AWSClusterProviderStatus{
+		Network: v1alpha1.Network{
+			SecurityGroups: map[v1alpha1.SecurityGroupRole]*v1alpha1.SecurityGroup{
+				v1alpha1.SecurityGroupBastion:      {ID: "sg-bastion"},
+				v1alpha1.SecurityGroupControlPlane: {ID: "sg-controlplane"},
+				v1alpha1.SecurityGroupNode:         {ID: "sg-node"},
+			},
+		},
+	}
+
+	rules, err := NewService(scope).getSecurityGroupIngressRules(v1alpha1.SecurityGroupNode)
+	if err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+
+	efa := rules[len(rules)-1]
+	if efa.Protocol != v1alpha1.SecurityGroupProtocolAll || len(efa.SourceSecurityGroupIDs) != 1 || efa.SourceSecurityGroupIDs[0] != "sg-node" {
+		t.Fatalf("expected the nodes to allow all the traffic from themselves, got %v", efa)
+	}
+
+	// Rules for all protocols are described without ports, and must match
+	// the rule they were authorized from.
+	described := ingressRuleFromSDKType(&ec2.IpPermission{
+		IpProtocol:       aws.String("-1"),
+		UserIdGroupPairs: []*ec2.UserIdGroupPair{{Description: aws.String("EFA"), GroupId: aws.String("sg-node")}},
+	})
+	if diff := (v1alpha1.IngressRules{described}).Difference(v1alpha1.IngressRules{efa}); len(diff) != 0 {
+		t.Fatalf("expected the described rule to match, got %v", diff)
+	}
+	if p := ingressRuleToSDKType(efa); p.FromPort != nil || p.ToPort != nil {
+		t.Fatalf("expected no ports for all protocols, got %v", p)
+	}
+}
+
+func TestRestrictedEgress(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	ec2Mock := mock_ec2iface.NewMockEC2API(mockCtrl)
+
+	scope, err := actuators.NewScope(actuators.ScopeParams{
+		Cluster: &clusterv1.Cluster{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"},
+		},
+		AWSClients: actuators.AWSClients{
+			EC2: ec2Mock,
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create test context: %v", err)
+	}
+
+	// Security groups described by AWS allow all egress traffic.
+	allowAll := ingressRuleFromSDKType(&ec2.IpPermission{
+		IpProtocol: aws.String("-1"),
+		IpRanges:   []*ec2.IpRange{{CidrIp: aws.String("0.0.0.0/0")}},
+	})
+
+	scope.ClusterConfig = &v1alpha1.AWSClusterProviderSpec{RestrictedEgress: true}
+	scope.ClusterStatus = &v1alpha1.AWSClusterProviderStatus{
+		Network: v1alpha1.Network{
+			SecurityGroups: map[v1alpha1.SecurityGroupRole]*v1alpha1.SecurityGroup{
+				v1alpha1.SecurityGroupBastion:      {ID: "sg-bastion", EgressRules: v1alpha1.IngressRules{allowAll}},
+				v1alpha1.SecurityGroupControlPlane: {ID: "sg-controlplane", EgressRules: v1alpha1.IngressRules{allowAll}},
+				v1alpha1.SecurityGroupNode:         {ID: "sg-node", EgressRules: v1alpha1.IngressRules{allowAll}},
+				v1alpha1.SecurityGroupAPIServerLB:  {ID: "sg-lb", EgressRules: v1alpha1.IngressRules{allowAll}},
+			},
+		},
+	}
+
+	s := NewService(scope)
+
+	// The restricted rules are authorized before the open egress is revoked.
+	gomock.InOrder(
+		ec2Mock.EXPECT().
+			AuthorizeSecurityGroupEgress(&ec2.AuthorizeSecurityGroupEgressInput{
+				GroupId: aws.String("sg-lb"),
+				IpPermissions: []*ec2.IpPermission{{
+					IpProtocol:       aws.String("tcp"),
+					FromPort:         aws.Int64(6443),
+					ToPort:           aws.Int64(6443),
+					UserIdGroupPairs: []*ec2.UserIdGroupPair{{Description: aws.String("Kubernetes API"), GroupId: aws.String("sg-controlplane")}},
+				}},
+			}).
+			Return(&ec2.AuthorizeSecurityGroupEgressOutput{}, nil),
+		ec2Mock.EXPECT().
+			RevokeSecurityGroupEgress(&ec2.RevokeSecurityGroupEgressInput{
+				GroupId:       aws.String("sg-lb"),
+				IpPermissions: []*ec2.IpPermission{ingressRuleToSDKType(allowAll)},
+			}).
+			Return(&ec2.RevokeSecurityGroupEgressOutput{}, nil),
+	)
+
+	lb := scope.SecurityGroups()[v1alpha1.SecurityGroupAPIServerLB]
+	if err := s.reconcileSecurityGroupEgressRules(v1alpha1.SecurityGroupAPIServerLB, lb); err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+
+	// Reconciling again is a no-op.
+	if err := s.reconcileSecurityGroupEgressRules(v1alpha1.SecurityGroupAPIServerLB, lb); err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+
+	// The bastion keeps its open egress.
+	bastion := scope.SecurityGroups()[v1alpha1.SecurityGroupBastion]
+	if err := s.reconcileSecurityGroupEgressRules(v1alpha1.SecurityGroupBastion, bastion); err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+
+	// The control plane and nodes reach each other, the load balancer, DNS and HTTPS.
+	egress := s.getSecurityGroupEgressRules(v1alpha1.SecurityGroupNode)
+	if diff := egress.Difference(defaultEgressRules()); len(diff) != len(egress) {
+		t.Fatalf("expected the node egress to be restricted, got %v", egress)
+	}
+	if egress[1].SourceSecurityGroupIDs[0] != "sg-lb" {
+		t.Fatalf("expected the nodes to reach the load balancer, got %v", egress[1])
+	}
+
+	// The API server only accepts traffic from the load balancer and the cluster.
+	api := s.controlPlaneAPIIngressRule()
+	if len(api.CidrBlocks) != 0 || len(api.SourceSecurityGroupIDs) != 3 || api.SourceSecurityGroupIDs[0] != "sg-lb" {
+		t.Fatalf("expected the API server to only accept traffic from the security groups of the cluster, got %+v", api)
+	}
+
+	// Unless it is exposed with PrivateLink, whose load balancer has no security group.
+	scope.ClusterConfig.APIServerPrivateLink = &v1alpha1.PrivateLink{}
+	scope.VPC().CidrBlock = "10.0.0.0/16"
+	if api := s.controlPlaneAPIIngressRule(); len(api.CidrBlocks) != 1 || api.CidrBlocks[0] != "10.0.0.0/16" {
+		t.Fatalf("expected the API server to accept traffic from the VPC, got %+v", api)
+	}
+	scope.ClusterConfig.APIServerPrivateLink = nil
+
+	// Without restricted egress, the load balancer shares the control plane
+	// security group and the API server accepts all traffic.
+	scope.ClusterConfig.RestrictedEgress = false
+	if diff := s.getSecurityGroupEgressRules(v1alpha1.SecurityGroupNode).Difference(defaultEgressRules()); len(diff) != 0 {
+		t.Fatalf("expected the node egress to be open, got %v", diff)
+	}
+	if api := s.controlPlaneAPIIngressRule(); len(api.CidrBlocks) != 1 || api.CidrBlocks[0] != anyIPv4CidrBlock {
+		t.Fatalf("expected the API server to accept all traffic, got %+v", api)
+	}
+
+	// Nor is the egress of the security groups reconciled, leaving the rules
+	// added outside of the cluster in place.
+	node := scope.SecurityGroups()[v1alpha1.SecurityGroupNode]
+	node.EgressRules = append(node.EgressRules, &v1alpha1.IngressRule{Protocol: v1alpha1.SecurityGroupProtocolAll, IPv6CidrBlocks: []string{"::/0"}})
+	if err := s.reconcileSecurityGroupEgressRules(v1alpha1.SecurityGroupNode, node); err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+}
+
+func TestIngressRuleSDKTypeRoundTrip(t *testing.T) {
+	permission := &ec2.IpPermission{
+		IpProtocol:    aws.String("tcp"),
+		FromPort:      aws.Int64(443),
+		ToPort:        aws.Int64(443),
+		IpRanges:      []*ec2.IpRange{{Description: aws.String("HTTPS"), CidrIp: aws.String("0.0.0.0/0")}},
+		Ipv6Ranges:    []*ec2.Ipv6Range{{Description: aws.String("HTTPS"), CidrIpv6: aws.String("::/0")}},
+		PrefixListIds: []*ec2.PrefixListId{{Description: aws.String("HTTPS"), PrefixListId: aws.String("pl-1")}},
+	}
+
+	rule := ingressRuleFromSDKType(permission)
+	if len(rule.IPv6CidrBlocks) != 1 || rule.IPv6CidrBlocks[0] != "::/0" {
+		t.Fatalf("expected the IPv6 ranges to be kept, got %v", rule.IPv6CidrBlocks)
+	}
+	if len(rule.PrefixListIDs) != 1 || rule.PrefixListIDs[0] != "pl-1" {
+		t.Fatalf("expected the prefix lists to be kept, got %v", rule.PrefixListIDs)
+	}
+
+	if back := ingressRuleToSDKType(rule); !reflect.DeepEqual(back, permission) {
+		t.Fatalf("expected %v, got %v", permission, back)
+	}
+}
+
+func TestSecurityGroupsInUseError(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	ec2Mock := mock_ec2iface.NewMockEC2API(mockCtrl)
+	ec2Mock.EXPECT().
+		DescribeInstancesPages(gomock.AssignableToTypeOf(&ec2.DescribeInstancesInput{}), gomock.Any()).
+		Do(func(_ *ec2.DescribeInstancesInput, fn func(*ec2.DescribeInstancesOutput, bool) bool) {
+			fn(&ec2.DescribeInstancesOutput{
+				Reservations: []*ec2.Reservation{{
+					Instances: []*ec2.Instance{{InstanceId: aws.String("i-own")}},
+				}},
+			}, true)
+		}).
+		Return(nil)
+	ec2Mock.EXPECT().
+		DescribeNetworkInterfacesPages(&ec2.DescribeNetworkInterfacesInput{
+			Filters: []*ec2.Filter{{Name: aws.String("group-id"), Values: aws.StringSlice([]string{"sg-cp", "sg-node"})}},
+		}, gomock.Any()).
+		Do(func(_ *ec2.DescribeNetworkInterfacesInput, fn func(*ec2.DescribeNetworkInterfacesOutput, bool) bool) {
+			pages := []*ec2.DescribeNetworkInterfacesOutput{
+				{NetworkInterfaces: []*ec2.NetworkInterface{
+					{
+						NetworkInterfaceId: aws.String("eni-1"),
+						Attachment:         &ec2.NetworkInterfaceAttachment{InstanceId: aws.String("i-other")},
+						Groups:             []*ec2.GroupIdentifier{{GroupId: aws.String("sg-cp")}},
+					},
+				}},
+				{NetworkInterfaces: []*ec2.NetworkInterface{
+					{
+						NetworkInterfaceId: aws.String("eni-2"),
+						Description:        aws.String("VPC endpoint"),
+						Groups:             []*ec2.GroupIdentifier{{GroupId: aws.String("sg-node")}},
+					},
+					{
+						NetworkInterfaceId: aws.String("eni-own"),
+						Attachment:         &ec2.NetworkInterfaceAttachment{InstanceId: aws.String("i-own")},
+						Groups:             []*ec2.GroupIdentifier{{GroupId: aws.String("sg-cp")}, {GroupId: aws.String("sg-node")}},
+					},
+				}},
+			}
+			for i, page := range pages {
+				if !fn(page, i == len(pages)-1) {
+					return
+				}
+			}
+		}).
+		Return(nil)
+	gomock.InOrder(
+		ec2Mock.EXPECT().
+			DescribeSecurityGroupsPages(gomock.AssignableToTypeOf(&ec2.DescribeSecurityGroupsInput{}), gomock.Any()).
+			Do(func(_ *ec2.DescribeSecurityGroupsInput, fn func(*ec2.DescribeSecurityGroupsOutput, bool) bool) {
+				fn(&ec2.DescribeSecurityGroupsOutput{
+					SecurityGroups: []*ec2.SecurityGroup{
+						{
+							GroupId:   aws.String("sg-node"),
+							GroupName: aws.String("test-cluster-node"),
+							IpPermissions: []*ec2.IpPermission{
+								{UserIdGroupPairs: []*ec2.UserIdGroupPair{{GroupId: aws.String("sg-cp")}}},
+							},
+						},
+						{
+							GroupId:   aws.String("sg-other"),
+							GroupName: aws.String("other-cluster-node"),
+							IpPermissions: []*ec2.IpPermission{
+								{UserIdGroupPairs: []*ec2.UserIdGroupPair{{GroupId: aws.String("sg-cp")}}},
+							},
+						},
+					},
+				}, true)
+			}).
+			Return(nil),
+		ec2Mock.EXPECT().
+			DescribeSecurityGroupsPages(gomock.AssignableToTypeOf(&ec2.DescribeSecurityGroupsInput{}), gomock.Any()).
+			Return(nil),
+	)
+
+	scope, err := actuators.NewScope(actuators.ScopeParams{
+		Cluster: &clusterv1.Cluster{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"},
+		},
+		AWSClients: actuators.AWSClients{
+			EC2: ec2Mock,
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create test context: %v", err)
+	}
+
+	scope.ClusterStatus.Network.SecurityGroups = map[v1alpha1.SecurityGroupRole]*v1alpha1.SecurityGroup{
+		v1alpha1.SecurityGroupControlPlane: {ID: "sg-cp"},
+		v1alpha1.SecurityGroupNode:         {ID: "sg-node"},
+	}
+
+	err = NewService(scope).securityGroupsInUseError()
+	if !awserrors.IsInUse(err) {
+		t.Fatalf("expected an in use error, got %v", err)
+	}
+
+	expected := `security group "sg-cp" is in use by network interface "eni-1" of instance "i-other", security group "sg-other" (other-cluster-node); ` +
+		`security group "sg-node" is in use by network interface "eni-2" (VPC endpoint)`
+	if err.Error() != expected {
+		t.Fatalf("expected error %q, got %q", expected, err.Error())
+	}
+}
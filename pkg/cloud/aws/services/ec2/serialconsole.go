@@ -0,0 +1,96 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ec2
+
+import (
+	"regexp"
+
+	"github.com/pkg/errors"
+	"k8s.io/klog"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/awserrors"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/userdata"
+)
+
+const (
+	defaultSerialConsoleUser = "root"
+
+	// hypervisorXen is the hypervisor of the instance types which are not
+	// built on the Nitro System, and have no serial console.
+	hypervisorXen = "xen"
+)
+
+var (
+	// userNamePattern matches the user names accepted by useradd.
+	userNamePattern = regexp.MustCompile(`^[a-z_][a-z0-9_-]*$`)
+
+	// passwordHashPattern matches the password hashes of /etc/shadow in the
+	// modular crypt format, e.g. $6$salt$hash.
+	passwordHashPattern = regexp.MustCompile(`^\$[0-9a-z]+(\$[a-zA-Z0-9./=,]+)+$`)
+)
+
+// validateSerialConsole checks that the serial console of an instance can
+// be accessed with its instance type, as described by EC2. Bare metal
+// instance types have no hypervisor, and a serial console. The instance type
+// check is skipped when it cannot be described.
+func (s *Service) validateSerialConsole(instanceType string, sc *v1alpha1.SerialConsole) error {
+	if sc == nil {
+		return nil
+	}
+
+	if sc.User != "" && !userNamePattern.MatchString(sc.User) {
+		return awserrors.NewInvalidConfiguration(errors.Errorf("invalid user name %q", sc.User))
+	}
+
+	if sc.PasswordHash != "" && !passwordHashPattern.MatchString(sc.PasswordHash) {
+		return awserrors.NewInvalidConfiguration(errors.New("password hash must be in the crypt(3) format, e.g. as generated by \"openssl passwd -6\""))
+	}
+
+	if instanceType == "" {
+		return nil
+	}
+
+	info, err := s.scope.InstanceTypes.Describe(instanceType)
+	if err != nil {
+		klog.Warningf("Skipping serial console validation of instance type %q: %v", instanceType, err)
+		return nil
+	}
+
+	if info != nil && info.Hypervisor == hypervisorXen {
+		return awserrors.NewInvalidConfiguration(errors.Errorf("instance type %q is not built on the Nitro System and has no serial console", instanceType))
+	}
+
+	return nil
+}
+
+// serialConsoleInput returns the serial console access set up by the user
+// data of an instance.
+func serialConsoleInput(sc *v1alpha1.SerialConsole) *userdata.SerialConsoleInput {
+	if sc == nil {
+		return nil
+	}
+
+	input := &userdata.SerialConsoleInput{
+		User:         sc.User,
+		PasswordHash: sc.PasswordHash,
+	}
+	if input.User == "" {
+		input.User = defaultSerialConsoleUser
+	}
+
+	return input
+}
@@ -0,0 +1,104 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ec2
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/actuators"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/instancetypes"
+	clusterv1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+)
+
+func TestValidateSerialConsole(t *testing.T) {
+	scope, err := actuators.NewScope(actuators.ScopeParams{
+		Cluster: &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"}},
+		InstanceTypes: &fakeInstanceTypes{
+			infos: map[string]*instancetypes.Info{
+				"m5.large":  {Hypervisor: "nitro"},
+				"t2.medium": {Hypervisor: "xen"},
+				"i3.metal":  {},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create test context: %v", err)
+	}
+
+	testCases := []struct {
+		name         string
+		instanceType string
+		sc           *v1alpha1.SerialConsole
+		expectError  bool
+	}{
+		{
+			name:         "no serial console",
+			instanceType: "t2.medium",
+		},
+		{
+			name:         "nitro instance",
+			instanceType: "m5.large",
+			sc:           &v1alpha1.SerialConsole{PasswordHash: "$6$rounds=5000$salt$Ab3./xYz"},
+		},
+		{
+			name:         "xen bare metal instance",
+			instanceType: "i3.metal",
+			sc:           &v1alpha1.SerialConsole{},
+		},
+		{
+			name: "instance type from a launch template",
+			sc:   &v1alpha1.SerialConsole{User: "ubuntu"},
+		},
+		{
+			name:         "instance type not described",
+			instanceType: "x9.huge",
+			sc:           &v1alpha1.SerialConsole{},
+		},
+		{
+			name:         "xen instance",
+			instanceType: "t2.medium",
+			sc:           &v1alpha1.SerialConsole{},
+			expectError:  true,
+		},
+		{
+			name:         "invalid user name",
+			instanceType: "m5.large",
+			sc:           &v1alpha1.SerialConsole{User: "root; reboot"},
+			expectError:  true,
+		},
+		{
+			name:         "plain text password",
+			instanceType: "m5.large",
+			sc:           &v1alpha1.SerialConsole{PasswordHash: "hunter2"},
+			expectError:  true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := NewService(scope).validateSerialConsole(tc.instanceType, tc.sc)
+			if tc.expectError && err == nil {
+				t.Fatalf("expected error")
+			}
+			if !tc.expectError && err != nil {
+				t.Fatalf("did not expect error: %v", err)
+			}
+		})
+	}
+}
@@ -59,31 +59,43 @@ func (s *Service) reconcileSubnets() error {
 
 	// If the subnets are empty, populate the slice with the default configuration.
 	// Adds a single private and public subnet in the first available zone.
+	// With IPAM, their CIDR blocks are allocated below.
 	if len(subnets) < 2 {
 		zones, err := s.getAvailableZones()
 		if err != nil {
 			return err
 		}
 
-		if len(subnets.FilterPrivate()) == 0 {
+		// Allocated subnets are only found by their visibility once created.
+		privateCidr, publicCidr := defaultPrivateSubnetCidr, defaultPublicSubnetCidr
+		needPrivate, needPublic := len(subnets.FilterPrivate()) == 0, len(subnets.FilterPublic()) == 0
+		if s.scope.IPAM != nil {
+			privateCidr, publicCidr = "", ""
+			needPrivate = needPrivate && len(existing.FilterPrivate()) == 0
+			needPublic = needPublic && len(existing.FilterPublic()) == 0
+		}
+
+		if needPrivate {
 			subnets = append(subnets, &v1alpha1.Subnet{
 				VpcID:            s.scope.VPC().ID,
-				CidrBlock:        defaultPrivateSubnetCidr,
+				CidrBlock:        privateCidr,
 				AvailabilityZone: zones[0],
 				IsPublic:         false,
 			})
 		}
 
-		if len(subnets.FilterPublic()) == 0 {
+		if needPublic {
 			subnets = append(subnets, &v1alpha1.Subnet{
 				VpcID:            s.scope.VPC().ID,
-				CidrBlock:        defaultPublicSubnetCidr,
+				CidrBlock:        publicCidr,
 				AvailabilityZone: zones[0],
 				IsPublic:         true,
 			})
 		}
 	}
 
+	batch := tags.NewBatch(s.scope.EC2)
+
 LoopExisting:
 	for _, exsn := range existing {
 		// Check if the subnet already exists in the state, in that case reconcile it.
@@ -93,14 +105,7 @@ LoopExisting:
 			if (sn.ID != "" && exsn.ID == sn.ID) || (sn.VpcID == exsn.VpcID && sn.CidrBlock == exsn.CidrBlock) {
 
 				// Make sure tags are up to date.
-				err = tags.Ensure(exsn.Tags, &tags.ApplyParams{
-					EC2Client:   s.scope.EC2,
-					BuildParams: s.getSubnetTagParams(exsn.ID, exsn.IsPublic),
-				})
-
-				if err != nil {
-					return errors.Wrapf(err, "failed to ensure tags on subnet %q", exsn.ID)
-				}
+				batch.Ensure(exsn.Tags, s.getSubnetTagParams(exsn.ID, exsn.IsPublic))
 
 				// TODO(vincepri): check if subnet needs to be updated.
 				exsn.DeepCopyInto(sn)
@@ -112,6 +117,14 @@ LoopExisting:
 		subnets = append(subnets, exsn)
 	}
 
+	if err := batch.Apply(); err != nil {
+		return errors.Wrap(err, "failed to ensure tags on subnets")
+	}
+
+	if err := s.allocateSubnetCidrBlocks(subnets); err != nil {
+		return err
+	}
+
 	// Proceed to create the rest of the subnets that don't have an ID.
 	for _, subnet := range subnets {
 		if subnet.ID != "" {
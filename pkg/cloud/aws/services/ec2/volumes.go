@@ -0,0 +1,233 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ec2
+
+import (
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/pkg/errors"
+	"k8s.io/klog"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/filter"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/awserrors"
+)
+
+// The vendored SDK predates gp3 and io2 volumes.
+const (
+	volumeTypeGp3 = "gp3"
+	volumeTypeIo2 = "io2"
+)
+
+// ModifyInstanceVolumes modifies in place the size, type, IOPS and throughput
+// of the root and additional volumes of an EC2 instance that differ from the given
+// configuration, and returns the IDs of the modified volumes.
+//
+// Volumes are never shrunk, and volumes with a modification in progress are
+// left alone until it completes. Volumes that AWS refuses to modify yet are
+// skipped, and reported together in a conflict error once the other volumes
+// have been modified.
+func (s *Service) ModifyInstanceVolumes(instanceID string, root *v1alpha1.RootVolume, volumes []v1alpha1.Volume) ([]string, error) {
+	if root == nil && len(volumes) == 0 {
+		return nil, nil
+	}
+
+	out, err := s.scope.EC2.DescribeInstances(&ec2.DescribeInstancesInput{
+		InstanceIds: aws.StringSlice([]string{instanceID}),
+		Filters:     []*ec2.Filter{filter.EC2.Cluster(s.scope.Name())},
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to describe instance %q", instanceID)
+	}
+
+	if len(out.Reservations) == 0 || len(out.Reservations[0].Instances) == 0 {
+		return nil, errors.Errorf("instance %q not found", instanceID)
+	}
+
+	instance := out.Reservations[0].Instances[0]
+
+	// Map the desired volumes to the volumes attached to the instance.
+	desired := map[string]v1alpha1.Volume{}
+	for _, v := range volumes {
+		desired[v.DeviceName] = v
+	}
+	if root != nil {
+		desired[aws.StringValue(instance.RootDeviceName)] = v1alpha1.Volume{Size: root.Size, Type: root.Type, IOPS: root.IOPS, Throughput: root.Throughput}
+	}
+
+	attached := map[string]v1alpha1.Volume{}
+	var ids []string
+	throughput := false
+	for _, bdm := range instance.BlockDeviceMappings {
+		if bdm.Ebs == nil {
+			continue
+		}
+		if v, ok := desired[aws.StringValue(bdm.DeviceName)]; ok {
+			id := aws.StringValue(bdm.Ebs.VolumeId)
+			attached[id] = v
+			ids = append(ids, id)
+			throughput = throughput || v.Throughput != 0
+		}
+	}
+
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	volumesOut, err := s.scope.EC2.DescribeVolumes(&ec2.DescribeVolumesInput{
+		VolumeIds: aws.StringSlice(ids),
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to describe volumes of instance %q", instanceID)
+	}
+
+	modificationsOut, err := s.scope.EC2.DescribeVolumesModifications(&ec2.DescribeVolumesModificationsInput{
+		Filters: []*ec2.Filter{
+			{Name: aws.String("volume-id"), Values: aws.StringSlice(ids)},
+			{Name: aws.String("modification-state"), Values: aws.StringSlice([]string{ec2.VolumeModificationStateModifying, ec2.VolumeModificationStateOptimizing})},
+		},
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to describe modifications of the volumes of instance %q", instanceID)
+	}
+
+	inProgress := map[string]bool{}
+	for _, m := range modificationsOut.VolumesModifications {
+		inProgress[aws.StringValue(m.VolumeId)] = true
+	}
+
+	// The vendored SDK does not describe the throughput of volumes.
+	var throughputs map[string]int64
+	if throughput {
+		if throughputs, err = s.scope.Volumes.Throughputs(ids); err != nil {
+			return nil, err
+		}
+	}
+
+	var modified, deferred []string
+	for _, volume := range volumesOut.Volumes {
+		id := aws.StringValue(volume.VolumeId)
+		if inProgress[id] {
+			klog.V(2).Infof("Volume %q of instance %q is being modified, skipping", id, instanceID)
+			continue
+		}
+
+		volumeType := attached[id].Type
+		if volumeType == "" {
+			volumeType = aws.StringValue(volume.VolumeType)
+		}
+		if err := validateVolumePerformance(volumeType, attached[id].IOPS, attached[id].Throughput); err != nil {
+			return modified, awserrors.NewInvalidConfiguration(errors.Wrapf(err, "invalid volume %q of instance %q", id, instanceID))
+		}
+
+		input := volumeModification(volume, attached[id])
+
+		throughput := attached[id].Throughput
+		if throughput == throughputs[id] {
+			throughput = 0
+		}
+
+		switch {
+		case throughput != 0:
+			if input == nil {
+				input = &ec2.ModifyVolumeInput{VolumeId: volume.VolumeId}
+			}
+			err = s.scope.Volumes.ModifyVolume(input, throughput)
+		case input != nil:
+			_, err = s.scope.EC2.ModifyVolume(input)
+		default:
+			continue
+		}
+
+		if awserrors.IsVolumeModificationCooldown(errors.Cause(err)) {
+			klog.V(2).Infof("Volume %q of instance %q cannot be modified yet: %v", id, instanceID, err)
+			deferred = append(deferred, id)
+			continue
+		}
+		if err != nil {
+			return modified, errors.Wrapf(err, "failed to modify volume %q of instance %q", id, instanceID)
+		}
+
+		klog.V(2).Infof("Modified volume %q of instance %q", id, instanceID)
+		modified = append(modified, id)
+	}
+
+	if len(deferred) > 0 {
+		return modified, awserrors.NewConflict(errors.Errorf("volumes %s of instance %q were modified less than six hours ago or are still being modified",
+			strings.Join(deferred, ", "), instanceID))
+	}
+
+	return modified, nil
+}
+
+// validateVolumePerformance checks that the IOPS and throughput of a volume
+// are supported by its type. io1 and io2 volumes require IOPS. An empty type
+// is the EC2 default volume type, gp2, which supports neither.
+func validateVolumePerformance(volumeType string, iops, throughput int64) error {
+	if volumeType == "" {
+		volumeType = ec2.VolumeTypeGp2
+	}
+
+	switch volumeType {
+	case ec2.VolumeTypeIo1, volumeTypeIo2:
+		if iops == 0 {
+			return errors.Errorf("%s volumes require IOPS", volumeType)
+		}
+	case volumeTypeGp3:
+	default:
+		if iops != 0 {
+			return errors.Errorf("IOPS are only supported by io1, io2 and gp3 volumes, not %s volumes", volumeType)
+		}
+	}
+
+	if throughput != 0 && volumeType != volumeTypeGp3 {
+		return errors.Errorf("throughput is only supported by gp3 volumes, not %s volumes", volumeType)
+	}
+
+	return nil
+}
+
+// volumeModification returns the modification turning the given volume into
+// the desired one, or nil if they do not differ.
+func volumeModification(volume *ec2.Volume, desired v1alpha1.Volume) *ec2.ModifyVolumeInput {
+	input := &ec2.ModifyVolumeInput{VolumeId: volume.VolumeId}
+	changed := false
+
+	if desired.Size > aws.Int64Value(volume.Size) {
+		input.Size = aws.Int64(desired.Size)
+		changed = true
+	} else if desired.Size != 0 && desired.Size < aws.Int64Value(volume.Size) {
+		klog.Warningf("Volume %q cannot be shrunk from %d to %d GiB, keeping its size", aws.StringValue(volume.VolumeId), aws.Int64Value(volume.Size), desired.Size)
+	}
+
+	if desired.Type != "" && desired.Type != aws.StringValue(volume.VolumeType) {
+		input.VolumeType = aws.String(desired.Type)
+		changed = true
+	}
+
+	if desired.IOPS != 0 && desired.IOPS != aws.Int64Value(volume.Iops) {
+		input.Iops = aws.Int64(desired.IOPS)
+		changed = true
+	}
+
+	if !changed {
+		return nil
+	}
+
+	return input
+}
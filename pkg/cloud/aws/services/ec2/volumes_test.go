@@ -0,0 +1,328 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ec2
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/golang/mock/gomock"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/actuators"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/filter"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/awserrors"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/ebs"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/ec2/mock_ec2iface"
+	clusterv1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+)
+
+// fakeVolumes provisions the throughput of volumes in memory.
+type fakeVolumes struct {
+	throughputs map[string]int64
+	modified    []*ec2.ModifyVolumeInput
+}
+
+var _ ebs.Volumes = &fakeVolumes{}
+
+func (f *fakeVolumes) Throughputs(volumeIDs []string) (map[string]int64, error) {
+	return f.throughputs, nil
+}
+
+func (f *fakeVolumes) ModifyVolume(input *ec2.ModifyVolumeInput, throughput int64) error {
+	f.modified = append(f.modified, input)
+	f.throughputs[aws.StringValue(input.VolumeId)] = throughput
+	return nil
+}
+
+func TestModifyInstanceVolumes(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	ec2Mock := mock_ec2iface.NewMockEC2API(mockCtrl)
+	ec2Mock.EXPECT().
+		DescribeInstances(&ec2.DescribeInstancesInput{
+			InstanceIds: aws.StringSlice([]string{"i-1"}),
+			Filters:     []*ec2.Filter{filter.EC2.Cluster("test-cluster")},
+		}).
+		Return(&ec2.DescribeInstancesOutput{
+			Reservations: []*ec2.Reservation{{
+				Instances: []*ec2.Instance{{
+					InstanceId:     aws.String("i-1"),
+					RootDeviceName: aws.String("/dev/sda1"),
+					BlockDeviceMappings: []*ec2.InstanceBlockDeviceMapping{
+						{DeviceName: aws.String("/dev/sda1"), Ebs: &ec2.EbsInstanceBlockDevice{VolumeId: aws.String("vol-root")}},
+						{DeviceName: aws.String("/dev/sdb"), Ebs: &ec2.EbsInstanceBlockDevice{VolumeId: aws.String("vol-data")}},
+						{DeviceName: aws.String("/dev/sdc"), Ebs: &ec2.EbsInstanceBlockDevice{VolumeId: aws.String("vol-busy")}},
+					},
+				}},
+			}},
+		}, nil)
+	ec2Mock.EXPECT().
+		DescribeVolumes(&ec2.DescribeVolumesInput{VolumeIds: aws.StringSlice([]string{"vol-root", "vol-data", "vol-busy"})}).
+		Return(&ec2.DescribeVolumesOutput{
+			Volumes: []*ec2.Volume{
+				{VolumeId: aws.String("vol-root"), Size: aws.Int64(8), VolumeType: aws.String("gp2"), Iops: aws.Int64(100)},
+				{VolumeId: aws.String("vol-data"), Size: aws.Int64(200), VolumeType: aws.String("gp2"), Iops: aws.Int64(600)},
+				{VolumeId: aws.String("vol-busy"), Size: aws.Int64(100), VolumeType: aws.String("gp2"), Iops: aws.Int64(300)},
+			},
+		}, nil)
+	ec2Mock.EXPECT().
+		DescribeVolumesModifications(gomock.AssignableToTypeOf(&ec2.DescribeVolumesModificationsInput{})).
+		Return(&ec2.DescribeVolumesModificationsOutput{
+			VolumesModifications: []*ec2.VolumeModification{
+				{VolumeId: aws.String("vol-busy"), ModificationState: aws.String(ec2.VolumeModificationStateOptimizing)},
+			},
+		}, nil)
+	// The root volume is grown and converted, the data volume is not shrunk.
+	ec2Mock.EXPECT().
+		ModifyVolume(&ec2.ModifyVolumeInput{
+			VolumeId:   aws.String("vol-root"),
+			Size:       aws.Int64(20),
+			VolumeType: aws.String("gp3"),
+			Iops:       aws.Int64(3000),
+		}).
+		Return(&ec2.ModifyVolumeOutput{}, nil)
+
+	scope, err := actuators.NewScope(actuators.ScopeParams{
+		Cluster: &clusterv1.Cluster{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"},
+		},
+		AWSClients: actuators.AWSClients{
+			EC2: ec2Mock,
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create test context: %v", err)
+	}
+
+	modified, err := NewService(scope).ModifyInstanceVolumes("i-1",
+		&v1alpha1.RootVolume{Size: 20, Type: "gp3", IOPS: 3000},
+		[]v1alpha1.Volume{
+			{DeviceName: "/dev/sdb", Size: 100, Type: "gp2"},
+			{DeviceName: "/dev/sdc", Size: 200, Type: "gp2"},
+		},
+	)
+	if err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+
+	if len(modified) != 1 || modified[0] != "vol-root" {
+		t.Fatalf("expected only the root volume to be modified, got %v", modified)
+	}
+}
+
+func TestModifyInstanceVolumesCooldown(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	ec2Mock := mock_ec2iface.NewMockEC2API(mockCtrl)
+	ec2Mock.EXPECT().
+		DescribeInstances(gomock.AssignableToTypeOf(&ec2.DescribeInstancesInput{})).
+		Return(&ec2.DescribeInstancesOutput{
+			Reservations: []*ec2.Reservation{{
+				Instances: []*ec2.Instance{{
+					InstanceId:     aws.String("i-1"),
+					RootDeviceName: aws.String("/dev/sda1"),
+					BlockDeviceMappings: []*ec2.InstanceBlockDeviceMapping{
+						{DeviceName: aws.String("/dev/sda1"), Ebs: &ec2.EbsInstanceBlockDevice{VolumeId: aws.String("vol-root")}},
+						{DeviceName: aws.String("/dev/sdb"), Ebs: &ec2.EbsInstanceBlockDevice{VolumeId: aws.String("vol-data")}},
+					},
+				}},
+			}},
+		}, nil)
+	ec2Mock.EXPECT().
+		DescribeVolumes(gomock.AssignableToTypeOf(&ec2.DescribeVolumesInput{})).
+		Return(&ec2.DescribeVolumesOutput{
+			Volumes: []*ec2.Volume{
+				{VolumeId: aws.String("vol-root"), Size: aws.Int64(8), VolumeType: aws.String("gp2")},
+				{VolumeId: aws.String("vol-data"), Size: aws.Int64(100), VolumeType: aws.String("gp2")},
+			},
+		}, nil)
+	ec2Mock.EXPECT().
+		DescribeVolumesModifications(gomock.AssignableToTypeOf(&ec2.DescribeVolumesModificationsInput{})).
+		Return(&ec2.DescribeVolumesModificationsOutput{}, nil)
+	// The root volume was modified less than six hours ago, the data volume
+	// is still modified.
+	ec2Mock.EXPECT().
+		ModifyVolume(&ec2.ModifyVolumeInput{VolumeId: aws.String("vol-root"), Size: aws.Int64(20)}).
+		Return(nil, awserr.New(awserrors.VolumeModificationRateLimit, "rate exceeded", nil))
+	ec2Mock.EXPECT().
+		ModifyVolume(&ec2.ModifyVolumeInput{VolumeId: aws.String("vol-data"), Size: aws.Int64(200)}).
+		Return(&ec2.ModifyVolumeOutput{}, nil)
+
+	scope, err := actuators.NewScope(actuators.ScopeParams{
+		Cluster: &clusterv1.Cluster{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"},
+		},
+		AWSClients: actuators.AWSClients{
+			EC2: ec2Mock,
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create test context: %v", err)
+	}
+
+	modified, err := NewService(scope).ModifyInstanceVolumes("i-1",
+		&v1alpha1.RootVolume{Size: 20},
+		[]v1alpha1.Volume{{DeviceName: "/dev/sdb", Size: 200}},
+	)
+	if !awserrors.IsConflict(errors.Cause(err)) {
+		t.Fatalf("expected a conflict error, got %v", err)
+	}
+
+	if len(modified) != 1 || modified[0] != "vol-data" {
+		t.Fatalf("expected the data volume to be modified, got %v", modified)
+	}
+}
+
+func TestModifyInstanceVolumesThroughput(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	ec2Mock := mock_ec2iface.NewMockEC2API(mockCtrl)
+	ec2Mock.EXPECT().
+		DescribeInstances(gomock.AssignableToTypeOf(&ec2.DescribeInstancesInput{})).
+		Return(&ec2.DescribeInstancesOutput{
+			Reservations: []*ec2.Reservation{{
+				Instances: []*ec2.Instance{{
+					InstanceId:     aws.String("i-1"),
+					RootDeviceName: aws.String("/dev/sda1"),
+					BlockDeviceMappings: []*ec2.InstanceBlockDeviceMapping{
+						{DeviceName: aws.String("/dev/sda1"), Ebs: &ec2.EbsInstanceBlockDevice{VolumeId: aws.String("vol-root")}},
+						{DeviceName: aws.String("/dev/sdb"), Ebs: &ec2.EbsInstanceBlockDevice{VolumeId: aws.String("vol-data")}},
+						{DeviceName: aws.String("/dev/sdc"), Ebs: &ec2.EbsInstanceBlockDevice{VolumeId: aws.String("vol-logs")}},
+					},
+				}},
+			}},
+		}, nil)
+	ec2Mock.EXPECT().
+		DescribeVolumes(gomock.AssignableToTypeOf(&ec2.DescribeVolumesInput{})).
+		Return(&ec2.DescribeVolumesOutput{
+			Volumes: []*ec2.Volume{
+				{VolumeId: aws.String("vol-root"), Size: aws.Int64(20), VolumeType: aws.String("gp3"), Iops: aws.Int64(3000)},
+				{VolumeId: aws.String("vol-data"), Size: aws.Int64(100), VolumeType: aws.String("gp2"), Iops: aws.Int64(300)},
+				{VolumeId: aws.String("vol-logs"), Size: aws.Int64(50), VolumeType: aws.String("gp3"), Iops: aws.Int64(3000)},
+			},
+		}, nil)
+	ec2Mock.EXPECT().
+		DescribeVolumesModifications(gomock.AssignableToTypeOf(&ec2.DescribeVolumesModificationsInput{})).
+		Return(&ec2.DescribeVolumesModificationsOutput{}, nil)
+
+	volumes := &fakeVolumes{throughputs: map[string]int64{"vol-root": 125, "vol-logs": 250}}
+	scope, err := actuators.NewScope(actuators.ScopeParams{
+		Cluster: &clusterv1.Cluster{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"},
+		},
+		AWSClients: actuators.AWSClients{
+			EC2: ec2Mock,
+		},
+		Volumes: volumes,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create test context: %v", err)
+	}
+
+	// The root volume gets more throughput, the data volume is converted to
+	// gp3 along with its throughput, and the logs volume is left alone.
+	modified, err := NewService(scope).ModifyInstanceVolumes("i-1",
+		&v1alpha1.RootVolume{Size: 20, Type: "gp3", Throughput: 500},
+		[]v1alpha1.Volume{
+			{DeviceName: "/dev/sdb", Size: 100, Type: "gp3", Throughput: 250},
+			{DeviceName: "/dev/sdc", Size: 50, Type: "gp3", Throughput: 250},
+		},
+	)
+	if err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+
+	if expected := []string{"vol-root", "vol-data"}; !reflect.DeepEqual(modified, expected) {
+		t.Fatalf("expected volumes %v to be modified, got %v", expected, modified)
+	}
+
+	expected := []*ec2.ModifyVolumeInput{
+		{VolumeId: aws.String("vol-root")},
+		{VolumeId: aws.String("vol-data"), VolumeType: aws.String("gp3")},
+	}
+	if !reflect.DeepEqual(volumes.modified, expected) {
+		t.Fatalf("expected modifications %v, got %v", expected, volumes.modified)
+	}
+	if volumes.throughputs["vol-root"] != 500 || volumes.throughputs["vol-data"] != 250 {
+		t.Fatalf("unexpected throughputs %v", volumes.throughputs)
+	}
+}
+
+func TestValidateVolumePerformance(t *testing.T) {
+	testCases := []struct {
+		name        string
+		volumeType  string
+		iops        int64
+		throughput  int64
+		expectError bool
+	}{
+		{
+			name: "default type",
+		},
+		{
+			name:        "iops of the default type",
+			iops:        1000,
+			expectError: true,
+		},
+		{
+			name:        "iops of a gp2 volume",
+			volumeType:  "gp2",
+			iops:        1000,
+			expectError: true,
+		},
+		{
+			name:       "iops and throughput of a gp3 volume",
+			volumeType: "gp3",
+			iops:       4000,
+			throughput: 250,
+		},
+		{
+			name:       "iops of an io2 volume",
+			volumeType: "io2",
+			iops:       4000,
+		},
+		{
+			name:        "io1 volume without iops",
+			volumeType:  "io1",
+			expectError: true,
+		},
+		{
+			name:        "throughput of an io1 volume",
+			volumeType:  "io1",
+			iops:        1000,
+			throughput:  250,
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateVolumePerformance(tc.volumeType, tc.iops, tc.throughput)
+			if (err != nil) != tc.expectError {
+				t.Fatalf("expected error %t, got %v", tc.expectError, err)
+			}
+		})
+	}
+}
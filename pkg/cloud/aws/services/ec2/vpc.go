@@ -17,8 +17,6 @@ limitations under the License.
 package ec2
 
 import (
-	"fmt"
-
 	"sigs.k8s.io/cluster-api-provider-aws/pkg/record"
 
 	"github.com/aws/aws-sdk-go/aws"
@@ -51,6 +49,10 @@ func (s *Service) reconcileVPC() error {
 		return errors.Wrap(err, "failed to describe VPCs")
 	}
 
+	if err := s.allocatePodCidrBlock(); err != nil {
+		return err
+	}
+
 	// Make sure tags are up to date.
 	err = tags.Ensure(vpc.Tags, &tags.ApplyParams{
 		EC2Client:   s.scope.EC2,
@@ -67,6 +69,21 @@ func (s *Service) reconcileVPC() error {
 }
 
 func (s *Service) createVPC() (*v1alpha1.VPC, error) {
+	if s.scope.VPC().CidrBlock == "" && s.scope.IPAM != nil {
+		cidr, err := s.scope.IPAM.AllocateVPC(s.ipamCluster())
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to allocate vpc cidr block")
+		}
+		klog.V(2).Infof("Allocated vpc cidr block %q for cluster %q", cidr, s.scope.Name())
+		s.scope.VPC().CidrBlock = cidr
+
+		// The block is recorded before the VPC is created, so that it is not
+		// allocated again if the creation fails.
+		if err := s.scope.Store(); err != nil {
+			return nil, errors.Wrapf(err, "failed to record vpc cidr block %q", cidr)
+		}
+	}
+
 	if s.scope.VPC().CidrBlock == "" {
 		s.scope.VPC().CidrBlock = defaultVpcCidr
 	}
@@ -157,13 +174,20 @@ func (s *Service) describeVPC() (*v1alpha1.VPC, error) {
 }
 
 func (s *Service) getVPCTagParams(id string) tags.BuildParams {
-	name := fmt.Sprintf("%s-vpc", s.scope.Name())
+	name := s.scope.ResourceName("vpc", 0)
 
-	return tags.BuildParams{
+	params := tags.BuildParams{
 		ClusterName: s.scope.Name(),
 		ResourceID:  id,
 		Lifecycle:   tags.ResourceLifecycleOwned,
 		Name:        aws.String(name),
 		Role:        aws.String(tags.ValueCommonRole),
 	}
+
+	// The pod CIDR block is recorded for the IPAM pools to skip it.
+	if pods := s.scope.Cluster.Spec.ClusterNetwork.Pods.CIDRBlocks; s.scope.IPAM != nil && len(pods) > 0 {
+		params.Additional = tags.Map{tags.NameAWSProviderPodCidrBlock: pods[0]}
+	}
+
+	return params
 }
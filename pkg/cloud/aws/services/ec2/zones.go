@@ -0,0 +1,261 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ec2
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/filter"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/awserrors"
+)
+
+const (
+	// zoneFailureThreshold is the number of consecutive launch failures
+	// after which an availability zone is considered unhealthy.
+	zoneFailureThreshold = 3
+
+	// zoneFailureCooldown is how long an unhealthy availability zone
+	// is avoided after its last launch failure.
+	zoneFailureCooldown = 15 * time.Minute
+
+	// controlPlaneZonesTTL is how long the control plane instances of a
+	// cluster counted per availability zone are cached, as every cluster
+	// reconciliation and control plane launch needs them.
+	controlPlaneZonesTTL = time.Minute
+)
+
+// controlPlaneZonesCache caches the control plane instances per availability zone
+// by cluster, for controlPlaneZonesTTL.
+var controlPlaneZonesCache = struct {
+	sync.Mutex
+	byCluster map[string]cachedControlPlaneZones
+}{byCluster: map[string]cachedControlPlaneZones{}}
+
+type cachedControlPlaneZones struct {
+	zones   map[string]int
+	expires time.Time
+}
+
+// zoneHealthy returns whether new instances should be placed in the availability zone.
+func (s *Service) zoneHealthy(zone string) bool {
+	failure, ok := s.scope.ClusterStatus.ZoneLaunchFailures[zone]
+	if !ok || failure.Count < zoneFailureThreshold {
+		return true
+	}
+
+	return time.Since(failure.LastFailureTime.Time) > zoneFailureCooldown
+}
+
+// healthySubnets returns the subnets in healthy availability zones.
+// If every zone is unhealthy, all subnets are returned.
+func (s *Service) healthySubnets(subnets v1alpha1.Subnets) v1alpha1.Subnets {
+	var res v1alpha1.Subnets
+	for _, sn := range subnets {
+		if s.zoneHealthy(sn.AvailabilityZone) {
+			res = append(res, sn)
+		}
+	}
+
+	if len(res) == 0 {
+		return subnets
+	}
+
+	return res
+}
+
+// recordZoneLaunchFailure records a failed instance launch in the availability zone of the subnet.
+func (s *Service) recordZoneLaunchFailure(subnetID string) {
+	sn, ok := s.scope.Subnets().ToMap()[subnetID]
+	if !ok {
+		return
+	}
+
+	if s.scope.ClusterStatus.ZoneLaunchFailures == nil {
+		s.scope.ClusterStatus.ZoneLaunchFailures = make(map[string]v1alpha1.ZoneLaunchFailure)
+	}
+
+	failure := s.scope.ClusterStatus.ZoneLaunchFailures[sn.AvailabilityZone]
+	failure.Count++
+	failure.LastFailureTime = metav1.Now()
+	s.scope.ClusterStatus.ZoneLaunchFailures[sn.AvailabilityZone] = failure
+
+	if failure.Count == zoneFailureThreshold {
+		klog.Warningf("Availability zone %q marked unhealthy for cluster %q after %d launch failures", sn.AvailabilityZone, s.scope.Name(), failure.Count)
+	}
+}
+
+// recordZoneLaunchSuccess clears the launch failures of the availability zone of the subnet.
+func (s *Service) recordZoneLaunchSuccess(subnetID string) {
+	sn, ok := s.scope.Subnets().ToMap()[subnetID]
+	if !ok {
+		return
+	}
+
+	delete(s.scope.ClusterStatus.ZoneLaunchFailures, sn.AvailabilityZone)
+}
+
+// ControlPlaneZones returns the number of control plane instances of the
+// cluster per availability zone. Stopped instances are counted, as they keep
+// their zone when started again. The counts are cached for
+// controlPlaneZonesTTL, and refreshed once a control plane instance is launched.
+func (s *Service) ControlPlaneZones() (map[string]int, error) {
+	key := s.controlPlaneZonesKey()
+
+	controlPlaneZonesCache.Lock()
+	cached, ok := controlPlaneZonesCache.byCluster[key]
+	controlPlaneZonesCache.Unlock()
+	if ok && time.Now().Before(cached.expires) {
+		return cached.zones, nil
+	}
+
+	input := &ec2.DescribeInstancesInput{
+		Filters: []*ec2.Filter{
+			filter.EC2.Cluster(s.scope.Name()),
+			filter.EC2.ProviderRole("controlplane"),
+			filter.EC2.InstanceStates(
+				ec2.InstanceStateNamePending,
+				ec2.InstanceStateNameRunning,
+				ec2.InstanceStateNameStopping,
+				ec2.InstanceStateNameStopped,
+			),
+		},
+	}
+
+	zones := map[string]int{}
+	err := s.scope.EC2.DescribeInstancesPages(input,
+		func(page *ec2.DescribeInstancesOutput, lastPage bool) bool {
+			for _, res := range page.Reservations {
+				for _, inst := range res.Instances {
+					if inst.Placement != nil {
+						zones[aws.StringValue(inst.Placement.AvailabilityZone)]++
+					}
+				}
+			}
+			return true
+		})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to describe control plane instances")
+	}
+
+	controlPlaneZonesCache.Lock()
+	controlPlaneZonesCache.byCluster[key] = cachedControlPlaneZones{zones: zones, expires: time.Now().Add(controlPlaneZonesTTL)}
+	controlPlaneZonesCache.Unlock()
+
+	return zones, nil
+}
+
+// forgetControlPlaneZones drops the cached control plane instances of the
+// cluster per availability zone, once they changed.
+func (s *Service) forgetControlPlaneZones() {
+	controlPlaneZonesCache.Lock()
+	delete(controlPlaneZonesCache.byCluster, s.controlPlaneZonesKey())
+	controlPlaneZonesCache.Unlock()
+}
+
+func (s *Service) controlPlaneZonesKey() string {
+	return string(s.scope.Cluster.UID) + "/" + s.scope.Namespace() + "/" + s.scope.Name()
+}
+
+// validateSpreadPlacement checks that a control plane machine of a cluster
+// spreading its control plane is not launched in a cluster placement group,
+// which packs its instances in a single availability zone.
+func (s *Service) validateSpreadPlacement(pg *v1alpha1.PlacementGroup) error {
+	if pg == nil {
+		return nil
+	}
+
+	strategy := pg.Strategy
+	if pg.Name != "" {
+		groups, err := s.describePlacementGroups(pg.Name)
+		if err != nil {
+			return err
+		}
+		if len(groups) > 0 {
+			strategy = v1alpha1.PlacementStrategy(aws.StringValue(groups[0].Strategy))
+		}
+	}
+
+	if strategy == v1alpha1.PlacementStrategyCluster {
+		return awserrors.NewInvalidConfiguration(
+			errors.Errorf("%s placement groups keep their instances in a single availability zone, which defeats the spread of the control plane", strategy),
+		)
+	}
+
+	return nil
+}
+
+// RunningControlPlaneInstance returns the ID of a running control plane
+// instance of the cluster, or an empty string if there is none.
+func (s *Service) RunningControlPlaneInstance() (string, error) {
+	input := &ec2.DescribeInstancesInput{
+		Filters: []*ec2.Filter{
+			filter.EC2.Cluster(s.scope.Name()),
+			filter.EC2.ProviderRole("controlplane"),
+			filter.EC2.InstanceStates(ec2.InstanceStateNameRunning),
+		},
+	}
+
+	out, err := s.scope.EC2.DescribeInstances(input)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to describe control plane instances")
+	}
+
+	for _, res := range out.Reservations {
+		for _, inst := range res.Instances {
+			return aws.StringValue(inst.InstanceId), nil
+		}
+	}
+
+	return "", nil
+}
+
+// spreadSubnets orders subnets by the number of control plane instances in
+// their availability zone, fewest first.
+func spreadSubnets(subnets v1alpha1.Subnets, controlPlaneZones map[string]int) v1alpha1.Subnets {
+	res := append(v1alpha1.Subnets{}, subnets...)
+	sort.SliceStable(res, func(i, j int) bool {
+		return controlPlaneZones[res[i].AvailabilityZone] < controlPlaneZones[res[j].AvailabilityZone]
+	})
+	return res
+}
+
+// validateControlPlaneSpread checks that a control plane instance is not
+// placed in an availability zone already hosting one while another zone of
+// the cluster hosts none.
+func validateControlPlaneSpread(zone string, subnets v1alpha1.Subnets, controlPlaneZones map[string]int) error {
+	if zone == "" || controlPlaneZones[zone] == 0 {
+		return nil
+	}
+
+	for _, sn := range subnets {
+		if sn.AvailabilityZone != "" && controlPlaneZones[sn.AvailabilityZone] == 0 {
+			return awserrors.NewInvalidConfiguration(
+				errors.Errorf("availability zone %q already hosts %d control plane instances while zone %q hosts none", zone, controlPlaneZones[zone], sn.AvailabilityZone),
+			)
+		}
+	}
+
+	return nil
+}
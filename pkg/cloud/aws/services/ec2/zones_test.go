@@ -0,0 +1,335 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ec2
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/golang/mock/gomock"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/actuators"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/filter"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/awserrors"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/ec2/mock_ec2iface"
+	clusterv1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+)
+
+func TestHealthySubnets(t *testing.T) {
+	subnets := v1alpha1.Subnets{
+		&v1alpha1.Subnet{ID: "subnet-a", AvailabilityZone: "us-east-1a"},
+		&v1alpha1.Subnet{ID: "subnet-b", AvailabilityZone: "us-east-1b"},
+	}
+
+	testCases := []struct {
+		name     string
+		failures map[string]v1alpha1.ZoneLaunchFailure
+		expect   string
+	}{
+		{
+			name:   "no failures",
+			expect: "subnet-a",
+		},
+		{
+			name: "failures below threshold",
+			failures: map[string]v1alpha1.ZoneLaunchFailure{
+				"us-east-1a": {Count: zoneFailureThreshold - 1, LastFailureTime: metav1.Now()},
+			},
+			expect: "subnet-a",
+		},
+		{
+			name: "recent failures above threshold",
+			failures: map[string]v1alpha1.ZoneLaunchFailure{
+				"us-east-1a": {Count: zoneFailureThreshold, LastFailureTime: metav1.Now()},
+			},
+			expect: "subnet-b",
+		},
+		{
+			name: "failures past cooldown",
+			failures: map[string]v1alpha1.ZoneLaunchFailure{
+				"us-east-1a": {Count: zoneFailureThreshold, LastFailureTime: metav1.NewTime(time.Now().Add(-2 * zoneFailureCooldown))},
+			},
+			expect: "subnet-a",
+		},
+		{
+			name: "all zones unhealthy",
+			failures: map[string]v1alpha1.ZoneLaunchFailure{
+				"us-east-1a": {Count: zoneFailureThreshold, LastFailureTime: metav1.Now()},
+				"us-east-1b": {Count: zoneFailureThreshold, LastFailureTime: metav1.Now()},
+			},
+			expect: "subnet-a",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			scope, err := actuators.NewScope(actuators.ScopeParams{
+				Cluster: &clusterv1.Cluster{
+					ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"},
+				},
+			})
+			if err != nil {
+				t.Fatalf("Failed to create test context: %v", err)
+			}
+
+			scope.ClusterStatus = &v1alpha1.AWSClusterProviderStatus{
+				ZoneLaunchFailures: tc.failures,
+			}
+
+			s := NewService(scope)
+			if got := s.healthySubnets(subnets)[0].ID; got != tc.expect {
+				t.Fatalf("expected subnet %q, got %q", tc.expect, got)
+			}
+		})
+	}
+}
+
+func TestRecordZoneLaunch(t *testing.T) {
+	scope, err := actuators.NewScope(actuators.ScopeParams{
+		Cluster: &clusterv1.Cluster{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create test context: %v", err)
+	}
+
+	scope.ClusterStatus = &v1alpha1.AWSClusterProviderStatus{
+		Network: v1alpha1.Network{
+			Subnets: v1alpha1.Subnets{
+				&v1alpha1.Subnet{ID: "subnet-a", AvailabilityZone: "us-east-1a"},
+			},
+		},
+	}
+
+	s := NewService(scope)
+	for i := 0; i < zoneFailureThreshold; i++ {
+		s.recordZoneLaunchFailure("subnet-a")
+	}
+	s.recordZoneLaunchFailure("subnet-unknown")
+
+	if len(scope.ClusterStatus.ZoneLaunchFailures) != 1 {
+		t.Fatalf("expected failures for one zone, got %+v", scope.ClusterStatus.ZoneLaunchFailures)
+	}
+
+	if s.zoneHealthy("us-east-1a") {
+		t.Fatalf("expected zone %q to be unhealthy", "us-east-1a")
+	}
+
+	s.recordZoneLaunchSuccess("subnet-a")
+	if !s.zoneHealthy("us-east-1a") {
+		t.Fatalf("expected zone %q to be healthy after a successful launch", "us-east-1a")
+	}
+}
+
+func TestSpreadSubnets(t *testing.T) {
+	subnets := v1alpha1.Subnets{
+		&v1alpha1.Subnet{ID: "subnet-a", AvailabilityZone: "us-east-1a"},
+		&v1alpha1.Subnet{ID: "subnet-b", AvailabilityZone: "us-east-1b"},
+		&v1alpha1.Subnet{ID: "subnet-c", AvailabilityZone: "us-east-1c"},
+	}
+
+	spread := spreadSubnets(subnets, map[string]int{"us-east-1a": 1, "us-east-1c": 1})
+	if spread[0].ID != "subnet-b" {
+		t.Fatalf("expected the subnet of the zone without control plane instances first, got %q", spread[0].ID)
+	}
+	if subnets[0].ID != "subnet-a" {
+		t.Fatalf("expected the subnets to be left in place")
+	}
+}
+
+func TestValidateControlPlaneSpread(t *testing.T) {
+	subnets := v1alpha1.Subnets{
+		&v1alpha1.Subnet{ID: "subnet-a", AvailabilityZone: "us-east-1a"},
+		&v1alpha1.Subnet{ID: "subnet-b", AvailabilityZone: "us-east-1b"},
+	}
+
+	testCases := []struct {
+		name        string
+		zone        string
+		zones       map[string]int
+		expectError bool
+	}{
+		{
+			name:  "first control plane instance",
+			zone:  "us-east-1a",
+			zones: map[string]int{},
+		},
+		{
+			name:        "zone already hosting one while another hosts none",
+			zone:        "us-east-1a",
+			zones:       map[string]int{"us-east-1a": 1},
+			expectError: true,
+		},
+		{
+			name:  "every zone hosting one",
+			zone:  "us-east-1a",
+			zones: map[string]int{"us-east-1a": 1, "us-east-1b": 1},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateControlPlaneSpread(tc.zone, subnets, tc.zones)
+			if tc.expectError && err == nil {
+				t.Fatalf("expected an error")
+			}
+			if !tc.expectError && err != nil {
+				t.Fatalf("did not expect error: %v", err)
+			}
+		})
+	}
+}
+
+func TestControlPlaneZones(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	ec2Mock := mock_ec2iface.NewMockEC2API(mockCtrl)
+	ec2Mock.EXPECT().
+		DescribeInstancesPages(&ec2.DescribeInstancesInput{
+			Filters: []*ec2.Filter{
+				filter.EC2.Cluster("test-cluster"),
+				filter.EC2.ProviderRole("controlplane"),
+				filter.EC2.InstanceStates(
+					ec2.InstanceStateNamePending,
+					ec2.InstanceStateNameRunning,
+					ec2.InstanceStateNameStopping,
+					ec2.InstanceStateNameStopped,
+				),
+			},
+		}, gomock.Any()).
+		Do(func(_ *ec2.DescribeInstancesInput, fn func(*ec2.DescribeInstancesOutput, bool) bool) {
+			fn(&ec2.DescribeInstancesOutput{
+				Reservations: []*ec2.Reservation{{
+					Instances: []*ec2.Instance{
+						{InstanceId: aws.String("i-1"), Placement: &ec2.Placement{AvailabilityZone: aws.String("us-east-1a")}},
+						{InstanceId: aws.String("i-2"), Placement: &ec2.Placement{AvailabilityZone: aws.String("us-east-1a")}},
+						{InstanceId: aws.String("i-3"), Placement: &ec2.Placement{AvailabilityZone: aws.String("us-east-1b")}},
+					},
+				}},
+			}, true)
+		}).
+		Return(nil).
+		Times(2)
+
+	scope, err := actuators.NewScope(actuators.ScopeParams{
+		Cluster: &clusterv1.Cluster{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-cluster", UID: types.UID("control-plane-zones")},
+		},
+		AWSClients: actuators.AWSClients{
+			EC2: ec2Mock,
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create test context: %v", err)
+	}
+
+	s := NewService(scope)
+	s.forgetControlPlaneZones()
+
+	// The second call is served from the cache.
+	for i := 0; i < 2; i++ {
+		zones, err := s.ControlPlaneZones()
+		if err != nil {
+			t.Fatalf("did not expect error: %v", err)
+		}
+		if zones["us-east-1a"] != 2 || zones["us-east-1b"] != 1 {
+			t.Fatalf("unexpected control plane zones: %v", zones)
+		}
+	}
+
+	// Launching a control plane instance refreshes the cache.
+	s.forgetControlPlaneZones()
+	if _, err := s.ControlPlaneZones(); err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+}
+
+func TestValidateSpreadPlacement(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	ec2Mock := mock_ec2iface.NewMockEC2API(mockCtrl)
+	ec2Mock.EXPECT().
+		DescribePlacementGroups(gomock.AssignableToTypeOf(&ec2.DescribePlacementGroupsInput{})).
+		Return(&ec2.DescribePlacementGroupsOutput{
+			PlacementGroups: []*ec2.PlacementGroup{{GroupName: aws.String("packed"), Strategy: aws.String(ec2.PlacementStrategyCluster)}},
+		}, nil)
+	ec2Mock.EXPECT().
+		DescribePlacementGroups(gomock.AssignableToTypeOf(&ec2.DescribePlacementGroupsInput{})).
+		Return(&ec2.DescribePlacementGroupsOutput{
+			PlacementGroups: []*ec2.PlacementGroup{{GroupName: aws.String("spread"), Strategy: aws.String(ec2.PlacementStrategySpread)}},
+		}, nil)
+
+	scope, err := actuators.NewScope(actuators.ScopeParams{
+		Cluster: &clusterv1.Cluster{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"},
+		},
+		AWSClients: actuators.AWSClients{
+			EC2: ec2Mock,
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create test context: %v", err)
+	}
+
+	testCases := []struct {
+		name        string
+		pg          *v1alpha1.PlacementGroup
+		expectError bool
+	}{
+		{
+			name: "no placement group",
+		},
+		{
+			name:        "cluster strategy",
+			pg:          &v1alpha1.PlacementGroup{Strategy: v1alpha1.PlacementStrategyCluster},
+			expectError: true,
+		},
+		{
+			name: "spread strategy",
+			pg:   &v1alpha1.PlacementGroup{Strategy: v1alpha1.PlacementStrategySpread},
+		},
+		{
+			name:        "existing cluster placement group",
+			pg:          &v1alpha1.PlacementGroup{Name: "packed"},
+			expectError: true,
+		},
+		{
+			name: "existing spread placement group",
+			pg:   &v1alpha1.PlacementGroup{Name: "spread"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := NewService(scope).validateSpreadPlacement(tc.pg)
+			if (err != nil) != tc.expectError {
+				t.Fatalf("expected error %t, got %v", tc.expectError, err)
+			}
+			if err != nil && !awserrors.IsInvalidConfiguration(errors.Cause(err)) {
+				t.Fatalf("expected an invalid configuration error, got %v", err)
+			}
+		})
+	}
+}
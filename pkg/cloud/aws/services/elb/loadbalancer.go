@@ -29,30 +29,59 @@ import (
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/elb"
 	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/klog"
 	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
 )
 
+const (
+	// elbNameMaxLength is the maximum length of a classic load balancer name.
+	elbNameMaxLength = 32
+
+	// connectionDrainingTimeout is how long the connections to an instance
+	// deregistered from the api server load balancer are kept open.
+	connectionDrainingTimeout = 30 * time.Second
+)
+
 // ReconcileLoadbalancers reconciles the load balancers for the given cluster.
 func (s *Service) ReconcileLoadbalancers() error {
+	// The API server is reached at an Elastic IP instead.
+	if s.scope.ClusterConfig.APIServerElasticIP {
+		return nil
+	}
+
 	klog.V(2).Info("Reconciling load balancers")
 
 	// Get default api server spec.
 	spec := s.getAPIServerClassicELBSpec()
 
 	// Describe or create.
-	apiELB, err := s.describeClassicELB(spec.Name)
+	apiELB, err := s.describeClassicELB(s.apiServerELBName())
 	if IsNotFound(err) {
 		apiELB, err = s.createClassicELB(spec)
 		if err != nil {
 			return err
 		}
 
+		// Control plane instances are terminated once the connections to
+		// them are drained.
+		if err := s.enableConnectionDraining(apiELB.Name); err != nil {
+			return err
+		}
+
 		klog.V(2).Infof("Created new classic load balancer for apiserver: %v", apiELB)
 	} else if err != nil {
 		return err
 	}
 
+	// The security group changes when restricted egress is toggled.
+	if !sets.NewString(apiELB.SecurityGroupIDs...).Equal(sets.NewString(spec.SecurityGroupIDs...)) {
+		if err := s.applyClassicELBSecurityGroups(apiELB.Name, spec.SecurityGroupIDs); err != nil {
+			return err
+		}
+		apiELB.SecurityGroupIDs = spec.SecurityGroupIDs
+	}
+
 	// TODO(vincepri): check if anything has changed and reconcile as necessary.
 	apiELB.DeepCopyInto(&s.scope.Network().APIServerELB)
 	klog.V(2).Info("Reconcile load balancers completed successfully")
@@ -61,7 +90,7 @@ func (s *Service) ReconcileLoadbalancers() error {
 
 // GetAPIServerDNSName returns the DNS name endpoint for the API server
 func (s *Service) GetAPIServerDNSName() (string, error) {
-	apiELB, err := s.describeClassicELB(GenerateELBName(s.scope.Name(), tags.ValueAPIServerRole))
+	apiELB, err := s.describeClassicELB(s.apiServerELBName())
 
 	if err != nil {
 		return "", err
@@ -74,14 +103,28 @@ func (s *Service) GetAPIServerDNSName() (string, error) {
 func (s *Service) DeleteLoadbalancers() error {
 	klog.V(2).Info("Deleting load balancers")
 
-	// Get default api server spec.
-	spec := s.getAPIServerClassicELBSpec()
+	if s.scope.ClusterConfig.APIServerPrivateLink != nil || s.scope.Network().APIServerPrivateLink != nil {
+		if err := s.deleteAPIServerPrivateLink(); err != nil {
+			return err
+		}
+	}
+
+	if name := s.otherMigrationELBName(); name != "" {
+		if err := s.deleteMigratedClassicELB(name); err != nil {
+			return err
+		}
+		s.scope.Network().APIServerELBMigration = nil
+	}
 
 	// Describe or create.
-	apiELB, err := s.describeClassicELB(spec.Name)
+	apiELB, err := s.describeClassicELB(s.apiServerELBName())
 	if IsNotFound(err) {
 		return nil
 	}
+	if IsConflict(err) {
+		klog.Warningf("Skipping deletion of load balancer: %v", err)
+		return nil
+	}
 	if err != nil {
 		return err
 	}
@@ -111,9 +154,13 @@ func (s *Service) RegisterInstanceWithClassicELB(instanceID string, loadBalancer
 
 // RegisterInstanceWithAPIServerELB registers an instance with a classic ELB
 func (s *Service) RegisterInstanceWithAPIServerELB(instanceID string) error {
+	if s.scope.ClusterConfig.APIServerElasticIP {
+		return nil
+	}
+
 	input := &elb.RegisterInstancesWithLoadBalancerInput{
 		Instances:        []*elb.Instance{{InstanceId: aws.String(instanceID)}},
-		LoadBalancerName: aws.String(GenerateELBName(s.scope.Name(), tags.ValueAPIServerRole)),
+		LoadBalancerName: aws.String(s.apiServerELBName()),
 	}
 
 	_, err := s.scope.ELB.RegisterInstancesWithLoadBalancer(input)
@@ -121,18 +168,112 @@ func (s *Service) RegisterInstanceWithAPIServerELB(instanceID string) error {
 		return err
 	}
 
+	// While the API server migrates, both load balancers serve it.
+	if name := s.otherMigrationELBName(); name != "" {
+		if err := s.RegisterInstanceWithClassicELB(instanceID, name); err != nil {
+			return errors.Wrapf(err, "failed to register instance %q with load balancer %q", instanceID, name)
+		}
+	}
+
+	return s.registerInstanceWithPrivateLink(instanceID)
+}
+
+// DeregisterInstanceFromAPIServerELB deregisters an instance from the api server load balancer.
+func (s *Service) DeregisterInstanceFromAPIServerELB(instanceID string) error {
+	if s.scope.ClusterConfig.APIServerElasticIP {
+		return nil
+	}
+
+	input := &elb.DeregisterInstancesFromLoadBalancerInput{
+		Instances:        []*elb.Instance{{InstanceId: aws.String(instanceID)}},
+		LoadBalancerName: aws.String(s.apiServerELBName()),
+	}
+
+	if _, err := s.scope.ELB.DeregisterInstancesFromLoadBalancer(input); err != nil {
+		return errors.Wrapf(err, "failed to deregister instance %q from load balancer %q", instanceID, s.apiServerELBName())
+	}
+
+	if name := s.otherMigrationELBName(); name != "" {
+		input.LoadBalancerName = aws.String(name)
+		if _, err := s.scope.ELB.DeregisterInstancesFromLoadBalancer(input); err != nil && !IsNotFound(err) {
+			return errors.Wrapf(err, "failed to deregister instance %q from load balancer %q", instanceID, name)
+		}
+	}
+
+	return s.deregisterInstanceFromPrivateLink(instanceID)
+}
+
+// InstanceDeregisteredFromAPIServerELB returns true once an instance is no
+// longer registered with the api server load balancer, that is once the
+// connections to an instance being deregistered are drained.
+func (s *Service) InstanceDeregisteredFromAPIServerELB(instanceID string) (bool, error) {
+	if s.scope.ClusterConfig.APIServerElasticIP {
+		return true, nil
+	}
+
+	out, err := s.scope.ELB.DescribeInstanceHealth(&elb.DescribeInstanceHealthInput{
+		Instances:        []*elb.Instance{{InstanceId: aws.String(instanceID)}},
+		LoadBalancerName: aws.String(s.apiServerELBName()),
+	})
+
+	switch code, _ := awserrors.Code(err); code {
+	case elb.ErrCodeInvalidEndPointException, elb.ErrCodeAccessPointNotFoundException:
+		return true, nil
+	}
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to describe instance %q in load balancer %q", instanceID, s.apiServerELBName())
+	}
+
+	for _, state := range out.InstanceStates {
+		if aws.StringValue(state.InstanceId) == instanceID {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// enableConnectionDraining keeps the connections to deregistered instances
+// open for connectionDrainingTimeout.
+func (s *Service) enableConnectionDraining(name string) error {
+	input := &elb.ModifyLoadBalancerAttributesInput{
+		LoadBalancerName: aws.String(name),
+		LoadBalancerAttributes: &elb.LoadBalancerAttributes{
+			ConnectionDraining: &elb.ConnectionDraining{
+				Enabled: aws.Bool(true),
+				Timeout: aws.Int64(int64(connectionDrainingTimeout.Seconds())),
+			},
+		},
+	}
+
+	if _, err := s.scope.ELB.ModifyLoadBalancerAttributes(input); err != nil {
+		return errors.Wrapf(err, "failed to enable connection draining for load balancer %q", name)
+	}
+
 	return nil
 }
 
-// GenerateELBName generates a formatted ELB name
-func GenerateELBName(clusterName string, elbName string) string {
-	return fmt.Sprintf("%s-%s", clusterName, elbName)
+// elbName returns the name of the cluster load balancer with the given role.
+func (s *Service) elbName(role string) string {
+	return s.scope.ResourceName(role, elbNameMaxLength)
+}
+
+// apiServerELBName returns the name of the api server load balancer recorded in
+// the cluster status, so that an existing load balancer keeps being found even if
+// the naming scheme of the cluster changes. Before the load balancer is created,
+// the name is generated from the naming scheme.
+func (s *Service) apiServerELBName() string {
+	if name := s.scope.Network().APIServerELB.Name; name != "" {
+		return name
+	}
+
+	return s.elbName(tags.ValueAPIServerRole)
 }
 
 func (s *Service) getAPIServerClassicELBSpec() *v1alpha1.ClassicELB {
 
 	res := &v1alpha1.ClassicELB{
-		Name:   GenerateELBName(s.scope.Name(), tags.ValueAPIServerRole),
+		Name:   s.elbName(tags.ValueAPIServerRole),
 		Scheme: v1alpha1.ClassicELBSchemeInternetFacing,
 		Listeners: []*v1alpha1.ClassicELBListener{
 			{
@@ -149,7 +290,7 @@ func (s *Service) getAPIServerClassicELBSpec() *v1alpha1.ClassicELB {
 			HealthyThreshold:   5,
 			UnhealthyThreshold: 3,
 		},
-		SecurityGroupIDs: []string{s.scope.SecurityGroups()[v1alpha1.SecurityGroupControlPlane].ID},
+		SecurityGroupIDs: []string{s.apiServerELBSecurityGroupID()},
 	}
 
 	res.Tags = tags.Build(tags.BuildParams{
@@ -158,13 +299,46 @@ func (s *Service) getAPIServerClassicELBSpec() *v1alpha1.ClassicELB {
 		Role:        aws.String(tags.ValueAPIServerRole),
 	})
 
-	for _, sn := range s.scope.Subnets().FilterPublic() {
+	subnets := s.scope.Subnets().FilterPublic()
+	if s.scope.ClusterConfig.PrivateAPIServer {
+		res.Scheme = v1alpha1.ClassicELBSchemeInternal
+		subnets = s.scope.Subnets().FilterPrivate()
+	}
+
+	for _, sn := range subnets {
 		res.SubnetIDs = append(res.SubnetIDs, sn.ID)
 	}
 
 	return res
 }
 
+// apiServerELBSecurityGroupID returns the security group of the API server
+// load balancer, which shares the control plane security group unless egress
+// is restricted.
+func (s *Service) apiServerELBSecurityGroupID() string {
+	if s.scope.ClusterConfig.RestrictedEgress {
+		if sg, ok := s.scope.SecurityGroups()[v1alpha1.SecurityGroupAPIServerLB]; ok {
+			return sg.ID
+		}
+	}
+	return s.scope.SecurityGroups()[v1alpha1.SecurityGroupControlPlane].ID
+}
+
+// applyClassicELBSecurityGroups replaces the security groups of a classic load balancer.
+func (s *Service) applyClassicELBSecurityGroups(name string, securityGroupIDs []string) error {
+	input := &elb.ApplySecurityGroupsToLoadBalancerInput{
+		LoadBalancerName: aws.String(name),
+		SecurityGroups:   aws.StringSlice(securityGroupIDs),
+	}
+
+	if _, err := s.scope.ELB.ApplySecurityGroupsToLoadBalancer(input); err != nil {
+		return errors.Wrapf(err, "failed to apply security groups %v to classic load balancer %q", securityGroupIDs, name)
+	}
+
+	klog.V(2).Infof("Applied security groups %v to classic load balancer %q", securityGroupIDs, name)
+	return nil
+}
+
 func (s *Service) createClassicELB(spec *v1alpha1.ClassicELB) (*v1alpha1.ClassicELB, error) {
 	input := &elb.CreateLoadBalancerInput{
 		LoadBalancerName: aws.String(spec.Name),
@@ -284,9 +458,40 @@ func (s *Service) describeClassicELB(name string) (*v1alpha1.ClassicELB, error)
 		return nil, NewNotFound(fmt.Errorf("no classic load balancer found with name %q", name))
 	}
 
+	// Load balancer names are only unique per account and region, make sure
+	// the one found belongs to this cluster before acting on it.
+	owned, err := s.ownsClassicELB(name)
+	if err != nil {
+		return nil, err
+	}
+	if !owned {
+		return nil, NewConflict(errors.Errorf("classic load balancer %q is not owned by cluster %q", name, s.scope.Name()))
+	}
+
 	return fromSDKTypeToClassicELB(out.LoadBalancerDescriptions[0]), nil
 }
 
+// ownsClassicELB returns true if the classic load balancer is tagged as belonging to the cluster.
+func (s *Service) ownsClassicELB(name string) (bool, error) {
+	out, err := s.scope.ELB.DescribeTags(&elb.DescribeTagsInput{
+		LoadBalancerNames: aws.StringSlice([]string{name}),
+	})
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to describe tags of classic load balancer %q", name)
+	}
+
+	key := tags.ClusterKey(s.scope.Name())
+	for _, desc := range out.TagDescriptions {
+		for _, tag := range desc.Tags {
+			if aws.StringValue(tag.Key) == key {
+				return true, nil
+			}
+		}
+	}
+
+	return false, nil
+}
+
 func fromSDKTypeToClassicELB(v *elb.LoadBalancerDescription) *v1alpha1.ClassicELB {
 	return &v1alpha1.ClassicELB{
 		Name:             aws.StringValue(v.LoadBalancerName),
@@ -0,0 +1,274 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package elb
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/elb"
+	"github.com/golang/mock/gomock"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/actuators"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/elb/mock_elbiface"
+	clusterv1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+)
+
+func TestLoadBalancerOfAnotherCluster(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	// A load balancer with the same name, left behind by a cluster of the
+	// same name in another namespace or management cluster.
+	elbMock := mock_elbiface.NewMockELBAPI(mockCtrl)
+	elbMock.EXPECT().
+		DescribeLoadBalancers(&elb.DescribeLoadBalancersInput{
+			LoadBalancerNames: aws.StringSlice([]string{"test-cluster-apiserver"}),
+		}).
+		Return(&elb.DescribeLoadBalancersOutput{
+			LoadBalancerDescriptions: []*elb.LoadBalancerDescription{{
+				LoadBalancerName: aws.String("test-cluster-apiserver"),
+				Scheme:           aws.String("internet-facing"),
+				DNSName:          aws.String("other.elb.amazonaws.com"),
+			}},
+		}, nil).
+		Times(2)
+	elbMock.EXPECT().
+		DescribeTags(&elb.DescribeTagsInput{
+			LoadBalancerNames: aws.StringSlice([]string{"test-cluster-apiserver"}),
+		}).
+		Return(&elb.DescribeTagsOutput{
+			TagDescriptions: []*elb.TagDescription{{
+				LoadBalancerName: aws.String("test-cluster-apiserver"),
+				Tags:             []*elb.Tag{{Key: aws.String("kubernetes.io/cluster/other-cluster"), Value: aws.String("owned")}},
+			}},
+		}, nil).
+		Times(2)
+
+	scope, err := actuators.NewScope(actuators.ScopeParams{
+		Cluster: &clusterv1.Cluster{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"},
+		},
+		AWSClients: actuators.AWSClients{
+			ELB: elbMock,
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create test context: %v", err)
+	}
+
+	scope.ClusterStatus.Network.SecurityGroups = map[v1alpha1.SecurityGroupRole]*v1alpha1.SecurityGroup{
+		v1alpha1.SecurityGroupControlPlane: {ID: "sg-cp"},
+	}
+
+	s := NewService(scope)
+
+	if err := s.ReconcileLoadbalancers(); !IsConflict(err) {
+		t.Fatalf("expected a conflict reconciling the load balancer of another cluster, got: %v", err)
+	}
+
+	// The load balancer must not be deleted.
+	if err := s.DeleteLoadbalancers(); err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+}
+
+func TestLoadBalancerRestrictedEgress(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	// The existing load balancer still uses the control plane security group.
+	elbMock := mock_elbiface.NewMockELBAPI(mockCtrl)
+	elbMock.EXPECT().
+		DescribeLoadBalancers(&elb.DescribeLoadBalancersInput{
+			LoadBalancerNames: aws.StringSlice([]string{"test-cluster-apiserver"}),
+		}).
+		Return(&elb.DescribeLoadBalancersOutput{
+			LoadBalancerDescriptions: []*elb.LoadBalancerDescription{{
+				LoadBalancerName: aws.String("test-cluster-apiserver"),
+				Scheme:           aws.String("internet-facing"),
+				SecurityGroups:   aws.StringSlice([]string{"sg-cp"}),
+			}},
+		}, nil)
+	elbMock.EXPECT().
+		DescribeTags(gomock.Any()).
+		Return(&elb.DescribeTagsOutput{
+			TagDescriptions: []*elb.TagDescription{{
+				LoadBalancerName: aws.String("test-cluster-apiserver"),
+				Tags:             []*elb.Tag{{Key: aws.String("kubernetes.io/cluster/test-cluster"), Value: aws.String("owned")}},
+			}},
+		}, nil)
+	elbMock.EXPECT().
+		ApplySecurityGroupsToLoadBalancer(&elb.ApplySecurityGroupsToLoadBalancerInput{
+			LoadBalancerName: aws.String("test-cluster-apiserver"),
+			SecurityGroups:   aws.StringSlice([]string{"sg-lb"}),
+		}).
+		Return(&elb.ApplySecurityGroupsToLoadBalancerOutput{}, nil)
+
+	scope, err := actuators.NewScope(actuators.ScopeParams{
+		Cluster: &clusterv1.Cluster{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"},
+		},
+		AWSClients: actuators.AWSClients{
+			ELB: elbMock,
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create test context: %v", err)
+	}
+
+	scope.ClusterConfig.RestrictedEgress = true
+	scope.ClusterStatus.Network.SecurityGroups = map[v1alpha1.SecurityGroupRole]*v1alpha1.SecurityGroup{
+		v1alpha1.SecurityGroupControlPlane: {ID: "sg-cp"},
+		v1alpha1.SecurityGroupAPIServerLB:  {ID: "sg-lb"},
+	}
+
+	if err := NewService(scope).ReconcileLoadbalancers(); err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+
+	if sgs := scope.Network().APIServerELB.SecurityGroupIDs; len(sgs) != 1 || sgs[0] != "sg-lb" {
+		t.Fatalf("expected the load balancer to use its own security group, got %v", sgs)
+	}
+}
+
+func TestInstanceDeregisteredFromAPIServerELB(t *testing.T) {
+	testCases := []struct {
+		name     string
+		output   *elb.DescribeInstanceHealthOutput
+		err      error
+		expected bool
+	}{
+		{
+			name: "connections being drained",
+			output: &elb.DescribeInstanceHealthOutput{
+				InstanceStates: []*elb.InstanceState{{
+					InstanceId:  aws.String("i-1"),
+					State:       aws.String("OutOfService"),
+					Description: aws.String("Instance deregistration currently in progress."),
+				}},
+			},
+		},
+		{
+			name:     "not registered",
+			err:      awserr.New(elb.ErrCodeInvalidEndPointException, "Could not find EC2 instance i-1.", nil),
+			expected: true,
+		},
+		{
+			name:     "load balancer deleted",
+			err:      awserr.New(elb.ErrCodeAccessPointNotFoundException, "There is no ACTIVE Load Balancer named 'test-cluster-apiserver'", nil),
+			expected: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+
+			elbMock := mock_elbiface.NewMockELBAPI(mockCtrl)
+			elbMock.EXPECT().
+				DescribeInstanceHealth(&elb.DescribeInstanceHealthInput{
+					Instances:        []*elb.Instance{{InstanceId: aws.String("i-1")}},
+					LoadBalancerName: aws.String("test-cluster-apiserver"),
+				}).
+				Return(tc.output, tc.err)
+
+			scope, err := actuators.NewScope(actuators.ScopeParams{
+				Cluster: &clusterv1.Cluster{
+					ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"},
+				},
+				AWSClients: actuators.AWSClients{
+					ELB: elbMock,
+				},
+			})
+			if err != nil {
+				t.Fatalf("Failed to create test context: %v", err)
+			}
+
+			deregistered, err := NewService(scope).InstanceDeregisteredFromAPIServerELB("i-1")
+			if err != nil {
+				t.Fatalf("did not expect error: %v", err)
+			}
+			if deregistered != tc.expected {
+				t.Fatalf("expected deregistered to be %t, got %t", tc.expected, deregistered)
+			}
+		})
+	}
+}
+
+func TestPrivateAPIServerELBSpec(t *testing.T) {
+	scope, err := actuators.NewScope(actuators.ScopeParams{
+		Cluster: &clusterv1.Cluster{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create test context: %v", err)
+	}
+
+	scope.ClusterStatus.Network.Subnets = v1alpha1.Subnets{
+		{ID: "subnet-public", IsPublic: true},
+		{ID: "subnet-private"},
+	}
+	scope.ClusterStatus.Network.SecurityGroups = map[v1alpha1.SecurityGroupRole]*v1alpha1.SecurityGroup{
+		v1alpha1.SecurityGroupControlPlane: {ID: "sg-cp"},
+	}
+
+	spec := NewService(scope).getAPIServerClassicELBSpec()
+	if spec.Scheme != v1alpha1.ClassicELBSchemeInternetFacing || len(spec.SubnetIDs) != 1 || spec.SubnetIDs[0] != "subnet-public" {
+		t.Fatalf("expected an internet-facing load balancer in the public subnets, got %q in %v", spec.Scheme, spec.SubnetIDs)
+	}
+
+	scope.ClusterConfig.PrivateAPIServer = true
+	spec = NewService(scope).getAPIServerClassicELBSpec()
+	if spec.Scheme != v1alpha1.ClassicELBSchemeInternal || len(spec.SubnetIDs) != 1 || spec.SubnetIDs[0] != "subnet-private" {
+		t.Fatalf("expected an internal load balancer in the private subnets, got %q in %v", spec.Scheme, spec.SubnetIDs)
+	}
+}
+
+func TestAPIServerElasticIP(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	// No load balancer calls are expected.
+	elbMock := mock_elbiface.NewMockELBAPI(mockCtrl)
+
+	scope, err := actuators.NewScope(actuators.ScopeParams{
+		Cluster: &clusterv1.Cluster{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"},
+		},
+		AWSClients: actuators.AWSClients{ELB: elbMock},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create test context: %v", err)
+	}
+	scope.ClusterConfig.APIServerElasticIP = true
+
+	s := NewService(scope)
+	if err := s.ReconcileLoadbalancers(); err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+	if err := s.RegisterInstanceWithAPIServerELB("i-1"); err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+	if deregistered, err := s.InstanceDeregisteredFromAPIServerELB("i-1"); err != nil || !deregistered {
+		t.Fatalf("expected the instance to be deregistered, got %t, %v", deregistered, err)
+	}
+}
@@ -0,0 +1,237 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package elb
+
+import (
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/elb"
+	"github.com/pkg/errors"
+	"k8s.io/klog"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/awserrors"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/ssm"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/userdata"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/tags"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/record"
+)
+
+// ReconcileAPIServerELBMigration moves the API server of a live cluster to a
+// new classic load balancer once the scheme requested for it changes, e.g.
+// when privateAPIServer is set on a cluster with an internet-facing load
+// balancer. The new load balancer is created, serving the control plane
+// instances of the current one, and its DNS name is added to the serving
+// certificates of the API servers. The endpoint of the cluster, from which the
+// kubeconfigs handed out by the controllers are generated, then switches to
+// it. The kubeconfigs of the instances and the kubeadm configuration of the
+// cluster are pointed at it, before the previous load balancer is deleted.
+//
+// The certificates and kubeconfigs of the instances are updated with Run
+// Command, one instance at a time.
+func (s *Service) ReconcileAPIServerELBMigration() error {
+	if s.scope.ClusterConfig.APIServerElasticIP {
+		return nil
+	}
+
+	migration := s.scope.Network().APIServerELBMigration
+	if migration == nil {
+		current := s.scope.Network().APIServerELB
+		spec := s.getAPIServerClassicELBSpec()
+		if current.Name == "" || strings.EqualFold(string(current.Scheme), string(spec.Scheme)) {
+			return nil
+		}
+
+		spec.Name = s.migrationELBName(current.Name)
+		to, err := s.describeClassicELB(spec.Name)
+		if IsNotFound(err) {
+			to, err = s.createClassicELB(spec)
+			if err != nil {
+				return err
+			}
+			if err := s.enableConnectionDraining(to.Name); err != nil {
+				return err
+			}
+		} else if err != nil {
+			return err
+		}
+
+		migration = &v1alpha1.APIServerELBMigration{
+			Phase: v1alpha1.APIServerELBMigrationCertificates,
+			From:  *current.DeepCopy(),
+			To:    *to,
+		}
+		s.scope.Network().APIServerELBMigration = migration
+		record.Eventf(s.scope.Cluster, "MigratingAPIServerELB", "Migrating the API server from load balancer %q to %s load balancer %q", migration.From.Name, spec.Scheme, to.Name)
+	}
+
+	input := &userdata.APIServerEndpointInput{
+		PreviousAddress: migration.From.DNSName,
+		Address:         migration.To.DNSName,
+		ServiceDomain:   s.scope.Cluster.Spec.ClusterNetwork.ServiceDomain,
+	}
+	if blocks := s.scope.Cluster.Spec.ClusterNetwork.Services.CIDRBlocks; len(blocks) > 0 {
+		input.ServiceSubnet = blocks[0]
+	}
+
+	if migration.Phase == v1alpha1.APIServerELBMigrationCertificates {
+		instanceIDs, err := s.classicELBInstances(migration.From.Name)
+		if err != nil {
+			return err
+		}
+
+		if len(instanceIDs) > 0 {
+			if _, err := s.scope.ELB.RegisterInstancesWithLoadBalancer(&elb.RegisterInstancesWithLoadBalancerInput{
+				Instances:        classicELBInstanceList(instanceIDs),
+				LoadBalancerName: aws.String(migration.To.Name),
+			}); err != nil {
+				return errors.Wrapf(err, "failed to register instances %v with load balancer %q", instanceIDs, migration.To.Name)
+			}
+
+			script, err := userdata.NewAPIServerCertificateScript(input)
+			if err != nil {
+				return err
+			}
+			if err := s.runMigrationCommand(migration, script, instanceIDs, nil); err != nil {
+				return err
+			}
+		}
+
+		// The endpoint of the cluster switches to the new load balancer.
+		migration.To.DeepCopyInto(&s.scope.Network().APIServerELB)
+		migration.Phase = v1alpha1.APIServerELBMigrationKubeconfigs
+		record.Eventf(s.scope.Cluster, "SwitchedAPIServerELB", "Switched the endpoint of the API server to load balancer %q", migration.To.Name)
+	}
+
+	script, err := userdata.NewAPIServerKubeconfigScript(input)
+	if err != nil {
+		return err
+	}
+	clusterTags := map[string]string{tags.ClusterKey(s.scope.Name()): string(tags.ResourceLifecycleOwned)}
+	if err := s.runMigrationCommand(migration, script, nil, clusterTags); err != nil {
+		return err
+	}
+
+	if err := s.deleteMigratedClassicELB(migration.From.Name); err != nil {
+		return err
+	}
+
+	s.scope.Network().APIServerELBMigration = nil
+	record.Eventf(s.scope.Cluster, "MigratedAPIServerELB", "Migrated the API server to load balancer %q", migration.To.Name)
+	klog.V(2).Infof("Migrated the API server of cluster %q from load balancer %q to %q", s.scope.Name(), migration.From.Name, migration.To.Name)
+	return nil
+}
+
+// migrationELBName returns the name of the load balancer the API server
+// migrates to from the named one: the API server load balancer names of the
+// cluster alternate between its default name and a suffixed one.
+func (s *Service) migrationELBName(current string) string {
+	if name := s.elbName(tags.ValueAPIServerRole); name != current {
+		return name
+	}
+	return s.elbName(tags.ValueAPIServerRole + "-2")
+}
+
+// otherMigrationELBName returns the name of the other load balancer of the
+// migration of the API server in progress, or an empty string.
+func (s *Service) otherMigrationELBName() string {
+	migration := s.scope.Network().APIServerELBMigration
+	if migration == nil {
+		return ""
+	}
+	if migration.To.Name == s.apiServerELBName() {
+		return migration.From.Name
+	}
+	return migration.To.Name
+}
+
+// runMigrationCommand runs a script of the current phase of a migration,
+// recording the command in the migration. It returns nil once the command
+// succeeded, and a failed dependency error while it runs.
+func (s *Service) runMigrationCommand(migration *v1alpha1.APIServerELBMigration, script string, instanceIDs []string, instanceTags map[string]string) error {
+	if migration.CommandID == "" {
+		comment := "cluster-api-provider-aws API server migration, phase " + string(migration.Phase)
+		id, err := s.scope.Commands.RunShellScript(comment, script, instanceIDs, instanceTags)
+		if err != nil {
+			return err
+		}
+		migration.CommandID = id
+		return awserrors.NewFailedDependency(errors.Errorf("running command %q of the %s phase of the API server migration", id, migration.Phase))
+	}
+
+	status, err := s.scope.Commands.CommandStatus(migration.CommandID)
+	if err != nil {
+		return err
+	}
+
+	switch status {
+	case ssm.CommandStatusSuccess:
+		migration.CommandID = ""
+		return nil
+	case ssm.CommandStatusFailed:
+		// The scripts are safe to run again, which the next reconciliation does.
+		id := migration.CommandID
+		migration.CommandID = ""
+		return errors.Errorf("command %q of the %s phase of the API server migration failed, see its output in Systems Manager", id, migration.Phase)
+	default:
+		return awserrors.NewFailedDependency(errors.Errorf("command %q of the %s phase of the API server migration is %s", migration.CommandID, migration.Phase, status))
+	}
+}
+
+// classicELBInstances returns the IDs of the instances registered with a
+// classic load balancer.
+func (s *Service) classicELBInstances(name string) ([]string, error) {
+	out, err := s.scope.ELB.DescribeLoadBalancers(&elb.DescribeLoadBalancersInput{
+		LoadBalancerNames: aws.StringSlice([]string{name}),
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to describe classic load balancer %q", name)
+	}
+
+	var ids []string
+	for _, desc := range out.LoadBalancerDescriptions {
+		for _, instance := range desc.Instances {
+			ids = append(ids, aws.StringValue(instance.InstanceId))
+		}
+	}
+	return ids, nil
+}
+
+// deleteMigratedClassicELB deletes a load balancer the API server migrated
+// from, if it still exists.
+func (s *Service) deleteMigratedClassicELB(name string) error {
+	_, err := s.describeClassicELB(name)
+	if IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := s.deleteClassicELBAndWait(name); err != nil {
+		return errors.Wrapf(err, "failed to delete load balancer %q", name)
+	}
+	return nil
+}
+
+func classicELBInstanceList(ids []string) []*elb.Instance {
+	instances := make([]*elb.Instance, 0, len(ids))
+	for _, id := range ids {
+		instances = append(instances, &elb.Instance{InstanceId: aws.String(id)})
+	}
+	return instances
+}
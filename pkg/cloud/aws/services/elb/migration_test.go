@@ -0,0 +1,285 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package elb
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/elb"
+	"github.com/aws/aws-sdk-go/service/elb/elbiface"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/actuators"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/awserrors"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/ssm"
+	clusterv1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+)
+
+// classicELBs is an account with the classic load balancers created by the
+// tests.
+type classicELBs struct {
+	elbiface.ELBAPI
+
+	loadBalancers map[string]*elb.LoadBalancerDescription
+	tags          map[string][]*elb.Tag
+}
+
+func (c *classicELBs) DescribeLoadBalancers(input *elb.DescribeLoadBalancersInput) (*elb.DescribeLoadBalancersOutput, error) {
+	lb, ok := c.loadBalancers[aws.StringValue(input.LoadBalancerNames[0])]
+	if !ok {
+		return &elb.DescribeLoadBalancersOutput{}, awserr.New(elb.ErrCodeAccessPointNotFoundException, "not found", nil)
+	}
+	return &elb.DescribeLoadBalancersOutput{LoadBalancerDescriptions: []*elb.LoadBalancerDescription{lb}}, nil
+}
+
+func (c *classicELBs) DescribeTags(input *elb.DescribeTagsInput) (*elb.DescribeTagsOutput, error) {
+	name := input.LoadBalancerNames[0]
+	return &elb.DescribeTagsOutput{
+		TagDescriptions: []*elb.TagDescription{{LoadBalancerName: name, Tags: c.tags[aws.StringValue(name)]}},
+	}, nil
+}
+
+func (c *classicELBs) CreateLoadBalancer(input *elb.CreateLoadBalancerInput) (*elb.CreateLoadBalancerOutput, error) {
+	name := aws.StringValue(input.LoadBalancerName)
+	c.loadBalancers[name] = &elb.LoadBalancerDescription{
+		LoadBalancerName: input.LoadBalancerName,
+		Scheme:           input.Scheme,
+		Subnets:          input.Subnets,
+		SecurityGroups:   input.SecurityGroups,
+		DNSName:          aws.String(name + ".elb.amazonaws.com"),
+	}
+	c.tags[name] = input.Tags
+	return &elb.CreateLoadBalancerOutput{DNSName: c.loadBalancers[name].DNSName}, nil
+}
+
+func (c *classicELBs) ConfigureHealthCheck(*elb.ConfigureHealthCheckInput) (*elb.ConfigureHealthCheckOutput, error) {
+	return &elb.ConfigureHealthCheckOutput{}, nil
+}
+
+func (c *classicELBs) ModifyLoadBalancerAttributes(*elb.ModifyLoadBalancerAttributesInput) (*elb.ModifyLoadBalancerAttributesOutput, error) {
+	return &elb.ModifyLoadBalancerAttributesOutput{}, nil
+}
+
+func (c *classicELBs) RegisterInstancesWithLoadBalancer(input *elb.RegisterInstancesWithLoadBalancerInput) (*elb.RegisterInstancesWithLoadBalancerOutput, error) {
+	lb := c.loadBalancers[aws.StringValue(input.LoadBalancerName)]
+	registered := sets.NewString()
+	for _, instance := range lb.Instances {
+		registered.Insert(aws.StringValue(instance.InstanceId))
+	}
+	for _, instance := range input.Instances {
+		if !registered.Has(aws.StringValue(instance.InstanceId)) {
+			lb.Instances = append(lb.Instances, instance)
+		}
+	}
+	return &elb.RegisterInstancesWithLoadBalancerOutput{}, nil
+}
+
+func (c *classicELBs) DeleteLoadBalancer(input *elb.DeleteLoadBalancerInput) (*elb.DeleteLoadBalancerOutput, error) {
+	delete(c.loadBalancers, aws.StringValue(input.LoadBalancerName))
+	return &elb.DeleteLoadBalancerOutput{}, nil
+}
+
+func (c *classicELBs) instances(name string) []string {
+	var ids []string
+	for _, instance := range c.loadBalancers[name].Instances {
+		ids = append(ids, aws.StringValue(instance.InstanceId))
+	}
+	return ids
+}
+
+// commands records the scripts sent, which complete with the given status.
+type commands struct {
+	scripts     []string
+	instanceIDs [][]string
+	tags        []map[string]string
+	status      string
+}
+
+func (c *commands) RunShellScript(comment, script string, instanceIDs []string, tags map[string]string) (string, error) {
+	c.scripts = append(c.scripts, script)
+	c.instanceIDs = append(c.instanceIDs, instanceIDs)
+	c.tags = append(c.tags, tags)
+	c.status = "InProgress"
+	return fmt.Sprintf("command-%d", len(c.scripts)), nil
+}
+
+func (c *commands) CommandStatus(id string) (string, error) {
+	return c.status, nil
+}
+
+func TestReconcileAPIServerELBMigration(t *testing.T) {
+	clusterTags := []*elb.Tag{{Key: aws.String("kubernetes.io/cluster/test-cluster"), Value: aws.String("owned")}}
+	elbs := &classicELBs{
+		loadBalancers: map[string]*elb.LoadBalancerDescription{
+			"test-cluster-apiserver": {
+				LoadBalancerName: aws.String("test-cluster-apiserver"),
+				Scheme:           aws.String("internet-facing"),
+				DNSName:          aws.String("public.elb.amazonaws.com"),
+				Instances:        []*elb.Instance{{InstanceId: aws.String("i-1")}},
+			},
+		},
+		tags: map[string][]*elb.Tag{"test-cluster-apiserver": clusterTags},
+	}
+	cmds := &commands{}
+
+	cluster := &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"}}
+	cluster.Spec.ClusterNetwork.ServiceDomain = "cluster.local"
+	cluster.Spec.ClusterNetwork.Services.CIDRBlocks = []string{"10.96.0.0/12"}
+
+	scope, err := actuators.NewScope(actuators.ScopeParams{
+		Cluster:    cluster,
+		AWSClients: actuators.AWSClients{ELB: elbs},
+		Commands:   cmds,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create test context: %v", err)
+	}
+
+	scope.ClusterConfig.PrivateAPIServer = true
+	scope.ClusterStatus.Network.Subnets = v1alpha1.Subnets{
+		{ID: "subnet-public", IsPublic: true},
+		{ID: "subnet-private"},
+	}
+	scope.ClusterStatus.Network.SecurityGroups = map[v1alpha1.SecurityGroupRole]*v1alpha1.SecurityGroup{
+		v1alpha1.SecurityGroupControlPlane: {ID: "sg-cp"},
+	}
+	scope.ClusterStatus.Network.APIServerELB = v1alpha1.ClassicELB{
+		Name:    "test-cluster-apiserver",
+		Scheme:  "internet-facing",
+		DNSName: "public.elb.amazonaws.com",
+	}
+
+	s := NewService(scope)
+
+	// The internal load balancer is created and serves the control plane
+	// instances, whose certificates are renewed.
+	if err := s.ReconcileAPIServerELBMigration(); !awserrors.IsFailedDependency(err) {
+		t.Fatalf("expected a failed dependency while the certificates are renewed, got %v", err)
+	}
+	internal := elbs.loadBalancers["test-cluster-apiserver-2"]
+	if internal == nil || aws.StringValue(internal.Scheme) != "internal" || !reflect.DeepEqual(aws.StringValueSlice(internal.Subnets), []string{"subnet-private"}) {
+		t.Fatalf("expected an internal load balancer in the private subnets, got %+v", internal)
+	}
+	if !reflect.DeepEqual(elbs.instances("test-cluster-apiserver-2"), []string{"i-1"}) {
+		t.Fatalf("expected i-1 to be registered with the internal load balancer, got %v", elbs.instances("test-cluster-apiserver-2"))
+	}
+	if len(cmds.scripts) != 1 || !reflect.DeepEqual(cmds.instanceIDs[0], []string{"i-1"}) ||
+		!strings.Contains(cmds.scripts[0], `- "public.elb.amazonaws.com"`) ||
+		!strings.Contains(cmds.scripts[0], `- "test-cluster-apiserver-2.elb.amazonaws.com"`) ||
+		!strings.Contains(cmds.scripts[0], `serviceSubnet: "10.96.0.0/12"`) {
+		t.Fatalf("expected a script adding the internal load balancer to the certificate of i-1, got %v on %v", cmds.scripts, cmds.instanceIDs)
+	}
+
+	// Control plane instances created meanwhile serve both load balancers.
+	if err := s.RegisterInstanceWithAPIServerELB("i-2"); err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+	if !reflect.DeepEqual(elbs.instances("test-cluster-apiserver"), []string{"i-1", "i-2"}) ||
+		!reflect.DeepEqual(elbs.instances("test-cluster-apiserver-2"), []string{"i-1", "i-2"}) {
+		t.Fatalf("expected i-2 to be registered with both load balancers")
+	}
+
+	if err := s.ReconcileAPIServerELBMigration(); !awserrors.IsFailedDependency(err) {
+		t.Fatalf("expected a failed dependency while the command runs, got %v", err)
+	}
+	if scope.APIServerEndpoint() != "public.elb.amazonaws.com" {
+		t.Fatalf("expected the endpoint not to switch before the certificates are renewed, got %q", scope.APIServerEndpoint())
+	}
+
+	// The endpoint switches once the certificates are renewed, and the
+	// kubeconfigs of all the instances of the cluster are updated.
+	cmds.status = ssm.CommandStatusSuccess
+	if err := s.ReconcileAPIServerELBMigration(); !awserrors.IsFailedDependency(err) {
+		t.Fatalf("expected a failed dependency while the kubeconfigs are updated, got %v", err)
+	}
+	if scope.APIServerEndpoint() != "test-cluster-apiserver-2.elb.amazonaws.com" {
+		t.Fatalf("expected the endpoint to switch to the internal load balancer, got %q", scope.APIServerEndpoint())
+	}
+	if len(cmds.scripts) != 2 || len(cmds.instanceIDs[1]) != 0 ||
+		!reflect.DeepEqual(cmds.tags[1], map[string]string{"kubernetes.io/cluster/test-cluster": "owned"}) ||
+		!strings.Contains(cmds.scripts[1], "s|https://public.elb.amazonaws.com:6443|https://test-cluster-apiserver-2.elb.amazonaws.com:6443|g") {
+		t.Fatalf("expected a script updating the kubeconfigs of the instances of the cluster, got %v on %v", cmds.scripts, cmds.tags)
+	}
+	if elbs.loadBalancers["test-cluster-apiserver"] == nil {
+		t.Fatalf("expected the public load balancer to be kept until the kubeconfigs are updated")
+	}
+
+	// The public load balancer is deleted once the kubeconfigs are updated.
+	cmds.status = ssm.CommandStatusSuccess
+	if err := s.ReconcileAPIServerELBMigration(); err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+	if elbs.loadBalancers["test-cluster-apiserver"] != nil {
+		t.Fatalf("expected the public load balancer to be deleted")
+	}
+	if scope.ClusterStatus.Network.APIServerELBMigration != nil {
+		t.Fatalf("expected the migration to complete, got %+v", scope.ClusterStatus.Network.APIServerELBMigration)
+	}
+
+	// Another scheme change migrates back to the default name.
+	scope.ClusterConfig.PrivateAPIServer = false
+	if err := s.ReconcileAPIServerELBMigration(); !awserrors.IsFailedDependency(err) {
+		t.Fatalf("expected a failed dependency while the certificates are renewed, got %v", err)
+	}
+	if lb := elbs.loadBalancers["test-cluster-apiserver"]; lb == nil || aws.StringValue(lb.Scheme) != string(v1alpha1.ClassicELBSchemeInternetFacing) {
+		t.Fatalf("expected an internet-facing load balancer named test-cluster-apiserver, got %+v", lb)
+	}
+}
+
+func TestReconcileAPIServerELBMigrationFailedCommand(t *testing.T) {
+	elbs := &classicELBs{
+		loadBalancers: map[string]*elb.LoadBalancerDescription{},
+		tags:          map[string][]*elb.Tag{},
+	}
+	cmds := &commands{}
+
+	scope, err := actuators.NewScope(actuators.ScopeParams{
+		Cluster:    &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"}},
+		AWSClients: actuators.AWSClients{ELB: elbs},
+		Commands:   cmds,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create test context: %v", err)
+	}
+
+	scope.ClusterStatus.Network.APIServerELBMigration = &v1alpha1.APIServerELBMigration{
+		Phase:     v1alpha1.APIServerELBMigrationKubeconfigs,
+		From:      v1alpha1.ClassicELB{Name: "test-cluster-apiserver", DNSName: "public.elb.amazonaws.com"},
+		To:        v1alpha1.ClassicELB{Name: "test-cluster-apiserver-2", DNSName: "internal.elb.amazonaws.com"},
+		CommandID: "command-1",
+	}
+	cmds.status = ssm.CommandStatusFailed
+
+	// The command is sent again by the next reconciliation.
+	err = NewService(scope).ReconcileAPIServerELBMigration()
+	if err == nil || awserrors.IsFailedDependency(err) {
+		t.Fatalf("expected the failure of the command, got %v", err)
+	}
+	if migration := scope.ClusterStatus.Network.APIServerELBMigration; migration == nil || migration.CommandID != "" {
+		t.Fatalf("expected the command to be forgotten, got %+v", migration)
+	}
+
+	if err := NewService(scope).ReconcileAPIServerELBMigration(); !awserrors.IsFailedDependency(err) || len(cmds.scripts) != 1 {
+		t.Fatalf("expected the command to be sent again, got %v", err)
+	}
+}
@@ -0,0 +1,372 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package elb
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/awserrors"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/elbv2"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/wait"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/tags"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/record"
+)
+
+const (
+	// privateLinkRole is the role in the names of the network load balancer
+	// and target group exposing the API server with PrivateLink.
+	privateLinkRole = "apiserver-pl"
+
+	// apiServerPort is the port of the API server on the control plane
+	// instances and on the load balancers.
+	apiServerPort = 6443
+
+	// errCodeEndpointServiceNotFound is the error code of the requests for
+	// endpoint services which do not exist.
+	errCodeEndpointServiceNotFound = "InvalidVpcEndpointServiceId.NotFound"
+)
+
+// ReconcileAPIServerPrivateLink exposes the API server as a PrivateLink
+// endpoint service, backed by an internal network load balancer forwarding
+// to the control plane instances, and records the service in the cluster
+// status. The load balancer and service are deleted once the cluster no
+// longer requests them.
+func (s *Service) ReconcileAPIServerPrivateLink() error {
+	config := s.scope.ClusterConfig.APIServerPrivateLink
+	if config == nil {
+		if s.scope.Network().APIServerPrivateLink == nil {
+			return nil
+		}
+		return s.deleteAPIServerPrivateLink()
+	}
+
+	klog.V(2).Info("Reconciling API server PrivateLink endpoint service")
+
+	name := s.privateLinkName()
+	resourceTags := tags.Build(tags.BuildParams{
+		ClusterName: s.scope.Name(),
+		Lifecycle:   tags.ResourceLifecycleOwned,
+		Role:        aws.String(tags.ValueAPIServerRole),
+	})
+
+	targetGroupARN, err := s.scope.NetworkLoadBalancers.DescribeTargetGroup(name)
+	if err != nil {
+		return err
+	}
+	if targetGroupARN == "" {
+		targetGroupARN, err = s.scope.NetworkLoadBalancers.CreateTargetGroup(&elbv2.TargetGroupSpec{
+			Name:  name,
+			VPCID: s.scope.VPC().ID,
+			Port:  apiServerPort,
+			Tags:  resourceTags,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	lb, err := s.describePrivateLinkLoadBalancer(name)
+	if err != nil {
+		return err
+	}
+	if lb == nil {
+		spec := &elbv2.LoadBalancerSpec{Name: name, Tags: resourceTags}
+		for _, sn := range s.scope.Subnets().FilterPrivate() {
+			spec.Subnets = append(spec.Subnets, sn.ID)
+		}
+
+		lb, err = s.scope.NetworkLoadBalancers.CreateLoadBalancer(spec)
+		if err != nil {
+			return err
+		}
+		klog.V(2).Infof("Created network load balancer %q for the PrivateLink endpoint service of the API server", lb.ARN)
+	}
+
+	ports, err := s.scope.NetworkLoadBalancers.DescribeListenerPorts(lb.ARN)
+	if err != nil {
+		return err
+	}
+	if !containsPort(ports, apiServerPort) {
+		if err := s.scope.NetworkLoadBalancers.CreateListener(lb.ARN, targetGroupARN, apiServerPort); err != nil {
+			return err
+		}
+	}
+
+	// Control plane instances are registered with the target group as soon
+	// as it is recorded, while the load balancer is provisioned.
+	status := s.scope.Network().APIServerPrivateLink
+	if status == nil {
+		status = &v1alpha1.PrivateLinkService{}
+		s.scope.Network().APIServerPrivateLink = status
+	}
+	status.LoadBalancerARN = lb.ARN
+	status.TargetGroupARN = targetGroupARN
+
+	if lb.State != elbv2.LoadBalancerStateActive {
+		return awserrors.NewFailedDependency(errors.Errorf("network load balancer %q is %s", lb.Name, lb.State))
+	}
+
+	service, err := s.describeEndpointService(status.ServiceID, lb.ARN)
+	if err != nil {
+		return err
+	}
+	if service == nil {
+		out, err := s.scope.EC2.CreateVpcEndpointServiceConfiguration(&ec2.CreateVpcEndpointServiceConfigurationInput{
+			AcceptanceRequired:      aws.Bool(config.AcceptanceRequired),
+			NetworkLoadBalancerArns: aws.StringSlice([]string{lb.ARN}),
+		})
+		if err != nil {
+			return errors.Wrapf(err, "failed to create endpoint service for network load balancer %q", lb.ARN)
+		}
+		service = out.ServiceConfiguration
+		record.Eventf(s.scope.Cluster, "CreatedPrivateLinkService", "Created PrivateLink endpoint service %q for the API server", aws.StringValue(service.ServiceName))
+	} else if aws.BoolValue(service.AcceptanceRequired) != config.AcceptanceRequired {
+		if _, err := s.scope.EC2.ModifyVpcEndpointServiceConfiguration(&ec2.ModifyVpcEndpointServiceConfigurationInput{
+			ServiceId:          service.ServiceId,
+			AcceptanceRequired: aws.Bool(config.AcceptanceRequired),
+		}); err != nil {
+			return errors.Wrapf(err, "failed to modify endpoint service %q", aws.StringValue(service.ServiceId))
+		}
+	}
+
+	status.ServiceID = aws.StringValue(service.ServiceId)
+	status.ServiceName = aws.StringValue(service.ServiceName)
+
+	if err := s.reconcileEndpointServicePermissions(status.ServiceID, config.AllowedPrincipals); err != nil {
+		return err
+	}
+
+	klog.V(2).Infof("Reconciled API server PrivateLink endpoint service %q", status.ServiceName)
+	return nil
+}
+
+// deleteAPIServerPrivateLink deletes the PrivateLink endpoint service of the
+// API server, then its network load balancer and target group. The endpoint
+// service cannot be deleted while endpoints are connected to it.
+func (s *Service) deleteAPIServerPrivateLink() error {
+	klog.V(2).Info("Deleting API server PrivateLink endpoint service")
+
+	name := s.privateLinkName()
+	lb, err := s.describePrivateLinkLoadBalancer(name)
+	if err != nil {
+		return err
+	}
+
+	var serviceID, loadBalancerARN string
+	if status := s.scope.Network().APIServerPrivateLink; status != nil {
+		serviceID = status.ServiceID
+	}
+	if lb != nil {
+		loadBalancerARN = lb.ARN
+	}
+
+	service, err := s.describeEndpointService(serviceID, loadBalancerARN)
+	if err != nil {
+		return err
+	}
+	if service != nil {
+		out, err := s.scope.EC2.DeleteVpcEndpointServiceConfigurations(&ec2.DeleteVpcEndpointServiceConfigurationsInput{
+			ServiceIds: []*string{service.ServiceId},
+		})
+		if err != nil {
+			return errors.Wrapf(err, "failed to delete endpoint service %q", aws.StringValue(service.ServiceId))
+		}
+		for _, item := range out.Unsuccessful {
+			if item.Error != nil {
+				return errors.Errorf("failed to delete endpoint service %q: %s", aws.StringValue(service.ServiceId), aws.StringValue(item.Error.Message))
+			}
+		}
+		record.Eventf(s.scope.Cluster, "DeletedPrivateLinkService", "Deleted PrivateLink endpoint service %q of the API server", aws.StringValue(service.ServiceName))
+	}
+
+	if lb != nil {
+		if err := s.scope.NetworkLoadBalancers.DeleteLoadBalancer(lb.ARN); err != nil {
+			return err
+		}
+	}
+
+	targetGroupARN, err := s.scope.NetworkLoadBalancers.DescribeTargetGroup(name)
+	if err != nil {
+		return err
+	}
+	if targetGroupARN != "" {
+		// The target group is in use until the load balancer is deleted.
+		deleteTargetGroup := func() (bool, error) {
+			err := s.scope.NetworkLoadBalancers.DeleteTargetGroup(targetGroupARN)
+			if code, _ := awserrors.Code(errors.Cause(err)); code == elbv2.ErrCodeResourceInUse {
+				return false, nil
+			}
+			return err == nil, err
+		}
+		if err := wait.WaitForWithRetryable(wait.NewBackoff(), deleteTargetGroup, []string{}); err != nil {
+			return errors.Wrapf(err, "failed to wait for the deletion of target group %q", targetGroupARN)
+		}
+	}
+
+	s.scope.Network().APIServerPrivateLink = nil
+	klog.V(2).Info("Deleting API server PrivateLink endpoint service completed successfully")
+	return nil
+}
+
+// registerInstanceWithPrivateLink registers a control plane instance with the
+// target group of the PrivateLink load balancer, if the API server is
+// exposed with PrivateLink.
+func (s *Service) registerInstanceWithPrivateLink(instanceID string) error {
+	status := s.scope.Network().APIServerPrivateLink
+	if s.scope.ClusterConfig.APIServerPrivateLink == nil || status == nil || status.TargetGroupARN == "" {
+		return nil
+	}
+
+	return s.scope.NetworkLoadBalancers.RegisterTargets(status.TargetGroupARN, []string{instanceID})
+}
+
+// deregisterInstanceFromPrivateLink deregisters a control plane instance from
+// the target group of the PrivateLink load balancer, if any.
+func (s *Service) deregisterInstanceFromPrivateLink(instanceID string) error {
+	status := s.scope.Network().APIServerPrivateLink
+	if status == nil || status.TargetGroupARN == "" {
+		return nil
+	}
+
+	err := s.scope.NetworkLoadBalancers.DeregisterTargets(status.TargetGroupARN, []string{instanceID})
+	if code, _ := awserrors.Code(errors.Cause(err)); code == elbv2.ErrCodeTargetGroupNotFound {
+		return nil
+	}
+	return err
+}
+
+// privateLinkName returns the name of the network load balancer and target
+// group of the PrivateLink endpoint service of the API server.
+func (s *Service) privateLinkName() string {
+	return s.elbName(privateLinkRole)
+}
+
+// describePrivateLinkLoadBalancer returns the PrivateLink load balancer of
+// the cluster, or nil if it does not exist. Load balancer names are only
+// unique per account and region, so a load balancer of another cluster is a
+// conflict.
+func (s *Service) describePrivateLinkLoadBalancer(name string) (*elbv2.LoadBalancer, error) {
+	lb, err := s.scope.NetworkLoadBalancers.DescribeLoadBalancer(name)
+	if err != nil || lb == nil {
+		return nil, err
+	}
+
+	if _, ok := lb.Tags[tags.ClusterKey(s.scope.Name())]; !ok {
+		return nil, NewConflict(errors.Errorf("network load balancer %q is not owned by cluster %q", name, s.scope.Name()))
+	}
+
+	return lb, nil
+}
+
+// describeEndpointService returns the endpoint service with the given ID or,
+// as endpoint services cannot be tagged on creation, the one backed by the
+// given load balancer. It returns nil if neither exists.
+func (s *Service) describeEndpointService(serviceID, loadBalancerARN string) (*ec2.ServiceConfiguration, error) {
+	if serviceID != "" {
+		out, err := s.scope.EC2.DescribeVpcEndpointServiceConfigurations(&ec2.DescribeVpcEndpointServiceConfigurationsInput{
+			ServiceIds: aws.StringSlice([]string{serviceID}),
+		})
+		switch code, _ := awserrors.Code(err); {
+		case code == errCodeEndpointServiceNotFound:
+		case err != nil:
+			return nil, errors.Wrapf(err, "failed to describe endpoint service %q", serviceID)
+		case len(out.ServiceConfigurations) > 0:
+			return out.ServiceConfigurations[0], nil
+		}
+	}
+
+	if loadBalancerARN == "" {
+		return nil, nil
+	}
+
+	input := &ec2.DescribeVpcEndpointServiceConfigurationsInput{}
+	for {
+		out, err := s.scope.EC2.DescribeVpcEndpointServiceConfigurations(input)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to describe endpoint services")
+		}
+
+		for _, service := range out.ServiceConfigurations {
+			for _, arn := range service.NetworkLoadBalancerArns {
+				if aws.StringValue(arn) == loadBalancerARN {
+					return service, nil
+				}
+			}
+		}
+
+		if aws.StringValue(out.NextToken) == "" {
+			return nil, nil
+		}
+		input.NextToken = out.NextToken
+	}
+}
+
+// reconcileEndpointServicePermissions allows exactly the given principals to
+// create endpoints to an endpoint service.
+func (s *Service) reconcileEndpointServicePermissions(serviceID string, principals []string) error {
+	current := sets.NewString()
+	input := &ec2.DescribeVpcEndpointServicePermissionsInput{ServiceId: aws.String(serviceID)}
+	for {
+		out, err := s.scope.EC2.DescribeVpcEndpointServicePermissions(input)
+		if err != nil {
+			return errors.Wrapf(err, "failed to describe the permissions of endpoint service %q", serviceID)
+		}
+
+		for _, p := range out.AllowedPrincipals {
+			current.Insert(aws.StringValue(p.Principal))
+		}
+
+		if aws.StringValue(out.NextToken) == "" {
+			break
+		}
+		input.NextToken = out.NextToken
+	}
+
+	desired := sets.NewString(principals...)
+	add, remove := desired.Difference(current), current.Difference(desired)
+	if add.Len() == 0 && remove.Len() == 0 {
+		return nil
+	}
+
+	modify := &ec2.ModifyVpcEndpointServicePermissionsInput{ServiceId: aws.String(serviceID)}
+	if add.Len() > 0 {
+		modify.AddAllowedPrincipals = aws.StringSlice(add.List())
+	}
+	if remove.Len() > 0 {
+		modify.RemoveAllowedPrincipals = aws.StringSlice(remove.List())
+	}
+
+	if _, err := s.scope.EC2.ModifyVpcEndpointServicePermissions(modify); err != nil {
+		return errors.Wrapf(err, "failed to modify the permissions of endpoint service %q", serviceID)
+	}
+
+	klog.V(2).Infof("Allowed principals %v and removed principals %v on endpoint service %q", add.List(), remove.List(), serviceID)
+	return nil
+}
+
+func containsPort(ports []int64, port int64) bool {
+	for _, p := range ports {
+		if p == port {
+			return true
+		}
+	}
+	return false
+}
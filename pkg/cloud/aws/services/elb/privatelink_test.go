@@ -0,0 +1,291 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package elb
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/actuators"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/awserrors"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/elbv2"
+	clusterv1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+)
+
+// networkLoadBalancers is an account with the network load balancers and
+// target groups created by the tests.
+type networkLoadBalancers struct {
+	loadBalancers map[string]*elbv2.LoadBalancer
+	targetGroups  map[string]string
+	listeners     map[string][]int64
+	targets       map[string]sets.String
+}
+
+func (n *networkLoadBalancers) DescribeLoadBalancer(name string) (*elbv2.LoadBalancer, error) {
+	return n.loadBalancers[name], nil
+}
+
+func (n *networkLoadBalancers) CreateLoadBalancer(spec *elbv2.LoadBalancerSpec) (*elbv2.LoadBalancer, error) {
+	lb := &elbv2.LoadBalancer{ARN: "arn:lb/" + spec.Name, Name: spec.Name, State: "provisioning", Tags: spec.Tags}
+	n.loadBalancers[spec.Name] = lb
+	return lb, nil
+}
+
+func (n *networkLoadBalancers) DeleteLoadBalancer(arn string) error {
+	for name, lb := range n.loadBalancers {
+		if lb.ARN == arn {
+			delete(n.loadBalancers, name)
+			delete(n.listeners, arn)
+		}
+	}
+	return nil
+}
+
+func (n *networkLoadBalancers) DescribeTargetGroup(name string) (string, error) {
+	return n.targetGroups[name], nil
+}
+
+func (n *networkLoadBalancers) CreateTargetGroup(spec *elbv2.TargetGroupSpec) (string, error) {
+	n.targetGroups[spec.Name] = "arn:tg/" + spec.Name
+	return n.targetGroups[spec.Name], nil
+}
+
+func (n *networkLoadBalancers) DeleteTargetGroup(arn string) error {
+	for name, tg := range n.targetGroups {
+		if tg == arn {
+			delete(n.targetGroups, name)
+		}
+	}
+	return nil
+}
+
+func (n *networkLoadBalancers) DescribeListenerPorts(loadBalancerARN string) ([]int64, error) {
+	return n.listeners[loadBalancerARN], nil
+}
+
+func (n *networkLoadBalancers) CreateListener(loadBalancerARN, targetGroupARN string, port int64) error {
+	n.listeners[loadBalancerARN] = append(n.listeners[loadBalancerARN], port)
+	return nil
+}
+
+func (n *networkLoadBalancers) RegisterTargets(targetGroupARN string, instanceIDs []string) error {
+	if n.targets[targetGroupARN] == nil {
+		n.targets[targetGroupARN] = sets.NewString()
+	}
+	n.targets[targetGroupARN].Insert(instanceIDs...)
+	return nil
+}
+
+func (n *networkLoadBalancers) DeregisterTargets(targetGroupARN string, instanceIDs []string) error {
+	n.targets[targetGroupARN].Delete(instanceIDs...)
+	return nil
+}
+
+// endpointServices is an EC2 account with the endpoint services created by
+// the tests.
+type endpointServices struct {
+	ec2iface.EC2API
+
+	services      map[string]*ec2.ServiceConfiguration
+	principals    map[string]sets.String
+	modifications int
+}
+
+func (e *endpointServices) CreateVpcEndpointServiceConfiguration(input *ec2.CreateVpcEndpointServiceConfigurationInput) (*ec2.CreateVpcEndpointServiceConfigurationOutput, error) {
+	id := fmt.Sprintf("vpce-svc-%d", len(e.services)+1)
+	service := &ec2.ServiceConfiguration{
+		ServiceId:               aws.String(id),
+		ServiceName:             aws.String("com.amazonaws.vpce.us-east-1." + id),
+		AcceptanceRequired:      input.AcceptanceRequired,
+		NetworkLoadBalancerArns: input.NetworkLoadBalancerArns,
+	}
+	e.services[id] = service
+	e.principals[id] = sets.NewString()
+	return &ec2.CreateVpcEndpointServiceConfigurationOutput{ServiceConfiguration: service}, nil
+}
+
+func (e *endpointServices) DescribeVpcEndpointServiceConfigurations(input *ec2.DescribeVpcEndpointServiceConfigurationsInput) (*ec2.DescribeVpcEndpointServiceConfigurationsOutput, error) {
+	out := &ec2.DescribeVpcEndpointServiceConfigurationsOutput{}
+	for id, service := range e.services {
+		if len(input.ServiceIds) == 0 || aws.StringValue(input.ServiceIds[0]) == id {
+			out.ServiceConfigurations = append(out.ServiceConfigurations, service)
+		}
+	}
+	return out, nil
+}
+
+func (e *endpointServices) ModifyVpcEndpointServiceConfiguration(input *ec2.ModifyVpcEndpointServiceConfigurationInput) (*ec2.ModifyVpcEndpointServiceConfigurationOutput, error) {
+	e.services[aws.StringValue(input.ServiceId)].AcceptanceRequired = input.AcceptanceRequired
+	return &ec2.ModifyVpcEndpointServiceConfigurationOutput{}, nil
+}
+
+func (e *endpointServices) DeleteVpcEndpointServiceConfigurations(input *ec2.DeleteVpcEndpointServiceConfigurationsInput) (*ec2.DeleteVpcEndpointServiceConfigurationsOutput, error) {
+	for _, id := range input.ServiceIds {
+		delete(e.services, aws.StringValue(id))
+	}
+	return &ec2.DeleteVpcEndpointServiceConfigurationsOutput{}, nil
+}
+
+func (e *endpointServices) DescribeVpcEndpointServicePermissions(input *ec2.DescribeVpcEndpointServicePermissionsInput) (*ec2.DescribeVpcEndpointServicePermissionsOutput, error) {
+	out := &ec2.DescribeVpcEndpointServicePermissionsOutput{}
+	for _, p := range e.principals[aws.StringValue(input.ServiceId)].List() {
+		out.AllowedPrincipals = append(out.AllowedPrincipals, &ec2.AllowedPrincipal{Principal: aws.String(p)})
+	}
+	return out, nil
+}
+
+func (e *endpointServices) ModifyVpcEndpointServicePermissions(input *ec2.ModifyVpcEndpointServicePermissionsInput) (*ec2.ModifyVpcEndpointServicePermissionsOutput, error) {
+	principals := e.principals[aws.StringValue(input.ServiceId)]
+	principals.Insert(aws.StringValueSlice(input.AddAllowedPrincipals)...)
+	principals.Delete(aws.StringValueSlice(input.RemoveAllowedPrincipals)...)
+	e.modifications++
+	return &ec2.ModifyVpcEndpointServicePermissionsOutput{}, nil
+}
+
+func TestReconcileAPIServerPrivateLink(t *testing.T) {
+	nlbs := &networkLoadBalancers{
+		loadBalancers: map[string]*elbv2.LoadBalancer{},
+		targetGroups:  map[string]string{},
+		listeners:     map[string][]int64{},
+		targets:       map[string]sets.String{},
+	}
+	services := &endpointServices{services: map[string]*ec2.ServiceConfiguration{}, principals: map[string]sets.String{}}
+
+	scope, err := actuators.NewScope(actuators.ScopeParams{
+		Cluster:              &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"}},
+		AWSClients:           actuators.AWSClients{EC2: services},
+		NetworkLoadBalancers: nlbs,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create test context: %v", err)
+	}
+	scope.ClusterConfig.APIServerPrivateLink = &v1alpha1.PrivateLink{AllowedPrincipals: []string{"arn:aws:iam::111111111111:root"}}
+	scope.ClusterStatus.Network.VPC.ID = "vpc-1"
+	scope.ClusterStatus.Network.Subnets = v1alpha1.Subnets{
+		{ID: "subnet-public", IsPublic: true},
+		{ID: "subnet-private-a"},
+		{ID: "subnet-private-b"},
+	}
+
+	s := NewService(scope)
+
+	// The endpoint service waits for the load balancer to be provisioned,
+	// while the control plane instances can already be registered.
+	if err := s.ReconcileAPIServerPrivateLink(); !awserrors.IsFailedDependency(err) {
+		t.Fatalf("expected a failed dependency while the load balancer is provisioned, got %v", err)
+	}
+	status := scope.Network().APIServerPrivateLink
+	if status == nil || status.LoadBalancerARN != "arn:lb/test-cluster-apiserver-pl" || status.TargetGroupARN != "arn:tg/test-cluster-apiserver-pl" {
+		t.Fatalf("unexpected status %+v", status)
+	}
+	if err := s.registerInstanceWithPrivateLink("i-1"); err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+	if !nlbs.targets[status.TargetGroupARN].Has("i-1") {
+		t.Fatalf("expected instance i-1 to be registered, got %v", nlbs.targets)
+	}
+
+	// Once the load balancer is active, the endpoint service is created.
+	nlbs.loadBalancers["test-cluster-apiserver-pl"].State = elbv2.LoadBalancerStateActive
+	for i := 0; i < 2; i++ {
+		if err := s.ReconcileAPIServerPrivateLink(); err != nil {
+			t.Fatalf("did not expect error: %v", err)
+		}
+	}
+	if len(services.services) != 1 || services.modifications != 1 {
+		t.Fatalf("expected a single endpoint service modified once, got %v, %d modifications", services.services, services.modifications)
+	}
+	if status.ServiceID != "vpce-svc-1" || status.ServiceName != "com.amazonaws.vpce.us-east-1.vpce-svc-1" {
+		t.Fatalf("unexpected status %+v", status)
+	}
+	if ports := nlbs.listeners[status.LoadBalancerARN]; !reflect.DeepEqual(ports, []int64{6443}) {
+		t.Fatalf("expected a single listener on port 6443, got %v", ports)
+	}
+	if principals := services.principals["vpce-svc-1"].List(); !reflect.DeepEqual(principals, []string{"arn:aws:iam::111111111111:root"}) {
+		t.Fatalf("unexpected allowed principals %v", principals)
+	}
+
+	// The allowed principals and acceptance follow the spec.
+	scope.ClusterConfig.APIServerPrivateLink = &v1alpha1.PrivateLink{AllowedPrincipals: []string{"arn:aws:iam::222222222222:root"}, AcceptanceRequired: true}
+	if err := s.ReconcileAPIServerPrivateLink(); err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+	if principals := services.principals["vpce-svc-1"].List(); !reflect.DeepEqual(principals, []string{"arn:aws:iam::222222222222:root"}) {
+		t.Fatalf("unexpected allowed principals %v", principals)
+	}
+	if !aws.BoolValue(services.services["vpce-svc-1"].AcceptanceRequired) {
+		t.Fatalf("expected the endpoint service to require acceptance")
+	}
+
+	// The endpoint service is found by its load balancer if its ID is lost.
+	status.ServiceID = ""
+	if err := s.ReconcileAPIServerPrivateLink(); err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+	if len(services.services) != 1 || status.ServiceID != "vpce-svc-1" {
+		t.Fatalf("expected the endpoint service to be found again, got %v", services.services)
+	}
+
+	// Removing the option deletes the endpoint service and load balancer.
+	scope.ClusterConfig.APIServerPrivateLink = nil
+	if err := s.ReconcileAPIServerPrivateLink(); err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+	if len(services.services) != 0 || len(nlbs.loadBalancers) != 0 || len(nlbs.targetGroups) != 0 {
+		t.Fatalf("expected everything to be deleted, got %v, %v, %v", services.services, nlbs.loadBalancers, nlbs.targetGroups)
+	}
+	if scope.Network().APIServerPrivateLink != nil {
+		t.Fatalf("expected the status to be cleared, got %+v", scope.Network().APIServerPrivateLink)
+	}
+}
+
+func TestPrivateLinkLoadBalancerOfAnotherCluster(t *testing.T) {
+	nlbs := &networkLoadBalancers{
+		loadBalancers: map[string]*elbv2.LoadBalancer{
+			"test-cluster-apiserver-pl": {
+				ARN:  "arn:lb/other",
+				Name: "test-cluster-apiserver-pl",
+				Tags: map[string]string{"kubernetes.io/cluster/other-cluster": "owned"},
+			},
+		},
+		targetGroups: map[string]string{},
+	}
+
+	scope, err := actuators.NewScope(actuators.ScopeParams{
+		Cluster:              &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"}},
+		AWSClients:           actuators.AWSClients{EC2: &endpointServices{}},
+		NetworkLoadBalancers: nlbs,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create test context: %v", err)
+	}
+	scope.ClusterConfig.APIServerPrivateLink = &v1alpha1.PrivateLink{}
+
+	if err := NewService(scope).ReconcileAPIServerPrivateLink(); !IsConflict(err) {
+		t.Fatalf("expected a conflict, got %v", err)
+	}
+	if _, ok := nlbs.loadBalancers["test-cluster-apiserver-pl"]; !ok {
+		t.Fatalf("expected the load balancer of the other cluster to be left alone")
+	}
+}
@@ -0,0 +1,580 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package elbv2 manages network load balancers and their target groups.
+package elbv2
+
+import (
+	"sort"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/awserrors"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/queryprotocol"
+)
+
+const (
+	serviceName = "elasticloadbalancing"
+	apiVersion  = "2015-12-01"
+
+	// ErrCodeLoadBalancerNotFound is the error code of the requests for load
+	// balancers which do not exist.
+	ErrCodeLoadBalancerNotFound = "LoadBalancerNotFound"
+
+	// ErrCodeTargetGroupNotFound is the error code of the requests for target
+	// groups which do not exist.
+	ErrCodeTargetGroupNotFound = "TargetGroupNotFound"
+
+	// ErrCodeResourceInUse is the error code of the deletions of target
+	// groups still used by a load balancer.
+	ErrCodeResourceInUse = "ResourceInUse"
+
+	// LoadBalancerStateActive is the state of the load balancers routing
+	// traffic.
+	LoadBalancerStateActive = "active"
+)
+
+// LoadBalancer is a network load balancer.
+type LoadBalancer struct {
+	// ARN is the ARN of the load balancer.
+	ARN string
+
+	// Name is the name of the load balancer.
+	Name string
+
+	// DNSName is the DNS name of the load balancer.
+	DNSName string
+
+	// State is the state of the load balancer, e.g. "provisioning" or
+	// LoadBalancerStateActive.
+	State string
+
+	// Tags are the tags of the load balancer.
+	Tags map[string]string
+}
+
+// LoadBalancerSpec is the configuration of an internal network load balancer.
+type LoadBalancerSpec struct {
+	// Name is the name of the load balancer.
+	Name string
+
+	// Subnets are the IDs of the subnets of the load balancer, one per
+	// availability zone.
+	Subnets []string
+
+	// Tags are the tags of the load balancer.
+	Tags map[string]string
+}
+
+// TargetGroupSpec is the configuration of a group of instances targeted
+// over TCP.
+type TargetGroupSpec struct {
+	// Name is the name of the target group.
+	Name string
+
+	// VPCID is the ID of the VPC of the instances.
+	VPCID string
+
+	// Port is the port of the instances receiving the traffic.
+	Port int64
+
+	// Tags are the tags of the target group.
+	Tags map[string]string
+}
+
+// LoadBalancers manages network load balancers.
+type LoadBalancers interface {
+	// DescribeLoadBalancer returns the load balancer with the given name, or
+	// nil if it does not exist.
+	DescribeLoadBalancer(name string) (*LoadBalancer, error)
+
+	// CreateLoadBalancer creates an internal network load balancer.
+	CreateLoadBalancer(spec *LoadBalancerSpec) (*LoadBalancer, error)
+
+	// DeleteLoadBalancer deletes a load balancer along with its listeners.
+	DeleteLoadBalancer(arn string) error
+
+	// DescribeTargetGroup returns the ARN of the target group with the given
+	// name, or an empty string if it does not exist.
+	DescribeTargetGroup(name string) (string, error)
+
+	// CreateTargetGroup creates a target group, and returns its ARN.
+	CreateTargetGroup(spec *TargetGroupSpec) (string, error)
+
+	// DeleteTargetGroup deletes a target group. It fails with
+	// ErrCodeResourceInUse while a load balancer still uses the group.
+	DeleteTargetGroup(arn string) error
+
+	// DescribeListenerPorts returns the ports of the listeners of a load
+	// balancer.
+	DescribeListenerPorts(loadBalancerARN string) ([]int64, error)
+
+	// CreateListener forwards the TCP traffic of a load balancer on a port
+	// to a target group.
+	CreateListener(loadBalancerARN, targetGroupARN string, port int64) error
+
+	// RegisterTargets registers instances with a target group.
+	RegisterTargets(targetGroupARN string, instanceIDs []string) error
+
+	// DeregisterTargets deregisters instances from a target group.
+	DeregisterTargets(targetGroupARN string, instanceIDs []string) error
+}
+
+// Service manages the network load balancers of a region.
+//
+// The vendored SDK has no client for version 2 of the Elastic Load Balancing
+// API, so requests are sent with a generic SDK client speaking the query
+// protocol. Requests still go through the handlers of the session, for
+// signing, retries and rate limiting.
+type Service struct {
+	client *client.Client
+}
+
+// NewService returns a service managing the network load balancers of the
+// region of the session.
+func NewService(sess *session.Session) *Service {
+	return &Service{client: queryprotocol.NewClient(sess, serviceName, apiVersion)}
+}
+
+func (s *Service) send(operation string, in, out interface{}) error {
+	return queryprotocol.Send(s.client, operation, in, out)
+}
+
+type tag struct {
+	_ struct{} `type:"structure"`
+
+	Key   *string `type:"string"`
+	Value *string `type:"string"`
+}
+
+type loadBalancer struct {
+	_ struct{} `type:"structure"`
+
+	DNSName          *string            `type:"string"`
+	LoadBalancerArn  *string            `type:"string"`
+	LoadBalancerName *string            `type:"string"`
+	State            *loadBalancerState `type:"structure"`
+}
+
+type loadBalancerState struct {
+	_ struct{} `type:"structure"`
+
+	Code *string `type:"string"`
+}
+
+type describeLoadBalancersInput struct {
+	_ struct{} `type:"structure"`
+
+	Names []*string `type:"list"`
+}
+
+type describeLoadBalancersOutput struct {
+	_ struct{} `type:"structure"`
+
+	LoadBalancers []*loadBalancer `type:"list"`
+}
+
+type describeTagsInput struct {
+	_ struct{} `type:"structure"`
+
+	ResourceArns []*string `type:"list"`
+}
+
+type describeTagsOutput struct {
+	_ struct{} `type:"structure"`
+
+	TagDescriptions []*tagDescription `type:"list"`
+}
+
+type tagDescription struct {
+	_ struct{} `type:"structure"`
+
+	ResourceArn *string `type:"string"`
+	Tags        []*tag  `type:"list"`
+}
+
+type createLoadBalancerInput struct {
+	_ struct{} `type:"structure"`
+
+	Name    *string   `type:"string"`
+	Scheme  *string   `type:"string"`
+	Subnets []*string `type:"list"`
+	Tags    []*tag    `type:"list"`
+	Type    *string   `type:"string"`
+}
+
+type createLoadBalancerOutput struct {
+	_ struct{} `type:"structure"`
+
+	LoadBalancers []*loadBalancer `type:"list"`
+}
+
+type deleteLoadBalancerInput struct {
+	_ struct{} `type:"structure"`
+
+	LoadBalancerArn *string `type:"string"`
+}
+
+type deleteLoadBalancerOutput struct {
+	_ struct{} `type:"structure"`
+}
+
+type targetGroup struct {
+	_ struct{} `type:"structure"`
+
+	TargetGroupArn *string `type:"string"`
+}
+
+type describeTargetGroupsInput struct {
+	_ struct{} `type:"structure"`
+
+	Names []*string `type:"list"`
+}
+
+type describeTargetGroupsOutput struct {
+	_ struct{} `type:"structure"`
+
+	TargetGroups []*targetGroup `type:"list"`
+}
+
+type createTargetGroupInput struct {
+	_ struct{} `type:"structure"`
+
+	HealthCheckProtocol *string `type:"string"`
+	Name                *string `type:"string"`
+	Port                *int64  `type:"integer"`
+	Protocol            *string `type:"string"`
+	TargetType          *string `type:"string"`
+	VpcId               *string `type:"string"`
+}
+
+type createTargetGroupOutput struct {
+	_ struct{} `type:"structure"`
+
+	TargetGroups []*targetGroup `type:"list"`
+}
+
+type addTagsInput struct {
+	_ struct{} `type:"structure"`
+
+	ResourceArns []*string `type:"list"`
+	Tags         []*tag    `type:"list"`
+}
+
+type addTagsOutput struct {
+	_ struct{} `type:"structure"`
+}
+
+type deleteTargetGroupInput struct {
+	_ struct{} `type:"structure"`
+
+	TargetGroupArn *string `type:"string"`
+}
+
+type deleteTargetGroupOutput struct {
+	_ struct{} `type:"structure"`
+}
+
+type listener struct {
+	_ struct{} `type:"structure"`
+
+	Port *int64 `type:"integer"`
+}
+
+type describeListenersInput struct {
+	_ struct{} `type:"structure"`
+
+	LoadBalancerArn *string `type:"string"`
+	Marker          *string `type:"string"`
+}
+
+type describeListenersOutput struct {
+	_ struct{} `type:"structure"`
+
+	Listeners  []*listener `type:"list"`
+	NextMarker *string     `type:"string"`
+}
+
+type action struct {
+	_ struct{} `type:"structure"`
+
+	TargetGroupArn *string `type:"string"`
+	Type           *string `type:"string"`
+}
+
+type createListenerInput struct {
+	_ struct{} `type:"structure"`
+
+	DefaultActions  []*action `type:"list"`
+	LoadBalancerArn *string   `type:"string"`
+	Port            *int64    `type:"integer"`
+	Protocol        *string   `type:"string"`
+}
+
+type createListenerOutput struct {
+	_ struct{} `type:"structure"`
+}
+
+type targetDescription struct {
+	_ struct{} `type:"structure"`
+
+	Id *string `type:"string"`
+}
+
+type registerTargetsInput struct {
+	_ struct{} `type:"structure"`
+
+	TargetGroupArn *string              `type:"string"`
+	Targets        []*targetDescription `type:"list"`
+}
+
+type registerTargetsOutput struct {
+	_ struct{} `type:"structure"`
+}
+
+type deregisterTargetsInput struct {
+	_ struct{} `type:"structure"`
+
+	TargetGroupArn *string              `type:"string"`
+	Targets        []*targetDescription `type:"list"`
+}
+
+type deregisterTargetsOutput struct {
+	_ struct{} `type:"structure"`
+}
+
+// DescribeLoadBalancer implements LoadBalancers.
+func (s *Service) DescribeLoadBalancer(name string) (*LoadBalancer, error) {
+	out := &describeLoadBalancersOutput{}
+	err := s.send("DescribeLoadBalancers", &describeLoadBalancersInput{Names: aws.StringSlice([]string{name})}, out)
+	if code, _ := awserrors.Code(err); code == ErrCodeLoadBalancerNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to describe load balancer %q", name)
+	}
+
+	if len(out.LoadBalancers) == 0 {
+		return nil, nil
+	}
+
+	lb := fromLoadBalancer(out.LoadBalancers[0])
+
+	tags := &describeTagsOutput{}
+	if err := s.send("DescribeTags", &describeTagsInput{ResourceArns: aws.StringSlice([]string{lb.ARN})}, tags); err != nil {
+		return nil, errors.Wrapf(err, "failed to describe tags of load balancer %q", name)
+	}
+
+	lb.Tags = map[string]string{}
+	for _, desc := range tags.TagDescriptions {
+		for _, t := range desc.Tags {
+			lb.Tags[aws.StringValue(t.Key)] = aws.StringValue(t.Value)
+		}
+	}
+
+	return lb, nil
+}
+
+// CreateLoadBalancer implements LoadBalancers.
+func (s *Service) CreateLoadBalancer(spec *LoadBalancerSpec) (*LoadBalancer, error) {
+	input := &createLoadBalancerInput{
+		Name:    aws.String(spec.Name),
+		Scheme:  aws.String("internal"),
+		Subnets: aws.StringSlice(spec.Subnets),
+		Tags:    toTags(spec.Tags),
+		Type:    aws.String("network"),
+	}
+
+	out := &createLoadBalancerOutput{}
+	if err := s.send("CreateLoadBalancer", input, out); err != nil {
+		return nil, errors.Wrapf(err, "failed to create load balancer %q", spec.Name)
+	}
+
+	if len(out.LoadBalancers) == 0 {
+		return nil, errors.Errorf("no load balancer returned for the creation of %q", spec.Name)
+	}
+
+	lb := fromLoadBalancer(out.LoadBalancers[0])
+	lb.Tags = spec.Tags
+	return lb, nil
+}
+
+// DeleteLoadBalancer implements LoadBalancers.
+func (s *Service) DeleteLoadBalancer(arn string) error {
+	if err := s.send("DeleteLoadBalancer", &deleteLoadBalancerInput{LoadBalancerArn: aws.String(arn)}, &deleteLoadBalancerOutput{}); err != nil {
+		return errors.Wrapf(err, "failed to delete load balancer %q", arn)
+	}
+
+	return nil
+}
+
+// DescribeTargetGroup implements LoadBalancers.
+func (s *Service) DescribeTargetGroup(name string) (string, error) {
+	out := &describeTargetGroupsOutput{}
+	err := s.send("DescribeTargetGroups", &describeTargetGroupsInput{Names: aws.StringSlice([]string{name})}, out)
+	if code, _ := awserrors.Code(err); code == ErrCodeTargetGroupNotFound {
+		return "", nil
+	}
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to describe target group %q", name)
+	}
+
+	if len(out.TargetGroups) == 0 {
+		return "", nil
+	}
+
+	return aws.StringValue(out.TargetGroups[0].TargetGroupArn), nil
+}
+
+// CreateTargetGroup implements LoadBalancers. The instances are checked
+// with TCP connections to the port.
+func (s *Service) CreateTargetGroup(spec *TargetGroupSpec) (string, error) {
+	input := &createTargetGroupInput{
+		HealthCheckProtocol: aws.String("TCP"),
+		Name:                aws.String(spec.Name),
+		Port:                aws.Int64(spec.Port),
+		Protocol:            aws.String("TCP"),
+		TargetType:          aws.String("instance"),
+		VpcId:               aws.String(spec.VPCID),
+	}
+
+	out := &createTargetGroupOutput{}
+	if err := s.send("CreateTargetGroup", input, out); err != nil {
+		return "", errors.Wrapf(err, "failed to create target group %q", spec.Name)
+	}
+
+	if len(out.TargetGroups) == 0 {
+		return "", errors.Errorf("no target group returned for the creation of %q", spec.Name)
+	}
+
+	arn := aws.StringValue(out.TargetGroups[0].TargetGroupArn)
+
+	// Target groups are only tagged once created.
+	if len(spec.Tags) > 0 {
+		tags := &addTagsInput{ResourceArns: aws.StringSlice([]string{arn}), Tags: toTags(spec.Tags)}
+		if err := s.send("AddTags", tags, &addTagsOutput{}); err != nil {
+			return "", errors.Wrapf(err, "failed to tag target group %q", spec.Name)
+		}
+	}
+
+	return arn, nil
+}
+
+// DeleteTargetGroup implements LoadBalancers.
+func (s *Service) DeleteTargetGroup(arn string) error {
+	if err := s.send("DeleteTargetGroup", &deleteTargetGroupInput{TargetGroupArn: aws.String(arn)}, &deleteTargetGroupOutput{}); err != nil {
+		return errors.Wrapf(err, "failed to delete target group %q", arn)
+	}
+
+	return nil
+}
+
+// DescribeListenerPorts implements LoadBalancers.
+func (s *Service) DescribeListenerPorts(loadBalancerARN string) ([]int64, error) {
+	input := &describeListenersInput{LoadBalancerArn: aws.String(loadBalancerARN)}
+
+	var ports []int64
+	for {
+		out := &describeListenersOutput{}
+		if err := s.send("DescribeListeners", input, out); err != nil {
+			return nil, errors.Wrapf(err, "failed to describe the listeners of load balancer %q", loadBalancerARN)
+		}
+
+		for _, l := range out.Listeners {
+			ports = append(ports, aws.Int64Value(l.Port))
+		}
+
+		if aws.StringValue(out.NextMarker) == "" {
+			return ports, nil
+		}
+		input.Marker = out.NextMarker
+	}
+}
+
+// CreateListener implements LoadBalancers.
+func (s *Service) CreateListener(loadBalancerARN, targetGroupARN string, port int64) error {
+	input := &createListenerInput{
+		DefaultActions:  []*action{{TargetGroupArn: aws.String(targetGroupARN), Type: aws.String("forward")}},
+		LoadBalancerArn: aws.String(loadBalancerARN),
+		Port:            aws.Int64(port),
+		Protocol:        aws.String("TCP"),
+	}
+
+	if err := s.send("CreateListener", input, &createListenerOutput{}); err != nil {
+		return errors.Wrapf(err, "failed to create listener on port %d of load balancer %q", port, loadBalancerARN)
+	}
+
+	return nil
+}
+
+// RegisterTargets implements LoadBalancers.
+func (s *Service) RegisterTargets(targetGroupARN string, instanceIDs []string) error {
+	input := &registerTargetsInput{TargetGroupArn: aws.String(targetGroupARN), Targets: toTargets(instanceIDs)}
+
+	if err := s.send("RegisterTargets", input, &registerTargetsOutput{}); err != nil {
+		return errors.Wrapf(err, "failed to register instances %v with target group %q", instanceIDs, targetGroupARN)
+	}
+
+	return nil
+}
+
+// DeregisterTargets implements LoadBalancers.
+func (s *Service) DeregisterTargets(targetGroupARN string, instanceIDs []string) error {
+	input := &deregisterTargetsInput{TargetGroupArn: aws.String(targetGroupARN), Targets: toTargets(instanceIDs)}
+
+	if err := s.send("DeregisterTargets", input, &deregisterTargetsOutput{}); err != nil {
+		return errors.Wrapf(err, "failed to deregister instances %v from target group %q", instanceIDs, targetGroupARN)
+	}
+
+	return nil
+}
+
+func fromLoadBalancer(lb *loadBalancer) *LoadBalancer {
+	res := &LoadBalancer{
+		ARN:     aws.StringValue(lb.LoadBalancerArn),
+		Name:    aws.StringValue(lb.LoadBalancerName),
+		DNSName: aws.StringValue(lb.DNSName),
+	}
+	if lb.State != nil {
+		res.State = aws.StringValue(lb.State.Code)
+	}
+	return res
+}
+
+// toTags returns tags sorted by key, so that requests are reproducible.
+func toTags(tags map[string]string) []*tag {
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	res := make([]*tag, 0, len(keys))
+	for _, k := range keys {
+		res = append(res, &tag{Key: aws.String(k), Value: aws.String(tags[k])})
+	}
+	return res
+}
+
+func toTargets(instanceIDs []string) []*targetDescription {
+	res := make([]*targetDescription, 0, len(instanceIDs))
+	for _, id := range instanceIDs {
+		res = append(res, &targetDescription{Id: aws.String(id)})
+	}
+	return res
+}
@@ -0,0 +1,246 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package elbv2
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/pkg/errors"
+)
+
+// newTestService returns a service sending its requests to the given
+// handler, and a function stopping the test server.
+func newTestService(t *testing.T, handler http.HandlerFunc) (*Service, func()) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256") {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		if err := r.ParseForm(); err != nil || r.Form.Get("Version") != apiVersion {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		handler(w, r)
+	}))
+
+	sess, err := session.NewSession(aws.NewConfig().
+		WithRegion("us-east-1").
+		WithEndpoint(server.URL).
+		WithMaxRetries(0).
+		WithCredentials(credentials.NewStaticCredentials("id", "secret", "")))
+	if err != nil {
+		server.Close()
+		t.Fatalf("failed to create session: %v", err)
+	}
+
+	return NewService(sess), server.Close
+}
+
+func notFound(w http.ResponseWriter, code string) {
+	w.WriteHeader(http.StatusBadRequest)
+	w.Write([]byte(`<ErrorResponse><Error><Type>Sender</Type><Code>` + code + `</Code><Message>not found</Message></Error><RequestId>1</RequestId></ErrorResponse>`))
+}
+
+func TestDescribeLoadBalancer(t *testing.T) {
+	s, closeServer := newTestService(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Form.Get("Action") {
+		case "DescribeLoadBalancers":
+			if r.Form.Get("Names.member.1") != "test-apiserver" {
+				notFound(w, ErrCodeLoadBalancerNotFound)
+				return
+			}
+			w.Write([]byte(`<DescribeLoadBalancersResponse><DescribeLoadBalancersResult><LoadBalancers><member>
+<LoadBalancerArn>arn:nlb</LoadBalancerArn><LoadBalancerName>test-apiserver</LoadBalancerName><DNSName>test.elb.amazonaws.com</DNSName>
+<State><Code>active</Code></State>
+</member></LoadBalancers></DescribeLoadBalancersResult><ResponseMetadata><RequestId>1</RequestId></ResponseMetadata></DescribeLoadBalancersResponse>`))
+		case "DescribeTags":
+			if r.Form.Get("ResourceArns.member.1") != "arn:nlb" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.Write([]byte(`<DescribeTagsResponse><DescribeTagsResult><TagDescriptions><member><ResourceArn>arn:nlb</ResourceArn>
+<Tags><member><Key>owner</Key><Value>test</Value></member></Tags>
+</member></TagDescriptions></DescribeTagsResult><ResponseMetadata><RequestId>2</RequestId></ResponseMetadata></DescribeTagsResponse>`))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	})
+	defer closeServer()
+
+	lb, err := s.DescribeLoadBalancer("test-apiserver")
+	if err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+
+	expected := &LoadBalancer{
+		ARN:     "arn:nlb",
+		Name:    "test-apiserver",
+		DNSName: "test.elb.amazonaws.com",
+		State:   LoadBalancerStateActive,
+		Tags:    map[string]string{"owner": "test"},
+	}
+	if !reflect.DeepEqual(lb, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, lb)
+	}
+
+	lb, err = s.DescribeLoadBalancer("other")
+	if err != nil || lb != nil {
+		t.Fatalf("expected no load balancer, got %+v, %v", lb, err)
+	}
+}
+
+func TestCreateLoadBalancer(t *testing.T) {
+	s, closeServer := newTestService(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Form.Get("Action") != "CreateLoadBalancer" ||
+			r.Form.Get("Name") != "test-apiserver" ||
+			r.Form.Get("Type") != "network" ||
+			r.Form.Get("Scheme") != "internal" ||
+			r.Form.Get("Subnets.member.2") != "subnet-2" ||
+			r.Form.Get("Tags.member.1.Key") != "a" ||
+			r.Form.Get("Tags.member.2.Value") != "2" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`<CreateLoadBalancerResponse><CreateLoadBalancerResult><LoadBalancers><member>
+<LoadBalancerArn>arn:nlb</LoadBalancerArn><LoadBalancerName>test-apiserver</LoadBalancerName><DNSName>test.elb.amazonaws.com</DNSName>
+<State><Code>provisioning</Code></State>
+</member></LoadBalancers></CreateLoadBalancerResult><ResponseMetadata><RequestId>1</RequestId></ResponseMetadata></CreateLoadBalancerResponse>`))
+	})
+	defer closeServer()
+
+	lb, err := s.CreateLoadBalancer(&LoadBalancerSpec{
+		Name:    "test-apiserver",
+		Subnets: []string{"subnet-1", "subnet-2"},
+		Tags:    map[string]string{"b": "2", "a": "1"},
+	})
+	if err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+	if lb.ARN != "arn:nlb" || lb.State != "provisioning" {
+		t.Fatalf("unexpected load balancer %+v", lb)
+	}
+}
+
+func TestCreateTargetGroup(t *testing.T) {
+	var tagged bool
+	s, closeServer := newTestService(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Form.Get("Action") {
+		case "CreateTargetGroup":
+			if r.Form.Get("Name") != "test-apiserver" || r.Form.Get("Port") != "6443" || r.Form.Get("VpcId") != "vpc-1" || r.Form.Get("TargetType") != "instance" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.Write([]byte(`<CreateTargetGroupResponse><CreateTargetGroupResult><TargetGroups><member>
+<TargetGroupArn>arn:tg</TargetGroupArn>
+</member></TargetGroups></CreateTargetGroupResult><ResponseMetadata><RequestId>1</RequestId></ResponseMetadata></CreateTargetGroupResponse>`))
+		case "AddTags":
+			tagged = r.Form.Get("ResourceArns.member.1") == "arn:tg" && r.Form.Get("Tags.member.1.Key") == "owner"
+			w.Write([]byte(`<AddTagsResponse><AddTagsResult/><ResponseMetadata><RequestId>2</RequestId></ResponseMetadata></AddTagsResponse>`))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	})
+	defer closeServer()
+
+	arn, err := s.CreateTargetGroup(&TargetGroupSpec{Name: "test-apiserver", VPCID: "vpc-1", Port: 6443, Tags: map[string]string{"owner": "test"}})
+	if err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+	if arn != "arn:tg" || !tagged {
+		t.Fatalf("expected tagged target group arn:tg, got %q, tagged %v", arn, tagged)
+	}
+}
+
+func TestListeners(t *testing.T) {
+	var created bool
+	s, closeServer := newTestService(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Form.Get("Action") {
+		case "DescribeListeners":
+			if r.Form.Get("Marker") == "" {
+				w.Write([]byte(`<DescribeListenersResponse><DescribeListenersResult><Listeners><member><Port>443</Port></member></Listeners>
+<NextMarker>next</NextMarker></DescribeListenersResult><ResponseMetadata><RequestId>1</RequestId></ResponseMetadata></DescribeListenersResponse>`))
+				return
+			}
+			w.Write([]byte(`<DescribeListenersResponse><DescribeListenersResult><Listeners><member><Port>6443</Port></member></Listeners>
+</DescribeListenersResult><ResponseMetadata><RequestId>2</RequestId></ResponseMetadata></DescribeListenersResponse>`))
+		case "CreateListener":
+			created = r.Form.Get("LoadBalancerArn") == "arn:nlb" &&
+				r.Form.Get("Port") == "6443" &&
+				r.Form.Get("Protocol") == "TCP" &&
+				r.Form.Get("DefaultActions.member.1.Type") == "forward" &&
+				r.Form.Get("DefaultActions.member.1.TargetGroupArn") == "arn:tg"
+			w.Write([]byte(`<CreateListenerResponse><CreateListenerResult/><ResponseMetadata><RequestId>3</RequestId></ResponseMetadata></CreateListenerResponse>`))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	})
+	defer closeServer()
+
+	ports, err := s.DescribeListenerPorts("arn:nlb")
+	if err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+	if !reflect.DeepEqual(ports, []int64{443, 6443}) {
+		t.Fatalf("expected ports [443 6443], got %v", ports)
+	}
+
+	if err := s.CreateListener("arn:nlb", "arn:tg", 6443); err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+	if !created {
+		t.Fatalf("expected the listener to forward port 6443 to the target group")
+	}
+}
+
+func TestTargets(t *testing.T) {
+	var requests []string
+	s, closeServer := newTestService(t, func(w http.ResponseWriter, r *http.Request) {
+		action := r.Form.Get("Action")
+		if r.Form.Get("TargetGroupArn") != "arn:tg" {
+			notFound(w, ErrCodeTargetGroupNotFound)
+			return
+		}
+		requests = append(requests, action+"="+r.Form.Get("Targets.member.1.Id"))
+		w.Write([]byte(`<` + action + `Response><` + action + `Result/><ResponseMetadata><RequestId>1</RequestId></ResponseMetadata></` + action + `Response>`))
+	})
+	defer closeServer()
+
+	if err := s.RegisterTargets("arn:tg", []string{"i-1"}); err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+	if err := s.DeregisterTargets("arn:tg", []string{"i-1"}); err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+	if !reflect.DeepEqual(requests, []string{"RegisterTargets=i-1", "DeregisterTargets=i-1"}) {
+		t.Fatalf("unexpected requests %v", requests)
+	}
+
+	err := s.RegisterTargets("arn:other", []string{"i-1"})
+	if aerr, ok := errors.Cause(err).(awserr.Error); !ok || aerr.Code() != ErrCodeTargetGroupNotFound {
+		t.Fatalf("expected a target group not found error, got %v", err)
+	}
+}
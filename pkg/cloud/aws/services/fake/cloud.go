@@ -0,0 +1,154 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package fake provides an in-memory implementation of the EC2 and ELB
+// services, to test automation built on top of the provider without AWS. A
+// Cloud is plugged into the actuators as the Getter of their params.
+package fake
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/actuators"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services"
+)
+
+var _ services.Getter = &Cloud{}
+
+// Cloud is an in-memory AWS account running a single cluster. Its EC2 and
+// ELB services keep the state of the cluster resources:
+//
+// - instances are pending when launched, and running once observed again;
+// - terminated instances are shutting down, then terminated once observed again;
+// - the API server load balancer tracks the instances registered with it.
+//
+// It is safe for concurrent use.
+type Cloud struct {
+	mu sync.Mutex
+
+	clusterName  string
+	imageCreated time.Time
+	lastID       int
+
+	network         bool
+	bastionID       string
+	instances       map[string]*v1alpha1.Instance
+	loadBalancerDNS string
+	registeredOnELB map[string]bool
+	launchTemplates map[string]int64
+}
+
+// NewCloud returns an empty account in which the cluster of the given name runs.
+func NewCloud(clusterName string) *Cloud {
+	return &Cloud{
+		clusterName:     clusterName,
+		imageCreated:    time.Now(),
+		instances:       map[string]*v1alpha1.Instance{},
+		registeredOnELB: map[string]bool{},
+		launchTemplates: map[string]int64{},
+	}
+}
+
+// EC2 returns the EC2 service of the account, whatever the scope.
+func (c *Cloud) EC2(scope *actuators.Scope) services.EC2Interface {
+	return &EC2{cloud: c}
+}
+
+// ELB returns the ELB service of the account, whatever the scope.
+func (c *Cloud) ELB(scope *actuators.Scope) services.ELBInterface {
+	return &ELB{cloud: c}
+}
+
+// NetworkReady returns true if the network of the cluster is reconciled.
+func (c *Cloud) NetworkReady() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.network
+}
+
+// Instances returns copies of the instances of the account, sorted by ID,
+// without observing them.
+func (c *Cloud) Instances() []*v1alpha1.Instance {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	instances := make([]*v1alpha1.Instance, 0, len(c.instances))
+	for _, i := range c.instances {
+		instances = append(instances, i.DeepCopy())
+	}
+
+	sort.Slice(instances, func(a, b int) bool { return instances[a].ID < instances[b].ID })
+	return instances
+}
+
+// RegisteredInstances returns the IDs of the instances registered with the
+// API server load balancer, sorted.
+func (c *Cloud) RegisteredInstances() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ids := make([]string, 0, len(c.registeredOnELB))
+	for id := range c.registeredOnELB {
+		ids = append(ids, id)
+	}
+
+	sort.Strings(ids)
+	return ids
+}
+
+// newInstanceID returns the ID of a new instance. It must be called with the lock held.
+func (c *Cloud) newInstanceID() string {
+	c.lastID++
+	return fmt.Sprintf("i-%017x", c.lastID)
+}
+
+// newLaunchTemplateID returns the ID of a new launch template. It must be
+// called with the lock held.
+func (c *Cloud) newLaunchTemplateID() string {
+	c.lastID++
+	return fmt.Sprintf("lt-%017x", c.lastID)
+}
+
+// observe moves an instance to the next state of its lifecycle, as the
+// transitions of EC2 are only seen by later requests. It must be called
+// with the lock held.
+func (c *Cloud) observe(i *v1alpha1.Instance) {
+	switch i.State {
+	case v1alpha1.InstanceStatePending:
+		i.State = v1alpha1.InstanceStateRunning
+	case v1alpha1.InstanceStateStopping:
+		i.State = v1alpha1.InstanceStateStopped
+	case v1alpha1.InstanceStateShuttingDown:
+		i.State = v1alpha1.InstanceStateTerminated
+		// Terminated instances are deregistered from load balancers by AWS.
+		delete(c.registeredOnELB, i.ID)
+	}
+}
+
+// live returns the instance of the given ID, unless it is missing or
+// terminated. It must be called with the lock held.
+func (c *Cloud) live(id string) (*v1alpha1.Instance, bool) {
+	i, ok := c.instances[id]
+	if !ok || i.State == v1alpha1.InstanceStateTerminated {
+		return nil, false
+	}
+	return i, true
+}
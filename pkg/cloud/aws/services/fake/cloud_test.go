@@ -0,0 +1,139 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fake
+
+import (
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/actuators"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/awserrors"
+	clusterv1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+)
+
+// servicesOf returns the EC2 and ELB services of the cloud for the scope of
+// a cluster, obtained the way the actuators do.
+func servicesOf(t *testing.T, getter services.Getter) (services.EC2Interface, services.ELBInterface) {
+	scope, err := actuators.NewScope(actuators.ScopeParams{
+		Cluster: &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"}},
+	})
+	if err != nil {
+		t.Fatalf("failed to create scope: %v", err)
+	}
+	return getter.EC2(scope), getter.ELB(scope)
+}
+
+func TestCloud(t *testing.T) {
+	cloud := NewCloud("test-cluster")
+	ec2svc, elbsvc := servicesOf(t, cloud)
+
+	machine := &actuators.MachineScope{
+		Machine: &clusterv1.Machine{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-machine", Labels: map[string]string{"set": "controlplane"}},
+		},
+		MachineConfig: &v1alpha1.AWSMachineProviderSpec{InstanceType: "m5.large"},
+		MachineStatus: &v1alpha1.AWSMachineProviderStatus{},
+	}
+
+	if _, err := ec2svc.CreateOrGetMachine(machine, "", ""); !awserrors.IsFailedDependency(err) {
+		t.Fatalf("expected machine creation to wait for the network, got: %v", err)
+	}
+
+	if err := ec2svc.ReconcileNetwork(); err != nil {
+		t.Fatalf("failed to reconcile network: %v", err)
+	}
+	if err := elbsvc.ReconcileLoadbalancers(); err != nil {
+		t.Fatalf("failed to reconcile load balancers: %v", err)
+	}
+
+	instance, err := ec2svc.CreateOrGetMachine(machine, "", "")
+	if err != nil {
+		t.Fatalf("failed to create machine: %v", err)
+	}
+	if instance.State != v1alpha1.InstanceStatePending {
+		t.Fatalf("expected new instance to be pending, got %q", instance.State)
+	}
+	if instance.Tags["Name"] != "test-machine" || instance.Tags["kubernetes.io/cluster/test-cluster"] != "owned" {
+		t.Fatalf("expected instance to be tagged for the machine and cluster, got %v", instance.Tags)
+	}
+
+	// The same instance is returned until the machine records it.
+	again, err := ec2svc.CreateOrGetMachine(machine, "", "")
+	if err != nil {
+		t.Fatalf("failed to get machine: %v", err)
+	}
+	if again.ID != instance.ID {
+		t.Fatalf("expected instance %q, got %q", instance.ID, again.ID)
+	}
+
+	running, err := ec2svc.InstanceIfExists(instance.ID)
+	if err != nil {
+		t.Fatalf("failed to get instance: %v", err)
+	}
+	if running == nil || running.State != v1alpha1.InstanceStateRunning {
+		t.Fatalf("expected instance to be running, got %+v", running)
+	}
+
+	if err := elbsvc.RegisterInstanceWithAPIServerELB(instance.ID); err != nil {
+		t.Fatalf("failed to register instance: %v", err)
+	}
+	if registered := cloud.RegisteredInstances(); !reflect.DeepEqual(registered, []string{instance.ID}) {
+		t.Fatalf("expected instance to be registered, got %v", registered)
+	}
+
+	if err := ec2svc.TerminateInstance(instance.ID); err != nil {
+		t.Fatalf("failed to terminate instance: %v", err)
+	}
+	if err := ec2svc.DeleteNetwork(); err != nil {
+		t.Fatalf("failed to delete network: %v", err)
+	}
+
+	if gone, _ := ec2svc.InstanceIfExists(instance.ID); gone != nil {
+		t.Fatalf("expected terminated instance to be gone, got %+v", gone)
+	}
+	if registered := cloud.RegisteredInstances(); len(registered) != 0 {
+		t.Fatalf("expected terminated instance to be deregistered, got %v", registered)
+	}
+	if cloud.NetworkReady() {
+		t.Fatalf("expected network to be deleted")
+	}
+}
+
+func TestDeleteNetworkWithRunningInstances(t *testing.T) {
+	ec2svc, _ := servicesOf(t, NewCloud("test-cluster"))
+
+	if err := ec2svc.ReconcileNetwork(); err != nil {
+		t.Fatalf("failed to reconcile network: %v", err)
+	}
+	if err := ec2svc.ReconcileBastion(); err != nil {
+		t.Fatalf("failed to reconcile bastion: %v", err)
+	}
+
+	if err := ec2svc.DeleteNetwork(); err == nil {
+		t.Fatalf("expected network deletion to fail while the bastion runs")
+	}
+
+	if err := ec2svc.DeleteBastion(); err != nil {
+		t.Fatalf("failed to delete bastion: %v", err)
+	}
+	if err := ec2svc.DeleteNetwork(); err != nil {
+		t.Fatalf("failed to delete network: %v", err)
+	}
+}
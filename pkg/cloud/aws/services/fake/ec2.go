@@ -0,0 +1,553 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fake
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/actuators"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/awserrors"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/tags"
+)
+
+var _ services.EC2Interface = &EC2{}
+
+// EC2 is the in-memory EC2 service of a Cloud.
+type EC2 struct {
+	cloud *Cloud
+}
+
+// ReconcileNetwork provisions the network of the cluster.
+func (e *EC2) ReconcileNetwork() error {
+	e.cloud.mu.Lock()
+	defer e.cloud.mu.Unlock()
+
+	e.cloud.network = true
+	return nil
+}
+
+// ReconcileAPIServerAddress does nothing, as Elastic IPs are not simulated.
+func (e *EC2) ReconcileAPIServerAddress() error {
+	return nil
+}
+
+// ReconcileBastion launches the bastion instance of the cluster, if not running yet.
+func (e *EC2) ReconcileBastion() error {
+	e.cloud.mu.Lock()
+	defer e.cloud.mu.Unlock()
+
+	if !e.cloud.network {
+		return awserrors.NewFailedDependency(errors.New("failed to launch bastion: network not ready"))
+	}
+
+	if _, ok := e.cloud.live(e.cloud.bastionID); ok {
+		return nil
+	}
+
+	i := e.cloud.launch(fmt.Sprintf("%s-bastion", e.cloud.clusterName), tags.ValueBastionRole, "t2.micro", "")
+	e.cloud.bastionID = i.ID
+	return nil
+}
+
+// DeleteNetwork deletes the network of the cluster. Like in AWS, it fails
+// while instances of the cluster are not terminated.
+func (e *EC2) DeleteNetwork() error {
+	e.cloud.mu.Lock()
+	defer e.cloud.mu.Unlock()
+
+	for _, i := range e.cloud.instances {
+		e.cloud.observe(i)
+		if i.State != v1alpha1.InstanceStateTerminated {
+			return errors.Errorf("failed to delete network: instance %q is %s", i.ID, i.State)
+		}
+	}
+
+	e.cloud.network = false
+	return nil
+}
+
+// DeleteBastion terminates the bastion instance of the cluster.
+func (e *EC2) DeleteBastion() error {
+	e.cloud.mu.Lock()
+	defer e.cloud.mu.Unlock()
+
+	if i, ok := e.cloud.live(e.cloud.bastionID); ok {
+		i.State = v1alpha1.InstanceStateShuttingDown
+	}
+	return nil
+}
+
+// DeleteImages does nothing, as images are never copied.
+func (e *EC2) DeleteImages() error {
+	return nil
+}
+
+// DeletePlacementGroups does nothing, as placement groups are not simulated.
+func (e *EC2) DeletePlacementGroups() error {
+	return nil
+}
+
+// ReconcileReservedInstanceCoverage does nothing, as reserved instances are not simulated.
+func (e *EC2) ReconcileReservedInstanceCoverage() error {
+	return nil
+}
+
+// DeleteStoppedInstances terminates the stopped instances of the cluster.
+func (e *EC2) DeleteStoppedInstances() error {
+	e.cloud.mu.Lock()
+	defer e.cloud.mu.Unlock()
+
+	for _, i := range e.cloud.instances {
+		e.cloud.observe(i)
+		if i.State == v1alpha1.InstanceStateStopped {
+			i.State = v1alpha1.InstanceStateShuttingDown
+		}
+	}
+	return nil
+}
+
+// ControlPlaneZones returns no availability zones, as they are not simulated.
+func (e *EC2) ControlPlaneZones() (map[string]int, error) {
+	return map[string]int{}, nil
+}
+
+// RunningControlPlaneInstance returns the ID of a running control plane
+// instance of the cluster, or an empty string if there is none.
+func (e *EC2) RunningControlPlaneInstance() (string, error) {
+	e.cloud.mu.Lock()
+	defer e.cloud.mu.Unlock()
+
+	for _, i := range e.cloud.instances {
+		if i.Tags[tags.NameAWSClusterAPIRole] == "controlplane" && i.State == v1alpha1.InstanceStateRunning {
+			return i.ID, nil
+		}
+	}
+
+	return "", nil
+}
+
+// SpotMachinePools returns no machine pools, as they are not simulated.
+func (e *EC2) SpotMachinePools() ([]string, error) {
+	return nil, nil
+}
+
+// CreateMachinePoolLaunchTemplateVersion creates the next version of the
+// launch template of a machine pool, creating the template if needed.
+func (e *EC2) CreateMachinePoolLaunchTemplateVersion(pool *v1alpha1.AWSMachinePool, bootstrapToken string) (string, int64, error) {
+	e.cloud.mu.Lock()
+	defer e.cloud.mu.Unlock()
+
+	if !e.cloud.network {
+		return "", 0, awserrors.NewFailedDependency(errors.New("failed to launch machine pool: network not ready"))
+	}
+
+	id := pool.Status.LaunchTemplateID
+	if _, ok := e.cloud.launchTemplates[id]; !ok {
+		id = e.cloud.newLaunchTemplateID()
+	}
+
+	e.cloud.launchTemplates[id]++
+	return id, e.cloud.launchTemplates[id], nil
+}
+
+// DeleteMachinePoolLaunchTemplate deletes the launch template of a machine pool.
+func (e *EC2) DeleteMachinePoolLaunchTemplate(pool *v1alpha1.AWSMachinePool) error {
+	e.cloud.mu.Lock()
+	defer e.cloud.mu.Unlock()
+
+	delete(e.cloud.launchTemplates, pool.Status.LaunchTemplateID)
+	return nil
+}
+
+// MachinePoolSubnets returns a single private subnet.
+func (e *EC2) MachinePoolSubnets(pool *v1alpha1.AWSMachinePool) ([]string, error) {
+	return []string{"subnet-private"}, nil
+}
+
+// DeletedInstanceIfExists returns the instance of the given ID in any state, if any.
+func (e *EC2) DeletedInstanceIfExists(id string) (*v1alpha1.Instance, error) {
+	e.cloud.mu.Lock()
+	defer e.cloud.mu.Unlock()
+
+	i, ok := e.cloud.instances[id]
+	if !ok {
+		return nil, nil
+	}
+
+	e.cloud.observe(i)
+	return i.DeepCopy(), nil
+}
+
+// InstancesIfExist returns the pending, running or stopped instances among
+// the given ones, by ID.
+func (e *EC2) InstancesIfExist(ids []string) (map[string]*v1alpha1.Instance, error) {
+	instances := map[string]*v1alpha1.Instance{}
+	for _, id := range ids {
+		i, err := e.InstanceIfExists(id)
+		if err != nil {
+			return nil, err
+		}
+		if i != nil {
+			instances[id] = i
+		}
+	}
+	return instances, nil
+}
+
+// InstanceIfExists returns the pending, running or stopped instance of the given ID, if any.
+func (e *EC2) InstanceIfExists(id string) (*v1alpha1.Instance, error) {
+	e.cloud.mu.Lock()
+	defer e.cloud.mu.Unlock()
+
+	i, ok := e.cloud.instances[id]
+	if !ok {
+		return nil, nil
+	}
+
+	e.cloud.observe(i)
+	switch i.State {
+	case v1alpha1.InstanceStatePending, v1alpha1.InstanceStateRunning, v1alpha1.InstanceStateStopping, v1alpha1.InstanceStateStopped:
+		return i.DeepCopy(), nil
+	default:
+		return nil, nil
+	}
+}
+
+// InstanceByTags returns the pending, running or stopped instance launched
+// for a machine, found by its Name tag, if any.
+func (e *EC2) InstanceByTags(machine *actuators.MachineScope) (*v1alpha1.Instance, error) {
+	e.cloud.mu.Lock()
+	defer e.cloud.mu.Unlock()
+
+	for _, i := range e.cloud.instances {
+		if i.Tags["Name"] != machine.Name() {
+			continue
+		}
+
+		e.cloud.observe(i)
+		switch i.State {
+		case v1alpha1.InstanceStatePending, v1alpha1.InstanceStateRunning, v1alpha1.InstanceStateStopping, v1alpha1.InstanceStateStopped:
+			return i.DeepCopy(), nil
+		}
+	}
+
+	return nil, nil
+}
+
+// MachineExists returns whether the instance of a machine is pending, running
+// or stopped.
+func (e *EC2) MachineExists(machine *actuators.MachineScope) (bool, error) {
+	var instance *v1alpha1.Instance
+	var err error
+	if id := machine.MachineStatus.InstanceID; id != nil {
+		instance, err = e.InstanceIfExists(*id)
+	} else {
+		instance, err = e.InstanceByTags(machine)
+	}
+	return instance != nil, err
+}
+
+// TerminateInstance terminates the instance of the given ID.
+func (e *EC2) TerminateInstance(id string) error {
+	e.cloud.mu.Lock()
+	defer e.cloud.mu.Unlock()
+
+	i, ok := e.cloud.live(id)
+	if !ok {
+		return awserrors.NewNotFound(errors.Errorf("instance %q not found", id))
+	}
+
+	i.State = v1alpha1.InstanceStateShuttingDown
+	return nil
+}
+
+// TerminateInstances terminates the instances of the given IDs, none of
+// them if one is not found.
+func (e *EC2) TerminateInstances(ids []string) error {
+	e.cloud.mu.Lock()
+	defer e.cloud.mu.Unlock()
+
+	instances := make([]*v1alpha1.Instance, 0, len(ids))
+	for _, id := range ids {
+		i, ok := e.cloud.live(id)
+		if !ok {
+			return awserrors.NewNotFound(errors.Errorf("instance %q not found", id))
+		}
+		instances = append(instances, i)
+	}
+
+	for _, i := range instances {
+		i.State = v1alpha1.InstanceStateShuttingDown
+	}
+	return nil
+}
+
+// RebootInstance reboots the instance of the given ID, which leaves its state unchanged.
+func (e *EC2) RebootInstance(id string) error {
+	e.cloud.mu.Lock()
+	defer e.cloud.mu.Unlock()
+
+	if _, ok := e.cloud.live(id); !ok {
+		return awserrors.NewNotFound(errors.Errorf("instance %q not found", id))
+	}
+
+	return nil
+}
+
+// StopInstance stops the instance of the given ID.
+func (e *EC2) StopInstance(id string) error {
+	e.cloud.mu.Lock()
+	defer e.cloud.mu.Unlock()
+
+	i, ok := e.cloud.live(id)
+	if !ok {
+		return awserrors.NewNotFound(errors.Errorf("instance %q not found", id))
+	}
+
+	i.State = v1alpha1.InstanceStateStopping
+	return nil
+}
+
+// StartInstance starts the stopped instance of the given ID.
+func (e *EC2) StartInstance(id string) error {
+	e.cloud.mu.Lock()
+	defer e.cloud.mu.Unlock()
+
+	i, ok := e.cloud.live(id)
+	if !ok {
+		return awserrors.NewNotFound(errors.Errorf("instance %q not found", id))
+	}
+
+	if i.State != v1alpha1.InstanceStateStopped {
+		return errors.Errorf("instance %q is %s", id, i.State)
+	}
+
+	i.State = v1alpha1.InstanceStatePending
+	return nil
+}
+
+// SetTerminationProtection sets the termination protection of the instance
+// of the given ID, which the fake does not enforce.
+func (e *EC2) SetTerminationProtection(id string, enabled bool) error {
+	e.cloud.mu.Lock()
+	defer e.cloud.mu.Unlock()
+
+	if _, ok := e.cloud.live(id); !ok {
+		return awserrors.NewNotFound(errors.Errorf("instance %q not found", id))
+	}
+
+	return nil
+}
+
+// AssociateMachineAddress checks that the instance of the given ID exists,
+// as Elastic IPs are not simulated.
+func (e *EC2) AssociateMachineAddress(machine *actuators.MachineScope, instanceID string) error {
+	e.cloud.mu.Lock()
+	defer e.cloud.mu.Unlock()
+
+	if _, ok := e.cloud.live(instanceID); !ok {
+		return awserrors.NewNotFound(errors.Errorf("instance %q not found", instanceID))
+	}
+
+	return nil
+}
+
+// AssociateAPIServerAddress checks that the instance of the given ID exists,
+// as Elastic IPs are not simulated.
+func (e *EC2) AssociateAPIServerAddress(machine *actuators.MachineScope, instanceID string) error {
+	return e.AssociateMachineAddress(machine, instanceID)
+}
+
+// ReleaseMachineAddress does nothing, as Elastic IPs are not simulated.
+func (e *EC2) ReleaseMachineAddress(machine *actuators.MachineScope) error {
+	return nil
+}
+
+// CreateOrGetMachine returns the instance of a machine, launching it if needed.
+func (e *EC2) CreateOrGetMachine(machine *actuators.MachineScope, token, kubeConfig string) (*v1alpha1.Instance, error) {
+	e.cloud.mu.Lock()
+	defer e.cloud.mu.Unlock()
+
+	if id := machine.MachineStatus.InstanceID; id != nil {
+		if i, ok := e.cloud.live(*id); ok && i.State != v1alpha1.InstanceStateShuttingDown {
+			e.cloud.observe(i)
+			return i.DeepCopy(), nil
+		}
+	}
+
+	// Instances launched for the machine are found by their Name tag, and
+	// started again if they were parked.
+	for _, i := range e.cloud.instances {
+		if i.Tags["Name"] != machine.Name() {
+			continue
+		}
+
+		e.cloud.observe(i)
+
+		switch i.State {
+		case v1alpha1.InstanceStateStopped:
+			i.State = v1alpha1.InstanceStatePending
+			return i.DeepCopy(), nil
+		case v1alpha1.InstanceStatePending, v1alpha1.InstanceStateRunning, v1alpha1.InstanceStateStopping:
+			return i.DeepCopy(), nil
+		}
+	}
+
+	if !e.cloud.network {
+		return nil, awserrors.NewFailedDependency(errors.Errorf("failed to run machine %q: network not ready", machine.Name()))
+	}
+
+	instanceTags, err := machine.InstanceTags()
+	if err != nil {
+		return nil, err
+	}
+
+	i := e.cloud.launch(machine.Name(), machine.Role(), machine.MachineConfig.InstanceType, aws.StringValue(machine.MachineConfig.AMI.ID))
+	i.CreditSpecification = machine.MachineConfig.CreditSpecification
+	i.DetailedMonitoring = machine.MachineConfig.EnableDetailedMonitoring
+	for k, v := range instanceTags {
+		i.Tags[k] = v
+	}
+	return i.DeepCopy(), nil
+}
+
+// UpdateInstanceSecurityGroups replaces the security groups of an instance.
+func (e *EC2) UpdateInstanceSecurityGroups(id string, securityGroups []string) error {
+	e.cloud.mu.Lock()
+	defer e.cloud.mu.Unlock()
+
+	i, ok := e.cloud.live(id)
+	if !ok {
+		return awserrors.NewNotFound(errors.Errorf("instance %q not found", id))
+	}
+
+	i.SecurityGroupIDs = append([]string(nil), securityGroups...)
+	return nil
+}
+
+// UpdateResourceTags adds and removes tags of an instance.
+func (e *EC2) UpdateResourceTags(resourceID *string, create map[string]string, remove map[string]string) error {
+	e.cloud.mu.Lock()
+	defer e.cloud.mu.Unlock()
+
+	i, ok := e.cloud.live(aws.StringValue(resourceID))
+	if !ok {
+		return awserrors.NewNotFound(errors.Errorf("instance %q not found", aws.StringValue(resourceID)))
+	}
+
+	for k := range remove {
+		delete(i.Tags, k)
+	}
+	for k, v := range create {
+		i.Tags[k] = v
+	}
+	return nil
+}
+
+// ScrubInstanceUserData removes the user data of an instance.
+func (e *EC2) ScrubInstanceUserData(id string) error {
+	e.cloud.mu.Lock()
+	defer e.cloud.mu.Unlock()
+
+	i, ok := e.cloud.live(id)
+	if !ok {
+		return awserrors.NewNotFound(errors.Errorf("instance %q not found", id))
+	}
+
+	i.UserData = nil
+	return nil
+}
+
+// ImageCreationDate returns the creation date of the account, the date every image was created at.
+func (e *EC2) ImageCreationDate(imageID string) (time.Time, error) {
+	return e.cloud.imageCreated, nil
+}
+
+// InstanceEvents returns no events, as maintenance is not simulated.
+func (e *EC2) InstanceEvents(id string) ([]v1alpha1.InstanceEvent, error) {
+	return nil, nil
+}
+
+// GetConsoleOutput returns an empty console output.
+func (e *EC2) GetConsoleOutput(id string) (string, error) {
+	return "", nil
+}
+
+// ModifyInstanceVolumes modifies no volumes, as volumes are not simulated.
+func (e *EC2) ModifyInstanceVolumes(id string, root *v1alpha1.RootVolume, volumes []v1alpha1.Volume) ([]string, error) {
+	return nil, nil
+}
+
+// ModifyInstanceCreditSpecification sets the CPU credit option of an instance.
+func (e *EC2) ModifyInstanceCreditSpecification(id string, credits v1alpha1.CPUCredits) (bool, error) {
+	e.cloud.mu.Lock()
+	defer e.cloud.mu.Unlock()
+
+	i, ok := e.cloud.live(id)
+	if !ok {
+		return false, awserrors.NewNotFound(errors.Errorf("instance %q not found", id))
+	}
+
+	if i.CreditSpecification == credits {
+		return false, nil
+	}
+
+	i.CreditSpecification = credits
+	return true, nil
+}
+
+// SetInstanceMonitoring enables or disables the detailed monitoring of an instance.
+func (e *EC2) SetInstanceMonitoring(id string, enabled bool) error {
+	e.cloud.mu.Lock()
+	defer e.cloud.mu.Unlock()
+
+	i, ok := e.cloud.live(id)
+	if !ok {
+		return awserrors.NewNotFound(errors.Errorf("instance %q not found", id))
+	}
+
+	i.DetailedMonitoring = enabled
+	return nil
+}
+
+// launch adds a pending instance tagged like the instances of the provider.
+// It must be called with the lock held.
+func (c *Cloud) launch(name, role, instanceType, imageID string) *v1alpha1.Instance {
+	id := c.newInstanceID()
+	i := &v1alpha1.Instance{
+		ID:             id,
+		State:          v1alpha1.InstanceStatePending,
+		Type:           instanceType,
+		ImageID:        imageID,
+		PrivateIP:      aws.String(fmt.Sprintf("10.0.%d.%d", c.lastID/256, c.lastID%256)),
+		PrivateDNSName: aws.String(fmt.Sprintf("ip-10-0-%d-%d.ec2.internal", c.lastID/256, c.lastID%256)),
+		Tags: tags.Build(tags.BuildParams{
+			ClusterName: c.clusterName,
+			Lifecycle:   tags.ResourceLifecycleOwned,
+			Name:        aws.String(name),
+			Role:        aws.String(role),
+		}),
+	}
+
+	c.instances[id] = i
+	return i
+}
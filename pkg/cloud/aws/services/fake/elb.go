@@ -0,0 +1,118 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fake
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/awserrors"
+)
+
+var _ services.ELBInterface = &ELB{}
+
+// ELB is the in-memory ELB service of a Cloud.
+type ELB struct {
+	cloud *Cloud
+}
+
+// ReconcileLoadbalancers creates the API server load balancer of the cluster.
+func (e *ELB) ReconcileLoadbalancers() error {
+	e.cloud.mu.Lock()
+	defer e.cloud.mu.Unlock()
+
+	if !e.cloud.network {
+		return awserrors.NewFailedDependency(errors.New("failed to create load balancer: network not ready"))
+	}
+
+	if e.cloud.loadBalancerDNS == "" {
+		e.cloud.loadBalancerDNS = fmt.Sprintf("%s-apiserver.elb.fake", e.cloud.clusterName)
+	}
+	return nil
+}
+
+// ReconcileAPIServerPrivateLink does nothing, PrivateLink is not faked.
+func (e *ELB) ReconcileAPIServerPrivateLink() error {
+	return nil
+}
+
+// ReconcileAPIServerELBMigration does nothing, the scheme of the fake load
+// balancer never changes.
+func (e *ELB) ReconcileAPIServerELBMigration() error {
+	return nil
+}
+
+// DeleteLoadbalancers deletes the API server load balancer of the cluster.
+func (e *ELB) DeleteLoadbalancers() error {
+	e.cloud.mu.Lock()
+	defer e.cloud.mu.Unlock()
+
+	e.cloud.loadBalancerDNS = ""
+	e.cloud.registeredOnELB = map[string]bool{}
+	return nil
+}
+
+// RegisterInstanceWithAPIServerELB registers an instance with the API server load balancer.
+func (e *ELB) RegisterInstanceWithAPIServerELB(instanceID string) error {
+	e.cloud.mu.Lock()
+	defer e.cloud.mu.Unlock()
+
+	if e.cloud.loadBalancerDNS == "" {
+		return awserrors.NewNotFound(errors.New("API server load balancer not found"))
+	}
+
+	if _, ok := e.cloud.live(instanceID); !ok {
+		return awserrors.NewNotFound(errors.Errorf("instance %q not found", instanceID))
+	}
+
+	e.cloud.registeredOnELB[instanceID] = true
+	return nil
+}
+
+// DeregisterInstanceFromAPIServerELB deregisters an instance from the API server load balancer.
+func (e *ELB) DeregisterInstanceFromAPIServerELB(instanceID string) error {
+	e.cloud.mu.Lock()
+	defer e.cloud.mu.Unlock()
+
+	if e.cloud.loadBalancerDNS == "" {
+		return awserrors.NewNotFound(errors.New("API server load balancer not found"))
+	}
+
+	delete(e.cloud.registeredOnELB, instanceID)
+	return nil
+}
+
+// InstanceDeregisteredFromAPIServerELB returns true if an instance is not
+// registered with the API server load balancer. Connections are not drained.
+func (e *ELB) InstanceDeregisteredFromAPIServerELB(instanceID string) (bool, error) {
+	e.cloud.mu.Lock()
+	defer e.cloud.mu.Unlock()
+
+	return !e.cloud.registeredOnELB[instanceID], nil
+}
+
+// GetAPIServerDNSName returns the DNS name of the API server load balancer.
+func (e *ELB) GetAPIServerDNSName() (string, error) {
+	e.cloud.mu.Lock()
+	defer e.cloud.mu.Unlock()
+
+	if e.cloud.loadBalancerDNS == "" {
+		return "", awserrors.NewNotFound(errors.New("API server load balancer not found"))
+	}
+	return e.cloud.loadBalancerDNS, nil
+}
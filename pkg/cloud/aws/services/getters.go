@@ -0,0 +1,39 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package services
+
+import (
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/actuators"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/ec2"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/elb"
+)
+
+// DefaultGetter returns the services calling the AWS APIs with the clients
+// of the scopes.
+var DefaultGetter Getter = awsGetter{}
+
+type awsGetter struct{}
+
+// EC2 returns the EC2 service of a scope.
+func (awsGetter) EC2(scope *actuators.Scope) EC2Interface {
+	return ec2.NewService(scope)
+}
+
+// ELB returns the ELB service of a scope.
+func (awsGetter) ELB(scope *actuators.Scope) ELBInterface {
+	return elb.NewService(scope)
+}
@@ -0,0 +1,413 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package instancetypes describes the capabilities of EC2 instance types.
+package instancetypes
+
+import (
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	awssts "github.com/aws/aws-sdk-go/service/sts"
+	"github.com/aws/aws-sdk-go/service/sts/stsiface"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/queryprotocol"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/sts"
+)
+
+const (
+	// offeringsTTL is how long the offerings of instance types are cached,
+	// since instance types are added to availability zones over time.
+	offeringsTTL = time.Hour
+)
+
+// Info describes the capabilities of an instance type.
+type Info struct {
+	// Architectures are the processor architectures supported by the instance type.
+	Architectures []string
+
+	// Hypervisor is the hypervisor of the instance type, "nitro" or "xen",
+	// and empty for bare metal instance types.
+	Hypervisor string
+
+	// Burstable is true if the CPU usage of the instance type is governed by
+	// credits.
+	Burstable bool
+
+	// VCPUs is the default number of vCPUs of the instance type.
+	VCPUs int64
+
+	// DefaultCores and DefaultThreadsPerCore are the default CPU options of
+	// the instance type.
+	DefaultCores          int64
+	DefaultThreadsPerCore int64
+
+	// ValidCores and ValidThreadsPerCore are the core counts and threads per
+	// core the CPU options of the instance type accept. They are empty for the
+	// instance types whose CPU options cannot be specified.
+	ValidCores          []int64
+	ValidThreadsPerCore []int64
+
+	// MemoryMiB is the memory of the instance type, in MiB.
+	MemoryMiB int64
+
+	// Accelerators are the GPUs and inference accelerators of the instance type.
+	Accelerators []Accelerator
+
+	// NVMeDisks is the number of NVMe instance store disks of the instance type.
+	NVMeDisks int64
+
+	// NetworkPerformance describes the network bandwidth of the instance
+	// type, e.g. "Up to 10 Gigabit".
+	NetworkPerformance string
+
+	// MaximumNetworkCards is the number of network cards of the instance type.
+	MaximumNetworkCards int64
+
+	// ENAExpressSupported is true if the instance type supports ENA Express.
+	ENAExpressSupported bool
+
+	// EFASupported is true if the instance type supports Elastic Fabric
+	// Adapters.
+	EFASupported bool
+}
+
+// Accelerator describes the GPUs or inference accelerators of a kind
+// attached to an instance type.
+type Accelerator struct {
+	// Manufacturer is the manufacturer of the accelerator, e.g. "NVIDIA".
+	Manufacturer string
+
+	// Name is the name of the accelerator, e.g. "T4".
+	Name string
+
+	// Count is the number of accelerators attached to the instance type.
+	Count int64
+}
+
+// Describer describes instance types.
+type Describer interface {
+	// Describe returns the capabilities of an instance type, or nil if the
+	// instance type is unknown.
+	Describe(instanceType string) (*Info, error)
+
+	// SupportedArchitectures returns the processor architectures supported by
+	// an instance type, or an empty list if the instance type is unknown.
+	SupportedArchitectures(instanceType string) ([]string, error)
+
+	// OfferedInZone returns true if an instance type is offered in an
+	// availability zone.
+	OfferedInZone(instanceType, zone string) (bool, error)
+}
+
+// Service describes the instance types offered in a region.
+//
+// The vendored SDK predates DescribeInstanceTypes, so requests are sent with
+// a generic SDK client speaking the query protocol of EC2. Requests still go
+// through the handlers of the session, for signing, retries and rate limiting.
+type Service struct {
+	client *client.Client
+	region string
+
+	// sts resolves the account of the credentials, which the offerings are
+	// cached by, since zone names map to different zones in each account.
+	sts     stsiface.STSAPI
+	account string
+}
+
+type describeInstanceTypesInput struct {
+	_ struct{} `type:"structure"`
+
+	InstanceTypes []*string `locationName:"InstanceType" type:"list" flattened:"true"`
+}
+
+type describeInstanceTypesOutput struct {
+	_ struct{} `type:"structure"`
+
+	InstanceTypes []*instanceTypeInfo `locationName:"instanceTypeSet" locationNameList:"item" type:"list"`
+}
+
+type instanceTypeInfo struct {
+	_ struct{} `type:"structure"`
+
+	InstanceType                  *string        `locationName:"instanceType" type:"string"`
+	Hypervisor                    *string        `locationName:"hypervisor" type:"string"`
+	BurstablePerformanceSupported *bool          `locationName:"burstablePerformanceSupported" type:"boolean"`
+	ProcessorInfo                 *processorInfo `locationName:"processorInfo" type:"structure"`
+	VCPUInfo                      *vcpuInfo      `locationName:"vCpuInfo" type:"structure"`
+	MemoryInfo                    *memoryInfo    `locationName:"memoryInfo" type:"structure"`
+
+	GPUInfo                  *gpuInfo                  `locationName:"gpuInfo" type:"structure"`
+	InferenceAcceleratorInfo *inferenceAcceleratorInfo `locationName:"inferenceAcceleratorInfo" type:"structure"`
+	InstanceStorageInfo      *instanceStorageInfo      `locationName:"instanceStorageInfo" type:"structure"`
+	NetworkInfo              *networkInfo              `locationName:"networkInfo" type:"structure"`
+}
+
+type processorInfo struct {
+	_ struct{} `type:"structure"`
+
+	SupportedArchitectures []*string `locationName:"supportedArchitectures" locationNameList:"item" type:"list"`
+}
+
+type vcpuInfo struct {
+	_ struct{} `type:"structure"`
+
+	DefaultVCPUs          *int64   `locationName:"defaultVCpus" type:"integer"`
+	DefaultCores          *int64   `locationName:"defaultCores" type:"integer"`
+	DefaultThreadsPerCore *int64   `locationName:"defaultThreadsPerCore" type:"integer"`
+	ValidCores            []*int64 `locationName:"validCores" locationNameList:"item" type:"list"`
+	ValidThreadsPerCore   []*int64 `locationName:"validThreadsPerCore" locationNameList:"item" type:"list"`
+}
+
+type memoryInfo struct {
+	_ struct{} `type:"structure"`
+
+	SizeInMiB *int64 `locationName:"sizeInMiB" type:"long"`
+}
+
+type gpuInfo struct {
+	_ struct{} `type:"structure"`
+
+	GPUs []*acceleratorInfo `locationName:"gpus" locationNameList:"item" type:"list"`
+}
+
+type inferenceAcceleratorInfo struct {
+	_ struct{} `type:"structure"`
+
+	Accelerators []*acceleratorInfo `locationName:"accelerators" locationNameList:"item" type:"list"`
+}
+
+type acceleratorInfo struct {
+	_ struct{} `type:"structure"`
+
+	Count        *int64  `locationName:"count" type:"integer"`
+	Manufacturer *string `locationName:"manufacturer" type:"string"`
+	Name         *string `locationName:"name" type:"string"`
+}
+
+type instanceStorageInfo struct {
+	_ struct{} `type:"structure"`
+
+	Disks       []*diskInfo `locationName:"disks" locationNameList:"item" type:"list"`
+	NVMeSupport *string     `locationName:"nvmeSupport" type:"string"`
+}
+
+type diskInfo struct {
+	_ struct{} `type:"structure"`
+
+	Count *int64 `locationName:"count" type:"integer"`
+}
+
+type networkInfo struct {
+	_ struct{} `type:"structure"`
+
+	NetworkPerformance  *string `locationName:"networkPerformance" type:"string"`
+	MaximumNetworkCards *int64  `locationName:"maximumNetworkCards" type:"integer"`
+	EnaSrdSupported     *bool   `locationName:"enaSrdSupported" type:"boolean"`
+	EfaSupported        *bool   `locationName:"efaSupported" type:"boolean"`
+}
+
+type describeInstanceTypeOfferingsInput struct {
+	_ struct{} `type:"structure"`
+
+	LocationType *string       `type:"string"`
+	Filters      []*ec2.Filter `locationName:"Filter" locationNameList:"Filter" type:"list"`
+}
+
+type describeInstanceTypeOfferingsOutput struct {
+	_ struct{} `type:"structure"`
+
+	InstanceTypeOfferings []*instanceTypeOffering `locationName:"instanceTypeOfferingSet" locationNameList:"item" type:"list"`
+}
+
+type instanceTypeOffering struct {
+	_ struct{} `type:"structure"`
+
+	InstanceType *string `locationName:"instanceType" type:"string"`
+	Location     *string `locationName:"location" type:"string"`
+}
+
+// infos caches the capabilities of instance types by region and instance
+// type, which never change and are looked up for every machine launched.
+var infos = struct {
+	sync.Mutex
+	byType map[string]*Info
+}{byType: map[string]*Info{}}
+
+// offerings caches whether instance types are offered by account, region,
+// zone and instance type, for offeringsTTL.
+var offerings = struct {
+	sync.Mutex
+	byZone map[string]offering
+}{byZone: map[string]offering{}}
+
+type offering struct {
+	offered bool
+	expires time.Time
+}
+
+// NewService returns a service describing the instance types of the region of the session.
+func NewService(sess *session.Session) *Service {
+	c := queryprotocol.NewEC2Client(sess)
+	return &Service{client: c, region: c.SigningRegion, sts: awssts.New(sess)}
+}
+
+// Describe implements Describer.
+func (s *Service) Describe(instanceType string) (*Info, error) {
+	key := s.region + "/" + instanceType
+
+	infos.Lock()
+	cached, ok := infos.byType[key]
+	infos.Unlock()
+	if ok {
+		return cached, nil
+	}
+
+	input := &describeInstanceTypesInput{
+		InstanceTypes: aws.StringSlice([]string{instanceType}),
+	}
+	out := &describeInstanceTypesOutput{}
+
+	if err := queryprotocol.Send(s.client, "DescribeInstanceTypes", input, out); err != nil {
+		return nil, errors.Wrapf(err, "failed to describe instance type %q", instanceType)
+	}
+
+	var described *Info
+	for _, it := range out.InstanceTypes {
+		if aws.StringValue(it.InstanceType) != instanceType {
+			continue
+		}
+
+		described = &Info{
+			Hypervisor: aws.StringValue(it.Hypervisor),
+			Burstable:  aws.BoolValue(it.BurstablePerformanceSupported),
+		}
+		if it.ProcessorInfo != nil {
+			described.Architectures = aws.StringValueSlice(it.ProcessorInfo.SupportedArchitectures)
+		}
+		if it.VCPUInfo != nil {
+			described.VCPUs = aws.Int64Value(it.VCPUInfo.DefaultVCPUs)
+			described.DefaultCores = aws.Int64Value(it.VCPUInfo.DefaultCores)
+			described.DefaultThreadsPerCore = aws.Int64Value(it.VCPUInfo.DefaultThreadsPerCore)
+			if len(it.VCPUInfo.ValidCores) > 0 {
+				described.ValidCores = aws.Int64ValueSlice(it.VCPUInfo.ValidCores)
+			}
+			if len(it.VCPUInfo.ValidThreadsPerCore) > 0 {
+				described.ValidThreadsPerCore = aws.Int64ValueSlice(it.VCPUInfo.ValidThreadsPerCore)
+			}
+		}
+		if it.MemoryInfo != nil {
+			described.MemoryMiB = aws.Int64Value(it.MemoryInfo.SizeInMiB)
+		}
+		if it.GPUInfo != nil {
+			described.Accelerators = append(described.Accelerators, accelerators(it.GPUInfo.GPUs)...)
+		}
+		if it.InferenceAcceleratorInfo != nil {
+			described.Accelerators = append(described.Accelerators, accelerators(it.InferenceAcceleratorInfo.Accelerators)...)
+		}
+		if it.InstanceStorageInfo != nil {
+			switch aws.StringValue(it.InstanceStorageInfo.NVMeSupport) {
+			case "required", "supported":
+				for _, disk := range it.InstanceStorageInfo.Disks {
+					described.NVMeDisks += aws.Int64Value(disk.Count)
+				}
+			}
+		}
+		if it.NetworkInfo != nil {
+			described.NetworkPerformance = aws.StringValue(it.NetworkInfo.NetworkPerformance)
+			described.MaximumNetworkCards = aws.Int64Value(it.NetworkInfo.MaximumNetworkCards)
+			described.ENAExpressSupported = aws.BoolValue(it.NetworkInfo.EnaSrdSupported)
+			described.EFASupported = aws.BoolValue(it.NetworkInfo.EfaSupported)
+		}
+	}
+
+	infos.Lock()
+	infos.byType[key] = described
+	infos.Unlock()
+
+	return described, nil
+}
+
+func accelerators(infos []*acceleratorInfo) []Accelerator {
+	var accelerators []Accelerator
+	for _, info := range infos {
+		accelerators = append(accelerators, Accelerator{
+			Manufacturer: aws.StringValue(info.Manufacturer),
+			Name:         aws.StringValue(info.Name),
+			Count:        aws.Int64Value(info.Count),
+		})
+	}
+	return accelerators
+}
+
+// SupportedArchitectures implements Describer.
+func (s *Service) SupportedArchitectures(instanceType string) ([]string, error) {
+	info, err := s.Describe(instanceType)
+	if err != nil || info == nil {
+		return nil, err
+	}
+	return info.Architectures, nil
+}
+
+// OfferedInZone implements Describer.
+func (s *Service) OfferedInZone(instanceType, zone string) (bool, error) {
+	if s.account == "" {
+		account, err := sts.NewService(s.sts).AccountID()
+		if err != nil {
+			return false, errors.Wrapf(err, "failed to describe the offerings of instance type %q in zone %q", instanceType, zone)
+		}
+		s.account = account
+	}
+
+	key := s.account + "/" + s.region + "/" + zone + "/" + instanceType
+
+	offerings.Lock()
+	cached, ok := offerings.byZone[key]
+	offerings.Unlock()
+	if ok && time.Now().Before(cached.expires) {
+		return cached.offered, nil
+	}
+
+	input := &describeInstanceTypeOfferingsInput{
+		LocationType: aws.String("availability-zone"),
+		Filters: []*ec2.Filter{
+			{Name: aws.String("location"), Values: aws.StringSlice([]string{zone})},
+			{Name: aws.String("instance-type"), Values: aws.StringSlice([]string{instanceType})},
+		},
+	}
+	out := &describeInstanceTypeOfferingsOutput{}
+
+	if err := queryprotocol.Send(s.client, "DescribeInstanceTypeOfferings", input, out); err != nil {
+		return false, errors.Wrapf(err, "failed to describe the offerings of instance type %q in zone %q", instanceType, zone)
+	}
+
+	offered := false
+	for _, o := range out.InstanceTypeOfferings {
+		if aws.StringValue(o.InstanceType) == instanceType && aws.StringValue(o.Location) == zone {
+			offered = true
+		}
+	}
+
+	offerings.Lock()
+	offerings.byZone[key] = offering{offered: offered, expires: time.Now().Add(offeringsTTL)}
+	offerings.Unlock()
+
+	return offered, nil
+}
@@ -0,0 +1,290 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package instancetypes
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/aws/aws-sdk-go/service/sts/stsiface"
+)
+
+// fakeSTS resolves the credentials to an account.
+type fakeSTS struct {
+	stsiface.STSAPI
+	account string
+}
+
+func (f *fakeSTS) GetCallerIdentity(*sts.GetCallerIdentityInput) (*sts.GetCallerIdentityOutput, error) {
+	return &sts.GetCallerIdentityOutput{Account: aws.String(f.account)}, nil
+}
+
+func TestSupportedArchitectures(t *testing.T) {
+	requests := 0
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256") {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		if err := r.ParseForm(); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		if r.Form.Get("Action") != "DescribeInstanceTypes" || r.Form.Get("Version") != "2016-11-15" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		requests++
+		switch r.Form.Get("InstanceType.1") {
+		case "a1.large":
+			w.Write([]byte(`<DescribeInstanceTypesResponse><requestId>1</requestId><instanceTypeSet><item><instanceType>a1.large</instanceType><processorInfo><supportedArchitectures><item>arm64</item></supportedArchitectures></processorInfo></item></instanceTypeSet></DescribeInstanceTypesResponse>`))
+		case "m5.large":
+			w.Write([]byte(`<DescribeInstanceTypesResponse><requestId>1</requestId><instanceTypeSet><item><instanceType>m5.large</instanceType><processorInfo><supportedArchitectures><item>i386</item><item>x86_64</item></supportedArchitectures></processorInfo></item></instanceTypeSet></DescribeInstanceTypesResponse>`))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`<Response><Errors><Error><Code>InvalidInstanceType</Code><Message>The following supplied instance types do not exist</Message></Error></Errors><RequestID>1</RequestID></Response>`))
+		}
+	}))
+	defer server.Close()
+
+	sess, err := session.NewSession(aws.NewConfig().
+		WithRegion("eu-west-3").
+		WithEndpoint(server.URL).
+		WithMaxRetries(0).
+		WithCredentials(credentials.NewStaticCredentials("id", "secret", "")))
+	if err != nil {
+		t.Fatalf("failed to create session: %v", err)
+	}
+
+	s := NewService(sess)
+
+	testCases := []struct {
+		instanceType string
+		expected     []string
+	}{
+		{instanceType: "a1.large", expected: []string{"arm64"}},
+		{instanceType: "m5.large", expected: []string{"i386", "x86_64"}},
+		{instanceType: "m5.large", expected: []string{"i386", "x86_64"}},
+	}
+
+	for _, tc := range testCases {
+		supported, err := s.SupportedArchitectures(tc.instanceType)
+		if err != nil {
+			t.Fatalf("did not expect error: %v", err)
+		}
+		if !reflect.DeepEqual(supported, tc.expected) {
+			t.Fatalf("expected architectures %v for %q, got %v", tc.expected, tc.instanceType, supported)
+		}
+	}
+
+	if requests != 2 {
+		t.Fatalf("expected architectures to be cached, got %d requests", requests)
+	}
+
+	if _, err := s.SupportedArchitectures("x9.huge"); err == nil {
+		t.Fatalf("expected error")
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		if r.Form.Get("Action") != "DescribeInstanceTypes" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		switch r.Form.Get("InstanceType.1") {
+		case "m5.large":
+			w.Write([]byte(`<DescribeInstanceTypesResponse><requestId>1</requestId><instanceTypeSet><item><instanceType>m5.large</instanceType><processorInfo><supportedArchitectures><item>x86_64</item></supportedArchitectures></processorInfo><vCpuInfo><defaultVCpus>2</defaultVCpus></vCpuInfo><memoryInfo><sizeInMiB>8192</sizeInMiB></memoryInfo></item></instanceTypeSet></DescribeInstanceTypesResponse>`))
+		case "t3.large":
+			w.Write([]byte(`<DescribeInstanceTypesResponse><requestId>1</requestId><instanceTypeSet><item><instanceType>t3.large</instanceType><burstablePerformanceSupported>true</burstablePerformanceSupported></item></instanceTypeSet></DescribeInstanceTypesResponse>`))
+		case "g4dn.xlarge":
+			w.Write([]byte(`<DescribeInstanceTypesResponse><requestId>1</requestId><instanceTypeSet><item><instanceType>g4dn.xlarge</instanceType><hypervisor>nitro</hypervisor><vCpuInfo><defaultVCpus>4</defaultVCpus><defaultCores>2</defaultCores><defaultThreadsPerCore>2</defaultThreadsPerCore><validCores><item>2</item></validCores><validThreadsPerCore><item>1</item><item>2</item></validThreadsPerCore></vCpuInfo><memoryInfo><sizeInMiB>16384</sizeInMiB></memoryInfo><gpuInfo><gpus><item><name>T4</name><manufacturer>NVIDIA</manufacturer><count>1</count></item></gpus></gpuInfo><instanceStorageInfo><totalSizeInGB>125</totalSizeInGB><disks><item><sizeInGB>125</sizeInGB><count>1</count><type>ssd</type></item></disks><nvmeSupport>required</nvmeSupport></instanceStorageInfo><networkInfo><networkPerformance>Up to 25 Gigabit</networkPerformance><maximumNetworkCards>1</maximumNetworkCards><enaSrdSupported>false</enaSrdSupported><efaSupported>true</efaSupported></networkInfo></item></instanceTypeSet></DescribeInstanceTypesResponse>`))
+		default:
+			w.Write([]byte(`<DescribeInstanceTypesResponse><requestId>1</requestId><instanceTypeSet/></DescribeInstanceTypesResponse>`))
+		}
+	}))
+	defer server.Close()
+
+	sess, err := session.NewSession(aws.NewConfig().
+		WithRegion("eu-central-1").
+		WithEndpoint(server.URL).
+		WithMaxRetries(0).
+		WithCredentials(credentials.NewStaticCredentials("id", "secret", "")))
+	if err != nil {
+		t.Fatalf("failed to create session: %v", err)
+	}
+
+	s := NewService(sess)
+
+	info, err := s.Describe("m5.large")
+	if err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+
+	expected := &Info{Architectures: []string{"x86_64"}, VCPUs: 2, MemoryMiB: 8192}
+	if !reflect.DeepEqual(info, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, info)
+	}
+
+	info, err = s.Describe("g4dn.xlarge")
+	if err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+
+	expected = &Info{
+		Hypervisor:            "nitro",
+		VCPUs:                 4,
+		DefaultCores:          2,
+		DefaultThreadsPerCore: 2,
+		ValidCores:            []int64{2},
+		ValidThreadsPerCore:   []int64{1, 2},
+		MemoryMiB:             16384,
+		Accelerators:          []Accelerator{{Manufacturer: "NVIDIA", Name: "T4", Count: 1}},
+		NVMeDisks:             1,
+		NetworkPerformance:    "Up to 25 Gigabit",
+		MaximumNetworkCards:   1,
+		EFASupported:          true,
+	}
+	if !reflect.DeepEqual(info, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, info)
+	}
+
+	info, err = s.Describe("t3.large")
+	if err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+	if !reflect.DeepEqual(info, &Info{Burstable: true}) {
+		t.Fatalf("expected a burstable instance type, got %+v", info)
+	}
+
+	info, err = s.Describe("x9.unknown")
+	if err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+	if info != nil {
+		t.Fatalf("expected unknown instance type to be described as nil, got %+v", info)
+	}
+}
+
+func TestOfferedInZone(t *testing.T) {
+	requests := 0
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+
+		if err := r.ParseForm(); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		if r.Form.Get("Action") != "DescribeInstanceTypeOfferings" ||
+			r.Form.Get("LocationType") != "availability-zone" ||
+			r.Form.Get("Filter.1.Name") != "location" ||
+			r.Form.Get("Filter.2.Name") != "instance-type" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		zone, instanceType := r.Form.Get("Filter.1.Value.1"), r.Form.Get("Filter.2.Value.1")
+		if zone == "eu-west-3a" && instanceType == "m5.large" {
+			w.Write([]byte(`<DescribeInstanceTypeOfferingsResponse><requestId>1</requestId><instanceTypeOfferingSet><item><instanceType>m5.large</instanceType><locationType>availability-zone</locationType><location>eu-west-3a</location></item></instanceTypeOfferingSet></DescribeInstanceTypeOfferingsResponse>`))
+			return
+		}
+		w.Write([]byte(`<DescribeInstanceTypeOfferingsResponse><requestId>1</requestId><instanceTypeOfferingSet/></DescribeInstanceTypeOfferingsResponse>`))
+	}))
+	defer server.Close()
+
+	sess, err := session.NewSession(aws.NewConfig().
+		WithRegion("eu-west-3").
+		WithEndpoint(server.URL).
+		WithMaxRetries(0).
+		WithCredentials(credentials.NewStaticCredentials("id", "secret", "")))
+	if err != nil {
+		t.Fatalf("failed to create session: %v", err)
+	}
+
+	s := NewService(sess)
+	s.sts = &fakeSTS{account: "111111111111"}
+
+	testCases := []struct {
+		instanceType string
+		zone         string
+		expected     bool
+	}{
+		{instanceType: "m5.large", zone: "eu-west-3a", expected: true},
+		{instanceType: "m5.large", zone: "eu-west-3c", expected: false},
+		{instanceType: "p4d.24xlarge", zone: "eu-west-3a", expected: false},
+	}
+
+	for _, tc := range testCases {
+		offered, err := s.OfferedInZone(tc.instanceType, tc.zone)
+		if err != nil {
+			t.Fatalf("did not expect error: %v", err)
+		}
+		if offered != tc.expected {
+			t.Fatalf("expected %q offered in %q: %t, got %t", tc.instanceType, tc.zone, tc.expected, offered)
+		}
+	}
+
+	// Offerings are cached.
+	if _, err := s.OfferedInZone("m5.large", "eu-west-3a"); err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+	if requests != len(testCases) {
+		t.Fatalf("expected %d requests, got %d", len(testCases), requests)
+	}
+
+	// Zone names map to different zones in each account.
+	other := NewService(sess)
+	other.sts = &fakeSTS{account: "222222222222"}
+	if _, err := other.OfferedInZone("m5.large", "eu-west-3a"); err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+	if requests != len(testCases)+1 {
+		t.Fatalf("expected the offerings of another account to be described, got %d requests", requests)
+	}
+
+	// Offerings are described again once the cache expires.
+	offerings.Lock()
+	key := "111111111111/eu-west-3/eu-west-3a/m5.large"
+	offerings.byZone[key] = offering{offered: offerings.byZone[key].offered, expires: time.Now().Add(-time.Second)}
+	offerings.Unlock()
+	if _, err := s.OfferedInZone("m5.large", "eu-west-3a"); err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+	if requests != len(testCases)+2 {
+		t.Fatalf("expected expired offerings to be described again, got %d requests", requests)
+	}
+}
@@ -17,37 +17,33 @@ limitations under the License.
 package services
 
 import (
-	"github.com/aws/aws-sdk-go/aws/session"
+	"time"
+
 	providerv1 "sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
 	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/actuators"
 )
 
 // Getter is a unified interfaces that includes all the getters.
 type Getter interface {
-	SDKSessionGetter
 	EC2Getter
 	ELBGetter
 }
 
-// SDKSessionGetter has a single method that returns an AWS session.
-type SDKSessionGetter interface {
-	Session(*providerv1.AWSClusterProviderSpec) *session.Session
-}
-
-// EC2Getter has a single method that returns an EC2 service interface.
+// EC2Getter has a single method that returns the EC2 service of a scope.
 type EC2Getter interface {
-	EC2(*session.Session) EC2Interface
+	EC2(*actuators.Scope) EC2Interface
 }
 
-// ELBGetter has a single method that returns an ELB service interface.
+// ELBGetter has a single method that returns the ELB service of a scope.
 type ELBGetter interface {
-	ELB(*session.Session) ELBInterface
+	ELB(*actuators.Scope) ELBInterface
 }
 
 // EC2Interface encapsulates the methods exposed by the ec2 service.
 type EC2Interface interface {
 	EC2ClusterInterface
 	EC2MachineInterface
+	EC2MachinePoolInterface
 }
 
 // EC2ClusterInterface encapsulates the methods exposed to the cluster
@@ -55,24 +51,63 @@ type EC2Interface interface {
 type EC2ClusterInterface interface {
 	ReconcileNetwork() error
 	ReconcileBastion() error
+	ReconcileAPIServerAddress() error
 	DeleteNetwork() error
 	DeleteBastion() error
+	DeleteImages() error
+	DeletePlacementGroups() error
+	ReconcileReservedInstanceCoverage() error
+	DeleteStoppedInstances() error
+	ControlPlaneZones() (map[string]int, error)
+	RunningControlPlaneInstance() (string, error)
+	SpotMachinePools() ([]string, error)
 }
 
 // EC2MachineInterface encapsulates the methods exposed to the machine
 // actuator
 type EC2MachineInterface interface {
 	InstanceIfExists(id string) (*providerv1.Instance, error)
+	InstancesIfExist(ids []string) (map[string]*providerv1.Instance, error)
+	DeletedInstanceIfExists(id string) (*providerv1.Instance, error)
+	InstanceByTags(machine *actuators.MachineScope) (*providerv1.Instance, error)
+	MachineExists(machine *actuators.MachineScope) (bool, error)
 	TerminateInstance(id string) error
+	TerminateInstances(ids []string) error
+	RebootInstance(id string) error
+	StopInstance(id string) error
+	StartInstance(id string) error
 	CreateOrGetMachine(machine *actuators.MachineScope, token, kubeConfig string) (*providerv1.Instance, error)
 	UpdateInstanceSecurityGroups(id string, securityGroups []string) error
 	UpdateResourceTags(resourceID *string, create map[string]string, remove map[string]string) error
+	ScrubInstanceUserData(id string) error
+	ImageCreationDate(imageID string) (time.Time, error)
+	InstanceEvents(id string) ([]providerv1.InstanceEvent, error)
+	GetConsoleOutput(id string) (string, error)
+	ModifyInstanceVolumes(id string, root *providerv1.RootVolume, volumes []providerv1.Volume) ([]string, error)
+	ModifyInstanceCreditSpecification(id string, credits providerv1.CPUCredits) (bool, error)
+	SetInstanceMonitoring(id string, enabled bool) error
+	SetTerminationProtection(id string, enabled bool) error
+	AssociateMachineAddress(machine *actuators.MachineScope, instanceID string) error
+	ReleaseMachineAddress(machine *actuators.MachineScope) error
+	AssociateAPIServerAddress(machine *actuators.MachineScope, instanceID string) error
+}
+
+// EC2MachinePoolInterface encapsulates the methods exposed to the machine
+// pool controller
+type EC2MachinePoolInterface interface {
+	CreateMachinePoolLaunchTemplateVersion(pool *providerv1.AWSMachinePool, bootstrapToken string) (string, int64, error)
+	DeleteMachinePoolLaunchTemplate(pool *providerv1.AWSMachinePool) error
+	MachinePoolSubnets(pool *providerv1.AWSMachinePool) ([]string, error)
 }
 
 // ELBInterface encapsulates the methods exposed by the elb service.
 type ELBInterface interface {
 	ReconcileLoadbalancers() error
+	ReconcileAPIServerPrivateLink() error
+	ReconcileAPIServerELBMigration() error
 	DeleteLoadbalancers() error
 	RegisterInstanceWithAPIServerELB(instanceID string) error
+	DeregisterInstanceFromAPIServerELB(instanceID string) error
+	InstanceDeregisteredFromAPIServerELB(instanceID string) (bool, error)
 	GetAPIServerDNSName() (string, error)
 }
@@ -0,0 +1,65 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package ipam allocates the CIDR blocks of the networks of clusters.
+package ipam
+
+import (
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+)
+
+// Cluster identifies the cluster CIDR blocks are allocated for.
+type Cluster struct {
+	Name      string `json:"cluster"`
+	Namespace string `json:"namespace"`
+	Region    string `json:"region"`
+}
+
+// Allocator allocates the CIDR blocks of the VPC and subnets of clusters.
+type Allocator interface {
+	// AllocateVPC returns the CIDR block of the VPC of a cluster.
+	AllocateVPC(cluster Cluster) (string, error)
+
+	// AllocateSubnets returns count CIDR blocks for new subnets of the VPC
+	// with the given CIDR block, not overlapping the used CIDR blocks of the
+	// existing subnets.
+	AllocateSubnets(cluster Cluster, vpcCidrBlock string, used []string, count int) ([]string, error)
+
+	// AllocatePods returns the pod CIDR block of a cluster, or an empty
+	// string if the allocator does not allocate pod CIDR blocks.
+	AllocatePods(cluster Cluster) (string, error)
+
+	// Release releases the CIDR blocks allocated to a cluster, once its
+	// network is deleted.
+	Release(cluster Cluster) error
+}
+
+// NewAllocator returns the allocator configured by spec. The session is used
+// by the allocators looking up the VPCs of the region.
+func NewAllocator(spec *v1alpha1.IPAM, sess *session.Session) (Allocator, error) {
+	switch {
+	case spec.Pool != nil && spec.Webhook != nil:
+		return nil, errors.New("ipam must specify only one of pool or webhook")
+	case spec.Pool != nil:
+		return NewPoolAllocator(spec.Pool, sess)
+	case spec.Webhook != nil:
+		return NewWebhookAllocator(spec.Webhook)
+	default:
+		return nil, errors.New("ipam must specify one of pool or webhook")
+	}
+}
@@ -0,0 +1,271 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ipam
+
+import (
+	"encoding/binary"
+	"net"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/queryprotocol"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/tags"
+)
+
+const (
+	// defaultVPCPrefixLength is the default prefix length of VPC CIDR blocks.
+	defaultVPCPrefixLength = 16
+
+	// defaultSubnetPrefixLength is the default prefix length of subnet CIDR blocks.
+	defaultSubnetPrefixLength = 24
+
+	// defaultPodPrefixLength is the default prefix length of pod CIDR blocks.
+	defaultPodPrefixLength = 16
+
+	// minPodPrefixLength bounds the size of pod CIDR blocks.
+	minPodPrefixLength = 8
+
+	// minPrefixLength and maxPrefixLength bound the prefix lengths of the
+	// CIDR blocks of VPCs and subnets accepted by AWS.
+	minPrefixLength = 16
+	maxPrefixLength = 28
+
+	// describeVpcsPageSize is the number of VPCs requested per page.
+	describeVpcsPageSize = 1000
+)
+
+// poolAllocator allocates CIDR blocks from an address pool. It keeps no
+// state: the VPC and pod CIDR blocks in use are looked up in the region, the
+// latter in the tags of the VPCs, and the subnet CIDR blocks in use are given
+// by the caller.
+type poolAllocator struct {
+	ec2                *client.Client
+	pool               *net.IPNet
+	vpcPrefixLength    int
+	subnetPrefixLength int
+	podPool            *net.IPNet
+	podPrefixLength    int
+}
+
+// NewPoolAllocator returns an allocator carving CIDR blocks out of a pool,
+// looking up the VPCs of the region of the session.
+func NewPoolAllocator(spec *v1alpha1.IPAMPool, sess *session.Session) (Allocator, error) {
+	_, pool, err := net.ParseCIDR(spec.CidrBlock)
+	if err != nil || pool.IP.To4() == nil {
+		return nil, errors.Errorf("invalid ipam pool %q, must be an IPv4 CIDR block", spec.CidrBlock)
+	}
+
+	a := &poolAllocator{
+		pool:               pool,
+		vpcPrefixLength:    spec.VPCPrefixLength,
+		subnetPrefixLength: spec.SubnetPrefixLength,
+		podPrefixLength:    spec.PodPrefixLength,
+	}
+	if a.vpcPrefixLength == 0 {
+		a.vpcPrefixLength = defaultVPCPrefixLength
+	}
+	if a.subnetPrefixLength == 0 {
+		a.subnetPrefixLength = defaultSubnetPrefixLength
+	}
+	if a.podPrefixLength == 0 {
+		a.podPrefixLength = defaultPodPrefixLength
+	}
+	if sess != nil {
+		a.ec2 = queryprotocol.NewEC2Client(sess)
+	}
+
+	if spec.PodCidrBlock != "" {
+		_, podPool, err := net.ParseCIDR(spec.PodCidrBlock)
+		if err != nil || podPool.IP.To4() == nil {
+			return nil, errors.Errorf("invalid ipam pod pool %q, must be an IPv4 CIDR block", spec.PodCidrBlock)
+		}
+
+		podPoolPrefixLength, _ := podPool.Mask.Size()
+		switch {
+		case a.podPrefixLength < minPodPrefixLength || a.podPrefixLength > maxPrefixLength:
+			return nil, errors.Errorf("invalid ipam pod prefix length %d, must be between %d and %d", a.podPrefixLength, minPodPrefixLength, maxPrefixLength)
+		case a.podPrefixLength < podPoolPrefixLength:
+			return nil, errors.Errorf("ipam pod prefix length %d is shorter than the prefix of the pod pool %q", a.podPrefixLength, spec.PodCidrBlock)
+		}
+		a.podPool = podPool
+	}
+
+	poolPrefixLength, _ := pool.Mask.Size()
+	switch {
+	case a.vpcPrefixLength < minPrefixLength || a.vpcPrefixLength > maxPrefixLength:
+		return nil, errors.Errorf("invalid ipam vpc prefix length %d, must be between %d and %d", a.vpcPrefixLength, minPrefixLength, maxPrefixLength)
+	case a.subnetPrefixLength < minPrefixLength || a.subnetPrefixLength > maxPrefixLength:
+		return nil, errors.Errorf("invalid ipam subnet prefix length %d, must be between %d and %d", a.subnetPrefixLength, minPrefixLength, maxPrefixLength)
+	case a.vpcPrefixLength < poolPrefixLength:
+		return nil, errors.Errorf("ipam vpc prefix length %d is shorter than the prefix of the pool %q", a.vpcPrefixLength, spec.CidrBlock)
+	}
+
+	return a, nil
+}
+
+// AllocateVPC implements Allocator. It returns the first block of the pool
+// not overlapping the CIDR blocks of the VPCs of the region, nor the pod CIDR
+// blocks of their clusters.
+func (a *poolAllocator) AllocateVPC(cluster Cluster) (string, error) {
+	used, err := a.usedBlocks()
+	if err != nil {
+		return "", err
+	}
+
+	blocks, err := allocate(a.pool, a.vpcPrefixLength, used, 1)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to allocate vpc cidr block from pool %q", a.pool)
+	}
+	return blocks[0], nil
+}
+
+// AllocatePods implements Allocator. It returns the first block of the pod
+// pool not overlapping the CIDR blocks of the VPCs of the region, nor the pod
+// CIDR blocks of their clusters, or nothing without a pod pool.
+func (a *poolAllocator) AllocatePods(cluster Cluster) (string, error) {
+	if a.podPool == nil {
+		return "", nil
+	}
+
+	used, err := a.usedBlocks()
+	if err != nil {
+		return "", err
+	}
+
+	blocks, err := allocate(a.podPool, a.podPrefixLength, used, 1)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to allocate pod cidr block from pool %q", a.podPool)
+	}
+	return blocks[0], nil
+}
+
+// describeVpcsInput and describeVpcsOutput are the DescribeVpcs shapes with
+// the pagination parameters the vendored SDK does not know.
+type describeVpcsInput struct {
+	_ struct{} `type:"structure"`
+
+	MaxResults *int64  `type:"integer"`
+	NextToken  *string `type:"string"`
+}
+
+type describeVpcsOutput struct {
+	_ struct{} `type:"structure"`
+
+	NextToken *string    `locationName:"nextToken" type:"string"`
+	Vpcs      []*ec2.Vpc `locationName:"vpcSet" locationNameList:"item" type:"list"`
+}
+
+// usedBlocks returns the CIDR blocks of the VPCs of the region, and the pod
+// CIDR blocks recorded in their tags.
+func (a *poolAllocator) usedBlocks() ([]string, error) {
+	input := &describeVpcsInput{MaxResults: aws.Int64(describeVpcsPageSize)}
+
+	var used []string
+	for {
+		out := &describeVpcsOutput{}
+		if err := queryprotocol.Send(a.ec2, "DescribeVpcs", input, out); err != nil {
+			return nil, errors.Wrap(err, "failed to describe vpcs")
+		}
+
+		for _, vpc := range out.Vpcs {
+			used = append(used, aws.StringValue(vpc.CidrBlock))
+			for _, association := range vpc.CidrBlockAssociationSet {
+				used = append(used, aws.StringValue(association.CidrBlock))
+			}
+			for _, tag := range vpc.Tags {
+				if aws.StringValue(tag.Key) == tags.NameAWSProviderPodCidrBlock {
+					used = append(used, aws.StringValue(tag.Value))
+				}
+			}
+		}
+
+		if aws.StringValue(out.NextToken) == "" {
+			return used, nil
+		}
+		input.NextToken = out.NextToken
+	}
+}
+
+// AllocateSubnets implements Allocator.
+func (a *poolAllocator) AllocateSubnets(cluster Cluster, vpcCidrBlock string, used []string, count int) ([]string, error) {
+	_, vpc, err := net.ParseCIDR(vpcCidrBlock)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid vpc cidr block %q", vpcCidrBlock)
+	}
+
+	if vpcPrefixLength, _ := vpc.Mask.Size(); a.subnetPrefixLength < vpcPrefixLength {
+		return nil, errors.Errorf("ipam subnet prefix length %d is shorter than the prefix of the vpc %q", a.subnetPrefixLength, vpcCidrBlock)
+	}
+
+	blocks, err := allocate(vpc, a.subnetPrefixLength, used, count)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to allocate subnet cidr blocks from vpc %q", vpcCidrBlock)
+	}
+	return blocks, nil
+}
+
+// Release implements Allocator. The pool allocator keeps no state, the CIDR
+// block of a VPC is available again once the VPC is deleted.
+func (a *poolAllocator) Release(cluster Cluster) error {
+	return nil
+}
+
+// allocate returns the first count blocks with the given prefix length in
+// the pool which do not overlap any of the used CIDR blocks.
+func allocate(pool *net.IPNet, prefixLength int, used []string, count int) ([]string, error) {
+	var usedNets []*net.IPNet
+	for _, block := range used {
+		_, n, err := net.ParseCIDR(block)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid cidr block %q", block)
+		}
+		usedNets = append(usedNets, n)
+	}
+
+	poolPrefixLength, _ := pool.Mask.Size()
+	start := binary.BigEndian.Uint32(pool.IP.To4())
+	size := uint64(1) << uint(32-prefixLength)
+	candidates := uint64(1) << uint(prefixLength-poolPrefixLength)
+
+	var blocks []string
+	for i := uint64(0); i < candidates && len(blocks) < count; i++ {
+		ip := make(net.IP, net.IPv4len)
+		binary.BigEndian.PutUint32(ip, start+uint32(i*size))
+		candidate := &net.IPNet{IP: ip, Mask: net.CIDRMask(prefixLength, 32)}
+
+		free := true
+		for _, n := range usedNets {
+			if n.Contains(candidate.IP) || candidate.Contains(n.IP) {
+				free = false
+				break
+			}
+		}
+		if free {
+			blocks = append(blocks, candidate.String())
+			usedNets = append(usedNets, candidate)
+		}
+	}
+
+	if len(blocks) < count {
+		return nil, errors.Errorf("no free /%d cidr block left", prefixLength)
+	}
+	return blocks, nil
+}
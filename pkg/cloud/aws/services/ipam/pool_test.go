@@ -0,0 +1,221 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ipam
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/tags"
+)
+
+// newVPCsSession returns a session sending requests to a server listing the
+// given pages of VPCs, and a function to shut the server down.
+func newVPCsSession(t *testing.T, pages ...string) (*session.Session, func()) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil || r.Form.Get("Action") != "DescribeVpcs" || r.Form.Get("MaxResults") != "1000" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		page := 0
+		if token := r.Form.Get("NextToken"); token != "" {
+			page, _ = strconv.Atoi(token)
+		}
+		if page >= len(pages) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		var nextToken string
+		if page+1 < len(pages) {
+			nextToken = fmt.Sprintf("<nextToken>%d</nextToken>", page+1)
+		}
+		fmt.Fprintf(w, "<DescribeVpcsResponse><requestId>1</requestId><vpcSet>%s</vpcSet>%s</DescribeVpcsResponse>", pages[page], nextToken)
+	}))
+
+	sess, err := session.NewSession(aws.NewConfig().
+		WithRegion("us-east-1").
+		WithEndpoint(server.URL).
+		WithMaxRetries(0).
+		WithCredentials(credentials.NewStaticCredentials("id", "secret", "")))
+	if err != nil {
+		server.Close()
+		t.Fatalf("failed to create session: %v", err)
+	}
+	return sess, server.Close
+}
+
+func TestPoolAllocateVPC(t *testing.T) {
+	// The first blocks of the pool overlap existing VPCs, including the
+	// secondary CIDR block of one of them, listed on a second page.
+	sess, closeServer := newVPCsSession(t,
+		`<item><cidrBlock>10.0.0.0/16</cidrBlock></item>`,
+		`<item><cidrBlock>172.31.0.0/16</cidrBlock><cidrBlockAssociationSet>
+<item><cidrBlock>172.31.0.0/16</cidrBlock></item>
+<item><cidrBlock>10.1.128.0/17</cidrBlock></item>
+</cidrBlockAssociationSet></item>`)
+	defer closeServer()
+
+	allocator, err := NewAllocator(&v1alpha1.IPAM{Pool: &v1alpha1.IPAMPool{CidrBlock: "10.0.0.0/8"}}, sess)
+	if err != nil {
+		t.Fatalf("failed to create allocator: %v", err)
+	}
+
+	cidr, err := allocator.AllocateVPC(Cluster{Name: "test-cluster"})
+	if err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+	if cidr != "10.2.0.0/16" {
+		t.Fatalf("expected vpc cidr block 10.2.0.0/16, got %q", cidr)
+	}
+}
+
+func TestPoolAllocatePods(t *testing.T) {
+	// The first block of the pod pool is recorded as the pod CIDR block of
+	// the cluster of a VPC, and the second one overlaps a VPC.
+	sess, closeServer := newVPCsSession(t, fmt.Sprintf(`<item><cidrBlock>10.0.0.0/16</cidrBlock><tagSet>
+<item><key>%s</key><value>100.64.0.0/16</value></item>
+</tagSet></item>
+<item><cidrBlock>100.65.0.0/16</cidrBlock></item>`, tags.NameAWSProviderPodCidrBlock))
+	defer closeServer()
+
+	allocator, err := NewAllocator(&v1alpha1.IPAM{Pool: &v1alpha1.IPAMPool{CidrBlock: "10.0.0.0/8", PodCidrBlock: "100.64.0.0/10"}}, sess)
+	if err != nil {
+		t.Fatalf("failed to create allocator: %v", err)
+	}
+
+	cidr, err := allocator.AllocatePods(Cluster{Name: "test-cluster"})
+	if err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+	if cidr != "100.66.0.0/16" {
+		t.Fatalf("expected pod cidr block 100.66.0.0/16, got %q", cidr)
+	}
+
+	// Pod CIDR blocks are not allocated without a pod pool.
+	allocator, err = NewAllocator(&v1alpha1.IPAM{Pool: &v1alpha1.IPAMPool{CidrBlock: "10.0.0.0/8"}}, nil)
+	if err != nil {
+		t.Fatalf("failed to create allocator: %v", err)
+	}
+	if cidr, err := allocator.AllocatePods(Cluster{Name: "test-cluster"}); cidr != "" || err != nil {
+		t.Fatalf("expected no pod cidr block, got %q, %v", cidr, err)
+	}
+}
+
+func TestPoolAllocateSubnets(t *testing.T) {
+	testCases := []struct {
+		name           string
+		prefixLength   int
+		vpc            string
+		used           []string
+		count          int
+		expectedBlocks []string
+		expectError    bool
+	}{
+		{
+			name:           "empty vpc",
+			vpc:            "10.2.0.0/16",
+			count:          2,
+			expectedBlocks: []string{"10.2.0.0/24", "10.2.1.0/24"},
+		},
+		{
+			name:           "used blocks are skipped",
+			vpc:            "10.2.0.0/16",
+			used:           []string{"10.2.0.0/24", "10.2.2.0/23"},
+			count:          2,
+			expectedBlocks: []string{"10.2.1.0/24", "10.2.4.0/24"},
+		},
+		{
+			name:           "prefix length",
+			prefixLength:   20,
+			vpc:            "10.2.0.0/16",
+			count:          1,
+			expectedBlocks: []string{"10.2.0.0/20"},
+		},
+		{
+			name:         "vpc exhausted",
+			prefixLength: 17,
+			vpc:          "10.2.0.0/16",
+			used:         []string{"10.2.0.0/24"},
+			count:        2,
+			expectError:  true,
+		},
+		{
+			name:        "subnets larger than the vpc",
+			vpc:         "10.2.0.0/25",
+			count:       1,
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			allocator, err := NewPoolAllocator(&v1alpha1.IPAMPool{CidrBlock: "10.0.0.0/8", SubnetPrefixLength: tc.prefixLength}, nil)
+			if err != nil {
+				t.Fatalf("failed to create allocator: %v", err)
+			}
+
+			blocks, err := allocator.AllocateSubnets(Cluster{Name: "test-cluster"}, tc.vpc, tc.used, tc.count)
+			if tc.expectError {
+				if err == nil {
+					t.Fatalf("expected error, got %v", blocks)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("did not expect error: %v", err)
+			}
+
+			if !reflect.DeepEqual(blocks, tc.expectedBlocks) {
+				t.Fatalf("expected cidr blocks %v, got %v", tc.expectedBlocks, blocks)
+			}
+		})
+	}
+}
+
+func TestNewPoolAllocator(t *testing.T) {
+	testCases := []struct {
+		name string
+		spec *v1alpha1.IPAMPool
+	}{
+		{name: "invalid pool", spec: &v1alpha1.IPAMPool{CidrBlock: "10.0.0.0"}},
+		{name: "ipv6 pool", spec: &v1alpha1.IPAMPool{CidrBlock: "2001:db8::/32"}},
+		{name: "vpc prefix too long", spec: &v1alpha1.IPAMPool{CidrBlock: "10.0.0.0/8", VPCPrefixLength: 29}},
+		{name: "vpc prefix shorter than the pool", spec: &v1alpha1.IPAMPool{CidrBlock: "10.0.0.0/20", VPCPrefixLength: 16}},
+		{name: "subnet prefix too short", spec: &v1alpha1.IPAMPool{CidrBlock: "10.0.0.0/8", SubnetPrefixLength: 12}},
+		{name: "invalid pod pool", spec: &v1alpha1.IPAMPool{CidrBlock: "10.0.0.0/8", PodCidrBlock: "100.64.0.0"}},
+		{name: "pod prefix too short", spec: &v1alpha1.IPAMPool{CidrBlock: "10.0.0.0/8", PodCidrBlock: "100.64.0.0/10", PodPrefixLength: 4}},
+		{name: "pod prefix shorter than the pod pool", spec: &v1alpha1.IPAMPool{CidrBlock: "10.0.0.0/8", PodCidrBlock: "100.64.0.0/16", PodPrefixLength: 12}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := NewPoolAllocator(tc.spec, nil); err == nil {
+				t.Fatalf("expected error")
+			}
+		})
+	}
+}
@@ -0,0 +1,217 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ipam
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/webhook"
+)
+
+// defaultWebhookTimeout bounds the duration of the requests to the IPAM service.
+const defaultWebhookTimeout = 30 * time.Second
+
+// Actions and kinds of the requests sent to IPAM services.
+const (
+	webhookActionAllocate = "allocate"
+	webhookActionRelease  = "release"
+
+	webhookKindVPC    = "vpc"
+	webhookKindSubnet = "subnet"
+	webhookKindPods   = "pods"
+)
+
+// webhookClient returns the HTTP client posting to IPAM services, and
+// validateWebhookURL validates their URLs. Both refuse non-public addresses,
+// and are only replaced in tests.
+var (
+	webhookClient      = webhook.NewClient
+	validateWebhookURL = webhook.ValidateURL
+)
+
+// webhookAllocator allocates CIDR blocks with an external IPAM service.
+type webhookAllocator struct {
+	client *http.Client
+	url    string
+}
+
+// webhookRequest is the body of the requests sent to IPAM services.
+type webhookRequest struct {
+	Cluster
+
+	// Action is either allocate or release.
+	Action string `json:"action"`
+
+	// Kind is the kind of the allocated CIDR blocks, vpc, subnet or pods.
+	Kind string `json:"kind,omitempty"`
+
+	// Count is the number of CIDR blocks to allocate.
+	Count int `json:"count,omitempty"`
+
+	// VPCCidrBlock is the CIDR block of the VPC subnets are allocated in.
+	VPCCidrBlock string `json:"vpcCidrBlock,omitempty"`
+
+	// UsedCidrBlocks are the CIDR blocks of the existing subnets of the VPC.
+	UsedCidrBlocks []string `json:"usedCidrBlocks,omitempty"`
+}
+
+// webhookResponse is the body of the responses to allocate requests.
+type webhookResponse struct {
+	CidrBlocks []string `json:"cidrBlocks"`
+}
+
+// NewWebhookAllocator returns an allocator delegating to an IPAM service.
+func NewWebhookAllocator(spec *v1alpha1.IPAMWebhook) (Allocator, error) {
+	if err := validateWebhookURL(spec.URL); err != nil {
+		return nil, errors.Wrap(err, "invalid ipam webhook")
+	}
+
+	timeout := defaultWebhookTimeout
+	if spec.Timeout != nil {
+		timeout = spec.Timeout.Duration
+	}
+
+	return &webhookAllocator{
+		client: webhookClient(timeout),
+		url:    spec.URL,
+	}, nil
+}
+
+// AllocateVPC implements Allocator.
+func (w *webhookAllocator) AllocateVPC(cluster Cluster) (string, error) {
+	blocks, err := w.allocate(&webhookRequest{
+		Cluster: cluster,
+		Action:  webhookActionAllocate,
+		Kind:    webhookKindVPC,
+		Count:   1,
+	}, nil)
+	if err != nil {
+		return "", err
+	}
+	return blocks[0], nil
+}
+
+// AllocateSubnets implements Allocator. The CIDR blocks returned by the
+// service must be in the VPC CIDR block.
+func (w *webhookAllocator) AllocateSubnets(cluster Cluster, vpcCidrBlock string, used []string, count int) ([]string, error) {
+	_, vpc, err := net.ParseCIDR(vpcCidrBlock)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid vpc cidr block %q", vpcCidrBlock)
+	}
+
+	return w.allocate(&webhookRequest{
+		Cluster:        cluster,
+		Action:         webhookActionAllocate,
+		Kind:           webhookKindSubnet,
+		Count:          count,
+		VPCCidrBlock:   vpcCidrBlock,
+		UsedCidrBlocks: used,
+	}, vpc)
+}
+
+// AllocatePods implements Allocator.
+func (w *webhookAllocator) AllocatePods(cluster Cluster) (string, error) {
+	blocks, err := w.allocate(&webhookRequest{
+		Cluster: cluster,
+		Action:  webhookActionAllocate,
+		Kind:    webhookKindPods,
+		Count:   1,
+	}, nil)
+	if err != nil {
+		return "", err
+	}
+	return blocks[0], nil
+}
+
+// Release implements Allocator.
+func (w *webhookAllocator) Release(cluster Cluster) error {
+	return w.do(&webhookRequest{Cluster: cluster, Action: webhookActionRelease}, nil)
+}
+
+// allocate sends an allocate request and validates the returned CIDR blocks,
+// which must be within parent if not nil.
+func (w *webhookAllocator) allocate(req *webhookRequest, parent *net.IPNet) ([]string, error) {
+	resp := &webhookResponse{}
+	if err := w.do(req, resp); err != nil {
+		return nil, err
+	}
+
+	if len(resp.CidrBlocks) != req.Count {
+		return nil, errors.Errorf("ipam webhook returned %d %s cidr blocks, expected %d", len(resp.CidrBlocks), req.Kind, req.Count)
+	}
+
+	for _, block := range resp.CidrBlocks {
+		ip, n, err := net.ParseCIDR(block)
+		if err != nil || ip.To4() == nil {
+			return nil, errors.Errorf("ipam webhook returned invalid %s cidr block %q", req.Kind, block)
+		}
+		if parent != nil {
+			if prefixLength, _ := n.Mask.Size(); !parent.Contains(n.IP) || prefixLength < maskSize(parent) {
+				return nil, errors.Errorf("ipam webhook returned %s cidr block %q outside of %q", req.Kind, block, parent)
+			}
+		}
+	}
+
+	return resp.CidrBlocks, nil
+}
+
+// do POSTs a request to the IPAM service, and decodes the response into out
+// if not nil.
+func (w *webhookAllocator) do(req *webhookRequest, out interface{}) error {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return errors.Wrap(err, "failed to encode ipam webhook request")
+	}
+
+	resp, err := w.client.Post(w.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrapf(err, "failed to send ipam webhook %s request", req.Action)
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return errors.Wrap(err, "failed to read ipam webhook response")
+	}
+
+	if resp.StatusCode >= 300 {
+		return errors.Errorf("ipam webhook %s request failed with status %d: %s", req.Action, resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+
+	if out == nil {
+		return nil
+	}
+
+	if err := json.Unmarshal(data, out); err != nil {
+		return errors.Wrap(err, "failed to decode ipam webhook response")
+	}
+	return nil
+}
+
+// maskSize returns the prefix length of a CIDR block.
+func maskSize(n *net.IPNet) int {
+	ones, _ := n.Mask.Size()
+	return ones
+}
@@ -0,0 +1,153 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ipam
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+)
+
+// allowTestServer lets the allocators reach a local test server, which the
+// webhook client refuses otherwise.
+func allowTestServer(t *testing.T, server *httptest.Server) func() {
+	client, validate := webhookClient, validateWebhookURL
+	webhookClient = func(time.Duration) *http.Client { return server.Client() }
+	validateWebhookURL = func(string) error { return nil }
+	return func() {
+		webhookClient, validateWebhookURL = client, validate
+	}
+}
+
+func TestWebhookAllocator(t *testing.T) {
+	var requests []webhookRequest
+	responses := map[string]webhookResponse{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := webhookRequest{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		requests = append(requests, req)
+
+		if req.Action == webhookActionRelease {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		json.NewEncoder(w).Encode(responses[req.Kind])
+	}))
+	defer server.Close()
+	defer allowTestServer(t, server)()
+
+	allocator, err := NewAllocator(&v1alpha1.IPAM{Webhook: &v1alpha1.IPAMWebhook{URL: server.URL}}, nil)
+	if err != nil {
+		t.Fatalf("failed to create allocator: %v", err)
+	}
+
+	cluster := Cluster{Name: "test-cluster", Namespace: "default", Region: "us-east-1"}
+
+	responses[webhookKindVPC] = webhookResponse{CidrBlocks: []string{"10.42.0.0/16"}}
+	cidr, err := allocator.AllocateVPC(cluster)
+	if err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+	if cidr != "10.42.0.0/16" {
+		t.Fatalf("expected vpc cidr block 10.42.0.0/16, got %q", cidr)
+	}
+
+	responses[webhookKindSubnet] = webhookResponse{CidrBlocks: []string{"10.42.1.0/24", "10.42.2.0/24"}}
+	blocks, err := allocator.AllocateSubnets(cluster, "10.42.0.0/16", []string{"10.42.0.0/24"}, 2)
+	if err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+	if !reflect.DeepEqual(blocks, responses[webhookKindSubnet].CidrBlocks) {
+		t.Fatalf("expected subnet cidr blocks %v, got %v", responses[webhookKindSubnet].CidrBlocks, blocks)
+	}
+
+	// Blocks outside of the VPC, or fewer blocks than requested, are rejected.
+	responses[webhookKindSubnet] = webhookResponse{CidrBlocks: []string{"10.43.1.0/24"}}
+	if _, err := allocator.AllocateSubnets(cluster, "10.42.0.0/16", nil, 1); err == nil {
+		t.Fatalf("expected error for a subnet outside of the vpc")
+	}
+	if _, err := allocator.AllocateSubnets(cluster, "10.42.0.0/16", nil, 2); err == nil {
+		t.Fatalf("expected error for missing subnets")
+	}
+
+	responses[webhookKindPods] = webhookResponse{CidrBlocks: []string{"100.64.0.0/16"}}
+	pods, err := allocator.AllocatePods(cluster)
+	if err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+	if pods != "100.64.0.0/16" {
+		t.Fatalf("expected pod cidr block 100.64.0.0/16, got %q", pods)
+	}
+
+	if err := allocator.Release(cluster); err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+
+	expected := []webhookRequest{
+		{Cluster: cluster, Action: webhookActionAllocate, Kind: webhookKindVPC, Count: 1},
+		{Cluster: cluster, Action: webhookActionAllocate, Kind: webhookKindSubnet, Count: 2, VPCCidrBlock: "10.42.0.0/16", UsedCidrBlocks: []string{"10.42.0.0/24"}},
+		{Cluster: cluster, Action: webhookActionAllocate, Kind: webhookKindSubnet, Count: 1, VPCCidrBlock: "10.42.0.0/16"},
+		{Cluster: cluster, Action: webhookActionAllocate, Kind: webhookKindSubnet, Count: 2, VPCCidrBlock: "10.42.0.0/16"},
+		{Cluster: cluster, Action: webhookActionAllocate, Kind: webhookKindPods, Count: 1},
+		{Cluster: cluster, Action: webhookActionRelease},
+	}
+	if !reflect.DeepEqual(requests, expected) {
+		t.Fatalf("expected requests %+v, got %+v", expected, requests)
+	}
+}
+
+func TestWebhookAllocatorError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "pool exhausted", http.StatusConflict)
+	}))
+	defer server.Close()
+	defer allowTestServer(t, server)()
+
+	allocator, err := NewWebhookAllocator(&v1alpha1.IPAMWebhook{URL: server.URL})
+	if err != nil {
+		t.Fatalf("failed to create allocator: %v", err)
+	}
+
+	if _, err := allocator.AllocateVPC(Cluster{Name: "test-cluster"}); err == nil {
+		t.Fatalf("expected error")
+	}
+}
+
+func TestNewWebhookAllocatorURL(t *testing.T) {
+	for _, url := range []string{
+		"http://ipam.example.com/allocate",
+		"https://169.254.169.254/latest/meta-data",
+		"https://10.0.0.1/allocate",
+		"ipam.example.com",
+	} {
+		if _, err := NewWebhookAllocator(&v1alpha1.IPAMWebhook{URL: url}); err == nil {
+			t.Errorf("expected url %q to be refused", url)
+		}
+	}
+
+	if _, err := NewWebhookAllocator(&v1alpha1.IPAMWebhook{URL: "https://ipam.example.com/allocate"}); err != nil {
+		t.Errorf("did not expect error: %v", err)
+	}
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package jsonprotocol sends requests to the AWS services speaking the JSON
+// protocol, for which the vendored SDK has no client.
+package jsonprotocol
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/client/metadata"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/aws/signer/v4"
+)
+
+// NewClient returns a generic SDK client speaking the JSON protocol of the
+// given service, in the region of the session. Requests still go through the
+// handlers of the session, for signing, retries and rate limiting.
+//
+// The endpoint of the client is empty if it cannot be resolved, in which
+// case requests fail with a missing endpoint error.
+func NewClient(sess *session.Session, serviceName, apiVersion, targetPrefix string) *client.Client {
+	cfg := sess.ClientConfig(serviceName)
+
+	c := client.New(*cfg.Config, metadata.ClientInfo{
+		ServiceName:   serviceName,
+		SigningName:   cfg.SigningName,
+		SigningRegion: cfg.SigningRegion,
+		Endpoint:      cfg.Endpoint,
+		APIVersion:    apiVersion,
+		JSONVersion:   "1.1",
+		TargetPrefix:  targetPrefix,
+	}, cfg.Handlers)
+
+	c.Handlers.Sign.PushBackNamed(v4.SignRequestHandler)
+	c.Handlers.Build.PushBackNamed(request.NamedHandler{Name: "jsonprotocol.Build", Fn: build})
+	c.Handlers.Unmarshal.PushBackNamed(request.NamedHandler{Name: "jsonprotocol.Unmarshal", Fn: unmarshal})
+	c.Handlers.UnmarshalError.PushBackNamed(request.NamedHandler{Name: "jsonprotocol.UnmarshalError", Fn: unmarshalError})
+
+	return c
+}
+
+// Send sends a request for the given operation and decodes its response into out if not nil.
+func Send(c *client.Client, operation string, in, out interface{}) error {
+	op := &request.Operation{
+		Name:       operation,
+		HTTPMethod: "POST",
+		HTTPPath:   "/",
+	}
+	return c.NewRequest(op, in, out).Send()
+}
+
+// build encodes the parameters of a request with the JSON protocol.
+func build(r *request.Request) {
+	body, err := json.Marshal(r.Params)
+	if err != nil {
+		r.Error = awserr.New(request.ErrCodeSerialization, "failed to encode request", err)
+		return
+	}
+
+	r.SetBufferBody(body)
+	r.HTTPRequest.Header.Set("Content-Type", "application/x-amz-json-"+r.ClientInfo.JSONVersion)
+	r.HTTPRequest.Header.Set("X-Amz-Target", r.ClientInfo.TargetPrefix+"."+r.Operation.Name)
+}
+
+// unmarshal decodes the body of a successful response into the request data.
+func unmarshal(r *request.Request) {
+	defer r.HTTPResponse.Body.Close()
+	if r.Data == nil {
+		return
+	}
+
+	if err := json.NewDecoder(r.HTTPResponse.Body).Decode(r.Data); err != nil {
+		r.Error = awserr.New(request.ErrCodeSerialization, "failed to decode response", err)
+	}
+}
+
+// unmarshalError decodes the body of a failed response into an error
+// carrying the code and message returned by the service.
+func unmarshalError(r *request.Request) {
+	defer r.HTTPResponse.Body.Close()
+
+	body := struct {
+		Type    string `json:"__type"`
+		Message string `json:"message"`
+	}{}
+	if err := json.NewDecoder(r.HTTPResponse.Body).Decode(&body); err != nil {
+		r.Error = awserr.New(request.ErrCodeSerialization, "failed to decode error response", err)
+		return
+	}
+
+	// Codes may be qualified with the namespace of the service, e.g.
+	// "com.amazonaws.secretsmanager#ResourceNotFoundException".
+	code := body.Type[strings.LastIndex(body.Type, "#")+1:]
+	r.Error = awserr.NewRequestFailure(awserr.New(code, body.Message, nil), r.HTTPResponse.StatusCode, r.RequestID)
+}
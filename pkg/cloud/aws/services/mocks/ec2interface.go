@@ -27,6 +27,7 @@ import (
 	reflect "reflect"
 	v1alpha1 "sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
 	actuators "sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/actuators"
+	time "time"
 )
 
 // MockEC2Interface is a mock of EC2Interface interface
@@ -52,6 +53,57 @@ func (m *MockEC2Interface) EXPECT() *MockEC2InterfaceMockRecorder {
 	return m.recorder
 }
 
+// AssociateAPIServerAddress mocks base method
+func (m *MockEC2Interface) AssociateAPIServerAddress(arg0 *actuators.MachineScope, arg1 string) error {
+	ret := m.ctrl.Call(m, "AssociateAPIServerAddress", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AssociateAPIServerAddress indicates an expected call of AssociateAPIServerAddress
+func (mr *MockEC2InterfaceMockRecorder) AssociateAPIServerAddress(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AssociateAPIServerAddress", reflect.TypeOf((*MockEC2Interface)(nil).AssociateAPIServerAddress), arg0, arg1)
+}
+
+// AssociateMachineAddress mocks base method
+func (m *MockEC2Interface) AssociateMachineAddress(arg0 *actuators.MachineScope, arg1 string) error {
+	ret := m.ctrl.Call(m, "AssociateMachineAddress", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AssociateMachineAddress indicates an expected call of AssociateMachineAddress
+func (mr *MockEC2InterfaceMockRecorder) AssociateMachineAddress(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AssociateMachineAddress", reflect.TypeOf((*MockEC2Interface)(nil).AssociateMachineAddress), arg0, arg1)
+}
+
+// ControlPlaneZones mocks base method
+func (m *MockEC2Interface) ControlPlaneZones() (map[string]int, error) {
+	ret := m.ctrl.Call(m, "ControlPlaneZones")
+	ret0, _ := ret[0].(map[string]int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ControlPlaneZones indicates an expected call of ControlPlaneZones
+func (mr *MockEC2InterfaceMockRecorder) ControlPlaneZones() *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ControlPlaneZones", reflect.TypeOf((*MockEC2Interface)(nil).ControlPlaneZones))
+}
+
+// CreateMachinePoolLaunchTemplateVersion mocks base method
+func (m *MockEC2Interface) CreateMachinePoolLaunchTemplateVersion(arg0 *v1alpha1.AWSMachinePool, arg1 string) (string, int64, error) {
+	ret := m.ctrl.Call(m, "CreateMachinePoolLaunchTemplateVersion", arg0, arg1)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(int64)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// CreateMachinePoolLaunchTemplateVersion indicates an expected call of CreateMachinePoolLaunchTemplateVersion
+func (mr *MockEC2InterfaceMockRecorder) CreateMachinePoolLaunchTemplateVersion(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateMachinePoolLaunchTemplateVersion", reflect.TypeOf((*MockEC2Interface)(nil).CreateMachinePoolLaunchTemplateVersion), arg0, arg1)
+}
+
 // CreateOrGetMachine mocks base method
 func (m *MockEC2Interface) CreateOrGetMachine(arg0 *actuators.MachineScope, arg1, arg2 string) (*v1alpha1.Instance, error) {
 	ret := m.ctrl.Call(m, "CreateOrGetMachine", arg0, arg1, arg2)
@@ -77,6 +129,30 @@ func (mr *MockEC2InterfaceMockRecorder) DeleteBastion() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteBastion", reflect.TypeOf((*MockEC2Interface)(nil).DeleteBastion))
 }
 
+// DeleteImages mocks base method
+func (m *MockEC2Interface) DeleteImages() error {
+	ret := m.ctrl.Call(m, "DeleteImages")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteImages indicates an expected call of DeleteImages
+func (mr *MockEC2InterfaceMockRecorder) DeleteImages() *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteImages", reflect.TypeOf((*MockEC2Interface)(nil).DeleteImages))
+}
+
+// DeleteMachinePoolLaunchTemplate mocks base method
+func (m *MockEC2Interface) DeleteMachinePoolLaunchTemplate(arg0 *v1alpha1.AWSMachinePool) error {
+	ret := m.ctrl.Call(m, "DeleteMachinePoolLaunchTemplate", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteMachinePoolLaunchTemplate indicates an expected call of DeleteMachinePoolLaunchTemplate
+func (mr *MockEC2InterfaceMockRecorder) DeleteMachinePoolLaunchTemplate(arg0 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteMachinePoolLaunchTemplate", reflect.TypeOf((*MockEC2Interface)(nil).DeleteMachinePoolLaunchTemplate), arg0)
+}
+
 // DeleteNetwork mocks base method
 func (m *MockEC2Interface) DeleteNetwork() error {
 	ret := m.ctrl.Call(m, "DeleteNetwork")
@@ -89,6 +165,95 @@ func (mr *MockEC2InterfaceMockRecorder) DeleteNetwork() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteNetwork", reflect.TypeOf((*MockEC2Interface)(nil).DeleteNetwork))
 }
 
+// DeletePlacementGroups mocks base method
+func (m *MockEC2Interface) DeletePlacementGroups() error {
+	ret := m.ctrl.Call(m, "DeletePlacementGroups")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeletePlacementGroups indicates an expected call of DeletePlacementGroups
+func (mr *MockEC2InterfaceMockRecorder) DeletePlacementGroups() *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeletePlacementGroups", reflect.TypeOf((*MockEC2Interface)(nil).DeletePlacementGroups))
+}
+
+// DeleteStoppedInstances mocks base method
+func (m *MockEC2Interface) DeleteStoppedInstances() error {
+	ret := m.ctrl.Call(m, "DeleteStoppedInstances")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteStoppedInstances indicates an expected call of DeleteStoppedInstances
+func (mr *MockEC2InterfaceMockRecorder) DeleteStoppedInstances() *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteStoppedInstances", reflect.TypeOf((*MockEC2Interface)(nil).DeleteStoppedInstances))
+}
+
+// GetConsoleOutput mocks base method
+func (m *MockEC2Interface) GetConsoleOutput(arg0 string) (string, error) {
+	ret := m.ctrl.Call(m, "GetConsoleOutput", arg0)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetConsoleOutput indicates an expected call of GetConsoleOutput
+func (mr *MockEC2InterfaceMockRecorder) GetConsoleOutput(arg0 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetConsoleOutput", reflect.TypeOf((*MockEC2Interface)(nil).GetConsoleOutput), arg0)
+}
+
+// ImageCreationDate mocks base method
+func (m *MockEC2Interface) ImageCreationDate(arg0 string) (time.Time, error) {
+	ret := m.ctrl.Call(m, "ImageCreationDate", arg0)
+	ret0, _ := ret[0].(time.Time)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ImageCreationDate indicates an expected call of ImageCreationDate
+func (mr *MockEC2InterfaceMockRecorder) ImageCreationDate(arg0 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ImageCreationDate", reflect.TypeOf((*MockEC2Interface)(nil).ImageCreationDate), arg0)
+}
+
+// InstanceByTags mocks base method
+func (m *MockEC2Interface) InstanceByTags(arg0 *actuators.MachineScope) (*v1alpha1.Instance, error) {
+	ret := m.ctrl.Call(m, "InstanceByTags", arg0)
+	ret0, _ := ret[0].(*v1alpha1.Instance)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// InstanceByTags indicates an expected call of InstanceByTags
+func (mr *MockEC2InterfaceMockRecorder) InstanceByTags(arg0 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InstanceByTags", reflect.TypeOf((*MockEC2Interface)(nil).InstanceByTags), arg0)
+}
+
+// InstanceEvents mocks base method
+func (m *MockEC2Interface) InstanceEvents(arg0 string) ([]v1alpha1.InstanceEvent, error) {
+	ret := m.ctrl.Call(m, "InstanceEvents", arg0)
+	ret0, _ := ret[0].([]v1alpha1.InstanceEvent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// InstanceEvents indicates an expected call of InstanceEvents
+func (mr *MockEC2InterfaceMockRecorder) InstanceEvents(arg0 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InstanceEvents", reflect.TypeOf((*MockEC2Interface)(nil).InstanceEvents), arg0)
+}
+
+// DeletedInstanceIfExists mocks base method
+func (m *MockEC2Interface) DeletedInstanceIfExists(arg0 string) (*v1alpha1.Instance, error) {
+	ret := m.ctrl.Call(m, "DeletedInstanceIfExists", arg0)
+	ret0, _ := ret[0].(*v1alpha1.Instance)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeletedInstanceIfExists indicates an expected call of DeletedInstanceIfExists
+func (mr *MockEC2InterfaceMockRecorder) DeletedInstanceIfExists(arg0 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeletedInstanceIfExists", reflect.TypeOf((*MockEC2Interface)(nil).DeletedInstanceIfExists), arg0)
+}
+
 // InstanceIfExists mocks base method
 func (m *MockEC2Interface) InstanceIfExists(arg0 string) (*v1alpha1.Instance, error) {
 	ret := m.ctrl.Call(m, "InstanceIfExists", arg0)
@@ -102,6 +267,95 @@ func (mr *MockEC2InterfaceMockRecorder) InstanceIfExists(arg0 interface{}) *gomo
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InstanceIfExists", reflect.TypeOf((*MockEC2Interface)(nil).InstanceIfExists), arg0)
 }
 
+// InstancesIfExist mocks base method
+func (m *MockEC2Interface) InstancesIfExist(arg0 []string) (map[string]*v1alpha1.Instance, error) {
+	ret := m.ctrl.Call(m, "InstancesIfExist", arg0)
+	ret0, _ := ret[0].(map[string]*v1alpha1.Instance)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// InstancesIfExist indicates an expected call of InstancesIfExist
+func (mr *MockEC2InterfaceMockRecorder) InstancesIfExist(arg0 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InstancesIfExist", reflect.TypeOf((*MockEC2Interface)(nil).InstancesIfExist), arg0)
+}
+
+// MachineExists mocks base method
+func (m *MockEC2Interface) MachineExists(arg0 *actuators.MachineScope) (bool, error) {
+	ret := m.ctrl.Call(m, "MachineExists", arg0)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MachineExists indicates an expected call of MachineExists
+func (mr *MockEC2InterfaceMockRecorder) MachineExists(arg0 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MachineExists", reflect.TypeOf((*MockEC2Interface)(nil).MachineExists), arg0)
+}
+
+// MachinePoolSubnets mocks base method
+func (m *MockEC2Interface) MachinePoolSubnets(arg0 *v1alpha1.AWSMachinePool) ([]string, error) {
+	ret := m.ctrl.Call(m, "MachinePoolSubnets", arg0)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MachinePoolSubnets indicates an expected call of MachinePoolSubnets
+func (mr *MockEC2InterfaceMockRecorder) MachinePoolSubnets(arg0 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MachinePoolSubnets", reflect.TypeOf((*MockEC2Interface)(nil).MachinePoolSubnets), arg0)
+}
+
+// ModifyInstanceCreditSpecification mocks base method
+func (m *MockEC2Interface) ModifyInstanceCreditSpecification(arg0 string, arg1 v1alpha1.CPUCredits) (bool, error) {
+	ret := m.ctrl.Call(m, "ModifyInstanceCreditSpecification", arg0, arg1)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ModifyInstanceCreditSpecification indicates an expected call of ModifyInstanceCreditSpecification
+func (mr *MockEC2InterfaceMockRecorder) ModifyInstanceCreditSpecification(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ModifyInstanceCreditSpecification", reflect.TypeOf((*MockEC2Interface)(nil).ModifyInstanceCreditSpecification), arg0, arg1)
+}
+
+// ModifyInstanceVolumes mocks base method
+func (m *MockEC2Interface) ModifyInstanceVolumes(arg0 string, arg1 *v1alpha1.RootVolume, arg2 []v1alpha1.Volume) ([]string, error) {
+	ret := m.ctrl.Call(m, "ModifyInstanceVolumes", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ModifyInstanceVolumes indicates an expected call of ModifyInstanceVolumes
+func (mr *MockEC2InterfaceMockRecorder) ModifyInstanceVolumes(arg0, arg1, arg2 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ModifyInstanceVolumes", reflect.TypeOf((*MockEC2Interface)(nil).ModifyInstanceVolumes), arg0, arg1, arg2)
+}
+
+// RebootInstance mocks base method
+func (m *MockEC2Interface) RebootInstance(arg0 string) error {
+	ret := m.ctrl.Call(m, "RebootInstance", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RebootInstance indicates an expected call of RebootInstance
+func (mr *MockEC2InterfaceMockRecorder) RebootInstance(arg0 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RebootInstance", reflect.TypeOf((*MockEC2Interface)(nil).RebootInstance), arg0)
+}
+
+// ReconcileAPIServerAddress mocks base method
+func (m *MockEC2Interface) ReconcileAPIServerAddress() error {
+	ret := m.ctrl.Call(m, "ReconcileAPIServerAddress")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReconcileAPIServerAddress indicates an expected call of ReconcileAPIServerAddress
+func (mr *MockEC2InterfaceMockRecorder) ReconcileAPIServerAddress() *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReconcileAPIServerAddress", reflect.TypeOf((*MockEC2Interface)(nil).ReconcileAPIServerAddress))
+}
+
 // ReconcileBastion mocks base method
 func (m *MockEC2Interface) ReconcileBastion() error {
 	ret := m.ctrl.Call(m, "ReconcileBastion")
@@ -126,6 +380,116 @@ func (mr *MockEC2InterfaceMockRecorder) ReconcileNetwork() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReconcileNetwork", reflect.TypeOf((*MockEC2Interface)(nil).ReconcileNetwork))
 }
 
+// ReconcileReservedInstanceCoverage mocks base method
+func (m *MockEC2Interface) ReconcileReservedInstanceCoverage() error {
+	ret := m.ctrl.Call(m, "ReconcileReservedInstanceCoverage")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReconcileReservedInstanceCoverage indicates an expected call of ReconcileReservedInstanceCoverage
+func (mr *MockEC2InterfaceMockRecorder) ReconcileReservedInstanceCoverage() *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReconcileReservedInstanceCoverage", reflect.TypeOf((*MockEC2Interface)(nil).ReconcileReservedInstanceCoverage))
+}
+
+// ReleaseMachineAddress mocks base method
+func (m *MockEC2Interface) ReleaseMachineAddress(arg0 *actuators.MachineScope) error {
+	ret := m.ctrl.Call(m, "ReleaseMachineAddress", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReleaseMachineAddress indicates an expected call of ReleaseMachineAddress
+func (mr *MockEC2InterfaceMockRecorder) ReleaseMachineAddress(arg0 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReleaseMachineAddress", reflect.TypeOf((*MockEC2Interface)(nil).ReleaseMachineAddress), arg0)
+}
+
+// RunningControlPlaneInstance mocks base method
+func (m *MockEC2Interface) RunningControlPlaneInstance() (string, error) {
+	ret := m.ctrl.Call(m, "RunningControlPlaneInstance")
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RunningControlPlaneInstance indicates an expected call of RunningControlPlaneInstance
+func (mr *MockEC2InterfaceMockRecorder) RunningControlPlaneInstance() *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RunningControlPlaneInstance", reflect.TypeOf((*MockEC2Interface)(nil).RunningControlPlaneInstance))
+}
+
+// ScrubInstanceUserData mocks base method
+func (m *MockEC2Interface) ScrubInstanceUserData(arg0 string) error {
+	ret := m.ctrl.Call(m, "ScrubInstanceUserData", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ScrubInstanceUserData indicates an expected call of ScrubInstanceUserData
+func (mr *MockEC2InterfaceMockRecorder) ScrubInstanceUserData(arg0 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ScrubInstanceUserData", reflect.TypeOf((*MockEC2Interface)(nil).ScrubInstanceUserData), arg0)
+}
+
+// SetInstanceMonitoring mocks base method
+func (m *MockEC2Interface) SetInstanceMonitoring(arg0 string, arg1 bool) error {
+	ret := m.ctrl.Call(m, "SetInstanceMonitoring", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetInstanceMonitoring indicates an expected call of SetInstanceMonitoring
+func (mr *MockEC2InterfaceMockRecorder) SetInstanceMonitoring(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetInstanceMonitoring", reflect.TypeOf((*MockEC2Interface)(nil).SetInstanceMonitoring), arg0, arg1)
+}
+
+// SetTerminationProtection mocks base method
+func (m *MockEC2Interface) SetTerminationProtection(arg0 string, arg1 bool) error {
+	ret := m.ctrl.Call(m, "SetTerminationProtection", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetTerminationProtection indicates an expected call of SetTerminationProtection
+func (mr *MockEC2InterfaceMockRecorder) SetTerminationProtection(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetTerminationProtection", reflect.TypeOf((*MockEC2Interface)(nil).SetTerminationProtection), arg0, arg1)
+}
+
+// SpotMachinePools mocks base method
+func (m *MockEC2Interface) SpotMachinePools() ([]string, error) {
+	ret := m.ctrl.Call(m, "SpotMachinePools")
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SpotMachinePools indicates an expected call of SpotMachinePools
+func (mr *MockEC2InterfaceMockRecorder) SpotMachinePools() *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SpotMachinePools", reflect.TypeOf((*MockEC2Interface)(nil).SpotMachinePools))
+}
+
+// StartInstance mocks base method
+func (m *MockEC2Interface) StartInstance(arg0 string) error {
+	ret := m.ctrl.Call(m, "StartInstance", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// StartInstance indicates an expected call of StartInstance
+func (mr *MockEC2InterfaceMockRecorder) StartInstance(arg0 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StartInstance", reflect.TypeOf((*MockEC2Interface)(nil).StartInstance), arg0)
+}
+
+// StopInstance mocks base method
+func (m *MockEC2Interface) StopInstance(arg0 string) error {
+	ret := m.ctrl.Call(m, "StopInstance", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// StopInstance indicates an expected call of StopInstance
+func (mr *MockEC2InterfaceMockRecorder) StopInstance(arg0 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StopInstance", reflect.TypeOf((*MockEC2Interface)(nil).StopInstance), arg0)
+}
+
 // TerminateInstance mocks base method
 func (m *MockEC2Interface) TerminateInstance(arg0 string) error {
 	ret := m.ctrl.Call(m, "TerminateInstance", arg0)
@@ -138,6 +502,18 @@ func (mr *MockEC2InterfaceMockRecorder) TerminateInstance(arg0 interface{}) *gom
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TerminateInstance", reflect.TypeOf((*MockEC2Interface)(nil).TerminateInstance), arg0)
 }
 
+// TerminateInstances mocks base method
+func (m *MockEC2Interface) TerminateInstances(arg0 []string) error {
+	ret := m.ctrl.Call(m, "TerminateInstances", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// TerminateInstances indicates an expected call of TerminateInstances
+func (mr *MockEC2InterfaceMockRecorder) TerminateInstances(arg0 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TerminateInstances", reflect.TypeOf((*MockEC2Interface)(nil).TerminateInstances), arg0)
+}
+
 // UpdateInstanceSecurityGroups mocks base method
 func (m *MockEC2Interface) UpdateInstanceSecurityGroups(arg0 string, arg1 []string) error {
 	ret := m.ctrl.Call(m, "UpdateInstanceSecurityGroups", arg0, arg1)
@@ -62,6 +62,18 @@ func (mr *MockELBInterfaceMockRecorder) DeleteLoadbalancers() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteLoadbalancers", reflect.TypeOf((*MockELBInterface)(nil).DeleteLoadbalancers))
 }
 
+// DeregisterInstanceFromAPIServerELB mocks base method
+func (m *MockELBInterface) DeregisterInstanceFromAPIServerELB(arg0 string) error {
+	ret := m.ctrl.Call(m, "DeregisterInstanceFromAPIServerELB", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeregisterInstanceFromAPIServerELB indicates an expected call of DeregisterInstanceFromAPIServerELB
+func (mr *MockELBInterfaceMockRecorder) DeregisterInstanceFromAPIServerELB(arg0 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeregisterInstanceFromAPIServerELB", reflect.TypeOf((*MockELBInterface)(nil).DeregisterInstanceFromAPIServerELB), arg0)
+}
+
 // GetAPIServerDNSName mocks base method
 func (m *MockELBInterface) GetAPIServerDNSName() (string, error) {
 	ret := m.ctrl.Call(m, "GetAPIServerDNSName")
@@ -75,6 +87,19 @@ func (mr *MockELBInterfaceMockRecorder) GetAPIServerDNSName() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAPIServerDNSName", reflect.TypeOf((*MockELBInterface)(nil).GetAPIServerDNSName))
 }
 
+// InstanceDeregisteredFromAPIServerELB mocks base method
+func (m *MockELBInterface) InstanceDeregisteredFromAPIServerELB(arg0 string) (bool, error) {
+	ret := m.ctrl.Call(m, "InstanceDeregisteredFromAPIServerELB", arg0)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// InstanceDeregisteredFromAPIServerELB indicates an expected call of InstanceDeregisteredFromAPIServerELB
+func (mr *MockELBInterfaceMockRecorder) InstanceDeregisteredFromAPIServerELB(arg0 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InstanceDeregisteredFromAPIServerELB", reflect.TypeOf((*MockELBInterface)(nil).InstanceDeregisteredFromAPIServerELB), arg0)
+}
+
 // ReconcileLoadbalancers mocks base method
 func (m *MockELBInterface) ReconcileLoadbalancers() error {
 	ret := m.ctrl.Call(m, "ReconcileLoadbalancers")
@@ -87,6 +112,30 @@ func (mr *MockELBInterfaceMockRecorder) ReconcileLoadbalancers() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReconcileLoadbalancers", reflect.TypeOf((*MockELBInterface)(nil).ReconcileLoadbalancers))
 }
 
+// ReconcileAPIServerPrivateLink mocks base method
+func (m *MockELBInterface) ReconcileAPIServerPrivateLink() error {
+	ret := m.ctrl.Call(m, "ReconcileAPIServerPrivateLink")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReconcileAPIServerPrivateLink indicates an expected call of ReconcileAPIServerPrivateLink
+func (mr *MockELBInterfaceMockRecorder) ReconcileAPIServerPrivateLink() *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReconcileAPIServerPrivateLink", reflect.TypeOf((*MockELBInterface)(nil).ReconcileAPIServerPrivateLink))
+}
+
+// ReconcileAPIServerELBMigration mocks base method
+func (m *MockELBInterface) ReconcileAPIServerELBMigration() error {
+	ret := m.ctrl.Call(m, "ReconcileAPIServerELBMigration")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReconcileAPIServerELBMigration indicates an expected call of ReconcileAPIServerELBMigration
+func (mr *MockELBInterfaceMockRecorder) ReconcileAPIServerELBMigration() *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReconcileAPIServerELBMigration", reflect.TypeOf((*MockELBInterface)(nil).ReconcileAPIServerELBMigration))
+}
+
 // RegisterInstanceWithAPIServerELB mocks base method
 func (m *MockELBInterface) RegisterInstanceWithAPIServerELB(arg0 string) error {
 	ret := m.ctrl.Call(m, "RegisterInstanceWithAPIServerELB", arg0)
@@ -17,9 +17,8 @@ limitations under the License.
 package mocks
 
 import (
-	"github.com/aws/aws-sdk-go/aws/session"
 	gomock "github.com/golang/mock/gomock"
-	v1alpha1 "sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/actuators"
 	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services"
 )
 
@@ -29,18 +28,13 @@ type SDKGetter struct {
 	ELBMock *MockELBInterface
 }
 
-// Session returns a nil session.
-func (*SDKGetter) Session(clusterConfig *v1alpha1.AWSClusterProviderSpec) *session.Session {
-	return nil
-}
-
 // EC2 returns a mocked EC2 service client.
-func (t *SDKGetter) EC2(session *session.Session) services.EC2Interface {
+func (t *SDKGetter) EC2(scope *actuators.Scope) services.EC2Interface {
 	return t.EC2Mock
 }
 
-// ELB returns a mocked EC2 service client.
-func (t *SDKGetter) ELB(session *session.Session) services.ELBInterface {
+// ELB returns a mocked ELB service client.
+func (t *SDKGetter) ELB(scope *actuators.Scope) services.ELBInterface {
 	return t.ELBMock
 }
 
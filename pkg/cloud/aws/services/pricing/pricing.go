@@ -0,0 +1,248 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package pricing looks up the hourly prices of EC2 instance types.
+package pricing
+
+import (
+	"encoding/json"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/jsonprotocol"
+)
+
+const (
+	pricingServiceName  = "api.pricing"
+	pricingSigningName  = "pricing"
+	pricingAPIVersion   = "2017-10-15"
+	pricingTargetPrefix = "AWSPriceListService"
+
+	// pricingRegion is the region of the Price List API endpoint the prices
+	// of every region are looked up from.
+	pricingRegion = "us-east-1"
+
+	// spotProductDescription is the product description of the spot prices
+	// of Linux instances.
+	spotProductDescription = "Linux/UNIX"
+
+	// onDemandTTL is how long on-demand prices are cached, since they rarely
+	// change.
+	onDemandTTL = 24 * time.Hour
+
+	// spotTTL is how long spot prices are cached, since they follow the
+	// long-term supply and demand of each availability zone.
+	spotTTL = time.Hour
+)
+
+// Prices looks up the hourly prices of the Linux instances of a region, in
+// USD.
+type Prices interface {
+	// OnDemandHourlyPrice returns the hourly price of the on-demand
+	// instances of a type. It returns false if the instance type has no
+	// price in the region.
+	OnDemandHourlyPrice(instanceType string) (float64, bool, error)
+
+	// SpotHourlyPrice returns the current hourly price of the spot
+	// instances of a type in an availability zone. It returns false if the
+	// instance type has no spot price in the zone.
+	SpotHourlyPrice(instanceType, zone string) (float64, bool, error)
+}
+
+// Service looks up the prices of the instance types of a region.
+//
+// The vendored SDK has no Price List client, so on-demand prices are
+// requested with a generic SDK client speaking the JSON protocol of the
+// service, from its endpoint in us-east-1. Spot prices come from EC2.
+type Service struct {
+	client *client.Client
+	ec2    ec2iface.EC2API
+	region string
+}
+
+type filter struct {
+	Type  string `json:"Type"`
+	Field string `json:"Field"`
+	Value string `json:"Value"`
+}
+
+type getProductsInput struct {
+	ServiceCode   string   `json:"ServiceCode"`
+	Filters       []filter `json:"Filters"`
+	FormatVersion string   `json:"FormatVersion"`
+	MaxResults    int64    `json:"MaxResults"`
+}
+
+type getProductsOutput struct {
+	// PriceList holds the products matching the filters, each encoded as a
+	// JSON document.
+	PriceList []string `json:"PriceList"`
+}
+
+type product struct {
+	Terms struct {
+		OnDemand map[string]struct {
+			PriceDimensions map[string]struct {
+				Unit         string            `json:"unit"`
+				PricePerUnit map[string]string `json:"pricePerUnit"`
+			} `json:"priceDimensions"`
+		} `json:"OnDemand"`
+	} `json:"terms"`
+}
+
+// prices caches the prices looked up by region or zone and instance type.
+var prices = struct {
+	sync.Mutex
+	byKey map[string]price
+}{byKey: map[string]price{}}
+
+type price struct {
+	hourly  float64
+	known   bool
+	expires time.Time
+}
+
+// NewService returns a service looking up the prices of the region of the session.
+func NewService(sess *session.Session) *Service {
+	c := jsonprotocol.NewClient(sess.Copy(aws.NewConfig().WithRegion(pricingRegion)), pricingServiceName, pricingAPIVersion, pricingTargetPrefix)
+
+	// The signing name is only resolved along with the endpoint, so it is
+	// set here for overridden endpoints, as the SDK clients do.
+	if c.SigningName == "" {
+		c.SigningName = pricingSigningName
+	}
+
+	return &Service{
+		client: c,
+		ec2:    ec2.New(sess),
+		region: aws.StringValue(sess.Config.Region),
+	}
+}
+
+// OnDemandHourlyPrice implements Prices.
+func (s *Service) OnDemandHourlyPrice(instanceType string) (float64, bool, error) {
+	return cached("on-demand/"+s.region+"/"+instanceType, onDemandTTL, func() (float64, bool, error) {
+		input := &getProductsInput{
+			ServiceCode: "AmazonEC2",
+			Filters: []filter{
+				{Type: "TERM_MATCH", Field: "instanceType", Value: instanceType},
+				{Type: "TERM_MATCH", Field: "regionCode", Value: s.region},
+				{Type: "TERM_MATCH", Field: "operatingSystem", Value: "Linux"},
+				{Type: "TERM_MATCH", Field: "tenancy", Value: "Shared"},
+				{Type: "TERM_MATCH", Field: "preInstalledSw", Value: "NA"},
+				{Type: "TERM_MATCH", Field: "capacitystatus", Value: "Used"},
+			},
+			FormatVersion: "aws_v1",
+			MaxResults:    10,
+		}
+
+		out := &getProductsOutput{}
+		if err := jsonprotocol.Send(s.client, "GetProducts", input, out); err != nil {
+			return 0, false, errors.Wrapf(err, "failed to get the price of instance type %q in region %q", instanceType, s.region)
+		}
+
+		return onDemandHourlyPrice(out.PriceList)
+	})
+}
+
+// SpotHourlyPrice implements Prices.
+func (s *Service) SpotHourlyPrice(instanceType, zone string) (float64, bool, error) {
+	return cached("spot/"+zone+"/"+instanceType, spotTTL, func() (float64, bool, error) {
+		// The price in effect now is the latest price changed before now.
+		out, err := s.ec2.DescribeSpotPriceHistory(&ec2.DescribeSpotPriceHistoryInput{
+			AvailabilityZone:    aws.String(zone),
+			InstanceTypes:       aws.StringSlice([]string{instanceType}),
+			ProductDescriptions: aws.StringSlice([]string{spotProductDescription}),
+			StartTime:           aws.Time(time.Now()),
+		})
+		if err != nil {
+			return 0, false, errors.Wrapf(err, "failed to get the spot price of instance type %q in zone %q", instanceType, zone)
+		}
+
+		var latest *ec2.SpotPrice
+		for _, p := range out.SpotPriceHistory {
+			if latest == nil || aws.TimeValue(p.Timestamp).After(aws.TimeValue(latest.Timestamp)) {
+				latest = p
+			}
+		}
+		if latest == nil {
+			return 0, false, nil
+		}
+
+		hourly, err := strconv.ParseFloat(aws.StringValue(latest.SpotPrice), 64)
+		if err != nil {
+			return 0, false, errors.Wrapf(err, "invalid spot price of instance type %q in zone %q", instanceType, zone)
+		}
+		return hourly, true, nil
+	})
+}
+
+// cached returns the price cached under a key, or looks it up and caches it
+// for the given duration. Failed lookups are not cached.
+func cached(key string, ttl time.Duration, lookup func() (float64, bool, error)) (float64, bool, error) {
+	prices.Lock()
+	p, ok := prices.byKey[key]
+	prices.Unlock()
+	if ok && time.Now().Before(p.expires) {
+		return p.hourly, p.known, nil
+	}
+
+	hourly, known, err := lookup()
+	if err != nil {
+		return 0, false, err
+	}
+
+	prices.Lock()
+	prices.byKey[key] = price{hourly: hourly, known: known, expires: time.Now().Add(ttl)}
+	prices.Unlock()
+
+	return hourly, known, nil
+}
+
+// onDemandHourlyPrice returns the hourly price in USD of the first product
+// of a price list with one.
+func onDemandHourlyPrice(priceList []string) (float64, bool, error) {
+	for _, doc := range priceList {
+		var p product
+		if err := json.Unmarshal([]byte(doc), &p); err != nil {
+			return 0, false, errors.Wrap(err, "failed to decode price list")
+		}
+
+		for _, term := range p.Terms.OnDemand {
+			for _, dimension := range term.PriceDimensions {
+				usd, ok := dimension.PricePerUnit["USD"]
+				if dimension.Unit != "Hrs" || !ok {
+					continue
+				}
+
+				hourly, err := strconv.ParseFloat(usd, 64)
+				if err != nil {
+					return 0, false, errors.Wrapf(err, "invalid price %q", usd)
+				}
+				return hourly, true, nil
+			}
+		}
+	}
+
+	return 0, false, nil
+}
@@ -0,0 +1,120 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pricing
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+)
+
+const m5LargeProduct = `{"product":{"attributes":{"instanceType":"m5.large"}},"terms":{"OnDemand":{"ABC.JRTCKXETXF":{"priceDimensions":{"ABC.JRTCKXETXF.6YS6EN2CT7":{"unit":"Hrs","pricePerUnit":{"USD":"0.0960000000"}}}}}}}`
+
+func TestHourlyPrices(t *testing.T) {
+	requests := 0
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256") {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		requests++
+
+		if r.Header.Get("X-Amz-Target") == "AWSPriceListService.GetProducts" {
+			// The Price List API is only served from us-east-1.
+			if !strings.Contains(r.Header.Get("Authorization"), "/us-east-1/pricing/") {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+
+			input := &getProductsInput{}
+			if err := json.NewDecoder(r.Body).Decode(input); err != nil || input.ServiceCode != "AmazonEC2" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+
+			filters := map[string]string{}
+			for _, f := range input.Filters {
+				filters[f.Field] = f.Value
+			}
+			if filters["regionCode"] != "eu-west-3" || filters["operatingSystem"] != "Linux" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+
+			out := getProductsOutput{PriceList: []string{}}
+			if filters["instanceType"] == "m5.large" {
+				out.PriceList = append(out.PriceList, m5LargeProduct)
+			}
+			json.NewEncoder(w).Encode(out)
+			return
+		}
+
+		if err := r.ParseForm(); err != nil || r.Form.Get("Action") != "DescribeSpotPriceHistory" ||
+			r.Form.Get("AvailabilityZone") != "eu-west-3a" || r.Form.Get("ProductDescription.1") != "Linux/UNIX" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		w.Write([]byte(`<DescribeSpotPriceHistoryResponse><requestId>1</requestId><spotPriceHistorySet>
+<item><instanceType>m5.large</instanceType><productDescription>Linux/UNIX</productDescription><spotPrice>0.040000</spotPrice><timestamp>2019-01-01T00:00:00.000Z</timestamp><availabilityZone>eu-west-3a</availabilityZone></item>
+<item><instanceType>m5.large</instanceType><productDescription>Linux/UNIX</productDescription><spotPrice>0.035000</spotPrice><timestamp>2019-01-02T00:00:00.000Z</timestamp><availabilityZone>eu-west-3a</availabilityZone></item>
+</spotPriceHistorySet></DescribeSpotPriceHistoryResponse>`))
+	}))
+	defer server.Close()
+
+	sess, err := session.NewSession(aws.NewConfig().
+		WithRegion("eu-west-3").
+		WithEndpoint(server.URL).
+		WithCredentials(credentials.NewStaticCredentials("id", "secret", "")))
+	if err != nil {
+		t.Fatalf("failed to create session: %v", err)
+	}
+
+	s := NewService(sess)
+
+	hourly, ok, err := s.OnDemandHourlyPrice("m5.large")
+	if err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+	if !ok || hourly != 0.096 {
+		t.Fatalf("expected an hourly price of 0.096, got %v, %v", hourly, ok)
+	}
+
+	// Prices are cached.
+	if _, _, err := s.OnDemandHourlyPrice("m5.large"); err != nil || requests != 1 {
+		t.Fatalf("expected the price to be cached, got %d requests, %v", requests, err)
+	}
+
+	if _, ok, err := s.OnDemandHourlyPrice("x9.huge"); err != nil || ok {
+		t.Fatalf("expected no price for an unknown instance type, got %v, %v", ok, err)
+	}
+
+	hourly, ok, err = s.SpotHourlyPrice("m5.large", "eu-west-3a")
+	if err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+	if !ok || hourly != 0.035 {
+		t.Fatalf("expected the latest spot price of 0.035, got %v, %v", hourly, ok)
+	}
+}
@@ -0,0 +1,91 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package queryprotocol sends requests to the AWS services speaking the query
+// protocol, for which the vendored SDK has no client, and to the EC2
+// operations the vendored SDK does not know.
+package queryprotocol
+
+import (
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/client/metadata"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/aws/signer/v4"
+	"github.com/aws/aws-sdk-go/private/protocol/ec2query"
+	"github.com/aws/aws-sdk-go/private/protocol/query"
+)
+
+const (
+	ec2ServiceName = "ec2"
+
+	// EC2APIVersion is the version of the EC2 API requested by the clients
+	// returned by NewEC2Client.
+	EC2APIVersion = "2016-11-15"
+)
+
+// NewClient returns a generic SDK client speaking the query protocol of the
+// given service, in the region of the session. Requests still go through the
+// handlers of the session, for signing, retries and rate limiting.
+func NewClient(sess *session.Session, serviceName, apiVersion string) *client.Client {
+	c := newClient(sess, serviceName, apiVersion)
+
+	c.Handlers.Build.PushBackNamed(query.BuildHandler)
+	c.Handlers.Unmarshal.PushBackNamed(query.UnmarshalHandler)
+	c.Handlers.UnmarshalMeta.PushBackNamed(query.UnmarshalMetaHandler)
+	c.Handlers.UnmarshalError.PushBackNamed(query.UnmarshalErrorHandler)
+
+	return c
+}
+
+// NewEC2Client returns a generic SDK client speaking the EC2 flavor of the
+// query protocol, in the region of the session.
+func NewEC2Client(sess *session.Session) *client.Client {
+	c := newClient(sess, ec2ServiceName, EC2APIVersion)
+
+	c.Handlers.Build.PushBackNamed(ec2query.BuildHandler)
+	c.Handlers.Unmarshal.PushBackNamed(ec2query.UnmarshalHandler)
+	c.Handlers.UnmarshalMeta.PushBackNamed(ec2query.UnmarshalMetaHandler)
+	c.Handlers.UnmarshalError.PushBackNamed(ec2query.UnmarshalErrorHandler)
+
+	return c
+}
+
+func newClient(sess *session.Session, serviceName, apiVersion string) *client.Client {
+	cfg := sess.ClientConfig(serviceName)
+
+	c := client.New(*cfg.Config, metadata.ClientInfo{
+		ServiceName:   serviceName,
+		SigningName:   cfg.SigningName,
+		SigningRegion: cfg.SigningRegion,
+		Endpoint:      cfg.Endpoint,
+		APIVersion:    apiVersion,
+	}, cfg.Handlers)
+
+	c.Handlers.Sign.PushBackNamed(v4.SignRequestHandler)
+
+	return c
+}
+
+// Send sends a request for the given operation and decodes its response into out.
+func Send(c *client.Client, operation string, in, out interface{}) error {
+	op := &request.Operation{
+		Name:       operation,
+		HTTPMethod: "POST",
+		HTTPPath:   "/",
+	}
+	return c.NewRequest(op, in, out).Send()
+}
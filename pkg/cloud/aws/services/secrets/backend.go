@@ -0,0 +1,52 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package secrets stores cluster secrets outside of the management cluster.
+package secrets
+
+import (
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+)
+
+// Backend stores secrets by key.
+type Backend interface {
+	// Get returns the value of a secret, or an error satisfying
+	// awserrors.IsNotFound if the secret does not exist.
+	Get(key string) ([]byte, error)
+
+	// Put creates or updates a secret.
+	Put(key string, value []byte) error
+
+	// Delete deletes a secret. Deleting a missing secret is not an error.
+	Delete(key string) error
+}
+
+// NewBackend returns the backend configured by spec. The AWS session is used
+// by the backends relying on AWS services.
+func NewBackend(spec *v1alpha1.SecretBackend, sess *session.Session) (Backend, error) {
+	switch {
+	case spec.Vault != nil && spec.SecretsManager != nil:
+		return nil, errors.New("secret backend must specify only one of vault or secretsManager")
+	case spec.Vault != nil:
+		return NewVaultBackend(spec.Vault)
+	case spec.SecretsManager != nil:
+		return NewSecretsManagerBackend(spec.SecretsManager, sess)
+	default:
+		return nil, errors.New("secret backend must specify one of vault or secretsManager")
+	}
+}
@@ -0,0 +1,161 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package secrets
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/awserrors"
+)
+
+// fakeSecretsManager serves the secret operations of Secrets Manager from memory.
+type fakeSecretsManager struct {
+	sync.Mutex
+	secrets map[string][]byte
+	kmsKeys map[string]string
+}
+
+func (f *fakeSecretsManager) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.Lock()
+	defer f.Unlock()
+
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256") {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	input := struct {
+		SecretID     string `json:"SecretId"`
+		Name         string `json:"Name"`
+		KMSKeyID     string `json:"KmsKeyId"`
+		SecretBinary []byte `json:"SecretBinary"`
+	}{}
+	json.NewDecoder(r.Body).Decode(&input)
+
+	notFound := func() {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{
+			"__type":  "ResourceNotFoundException",
+			"message": "Secrets Manager can't find the specified secret.",
+		})
+	}
+
+	switch r.Header.Get("X-Amz-Target") {
+	case "secretsmanager.GetSecretValue":
+		value, ok := f.secrets[input.SecretID]
+		if !ok {
+			notFound()
+			return
+		}
+		json.NewEncoder(w).Encode(map[string][]byte{"SecretBinary": value})
+	case "secretsmanager.PutSecretValue":
+		if _, ok := f.secrets[input.SecretID]; !ok {
+			notFound()
+			return
+		}
+		f.secrets[input.SecretID] = input.SecretBinary
+		w.Write([]byte("{}"))
+	case "secretsmanager.CreateSecret":
+		f.secrets[input.Name] = input.SecretBinary
+		f.kmsKeys[input.Name] = input.KMSKeyID
+		w.Write([]byte("{}"))
+	case "secretsmanager.DeleteSecret":
+		if _, ok := f.secrets[input.SecretID]; !ok {
+			notFound()
+			return
+		}
+		delete(f.secrets, input.SecretID)
+		w.Write([]byte("{}"))
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+	}
+}
+
+func TestSecretsManagerBackend(t *testing.T) {
+	sm := &fakeSecretsManager{secrets: map[string][]byte{}, kmsKeys: map[string]string{}}
+	server := httptest.NewServer(sm)
+	defer server.Close()
+
+	sess, err := session.NewSession(aws.NewConfig().
+		WithRegion("us-east-1").
+		WithEndpoint(server.URL).
+		WithCredentials(credentials.NewStaticCredentials("id", "secret", "")))
+	if err != nil {
+		t.Fatalf("failed to create session: %v", err)
+	}
+
+	backend, err := NewBackend(&v1alpha1.SecretBackend{
+		SecretsManager: &v1alpha1.SecretsManagerSecretBackend{Prefix: "clusters/", KMSKeyID: "alias/clusters"},
+	}, sess)
+	if err != nil {
+		t.Fatalf("failed to create backend: %v", err)
+	}
+
+	key := "default/test-cluster/ca.key"
+	if _, err := backend.Get(key); !awserrors.IsNotFound(err) {
+		t.Fatalf("expected not found error, got: %v", err)
+	}
+
+	// The secret is created with the KMS key, then updated.
+	for _, value := range []string{"key", "rotated"} {
+		if err := backend.Put(key, []byte(value)); err != nil {
+			t.Fatalf("failed to put secret: %v", err)
+		}
+
+		got, err := backend.Get(key)
+		if err != nil {
+			t.Fatalf("failed to get secret: %v", err)
+		}
+		if string(got) != value {
+			t.Fatalf("expected secret value %q, got %q", value, got)
+		}
+	}
+
+	if kmsKey := sm.kmsKeys["clusters/default/test-cluster/ca.key"]; kmsKey != "alias/clusters" {
+		t.Fatalf("expected secret to be encrypted with %q, got %q", "alias/clusters", kmsKey)
+	}
+
+	if err := backend.Delete(key); err != nil {
+		t.Fatalf("failed to delete secret: %v", err)
+	}
+	if err := backend.Delete(key); err != nil {
+		t.Fatalf("did not expect error deleting a missing secret: %v", err)
+	}
+}
+
+func TestNewBackend(t *testing.T) {
+	if _, err := NewBackend(&v1alpha1.SecretBackend{}, nil); err == nil {
+		t.Fatalf("expected error without a backend")
+	}
+
+	_, err := NewBackend(&v1alpha1.SecretBackend{
+		Vault:          &v1alpha1.VaultSecretBackend{Address: "https://vault.example.com:8200"},
+		SecretsManager: &v1alpha1.SecretsManagerSecretBackend{},
+	}, nil)
+	if err == nil {
+		t.Fatalf("expected error with several backends")
+	}
+}
@@ -0,0 +1,166 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package secrets
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/awserrors"
+)
+
+const (
+	// vaultTokenEnv is the environment variable holding the Vault token of the controller.
+	vaultTokenEnv = "VAULT_TOKEN"
+
+	// defaultVaultMountPath is the default mount path of the KV secrets engine.
+	defaultVaultMountPath = "secret"
+
+	// vaultTimeout bounds the duration of the requests to Vault.
+	vaultTimeout = 30 * time.Second
+)
+
+// vaultBackend stores secrets in the KV version 2 secrets engine of Vault.
+// Values are base64 encoded in the "value" field of the secrets.
+type vaultBackend struct {
+	client     *http.Client
+	address    string
+	token      string
+	mountPath  string
+	pathPrefix string
+}
+
+// vaultSecret is the body of the requests and responses of the KV data API.
+type vaultSecret struct {
+	Data struct {
+		Value []byte `json:"value"`
+	} `json:"data"`
+}
+
+// NewVaultBackend returns a backend storing secrets in Vault.
+func NewVaultBackend(spec *v1alpha1.VaultSecretBackend) (Backend, error) {
+	if _, err := url.ParseRequestURI(spec.Address); err != nil {
+		return nil, errors.Wrapf(err, "invalid vault address %q", spec.Address)
+	}
+
+	token := os.Getenv(vaultTokenEnv)
+	if token == "" {
+		return nil, errors.Errorf("%s must be set to use the vault secret backend", vaultTokenEnv)
+	}
+
+	mountPath := spec.MountPath
+	if mountPath == "" {
+		mountPath = defaultVaultMountPath
+	}
+
+	return &vaultBackend{
+		client:     &http.Client{Timeout: vaultTimeout},
+		address:    strings.TrimSuffix(spec.Address, "/"),
+		token:      token,
+		mountPath:  mountPath,
+		pathPrefix: spec.PathPrefix,
+	}, nil
+}
+
+// Get implements Backend.
+func (v *vaultBackend) Get(key string) ([]byte, error) {
+	secret := &vaultSecret{}
+	if err := v.do(http.MethodGet, "data", key, nil, secret); err != nil {
+		return nil, err
+	}
+
+	// The KV version 2 engine nests the secret data in the response data.
+	return secret.Data.Value, nil
+}
+
+// Put implements Backend.
+func (v *vaultBackend) Put(key string, value []byte) error {
+	secret := &vaultSecret{}
+	secret.Data.Value = value
+	return v.do(http.MethodPost, "data", key, secret, nil)
+}
+
+// Delete implements Backend. All the versions of the secret are deleted.
+func (v *vaultBackend) Delete(key string) error {
+	if err := v.do(http.MethodDelete, "metadata", key, nil, nil); err != nil && !awserrors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// do sends a request to the given API of the KV secrets engine for a key,
+// and decodes the data of the response into out if not nil.
+func (v *vaultBackend) do(method, api, key string, in, out interface{}) error {
+	var body []byte
+	if in != nil {
+		var err error
+		if body, err = json.Marshal(in); err != nil {
+			return errors.Wrapf(err, "failed to encode vault request for %q", key)
+		}
+	}
+
+	u := v.address + "/" + path.Join("v1", v.mountPath, api, v.pathPrefix, key)
+	req, err := http.NewRequest(method, u, bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrapf(err, "failed to create vault request for %q", key)
+	}
+	req.Header.Set("X-Vault-Token", v.token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return errors.Wrapf(err, "failed to send vault request for %q", key)
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return errors.Wrapf(err, "failed to read vault response for %q", key)
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return awserrors.NewNotFound(errors.Errorf("secret %q not found in vault", key))
+	case resp.StatusCode >= 300:
+		return errors.Errorf("vault request for %q failed with status %d: %s", key, resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+
+	if out == nil {
+		return nil
+	}
+
+	wrapper := struct {
+		Data json.RawMessage `json:"data"`
+	}{}
+	if err := json.Unmarshal(data, &wrapper); err != nil {
+		return errors.Wrapf(err, "failed to decode vault response for %q", key)
+	}
+	if err := json.Unmarshal(wrapper.Data, out); err != nil {
+		return errors.Wrapf(err, "failed to decode vault secret %q", key)
+	}
+
+	return nil
+}
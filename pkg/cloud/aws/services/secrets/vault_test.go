@@ -0,0 +1,115 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package secrets
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/awserrors"
+)
+
+// fakeVault serves the KV version 2 API of Vault from memory.
+type fakeVault struct {
+	sync.Mutex
+	secrets map[string]json.RawMessage
+}
+
+func (f *fakeVault) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.Lock()
+	defer f.Unlock()
+
+	if r.Header.Get("X-Vault-Token") != "test-token" {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		secret, ok := f.secrets[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": secret})
+	case http.MethodPost:
+		var secret json.RawMessage
+		json.NewDecoder(r.Body).Decode(&secret)
+		f.secrets[r.URL.Path] = secret
+	case http.MethodDelete:
+		delete(f.secrets, strings.Replace(r.URL.Path, "/metadata/", "/data/", 1))
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func TestVaultBackend(t *testing.T) {
+	vault := &fakeVault{secrets: map[string]json.RawMessage{}}
+	server := httptest.NewServer(vault)
+	defer server.Close()
+
+	os.Setenv(vaultTokenEnv, "test-token")
+	defer os.Unsetenv(vaultTokenEnv)
+
+	backend, err := NewBackend(&v1alpha1.SecretBackend{
+		Vault: &v1alpha1.VaultSecretBackend{Address: server.URL, MountPath: "kv", PathPrefix: "clusters"},
+	}, nil)
+	if err != nil {
+		t.Fatalf("failed to create backend: %v", err)
+	}
+
+	key := "default/test-cluster/ca.key"
+	if _, err := backend.Get(key); !awserrors.IsNotFound(err) {
+		t.Fatalf("expected not found error, got: %v", err)
+	}
+
+	if err := backend.Put(key, []byte("key")); err != nil {
+		t.Fatalf("failed to put secret: %v", err)
+	}
+	if _, ok := vault.secrets["/v1/kv/data/clusters/default/test-cluster/ca.key"]; !ok {
+		t.Fatalf("expected secret to be stored under the path prefix, got: %v", vault.secrets)
+	}
+
+	value, err := backend.Get(key)
+	if err != nil {
+		t.Fatalf("failed to get secret: %v", err)
+	}
+	if string(value) != "key" {
+		t.Fatalf("expected secret value %q, got %q", "key", value)
+	}
+
+	if err := backend.Delete(key); err != nil {
+		t.Fatalf("failed to delete secret: %v", err)
+	}
+	if _, err := backend.Get(key); !awserrors.IsNotFound(err) {
+		t.Fatalf("expected not found error after deletion, got: %v", err)
+	}
+}
+
+func TestVaultBackendWithoutToken(t *testing.T) {
+	os.Unsetenv(vaultTokenEnv)
+
+	_, err := NewVaultBackend(&v1alpha1.VaultSecretBackend{Address: "https://vault.example.com:8200"})
+	if err == nil {
+		t.Fatalf("expected error without a vault token")
+	}
+}
@@ -0,0 +1,97 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package sns publishes messages to topics of the Simple Notification Service.
+package sns
+
+import (
+	"unicode/utf8"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/queryprotocol"
+)
+
+const (
+	snsServiceName = "sns"
+	snsAPIVersion  = "2010-03-31"
+
+	// subjectMaxLength is the maximum length of the subject of messages.
+	subjectMaxLength = 100
+)
+
+// Publisher publishes messages to topics.
+type Publisher interface {
+	// Publish publishes a message with the given subject to a topic.
+	Publish(topicARN, subject, message string) error
+}
+
+// Service publishes messages to SNS topics.
+//
+// The vendored SDK has no SNS client, so requests are sent with a generic
+// SDK client speaking the query protocol of the service. Requests still go
+// through the handlers of the session, for signing, retries and rate limiting.
+type Service struct {
+	client *client.Client
+}
+
+type publishInput struct {
+	_ struct{} `type:"structure"`
+
+	Message  *string `type:"string" required:"true"`
+	Subject  *string `type:"string"`
+	TopicArn *string `type:"string"`
+}
+
+type publishOutput struct {
+	_ struct{} `type:"structure"`
+}
+
+// NewService returns a service publishing to the topics of the region of the session.
+func NewService(sess *session.Session) *Service {
+	return &Service{client: queryprotocol.NewClient(sess, snsServiceName, snsAPIVersion)}
+}
+
+// Publish implements Publisher. Subjects longer than allowed are truncated.
+func (s *Service) Publish(topicARN, subject, message string) error {
+	input := &publishInput{
+		Message:  aws.String(message),
+		Subject:  aws.String(truncateSubject(subject)),
+		TopicArn: aws.String(topicARN),
+	}
+
+	if err := queryprotocol.Send(s.client, "Publish", input, &publishOutput{}); err != nil {
+		return errors.Wrapf(err, "failed to publish to topic %q", topicARN)
+	}
+
+	return nil
+}
+
+// truncateSubject truncates a subject to subjectMaxLength bytes, without
+// cutting a multi-byte character in half.
+func truncateSubject(subject string) string {
+	if len(subject) <= subjectMaxLength {
+		return subject
+	}
+
+	n := subjectMaxLength
+	for n > 0 && !utf8.RuneStart(subject[n]) {
+		n--
+	}
+	return subject[:n]
+}
@@ -0,0 +1,125 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sns
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+)
+
+func TestPublish(t *testing.T) {
+	var published map[string]string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256") {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		if err := r.ParseForm(); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		if r.Form.Get("TopicArn") == "arn:aws:sns:us-east-1:123456789012:missing" {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`<ErrorResponse><Error><Type>Sender</Type><Code>NotFound</Code><Message>Topic does not exist</Message></Error><RequestId>1</RequestId></ErrorResponse>`))
+			return
+		}
+
+		published = map[string]string{}
+		for key := range r.Form {
+			published[key] = r.Form.Get(key)
+		}
+		w.Write([]byte(`<PublishResponse><PublishResult><MessageId>m-1</MessageId></PublishResult><ResponseMetadata><RequestId>1</RequestId></ResponseMetadata></PublishResponse>`))
+	}))
+	defer server.Close()
+
+	sess, err := session.NewSession(aws.NewConfig().
+		WithRegion("us-east-1").
+		WithEndpoint(server.URL).
+		WithCredentials(credentials.NewStaticCredentials("id", "secret", "")))
+	if err != nil {
+		t.Fatalf("failed to create session: %v", err)
+	}
+
+	s := NewService(sess)
+
+	if err := s.Publish("arn:aws:sns:us-east-1:123456789012:machines", strings.Repeat("s", 120), `{"event":"created"}`); err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+
+	expected := map[string]string{
+		"Action":   "Publish",
+		"Version":  "2010-03-31",
+		"TopicArn": "arn:aws:sns:us-east-1:123456789012:machines",
+		"Subject":  strings.Repeat("s", 100),
+		"Message":  `{"event":"created"}`,
+	}
+	for key, value := range expected {
+		if published[key] != value {
+			t.Fatalf("expected %s %q, got %q", key, value, published[key])
+		}
+	}
+
+	if err := s.Publish("arn:aws:sns:us-east-1:123456789012:missing", "subject", "message"); err == nil {
+		t.Fatalf("expected error")
+	}
+}
+
+func TestTruncateSubject(t *testing.T) {
+	testCases := []struct {
+		name     string
+		subject  string
+		expected string
+	}{
+		{
+			name:     "short subject",
+			subject:  "machine created",
+			expected: "machine created",
+		},
+		{
+			name:     "long subject",
+			subject:  strings.Repeat("s", 120),
+			expected: strings.Repeat("s", 100),
+		},
+		{
+			name:     "multi-byte character across the limit",
+			subject:  strings.Repeat("s", 99) + "é" + "s",
+			expected: strings.Repeat("s", 99),
+		},
+		{
+			name:     "multi-byte character at the limit",
+			subject:  strings.Repeat("s", 98) + "é" + "s",
+			expected: strings.Repeat("s", 98) + "é",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if subject := truncateSubject(tc.subject); subject != tc.expected {
+				t.Fatalf("expected subject %q, got %q", tc.expected, subject)
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package sqs receives the messages of SQS queues.
+package sqs
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/queryprotocol"
+)
+
+const (
+	sqsServiceName = "sqs"
+	sqsAPIVersion  = "2012-11-05"
+
+	// maxMessages is the maximum number of messages received at once.
+	maxMessages = 10
+)
+
+// Message is a message received from a queue.
+type Message struct {
+	// ID is the ID of the message.
+	ID string
+
+	// ReceiptHandle identifies the receipt of the message, to delete it.
+	ReceiptHandle string
+
+	// Body is the body of the message.
+	Body string
+}
+
+// Queue receives and deletes the messages of queues.
+type Queue interface {
+	// ReceiveMessages returns the messages available in a queue, without
+	// waiting for messages to arrive. The messages are hidden from other
+	// receivers for the visibility timeout, in seconds, and received again
+	// afterwards unless deleted.
+	ReceiveMessages(queueURL string, visibilityTimeout int64) ([]Message, error)
+
+	// DeleteMessage deletes a received message from a queue.
+	DeleteMessage(queueURL, receiptHandle string) error
+}
+
+// Service receives the messages of SQS queues.
+//
+// The vendored SDK has no SQS client, so requests are sent with a generic
+// SDK client speaking the query protocol of the service. Requests still go
+// through the handlers of the session, for signing, retries and rate limiting.
+type Service struct {
+	client *client.Client
+}
+
+type receiveMessageInput struct {
+	_ struct{} `type:"structure"`
+
+	MaxNumberOfMessages *int64  `type:"integer"`
+	QueueUrl            *string `type:"string" required:"true"`
+	VisibilityTimeout   *int64  `type:"integer"`
+	WaitTimeSeconds     *int64  `type:"integer"`
+}
+
+type receiveMessageOutput struct {
+	_ struct{} `type:"structure"`
+
+	Messages []*message `locationNameList:"Message" type:"list" flattened:"true"`
+}
+
+type message struct {
+	_ struct{} `type:"structure"`
+
+	Body          *string `type:"string"`
+	MessageId     *string `type:"string"`
+	ReceiptHandle *string `type:"string"`
+}
+
+type deleteMessageInput struct {
+	_ struct{} `type:"structure"`
+
+	QueueUrl      *string `type:"string" required:"true"`
+	ReceiptHandle *string `type:"string" required:"true"`
+}
+
+type deleteMessageOutput struct {
+	_ struct{} `type:"structure"`
+}
+
+// NewService returns a service receiving the messages of the queues of the
+// region of the session.
+func NewService(sess *session.Session) *Service {
+	return &Service{client: queryprotocol.NewClient(sess, sqsServiceName, sqsAPIVersion)}
+}
+
+func (s *Service) send(operation string, in, out interface{}) error {
+	return queryprotocol.Send(s.client, operation, in, out)
+}
+
+// ReceiveMessages implements Queue.
+func (s *Service) ReceiveMessages(queueURL string, visibilityTimeout int64) ([]Message, error) {
+	input := &receiveMessageInput{
+		MaxNumberOfMessages: aws.Int64(maxMessages),
+		QueueUrl:            aws.String(queueURL),
+		VisibilityTimeout:   aws.Int64(visibilityTimeout),
+		WaitTimeSeconds:     aws.Int64(0),
+	}
+
+	out := &receiveMessageOutput{}
+	if err := s.send("ReceiveMessage", input, out); err != nil {
+		return nil, errors.Wrapf(err, "failed to receive messages from queue %q", queueURL)
+	}
+
+	messages := make([]Message, 0, len(out.Messages))
+	for _, m := range out.Messages {
+		messages = append(messages, Message{
+			ID:            aws.StringValue(m.MessageId),
+			ReceiptHandle: aws.StringValue(m.ReceiptHandle),
+			Body:          aws.StringValue(m.Body),
+		})
+	}
+
+	return messages, nil
+}
+
+// DeleteMessage implements Queue.
+func (s *Service) DeleteMessage(queueURL, receiptHandle string) error {
+	input := &deleteMessageInput{
+		QueueUrl:      aws.String(queueURL),
+		ReceiptHandle: aws.String(receiptHandle),
+	}
+
+	if err := s.send("DeleteMessage", input, &deleteMessageOutput{}); err != nil {
+		return errors.Wrapf(err, "failed to delete message from queue %q", queueURL)
+	}
+
+	return nil
+}
@@ -0,0 +1,97 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sqs
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+)
+
+const queueURL = "https://sqs.us-east-1.amazonaws.com/123456789012/lifecycle-hooks"
+
+func TestReceiveAndDeleteMessages(t *testing.T) {
+	var deleted []string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256") {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		if err := r.ParseForm(); err != nil || r.Form.Get("Version") != sqsAPIVersion || r.Form.Get("QueueUrl") != queueURL {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		switch r.Form.Get("Action") {
+		case "ReceiveMessage":
+			if r.Form.Get("MaxNumberOfMessages") != "10" || r.Form.Get("VisibilityTimeout") != "30" || r.Form.Get("WaitTimeSeconds") != "0" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.Write([]byte(`<ReceiveMessageResponse><ReceiveMessageResult>
+<Message><MessageId>m-1</MessageId><ReceiptHandle>r-1</ReceiptHandle><MD5OfBody>x</MD5OfBody><Body>{"a":1}</Body></Message>
+<Message><MessageId>m-2</MessageId><ReceiptHandle>r-2</ReceiptHandle><MD5OfBody>x</MD5OfBody><Body>{"b":2}</Body></Message>
+</ReceiveMessageResult><ResponseMetadata><RequestId>1</RequestId></ResponseMetadata></ReceiveMessageResponse>`))
+
+		case "DeleteMessage":
+			deleted = append(deleted, r.Form.Get("ReceiptHandle"))
+			w.Write([]byte(`<DeleteMessageResponse><ResponseMetadata><RequestId>2</RequestId></ResponseMetadata></DeleteMessageResponse>`))
+
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	sess, err := session.NewSession(aws.NewConfig().
+		WithRegion("us-east-1").
+		WithEndpoint(server.URL).
+		WithCredentials(credentials.NewStaticCredentials("id", "secret", "")))
+	if err != nil {
+		t.Fatalf("failed to create session: %v", err)
+	}
+
+	s := NewService(sess)
+
+	messages, err := s.ReceiveMessages(queueURL, 30)
+	if err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+
+	expected := []Message{
+		{ID: "m-1", ReceiptHandle: "r-1", Body: `{"a":1}`},
+		{ID: "m-2", ReceiptHandle: "r-2", Body: `{"b":2}`},
+	}
+	if !reflect.DeepEqual(messages, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, messages)
+	}
+
+	if err := s.DeleteMessage(queueURL, "r-1"); err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+	if !reflect.DeepEqual(deleted, []string{"r-1"}) {
+		t.Fatalf("expected message r-1 to be deleted, got %v", deleted)
+	}
+}
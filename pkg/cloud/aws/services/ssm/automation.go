@@ -0,0 +1,180 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssm
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/jsonprotocol"
+)
+
+// Statuses of Automation executions.
+const (
+	AutomationStatusSuccess = "Success"
+	AutomationStatusFailed  = "Failed"
+)
+
+// AutomationExecution describes an execution of an Automation runbook.
+type AutomationExecution struct {
+	// ID is the ID of the execution.
+	ID string
+
+	// Status is Success once the execution completed, Failed if it did not,
+	// or the status reported by Systems Manager while it is in progress.
+	Status string
+
+	// FailureMessage is the reason of the failure of the execution.
+	FailureMessage string
+
+	// EndTime is the time at which the execution completed.
+	EndTime time.Time
+
+	// Outputs are the outputs of an execution which completed.
+	Outputs map[string][]string
+}
+
+// Automation runs Automation runbooks.
+type Automation interface {
+	// StartAutomationExecution starts an execution of a runbook with the
+	// given parameters, tagged with the given key, and returns its ID.
+	StartAutomationExecution(documentName string, parameters map[string][]string, tagKey string) (string, error)
+
+	// LatestAutomationExecution returns the latest execution tagged with the
+	// given key, or nil if there is none.
+	LatestAutomationExecution(tagKey string) (*AutomationExecution, error)
+}
+
+type automationFilter struct {
+	Key    string   `json:"Key"`
+	Values []string `json:"Values"`
+}
+
+type tag struct {
+	Key   string `json:"Key"`
+	Value string `json:"Value"`
+}
+
+type startAutomationExecutionInput struct {
+	DocumentName string              `json:"DocumentName"`
+	Parameters   map[string][]string `json:"Parameters,omitempty"`
+	Tags         []tag               `json:"Tags,omitempty"`
+}
+
+type startAutomationExecutionOutput struct {
+	AutomationExecutionID string `json:"AutomationExecutionId"`
+}
+
+type describeAutomationExecutionsInput struct {
+	Filters   []automationFilter `json:"Filters"`
+	NextToken string             `json:"NextToken,omitempty"`
+}
+
+type describeAutomationExecutionsOutput struct {
+	AutomationExecutionMetadataList []struct {
+		AutomationExecutionID     string  `json:"AutomationExecutionId"`
+		AutomationExecutionStatus string  `json:"AutomationExecutionStatus"`
+		ExecutionStartTime        float64 `json:"ExecutionStartTime"`
+	} `json:"AutomationExecutionMetadataList"`
+	NextToken string `json:"NextToken"`
+}
+
+type getAutomationExecutionInput struct {
+	AutomationExecutionID string `json:"AutomationExecutionId"`
+}
+
+type getAutomationExecutionOutput struct {
+	AutomationExecution struct {
+		AutomationExecutionID     string              `json:"AutomationExecutionId"`
+		AutomationExecutionStatus string              `json:"AutomationExecutionStatus"`
+		FailureMessage            string              `json:"FailureMessage"`
+		ExecutionEndTime          float64             `json:"ExecutionEndTime"`
+		Outputs                   map[string][]string `json:"Outputs"`
+	} `json:"AutomationExecution"`
+}
+
+// StartAutomationExecution implements Automation.
+func (s *Service) StartAutomationExecution(documentName string, parameters map[string][]string, tagKey string) (string, error) {
+	input := &startAutomationExecutionInput{
+		DocumentName: documentName,
+		Parameters:   parameters,
+		Tags:         []tag{{Key: tagKey, Value: "true"}},
+	}
+	out := &startAutomationExecutionOutput{}
+	if err := jsonprotocol.Send(s.client, "StartAutomationExecution", input, out); err != nil {
+		return "", errors.Wrapf(err, "failed to start automation %q", documentName)
+	}
+	return out.AutomationExecutionID, nil
+}
+
+// LatestAutomationExecution implements Automation.
+func (s *Service) LatestAutomationExecution(tagKey string) (*AutomationExecution, error) {
+	var latestID string
+	var latestStart float64
+	input := &describeAutomationExecutionsInput{
+		Filters: []automationFilter{{Key: "TagKey", Values: []string{tagKey}}},
+	}
+	for {
+		out := &describeAutomationExecutionsOutput{}
+		if err := jsonprotocol.Send(s.client, "DescribeAutomationExecutions", input, out); err != nil {
+			return nil, errors.Wrapf(err, "failed to describe automation executions tagged %q", tagKey)
+		}
+		for _, e := range out.AutomationExecutionMetadataList {
+			if latestID == "" || e.ExecutionStartTime > latestStart {
+				latestID, latestStart = e.AutomationExecutionID, e.ExecutionStartTime
+			}
+		}
+		if out.NextToken == "" {
+			break
+		}
+		input.NextToken = out.NextToken
+	}
+
+	if latestID == "" {
+		return nil, nil
+	}
+
+	out := &getAutomationExecutionOutput{}
+	if err := jsonprotocol.Send(s.client, "GetAutomationExecution", &getAutomationExecutionInput{AutomationExecutionID: latestID}, out); err != nil {
+		return nil, errors.Wrapf(err, "failed to get automation execution %q", latestID)
+	}
+
+	e := out.AutomationExecution
+	execution := &AutomationExecution{
+		ID:             e.AutomationExecutionID,
+		Status:         automationStatus(e.AutomationExecutionStatus),
+		FailureMessage: e.FailureMessage,
+		Outputs:        e.Outputs,
+	}
+	if e.ExecutionEndTime > 0 {
+		execution.EndTime = time.Unix(0, int64(e.ExecutionEndTime*float64(time.Second)))
+	}
+	return execution, nil
+}
+
+// automationStatus reduces the statuses of completed executions to Success
+// and Failed.
+func automationStatus(status string) string {
+	switch status {
+	case "Success", "CompletedWithSuccess":
+		return AutomationStatusSuccess
+	case "Failed", "TimedOut", "Cancelled", "ApprovalRejected", "CompletedWithFailure", "ChangeCalendarOverrideRejected", "Exited", "Rejected":
+		return AutomationStatusFailed
+	default:
+		return status
+	}
+}
@@ -0,0 +1,133 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssm
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+)
+
+func TestAutomationExecutions(t *testing.T) {
+	type execution struct {
+		tagKey  string
+		start   float64
+		status  string
+		outputs map[string][]string
+	}
+	executions := map[string]*execution{
+		"old": {tagKey: "build-1", start: 1500000000, status: "Failed"},
+		"new": {tagKey: "build-1", start: 1600000000.5, status: "Success", outputs: map[string][]string{"createImage.ImageId": {"ami-1"}}},
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		input := struct {
+			DocumentName          string              `json:"DocumentName"`
+			Parameters            map[string][]string `json:"Parameters"`
+			Tags                  []tag               `json:"Tags"`
+			Filters               []automationFilter  `json:"Filters"`
+			AutomationExecutionID string              `json:"AutomationExecutionId"`
+		}{}
+		json.NewDecoder(r.Body).Decode(&input)
+
+		switch r.Header.Get("X-Amz-Target") {
+		case "AmazonSSM.StartAutomationExecution":
+			if input.DocumentName != "BuildImage" || len(input.Tags) != 1 || input.Parameters["KubernetesVersion"][0] != "1.13.0" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			executions["started"] = &execution{tagKey: input.Tags[0].Key, start: 1700000000, status: "InProgress"}
+			json.NewEncoder(w).Encode(map[string]string{"AutomationExecutionId": "started"})
+
+		case "AmazonSSM.DescribeAutomationExecutions":
+			if len(input.Filters) != 1 || input.Filters[0].Key != "TagKey" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			var list []map[string]interface{}
+			for id, e := range executions {
+				if e.tagKey == input.Filters[0].Values[0] {
+					list = append(list, map[string]interface{}{
+						"AutomationExecutionId":     id,
+						"AutomationExecutionStatus": e.status,
+						"ExecutionStartTime":        e.start,
+					})
+				}
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"AutomationExecutionMetadataList": list})
+
+		case "AmazonSSM.GetAutomationExecution":
+			e := executions[input.AutomationExecutionID]
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"AutomationExecution": map[string]interface{}{
+					"AutomationExecutionId":     input.AutomationExecutionID,
+					"AutomationExecutionStatus": e.status,
+					"ExecutionEndTime":          e.start + 60,
+					"Outputs":                   e.outputs,
+				},
+			})
+
+		default:
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	defer server.Close()
+
+	sess, err := session.NewSession(aws.NewConfig().
+		WithRegion("us-east-1").
+		WithEndpoint(server.URL).
+		WithCredentials(credentials.NewStaticCredentials("id", "secret", "")))
+	if err != nil {
+		t.Fatalf("failed to create session: %v", err)
+	}
+
+	s := NewService(sess)
+
+	latest, err := s.LatestAutomationExecution("build-1")
+	if err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+	if latest == nil || latest.ID != "new" || latest.Status != AutomationStatusSuccess {
+		t.Fatalf("expected the latest successful execution, got %+v", latest)
+	}
+	if !reflect.DeepEqual(latest.Outputs, executions["new"].outputs) {
+		t.Fatalf("expected outputs %v, got %v", executions["new"].outputs, latest.Outputs)
+	}
+	if latest.EndTime.Unix() != 1600000060 {
+		t.Fatalf("expected end time %d, got %v", 1600000060, latest.EndTime)
+	}
+
+	latest, err = s.LatestAutomationExecution("build-2")
+	if err != nil || latest != nil {
+		t.Fatalf("expected no execution, got %+v, %v", latest, err)
+	}
+
+	id, err := s.StartAutomationExecution("BuildImage", map[string][]string{"KubernetesVersion": {"1.13.0"}}, "build-2")
+	if err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+	latest, err = s.LatestAutomationExecution("build-2")
+	if err != nil || latest == nil || latest.ID != id || latest.Status != "InProgress" {
+		t.Fatalf("expected the started execution to be in progress, got %+v, %v", latest, err)
+	}
+}
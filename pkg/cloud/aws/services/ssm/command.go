@@ -0,0 +1,129 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssm
+
+import (
+	"sort"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/jsonprotocol"
+)
+
+// Statuses of Run Command commands.
+const (
+	CommandStatusSuccess = "Success"
+	CommandStatusFailed  = "Failed"
+)
+
+// shellScriptDocument is the Run Command document running shell commands.
+const shellScriptDocument = "AWS-RunShellScript"
+
+// Commands runs shell scripts on instances with Run Command.
+type Commands interface {
+	// RunShellScript runs a script on the given instances, or, if there are
+	// none, on the instances with all the given tags, one instance at a time,
+	// and returns the ID of the command.
+	RunShellScript(comment, script string, instanceIDs []string, tags map[string]string) (string, error)
+
+	// CommandStatus returns Success once a command succeeded on all its
+	// instances, Failed if it did not, or the status reported by Systems
+	// Manager while it is in progress.
+	CommandStatus(id string) (string, error)
+}
+
+type commandTarget struct {
+	Key    string   `json:"Key"`
+	Values []string `json:"Values"`
+}
+
+type sendCommandInput struct {
+	DocumentName   string              `json:"DocumentName"`
+	Comment        string              `json:"Comment,omitempty"`
+	InstanceIDs    []string            `json:"InstanceIds,omitempty"`
+	Targets        []commandTarget     `json:"Targets,omitempty"`
+	Parameters     map[string][]string `json:"Parameters"`
+	MaxConcurrency string              `json:"MaxConcurrency,omitempty"`
+}
+
+type sendCommandOutput struct {
+	Command struct {
+		CommandID string `json:"CommandId"`
+	} `json:"Command"`
+}
+
+type listCommandsInput struct {
+	CommandID string `json:"CommandId"`
+}
+
+type listCommandsOutput struct {
+	Commands []struct {
+		CommandID string `json:"CommandId"`
+		Status    string `json:"Status"`
+	} `json:"Commands"`
+}
+
+// RunShellScript implements Commands.
+func (s *Service) RunShellScript(comment, script string, instanceIDs []string, tags map[string]string) (string, error) {
+	input := &sendCommandInput{
+		DocumentName:   shellScriptDocument,
+		Comment:        comment,
+		InstanceIDs:    instanceIDs,
+		Parameters:     map[string][]string{"commands": {script}},
+		MaxConcurrency: "1",
+	}
+	if len(instanceIDs) == 0 {
+		keys := make([]string, 0, len(tags))
+		for k := range tags {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			input.Targets = append(input.Targets, commandTarget{Key: "tag:" + k, Values: []string{tags[k]}})
+		}
+	}
+
+	out := &sendCommandOutput{}
+	if err := jsonprotocol.Send(s.client, "SendCommand", input, out); err != nil {
+		return "", errors.Wrapf(err, "failed to send command %q", comment)
+	}
+	return out.Command.CommandID, nil
+}
+
+// CommandStatus implements Commands.
+func (s *Service) CommandStatus(id string) (string, error) {
+	out := &listCommandsOutput{}
+	if err := jsonprotocol.Send(s.client, "ListCommands", &listCommandsInput{CommandID: id}, out); err != nil {
+		return "", errors.Wrapf(err, "failed to list command %q", id)
+	}
+	if len(out.Commands) == 0 {
+		return "", errors.Errorf("command %q not found", id)
+	}
+	return commandStatus(out.Commands[0].Status), nil
+}
+
+// commandStatus reduces the statuses of completed commands to Success and
+// Failed.
+func commandStatus(status string) string {
+	switch status {
+	case "Success":
+		return CommandStatusSuccess
+	case "Failed", "TimedOut", "Cancelled", "Cancelling":
+		return CommandStatusFailed
+	default:
+		return status
+	}
+}
@@ -0,0 +1,91 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssm
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+)
+
+func TestCommands(t *testing.T) {
+	var sent []sendCommandInput
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Header.Get("X-Amz-Target") {
+		case "AmazonSSM.SendCommand":
+			input := sendCommandInput{}
+			json.NewDecoder(r.Body).Decode(&input)
+			if input.DocumentName != shellScriptDocument || input.MaxConcurrency != "1" || input.Parameters["commands"][0] != "echo hello" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			sent = append(sent, input)
+			json.NewEncoder(w).Encode(map[string]interface{}{"Command": map[string]string{"CommandId": "command-1"}})
+
+		case "AmazonSSM.ListCommands":
+			input := listCommandsInput{}
+			json.NewDecoder(r.Body).Decode(&input)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"Commands": []map[string]string{{"CommandId": input.CommandID, "Status": "TimedOut"}},
+			})
+
+		default:
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	defer server.Close()
+
+	sess, err := session.NewSession(aws.NewConfig().
+		WithRegion("us-east-1").
+		WithEndpoint(server.URL).
+		WithCredentials(credentials.NewStaticCredentials("id", "secret", "")))
+	if err != nil {
+		t.Fatalf("failed to create session: %v", err)
+	}
+
+	s := NewService(sess)
+
+	id, err := s.RunShellScript("hello", "echo hello", []string{"i-1"}, map[string]string{"ignored": "true"})
+	if err != nil || id != "command-1" {
+		t.Fatalf("expected command-1, got %q, %v", id, err)
+	}
+	if _, err := s.RunShellScript("hello", "echo hello", nil, map[string]string{"role": "node", "cluster": "owned"}); err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+
+	if len(sent) != 2 {
+		t.Fatalf("expected 2 commands, got %d", len(sent))
+	}
+	if !reflect.DeepEqual(sent[0].InstanceIDs, []string{"i-1"}) || len(sent[0].Targets) != 0 {
+		t.Fatalf("expected the first command to target i-1, got %+v", sent[0])
+	}
+	expected := []commandTarget{{Key: "tag:cluster", Values: []string{"owned"}}, {Key: "tag:role", Values: []string{"node"}}}
+	if len(sent[1].InstanceIDs) != 0 || !reflect.DeepEqual(sent[1].Targets, expected) {
+		t.Fatalf("expected the second command to target %+v, got %+v", expected, sent[1])
+	}
+
+	status, err := s.CommandStatus("command-1")
+	if err != nil || status != CommandStatusFailed {
+		t.Fatalf("expected status %q, got %q, %v", CommandStatusFailed, status, err)
+	}
+}
@@ -0,0 +1,94 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssm
+
+import (
+	"strconv"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/jsonprotocol"
+)
+
+// portForwardingDocument is the Session Manager document forwarding a port
+// of a host reachable from the target instance.
+const portForwardingDocument = "AWS-StartPortForwardingSessionToRemoteHost"
+
+// Session is a Session Manager session, whose data channel is opened with
+// its token on its stream URL.
+type Session struct {
+	// ID is the ID of the session.
+	ID string
+
+	// StreamURL is the URL of the WebSocket of the data channel of the session.
+	StreamURL string
+
+	// TokenValue authenticates the opening of the data channel.
+	TokenValue string
+}
+
+// Sessions starts and terminates Session Manager sessions.
+type Sessions interface {
+	// StartPortForwardingSession starts a session forwarding a port of a
+	// host through the SSM agent of the target instance.
+	StartPortForwardingSession(target, host string, port int) (*Session, error)
+
+	// TerminateSession terminates a session.
+	TerminateSession(id string) error
+}
+
+type startSessionInput struct {
+	Target       string              `json:"Target"`
+	DocumentName string              `json:"DocumentName"`
+	Parameters   map[string][]string `json:"Parameters"`
+	Reason       string              `json:"Reason,omitempty"`
+}
+
+type startSessionOutput struct {
+	SessionID  string `json:"SessionId"`
+	StreamURL  string `json:"StreamUrl"`
+	TokenValue string `json:"TokenValue"`
+}
+
+type terminateSessionInput struct {
+	SessionID string `json:"SessionId"`
+}
+
+// StartPortForwardingSession implements Sessions.
+func (s *Service) StartPortForwardingSession(target, host string, port int) (*Session, error) {
+	input := &startSessionInput{
+		Target:       target,
+		DocumentName: portForwardingDocument,
+		Parameters: map[string][]string{
+			"host":       {host},
+			"portNumber": {strconv.Itoa(port)},
+		},
+		Reason: "cluster-api-provider-aws",
+	}
+	out := &startSessionOutput{}
+	if err := jsonprotocol.Send(s.client, "StartSession", input, out); err != nil {
+		return nil, errors.Wrapf(err, "failed to start session forwarding %s:%d through instance %q", host, port, target)
+	}
+	return &Session{ID: out.SessionID, StreamURL: out.StreamURL, TokenValue: out.TokenValue}, nil
+}
+
+// TerminateSession implements Sessions.
+func (s *Service) TerminateSession(id string) error {
+	if err := jsonprotocol.Send(s.client, "TerminateSession", &terminateSessionInput{SessionID: id}, nil); err != nil {
+		return errors.Wrapf(err, "failed to terminate session %q", id)
+	}
+	return nil
+}
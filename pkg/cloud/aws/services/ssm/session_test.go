@@ -0,0 +1,84 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssm
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+)
+
+func TestSessions(t *testing.T) {
+	var terminated []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Header.Get("X-Amz-Target") {
+		case "AmazonSSM.StartSession":
+			input := &startSessionInput{}
+			json.NewDecoder(r.Body).Decode(input)
+			if input.Target != "i-1" || input.DocumentName != portForwardingDocument ||
+				input.Parameters["host"][0] != "api.example.com" || input.Parameters["portNumber"][0] != "6443" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]string{
+				"SessionId":  "session-1",
+				"StreamUrl":  "wss://ssmmessages.us-east-1.amazonaws.com/v1/data-channel/session-1",
+				"TokenValue": "token",
+			})
+
+		case "AmazonSSM.TerminateSession":
+			input := &terminateSessionInput{}
+			json.NewDecoder(r.Body).Decode(input)
+			terminated = append(terminated, input.SessionID)
+			json.NewEncoder(w).Encode(map[string]string{"SessionId": input.SessionID})
+
+		default:
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	defer server.Close()
+
+	sess, err := session.NewSession(aws.NewConfig().
+		WithRegion("us-east-1").
+		WithEndpoint(server.URL).
+		WithCredentials(credentials.NewStaticCredentials("id", "secret", "")))
+	if err != nil {
+		t.Fatalf("failed to create session: %v", err)
+	}
+
+	s := NewService(sess)
+
+	started, err := s.StartPortForwardingSession("i-1", "api.example.com", 6443)
+	if err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+	if started.ID != "session-1" || started.TokenValue != "token" || started.StreamURL == "" {
+		t.Fatalf("unexpected session %+v", started)
+	}
+
+	if err := s.TerminateSession(started.ID); err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+	if len(terminated) != 1 || terminated[0] != "session-1" {
+		t.Fatalf("expected the session to be terminated, got %v", terminated)
+	}
+}
@@ -0,0 +1,168 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package ssm reads and writes parameters of the Parameter Store of AWS
+// Systems Manager, runs its Automation runbooks and Run Command scripts, and
+// starts Session Manager sessions.
+package ssm
+
+import (
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/awserrors"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/jsonprotocol"
+)
+
+const (
+	ssmServiceName  = "ssm"
+	ssmAPIVersion   = "2014-11-06"
+	ssmTargetPrefix = "AmazonSSM"
+
+	// errCodeParameterNotFound is returned by Systems Manager for missing parameters.
+	errCodeParameterNotFound = "ParameterNotFound"
+
+	// deleteParametersMaxNames is the maximum number of parameters deleted
+	// by a DeleteParameters request.
+	deleteParametersMaxNames = 10
+)
+
+// ParameterStore reads and writes parameters by name.
+type ParameterStore interface {
+	// GetParameter returns the value of a parameter, or an error satisfying
+	// awserrors.IsNotFound if the parameter does not exist.
+	GetParameter(name string) (string, error)
+
+	// PutSecureParameter creates or overwrites a SecureString parameter,
+	// encrypted with the default key of the account.
+	PutSecureParameter(name, value string) error
+
+	// DeleteParametersByPath deletes the parameters under the given path.
+	// Deleting missing parameters is not an error.
+	DeleteParametersByPath(path string) error
+}
+
+// Service reads and writes parameters of the Parameter Store, runs
+// Automation runbooks and scripts, and starts sessions.
+//
+// The vendored SDK has no Systems Manager client, so requests are sent with a
+// generic SDK client speaking the JSON protocol of the service.
+type Service struct {
+	client *client.Client
+}
+
+type getParameterInput struct {
+	Name string `json:"Name"`
+}
+
+type getParameterOutput struct {
+	Parameter struct {
+		Value string `json:"Value"`
+	} `json:"Parameter"`
+}
+
+type putParameterInput struct {
+	Name      string `json:"Name"`
+	Value     string `json:"Value"`
+	Type      string `json:"Type"`
+	Tier      string `json:"Tier"`
+	Overwrite bool   `json:"Overwrite"`
+}
+
+type getParametersByPathInput struct {
+	Path      string `json:"Path"`
+	Recursive bool   `json:"Recursive"`
+	NextToken string `json:"NextToken,omitempty"`
+}
+
+type getParametersByPathOutput struct {
+	Parameters []struct {
+		Name string `json:"Name"`
+	} `json:"Parameters"`
+	NextToken string `json:"NextToken"`
+}
+
+type deleteParametersInput struct {
+	Names []string `json:"Names"`
+}
+
+// NewService returns a service reading parameters in the region of the session.
+func NewService(sess *session.Session) *Service {
+	return &Service{
+		client: jsonprotocol.NewClient(sess, ssmServiceName, ssmAPIVersion, ssmTargetPrefix),
+	}
+}
+
+// GetParameter implements ParameterStore.
+func (s *Service) GetParameter(name string) (string, error) {
+	out := &getParameterOutput{}
+	if err := jsonprotocol.Send(s.client, "GetParameter", &getParameterInput{Name: name}, out); err != nil {
+		if code, _ := awserrors.Code(err); code == errCodeParameterNotFound {
+			return "", awserrors.NewNotFound(errors.Errorf("ssm parameter %q not found", name))
+		}
+		return "", errors.Wrapf(err, "failed to get ssm parameter %q", name)
+	}
+
+	return out.Parameter.Value, nil
+}
+
+// PutSecureParameter implements ParameterStore. Parameters larger than the
+// 4KB of the standard tier are stored in the advanced tier.
+func (s *Service) PutSecureParameter(name, value string) error {
+	input := &putParameterInput{
+		Name:      name,
+		Value:     value,
+		Type:      "SecureString",
+		Tier:      "Intelligent-Tiering",
+		Overwrite: true,
+	}
+	if err := jsonprotocol.Send(s.client, "PutParameter", input, nil); err != nil {
+		return errors.Wrapf(err, "failed to put ssm parameter %q", name)
+	}
+	return nil
+}
+
+// DeleteParametersByPath implements ParameterStore.
+func (s *Service) DeleteParametersByPath(path string) error {
+	var names []string
+	input := &getParametersByPathInput{Path: path, Recursive: true}
+	for {
+		out := &getParametersByPathOutput{}
+		if err := jsonprotocol.Send(s.client, "GetParametersByPath", input, out); err != nil {
+			return errors.Wrapf(err, "failed to list ssm parameters under %q", path)
+		}
+		for _, p := range out.Parameters {
+			names = append(names, p.Name)
+		}
+		if out.NextToken == "" {
+			break
+		}
+		input.NextToken = out.NextToken
+	}
+
+	for len(names) > 0 {
+		n := len(names)
+		if n > deleteParametersMaxNames {
+			n = deleteParametersMaxNames
+		}
+		if err := jsonprotocol.Send(s.client, "DeleteParameters", &deleteParametersInput{Names: names[:n]}, nil); err != nil {
+			return errors.Wrapf(err, "failed to delete ssm parameters under %q", path)
+		}
+		names = names[n:]
+	}
+
+	return nil
+}
@@ -0,0 +1,163 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssm
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/awserrors"
+)
+
+func TestGetParameter(t *testing.T) {
+	parameters := map[string]string{
+		"/aws/service/ami-amazon-linux-latest/amzn2-ami-hvm-x86_64-gp2": "ami-0123456789abcdef0",
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256") || r.Header.Get("X-Amz-Target") != "AmazonSSM.GetParameter" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		input := struct {
+			Name string `json:"Name"`
+		}{}
+		json.NewDecoder(r.Body).Decode(&input)
+
+		value, ok := parameters[input.Name]
+		if !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"__type": "ParameterNotFound"})
+			return
+		}
+
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"Parameter": map[string]string{"Name": input.Name, "Type": "String", "Value": value},
+		})
+	}))
+	defer server.Close()
+
+	sess, err := session.NewSession(aws.NewConfig().
+		WithRegion("us-east-1").
+		WithEndpoint(server.URL).
+		WithCredentials(credentials.NewStaticCredentials("id", "secret", "")))
+	if err != nil {
+		t.Fatalf("failed to create session: %v", err)
+	}
+
+	s := NewService(sess)
+
+	value, err := s.GetParameter("/aws/service/ami-amazon-linux-latest/amzn2-ami-hvm-x86_64-gp2")
+	if err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+	if value != "ami-0123456789abcdef0" {
+		t.Fatalf("expected parameter value %q, got %q", "ami-0123456789abcdef0", value)
+	}
+
+	if _, err := s.GetParameter("/missing"); !awserrors.IsNotFound(err) {
+		t.Fatalf("expected not found error, got: %v", err)
+	}
+}
+
+func TestPutAndDeleteParameters(t *testing.T) {
+	parameters := map[string]string{
+		"/other/file": "b3RoZXI=",
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		input := struct {
+			Name      string   `json:"Name"`
+			Value     string   `json:"Value"`
+			Type      string   `json:"Type"`
+			Overwrite bool     `json:"Overwrite"`
+			Path      string   `json:"Path"`
+			NextToken string   `json:"NextToken"`
+			Names     []string `json:"Names"`
+		}{}
+		json.NewDecoder(r.Body).Decode(&input)
+
+		switch r.Header.Get("X-Amz-Target") {
+		case "AmazonSSM.PutParameter":
+			if input.Type != "SecureString" || !input.Overwrite {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			parameters[input.Name] = input.Value
+			json.NewEncoder(w).Encode(map[string]int{"Version": 1})
+
+		case "AmazonSSM.GetParametersByPath":
+			// Return one parameter per page.
+			var names []map[string]string
+			for name := range parameters {
+				if strings.HasPrefix(name, input.Path+"/") && name > input.NextToken {
+					if len(names) == 0 || name < names[0]["Name"] {
+						names = []map[string]string{{"Name": name}}
+					}
+				}
+			}
+			out := map[string]interface{}{"Parameters": names}
+			if len(names) > 0 {
+				out["NextToken"] = names[0]["Name"]
+			}
+			json.NewEncoder(w).Encode(out)
+
+		case "AmazonSSM.DeleteParameters":
+			for _, name := range input.Names {
+				delete(parameters, name)
+			}
+			json.NewEncoder(w).Encode(map[string][]string{"DeletedParameters": input.Names})
+
+		default:
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	defer server.Close()
+
+	sess, err := session.NewSession(aws.NewConfig().
+		WithRegion("us-east-1").
+		WithEndpoint(server.URL).
+		WithCredentials(credentials.NewStaticCredentials("id", "secret", "")))
+	if err != nil {
+		t.Fatalf("failed to create session: %v", err)
+	}
+
+	s := NewService(sess)
+
+	for _, name := range []string{"/machine/files/a", "/machine/files/b"} {
+		if err := s.PutSecureParameter(name, "c2VjcmV0"); err != nil {
+			t.Fatalf("did not expect error: %v", err)
+		}
+	}
+	if parameters["/machine/files/a"] != "c2VjcmV0" {
+		t.Fatalf("expected parameter to be stored, got %v", parameters)
+	}
+
+	if err := s.DeleteParametersByPath("/machine"); err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+	if len(parameters) != 1 || parameters["/other/file"] == "" {
+		t.Fatalf("expected only the parameters under the path to be deleted, got %v", parameters)
+	}
+}
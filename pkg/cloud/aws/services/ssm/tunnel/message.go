@@ -0,0 +1,145 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tunnel
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+// Types of the messages of a data channel.
+const (
+	messageInputStreamData  = "input_stream_data"
+	messageOutputStreamData = "output_stream_data"
+	messageAcknowledge      = "acknowledge"
+	messageChannelClosed    = "channel_closed"
+	messageStartPublication = "start_publication"
+	messagePausePublication = "pause_publication"
+)
+
+// Types of the payloads of stream data messages.
+const (
+	payloadOutput            = 1
+	payloadHandshakeRequest  = 5
+	payloadHandshakeResponse = 6
+	payloadHandshakeComplete = 7
+	payloadFlag              = 10
+)
+
+// Flags of stream data messages.
+const (
+	flagData = 0
+	flagSyn  = 1
+	flagAck  = 3
+)
+
+// flagDisconnectToPort is the payload of the flag message asking the agent
+// to disconnect from the forwarded port.
+const flagDisconnectToPort = 1
+
+// Layout of the header of a message, whose fields are big endian.
+const (
+	messageTypeOffset     = 4
+	messageTypeLength     = 32
+	schemaVersionOffset   = messageTypeOffset + messageTypeLength
+	createdDateOffset     = schemaVersionOffset + 4
+	sequenceNumberOffset  = createdDateOffset + 8
+	flagsOffset           = sequenceNumberOffset + 8
+	messageIDOffset       = flagsOffset + 8
+	payloadDigestOffset   = messageIDOffset + 16
+	payloadTypeOffset     = payloadDigestOffset + 32
+	payloadLengthOffset   = payloadTypeOffset + 4
+	payloadOffset         = payloadLengthOffset + 4
+	messageSchemaVersion  = 1
+	messageHeaderLength   = payloadLengthOffset
+	streamDataPayloadSize = 1024
+)
+
+// message is a message of the data channel of a session.
+type message struct {
+	Type           string
+	CreatedDate    time.Time
+	SequenceNumber int64
+	Flags          uint64
+	ID             uuid.UUID
+	PayloadType    uint32
+	Payload        []byte
+}
+
+// marshal encodes a message. The halves of its ID are swapped, the least
+// significant one coming first.
+func (m *message) marshal() []byte {
+	b := make([]byte, payloadOffset+len(m.Payload))
+
+	binary.BigEndian.PutUint32(b, messageHeaderLength)
+	copy(b[messageTypeOffset:schemaVersionOffset], m.Type+strings.Repeat(" ", messageTypeLength-len(m.Type)))
+	binary.BigEndian.PutUint32(b[schemaVersionOffset:], messageSchemaVersion)
+	binary.BigEndian.PutUint64(b[createdDateOffset:], uint64(m.CreatedDate.UnixNano()/int64(time.Millisecond)))
+	binary.BigEndian.PutUint64(b[sequenceNumberOffset:], uint64(m.SequenceNumber))
+	binary.BigEndian.PutUint64(b[flagsOffset:], m.Flags)
+	copy(b[messageIDOffset:], m.ID[8:])
+	copy(b[messageIDOffset+8:], m.ID[:8])
+	digest := sha256.Sum256(m.Payload)
+	copy(b[payloadDigestOffset:], digest[:])
+	binary.BigEndian.PutUint32(b[payloadTypeOffset:], m.PayloadType)
+	binary.BigEndian.PutUint32(b[payloadLengthOffset:], uint32(len(m.Payload)))
+	copy(b[payloadOffset:], m.Payload)
+
+	return b
+}
+
+// unmarshalMessage decodes a message, checking the digest of its payload.
+func unmarshalMessage(b []byte) (*message, error) {
+	if len(b) < payloadOffset {
+		return nil, errors.Errorf("message of %d bytes is shorter than its header", len(b))
+	}
+
+	headerLength := binary.BigEndian.Uint32(b)
+	if int(headerLength)+4 > len(b) || headerLength < messageHeaderLength {
+		return nil, errors.Errorf("invalid message header length %d", headerLength)
+	}
+
+	m := &message{
+		Type:           strings.TrimRight(string(bytes.TrimRight(b[messageTypeOffset:schemaVersionOffset], "\x00")), " "),
+		CreatedDate:    time.Unix(0, int64(binary.BigEndian.Uint64(b[createdDateOffset:]))*int64(time.Millisecond)),
+		SequenceNumber: int64(binary.BigEndian.Uint64(b[sequenceNumberOffset:])),
+		Flags:          binary.BigEndian.Uint64(b[flagsOffset:]),
+		PayloadType:    binary.BigEndian.Uint32(b[payloadTypeOffset:]),
+	}
+	copy(m.ID[8:], b[messageIDOffset:])
+	copy(m.ID[:8], b[messageIDOffset+8:payloadDigestOffset])
+
+	payloadLength := binary.BigEndian.Uint32(b[headerLength:])
+	start := int(headerLength) + 4
+	if uint64(start)+uint64(payloadLength) > uint64(len(b)) {
+		return nil, errors.Errorf("message payload of %d bytes exceeds the message", payloadLength)
+	}
+	m.Payload = b[start : start+int(payloadLength)]
+
+	digest := sha256.Sum256(m.Payload)
+	if !bytes.Equal(digest[:], b[payloadDigestOffset:payloadTypeOffset]) {
+		return nil, errors.Errorf("invalid digest of %s message %d", m.Type, m.SequenceNumber)
+	}
+
+	return m, nil
+}
@@ -0,0 +1,514 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package tunnel connects to the hosts of a VPC through Session Manager port
+// forwarding sessions, speaking the protocol of the data channels of the
+// Session Manager plugin.
+package tunnel
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/ssm"
+)
+
+const (
+	// clientVersion is the version of the Session Manager plugin presented to
+	// the agents. Agents forward a single connection per session, without
+	// multiplexing, to the plugins older than 1.1.70.
+	clientVersion = "1.1.61"
+
+	// resendInterval is how often unacknowledged messages are sent again.
+	resendInterval = 3 * time.Second
+
+	// closeTimeout bounds the time spent notifying the agent of a closing
+	// connection.
+	closeTimeout = 5 * time.Second
+)
+
+// errClosed is returned by the operations on closed connections, with the
+// message of the errors of closed network connections.
+var errClosed = errors.New("use of closed network connection")
+
+// Statuses of the client actions processed during a handshake.
+const (
+	actionSuccess     = 1
+	actionUnsupported = 3
+)
+
+// Dial opens a connection to a port of a host through a new port forwarding
+// session of the SSM agent of the target instance. Closing the connection
+// terminates the session.
+func Dial(ctx context.Context, sessions ssm.Sessions, target, host string, port int) (net.Conn, error) {
+	return dial(ctx, sessions, target, host, port, nil)
+}
+
+func dial(ctx context.Context, sessions ssm.Sessions, target, host string, port int, tlsConfig *tls.Config) (*conn, error) {
+	session, err := sessions.StartPortForwardingSession(target, host, port)
+	if err != nil {
+		return nil, err
+	}
+
+	ws, err := dialWebSocket(ctx, session.StreamURL, tlsConfig)
+	if err != nil {
+		sessions.TerminateSession(session.ID)
+		return nil, errors.Wrapf(err, "failed to open the data channel of session %q", session.ID)
+	}
+
+	c := &conn{
+		ws:         ws,
+		sessions:   sessions,
+		sessionID:  session.ID,
+		remote:     addr(net.JoinHostPort(host, strconv.Itoa(port))),
+		pending:    map[int64]*message{},
+		unacked:    map[int64]*sentMessage{},
+		handshaken: make(chan struct{}),
+		failed:     make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+	c.cond = sync.NewCond(&c.mu)
+
+	open, err := json.Marshal(map[string]string{
+		"MessageSchemaVersion": "1.0",
+		"RequestId":            uuid.New().String(),
+		"TokenValue":           session.TokenValue,
+		"ClientId":             uuid.New().String(),
+		"ClientVersion":        clientVersion,
+	})
+	if err != nil {
+		c.Close()
+		return nil, err
+	}
+	if err := ws.WriteMessage(opText, open); err != nil {
+		c.Close()
+		return nil, errors.Wrapf(err, "failed to open the data channel of session %q", session.ID)
+	}
+
+	go c.readLoop()
+	go c.resendLoop()
+
+	select {
+	case <-c.handshaken:
+		return c, nil
+	case <-c.failed:
+		c.Close()
+		return nil, errors.Wrapf(c.err, "failed to open the data channel of session %q", session.ID)
+	case <-ctx.Done():
+		c.Close()
+		return nil, ctx.Err()
+	}
+}
+
+// addr is the address of the forwarded host.
+type addr string
+
+func (a addr) Network() string { return "ssm" }
+func (a addr) String() string  { return string(a) }
+
+// sentMessage is a message sent but not acknowledged yet.
+type sentMessage struct {
+	data []byte
+	at   time.Time
+}
+
+// conn is a connection forwarded by a session.
+type conn struct {
+	ws        *webSocket
+	sessions  ssm.Sessions
+	sessionID string
+	remote    addr
+
+	// wmu serializes the messages sent, in the order of their sequence numbers.
+	wmu      sync.Mutex
+	sequence int64
+
+	mu   sync.Mutex
+	cond *sync.Cond
+
+	// expected is the sequence number of the next message of the agent, the
+	// messages following it being pending.
+	expected int64
+	pending  map[int64]*message
+
+	// buf holds the data forwarded by the agent and not read yet.
+	buf []byte
+
+	unacked      map[int64]*sentMessage
+	paused       bool
+	readDeadline time.Time
+	readTimer    *time.Timer
+
+	// err is the error ending the connection, after which failed is closed.
+	err    error
+	failed chan struct{}
+
+	handshaken chan struct{}
+	closeOnce  sync.Once
+	done       chan struct{}
+}
+
+// readLoop handles the messages of the agent until the data channel closes.
+func (c *conn) readLoop() {
+	for {
+		_, data, err := c.ws.ReadMessage()
+		if err != nil {
+			c.fail(err)
+			return
+		}
+
+		m, err := unmarshalMessage(data)
+		if err != nil {
+			c.fail(err)
+			return
+		}
+
+		switch m.Type {
+		case messageOutputStreamData:
+			if err := c.acknowledge(m); err != nil {
+				c.fail(err)
+				return
+			}
+			for _, m := range c.receive(m) {
+				if err := c.handle(m); err != nil {
+					c.fail(err)
+					return
+				}
+			}
+
+		case messageAcknowledge:
+			ack := struct {
+				AcknowledgedMessageSequenceNumber int64
+			}{}
+			if err := json.Unmarshal(m.Payload, &ack); err != nil {
+				c.fail(errors.Wrap(err, "invalid acknowledgement"))
+				return
+			}
+			c.mu.Lock()
+			delete(c.unacked, ack.AcknowledgedMessageSequenceNumber)
+			c.mu.Unlock()
+
+		case messagePausePublication, messageStartPublication:
+			c.mu.Lock()
+			c.paused = m.Type == messagePausePublication
+			c.cond.Broadcast()
+			c.mu.Unlock()
+
+		case messageChannelClosed:
+			c.fail(io.EOF)
+			return
+		}
+	}
+}
+
+// receive returns the messages of the agent which are next in sequence once
+// the given one is received, ignoring those already received.
+func (c *conn) receive(m *message) []*message {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if m.SequenceNumber < c.expected {
+		return nil
+	}
+	c.pending[m.SequenceNumber] = m
+
+	var next []*message
+	for {
+		m, ok := c.pending[c.expected]
+		if !ok {
+			return next
+		}
+		delete(c.pending, c.expected)
+		c.expected++
+		next = append(next, m)
+	}
+}
+
+// handle handles a stream data message of the agent.
+func (c *conn) handle(m *message) error {
+	switch m.PayloadType {
+	case payloadOutput:
+		c.mu.Lock()
+		c.buf = append(c.buf, m.Payload...)
+		c.cond.Broadcast()
+		c.mu.Unlock()
+		return nil
+
+	case payloadHandshakeRequest:
+		return c.handshake(m.Payload)
+
+	case payloadHandshakeComplete:
+		close(c.handshaken)
+		return nil
+	}
+
+	return nil
+}
+
+type clientAction struct {
+	ActionType   string `json:"ActionType"`
+	ActionStatus int    `json:"ActionStatus"`
+	Error        string `json:"Error,omitempty"`
+}
+
+// handshake answers the handshake request of the agent. Sessions requiring
+// client actions other than setting their type, such as KMS encryption,
+// are not supported.
+func (c *conn) handshake(payload []byte) error {
+	req := struct {
+		RequestedClientActions []struct {
+			ActionType string `json:"ActionType"`
+		} `json:"RequestedClientActions"`
+	}{}
+	if err := json.Unmarshal(payload, &req); err != nil {
+		return errors.Wrap(err, "invalid handshake request")
+	}
+
+	resp := struct {
+		ClientVersion          string         `json:"ClientVersion"`
+		ProcessedClientActions []clientAction `json:"ProcessedClientActions"`
+		Errors                 []string       `json:"Errors"`
+	}{ClientVersion: clientVersion, Errors: []string{}}
+
+	var unsupported error
+	for _, a := range req.RequestedClientActions {
+		if a.ActionType == "SessionType" {
+			resp.ProcessedClientActions = append(resp.ProcessedClientActions, clientAction{ActionType: a.ActionType, ActionStatus: actionSuccess})
+			continue
+		}
+		unsupported = errors.Errorf("session requires the unsupported client action %q", a.ActionType)
+		resp.ProcessedClientActions = append(resp.ProcessedClientActions, clientAction{ActionType: a.ActionType, ActionStatus: actionUnsupported, Error: unsupported.Error()})
+		resp.Errors = append(resp.Errors, unsupported.Error())
+	}
+
+	data, err := json.Marshal(resp)
+	if err != nil {
+		return err
+	}
+	if err := c.send(payloadHandshakeResponse, data); err != nil {
+		return err
+	}
+	return unsupported
+}
+
+// acknowledge acknowledges a stream data message of the agent.
+func (c *conn) acknowledge(m *message) error {
+	payload, err := json.Marshal(map[string]interface{}{
+		"AcknowledgedMessageType":           m.Type,
+		"AcknowledgedMessageId":             m.ID.String(),
+		"AcknowledgedMessageSequenceNumber": m.SequenceNumber,
+		"IsSequentialMessage":               true,
+	})
+	if err != nil {
+		return err
+	}
+
+	ack := &message{
+		Type:        messageAcknowledge,
+		CreatedDate: time.Now(),
+		Flags:       flagAck,
+		ID:          uuid.New(),
+		Payload:     payload,
+	}
+
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	return c.ws.WriteMessage(opBinary, ack.marshal())
+}
+
+// send sends a stream data message, which is sent again until the agent
+// acknowledges it.
+func (c *conn) send(payloadType uint32, payload []byte) error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+
+	m := &message{
+		Type:           messageInputStreamData,
+		CreatedDate:    time.Now(),
+		SequenceNumber: c.sequence,
+		Flags:          flagData,
+		ID:             uuid.New(),
+		PayloadType:    payloadType,
+		Payload:        payload,
+	}
+	if m.SequenceNumber == 0 {
+		m.Flags = flagSyn
+	}
+	data := m.marshal()
+
+	c.mu.Lock()
+	c.unacked[m.SequenceNumber] = &sentMessage{data: data, at: time.Now()}
+	c.mu.Unlock()
+
+	c.sequence++
+	return c.ws.WriteMessage(opBinary, data)
+}
+
+// resendLoop sends the messages the agent did not acknowledge again.
+func (c *conn) resendLoop() {
+	ticker := time.NewTicker(resendInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.done:
+			return
+		case now := <-ticker.C:
+			var resend [][]byte
+			c.mu.Lock()
+			for _, s := range c.unacked {
+				if now.Sub(s.at) >= resendInterval {
+					s.at = now
+					resend = append(resend, s.data)
+				}
+			}
+			c.mu.Unlock()
+
+			c.wmu.Lock()
+			for _, data := range resend {
+				c.ws.WriteMessage(opBinary, data)
+			}
+			c.wmu.Unlock()
+		}
+	}
+}
+
+// fail ends the connection with an error, once.
+func (c *conn) fail(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.err != nil {
+		return
+	}
+	c.err = err
+	close(c.failed)
+	c.cond.Broadcast()
+}
+
+// Read implements net.Conn.
+func (c *conn) Read(b []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for len(c.buf) == 0 && c.err == nil {
+		if !c.readDeadline.IsZero() && !time.Now().Before(c.readDeadline) {
+			return 0, os.ErrDeadlineExceeded
+		}
+		c.cond.Wait()
+	}
+
+	if len(c.buf) == 0 {
+		return 0, c.err
+	}
+
+	n := copy(b, c.buf)
+	c.buf = c.buf[n:]
+	return n, nil
+}
+
+// Write implements net.Conn. Data is sent in chunks of the size the agent
+// expects, waiting while the agent pauses publication.
+func (c *conn) Write(b []byte) (int, error) {
+	written := 0
+	for written < len(b) {
+		c.mu.Lock()
+		for c.paused && c.err == nil {
+			c.cond.Wait()
+		}
+		err := c.err
+		c.mu.Unlock()
+		if err != nil {
+			return written, err
+		}
+
+		n := len(b) - written
+		if n > streamDataPayloadSize {
+			n = streamDataPayloadSize
+		}
+		if err := c.send(payloadOutput, b[written:written+n]); err != nil {
+			return written, err
+		}
+		written += n
+	}
+	return written, nil
+}
+
+// Close implements net.Conn. The agent disconnects from the forwarded port,
+// and the session is terminated.
+func (c *conn) Close() error {
+	var err error
+	c.closeOnce.Do(func() {
+		c.ws.conn.SetWriteDeadline(time.Now().Add(closeTimeout))
+		flag := make([]byte, 4)
+		binary.BigEndian.PutUint32(flag, flagDisconnectToPort)
+		c.send(payloadFlag, flag)
+
+		c.fail(errClosed)
+		close(c.done)
+		c.ws.Close()
+		err = c.sessions.TerminateSession(c.sessionID)
+	})
+	return err
+}
+
+// LocalAddr implements net.Conn.
+func (c *conn) LocalAddr() net.Addr { return addr(c.sessionID) }
+
+// RemoteAddr implements net.Conn.
+func (c *conn) RemoteAddr() net.Addr { return c.remote }
+
+// SetDeadline implements net.Conn.
+func (c *conn) SetDeadline(t time.Time) error {
+	c.SetReadDeadline(t)
+	return c.SetWriteDeadline(t)
+}
+
+// SetReadDeadline implements net.Conn.
+func (c *conn) SetReadDeadline(t time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.readDeadline = t
+	if c.readTimer != nil {
+		c.readTimer.Stop()
+		c.readTimer = nil
+	}
+	if !t.IsZero() {
+		c.readTimer = time.AfterFunc(time.Until(t), func() {
+			c.mu.Lock()
+			c.cond.Broadcast()
+			c.mu.Unlock()
+		})
+	}
+	c.cond.Broadcast()
+	return nil
+}
+
+// SetWriteDeadline implements net.Conn.
+func (c *conn) SetWriteDeadline(t time.Time) error {
+	return c.ws.conn.SetWriteDeadline(t)
+}
@@ -0,0 +1,244 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tunnel
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/ssm"
+)
+
+type fakeSessions struct {
+	url        string
+	started    []string
+	terminated []string
+}
+
+func (f *fakeSessions) StartPortForwardingSession(target, host string, port int) (*ssm.Session, error) {
+	f.started = append(f.started, target)
+	return &ssm.Session{ID: "session-1", StreamURL: f.url, TokenValue: "token"}, nil
+}
+
+func (f *fakeSessions) TerminateSession(id string) error {
+	f.terminated = append(f.terminated, id)
+	return nil
+}
+
+// fakeAgent speaks the agent side of a data channel, upper-casing the data
+// it receives. Its messages are sent twice, the second time out of order,
+// to check that the client reorders them and ignores duplicates.
+type fakeAgent struct {
+	t        *testing.T
+	ws       *webSocket
+	sequence int64
+	actions  []string
+}
+
+func (a *fakeAgent) send(payloadType uint32, payload []byte) {
+	m := &message{
+		Type:           messageOutputStreamData,
+		CreatedDate:    time.Now(),
+		SequenceNumber: a.sequence,
+		ID:             uuid.New(),
+		PayloadType:    payloadType,
+		Payload:        payload,
+	}
+	a.sequence++
+	if err := a.ws.WriteMessage(opBinary, m.marshal()); err != nil {
+		a.t.Errorf("failed to send message: %v", err)
+	}
+}
+
+func (a *fakeAgent) serve(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Upgrade") != "websocket" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	conn, rw, err := w.(http.Hijacker).Hijack()
+	if err != nil {
+		a.t.Errorf("failed to hijack connection: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: " + acceptKey(r.Header.Get("Sec-WebSocket-Key")) + "\r\n\r\n")
+	rw.Flush()
+	a.ws = &webSocket{conn: conn, r: bufio.NewReader(rw)}
+
+	op, data, err := a.ws.ReadMessage()
+	if err != nil || op != opText || !strings.Contains(string(data), `"TokenValue":"token"`) {
+		a.t.Errorf("expected the data channel to be opened with the token, got %q, %v", data, err)
+		return
+	}
+
+	request, _ := json.Marshal(map[string]interface{}{
+		"AgentVersion": "3.1.1374.0",
+		"RequestedClientActions": []map[string]interface{}{
+			{"ActionType": "SessionType", "ActionParameters": map[string]string{"SessionType": "Port"}},
+		},
+	})
+	a.send(payloadHandshakeRequest, request)
+
+	var held *message
+	for {
+		_, data, err := a.ws.ReadMessage()
+		if err != nil {
+			return
+		}
+		m, err := unmarshalMessage(data)
+		if err != nil {
+			a.t.Errorf("invalid message: %v", err)
+			return
+		}
+		if m.Type != messageInputStreamData {
+			continue
+		}
+
+		ack, _ := json.Marshal(map[string]interface{}{
+			"AcknowledgedMessageType":           m.Type,
+			"AcknowledgedMessageId":             m.ID.String(),
+			"AcknowledgedMessageSequenceNumber": m.SequenceNumber,
+			"IsSequentialMessage":               true,
+		})
+		a.ws.WriteMessage(opBinary, (&message{Type: messageAcknowledge, ID: uuid.New(), Flags: flagAck, Payload: ack}).marshal())
+
+		switch m.PayloadType {
+		case payloadHandshakeResponse:
+			resp := struct {
+				ProcessedClientActions []clientAction
+			}{}
+			json.Unmarshal(m.Payload, &resp)
+			for _, action := range resp.ProcessedClientActions {
+				if action.ActionStatus == actionSuccess {
+					a.actions = append(a.actions, action.ActionType)
+				}
+			}
+			a.send(payloadHandshakeComplete, []byte("{}"))
+
+		case payloadOutput:
+			// Send the response of the first chunk after the second one.
+			reply := &message{
+				Type:           messageOutputStreamData,
+				SequenceNumber: a.sequence,
+				ID:             uuid.New(),
+				PayloadType:    payloadOutput,
+				Payload:        bytes.ToUpper(m.Payload),
+			}
+			a.sequence++
+			if held == nil {
+				held = reply
+				continue
+			}
+			a.ws.WriteMessage(opBinary, reply.marshal())
+			a.ws.WriteMessage(opBinary, held.marshal())
+			a.ws.WriteMessage(opBinary, held.marshal())
+
+		case payloadFlag:
+			a.ws.WriteMessage(opBinary, (&message{Type: messageChannelClosed, ID: uuid.New(), Payload: []byte("{}")}).marshal())
+			return
+		}
+	}
+}
+
+func TestDial(t *testing.T) {
+	agent := &fakeAgent{t: t}
+	server := httptest.NewTLSServer(http.HandlerFunc(agent.serve))
+	defer server.Close()
+
+	sessions := &fakeSessions{url: "wss://" + strings.TrimPrefix(server.URL, "https://") + "/v1/data-channel/session-1"}
+	tlsConfig := server.Client().Transport.(*http.Transport).TLSClientConfig
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	c, err := dial(ctx, sessions, "i-1", "internal-api.elb.amazonaws.com", 6443, tlsConfig.Clone())
+	if err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+
+	if len(agent.actions) != 1 || agent.actions[0] != "SessionType" {
+		t.Fatalf("expected the session type action to be processed, got %v", agent.actions)
+	}
+
+	for _, chunk := range []string{"hello ", "world"} {
+		if _, err := c.Write([]byte(chunk)); err != nil {
+			t.Fatalf("failed to write: %v", err)
+		}
+	}
+
+	c.SetReadDeadline(time.Now().Add(5 * time.Second))
+	out := make([]byte, len("HELLO WORLD"))
+	if _, err := io.ReadFull(c, out); err != nil {
+		t.Fatalf("failed to read: %v", err)
+	}
+	if string(out) != "HELLO WORLD" {
+		t.Fatalf("expected %q, got %q", "HELLO WORLD", out)
+	}
+
+	if err := c.Close(); err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+	if len(sessions.terminated) != 1 || sessions.terminated[0] != "session-1" {
+		t.Fatalf("expected the session to be terminated, got %v", sessions.terminated)
+	}
+	if _, err := c.Read(out); err == nil {
+		t.Fatalf("expected reads to fail once closed")
+	}
+}
+
+func TestMessage(t *testing.T) {
+	m := &message{
+		Type:           messageInputStreamData,
+		CreatedDate:    time.Unix(1500000000, 0),
+		SequenceNumber: 42,
+		Flags:          flagSyn,
+		ID:             uuid.New(),
+		PayloadType:    payloadOutput,
+		Payload:        []byte("data"),
+	}
+
+	data := m.marshal()
+	if len(data) != 120+len(m.Payload) {
+		t.Fatalf("expected a header of 120 bytes, got %d", len(data)-len(m.Payload))
+	}
+
+	decoded, err := unmarshalMessage(data)
+	if err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+	if decoded.Type != m.Type || decoded.SequenceNumber != m.SequenceNumber || decoded.Flags != m.Flags ||
+		decoded.ID != m.ID || decoded.PayloadType != m.PayloadType || !bytes.Equal(decoded.Payload, m.Payload) ||
+		!decoded.CreatedDate.Equal(m.CreatedDate) {
+		t.Fatalf("expected %+v, got %+v", m, decoded)
+	}
+
+	data[len(data)-1] ^= 1
+	if _, err := unmarshalMessage(data); err == nil {
+		t.Fatalf("expected an error for a corrupted payload")
+	}
+}
@@ -0,0 +1,30 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package userdata
+
+const (
+	// kubeadmCommandsTemplate runs the commands of the machine before and
+	// after kubeadm bootstraps the instance, such as to mount disks, join a
+	// directory or install agents.
+	kubeadmCommandsTemplate = `{{define "prekubeadm"}}{{if .PreKubeadmCommands}}
+# Run the commands configured to run before kubeadm.
+{{range .PreKubeadmCommands}}{{.}}
+{{end}}{{end}}{{end}}{{define "postkubeadm"}}{{if .PostKubeadmCommands}}
+# Run the commands configured to run after kubeadm.
+{{range .PostKubeadmCommands}}{{.}}
+{{end}}{{end}}{{end}}`
+)
@@ -19,15 +19,14 @@ package userdata
 import "github.com/pkg/errors"
 
 const (
-	controlPlaneBashScript = `{{.Header}}
-
+	controlPlaneBashScript = `{{.Header}}{{template "metadata" .}}{{template "fips" .}}{{template "serialconsole" .}}{{template "hardening" .}}{{template "ntp" .}}{{template "preflight" .}}{{template "gpu" .}}{{template "etcdvolume" .}}{{template "files" .}}
 mkdir -p /etc/kubernetes/pki
 
 echo '{{.CACert}}' > /etc/kubernetes/pki/ca.crt
 echo '{{.CAKey}}' > /etc/kubernetes/pki/ca.key
 
-PRIVATE_IP=$(curl http://169.254.169.254/latest/meta-data/local-ipv4)
-HOSTNAME="$(curl http://169.254.169.254/latest/meta-data/local-hostname)"
+PRIVATE_IP=$(metadata local-ipv4)
+HOSTNAME="$(metadata local-hostname)"
 
 cat >/tmp/kubeadm.yaml <<EOF
 ---
@@ -39,6 +38,19 @@ apiServer:
     - "{{.ELBAddress}}"
   extraArgs:
     cloud-provider: aws
+{{- if and .TransitEncryption .TransitEncryption.KubeletServingCertificates}}
+    kubelet-certificate-authority: /etc/kubernetes/pki/ca.crt
+{{- end}}
+{{- with .TransitEncryption}}
+etcd:
+  local:
+    extraArgs:
+      client-cert-auth: "true"
+      peer-client-cert-auth: "true"
+{{- if .EtcdCipherSuites}}
+      cipher-suites: "{{.EtcdCipherSuites}}"
+{{- end}}
+{{- end}}
 controlPlaneEndpoint: "{{.ELBAddress}}:6443"
 clusterName: "{{.ClusterName}}"
 networking:
@@ -54,10 +66,20 @@ nodeRegistration:
   criSocket: /var/run/containerd/containerd.sock
   kubeletExtraArgs:
     cloud-provider: aws
+{{- range $k, $v := .KubeletExtraArgs}}
+    {{$k}}: "{{$v}}"
+{{- end}}
+---
+apiVersion: kubelet.config.k8s.io/v1beta1
+kind: KubeletConfiguration
+rotateCertificates: true
+{{- if and .TransitEncryption .TransitEncryption.KubeletServingCertificates}}
+serverTLSBootstrap: true
+{{- end}}
 EOF
-
+{{template "prekubeadm" .}}
 kubeadm init --config /tmp/kubeadm.yaml
-
+{{template "etcdtls" .}}
 kubectl -n kube-system --kubeconfig /etc/kubernetes/admin.conf \
 create secret tls kubeadm-certs-ca \
 --key /etc/kubernetes/pki/ca.key \
@@ -76,10 +98,9 @@ create secret tls kubeadm-certs-front-proxy \
 # service account keys are different
 tar -cvzf /etc/kubernetes/pki/sa-certs.tar.gz /etc/kubernetes/pki/sa.*
 kubectl -n kube-system --kubeconfig /etc/kubernetes/admin.conf create secret generic kubeadm-sa-certs --from-file=/etc/kubernetes/pki/sa-certs.tar.gz
-`
-
-	controlPlaneJoinBashScript = `{{.Header}}
+{{template "postkubeadm" .}}`
 
+	controlPlaneJoinBashScript = `{{.Header}}{{template "metadata" .}}{{template "fips" .}}{{template "serialconsole" .}}{{template "hardening" .}}{{template "ntp" .}}{{template "preflight" .}}{{template "gpu" .}}{{template "etcdvolume" .}}{{template "files" .}}
 mkdir -p /etc/kubernetes/pki
 
 echo '{{.CACert}}' > /etc/kubernetes/pki/ca.crt
@@ -87,8 +108,8 @@ echo '{{.CAKey}}' > /etc/kubernetes/pki/ca.key
 
 echo '{{.KubeConfig}}' > /etc/kubernetes/admin.conf
 
-PRIVATE_IP=$(curl http://169.254.169.254/latest/meta-data/local-ipv4)
-HOSTNAME="$(curl http://169.254.169.254/latest/meta-data/local-hostname)"
+PRIVATE_IP=$(metadata local-ipv4)
+HOSTNAME="$(metadata local-hostname)"
 
 cat >/tmp/kubeadm-controlplane-join-config.yaml <<EOF
 ---
@@ -105,6 +126,9 @@ nodeRegistration:
   criSocket: /var/run/containerd/containerd.sock
   kubeletExtraArgs:
     cloud-provider: aws
+{{- range $k, $v := .KubeletExtraArgs}}
+    {{$k}}: "{{$v}}"
+{{- end}}
 controlPlane:
   localAPIEndpoint:
     advertiseAddress: "${PRIVATE_IP}"
@@ -131,9 +155,9 @@ get secret kubeadm-certs-front-proxy -ojson | jq '.data."tls.key"' -r | base64 -
 kubectl --kubeconfig /etc/kubernetes/admin.conf -n kube-system get secrets kubeadm-sa-certs -ojson | jq '.data."sa-certs.tar.gz"' -r | base64 --decode > /etc/kubernetes/pki/sa-certs.tar.gz
 cd / 
 tar -xvf /etc/kubernetes/pki/sa-certs.tar.gz
-
+{{template "prekubeadm" .}}
 kubeadm join --config /tmp/kubeadm-controlplane-join-config.yaml --v 10
-`
+{{template "etcdtls" .}}{{template "postkubeadm" .}}`
 )
 
 // ControlPlaneInput defines the context to generate a controlplane instance user data.
@@ -148,6 +172,50 @@ type ControlPlaneInput struct {
 	ServiceDomain     string
 	ServiceSubnet     string
 	KubernetesVersion string
+
+	// PreflightEndpoints are the host:port endpoints checked for connectivity
+	// before bootstrapping. No check is done if empty.
+	PreflightEndpoints []string
+
+	// KubeletExtraArgs are additional kubelet flags, keyed by flag name.
+	KubeletExtraArgs map[string]string
+
+	// FIPSMode enables the FIPS mode of the kernel, rebooting the instance
+	// before bootstrapping, and points the node agents at FIPS endpoints.
+	FIPSMode bool
+
+	// HardeningProfile is the host hardening profile applied before bootstrapping.
+	// No hardening is applied if empty.
+	HardeningProfile string
+
+	// SerialConsole enables logging in on the serial console, if set.
+	SerialConsole *SerialConsoleInput
+
+	// GPU installs the NVIDIA driver and container toolkit, if set.
+	GPU *GPUInput
+
+	// EtcdInstanceStore mounts the first NVMe instance store volume at /var/lib/etcd.
+	EtcdInstanceStore bool
+
+	// EtcdDevice is the device name of the EBS volume mounted at /var/lib/etcd.
+	// No volume is mounted if empty, unless EtcdInstanceStore is set.
+	EtcdDevice string
+
+	// TransitEncryption enforces the encryption in transit of the traffic of
+	// the kubelets and of etcd, if set.
+	TransitEncryption *TransitEncryptionInput
+
+	// PreKubeadmCommands are the shell commands run before kubeadm.
+	PreKubeadmCommands []string
+
+	// PostKubeadmCommands are the shell commands run after kubeadm.
+	PostKubeadmCommands []string
+
+	// Files are written before kubeadm runs.
+	Files []FileInput
+
+	// NTPServers are the NTP servers the clock of the instance is synchronised with.
+	NTPServers []string
 }
 
 // ContolPlaneJoinInput defines context to generate controlplane instance user data for controlplane node join.
@@ -160,6 +228,50 @@ type ContolPlaneJoinInput struct {
 	BootstrapToken string
 	ELBAddress     string
 	KubeConfig     string
+
+	// PreflightEndpoints are the host:port endpoints checked for connectivity
+	// before bootstrapping. No check is done if empty.
+	PreflightEndpoints []string
+
+	// KubeletExtraArgs are additional kubelet flags, keyed by flag name.
+	KubeletExtraArgs map[string]string
+
+	// FIPSMode enables the FIPS mode of the kernel, rebooting the instance
+	// before bootstrapping, and points the node agents at FIPS endpoints.
+	FIPSMode bool
+
+	// HardeningProfile is the host hardening profile applied before bootstrapping.
+	// No hardening is applied if empty.
+	HardeningProfile string
+
+	// SerialConsole enables logging in on the serial console, if set.
+	SerialConsole *SerialConsoleInput
+
+	// GPU installs the NVIDIA driver and container toolkit, if set.
+	GPU *GPUInput
+
+	// EtcdInstanceStore mounts the first NVMe instance store volume at /var/lib/etcd.
+	EtcdInstanceStore bool
+
+	// EtcdDevice is the device name of the EBS volume mounted at /var/lib/etcd.
+	// No volume is mounted if empty, unless EtcdInstanceStore is set.
+	EtcdDevice string
+
+	// TransitEncryption enforces the encryption in transit of the traffic of
+	// the kubelets and of etcd, if set.
+	TransitEncryption *TransitEncryptionInput
+
+	// PreKubeadmCommands are the shell commands run before kubeadm.
+	PreKubeadmCommands []string
+
+	// PostKubeadmCommands are the shell commands run after kubeadm.
+	PostKubeadmCommands []string
+
+	// Files are written before kubeadm runs.
+	Files []FileInput
+
+	// NTPServers are the NTP servers the clock of the instance is synchronised with.
+	NTPServers []string
 }
 
 // NewControlPlane returns the user data string to be used on a controlplane instance.
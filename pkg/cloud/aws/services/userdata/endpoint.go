@@ -0,0 +1,157 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package userdata
+
+import (
+	"github.com/pkg/errors"
+)
+
+const (
+	restartStaticPodTemplate = `{{define "restartstaticpod"}}
+# Restart a static pod by moving its manifest out of the manifests directory
+# until the kubelet stops it.
+restart_static_pod() {
+  mv "/etc/kubernetes/manifests/$1.yaml" "/etc/kubernetes/$1.yaml"
+  sleep 30
+  mv "/etc/kubernetes/$1.yaml" "/etc/kubernetes/manifests/$1.yaml"
+}
+{{end}}`
+
+	// apiServerCertificateScript adds the new endpoint to the serving
+	// certificate of the API server of a control plane instance.
+	apiServerCertificateScript = `{{.Header}}{{template "metadata" .}}{{template "restartstaticpod" .}}
+if [ ! -f /etc/kubernetes/manifests/kube-apiserver.yaml ]; then
+  exit 0
+fi
+
+PRIVATE_IP=$(metadata local-ipv4)
+HOSTNAME="$(metadata local-hostname)"
+KUBERNETES_VERSION=$(kubeadm config view --kubeconfig /etc/kubernetes/admin.conf | sed -n 's/^kubernetesVersion: //p')
+
+cat >/tmp/kubeadm-endpoint.yaml <<EOF
+---
+apiVersion: kubeadm.k8s.io/v1beta1
+kind: InitConfiguration
+localAPIEndpoint:
+  advertiseAddress: "${PRIVATE_IP}"
+nodeRegistration:
+  name: "${HOSTNAME}"
+---
+apiVersion: kubeadm.k8s.io/v1beta1
+kind: ClusterConfiguration
+apiServer:
+  certSANs:
+    - "${PRIVATE_IP}"
+    - "{{.PreviousAddress}}"
+    - "{{.Address}}"
+controlPlaneEndpoint: "{{.Address}}:6443"
+networking:
+  dnsDomain: "{{.ServiceDomain}}"
+  serviceSubnet: "{{.ServiceSubnet}}"
+kubernetesVersion: "${KUBERNETES_VERSION}"
+EOF
+
+for file in apiserver.crt apiserver.key; do
+  if [ -f "/etc/kubernetes/pki/${file}" ]; then
+    mv "/etc/kubernetes/pki/${file}" "/etc/kubernetes/pki/${file}.previous"
+  fi
+done
+kubeadm init phase certs apiserver --config /tmp/kubeadm-endpoint.yaml
+restart_static_pod kube-apiserver
+
+for _ in $(seq 1 60); do
+  if curl -sfk https://127.0.0.1:6443/healthz >/dev/null; then
+    exit 0
+  fi
+  sleep 5
+done
+echo "API server not healthy after renewing its certificate" >&2
+exit 1
+`
+
+	// apiServerKubeconfigScript points the kubeconfigs of an instance at the
+	// new endpoint. On control plane instances, the configuration maps read
+	// by joining machines and by kube-proxy are updated too.
+	apiServerKubeconfigScript = `{{.Header}}{{template "restartstaticpod" .}}
+if [ ! -d /etc/kubernetes ]; then
+  exit 0
+fi
+
+for conf in /etc/kubernetes/*.conf; do
+  [ -f "${conf}" ] || continue
+  sed -i "s|https://{{.PreviousAddress}}:6443|https://{{.Address}}:6443|g" "${conf}"
+done
+
+if [ -f /etc/kubernetes/manifests/kube-apiserver.yaml ]; then
+  kubectl="kubectl --kubeconfig /etc/kubernetes/admin.conf"
+  for configmap in kube-system/kubeadm-config kube-system/kube-proxy kube-public/cluster-info; do
+    manifest=$(${kubectl} -n "${configmap%/*}" get configmap "${configmap#*/}" -o yaml)
+    if grep -qF "{{.PreviousAddress}}" <<<"${manifest}"; then
+      sed "s|{{.PreviousAddress}}|{{.Address}}|g" <<<"${manifest}" | ${kubectl} replace -f -
+      if [ "${configmap}" = "kube-system/kube-proxy" ]; then
+        ${kubectl} -n kube-system delete pods -l k8s-app=kube-proxy --wait=false
+      fi
+    fi
+  done
+
+  restart_static_pod kube-controller-manager
+  restart_static_pod kube-scheduler
+fi
+
+if systemctl is-active --quiet kubelet; then
+  systemctl restart kubelet
+fi
+`
+)
+
+// APIServerEndpointInput defines the context to generate the scripts moving
+// the instances of a cluster to a new API server endpoint.
+type APIServerEndpointInput struct {
+	baseUserData
+
+	// PreviousAddress is the host the API server was reached at.
+	PreviousAddress string
+
+	// Address is the host the API server is reached at.
+	Address string
+
+	ServiceDomain string
+	ServiceSubnet string
+}
+
+// NewAPIServerCertificateScript returns the script adding the new endpoint to
+// the serving certificate of the API server of a control plane instance, and
+// restarting it. It does nothing on other instances.
+func NewAPIServerCertificateScript(input *APIServerEndpointInput) (string, error) {
+	input.Header = defaultHeader
+	script, err := generate("apiservercertificate", apiServerCertificateScript, input)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to generate the API server certificate script")
+	}
+	return script, nil
+}
+
+// NewAPIServerKubeconfigScript returns the script pointing the kubeconfigs of
+// an instance at the new endpoint.
+func NewAPIServerKubeconfigScript(input *APIServerEndpointInput) (string, error) {
+	input.Header = defaultHeader
+	script, err := generate("apiserverkubeconfig", apiServerKubeconfigScript, input)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to generate the API server kubeconfig script")
+	}
+	return script, nil
+}
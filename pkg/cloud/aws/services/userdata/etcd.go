@@ -0,0 +1,61 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package userdata
+
+const (
+	// EtcdVolumeFailureMarker prefixes the console line written when the
+	// volume dedicated to etcd cannot be found.
+	EtcdVolumeFailureMarker = "cluster-api-provider-aws etcd volume: device not found"
+
+	// etcdVolumeTemplate formats and mounts the volume dedicated to etcd.
+	// EBS volumes are exposed as NVMe devices on Nitro instances, whose
+	// controller reports the device name requested at launch.
+	etcdVolumeTemplate = `{{define "etcdvolume"}}{{if or .EtcdInstanceStore .EtcdDevice}}
+# Format and mount the volume dedicated to etcd.
+etcd_device=""
+for _ in $(seq 1 30); do
+{{- if .EtcdInstanceStore}}
+  etcd_device=$(lsblk -d -n -p -o NAME,MODEL | awk '/Instance Storage/ {print $1; exit}')
+{{- else}}
+  if [ -b "{{.EtcdDevice}}" ]; then
+    etcd_device="{{.EtcdDevice}}"
+  else
+    for nvme in /dev/nvme*n1; do
+      name=$(nvme id-ctrl --raw-binary "${nvme}" 2>/dev/null | cut -c3073-3104 | tr -d ' \000' || true)
+      if [ "${name#/dev/}" = "$(basename {{.EtcdDevice}})" ]; then
+        etcd_device="${nvme}"
+      fi
+    done
+  fi
+{{- end}}
+  if [ -n "${etcd_device}" ]; then
+    break
+  fi
+  sleep 2
+done
+if [ -z "${etcd_device}" ]; then
+  echo "` + EtcdVolumeFailureMarker + `" | tee /dev/console
+  exit 1
+fi
+if ! blkid "${etcd_device}" >/dev/null 2>&1; then
+  mkfs.ext4 -q -L etcd "${etcd_device}"
+fi
+mkdir -p /var/lib/etcd
+echo "LABEL=etcd /var/lib/etcd ext4 defaults,noatime,nofail 0 2" >> /etc/fstab
+mount /var/lib/etcd
+{{end}}{{end}}`
+)
@@ -0,0 +1,52 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package userdata
+
+const (
+	// filesTemplate writes the files of the machine, whose content is base64
+	// encoded so that it is written as is. The content of secret files is
+	// read from SSM parameters with the instance profile, rather than being
+	// part of the user data.
+	filesTemplate = `{{define "files"}}{{if .Files}}
+# Write the files of the machine.
+{{- range .Files}}
+mkdir -p "$(dirname '{{.Path}}')"
+{{- if .Parameter}}
+(umask 077 && aws ssm get-parameter --region "$(metadata placement/region)" --with-decryption --name '{{.Parameter}}' --query Parameter.Value --output text | base64 --decode > '{{.Path}}')
+{{- else}}
+echo '{{.Content}}' | base64 --decode > '{{.Path}}'
+{{- end}}
+chmod {{.Permissions}} '{{.Path}}'
+{{- end}}
+{{end}}{{end}}`
+)
+
+// FileInput defines a file written on an instance.
+type FileInput struct {
+	// Path is the absolute path of the file, without single quotes.
+	Path string
+
+	// Permissions are the octal permissions of the file.
+	Permissions string
+
+	// Content is the base64 encoded content of the file.
+	Content string
+
+	// Parameter is the name of the SecureString SSM parameter holding the
+	// base64 encoded content of the file, in place of Content.
+	Parameter string
+}
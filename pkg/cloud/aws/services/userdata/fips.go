@@ -0,0 +1,79 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package userdata
+
+const (
+	// FIPSFailureMarker prefixes the console line written when FIPS mode
+	// cannot be enabled on the image of an instance.
+	FIPSFailureMarker = "cluster-api-provider-aws fips: cannot enable FIPS mode"
+
+	// fipsTemplate enables the FIPS mode of the kernel, which only takes
+	// effect after a reboot: the script is then run again by a oneshot unit,
+	// and goes on with the bootstrap once the kernel runs in FIPS mode. The
+	// AWS SDKs and CLI of the node agents use the FIPS endpoints of the
+	// region.
+	fipsTemplate = `{{define "fips"}}{{if .FIPSMode}}
+# Enable FIPS mode, rebooting into it before bootstrapping.
+if [ "$(cat /proc/sys/crypto/fips_enabled 2>/dev/null)" != "1" ]; then
+  if command -v fips-mode-setup >/dev/null; then
+    fips-mode-setup --enable
+  elif command -v pro >/dev/null; then
+    pro enable fips-updates --assume-yes
+  elif command -v ua >/dev/null; then
+    ua enable fips-updates --assume-yes
+  elif command -v yum >/dev/null && command -v grubby >/dev/null; then
+    yum install -y dracut-fips
+    dracut -f
+    grubby --update-kernel=ALL --args="fips=1"
+  else
+    echo "` + FIPSFailureMarker + `, no FIPS tooling on the image" | tee /dev/console
+    exit 1
+  fi
+
+  mkdir -p /var/lib/cluster-api
+  cp "$(readlink -f "$0")" /var/lib/cluster-api/bootstrap.sh
+  chmod 0700 /var/lib/cluster-api/bootstrap.sh
+  cat >/etc/systemd/system/cluster-api-bootstrap.service <<EOF
+[Unit]
+Description=Bootstrap the node after enabling FIPS mode
+After=network-online.target
+Wants=network-online.target
+
+[Service]
+Type=oneshot
+ExecStart=/var/lib/cluster-api/bootstrap.sh
+StandardOutput=journal+console
+
+[Install]
+WantedBy=multi-user.target
+EOF
+  systemctl enable cluster-api-bootstrap.service
+  systemctl reboot
+  exit 0
+fi
+systemctl disable cluster-api-bootstrap.service >/dev/null 2>&1 || true
+
+echo "AWS_USE_FIPS_ENDPOINT=true" >>/etc/environment
+mkdir -p /etc/systemd/system.conf.d
+cat >/etc/systemd/system.conf.d/90-cluster-api-fips.conf <<EOF
+[Manager]
+DefaultEnvironment=AWS_USE_FIPS_ENDPOINT=true
+EOF
+systemctl daemon-reexec
+export AWS_USE_FIPS_ENDPOINT=true
+{{end}}{{end}}`
+)
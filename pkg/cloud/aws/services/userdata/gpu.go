@@ -0,0 +1,53 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package userdata
+
+const (
+	// gpuTemplate installs the NVIDIA driver and container toolkit from the
+	// NVIDIA repositories, and makes the NVIDIA runtime the default runtime of
+	// containerd, so that GPU workloads can run as soon as the node joins.
+	gpuTemplate = `{{define "gpu"}}{{with .GPU}}
+# Install the NVIDIA driver {{.DriverVersion}} and the NVIDIA container toolkit.
+arch="$(uname -m)"
+if [ "${arch}" = "aarch64" ]; then
+  arch=sbsa
+fi
+. /etc/os-release
+if command -v apt-get >/dev/null; then
+  distribution="${ID}${VERSION_ID//./}"
+  curl -fsSL "https://developer.download.nvidia.com/compute/cuda/repos/${distribution}/${arch}/3bf863cc.pub" | apt-key add -
+  echo "deb https://developer.download.nvidia.com/compute/cuda/repos/${distribution}/${arch} /" >/etc/apt/sources.list.d/cuda.list
+  curl -fsSL https://nvidia.github.io/libnvidia-container/gpgkey | apt-key add -
+  curl -fsSL "https://nvidia.github.io/libnvidia-container/${ID}${VERSION_ID}/libnvidia-container.list" >/etc/apt/sources.list.d/libnvidia-container.list
+  apt-get update -qq
+  DEBIAN_FRONTEND=noninteractive apt-get install -qqy "linux-headers-$(uname -r)" "cuda-drivers-{{.DriverVersion}}" nvidia-container-toolkit
+else
+  yum install -y yum-utils "kernel-devel-$(uname -r)" "kernel-headers-$(uname -r)"
+  yum-config-manager --add-repo "https://developer.download.nvidia.com/compute/cuda/repos/rhel7/${arch}/cuda-rhel7.repo"
+  curl -fsSL https://nvidia.github.io/libnvidia-container/centos7/libnvidia-container.repo >/etc/yum.repos.d/libnvidia-container.repo
+  yum install -y "cuda-drivers-{{.DriverVersion}}" nvidia-container-toolkit
+fi
+nvidia-ctk runtime configure --runtime=containerd --set-as-default
+systemctl restart containerd
+{{end}}{{end}}`
+)
+
+// GPUInput defines the NVIDIA driver installed on a GPU instance.
+type GPUInput struct {
+	// DriverVersion is the branch of the NVIDIA driver installed.
+	DriverVersion string
+}
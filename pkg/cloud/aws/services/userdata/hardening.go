@@ -0,0 +1,70 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package userdata
+
+const (
+	// hardeningTemplate applies CIS-style host hardening settings that are
+	// compatible with running Kubernetes: IP forwarding stays enabled and
+	// reverse path filtering is left to the network plugin.
+	hardeningTemplate = `{{define "hardening"}}{{if .HardeningProfile}}
+# Apply the {{.HardeningProfile}} host hardening profile.
+cat >/etc/sysctl.d/90-cluster-api-hardening.conf <<EOF
+fs.suid_dumpable = 0
+kernel.randomize_va_space = 2
+kernel.kptr_restrict = 1
+kernel.dmesg_restrict = 1
+net.ipv4.conf.all.accept_redirects = 0
+net.ipv4.conf.default.accept_redirects = 0
+net.ipv4.conf.all.secure_redirects = 0
+net.ipv4.conf.default.secure_redirects = 0
+net.ipv4.conf.all.send_redirects = 0
+net.ipv4.conf.default.send_redirects = 0
+net.ipv4.conf.all.accept_source_route = 0
+net.ipv4.conf.default.accept_source_route = 0
+net.ipv4.conf.all.log_martians = 1
+net.ipv4.conf.default.log_martians = 1
+net.ipv4.icmp_echo_ignore_broadcasts = 1
+net.ipv4.icmp_ignore_bogus_error_responses = 1
+net.ipv4.tcp_syncookies = 1
+net.ipv6.conf.all.accept_redirects = 0
+net.ipv6.conf.default.accept_redirects = 0
+net.ipv6.conf.all.accept_ra = 0
+net.ipv6.conf.default.accept_ra = 0
+EOF
+sysctl --system >/dev/null
+
+for service in avahi-daemon cups rpcbind nfs-server snmpd rsync; do
+  systemctl disable --now "${service}" >/dev/null 2>&1 || true
+done
+
+if [ -d /etc/audit/rules.d ]; then
+  cat >/etc/audit/rules.d/90-cluster-api-hardening.rules <<EOF
+-w /etc/group -p wa -k identity
+-w /etc/passwd -p wa -k identity
+-w /etc/shadow -p wa -k identity
+-w /etc/sudoers -p wa -k scope
+-w /etc/sudoers.d/ -p wa -k scope
+-w /sbin/insmod -p x -k modules
+-w /sbin/rmmod -p x -k modules
+-w /sbin/modprobe -p x -k modules
+-a always,exit -F arch=b64 -S adjtimex -S settimeofday -S clock_settime -k time-change
+-a always,exit -F arch=b64 -S init_module -S delete_module -k modules
+EOF
+  augenrules --load >/dev/null 2>&1 || true
+fi
+{{end}}{{end}}`
+)
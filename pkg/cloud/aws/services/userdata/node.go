@@ -17,9 +17,8 @@ limitations under the License.
 package userdata
 
 const (
-	nodeBashScript = `{{.Header}}
-
-HOSTNAME="$(curl http://169.254.169.254/latest/meta-data/local-hostname)"
+	nodeBashScript = `{{.Header}}{{template "metadata" .}}{{template "fips" .}}{{template "serialconsole" .}}{{template "hardening" .}}{{template "ntp" .}}{{template "preflight" .}}{{template "gpu" .}}{{template "files" .}}
+HOSTNAME="$(metadata local-hostname)"
 
 cat >/tmp/kubeadm-node.yaml <<EOF
 ---
@@ -36,10 +35,13 @@ nodeRegistration:
   criSocket: /var/run/containerd/containerd.sock
   kubeletExtraArgs:
     cloud-provider: aws
+{{- range $k, $v := .KubeletExtraArgs}}
+    {{$k}}: "{{$v}}"
+{{- end}}
 EOF
-
+{{template "prekubeadm" .}}
 kubeadm join --config /tmp/kubeadm-node.yaml
-`
+{{template "postkubeadm" .}}`
 )
 
 // NodeInput defines the context to generate a node user data.
@@ -49,6 +51,39 @@ type NodeInput struct {
 	CACertHash     string
 	BootstrapToken string
 	ELBAddress     string
+
+	// PreflightEndpoints are the host:port endpoints checked for connectivity
+	// before bootstrapping. No check is done if empty.
+	PreflightEndpoints []string
+
+	// KubeletExtraArgs are additional kubelet flags, keyed by flag name.
+	KubeletExtraArgs map[string]string
+
+	// FIPSMode enables the FIPS mode of the kernel, rebooting the instance
+	// before bootstrapping, and points the node agents at FIPS endpoints.
+	FIPSMode bool
+
+	// HardeningProfile is the host hardening profile applied before bootstrapping.
+	// No hardening is applied if empty.
+	HardeningProfile string
+
+	// SerialConsole enables logging in on the serial console, if set.
+	SerialConsole *SerialConsoleInput
+
+	// GPU installs the NVIDIA driver and container toolkit, if set.
+	GPU *GPUInput
+
+	// PreKubeadmCommands are the shell commands run before kubeadm.
+	PreKubeadmCommands []string
+
+	// PostKubeadmCommands are the shell commands run after kubeadm.
+	PostKubeadmCommands []string
+
+	// Files are written before kubeadm runs.
+	Files []FileInput
+
+	// NTPServers are the NTP servers the clock of the instance is synchronised with.
+	NTPServers []string
 }
 
 // NewNode returns the user data string to be used on a node instance.
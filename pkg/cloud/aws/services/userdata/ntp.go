@@ -0,0 +1,48 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package userdata
+
+const (
+	// ntpTemplate points the time daemon of the instance, chrony or else
+	// systemd-timesyncd, to the NTP servers of the cluster, as clock skew
+	// breaks TLS and etcd.
+	ntpTemplate = `{{define "ntp"}}{{if .NTPServers}}
+# Synchronise the clock with the NTP servers of the cluster.
+if command -v chronyd >/dev/null; then
+  chrony_conf=/etc/chrony.conf
+  if [ -d /etc/chrony ]; then
+    chrony_conf=/etc/chrony/chrony.conf
+  fi
+  cat >"${chrony_conf}" <<'EOF'
+{{- range .NTPServers}}
+server {{.}} prefer iburst
+{{- end}}
+driftfile /var/lib/chrony/drift
+makestep 1.0 3
+rtcsync
+EOF
+  systemctl restart chronyd || systemctl restart chrony
+else
+  mkdir -p /etc/systemd/timesyncd.conf.d
+  cat >/etc/systemd/timesyncd.conf.d/ntp-servers.conf <<'EOF'
+[Time]
+NTP={{range $i, $server := .NTPServers}}{{if $i}} {{end}}{{$server}}{{end}}
+EOF
+  systemctl restart systemd-timesyncd
+fi
+{{end}}{{end}}`
+)
@@ -0,0 +1,37 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package userdata
+
+const (
+	// serialConsoleTemplate starts a login prompt on the serial console,
+	// the only way in to instances whose network is broken.
+	serialConsoleTemplate = `{{define "serialconsole"}}{{with .SerialConsole}}
+# Allow logging in on the serial console.
+{{if .PasswordHash}}usermod --password '{{.PasswordHash}}' {{.User}}
+{{end}}systemctl enable --now serial-getty@ttyS0.service
+{{end}}{{end}}`
+)
+
+// SerialConsoleInput defines the access to the serial console of an instance.
+type SerialConsoleInput struct {
+	// User is the user logging in on the serial console.
+	User string
+
+	// PasswordHash is the crypt(3) hash of the password set for the user.
+	// The password is left untouched if empty.
+	PasswordHash string
+}
@@ -0,0 +1,53 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package userdata
+
+const (
+	// EtcdTLSFailureMarker prefixes the console line written when the etcd
+	// static pod manifest does not enforce peer TLS.
+	EtcdTLSFailureMarker = "cluster-api-provider-aws etcd TLS: peer TLS not enforced"
+
+	// etcdTLSTemplate checks that the etcd static pod rendered by kubeadm
+	// authenticates its peers over TLS, so that a control plane machine whose
+	// etcd would accept plaintext peer traffic fails to bootstrap.
+	etcdTLSTemplate = `{{define "etcdtls"}}{{if .TransitEncryption}}
+# Verify that etcd requires TLS client certificates from its peers.
+etcd_manifest=/etc/kubernetes/manifests/etcd.yaml
+for check in "--peer-client-cert-auth=true" "--peer-cert-file=" "--peer-key-file=" "--peer-trusted-ca-file=" "--listen-peer-urls=https://"; do
+  if ! grep -q -- "${check}" "${etcd_manifest}"; then
+    echo "` + EtcdTLSFailureMarker + `: missing ${check}" | tee /dev/console
+    exit 1
+  fi
+done
+if grep -q -- "--listen-peer-urls=.*http://" "${etcd_manifest}"; then
+  echo "` + EtcdTLSFailureMarker + `: plaintext peer URL" | tee /dev/console
+  exit 1
+fi
+{{end}}{{end}}`
+)
+
+// TransitEncryptionInput defines the encryption in transit enforced on a
+// control plane instance.
+type TransitEncryptionInput struct {
+	// KubeletServingCertificates has the API server require kubelet serving
+	// certificates signed by the cluster CA.
+	KubeletServingCertificates bool
+
+	// EtcdCipherSuites is the comma separated list of the TLS cipher suites of
+	// etcd. The etcd defaults are kept if empty.
+	EtcdCipherSuites string
+}
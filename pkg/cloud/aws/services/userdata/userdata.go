@@ -40,11 +40,43 @@ const (
 #    See the License for the specific language governing permissions and
 #    limitations under the License.
 
-set -o verbose
 set -o errexit
 set -o nounset
 set -o pipefail
 `
+
+	// PreflightFailureMarker prefixes the console line written by the connectivity
+	// preflight when a required endpoint is unreachable.
+	PreflightFailureMarker = "cluster-api-provider-aws preflight: unreachable endpoint"
+
+	metadataTemplate = `{{define "metadata"}}
+# Read the instance metadata with a session token, which works whether or not
+# the instance requires IMDSv2.
+metadata() {
+  local token
+  token=$(curl -s -X PUT http://169.254.169.254/latest/api/token -H "X-aws-ec2-metadata-token-ttl-seconds: 300")
+  curl -s -H "X-aws-ec2-metadata-token: ${token}" "http://169.254.169.254/latest/meta-data/$1"
+}
+{{end}}`
+
+	preflightTemplate = `{{define "preflight"}}{{if .PreflightEndpoints}}
+# Verify connectivity to the endpoints required to bootstrap, so that networking
+# issues are reported on the console rather than surfacing as a join timeout.
+for endpoint in{{range .PreflightEndpoints}} {{.}}{{end}}; do
+  reachable=false
+  for _ in $(seq 1 10); do
+    if timeout 5 bash -c "</dev/tcp/${endpoint%:*}/${endpoint##*:}" 2>/dev/null; then
+      reachable=true
+      break
+    fi
+    sleep 6
+  done
+  if [ "${reachable}" != "true" ]; then
+    echo "` + PreflightFailureMarker + ` ${endpoint}" | tee /dev/console
+    exit 1
+  fi
+done
+{{end}}{{end}}`
 )
 
 type baseUserData struct {
@@ -52,7 +84,14 @@ type baseUserData struct {
 }
 
 func generate(kind string, tpl string, data interface{}) (string, error) {
-	t, err := template.New(kind).Parse(tpl)
+	t := template.New(kind)
+	for _, fragment := range []string{metadataTemplate, fipsTemplate, serialConsoleTemplate, hardeningTemplate, preflightTemplate, etcdVolumeTemplate, etcdTLSTemplate, gpuTemplate, kubeadmCommandsTemplate, filesTemplate, ntpTemplate, restartStaticPodTemplate} {
+		if _, err := t.Parse(fragment); err != nil {
+			return "", errors.Wrapf(err, "failed to parse %s template fragment", kind)
+		}
+	}
+
+	t, err := t.Parse(tpl)
 	if err != nil {
 		return "", errors.Wrapf(err, "failed to parse %s template", kind)
 	}
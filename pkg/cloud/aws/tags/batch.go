@@ -0,0 +1,127 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tags
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/pkg/errors"
+)
+
+const (
+	// maxResourcesPerCreateTags is the maximum number of resources
+	// a single CreateTags call accepts.
+	maxResourcesPerCreateTags = 1000
+)
+
+// Batch collects the tags missing from several resources, so that all the
+// resources missing the same tags are tagged with a single CreateTags call.
+type Batch struct {
+	client  ec2iface.EC2API
+	pending map[string]*batchEntry
+	keys    []string
+}
+
+type batchEntry struct {
+	tags      Map
+	resources []string
+}
+
+// NewBatch returns an empty batch of tag mutations issued through the given client.
+func NewBatch(client ec2iface.EC2API) *Batch {
+	return &Batch{
+		client:  client,
+		pending: make(map[string]*batchEntry),
+	}
+}
+
+// Ensure adds the tags built from params that are missing from, or differ in,
+// the current tags of the resource to the batch.
+func (b *Batch) Ensure(current Map, params BuildParams) {
+	diff := Build(params).Difference(current)
+	if len(diff) == 0 {
+		return
+	}
+
+	key := diff.key()
+	entry, ok := b.pending[key]
+	if !ok {
+		entry = &batchEntry{tags: diff}
+		b.pending[key] = entry
+		b.keys = append(b.keys, key)
+	}
+
+	entry.resources = append(entry.resources, params.ResourceID)
+}
+
+// Apply issues the CreateTags calls for the collected tags and empties the batch.
+func (b *Batch) Apply() error {
+	defer func() {
+		b.pending = make(map[string]*batchEntry)
+		b.keys = nil
+	}()
+
+	for _, key := range b.keys {
+		entry := b.pending[key]
+
+		for start := 0; start < len(entry.resources); start += maxResourcesPerCreateTags {
+			end := start + maxResourcesPerCreateTags
+			if end > len(entry.resources) {
+				end = len(entry.resources)
+			}
+
+			input := &ec2.CreateTagsInput{
+				Resources: aws.StringSlice(entry.resources[start:end]),
+				Tags:      entry.tags.toEC2(),
+			}
+
+			if _, err := b.client.CreateTags(input); err != nil {
+				return errors.Wrapf(err, "failed to tag resources %v", entry.resources[start:end])
+			}
+		}
+	}
+
+	return nil
+}
+
+// key returns a canonical representation of the tags,
+// identical for maps with the same keys and values.
+func (m Map) key() string {
+	pairs := make([]string, 0, len(m))
+	for k, v := range m {
+		pairs = append(pairs, k+"="+v)
+	}
+
+	sort.Strings(pairs)
+	return strings.Join(pairs, "\x00")
+}
+
+func (m Map) toEC2() []*ec2.Tag {
+	res := make([]*ec2.Tag, 0, len(m))
+	for k, v := range m {
+		res = append(res, &ec2.Tag{
+			Key:   aws.String(k),
+			Value: aws.String(v),
+		})
+	}
+
+	return res
+}
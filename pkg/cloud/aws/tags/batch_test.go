@@ -0,0 +1,74 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tags
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/golang/mock/gomock"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/ec2/mock_ec2iface"
+)
+
+func TestBatch(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	params := func(id string) BuildParams {
+		return BuildParams{
+			ClusterName: "test-cluster",
+			ResourceID:  id,
+			Lifecycle:   ResourceLifecycleOwned,
+			Role:        aws.String(ValueCommonRole),
+		}
+	}
+
+	upToDate := Build(params("sg-1"))
+	upToDate["user-tag"] = "kept"
+
+	ec2Mock := mock_ec2iface.NewMockEC2API(mockCtrl)
+	ec2Mock.EXPECT().
+		CreateTags(gomock.AssignableToTypeOf(&ec2.CreateTagsInput{})).
+		Do(func(input *ec2.CreateTagsInput) {
+			if len(input.Resources) != 2 {
+				t.Fatalf("expected resources missing the same tags to be tagged together, got %v", aws.StringValueSlice(input.Resources))
+			}
+
+			if len(input.Tags) != 1 || aws.StringValue(input.Tags[0].Key) != NameAWSClusterAPIRole {
+				t.Fatalf("expected only the missing tag to be applied, got %v", input.Tags)
+			}
+		}).
+		Return(&ec2.CreateTagsOutput{}, nil)
+
+	missingRole := Build(params(""))
+	delete(missingRole, NameAWSClusterAPIRole)
+
+	b := NewBatch(ec2Mock)
+	b.Ensure(upToDate, params("sg-1"))
+	b.Ensure(missingRole, params("sg-2"))
+	b.Ensure(missingRole, params("sg-3"))
+
+	if err := b.Apply(); err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+
+	// The batch is empty once applied.
+	if err := b.Apply(); err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+}
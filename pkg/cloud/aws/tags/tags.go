@@ -51,13 +51,12 @@ func Apply(params *ApplyParams) error {
 	return errors.Wrapf(err, "failed to tag resource %q in cluster %q", params.ResourceID, params.ClusterName)
 }
 
-// Ensure applies the tags if the current tags differ from the params.
+// Ensure applies the tags built from the params that are missing from, or
+// differ in, the current tags. No call is made if all the tags are up to date.
 func Ensure(current Map, params *ApplyParams) error {
-	want := Build(params.BuildParams)
-	if !current.Equals(want) {
-		return Apply(params)
-	}
-	return nil
+	b := NewBatch(params.EC2Client)
+	b.Ensure(current, params.BuildParams)
+	return errors.Wrapf(b.Apply(), "failed to tag resource %q in cluster %q", params.ResourceID, params.ClusterName)
 }
 
 // BuildParams is used to build tags around an aws resource.
@@ -70,6 +70,34 @@ const (
 	// dedicated to this cluster api provider implementation.
 	NameAWSClusterAPIRole = "sigs.k8s.io/cluster-api-provider-aws/role"
 
+	// NameAWSProviderSourceAMI is the tag name we use to record the AMI an
+	// encrypted copy was created from.
+	NameAWSProviderSourceAMI = "sigs.k8s.io/cluster-api-provider-aws/source-ami"
+
+	// NameAWSProviderQuarantined is the tag name we use to mark instances
+	// quarantined for debugging. The tag value is the time of the quarantine.
+	NameAWSProviderQuarantined = "sigs.k8s.io/cluster-api-provider-aws/quarantined"
+
+	// NameAWSProviderMachineSet is the tag name we use to record the machine
+	// set an instance belongs to.
+	NameAWSProviderMachineSet = "sigs.k8s.io/cluster-api-provider-aws/machine-set"
+
+	// NameAWSProviderMachineDeployment is the tag name we use to record the
+	// machine deployment an instance belongs to.
+	NameAWSProviderMachineDeployment = "sigs.k8s.io/cluster-api-provider-aws/machine-deployment"
+
+	// NameAWSProviderMachinePool is the tag name we use to record the
+	// machine pool an instance belongs to.
+	NameAWSProviderMachinePool = "sigs.k8s.io/cluster-api-provider-aws/machine-pool"
+
+	// NameAWSProviderKubernetesVersion is the tag name we use to record the
+	// Kubernetes version of the kubelet of an instance.
+	NameAWSProviderKubernetesVersion = "sigs.k8s.io/cluster-api-provider-aws/kubernetes-version"
+
+	// NameAWSProviderPodCidrBlock is the tag name we use to record the pod
+	// CIDR block allocated to the cluster of a VPC.
+	NameAWSProviderPodCidrBlock = "sigs.k8s.io/cluster-api-provider-aws/pod-cidr-block"
+
 	// ValueAPIServerRole describes the value for the apiserver role
 	ValueAPIServerRole = "apiserver"
 
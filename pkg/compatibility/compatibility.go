@@ -0,0 +1,235 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package compatibility checks the Kubernetes versions of machines against a
+// matrix of the versions known to work with the published AMIs and kubeadm.
+// The matrix is embedded in the controller, and can be overridden with a
+// ConfigMap to support new versions without a new release.
+package compatibility
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// MatrixKey is the key of the matrix in the data of the ConfigMap overriding it.
+const MatrixKey = "matrix.yaml"
+
+// defaultMatrix lists the versions of the AMIs published for this project,
+// see docs/amis.md. kubeadm deploys control planes of its own minor version
+// and of the previous one.
+const defaultMatrix = `
+releases:
+- kubernetes: "1.13"
+  amis: ["1.13.0"]
+  controlPlanes: ["1.13", "1.12"]
+`
+
+// Matrix lists the supported Kubernetes releases.
+type Matrix struct {
+	Releases []Release `json:"releases"`
+}
+
+// Release describes the compatibility of a minor Kubernetes release, as
+// installed on machines along with kubeadm.
+type Release struct {
+	// Kubernetes is the minor version of the release, e.g. "1.13".
+	Kubernetes string `json:"kubernetes"`
+
+	// AMIs are the patch versions of the release with a known-good AMI
+	// published for this project, e.g. "1.13.0".
+	AMIs []string `json:"amis,omitempty"`
+
+	// ControlPlanes are the minor versions of the control planes that
+	// kubeadm of the release can deploy.
+	ControlPlanes []string `json:"controlPlanes,omitempty"`
+}
+
+// Default returns the matrix embedded in the controller.
+func Default() *Matrix {
+	m, err := Parse([]byte(defaultMatrix))
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+// Parse parses a matrix from YAML.
+func Parse(data []byte) (*Matrix, error) {
+	m := &Matrix{}
+	if err := yaml.UnmarshalStrict(data, m); err != nil {
+		return nil, errors.Wrap(err, "failed to parse compatibility matrix")
+	}
+
+	for _, r := range m.Releases {
+		if _, _, ok := parseMinor(r.Kubernetes); !ok {
+			return nil, errors.Errorf("invalid kubernetes version %q in compatibility matrix, expected a minor version such as 1.13", r.Kubernetes)
+		}
+	}
+
+	return m, nil
+}
+
+// Source returns the matrix in effect.
+type Source interface {
+	Matrix() (*Matrix, error)
+}
+
+// ConfigMapSource reads the matrix from a ConfigMap, falling back to the
+// embedded matrix while the ConfigMap does not exist.
+type ConfigMapSource struct {
+	Client    corev1client.ConfigMapsGetter
+	Namespace string
+	Name      string
+}
+
+// Matrix implements Source.
+func (s *ConfigMapSource) Matrix() (*Matrix, error) {
+	cm, err := s.Client.ConfigMaps(s.Namespace).Get(s.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return Default(), nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get compatibility matrix ConfigMap %s/%s", s.Namespace, s.Name)
+	}
+
+	return parseConfigMap(cm)
+}
+
+func parseConfigMap(cm *corev1.ConfigMap) (*Matrix, error) {
+	data, ok := cm.Data[MatrixKey]
+	if !ok {
+		return nil, errors.Errorf("ConfigMap %s/%s has no %q key", cm.Namespace, cm.Name, MatrixKey)
+	}
+
+	m, err := Parse([]byte(data))
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid ConfigMap %s/%s", cm.Namespace, cm.Name)
+	}
+	return m, nil
+}
+
+// Machine holds the versions of a machine to validate.
+type Machine struct {
+	// Kubelet is the Kubernetes version installed on the machine.
+	Kubelet string
+
+	// ControlPlane is the version of the control plane deployed by the
+	// machine, empty for nodes.
+	ControlPlane string
+
+	// DefaultImage is true if the machine uses the AMIs published for this
+	// project, rather than an image of its own.
+	DefaultImage bool
+}
+
+// Validate returns an error with guidance if the versions of the machine are
+// not supported by the matrix. Machines without a kubelet version are not
+// checked.
+func (m *Matrix) Validate(machine Machine) error {
+	if machine.Kubelet == "" {
+		return nil
+	}
+
+	kubeletMajor, kubeletMinor, ok := parseMinor(machine.Kubelet)
+	if !ok {
+		return errors.Errorf("invalid kubelet version %q", machine.Kubelet)
+	}
+
+	var release *Release
+	for i := range m.Releases {
+		if major, minor, _ := parseMinor(m.Releases[i].Kubernetes); major == kubeletMajor && minor == kubeletMinor {
+			release = &m.Releases[i]
+			break
+		}
+	}
+	if release == nil {
+		return errors.Errorf("kubernetes version %s is not supported, supported versions are %s: use a supported version, or add it to the compatibility matrix",
+			machine.Kubelet, list(m.versions()))
+	}
+
+	if machine.DefaultImage {
+		version := strings.TrimPrefix(machine.Kubelet, "v")
+		if !contains(release.AMIs, version) {
+			return errors.Errorf("no known-good AMI is published for kubernetes version %s, AMIs are published for %s: use one of these versions, or set the image of the machine",
+				machine.Kubelet, list(release.AMIs))
+		}
+	}
+
+	if machine.ControlPlane != "" {
+		major, minor, ok := parseMinor(machine.ControlPlane)
+		if !ok {
+			return errors.Errorf("invalid control plane version %q", machine.ControlPlane)
+		}
+		if !contains(release.ControlPlanes, fmt.Sprintf("%d.%d", major, minor)) {
+			return errors.Errorf("kubeadm %s cannot deploy control plane version %s, it supports control plane versions %s: align the kubelet and control plane versions of the machine",
+				release.Kubernetes, machine.ControlPlane, list(release.ControlPlanes))
+		}
+	}
+
+	return nil
+}
+
+func (m *Matrix) versions() []string {
+	versions := make([]string, 0, len(m.Releases))
+	for _, r := range m.Releases {
+		versions = append(versions, r.Kubernetes)
+	}
+	return versions
+}
+
+// parseMinor returns the major and minor numbers of a version such as v1.13.0 or 1.13.
+func parseMinor(version string) (int, int, bool) {
+	parts := strings.SplitN(strings.TrimPrefix(version, "v"), ".", 3)
+	if len(parts) < 2 {
+		return 0, 0, false
+	}
+
+	major, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, 0, false
+	}
+	minor, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return 0, 0, false
+	}
+
+	return major, minor, true
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func list(values []string) string {
+	if len(values) == 0 {
+		return "none"
+	}
+	return strings.Join(values, ", ")
+}
@@ -0,0 +1,146 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package compatibility
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+)
+
+func TestValidate(t *testing.T) {
+	testCases := []struct {
+		name        string
+		machine     Machine
+		expectError bool
+	}{
+		{
+			name:    "no kubelet version",
+			machine: Machine{DefaultImage: true},
+		},
+		{
+			name:    "node with a published AMI",
+			machine: Machine{Kubelet: "v1.13.0", DefaultImage: true},
+		},
+		{
+			name:    "control plane of the previous minor version",
+			machine: Machine{Kubelet: "1.13.0", ControlPlane: "1.12.5", DefaultImage: true},
+		},
+		{
+			name:    "patch version without a published AMI, with an image of its own",
+			machine: Machine{Kubelet: "1.13.4"},
+		},
+		{
+			name:        "patch version without a published AMI",
+			machine:     Machine{Kubelet: "1.13.4", DefaultImage: true},
+			expectError: true,
+		},
+		{
+			name:        "unsupported minor version",
+			machine:     Machine{Kubelet: "1.14.0"},
+			expectError: true,
+		},
+		{
+			name:        "control plane newer than kubeadm",
+			machine:     Machine{Kubelet: "1.13.0", ControlPlane: "1.14.0"},
+			expectError: true,
+		},
+		{
+			name:        "invalid kubelet version",
+			machine:     Machine{Kubelet: "latest"},
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := Default().Validate(tc.machine)
+			if tc.expectError && err == nil {
+				t.Fatalf("expected error")
+			}
+			if !tc.expectError && err != nil {
+				t.Fatalf("did not expect error: %v", err)
+			}
+		})
+	}
+}
+
+type fakeConfigMaps struct {
+	corev1client.ConfigMapInterface
+	configMaps map[string]*corev1.ConfigMap
+}
+
+func (f *fakeConfigMaps) Get(name string, _ metav1.GetOptions) (*corev1.ConfigMap, error) {
+	if cm, ok := f.configMaps[name]; ok {
+		return cm, nil
+	}
+	return nil, apierrors.NewNotFound(corev1.Resource("configmaps"), name)
+}
+
+type fakeConfigMapsGetter struct {
+	configMaps *fakeConfigMaps
+}
+
+func (f *fakeConfigMapsGetter) ConfigMaps(namespace string) corev1client.ConfigMapInterface {
+	return f.configMaps
+}
+
+func TestConfigMapSource(t *testing.T) {
+	client := &fakeConfigMapsGetter{configMaps: &fakeConfigMaps{configMaps: map[string]*corev1.ConfigMap{
+		"override": {
+			ObjectMeta: metav1.ObjectMeta{Name: "override"},
+			Data: map[string]string{
+				MatrixKey: `
+releases:
+- kubernetes: "1.14"
+  amis: ["1.14.1"]
+  controlPlanes: ["1.14", "1.13"]
+`,
+			},
+		},
+		"invalid": {
+			ObjectMeta: metav1.ObjectMeta{Name: "invalid"},
+			Data:       map[string]string{MatrixKey: "releases: [{kubernetes: latest}]"},
+		},
+	}}}
+
+	m, err := (&ConfigMapSource{Client: client, Namespace: "default", Name: "missing"}).Matrix()
+	if err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+	if err := m.Validate(Machine{Kubelet: "1.13.0", DefaultImage: true}); err != nil {
+		t.Fatalf("expected the default matrix without ConfigMap, got: %v", err)
+	}
+
+	m, err = (&ConfigMapSource{Client: client, Namespace: "default", Name: "override"}).Matrix()
+	if err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+	if err := m.Validate(Machine{Kubelet: "1.14.1", ControlPlane: "1.14.1", DefaultImage: true}); err != nil {
+		t.Fatalf("expected the matrix of the ConfigMap, got: %v", err)
+	}
+	if err := m.Validate(Machine{Kubelet: "1.13.0"}); err == nil {
+		t.Fatalf("expected the matrix of the ConfigMap to replace the default one")
+	}
+
+	if _, err := (&ConfigMapSource{Client: client, Namespace: "default", Name: "invalid"}).Matrix(); err == nil {
+		t.Fatalf("expected error for an invalid matrix")
+	}
+}
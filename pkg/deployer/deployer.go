@@ -18,56 +18,152 @@ package deployer
 
 import (
 	"fmt"
+	"sync"
 
 	"github.com/pkg/errors"
 	"k8s.io/client-go/tools/clientcmd"
-	providerv1 "sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
 	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/actuators"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services"
 	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/certificates"
-	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/elb"
 	clusterv1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
 )
 
+// IPGetter returns the address of the API server of a cluster.
+type IPGetter interface {
+	GetIP(cluster *clusterv1.Cluster, machine *clusterv1.Machine) (string, error)
+}
+
+// IPGetterFunc is an adapter to use ordinary functions as IPGetters.
+type IPGetterFunc func(cluster *clusterv1.Cluster, machine *clusterv1.Machine) (string, error)
+
+// GetIP calls f(cluster, machine).
+func (f IPGetterFunc) GetIP(cluster *clusterv1.Cluster, machine *clusterv1.Machine) (string, error) {
+	return f(cluster, machine)
+}
+
+// KubeConfigGetter returns a kubeconfig to access a cluster.
+type KubeConfigGetter interface {
+	GetKubeConfig(cluster *clusterv1.Cluster, machine *clusterv1.Machine) (string, error)
+}
+
+// KubeConfigGetterFunc is an adapter to use ordinary functions as KubeConfigGetters.
+type KubeConfigGetterFunc func(cluster *clusterv1.Cluster, machine *clusterv1.Machine) (string, error)
+
+// GetKubeConfig calls f(cluster, machine).
+func (f KubeConfigGetterFunc) GetKubeConfig(cluster *clusterv1.Cluster, machine *clusterv1.Machine) (string, error) {
+	return f(cluster, machine)
+}
+
 // Deployer satisfies the ProviderDeployer(https://github.com/kubernetes-sigs/cluster-api/blob/master/cmd/clusterctl/clusterdeployer/clusterdeployer.go) interface.
 type Deployer struct {
-	scopeGetter actuators.ScopeGetter
+	scopeGetter      actuators.ScopeGetter
+	ipGetter         IPGetter
+	kubeConfigGetter KubeConfigGetter
+	servicesGetter   services.Getter
 }
 
 // Params is used to create a new deployer.
 type Params struct {
+	// ScopeGetter creates the scopes used to look up cluster resources.
+	// Defaults to actuators.DefaultScopeGetter.
 	ScopeGetter actuators.ScopeGetter
+
+	// IPGetter, if set, replaces the lookup of the API server load balancer.
+	IPGetter IPGetter
+
+	// KubeConfigGetter, if set, replaces the generation of kubeconfigs from
+	// the cluster CA, e.g. to fetch them from a secret store.
+	KubeConfigGetter KubeConfigGetter
+
+	// ServicesGetter returns the EC2 and ELB services of the clusters.
+	// Defaults to services.DefaultGetter.
+	ServicesGetter services.Getter
+}
+
+var (
+	registeredLock sync.RWMutex
+	registered     Params
+)
+
+// Register sets the getters used by the deployers created afterwards, when
+// their own params leave them unset. This lets programs embedding the
+// provider plug in their own implementations before creating the actuators.
+// Calling it again replaces the previous registration.
+func Register(params Params) {
+	registeredLock.Lock()
+	defer registeredLock.Unlock()
+	registered = params
 }
 
-// New returns a new Deployer.
+// New returns a new Deployer. Getters unset in params fall back to the
+// registered ones, then to the defaults.
 func New(params Params) *Deployer {
-	return &Deployer{
-		scopeGetter: params.ScopeGetter,
+	registeredLock.RLock()
+	defer registeredLock.RUnlock()
+
+	d := &Deployer{
+		scopeGetter:      params.ScopeGetter,
+		ipGetter:         params.IPGetter,
+		kubeConfigGetter: params.KubeConfigGetter,
+		servicesGetter:   params.ServicesGetter,
+	}
+
+	if d.scopeGetter == nil {
+		d.scopeGetter = registered.ScopeGetter
+	}
+	if d.scopeGetter == nil {
+		d.scopeGetter = actuators.DefaultScopeGetter
+	}
+	if d.ipGetter == nil {
+		d.ipGetter = registered.IPGetter
+	}
+	if d.kubeConfigGetter == nil {
+		d.kubeConfigGetter = registered.KubeConfigGetter
+	}
+	if d.servicesGetter == nil {
+		d.servicesGetter = registered.ServicesGetter
+	}
+	if d.servicesGetter == nil {
+		d.servicesGetter = services.DefaultGetter
 	}
+
+	return d
 }
 
 // GetIP returns the IP of a machine, but this is going away.
-func (d *Deployer) GetIP(cluster *clusterv1.Cluster, _ *clusterv1.Machine) (string, error) {
+func (d *Deployer) GetIP(cluster *clusterv1.Cluster, machine *clusterv1.Machine) (string, error) {
+	if d.ipGetter != nil {
+		return d.ipGetter.GetIP(cluster, machine)
+	}
+
 	scope, err := d.scopeGetter.GetScope(actuators.ScopeParams{Cluster: cluster})
 	if err != nil {
 		return "", err
 	}
 
-	if scope.ClusterStatus != nil && scope.ClusterStatus.Network.APIServerELB.DNSName != "" {
-		return scope.ClusterStatus.Network.APIServerELB.DNSName, nil
+	if scope.ClusterStatus != nil && scope.APIServerEndpoint() != "" {
+		return scope.APIServerEndpoint(), nil
+	}
+
+	if scope.ClusterConfig.APIServerElasticIP {
+		return "", errors.Errorf("the Elastic IP of the API server of cluster %q is not allocated yet", cluster.Name)
 	}
 
-	elbsvc := elb.NewService(scope)
-	return elbsvc.GetAPIServerDNSName()
+	return d.servicesGetter.ELB(scope).GetAPIServerDNSName()
 }
 
 // GetKubeConfig returns the kubeconfig after the bootstrap process is complete.
-func (d *Deployer) GetKubeConfig(cluster *clusterv1.Cluster, _ *clusterv1.Machine) (string, error) {
+func (d *Deployer) GetKubeConfig(cluster *clusterv1.Cluster, machine *clusterv1.Machine) (string, error) {
+	if d.kubeConfigGetter != nil {
+		return d.kubeConfigGetter.GetKubeConfig(cluster, machine)
+	}
 
-	// Load provider config.
-	config, err := providerv1.ClusterConfigFromProviderSpec(cluster.Spec.ProviderSpec)
+	// The scope loads the CA private key from the secret backend of the cluster, if any.
+	scope, err := d.scopeGetter.GetScope(actuators.ScopeParams{Cluster: cluster})
 	if err != nil {
-		return "", errors.Errorf("failed to load cluster provider status: %v", err)
+		return "", err
 	}
+	config := scope.ClusterConfig
 
 	cert, err := certificates.DecodeCertPEM(config.CACertificate)
 	if err != nil {
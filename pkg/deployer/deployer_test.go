@@ -73,6 +73,16 @@ func TestGetIP(t *testing.T) {
 						},
 					},
 				}, nil)
+				m.DescribeTags(&elb.DescribeTagsInput{
+					LoadBalancerNames: []*string{aws.String("test-apiserver")},
+				}).Return(&elb.DescribeTagsOutput{
+					TagDescriptions: []*elb.TagDescription{
+						{
+							LoadBalancerName: aws.String("test-apiserver"),
+							Tags:             []*elb.Tag{{Key: aws.String("kubernetes.io/cluster/test"), Value: aws.String("owned")}},
+						},
+					},
+				}, nil)
 			},
 		},
 		{
@@ -97,6 +107,16 @@ func TestGetIP(t *testing.T) {
 						},
 					},
 				}, nil)
+				m.DescribeTags(&elb.DescribeTagsInput{
+					LoadBalancerNames: []*string{aws.String("test-apiserver")},
+				}).Return(&elb.DescribeTagsOutput{
+					TagDescriptions: []*elb.TagDescription{
+						{
+							LoadBalancerName: aws.String("test-apiserver"),
+							Tags:             []*elb.Tag{{Key: aws.String("kubernetes.io/cluster/test"), Value: aws.String("owned")}},
+						},
+					},
+				}, nil)
 			},
 		},
 		{
@@ -152,3 +172,54 @@ func TestGetIP(t *testing.T) {
 		})
 	}
 }
+
+func TestRegister(t *testing.T) {
+	defer deployer.Register(deployer.Params{})
+
+	cluster := &clusterv1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"},
+	}
+
+	deployer.Register(deployer.Params{
+		IPGetter: deployer.IPGetterFunc(func(*clusterv1.Cluster, *clusterv1.Machine) (string, error) {
+			return "registered.example.com", nil
+		}),
+		KubeConfigGetter: deployer.KubeConfigGetterFunc(func(*clusterv1.Cluster, *clusterv1.Machine) (string, error) {
+			return "registered-kubeconfig", nil
+		}),
+	})
+
+	// Registered getters apply to deployers created afterwards.
+	d := deployer.New(deployer.Params{})
+
+	ip, err := d.GetIP(cluster, nil)
+	if err != nil {
+		t.Fatalf("failed to get API server address: %v", err)
+	}
+	if ip != "registered.example.com" {
+		t.Fatalf("expected the registered IP getter to be used, got %q", ip)
+	}
+
+	kubeConfig, err := d.GetKubeConfig(cluster, nil)
+	if err != nil {
+		t.Fatalf("failed to get kubeconfig: %v", err)
+	}
+	if kubeConfig != "registered-kubeconfig" {
+		t.Fatalf("expected the registered kubeconfig getter to be used, got %q", kubeConfig)
+	}
+
+	// Getters set in the params take precedence.
+	d = deployer.New(deployer.Params{
+		IPGetter: deployer.IPGetterFunc(func(*clusterv1.Cluster, *clusterv1.Machine) (string, error) {
+			return "params.example.com", nil
+		}),
+	})
+
+	ip, err = d.GetIP(cluster, nil)
+	if err != nil {
+		t.Fatalf("failed to get API server address: %v", err)
+	}
+	if ip != "params.example.com" {
+		t.Fatalf("expected the IP getter of the params to be used, got %q", ip)
+	}
+}
@@ -0,0 +1,88 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deployer
+
+import (
+	"context"
+	"net"
+
+	"github.com/pkg/errors"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/actuators"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/awserrors"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/ssm/tunnel"
+)
+
+// WorkloadConfig returns a client configuration of the Kubernetes API of a
+// cluster. If server is not empty, it replaces the address of the API server
+// found in the kubeconfig of the cluster.
+func (d *Deployer) WorkloadConfig(scope *actuators.Scope, server string) (*rest.Config, error) {
+	kubeConfig, err := d.GetKubeConfig(scope.Cluster, nil)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to retrieve kubeconfig for cluster %q.", scope.Name())
+	}
+
+	config, err := clientcmd.BuildConfigFromKubeconfigGetter(server, func() (*clientcmdapi.Config, error) {
+		return clientcmd.Load([]byte(kubeConfig))
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get client config for cluster %q", scope.Name())
+	}
+
+	if err := d.tunnelConfig(scope, config); err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+// WorkloadClient returns a client of the Kubernetes API of a cluster.
+func (d *Deployer) WorkloadClient(scope *actuators.Scope) (kubernetes.Interface, error) {
+	config, err := d.WorkloadConfig(scope, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return kubernetes.NewForConfig(config)
+}
+
+// tunnelConfig makes a client configuration of the Kubernetes API of a
+// cluster with a private API server connect to it through a Session Manager
+// port forwarding session to a running control plane instance.
+func (d *Deployer) tunnelConfig(scope *actuators.Scope, config *rest.Config) error {
+	if !scope.ClusterConfig.PrivateAPIServer {
+		return nil
+	}
+
+	target, err := d.servicesGetter.EC2(scope).RunningControlPlaneInstance()
+	if err != nil {
+		return err
+	}
+	if target == "" {
+		return awserrors.NewFailedDependency(errors.Errorf("no running control plane instance of cluster %q to reach its private API server through", scope.Name()))
+	}
+
+	host := scope.APIServerEndpoint()
+	config.Dial = func(ctx context.Context, network, address string) (net.Conn, error) {
+		return tunnel.Dial(ctx, scope.Sessions, target, host, 6443)
+	}
+
+	return nil
+}
@@ -0,0 +1,111 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package drain cordons and drains the nodes of workload clusters.
+package drain
+
+import (
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog"
+)
+
+// Cordon marks a node as unschedulable, or schedulable again.
+func Cordon(client kubernetes.Interface, node *corev1.Node, unschedulable bool) error {
+	if node.Spec.Unschedulable == unschedulable {
+		return nil
+	}
+
+	node = node.DeepCopy()
+	node.Spec.Unschedulable = unschedulable
+	if _, err := client.CoreV1().Nodes().Update(node); err != nil {
+		return errors.Wrapf(err, "failed to set node %q unschedulable to %t", node.Name, unschedulable)
+	}
+
+	return nil
+}
+
+// Drain evicts the pods running on a node, except for the pods of daemon
+// sets and the static pods, which would be recreated on the node anyway.
+// Evictions honour pod disruption budgets, so it returns true once no pod is
+// left to evict.
+func Drain(client kubernetes.Interface, name string) (bool, error) {
+	pods, err := Pending(client, name)
+	if err != nil {
+		return false, err
+	}
+
+	for _, pod := range pods {
+		if pod.DeletionTimestamp != nil {
+			continue
+		}
+
+		err := client.PolicyV1beta1().Evictions(pod.Namespace).Evict(&policyv1beta1.Eviction{
+			ObjectMeta: metav1.ObjectMeta{Name: pod.Name, Namespace: pod.Namespace},
+		})
+		switch {
+		case err == nil, apierrors.IsNotFound(err):
+		case apierrors.IsTooManyRequests(err):
+			// The eviction would violate a pod disruption budget, and is retried later.
+			klog.V(2).Infof("Postponing the eviction of pod %s/%s: %v", pod.Namespace, pod.Name, err)
+		default:
+			return false, errors.Wrapf(err, "failed to evict pod %s/%s", pod.Namespace, pod.Name)
+		}
+	}
+
+	return len(pods) == 0, nil
+}
+
+// Pending returns the pods left to evict, or still terminating, on a node.
+func Pending(client kubernetes.Interface, name string) ([]corev1.Pod, error) {
+	pods, err := client.CoreV1().Pods(metav1.NamespaceAll).List(metav1.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("spec.nodeName", name).String(),
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list the pods of node %q", name)
+	}
+
+	var pending []corev1.Pod
+	for _, pod := range pods.Items {
+		if Evictable(&pod) {
+			pending = append(pending, pod)
+		}
+	}
+
+	return pending, nil
+}
+
+// Evictable returns true if a pod must be evicted to drain its node.
+func Evictable(pod *corev1.Pod) bool {
+	if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+		return false
+	}
+
+	if _, ok := pod.Annotations[corev1.MirrorPodAnnotationKey]; ok {
+		return false
+	}
+
+	if owner := metav1.GetControllerOf(pod); owner != nil && owner.Kind == "DaemonSet" {
+		return false
+	}
+
+	return true
+}
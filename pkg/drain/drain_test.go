@@ -0,0 +1,61 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package drain
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestEvictable(t *testing.T) {
+	daemonSet := metav1.NewControllerRef(&metav1.ObjectMeta{Name: "ds"}, schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "DaemonSet"})
+
+	testCases := []struct {
+		name   string
+		pod    *corev1.Pod
+		expect bool
+	}{
+		{
+			name:   "running pod",
+			pod:    &corev1.Pod{Status: corev1.PodStatus{Phase: corev1.PodRunning}},
+			expect: true,
+		},
+		{
+			name: "completed pod",
+			pod:  &corev1.Pod{Status: corev1.PodStatus{Phase: corev1.PodSucceeded}},
+		},
+		{
+			name: "static pod",
+			pod:  &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{corev1.MirrorPodAnnotationKey: "hash"}}},
+		},
+		{
+			name: "daemon set pod",
+			pod:  &corev1.Pod{ObjectMeta: metav1.ObjectMeta{OwnerReferences: []metav1.OwnerReference{*daemonSet}}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := Evictable(tc.pod); got != tc.expect {
+				t.Fatalf("expected Evictable to be %t, got %t", tc.expect, got)
+			}
+		})
+	}
+}
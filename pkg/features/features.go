@@ -0,0 +1,185 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package features gates behaviors that are rolled out gradually. A gate is
+// enabled or disabled for the whole controller with the --feature-gates flag,
+// and can be overridden per cluster or machine with the FeatureGatesAnnotation.
+package features
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"k8s.io/klog"
+)
+
+// FeatureGatesAnnotation overrides the feature gates for a cluster or a machine.
+// Its value uses the same format as the --feature-gates flag, e.g. "Foo=true,Bar=false".
+const FeatureGatesAnnotation = "sigs.k8s.io/cluster-api-provider-aws/feature-gates"
+
+// Feature is the name of a feature gate.
+type Feature string
+
+const (
+	// DrainBeforeDelete cordons and drains the nodes of deleted machines
+	// before their instances are terminated.
+	DrainBeforeDelete Feature = "DrainBeforeDelete"
+
+	// TerminationProtection launches control plane instances protected
+	// against terminations through the console or the API.
+	TerminationProtection Feature = "TerminationProtection"
+
+	// KubeletReservedDefaults reserves resources for the system and
+	// Kubernetes daemons of machines which do not set kubeletReserved,
+	// computed from their instance type.
+	KubeletReservedDefaults Feature = "KubeletReservedDefaults"
+
+	// ConsolidationHints annotates machines with the CPU utilization and
+	// hourly price of their instances, from CloudWatch and the Price List
+	// API, for consolidation tooling to pick the machines to remove.
+	ConsolidationHints Feature = "ConsolidationHints"
+)
+
+// defaultFeatures holds every known feature gate and whether it is enabled by default.
+// New behaviors register their gate here, disabled by default, until they graduate.
+var defaultFeatures = map[Feature]bool{
+	DrainBeforeDelete:       false,
+	TerminationProtection:   false,
+	KubeletReservedDefaults: false,
+	ConsolidationHints:      false,
+}
+
+// DefaultFeatureGate is the feature gate shared by the controllers.
+var DefaultFeatureGate = NewFeatureGate(defaultFeatures)
+
+// FeatureGate tracks which of a set of known features are enabled.
+// It implements flag.Value so it can be set from the command line.
+type FeatureGate struct {
+	lock    sync.RWMutex
+	known   map[Feature]bool
+	enabled map[Feature]bool
+}
+
+// NewFeatureGate returns a feature gate for the given known features and their defaults.
+func NewFeatureGate(known map[Feature]bool) *FeatureGate {
+	return &FeatureGate{
+		known:   known,
+		enabled: map[Feature]bool{},
+	}
+}
+
+// Set enables or disables the features listed in the given comma separated
+// list of Feature=bool pairs. Unknown features are rejected.
+func (f *FeatureGate) Set(value string) error {
+	overrides, err := parse(value)
+	if err != nil {
+		return err
+	}
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	for feature, enabled := range overrides {
+		if _, ok := f.known[feature]; !ok {
+			return errors.Errorf("unknown feature gate %q", feature)
+		}
+		f.enabled[feature] = enabled
+	}
+
+	return nil
+}
+
+// String returns the features set on the gate, as accepted by Set.
+func (f *FeatureGate) String() string {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+
+	pairs := make([]string, 0, len(f.enabled))
+	for feature, enabled := range f.enabled {
+		pairs = append(pairs, fmt.Sprintf("%s=%t", feature, enabled))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// Enabled returns true if the feature is enabled for the whole controller.
+func (f *FeatureGate) Enabled(feature Feature) bool {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+
+	if enabled, ok := f.enabled[feature]; ok {
+		return enabled
+	}
+	return f.known[feature]
+}
+
+// EnabledFor returns true if the feature is enabled for an object, given the
+// annotations of the object and of its owners, from the most specific to the
+// least specific. The first annotation setting the feature wins, falling back
+// to the controller-wide setting.
+//
+// Annotations naming features unknown to this controller are ignored, so
+// objects annotated for a newer release keep working across upgrades and
+// rollbacks.
+func (f *FeatureGate) EnabledFor(feature Feature, annotations ...map[string]string) bool {
+	for _, a := range annotations {
+		value, ok := a[FeatureGatesAnnotation]
+		if !ok {
+			continue
+		}
+
+		overrides, err := parse(value)
+		if err != nil {
+			klog.Warningf("Ignoring invalid %s annotation %q: %v", FeatureGatesAnnotation, value, err)
+			continue
+		}
+
+		if enabled, ok := overrides[feature]; ok {
+			return enabled
+		}
+	}
+
+	return f.Enabled(feature)
+}
+
+// parse parses a comma separated list of Feature=bool pairs.
+func parse(value string) (map[Feature]bool, error) {
+	overrides := map[Feature]bool{}
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 {
+			return nil, errors.Errorf("missing bool value for feature gate %q", pair)
+		}
+
+		enabled, err := strconv.ParseBool(strings.TrimSpace(kv[1]))
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid value for feature gate %q", kv[0])
+		}
+
+		overrides[Feature(strings.TrimSpace(kv[0]))] = enabled
+	}
+
+	return overrides, nil
+}
@@ -0,0 +1,108 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package features
+
+import (
+	"testing"
+)
+
+const (
+	alphaFeature Feature = "AlphaFeature"
+	betaFeature  Feature = "BetaFeature"
+)
+
+func TestFeatureGateSet(t *testing.T) {
+	gate := NewFeatureGate(map[Feature]bool{alphaFeature: false, betaFeature: true})
+
+	if gate.Enabled(alphaFeature) || !gate.Enabled(betaFeature) {
+		t.Fatalf("expected the defaults to apply, got %q", gate.String())
+	}
+
+	if err := gate.Set("AlphaFeature=true, BetaFeature=false"); err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+
+	if !gate.Enabled(alphaFeature) || gate.Enabled(betaFeature) {
+		t.Fatalf("expected the flag to override the defaults, got %q", gate.String())
+	}
+
+	if gate.String() != "AlphaFeature=true,BetaFeature=false" {
+		t.Fatalf("unexpected string value %q", gate.String())
+	}
+
+	if err := gate.Set("UnknownFeature=true"); err == nil {
+		t.Fatalf("expected an unknown feature to be rejected")
+	}
+
+	if err := gate.Set("AlphaFeature"); err == nil {
+		t.Fatalf("expected a feature without value to be rejected")
+	}
+}
+
+func TestFeatureGateEnabledFor(t *testing.T) {
+	gate := NewFeatureGate(map[Feature]bool{alphaFeature: false, betaFeature: true})
+
+	testCases := []struct {
+		name        string
+		annotations []map[string]string
+		expected    bool
+	}{
+		{
+			name:     "no annotations",
+			expected: false,
+		},
+		{
+			name: "enabled on the owner",
+			annotations: []map[string]string{
+				nil,
+				{FeatureGatesAnnotation: "AlphaFeature=true"},
+			},
+			expected: true,
+		},
+		{
+			name: "disabled on the object, enabled on the owner",
+			annotations: []map[string]string{
+				{FeatureGatesAnnotation: "AlphaFeature=false"},
+				{FeatureGatesAnnotation: "AlphaFeature=true"},
+			},
+			expected: false,
+		},
+		{
+			name: "other features on the object",
+			annotations: []map[string]string{
+				{FeatureGatesAnnotation: "BetaFeature=false,FutureFeature=true"},
+				{FeatureGatesAnnotation: "AlphaFeature=true"},
+			},
+			expected: true,
+		},
+		{
+			name: "invalid annotation",
+			annotations: []map[string]string{
+				{FeatureGatesAnnotation: "AlphaFeature=maybe"},
+			},
+			expected: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if actual := gate.EnabledFor(alphaFeature, tc.annotations...); actual != tc.expected {
+				t.Fatalf("expected %v, got %v", tc.expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package metrics records how long the infrastructure of clusters and
+// machines takes to converge. The durations are published with expvar,
+// under convergence_seconds.
+package metrics
+
+import (
+	"expvar"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/klog"
+)
+
+// SlowThreshold is the time after which a component that has not converged
+// yet is logged as a warning, along with the error blocking it.
+var SlowThreshold = 10 * time.Minute
+
+var (
+	convergence = expvar.NewMap("convergence_seconds")
+
+	lock      sync.Mutex
+	started   = map[string]time.Time{}
+	converged = map[string]bool{}
+)
+
+// Observe records an attempt at converging a component of the given object,
+// e.g. "cluster/default/test" and "vpc". The convergence time is measured from
+// the start of the first attempt, or from the given start time if earlier
+// attempts were not observed, to the first attempt without error. It is only
+// recorded once per object and component.
+func Observe(object, component string, start time.Time, err error) {
+	key := object + "/" + component
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	if converged[key] {
+		return
+	}
+
+	if first, ok := started[key]; ok {
+		start = first
+	} else {
+		started[key] = start
+	}
+
+	elapsed := time.Since(start)
+
+	if err == nil {
+		f := new(expvar.Float)
+		f.Set(elapsed.Seconds())
+		convergence.Set(key, f)
+
+		converged[key] = true
+		delete(started, key)
+		klog.V(2).Infof("Component %s of %s converged after %s", component, object, elapsed.Round(time.Second))
+		return
+	}
+
+	if elapsed > SlowThreshold {
+		klog.Warningf("Component %s of %s has not converged after %s, blocked by: %v", component, object, elapsed.Round(time.Second), err)
+	}
+}
+
+// Forget drops the convergence state and times recorded for the given object,
+// once it is deleted.
+func Forget(object string) {
+	prefix := object + "/"
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	for key := range started {
+		if strings.HasPrefix(key, prefix) {
+			delete(started, key)
+		}
+	}
+
+	for key := range converged {
+		if strings.HasPrefix(key, prefix) {
+			delete(converged, key)
+			convergence.Delete(key)
+		}
+	}
+}
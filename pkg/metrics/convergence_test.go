@@ -0,0 +1,79 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"errors"
+	"expvar"
+	"testing"
+	"time"
+)
+
+func recorded(key string) float64 {
+	f, ok := convergence.Get(key).(*expvar.Float)
+	if !ok {
+		return -1
+	}
+	return f.Value()
+}
+
+func TestObserve(t *testing.T) {
+	defer Forget("cluster/default/test")
+
+	start := time.Now().Add(-time.Minute)
+	Observe("cluster/default/test", "vpc", start, errors.New("pending"))
+
+	if recorded("cluster/default/test/vpc") != -1 {
+		t.Fatalf("did not expect a convergence time before the component converged")
+	}
+
+	// The time is measured from the first attempt, not from the given start.
+	Observe("cluster/default/test", "vpc", time.Now(), nil)
+
+	first := recorded("cluster/default/test/vpc")
+	if first < time.Minute.Seconds() {
+		t.Fatalf("expected the convergence time to be measured from the first attempt, got %v", first)
+	}
+
+	// Later reconciliations do not overwrite the convergence time.
+	Observe("cluster/default/test", "vpc", time.Now(), nil)
+
+	if recorded("cluster/default/test/vpc") != first {
+		t.Fatalf("expected the convergence time to be recorded once")
+	}
+}
+
+func TestForget(t *testing.T) {
+	Observe("machine/default/test", "instance-running", time.Now(), nil)
+	Observe("machine/default/test", "node-ready", time.Now(), errors.New("pending"))
+	Observe("machine/default/test-other", "instance-running", time.Now(), nil)
+	defer Forget("machine/default/test-other")
+
+	Forget("machine/default/test")
+
+	if recorded("machine/default/test/instance-running") != -1 {
+		t.Fatalf("expected the convergence times of the machine to be forgotten")
+	}
+
+	if _, ok := started["machine/default/test/node-ready"]; ok {
+		t.Fatalf("expected the pending attempts of the machine to be forgotten")
+	}
+
+	if recorded("machine/default/test-other/instance-running") == -1 {
+		t.Fatalf("did not expect the convergence times of other machines to be forgotten")
+	}
+}
@@ -0,0 +1,250 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package template captures provisioned clusters as golden templates, and
+// creates new clusters from them.
+package template
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/actuators"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/filter"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/tags"
+	clusterv1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+)
+
+// Capture returns a template of the cluster of the given scope and of its machines.
+//
+// The AMIs and instance types left to defaults or launch templates are
+// resolved from the running instances, so that clusters created from the
+// template run the same images. Encrypted copies of AMIs are replaced by
+// their source AMI, as the copies are owned by the captured cluster.
+//
+// The CA of the cluster and the references to the subnets and security
+// groups owned by the cluster are left out, as they are unique to it.
+func Capture(scope *actuators.Scope, machines []clusterv1.Machine) (*v1alpha1.AWSClusterTemplate, error) {
+	config := scope.ClusterConfig.DeepCopy()
+	config.ObjectMeta = metav1.ObjectMeta{}
+	config.CACertificate = nil
+	config.CAPrivateKey = nil
+
+	network := scope.Cluster.Spec.ClusterNetwork
+	template := &v1alpha1.AWSClusterTemplate{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: v1alpha1.SchemeGroupVersion.String(),
+			Kind:       "AWSClusterTemplate",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: scope.Name(),
+		},
+		Cluster: v1alpha1.ClusterTemplate{
+			PodsCIDRBlocks:     network.Pods.CIDRBlocks,
+			ServicesCIDRBlocks: network.Services.CIDRBlocks,
+			ServiceDomain:      network.ServiceDomain,
+			ProviderSpec:       *config,
+		},
+		Network: v1alpha1.NetworkLayout{
+			VPCCidrBlock: scope.VPC().CidrBlock,
+		},
+	}
+
+	for _, sn := range scope.Subnets() {
+		template.Network.Subnets = append(template.Network.Subnets, v1alpha1.SubnetLayout{
+			AvailabilityZone: sn.AvailabilityZone,
+			CidrBlock:        sn.CidrBlock,
+			IsPublic:         sn.IsPublic,
+		})
+	}
+
+	for i := range machines {
+		machine, err := captureMachine(scope, &machines[i])
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to capture machine %q", machines[i].Name)
+		}
+		template.Machines = append(template.Machines, *machine)
+	}
+
+	return template, nil
+}
+
+func captureMachine(scope *actuators.Scope, machine *clusterv1.Machine) (*v1alpha1.MachineTemplate, error) {
+	config, err := v1alpha1.MachineConfigFromProviderSpec(machine.Spec.ProviderSpec)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load machine provider spec")
+	}
+	config.ObjectMeta = metav1.ObjectMeta{}
+
+	status, err := v1alpha1.MachineStatusFromProviderStatus(machine.Status.ProviderStatus)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load machine provider status")
+	}
+
+	if config.AMI.ID == nil || config.InstanceType == "" {
+		if status.InstanceID == nil {
+			return nil, errors.New("machine has no instance yet")
+		}
+
+		instance, err := describeInstance(scope, *status.InstanceID)
+		if err != nil {
+			return nil, err
+		}
+
+		if config.AMI.ID == nil {
+			imageID, err := sourceImage(scope, aws.StringValue(instance.ImageId))
+			if err != nil {
+				return nil, err
+			}
+			config.AMI = v1alpha1.AWSResourceReference{ID: aws.String(imageID)}
+		}
+
+		if config.InstanceType == "" {
+			config.InstanceType = aws.StringValue(instance.InstanceType)
+		}
+	}
+
+	// Resources owned by the cluster do not exist in the clusters created from the template.
+	if scope.ClusterConfig.ExternalNetwork == nil {
+		if config.Subnet != nil && config.Subnet.ID != nil {
+			if _, ok := scope.Subnets().ToMap()[*config.Subnet.ID]; ok {
+				config.Subnet = nil
+			}
+		}
+
+		owned := map[string]bool{}
+		for _, sg := range scope.SecurityGroups() {
+			owned[sg.ID] = true
+		}
+
+		var groups []v1alpha1.AWSResourceReference
+		for _, sg := range config.AdditionalSecurityGroups {
+			if sg.ID == nil || !owned[*sg.ID] {
+				groups = append(groups, sg)
+			}
+		}
+		config.AdditionalSecurityGroups = groups
+	}
+
+	return &v1alpha1.MachineTemplate{
+		Name:                strings.TrimPrefix(machine.Name, scope.Name()+"-"),
+		Labels:              machine.Labels,
+		KubeletVersion:      machine.Spec.Versions.Kubelet,
+		ControlPlaneVersion: machine.Spec.Versions.ControlPlane,
+		ProviderSpec:        *config,
+	}, nil
+}
+
+func describeInstance(scope *actuators.Scope, instanceID string) (*ec2.Instance, error) {
+	out, err := scope.EC2.DescribeInstances(&ec2.DescribeInstancesInput{
+		InstanceIds: aws.StringSlice([]string{instanceID}),
+		Filters:     []*ec2.Filter{filter.EC2.Cluster(scope.Name())},
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to describe instance %q", instanceID)
+	}
+
+	if len(out.Reservations) == 0 || len(out.Reservations[0].Instances) == 0 {
+		return nil, errors.Errorf("instance %q not found", instanceID)
+	}
+
+	return out.Reservations[0].Instances[0], nil
+}
+
+// sourceImage returns the AMI the given AMI was copied from, if it is an
+// encrypted copy owned by the cluster, or the given AMI otherwise.
+func sourceImage(scope *actuators.Scope, imageID string) (string, error) {
+	out, err := scope.EC2.DescribeImages(&ec2.DescribeImagesInput{
+		ImageIds: aws.StringSlice([]string{imageID}),
+	})
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to describe ami %q", imageID)
+	}
+
+	for _, image := range out.Images {
+		for _, tag := range image.Tags {
+			if aws.StringValue(tag.Key) == tags.NameAWSProviderSourceAMI {
+				return aws.StringValue(tag.Value), nil
+			}
+		}
+	}
+
+	return imageID, nil
+}
+
+// Instantiate returns a cluster and its machines, created from the given template.
+// Machines are named after the cluster.
+func Instantiate(template *v1alpha1.AWSClusterTemplate, name, namespace string) (*clusterv1.Cluster, []*clusterv1.Machine, error) {
+	spec, err := v1alpha1.EncodeClusterSpec(&template.Cluster.ProviderSpec)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to encode cluster provider spec")
+	}
+
+	cluster := &clusterv1.Cluster{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: clusterv1.SchemeGroupVersion.String(),
+			Kind:       "Cluster",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Spec: clusterv1.ClusterSpec{
+			ClusterNetwork: clusterv1.ClusterNetworkingConfig{
+				Pods:          clusterv1.NetworkRanges{CIDRBlocks: template.Cluster.PodsCIDRBlocks},
+				Services:      clusterv1.NetworkRanges{CIDRBlocks: template.Cluster.ServicesCIDRBlocks},
+				ServiceDomain: template.Cluster.ServiceDomain,
+			},
+			ProviderSpec: clusterv1.ProviderSpec{Value: spec},
+		},
+	}
+
+	var machines []*clusterv1.Machine
+	for i := range template.Machines {
+		m := &template.Machines[i]
+
+		spec, err := v1alpha1.EncodeMachineSpec(&m.ProviderSpec)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "failed to encode provider spec of machine %q", m.Name)
+		}
+
+		machines = append(machines, &clusterv1.Machine{
+			TypeMeta: metav1.TypeMeta{
+				APIVersion: clusterv1.SchemeGroupVersion.String(),
+				Kind:       "Machine",
+			},
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      fmt.Sprintf("%s-%s", name, m.Name),
+				Namespace: namespace,
+				Labels:    m.Labels,
+			},
+			Spec: clusterv1.MachineSpec{
+				Versions: clusterv1.MachineVersionInfo{
+					Kubelet:      m.KubeletVersion,
+					ControlPlane: m.ControlPlaneVersion,
+				},
+				ProviderSpec: clusterv1.ProviderSpec{Value: spec},
+			},
+		})
+	}
+
+	return cluster, machines, nil
+}
@@ -0,0 +1,189 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package template
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/golang/mock/gomock"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/actuators"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/filter"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/ec2/mock_ec2iface"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/tags"
+	clusterv1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+)
+
+func TestCaptureAndInstantiate(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	ec2Mock := mock_ec2iface.NewMockEC2API(mockCtrl)
+	ec2Mock.EXPECT().
+		DescribeInstances(&ec2.DescribeInstancesInput{
+			InstanceIds: aws.StringSlice([]string{"i-controlplane"}),
+			Filters:     []*ec2.Filter{filter.EC2.Cluster("golden")},
+		}).
+		Return(&ec2.DescribeInstancesOutput{
+			Reservations: []*ec2.Reservation{{
+				Instances: []*ec2.Instance{{
+					InstanceId:   aws.String("i-controlplane"),
+					ImageId:      aws.String("ami-encrypted"),
+					InstanceType: aws.String("m5.large"),
+				}},
+			}},
+		}, nil)
+	ec2Mock.EXPECT().
+		DescribeImages(&ec2.DescribeImagesInput{ImageIds: aws.StringSlice([]string{"ami-encrypted"})}).
+		Return(&ec2.DescribeImagesOutput{
+			Images: []*ec2.Image{{
+				ImageId: aws.String("ami-encrypted"),
+				Tags:    []*ec2.Tag{{Key: aws.String(tags.NameAWSProviderSourceAMI), Value: aws.String("ami-source")}},
+			}},
+		}, nil)
+
+	clusterSpec, err := v1alpha1.EncodeClusterSpec(&v1alpha1.AWSClusterProviderSpec{
+		Region:        "us-east-1",
+		SSHKeyName:    "default",
+		CACertificate: []byte("ca"),
+		CAPrivateKey:  []byte("key"),
+	})
+	if err != nil {
+		t.Fatalf("failed to encode cluster spec: %v", err)
+	}
+
+	clusterStatus, err := v1alpha1.EncodeClusterStatus(&v1alpha1.AWSClusterProviderStatus{
+		Network: v1alpha1.Network{
+			VPC: v1alpha1.VPC{ID: "vpc-1", CidrBlock: "10.0.0.0/16"},
+			Subnets: v1alpha1.Subnets{
+				{ID: "subnet-public", AvailabilityZone: "us-east-1a", CidrBlock: "10.0.0.0/24", IsPublic: true},
+				{ID: "subnet-private", AvailabilityZone: "us-east-1a", CidrBlock: "10.0.1.0/24"},
+			},
+			SecurityGroups: map[v1alpha1.SecurityGroupRole]*v1alpha1.SecurityGroup{
+				v1alpha1.SecurityGroupNode: {ID: "sg-node"},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to encode cluster status: %v", err)
+	}
+
+	scope, err := actuators.NewScope(actuators.ScopeParams{
+		Cluster: &clusterv1.Cluster{
+			ObjectMeta: metav1.ObjectMeta{Name: "golden"},
+			Spec: clusterv1.ClusterSpec{
+				ClusterNetwork: clusterv1.ClusterNetworkingConfig{
+					Pods:          clusterv1.NetworkRanges{CIDRBlocks: []string{"192.168.0.0/16"}},
+					Services:      clusterv1.NetworkRanges{CIDRBlocks: []string{"10.96.0.0/12"}},
+					ServiceDomain: "cluster.local",
+				},
+				ProviderSpec: clusterv1.ProviderSpec{Value: clusterSpec},
+			},
+			Status: clusterv1.ClusterStatus{ProviderStatus: clusterStatus},
+		},
+		AWSClients: actuators.AWSClients{
+			EC2: ec2Mock,
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create test context: %v", err)
+	}
+
+	machineSpec, err := v1alpha1.EncodeMachineSpec(&v1alpha1.AWSMachineProviderSpec{
+		Subnet:                   &v1alpha1.AWSResourceReference{ID: aws.String("subnet-private")},
+		AdditionalSecurityGroups: []v1alpha1.AWSResourceReference{{ID: aws.String("sg-node")}, {ID: aws.String("sg-shared")}},
+	})
+	if err != nil {
+		t.Fatalf("failed to encode machine spec: %v", err)
+	}
+
+	machineStatus, err := v1alpha1.EncodeMachineStatus(&v1alpha1.AWSMachineProviderStatus{InstanceID: aws.String("i-controlplane")})
+	if err != nil {
+		t.Fatalf("failed to encode machine status: %v", err)
+	}
+
+	machines := []clusterv1.Machine{{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "golden-controlplane-0",
+			Labels: map[string]string{"set": "controlplane"},
+		},
+		Spec: clusterv1.MachineSpec{
+			Versions:     clusterv1.MachineVersionInfo{Kubelet: "1.13.0", ControlPlane: "1.13.0"},
+			ProviderSpec: clusterv1.ProviderSpec{Value: machineSpec},
+		},
+		Status: clusterv1.MachineStatus{ProviderStatus: machineStatus},
+	}}
+
+	tpl, err := Capture(scope, machines)
+	if err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+
+	if tpl.Cluster.ProviderSpec.CACertificate != nil || tpl.Cluster.ProviderSpec.CAPrivateKey != nil {
+		t.Fatalf("did not expect the CA of the cluster to be captured")
+	}
+
+	if tpl.Network.VPCCidrBlock != "10.0.0.0/16" || len(tpl.Network.Subnets) != 2 {
+		t.Fatalf("expected the network layout to be captured, got %+v", tpl.Network)
+	}
+
+	if len(tpl.Machines) != 1 {
+		t.Fatalf("expected a single machine, got %d", len(tpl.Machines))
+	}
+
+	m := tpl.Machines[0]
+	if m.Name != "controlplane-0" {
+		t.Fatalf("expected the cluster name prefix to be trimmed, got %q", m.Name)
+	}
+
+	if aws.StringValue(m.ProviderSpec.AMI.ID) != "ami-source" || m.ProviderSpec.InstanceType != "m5.large" {
+		t.Fatalf("expected the source ami and instance type to be resolved, got %q and %q", aws.StringValue(m.ProviderSpec.AMI.ID), m.ProviderSpec.InstanceType)
+	}
+
+	if m.ProviderSpec.Subnet != nil {
+		t.Fatalf("did not expect the cluster owned subnet to be captured")
+	}
+
+	if groups := m.ProviderSpec.AdditionalSecurityGroups; len(groups) != 1 || aws.StringValue(groups[0].ID) != "sg-shared" {
+		t.Fatalf("expected only the shared security group to be captured, got %v", groups)
+	}
+
+	cluster, created, err := Instantiate(tpl, "copy", "team-a")
+	if err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+
+	if cluster.Name != "copy" || cluster.Namespace != "team-a" || cluster.Spec.ClusterNetwork.ServiceDomain != "cluster.local" {
+		t.Fatalf("unexpected cluster %v", cluster.ObjectMeta)
+	}
+
+	if len(created) != 1 || created[0].Name != "copy-controlplane-0" || created[0].Spec.Versions.ControlPlane != "1.13.0" {
+		t.Fatalf("unexpected machines %v", created)
+	}
+
+	config, err := v1alpha1.MachineConfigFromProviderSpec(created[0].Spec.ProviderSpec)
+	if err != nil {
+		t.Fatalf("failed to decode machine spec: %v", err)
+	}
+
+	if aws.StringValue(config.AMI.ID) != "ami-source" {
+		t.Fatalf("expected the captured ami to be used, got %q", aws.StringValue(config.AMI.ID))
+	}
+}
@@ -0,0 +1,127 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package webhook sends requests to the endpoints configured by the users of
+// the provider, such as lifecycle hooks and IPAM services. The endpoints are
+// restricted to HTTPS on public addresses, so that a cluster spec cannot
+// reach the instance metadata service or the internal services of the
+// network of the controller.
+package webhook
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// blockedNetworks are the loopback, link-local, private and otherwise
+// non-public networks webhooks may not connect to.
+var blockedNetworks = parseCIDRs(
+	"0.0.0.0/8",
+	"10.0.0.0/8",
+	"100.64.0.0/10",
+	"127.0.0.0/8",
+	"169.254.0.0/16",
+	"172.16.0.0/12",
+	"192.168.0.0/16",
+	"::/128",
+	"::1/128",
+	"fc00::/7",
+	"fe80::/10",
+)
+
+func parseCIDRs(cidrs ...string) []*net.IPNet {
+	var networks []*net.IPNet
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}
+
+// ValidateURL returns an error if the URL of a webhook is not an absolute
+// HTTPS URL, or if its host is an IP address webhooks may not connect to.
+func ValidateURL(rawurl string) error {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return errors.Wrapf(err, "invalid url %q", rawurl)
+	}
+
+	if u.Scheme != "https" || u.Host == "" {
+		return errors.Errorf("invalid url %q: only https urls are allowed", rawurl)
+	}
+
+	if ip := net.ParseIP(u.Hostname()); ip != nil && Blocked(ip) {
+		return errors.Errorf("invalid url %q: %s is not a public address", rawurl, ip)
+	}
+
+	return nil
+}
+
+// Blocked returns true if webhooks may not connect to the IP address.
+func Blocked(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() {
+		return true
+	}
+
+	for _, network := range blockedNetworks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// NewClient returns an HTTP client with the given timeout which refuses to
+// connect to the addresses webhooks may not connect to. The addresses are
+// checked once resolved, so that host names resolving to blocked addresses
+// are refused too. Redirects are not followed.
+func NewClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || Blocked(ip) {
+				return errors.Errorf("refusing to connect to %s: not a public address", host)
+			}
+			return nil
+		},
+	}
+
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, network, address string) (net.Conn, error) {
+				return dialer.DialContext(ctx, network, address)
+			},
+			TLSHandshakeTimeout: timeout,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
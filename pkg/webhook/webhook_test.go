@@ -0,0 +1,74 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidateURL(t *testing.T) {
+	testCases := []struct {
+		url         string
+		expectError bool
+	}{
+		{url: "https://ipam.example.com/allocate"},
+		{url: "https://203.0.113.10:8443/hooks"},
+		{url: "http://ipam.example.com/allocate", expectError: true},
+		{url: "ipam.example.com", expectError: true},
+		{url: "https://169.254.169.254/latest/meta-data", expectError: true},
+		{url: "https://10.0.0.1/hooks", expectError: true},
+		{url: "https://[::1]/hooks", expectError: true},
+		{url: "https://[fd00:ec2::254]/hooks", expectError: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.url, func(t *testing.T) {
+			err := ValidateURL(tc.url)
+			if tc.expectError != (err != nil) {
+				t.Fatalf("expected error: %t, got: %v", tc.expectError, err)
+			}
+		})
+	}
+}
+
+func TestBlocked(t *testing.T) {
+	for _, ip := range []string{"127.0.0.1", "169.254.169.254", "172.20.1.1", "192.168.0.1", "100.64.0.1", "0.0.0.0", "::1", "fe80::1"} {
+		if !Blocked(net.ParseIP(ip)) {
+			t.Errorf("expected %s to be blocked", ip)
+		}
+	}
+	for _, ip := range []string{"203.0.113.10", "8.8.8.8", "2001:4860:4860::8888"} {
+		if Blocked(net.ParseIP(ip)) {
+			t.Errorf("expected %s not to be blocked", ip)
+		}
+	}
+}
+
+func TestClientRefusesBlockedAddresses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	_, err := NewClient(time.Second).Get(server.URL)
+	if err == nil || !strings.Contains(err.Error(), "not a public address") {
+		t.Fatalf("expected the loopback address to be refused, got %v", err)
+	}
+}
@@ -0,0 +1,43 @@
+#!/bin/bash
+
+# Copyright 2019 The Kubernetes Authors.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+
+# Runs the end-to-end tests creating a real cluster on AWS. The AWS
+# credentials, region and key pair are read from the environment, and the
+# artifacts collected for debugging are written to ${ARTIFACTS}.
+
+set -o nounset
+set -o pipefail
+
+REPO_ROOT=$(dirname "${BASH_SOURCE}")/..
+
+: "${AWS_REGION:?must be set}"
+: "${AWS_SHARED_CREDENTIALS_FILE:=${HOME}/.aws/credentials}"
+: "${SSH_KEY_NAME:=default}"
+: "${ARTIFACTS:=${PWD}/_artifacts}"
+
+mkdir -p "${ARTIFACTS}"
+
+cd $REPO_ROOT
+bazel test --define='gotags=e2e' --test_output all --test_timeout=7200 \
+  --test_env=AWS_REGION="${AWS_REGION}" \
+  --test_env=AWS_SHARED_CREDENTIALS_FILE="${AWS_SHARED_CREDENTIALS_FILE}" \
+  --test_env=SSH_KEY_NAME="${SSH_KEY_NAME}" \
+  --test_env=ARTIFACTS="${ARTIFACTS}" \
+  ${E2E_ARGS:-} \
+  //test/e2e/...
+bazel_status=$?
+python hack/coalesce.py
+exit $bazel_status
@@ -0,0 +1,593 @@
+// +build e2e
+
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package e2e_test
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+	providerv1 "sigs.k8s.io/cluster-api-provider-aws/pkg/apis/awsprovider/v1alpha1"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/actuators"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/aws/services/ec2"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/deployer"
+	"sigs.k8s.io/cluster-api-provider-aws/test/e2e/util/kind"
+	clusterv1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+	"sigs.k8s.io/cluster-api/pkg/client/clientset_generated/clientset"
+)
+
+const (
+	clusterNamespace = "default"
+	workerSetName    = "workers"
+
+	clusterTimeout = 30 * time.Minute
+	machineTimeout = 20 * time.Minute
+	deleteTimeout  = 30 * time.Minute
+	pollInterval   = 15 * time.Second
+)
+
+var (
+	region            = flag.String("region", os.Getenv("AWS_REGION"), "AWS region the cluster is created in")
+	sshKeyName        = flag.String("sshKeyName", os.Getenv("SSH_KEY_NAME"), "name of the EC2 key pair installed on the machines")
+	credentialsFile   = flag.String("credentialsFile", os.Getenv("AWS_SHARED_CREDENTIALS_FILE"), "path to the AWS credentials file used by the controllers")
+	addonsYAML        = flag.String("addonsYAML", "../../cmd/clusterctl/examples/aws/addons.yaml", "path to the Kubernetes YAML for the addons of the cluster")
+	kubernetesVersion = flag.String("kubernetesVersion", "v1.13.0", "Kubernetes version of the machines")
+	controlPlaneType  = flag.String("controlPlaneMachineType", "t2.medium", "instance type of the control plane machines")
+	nodeType          = flag.String("nodeMachineType", "t2.medium", "instance type of the worker machines")
+	controlPlaneCount = flag.Int("controlPlaneCount", 3, "number of control plane machines, at least 3 keep etcd quorum while the control plane is rolled")
+	rollAMI           = flag.String("rollAMI", "", "ID of the AMI the control plane machines are rolled to, the roll is skipped if empty")
+	conformanceBinary = flag.String("conformanceBinary", "", "path to the Kubernetes e2e.test binary, only the smoke tests are run if empty")
+	artifactsDir      = flag.String("artifacts", defaultArtifactsDir(), "directory the artifacts collected for debugging are written to")
+)
+
+// defaultArtifactsDir returns the directory uploaded by the CI, if any.
+func defaultArtifactsDir() string {
+	if dir := os.Getenv("ARTIFACTS"); dir != "" {
+		return dir
+	}
+	return "_artifacts"
+}
+
+// keyMaterial matches the private keys of the cluster in the YAML of the
+// resources, and in the JSON of their last applied configuration.
+var keyMaterial = regexp.MustCompile(`((?:caKey|"caKey"):\s*)("[^"]*"|\S+)`)
+
+// redactKeys replaces the private keys of the cluster, so that they are not
+// uploaded with the artifacts.
+func redactKeys(data []byte) []byte {
+	return keyMaterial.ReplaceAll(data, []byte(`${1}"REDACTED"`))
+}
+
+var _ = Describe("AWS", func() {
+	var (
+		metacluster kind.Cluster
+		capi        clientset.Interface
+		name        string
+		kubeconfig  string
+		deleted     bool
+	)
+
+	BeforeEach(func() {
+		capi = nil
+		Expect(*region).NotTo(BeEmpty(), "the region must be set with -region or AWS_REGION")
+		Expect(*credentialsFile).NotTo(BeEmpty(), "the credentials must be set with -credentialsFile or AWS_SHARED_CREDENTIALS_FILE")
+
+		metacluster.Setup()
+		setControllerCredentials(metacluster.KubeClient(), *credentialsFile)
+		capi = metacluster.ClusterAPIClient()
+		name = "e2e-" + strconv.FormatInt(time.Now().Unix(), 36)
+		kubeconfig = ""
+		deleted = false
+	}, kindTimeout)
+
+	AfterEach(func() {
+		if capi != nil {
+			collectArtifacts(&metacluster, capi, name, kubeconfig)
+			if !deleted {
+				deleteCluster(capi, name)
+			}
+		}
+		metacluster.Teardown()
+	})
+
+	It("Should create, roll, scale and delete a cluster", func() {
+		By("Creating the cluster")
+		_, err := capi.ClusterV1alpha1().Clusters(clusterNamespace).Create(newCluster(name))
+		Expect(err).To(BeNil())
+
+		By("Creating the first control plane machine")
+		var controlPlane []*clusterv1.Machine
+		controlPlane = append(controlPlane, createMachine(capi, newControlPlaneMachine(name, "")))
+		Eventually(func() (providerv1.ClusterPhase, error) {
+			return clusterPhase(capi, name)
+		}, clusterTimeout, pollInterval).Should(Equal(providerv1.ClusterPhaseControlPlaneReady))
+
+		By("Installing the addons")
+		kubeconfig = writeKubeConfig(capi, name)
+		client := workloadClient(kubeconfig)
+		out, err := metacluster.Kubectl(kubeconfig, "apply", "-f", *addonsYAML)
+		Expect(err).To(BeNil(), string(out))
+		waitForMachineNodes(capi, client, controlPlane...)
+
+		By("Joining the other control plane machines")
+		for i := 1; i < *controlPlaneCount; i++ {
+			controlPlane = append(controlPlane, createMachine(capi, newControlPlaneMachine(name, "")))
+		}
+		waitForMachineNodes(capi, client, controlPlane...)
+
+		By("Creating the worker machines")
+		_, err = capi.ClusterV1alpha1().MachineDeployments(clusterNamespace).Create(newWorkerDeployment(name, 1))
+		Expect(err).To(BeNil())
+		waitForReadyNodes(client, *controlPlaneCount+1)
+
+		By("Running the smoke tests")
+		runSmokeTests(client)
+
+		if *conformanceBinary != "" {
+			By("Running the conformance tests")
+			runConformanceTests(kubeconfig)
+		}
+
+		By("Scaling the worker machines")
+		scaleWorkers(capi, 2)
+		waitForReadyNodes(client, *controlPlaneCount+2)
+
+		if *rollAMI != "" {
+			By(fmt.Sprintf("Rolling the control plane to AMI %q", *rollAMI))
+			for _, old := range controlPlane {
+				replacement := createMachine(capi, newControlPlaneMachine(name, *rollAMI))
+				waitForMachineNodes(capi, client, replacement)
+				replaceControlPlaneMachine(&metacluster, capi, client, kubeconfig, old, replacement)
+			}
+			waitForReadyNodes(client, *controlPlaneCount+2)
+			runSmokeTests(client)
+		}
+
+		By("Deleting the cluster")
+		deleteCluster(capi, name)
+		deleted = true
+	})
+})
+
+// setControllerCredentials stores the AWS credentials in the secret mounted
+// by the manager, then restarts it to pick them up.
+func setControllerCredentials(client kubernetes.Interface, path string) {
+	credentials, err := ioutil.ReadFile(path)
+	Expect(err).To(BeNil())
+
+	set, err := client.AppsV1().StatefulSets(controllerNamespace).Get(controllerName, metav1.GetOptions{})
+	Expect(err).To(BeNil())
+
+	// The name of the secret is suffixed with a hash of its contents.
+	var secretName string
+	for _, v := range set.Spec.Template.Spec.Volumes {
+		if v.Name == "credentials" && v.Secret != nil {
+			secretName = v.Secret.SecretName
+		}
+	}
+	Expect(secretName).NotTo(BeEmpty(), "the manager does not mount any credentials")
+
+	secret, err := client.CoreV1().Secrets(controllerNamespace).Get(secretName, metav1.GetOptions{})
+	Expect(err).To(BeNil())
+	secret.Data = map[string][]byte{"credentials": credentials}
+	_, err = client.CoreV1().Secrets(controllerNamespace).Update(secret)
+	Expect(err).To(BeNil())
+
+	err = client.CoreV1().Pods(controllerNamespace).DeleteCollection(&metav1.DeleteOptions{}, metav1.ListOptions{
+		LabelSelector: metav1.FormatLabelSelector(set.Spec.Selector),
+	})
+	Expect(err).To(BeNil())
+}
+
+func newCluster(name string) *clusterv1.Cluster {
+	spec, err := json.Marshal(&providerv1.AWSClusterProviderSpec{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "awsprovider/v1alpha1", Kind: "AWSClusterProviderSpec"},
+		Region:     *region,
+		SSHKeyName: *sshKeyName,
+	})
+	Expect(err).To(BeNil())
+
+	return &clusterv1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: clusterv1.ClusterSpec{
+			ClusterNetwork: clusterv1.ClusterNetworkingConfig{
+				Services:      clusterv1.NetworkRanges{CIDRBlocks: []string{"10.96.0.0/12"}},
+				Pods:          clusterv1.NetworkRanges{CIDRBlocks: []string{"192.168.0.0/16"}},
+				ServiceDomain: "cluster.local",
+			},
+			ProviderSpec: clusterv1.ProviderSpec{Value: &runtime.RawExtension{Raw: spec}},
+		},
+	}
+}
+
+// newMachineSpec returns the spec of a machine of the cluster, launched from
+// the given AMI or from the default AMI of its Kubernetes version if empty.
+func newMachineSpec(instanceType, profile, ami string, controlPlane bool) clusterv1.MachineSpec {
+	config := &providerv1.AWSMachineProviderSpec{
+		TypeMeta:           metav1.TypeMeta{APIVersion: "awsprovider/v1alpha1", Kind: "AWSMachineProviderSpec"},
+		InstanceType:       instanceType,
+		IAMInstanceProfile: profile,
+		KeyName:            *sshKeyName,
+	}
+	if ami != "" {
+		config.AMI.ID = aws.String(ami)
+	}
+
+	raw, err := providerv1.EncodeMachineSpec(config)
+	Expect(err).To(BeNil())
+
+	spec := clusterv1.MachineSpec{
+		Versions:     clusterv1.MachineVersionInfo{Kubelet: *kubernetesVersion},
+		ProviderSpec: clusterv1.ProviderSpec{Value: raw},
+	}
+	if controlPlane {
+		spec.Versions.ControlPlane = *kubernetesVersion
+	}
+
+	return spec
+}
+
+func newControlPlaneMachine(cluster, ami string) *clusterv1.Machine {
+	return &clusterv1.Machine{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: cluster + "-controlplane-",
+			Labels:       map[string]string{"set": "controlplane"},
+		},
+		Spec: newMachineSpec(*controlPlaneType, "control-plane.cluster-api-provider-aws.sigs.k8s.io", ami, true),
+	}
+}
+
+func newWorkerDeployment(cluster string, replicas int32) *clusterv1.MachineDeployment {
+	labels := map[string]string{"set": "node", "machine-deployment": workerSetName}
+
+	return &clusterv1.MachineDeployment{
+		ObjectMeta: metav1.ObjectMeta{Name: workerSetName},
+		Spec: clusterv1.MachineDeploymentSpec{
+			Replicas: &replicas,
+			Selector: metav1.LabelSelector{MatchLabels: labels},
+			Template: clusterv1.MachineTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					GenerateName: cluster + "-node-",
+					Labels:       labels,
+				},
+				Spec: newMachineSpec(*nodeType, "nodes.cluster-api-provider-aws.sigs.k8s.io", "", false),
+			},
+		},
+	}
+}
+
+func createMachine(capi clientset.Interface, machine *clusterv1.Machine) *clusterv1.Machine {
+	machine, err := capi.ClusterV1alpha1().Machines(clusterNamespace).Create(machine)
+	Expect(err).To(BeNil())
+	fmt.Fprintf(GinkgoWriter, "created machine %q\n", machine.Name)
+	return machine
+}
+
+func clusterPhase(capi clientset.Interface, name string) (providerv1.ClusterPhase, error) {
+	cluster, err := capi.ClusterV1alpha1().Clusters(clusterNamespace).Get(name, metav1.GetOptions{})
+	if err != nil {
+		return "", err
+	}
+
+	status, err := providerv1.ClusterStatusFromProviderStatus(cluster.Status.ProviderStatus)
+	if err != nil {
+		return "", err
+	}
+
+	return status.Phase, nil
+}
+
+// writeKubeConfig generates an admin kubeconfig for the cluster from its CA,
+// the way clusterctl does, and returns its path.
+func writeKubeConfig(capi clientset.Interface, name string) string {
+	cluster, err := capi.ClusterV1alpha1().Clusters(clusterNamespace).Get(name, metav1.GetOptions{})
+	Expect(err).To(BeNil())
+
+	config, err := deployer.New(deployer.Params{}).GetKubeConfig(cluster, nil)
+	Expect(err).To(BeNil())
+
+	Expect(os.MkdirAll(*artifactsDir, 0700)).To(Succeed())
+	path := filepath.Join(*artifactsDir, name+".kubeconfig")
+	Expect(ioutil.WriteFile(path, []byte(config), 0600)).To(Succeed())
+	return path
+}
+
+func workloadClient(kubeconfig string) kubernetes.Interface {
+	cfg, err := clientcmd.BuildConfigFromFlags("", kubeconfig)
+	Expect(err).To(BeNil())
+	client, err := kubernetes.NewForConfig(cfg)
+	Expect(err).To(BeNil())
+	return client
+}
+
+// machineNode returns the node of a machine, found by the instance ID in its
+// provider ID, or nil if the machine has not joined the cluster yet.
+func machineNode(capi clientset.Interface, client kubernetes.Interface, name string) (*corev1.Node, error) {
+	machine, err := capi.ClusterV1alpha1().Machines(clusterNamespace).Get(name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	status, err := providerv1.MachineStatusFromProviderStatus(machine.Status.ProviderStatus)
+	if err != nil || status.InstanceID == nil {
+		return nil, err
+	}
+
+	nodes, err := client.CoreV1().Nodes().List(metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range nodes.Items {
+		if strings.HasSuffix(nodes.Items[i].Spec.ProviderID, "/"+*status.InstanceID) {
+			return &nodes.Items[i], nil
+		}
+	}
+
+	return nil, nil
+}
+
+func waitForMachineNodes(capi clientset.Interface, client kubernetes.Interface, machines ...*clusterv1.Machine) {
+	for _, m := range machines {
+		Eventually(func() (bool, error) {
+			node, err := machineNode(capi, client, m.Name)
+			return node != nil && nodeReady(node), err
+		}, machineTimeout, pollInterval).Should(BeTrue(), "machine %q did not become a ready node", m.Name)
+	}
+}
+
+func waitForReadyNodes(client kubernetes.Interface, count int) {
+	Eventually(func() (int, error) {
+		nodes, err := client.CoreV1().Nodes().List(metav1.ListOptions{})
+		if err != nil {
+			return 0, err
+		}
+
+		ready := 0
+		for i := range nodes.Items {
+			if nodeReady(&nodes.Items[i]) {
+				ready++
+			}
+		}
+		return ready, nil
+	}, machineTimeout, pollInterval).Should(Equal(count))
+}
+
+func nodeReady(node *corev1.Node) bool {
+	for _, c := range node.Status.Conditions {
+		if c.Type == corev1.NodeReady {
+			return c.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// runSmokeTests checks that the system pods are running and that workloads
+// can be scheduled on the cluster.
+func runSmokeTests(client kubernetes.Interface) {
+	Eventually(func() ([]string, error) {
+		pods, err := client.CoreV1().Pods("kube-system").List(metav1.ListOptions{})
+		if err != nil {
+			return nil, err
+		}
+
+		var pending []string
+		for _, p := range pods.Items {
+			if p.Status.Phase != corev1.PodRunning && p.Status.Phase != corev1.PodSucceeded {
+				pending = append(pending, p.Name)
+			}
+		}
+		return pending, nil
+	}, machineTimeout, pollInterval).Should(BeEmpty())
+
+	replicas := int32(2)
+	labels := map[string]string{"app": "e2e-smoke"}
+	deployments := client.AppsV1().Deployments(metav1.NamespaceDefault)
+	_, err := deployments.Create(&appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "e2e-smoke"},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{MatchLabels: labels},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: "pause", Image: "k8s.gcr.io/pause:3.1"}},
+				},
+			},
+		},
+	})
+	Expect(err).To(BeNil())
+
+	Eventually(func() (int32, error) {
+		d, err := deployments.Get("e2e-smoke", metav1.GetOptions{})
+		if err != nil {
+			return 0, err
+		}
+		return d.Status.AvailableReplicas, nil
+	}, machineTimeout, pollInterval).Should(Equal(replicas))
+
+	Expect(deployments.Delete("e2e-smoke", &metav1.DeleteOptions{})).To(Succeed())
+}
+
+// runConformanceTests runs the parallel conformance tests of the Kubernetes
+// e2e suite, writing their JUnit report to the artifacts.
+func runConformanceTests(kubeconfig string) {
+	cmd := exec.Command(*conformanceBinary,
+		"--kubeconfig="+kubeconfig,
+		"--provider=skeleton",
+		"--ginkgo.focus=\\[Conformance\\]",
+		"--ginkgo.skip=\\[Serial\\]|\\[Disruptive\\]",
+		"--report-dir="+filepath.Join(*artifactsDir, "conformance"),
+	)
+	cmd.Stdout = GinkgoWriter
+	cmd.Stderr = GinkgoWriter
+	Expect(cmd.Run()).To(Succeed())
+}
+
+func scaleWorkers(capi clientset.Interface, replicas int32) {
+	deployments := capi.ClusterV1alpha1().MachineDeployments(clusterNamespace)
+	d, err := deployments.Get(workerSetName, metav1.GetOptions{})
+	Expect(err).To(BeNil())
+	d.Spec.Replicas = &replicas
+	_, err = deployments.Update(d)
+	Expect(err).To(BeNil())
+}
+
+// replaceControlPlaneMachine removes a control plane machine replaced by a
+// ready one. Its etcd member is removed first, as the machine controller does
+// not, so that etcd keeps quorum across the whole roll.
+func replaceControlPlaneMachine(metacluster *kind.Cluster, capi clientset.Interface, client kubernetes.Interface, kubeconfig string, old, replacement *clusterv1.Machine) {
+	oldNode, err := machineNode(capi, client, old.Name)
+	Expect(err).To(BeNil())
+	Expect(oldNode).NotTo(BeNil())
+	newNode, err := machineNode(capi, client, replacement.Name)
+	Expect(err).To(BeNil())
+	Expect(newNode).NotTo(BeNil())
+
+	etcdctl := func(args string) string {
+		out, err := metacluster.Kubectl(kubeconfig, "-n", "kube-system", "exec", "etcd-"+newNode.Name, "--", "sh", "-c",
+			"ETCDCTL_API=3 etcdctl --endpoints=https://127.0.0.1:2379 --cacert=/etc/kubernetes/pki/etcd/ca.crt "+
+				"--cert=/etc/kubernetes/pki/etcd/peer.crt --key=/etc/kubernetes/pki/etcd/peer.key "+args)
+		Expect(err).To(BeNil(), string(out))
+		return string(out)
+	}
+
+	// Members are listed as "ID, status, name, peer URLs, client URLs".
+	for _, line := range strings.Split(etcdctl("member list"), "\n") {
+		fields := strings.Split(line, ", ")
+		if len(fields) >= 3 && fields[2] == oldNode.Name {
+			etcdctl("member remove " + fields[0])
+		}
+	}
+
+	Expect(capi.ClusterV1alpha1().Machines(clusterNamespace).Delete(old.Name, &metav1.DeleteOptions{})).To(Succeed())
+	Eventually(func() bool {
+		_, err := capi.ClusterV1alpha1().Machines(clusterNamespace).Get(old.Name, metav1.GetOptions{})
+		return apierrors.IsNotFound(err)
+	}, deleteTimeout, pollInterval).Should(BeTrue(), "machine %q was not deleted", old.Name)
+
+	err = client.CoreV1().Nodes().Delete(oldNode.Name, &metav1.DeleteOptions{})
+	Expect(err == nil || apierrors.IsNotFound(err)).To(BeTrue())
+}
+
+// deleteCluster deletes the machines of the cluster, then the cluster, and
+// waits for the controllers to delete their AWS resources.
+func deleteCluster(capi clientset.Interface, name string) {
+	client := capi.ClusterV1alpha1()
+
+	err := client.MachineDeployments(clusterNamespace).DeleteCollection(&metav1.DeleteOptions{}, metav1.ListOptions{})
+	Expect(err).To(BeNil())
+	err = client.Machines(clusterNamespace).DeleteCollection(&metav1.DeleteOptions{}, metav1.ListOptions{})
+	Expect(err).To(BeNil())
+	Eventually(func() (int, error) {
+		machines, err := client.Machines(clusterNamespace).List(metav1.ListOptions{})
+		if err != nil {
+			return 0, err
+		}
+		return len(machines.Items), nil
+	}, deleteTimeout, pollInterval).Should(BeZero())
+
+	err = client.Clusters(clusterNamespace).Delete(name, &metav1.DeleteOptions{})
+	if apierrors.IsNotFound(err) {
+		return
+	}
+	Expect(err).To(BeNil())
+	Eventually(func() bool {
+		_, err := client.Clusters(clusterNamespace).Get(name, metav1.GetOptions{})
+		return apierrors.IsNotFound(err)
+	}, deleteTimeout, pollInterval).Should(BeTrue(), "cluster %q was not deleted", name)
+}
+
+// collectArtifacts writes the state of the cluster to the artifacts for
+// debugging: the Cluster API resources, the logs of the controllers, the
+// console output of the instances and the pods of the workload cluster.
+// Failures are logged rather than asserted, to collect as much as possible.
+func collectArtifacts(metacluster *kind.Cluster, capi clientset.Interface, name, kubeconfig string) {
+	if err := os.MkdirAll(*artifactsDir, 0700); err != nil {
+		fmt.Fprintf(GinkgoWriter, "failed to create artifacts directory: %v\n", err)
+		return
+	}
+
+	write := func(file string, data []byte, err error) {
+		if err != nil {
+			fmt.Fprintf(GinkgoWriter, "failed to collect %s: %v\n", file, err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(*artifactsDir, file), data, 0600); err != nil {
+			fmt.Fprintf(GinkgoWriter, "failed to write %s: %v\n", file, err)
+		}
+	}
+
+	meta := metacluster.KubeConfigPath()
+	out, err := metacluster.Kubectl(meta, "get", "clusters,machines,machinesets,machinedeployments", "--all-namespaces", "-o", "yaml")
+	write("resources.yaml", redactKeys(out), err)
+	out, err = metacluster.Kubectl(meta, "-n", controllerNamespace, "logs", "statefulset/"+controllerName)
+	write("aws-provider-controller-manager.log", out, err)
+	out, err = metacluster.Kubectl(meta, "-n", "cluster-api-system", "logs", "statefulset/cluster-api-controller-manager")
+	write("cluster-api-controller-manager.log", out, err)
+
+	if kubeconfig != "" {
+		out, err = metacluster.Kubectl(kubeconfig, "get", "nodes,pods", "--all-namespaces", "-o", "wide")
+		write("workload-cluster.txt", out, err)
+	}
+
+	cluster, err := capi.ClusterV1alpha1().Clusters(clusterNamespace).Get(name, metav1.GetOptions{})
+	if err != nil {
+		fmt.Fprintf(GinkgoWriter, "failed to get cluster %q: %v\n", name, err)
+		return
+	}
+	machines, err := capi.ClusterV1alpha1().Machines(clusterNamespace).List(metav1.ListOptions{})
+	if err != nil {
+		fmt.Fprintf(GinkgoWriter, "failed to list machines: %v\n", err)
+		return
+	}
+
+	scope, err := actuators.NewScope(actuators.ScopeParams{Cluster: cluster})
+	if err != nil {
+		fmt.Fprintf(GinkgoWriter, "failed to create scope: %v\n", err)
+		return
+	}
+	ec2svc := ec2.NewService(scope)
+
+	for _, m := range machines.Items {
+		status, err := providerv1.MachineStatusFromProviderStatus(m.Status.ProviderStatus)
+		if err != nil || status.InstanceID == nil {
+			continue
+		}
+		console, err := ec2svc.GetConsoleOutput(*status.InstanceID)
+		write(m.Name+".console.log", []byte(console), err)
+	}
+}
@@ -30,6 +30,7 @@ import (
 	"github.com/onsi/gomega"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/cluster-api/pkg/client/clientset_generated/clientset"
 )
 
 var (
@@ -105,6 +106,29 @@ func (c *Cluster) KubeClient() kubernetes.Interface {
 
 }
 
+// ClusterAPIClient returns a client for the Cluster API resources of the KIND cluster
+func (c *Cluster) ClusterAPIClient() clientset.Interface {
+	cfg, err := clientcmd.BuildConfigFromFlags("", c.kubepath)
+	gomega.ExpectWithOffset(1, err).To(gomega.BeNil())
+	client, err := clientset.NewForConfig(cfg)
+	gomega.ExpectWithOffset(1, err).To(gomega.BeNil())
+	return client
+}
+
+// Kubectl runs kubectl with the given kubeconfig and returns its output.
+// Unlike the commands run against the KIND cluster, failures are returned
+// rather than asserted, so callers collecting debugging output can carry on.
+func (c *Cluster) Kubectl(kubeconfig string, args ...string) ([]byte, error) {
+	cmd := exec.Command(*kubectlBinary, append([]string{"--kubeconfig=" + kubeconfig}, args...)...)
+	cmd.Env = append(cmd.Env, fmt.Sprintf("HOME=%s", c.tmpDir), fmt.Sprintf("PATH=%s", os.Getenv("PATH")))
+	return cmd.CombinedOutput()
+}
+
+// KubeConfigPath returns the path to the kubeconfig of the KIND cluster
+func (c *Cluster) KubeConfigPath() string {
+	return c.kubepath
+}
+
 func (c *Cluster) runWithOutput(cmd *exec.Cmd) []byte {
 	var stdout bytes.Buffer
 	cmd.Stdout = &stdout